@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/leora/leora-server/internal/modules/finance"
+)
+
+// runLedgerCheck implements `leora-server ledger-check [-user id] [-repair]`.
+// It prints the report as JSON and returns 1 when the check fails and 2 when
+// drift is left unrepaired, so it can gate a cron job.
+func runLedgerCheck(args []string, dbConn *sqlx.DB, cache *redis.Client) int {
+	flags := flag.NewFlagSet("ledger-check", flag.ExitOnError)
+	userID := flags.String("user", "", "check a single user instead of everyone")
+	repair := flags.Bool("repair", false, "repair the drift found and record it in ledger_repairs")
+	_ = flags.Parse(args)
+
+	service := finance.NewService(finance.NewPostgresRepository(dbConn), cache)
+	report, err := service.CheckLedger(context.Background(), finance.LedgerCheckOptions{
+		UserID:     *userID,
+		Repair:     *repair,
+		RepairedBy: "cli",
+	})
+	if err != nil {
+		log.Printf("ledger-check: %v", err)
+		return 1
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Printf("ledger-check: %v", err)
+		return 1
+	}
+	if report.DriftCount > report.RepairedCount {
+		return 2
+	}
+	return 0
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -38,9 +42,16 @@ func main() {
 		log.Fatalf("redis: %v", err)
 	}
 
+	// Maintenance subcommands share the config and connections but never
+	// start the HTTP server.
+	if len(os.Args) > 1 && os.Args[1] == "ledger-check" {
+		os.Exit(runLedgerCheck(os.Args[2:], dbConn, cache))
+	}
+
 	app := fiber.New(fiber.Config{
 		Prefork:      false,
 		ServerHeader: cfg.App.Name,
+		BodyLimit:    cfg.Storage.RequestBodyLimit(fiber.DefaultBodyLimit),
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			return response.Failure(c, appErrors.InternalServerError)
 		},
@@ -70,7 +81,17 @@ func main() {
 	})
 
 	moduleRouter := app.Group(cfg.App.BasePath)
-	modules.RegisterRoutes(moduleRouter, cfg, dbConn, cache)
+	workers := modules.RegisterRoutes(moduleRouter, cfg, dbConn, cache)
+
+	// Background jobs live as long as the server: an interrupt or SIGTERM
+	// stops both.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	workers.Start(ctx)
+	go func() {
+		<-ctx.Done()
+		_ = app.Shutdown()
+	}()
 
 	addr := fmt.Sprintf(":%d", cfg.App.Port)
 	if err := app.Listen(addr); err != nil {
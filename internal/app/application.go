@@ -1,13 +1,18 @@
 package app
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/jmoiron/sqlx"
+	"github.com/leora/leora-server/internal/common/localization"
 	"github.com/leora/leora-server/internal/config"
 	"github.com/leora/leora-server/internal/db"
-	"github.com/leora/leora-server/internal/common/localization"
+	"github.com/leora/leora-server/internal/modules"
 	redisclient "github.com/leora/leora-server/internal/redis"
 	"github.com/leora/leora-server/internal/services"
 	"github.com/redis/go-redis/v9"
@@ -20,6 +25,7 @@ type Application struct {
 	Services *services.Services
 	DB       *sqlx.DB
 	Cache    *redis.Client
+	Workers  *modules.Workers
 }
 
 // NewApplication builds the application with configuration and handlers.
@@ -32,6 +38,7 @@ func NewApplication() (*Application, error) {
 	fiberApp := fiber.New(fiber.Config{
 		Prefork:      false,
 		ServerHeader: "Leora",
+		BodyLimit:    cfg.Storage.RequestBodyLimit(fiber.DefaultBodyLimit),
 	})
 
 	dbConn, err := db.NewPostgres(cfg.Database)
@@ -64,7 +71,15 @@ func NewApplication() (*Application, error) {
 	return application, nil
 }
 
-// Start runs the Fiber server loop.
+// Start runs the background workers and the Fiber server loop. An interrupt
+// or SIGTERM shuts the server down and stops the workers.
 func (a *Application) Start() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	a.Workers.Start(ctx)
+	go func() {
+		<-ctx.Done()
+		_ = a.FiberApp.Shutdown()
+	}()
 	return a.FiberApp.Listen(fmt.Sprintf(":%d", a.Config.App.Port))
 }
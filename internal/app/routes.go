@@ -15,7 +15,7 @@ import (
 func (a *Application) registerRoutes() {
 	base := a.FiberApp.Group(a.Config.App.BasePath)
 
-	modules.RegisterRoutes(base, a.Config, a.DB, a.Cache)
+	a.Workers = modules.RegisterRoutes(base, a.Config, a.DB, a.Cache)
 
 	insightsTransport.RegisterRoutes(base)
 	syncTransport.RegisterRoutes(base)
@@ -6,12 +6,15 @@ import (
 	appErrors "github.com/leora/leora-server/internal/errors"
 )
 
-// Meta defines pagination metadata.
+// Meta defines pagination metadata. Page-based lists fill Page, Total and
+// TotalPages; cursor-based lists fill NextCursor and HasMore.
 type Meta struct {
-	Page       int `json:"page,omitempty"`
-	Limit      int `json:"limit,omitempty"`
-	Total      int `json:"total,omitempty"`
-	TotalPages int `json:"totalPages,omitempty"`
+	Page       int    `json:"page,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+	Total      int    `json:"total,omitempty"`
+	TotalPages int    `json:"totalPages,omitempty"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	HasMore    bool   `json:"hasMore,omitempty"`
 }
 
 // ErrorDetail contains field-specific errors.
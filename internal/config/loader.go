@@ -11,9 +11,12 @@ import (
 
 // Config holds global configuration values.
 type Config struct {
-	App      AppConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
+	App       AppConfig
+	Database  DatabaseConfig
+	Redis     RedisConfig
+	FX        FXConfig
+	Storage   StorageConfig
+	Scheduler SchedulerConfig
 }
 
 // AppConfig stores application-level settings.
@@ -48,6 +51,50 @@ type RedisConfig struct {
 	URL      string // populated from REDIS_URL env var
 }
 
+// FXConfig selects the FX rate feeds ingested by the finance scheduler. An
+// empty URL disables that feed.
+type FXConfig struct {
+	CBUURL          string  `mapstructure:"cbuURL"`
+	ECBURL          string  `mapstructure:"ecbURL"`
+	ECBBaseCurrency string  `mapstructure:"ecbBaseCurrency"`
+	SpreadPercent   float64 `mapstructure:"spreadPercent"`
+}
+
+// SchedulerConfig controls the finance background jobs. Interval is the
+// time between runs.
+type SchedulerConfig struct {
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+// StorageConfig selects where uploaded attachments live. Backend "local"
+// keeps files under LocalDir and serves them through signed API links;
+// "s3" uses any S3-compatible service and presigned URLs.
+type StorageConfig struct {
+	Backend       string        `mapstructure:"backend"`
+	LocalDir      string        `mapstructure:"localDir"`
+	SigningSecret string        `mapstructure:"signingSecret"`
+	URLTTL        time.Duration `mapstructure:"urlTTL"`
+	MaxFileBytes  int64         `mapstructure:"maxFileBytes"`
+	QuotaBytes    int64         `mapstructure:"quotaBytes"`
+	S3Endpoint    string        `mapstructure:"s3Endpoint"`
+	S3Region      string        `mapstructure:"s3Region"`
+	S3Bucket      string        `mapstructure:"s3Bucket"`
+	S3AccessKey   string        `mapstructure:"s3AccessKey"`
+	S3SecretKey   string        `mapstructure:"s3SecretKey"`
+	S3PathStyle   bool          `mapstructure:"s3PathStyle"`
+}
+
+// RequestBodyLimit is the largest request body the server should accept:
+// an attachment upload carries a whole file plus the multipart envelope.
+// It never drops below floor.
+func (c StorageConfig) RequestBodyLimit(floor int) int {
+	limit := int(c.MaxFileBytes) + 1<<20
+	if limit < floor {
+		return floor
+	}
+	return limit
+}
+
 // Load reads configuration from env and config file.
 func Load(path string) (*Config, error) {
 	v := viper.New()
@@ -59,6 +106,15 @@ func Load(path string) (*Config, error) {
 
 	v.SetDefault("app.basePath", "/api/v1")
 	v.SetDefault("app.port", 9090)
+	v.SetDefault("fx.cbuURL", "https://cbu.uz/uz/arkhiv-kursov-valyut/json")
+	v.SetDefault("fx.ecbBaseCurrency", "EUR")
+	v.SetDefault("storage.backend", "local")
+	v.SetDefault("storage.localDir", "data/attachments")
+	v.SetDefault("storage.urlTTL", "15m")
+	v.SetDefault("storage.maxFileBytes", 10<<20)
+	v.SetDefault("storage.quotaBytes", 500<<20)
+	v.SetDefault("storage.s3Region", "us-east-1")
+	v.SetDefault("scheduler.interval", "5m")
 
 	if err := v.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("read config: %w", err)
@@ -111,6 +167,34 @@ func Load(path string) (*Config, error) {
 	// REDIS_URL takes precedence over individual fields.
 	cfg.Redis.URL = os.Getenv("REDIS_URL")
 
+	// S3 credentials and the link signing secret from env.
+	if key := os.Getenv("S3_ACCESS_KEY_ID"); key != "" {
+		cfg.Storage.S3AccessKey = key
+	}
+	if secret := os.Getenv("S3_SECRET_ACCESS_KEY"); secret != "" {
+		cfg.Storage.S3SecretKey = secret
+	}
+	if secret := os.Getenv("STORAGE_SIGNING_SECRET"); secret != "" {
+		cfg.Storage.SigningSecret = secret
+	}
+	// Local files are served through links signed with this key; it must not
+	// double as the JWT secret.
+	if cfg.Storage.Backend != "s3" {
+		if cfg.Storage.SigningSecret == "" {
+			return nil, fmt.Errorf("storage signing secret not configured")
+		}
+		if cfg.Storage.SigningSecret == cfg.App.JWTSecret {
+			return nil, fmt.Errorf("storage signing secret must differ from the jwt secret")
+		}
+	}
+	if cfg.Storage.Backend == "s3" && (cfg.Storage.S3Endpoint == "" || cfg.Storage.S3Bucket == "") {
+		return nil, fmt.Errorf("s3 storage needs s3Endpoint and s3Bucket")
+	}
+
+	if cfg.Scheduler.Interval <= 0 {
+		return nil, fmt.Errorf("scheduler interval must be positive")
+	}
+
 	accessTTL := 30 * time.Minute
 	if cfg.App.JWTAccessTTL == 0 || cfg.App.JWTAccessTTL > accessTTL {
 		cfg.App.JWTAccessTTL = accessTTL
@@ -40,6 +40,8 @@ func StatusFromType(errType string) int {
 		return http.StatusConflict
 	case "RATE_LIMITED":
 		return http.StatusTooManyRequests
+	case "QUOTA_EXCEEDED":
+		return http.StatusForbidden
 	case "ACCOUNT_REQUIRED":
 		return http.StatusBadRequest
 	case "INSUFFICIENT_FUNDS":
@@ -113,6 +115,66 @@ var (
 	AccountHasTransactions    = &Error{Code: -5023, Type: "ACCOUNT_HAS_TRANSACTIONS", Message: "Account has transactions and cannot be deleted", Slug: "FIN_ACCOUNT_HAS_TRANSACTIONS"}
 	InsufficientFunds         = &Error{Code: -5024, Type: "INSUFFICIENT_FUNDS", Message: "Insufficient funds", Slug: "FIN_INSUFFICIENT_FUNDS"}
 	TransactionImmutable      = &Error{Code: -5025, Type: "TXN_IMMUTABLE", Message: "Transaction cannot be modified", Slug: "FIN_TXN_IMMUTABLE"}
+	TransactionAlreadyVoided  = &Error{Code: -5029, Type: "CONFLICT", Message: "Transaction has already been reversed", Slug: "FIN_TXN_ALREADY_VOIDED"}
+	TransactionNotReversible  = &Error{Code: -5030, Type: "TXN_IMMUTABLE", Message: "Transaction type cannot be reversed", Slug: "FIN_TXN_NOT_REVERSIBLE"}
+
+	// Recurring transaction errors
+	RecurringNotFound           = &Error{Code: -5031, Type: "NOT_FOUND", Message: "Recurring transaction not found", Slug: "FIN_RECURRING_NOT_FOUND"}
+	InvalidRecurrenceRule       = &Error{Code: -5032, Type: "VALIDATION", Message: "Invalid recurrence rule", Slug: "FIN_INVALID_RECURRENCE"}
+	RecurringOccurrenceNotFound = &Error{Code: -5033, Type: "NOT_FOUND", Message: "Recurring occurrence not found", Slug: "FIN_RECURRING_OCCURRENCE_NOT_FOUND"}
+	RecurringOccurrenceResolved = &Error{Code: -5034, Type: "CONFLICT", Message: "Recurring occurrence has already been resolved", Slug: "FIN_RECURRING_OCCURRENCE_RESOLVED"}
+
+	// Budget period errors
+	BudgetPeriodClosed     = &Error{Code: -5035, Type: "CONFLICT", Message: "Budget period has already been closed", Slug: "FIN_BUDGET_PERIOD_CLOSED"}
+	BudgetPeriodNotRolling = &Error{Code: -5036, Type: "VALIDATION", Message: "Only weekly and monthly budgets have periods", Slug: "FIN_BUDGET_PERIOD_NOT_ROLLING"}
+	BudgetPeriodNotEnded   = &Error{Code: -5061, Type: "CONFLICT", Message: "Budget period has not ended yet", Slug: "FIN_BUDGET_PERIOD_NOT_ENDED"}
+
+	// Debt schedule errors
+	InvalidDebtSchedule = &Error{Code: -5037, Type: "VALIDATION", Message: "Invalid debt repayment schedule", Slug: "FIN_INVALID_DEBT_SCHEDULE"}
+
+	// Statement import errors
+	StatementImportNotFound        = &Error{Code: -5038, Type: "NOT_FOUND", Message: "Statement import not found", Slug: "FIN_IMPORT_NOT_FOUND"}
+	StatementImportRowNotFound     = &Error{Code: -5039, Type: "NOT_FOUND", Message: "Statement import row not found", Slug: "FIN_IMPORT_ROW_NOT_FOUND"}
+	StatementImportProfileNotFound = &Error{Code: -5040, Type: "NOT_FOUND", Message: "Statement import profile not found", Slug: "FIN_IMPORT_PROFILE_NOT_FOUND"}
+	InvalidStatementFile           = &Error{Code: -5041, Type: "VALIDATION", Message: "Statement file could not be read", Slug: "FIN_INVALID_STATEMENT"}
+	StatementImportUndone          = &Error{Code: -5042, Type: "CONFLICT", Message: "Statement import has been undone", Slug: "FIN_IMPORT_UNDONE"}
+
+	// SMS notification errors
+	SMSMessageNotFound  = &Error{Code: -5043, Type: "NOT_FOUND", Message: "SMS message not found", Slug: "FIN_SMS_NOT_FOUND"}
+	SMSCardLinkNotFound = &Error{Code: -5044, Type: "NOT_FOUND", Message: "Card link not found", Slug: "FIN_SMS_CARD_LINK_NOT_FOUND"}
+	SMSCardLinkExists   = &Error{Code: -5045, Type: "CONFLICT", Message: "Card is already linked to an account", Slug: "FIN_SMS_CARD_LINK_EXISTS"}
+
+	// Categorization rule errors
+	CategorizationRuleNotFound = &Error{Code: -5046, Type: "NOT_FOUND", Message: "Categorization rule not found", Slug: "FIN_RULE_NOT_FOUND"}
+	InvalidCategorizationRule  = &Error{Code: -5047, Type: "VALIDATION", Message: "Invalid categorization rule", Slug: "FIN_INVALID_RULE"}
+
+	// Split transaction errors
+	InvalidTransactionSplit = &Error{Code: -5048, Type: "VALIDATION", Message: "Split lines must sum to the transaction amount", Slug: "FIN_INVALID_SPLIT"}
+
+	// Attachment errors
+	AttachmentNotFound      = &Error{Code: -5049, Type: "NOT_FOUND", Message: "Attachment not found", Slug: "FIN_ATTACHMENT_NOT_FOUND"}
+	InvalidAttachment       = &Error{Code: -5050, Type: "VALIDATION", Message: "File type or size is not allowed", Slug: "FIN_INVALID_ATTACHMENT"}
+	AttachmentQuotaExceeded = &Error{Code: -5051, Type: "QUOTA_EXCEEDED", Message: "Attachment storage quota exceeded", Slug: "FIN_ATTACHMENT_QUOTA_EXCEEDED"}
+	AttachmentInUse         = &Error{Code: -5052, Type: "CONFLICT", Message: "Attachment is still referenced", Slug: "FIN_ATTACHMENT_IN_USE"}
+	AttachmentLinkInvalid   = &Error{Code: -5053, Type: "FORBIDDEN", Message: "Download link is invalid or has expired", Slug: "FIN_ATTACHMENT_LINK_INVALID"}
+
+	// Reconciliation errors
+	ReconciliationNotFound   = &Error{Code: -5054, Type: "NOT_FOUND", Message: "Reconciliation not found", Slug: "FIN_RECONCILIATION_NOT_FOUND"}
+	InvalidReconciliation    = &Error{Code: -5055, Type: "VALIDATION", Message: "Invalid reconciliation", Slug: "FIN_INVALID_RECONCILIATION"}
+	ReconciliationInProgress = &Error{Code: -5056, Type: "CONFLICT", Message: "Account already has an open reconciliation", Slug: "FIN_RECONCILIATION_IN_PROGRESS"}
+	TransactionLocked        = &Error{Code: -5057, Type: "CONFLICT", Message: "Transaction is locked by a completed reconciliation", Slug: "FIN_TRANSACTION_RECONCILED"}
+
+	// Ledger integrity errors
+	LedgerDriftChanged = &Error{Code: -5058, Type: "CONFLICT", Message: "Balance changed while the ledger was being checked", Slug: "FIN_LEDGER_DRIFT_CHANGED"}
+
+	// Scheduled transaction errors
+	TransactionNotScheduled = &Error{Code: -5059, Type: "CONFLICT", Message: "Transaction is not waiting to be posted", Slug: "FIN_TRANSACTION_NOT_SCHEDULED"}
+
+	// Credit card errors
+	CreditStatementNotFound = &Error{Code: -5060, Type: "NOT_FOUND", Message: "Credit statement not found", Slug: "FIN_CREDIT_STATEMENT_NOT_FOUND"}
+
+	// Account merge errors
+	AccountMergeBlocked = &Error{Code: -5062, Type: "CONFLICT", Message: "Account has reconciliations or card statements and cannot be merged", Slug: "FIN_ACCOUNT_MERGE_BLOCKED"}
 )
 
 var (
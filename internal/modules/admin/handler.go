@@ -5,6 +5,7 @@ import (
 	"github.com/leora/leora-server/internal/common/response"
 	appErrors "github.com/leora/leora-server/internal/errors"
 	"github.com/leora/leora-server/internal/modules/auth"
+	"github.com/leora/leora-server/internal/modules/finance"
 )
 
 // Handler exposes admin-only endpoints.
@@ -52,3 +53,30 @@ func (h *Handler) UpdateUserRole(c *fiber.Ctx) error {
 	}
 	return response.Success(c, user, nil)
 }
+
+func (h *Handler) CheckLedger(c *fiber.Ctx) error {
+	report, err := h.service.CheckLedger(c.Context(), finance.LedgerCheckOptions{UserID: c.Query("userId")})
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, report, nil)
+}
+
+func (h *Handler) RepairLedger(c *fiber.Ctx) error {
+	adminID, _ := c.Locals(auth.ContextUserIDKey).(string)
+	report, err := h.service.CheckLedger(c.Context(), finance.LedgerCheckOptions{
+		UserID:     c.Query("userId"),
+		Repair:     true,
+		RepairedBy: "admin:" + adminID,
+	})
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, report, nil)
+}
@@ -10,4 +10,6 @@ func RegisterRoutes(router fiber.Router, handler *Handler, middleware *auth.Midd
 	group := router.Group("/admin")
 	group.Get("/users", middleware.RequireRoleAtLeast(auth.RoleModeratorAdmin), handler.ListUsers)
 	group.Patch("/users/:id/role", middleware.RequireRoleAtLeast(auth.RoleSuperAdmin), handler.UpdateUserRole)
+	group.Get("/finance/ledger", middleware.RequireRoleAtLeast(auth.RoleAdmin), handler.CheckLedger)
+	group.Post("/finance/ledger/repair", middleware.RequireRoleAtLeast(auth.RoleSuperAdmin), handler.RepairLedger)
 }
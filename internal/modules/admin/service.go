@@ -4,12 +4,15 @@ import (
 	"context"
 	"time"
 
+	appErrors "github.com/leora/leora-server/internal/errors"
 	"github.com/leora/leora-server/internal/modules/auth"
+	"github.com/leora/leora-server/internal/modules/finance"
 )
 
 // Service handles administrative operations around users.
 type Service struct {
-	repo auth.Repository
+	repo   auth.Repository
+	ledger *finance.Service
 }
 
 // NewService creates a new admin service wired to the auth repository.
@@ -17,6 +20,11 @@ func NewService(repo auth.Repository) *Service {
 	return &Service{repo: repo}
 }
 
+// SetLedger enables the finance ledger integrity endpoints.
+func (s *Service) SetLedger(ledger *finance.Service) {
+	s.ledger = ledger
+}
+
 func (s *Service) ListUsers(ctx context.Context) ([]*auth.User, error) {
 	users, _, err := s.repo.ListUsers(ctx, auth.ListUsersOptions{
 		Page:  1,
@@ -38,3 +46,10 @@ func (s *Service) UpdateUserRole(ctx context.Context, userID string, role auth.R
 	}
 	return user, nil
 }
+
+func (s *Service) CheckLedger(ctx context.Context, options finance.LedgerCheckOptions) (*finance.LedgerReport, error) {
+	if s.ledger == nil {
+		return nil, appErrors.InternalServerError
+	}
+	return s.ledger.CheckLedger(ctx, options)
+}
@@ -80,9 +80,9 @@ func (s *Service) Summary(ctx context.Context, date string) (*SummaryResponse, e
 	response.Counts.Transactions = transactionsCount
 
 	var income float64
-	_ = s.db.GetContext(ctx, &income, `SELECT COALESCE(SUM(converted_amount_to_base), 0) FROM transactions WHERE user_id = $1 AND date = $2 AND type = 'income' AND deleted_at IS NULL`, userID, date)
+	_ = s.db.GetContext(ctx, &income, `SELECT COALESCE(SUM(converted_amount_to_base), 0) FROM transactions WHERE user_id = $1 AND date = $2 AND type = 'income' AND status <> 'voided' AND NOT is_scheduled AND deleted_at IS NULL`, userID, date)
 	var expense float64
-	_ = s.db.GetContext(ctx, &expense, `SELECT COALESCE(SUM(converted_amount_to_base), 0) FROM transactions WHERE user_id = $1 AND date = $2 AND type = 'expense' AND deleted_at IS NULL`, userID, date)
+	_ = s.db.GetContext(ctx, &expense, `SELECT COALESCE(SUM(converted_amount_to_base), 0) FROM transactions WHERE user_id = $1 AND date = $2 AND type = 'expense' AND status <> 'voided' AND NOT is_scheduled AND deleted_at IS NULL`, userID, date)
 	response.Finance.Income = income
 	response.Finance.Expense = expense
 	response.Finance.Net = income - expense
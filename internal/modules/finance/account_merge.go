@@ -0,0 +1,240 @@
+package finance
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	appErrors "github.com/leora/leora-server/internal/errors"
+)
+
+// AccountMerge is the audit record of one account folded into another. Rate
+// converts the source currency into the target's; it is zero when they
+// match. The counts say how many rows were re-pointed.
+type AccountMerge struct {
+	ID                string  `json:"id"`
+	UserID            string  `json:"userId"`
+	SourceAccountID   string  `json:"sourceAccountId"`
+	TargetAccountID   string  `json:"targetAccountId"`
+	SourceCurrency    string  `json:"sourceCurrency"`
+	TargetCurrency    string  `json:"targetCurrency"`
+	Rate              float64 `json:"rate"`
+	SourceBalance     Money   `json:"sourceBalance"`
+	ConvertedBalance  Money   `json:"convertedBalance"`
+	TargetBalance     Money   `json:"targetBalance"`
+	TransactionsMoved int     `json:"transactionsMoved"`
+	RecurringMoved    int     `json:"recurringMoved"`
+	BudgetsMoved      int     `json:"budgetsMoved"`
+	DebtsMoved        int     `json:"debtsMoved"`
+	GoalMoved         bool    `json:"goalMoved"`
+	CreatedAt         string  `json:"createdAt,omitempty"`
+}
+
+type AccountMergeResult struct {
+	Account *Account      `json:"account"`
+	Merge   *AccountMerge `json:"merge"`
+}
+
+// MergeAccount folds a duplicate account into another one. Everything that
+// points at the source — transactions, recurring templates, budgets, debts,
+// debt payments, linked SMS cards and the linked goal — moves to the target,
+// amounts in the source currency are converted at today's rate, the target
+// balance is recomputed from its entries and the source is archived. The
+// repository does all of it in one step and records the merge. A source with
+// reconciliations or card statements is refused.
+func (s *Service) MergeAccount(ctx context.Context, sourceID, targetID string) (*AccountMergeResult, error) {
+	if sourceID == targetID {
+		return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "targetId"})
+	}
+	source, err := s.repo.GetAccountByID(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.repo.GetAccountByID(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	normalizeAccount(source)
+	normalizeAccount(target)
+	if isSubBalance(source) {
+		return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "accountId"})
+	}
+	if isSubBalance(target) || target.IsArchived {
+		return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "targetId"})
+	}
+	subs, err := s.subBalances(ctx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(subs) > 0 {
+		// Each sub-balance would need a target of its own currency.
+		return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "accountId"})
+	}
+	if err := s.ensureMergeableHistory(ctx, source.ID); err != nil {
+		return nil, err
+	}
+
+	merge := &AccountMerge{
+		SourceAccountID: source.ID,
+		TargetAccountID: target.ID,
+		SourceCurrency:  strings.ToUpper(source.Currency),
+		TargetCurrency:  strings.ToUpper(target.Currency),
+	}
+	var rate ExchangeRate
+	if merge.SourceCurrency != merge.TargetCurrency {
+		rate, err = s.resolveFXRate(ctx, merge.SourceCurrency, merge.TargetCurrency, time.Now().UTC().Format("2006-01-02"))
+		if err != nil || rate.IsZero() {
+			return nil, appErrors.FXRateNotFound
+		}
+		merge.Rate = rate.Float64()
+	}
+	if err := s.repo.MergeAccount(ctx, merge, rate); err != nil {
+		return nil, err
+	}
+	s.invalidateFinanceSummaryCache(ctx)
+	if !rate.IsZero() {
+		// Budgets whose spending moved with the converted entries.
+		s.syncAllBudgetRollups(ctx)
+	}
+
+	merged, err := s.GetAccount(ctx, target.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &AccountMergeResult{Account: merged, Merge: merge}, nil
+}
+
+// ensureMergeableHistory refuses a source whose reconciliations or card
+// statements would be left describing entries that moved away and, across
+// currencies, no longer add up to what they recorded.
+func (s *Service) ensureMergeableHistory(ctx context.Context, accountID string) error {
+	reconciliations, err := s.repo.ListReconciliations(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	for _, reconciliation := range reconciliations {
+		if reconciliation.Status != ReconciliationStatusCancelled {
+			return appErrors.WithDetails(appErrors.AccountMergeBlocked, map[string]interface{}{"reason": "reconciliations"})
+		}
+	}
+	statements, err := s.repo.ListCreditStatements(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	if len(statements) > 0 {
+		return appErrors.WithDetails(appErrors.AccountMergeBlocked, map[string]interface{}{"reason": "creditStatements"})
+	}
+	return nil
+}
+
+// moveTransactionToAccount re-points txn from the merged account to the
+// target and reports whether it touched the merged account. With a rate, the
+// amounts that were in the source currency are converted and the first
+// amount recorded is kept as the original.
+func moveTransactionToAccount(txn *Transaction, merge *AccountMerge, rate ExchangeRate) bool {
+	onAccount := txn.AccountID != nil && *txn.AccountID == merge.SourceAccountID
+	fromSide := txn.FromAccountID != nil && *txn.FromAccountID == merge.SourceAccountID
+	toSide := txn.ToAccountID != nil && *txn.ToAccountID == merge.SourceAccountID
+	if !onAccount && !fromSide && !toSide {
+		return false
+	}
+	if toSide && txn.Type == TransactionTypeTransfer && txn.ToAmount == 0 {
+		txn.ToAmount = txn.Amount
+	}
+	if !rate.IsZero() {
+		// The incoming leg of a transfer is booked in its own account's
+		// currency, whoever sent it.
+		if onAccount || (fromSide && txn.Type == TransactionTypeTransfer) {
+			if txn.OriginalCurrency == nil && txn.OriginalAmount == 0 {
+				currency := txn.Currency
+				txn.OriginalCurrency = &currency
+				txn.OriginalAmount = txn.Amount
+				txn.ConversionRate = rate.Float64()
+			}
+			converted := rate.Convert(txn.Amount, merge.TargetCurrency)
+			if len(txn.Splits) > 0 {
+				for i, amount := range splitAmounts(txn, converted) {
+					txn.Splits[i].Amount = amount
+				}
+			}
+			txn.Amount = converted
+			txn.Currency = merge.TargetCurrency
+		}
+		if toSide && txn.ToAmount != 0 {
+			currency := merge.TargetCurrency
+			txn.ToAmount = rate.Convert(txn.ToAmount, currency)
+			txn.ToCurrency = &currency
+		}
+		if isTransferLeg(txn.Type) && txn.Amount != 0 && txn.ToAmount != 0 {
+			txn.EffectiveRateFromTo = txn.ToAmount.Ratio(txn.Amount)
+		}
+	}
+	target := merge.TargetAccountID
+	if onAccount {
+		txn.AccountID = &target
+	}
+	if fromSide {
+		txn.FromAccountID = &target
+	}
+	if toSide {
+		txn.ToAccountID = &target
+	}
+	return true
+}
+
+// moveRecurringToAccount does for a recurring template what
+// moveTransactionToAccount does for an entry, so what it posts from now on
+// lands on the target in the target currency.
+func moveRecurringToAccount(item *RecurringTransaction, merge *AccountMerge, rate ExchangeRate) bool {
+	onAccount := item.AccountID != nil && *item.AccountID == merge.SourceAccountID
+	fromSide := item.FromAccountID != nil && *item.FromAccountID == merge.SourceAccountID
+	toSide := item.ToAccountID != nil && *item.ToAccountID == merge.SourceAccountID
+	if !onAccount && !fromSide && !toSide {
+		return false
+	}
+	if toSide && item.ToAmount == 0 {
+		item.ToAmount = item.Amount
+	}
+	if !rate.IsZero() {
+		if (onAccount || fromSide) && strings.EqualFold(item.Currency, merge.SourceCurrency) {
+			item.Amount = rate.Convert(item.Amount, merge.TargetCurrency)
+			item.Currency = merge.TargetCurrency
+		}
+		if toSide {
+			currency := merge.TargetCurrency
+			item.ToAmount = rate.Convert(item.ToAmount, currency)
+			item.ToCurrency = &currency
+		}
+	}
+	target := merge.TargetAccountID
+	if onAccount {
+		item.AccountID = &target
+	}
+	if fromSide {
+		item.FromAccountID = &target
+	}
+	if toSide {
+		item.ToAccountID = &target
+	}
+	return true
+}
+
+// moveDebtToAccount re-points every account reference of a debt and reports
+// whether there was one.
+func moveDebtToAccount(debt *Debt, merge *AccountMerge) bool {
+	moved := false
+	for _, field := range []**string{
+		&debt.FundingAccountID,
+		&debt.LentFromAccountID,
+		&debt.ReturnToAccountID,
+		&debt.ReceivedToAccountID,
+		&debt.PayFromAccountID,
+	} {
+		if *field != nil && **field == merge.SourceAccountID {
+			target := merge.TargetAccountID
+			*field = &target
+			moved = true
+		}
+	}
+	return moved
+}
@@ -0,0 +1,357 @@
+package finance
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	appErrors "github.com/leora/leora-server/internal/errors"
+	"github.com/leora/leora-server/internal/storage"
+)
+
+const (
+	defaultAttachmentMaxFileBytes = 10 << 20
+	defaultAttachmentQuotaBytes   = 500 << 20
+	defaultAttachmentURLTTL       = 15 * time.Minute
+	maxAttachmentsPerEntry        = 10
+)
+
+// attachmentExtensions lists the accepted file types by the MIME type sniffed
+// from their content; the client's Content-Type header is not trusted.
+var attachmentExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// Attachment is an uploaded receipt or document. Transactions, debts and debt
+// payments reference attachments by id. URL and ThumbnailURL are signed,
+// expiring links generated on every read.
+type Attachment struct {
+	ID            string  `json:"id"`
+	UserID        string  `json:"userId"`
+	FileName      string  `json:"fileName"`
+	ContentType   string  `json:"contentType"`
+	Size          int64   `json:"size"`
+	Checksum      string  `json:"checksum"`
+	Width         *int    `json:"width,omitempty"`
+	Height        *int    `json:"height,omitempty"`
+	StorageKey    string  `json:"-"`
+	ThumbnailKey  *string `json:"-"`
+	ThumbnailSize int64   `json:"-"`
+	URL           string  `json:"url,omitempty"`
+	ThumbnailURL  string  `json:"thumbnailUrl,omitempty"`
+	URLExpiresAt  string  `json:"urlExpiresAt,omitempty"`
+	CreatedAt     string  `json:"createdAt,omitempty"`
+}
+
+// AttachmentUpload is one uploaded file as read from the request.
+type AttachmentUpload struct {
+	FileName string
+	Data     []byte
+}
+
+// AttachmentUsage reports a user's storage against the quota. Thumbnails
+// count towards UsedBytes.
+type AttachmentUsage struct {
+	Count      int   `json:"count"`
+	UsedBytes  int64 `json:"usedBytes"`
+	QuotaBytes int64 `json:"quotaBytes"`
+}
+
+// AttachmentOptions bounds uploads. Zero values use the defaults.
+type AttachmentOptions struct {
+	MaxFileBytes int64
+	QuotaBytes   int64
+	URLTTL       time.Duration
+}
+
+// SetAttachmentStorage enables uploads. Without a store, upload requests
+// fail and stored attachments cannot be downloaded.
+func (s *Service) SetAttachmentStorage(store storage.BlobStore, options AttachmentOptions) {
+	if options.MaxFileBytes <= 0 {
+		options.MaxFileBytes = defaultAttachmentMaxFileBytes
+	}
+	if options.QuotaBytes <= 0 {
+		options.QuotaBytes = defaultAttachmentQuotaBytes
+	}
+	if options.URLTTL <= 0 {
+		options.URLTTL = defaultAttachmentURLTTL
+	}
+	s.blobs = store
+	s.attachmentOptions = options
+}
+
+// AttachmentMaxFileBytes is the largest file an upload may carry.
+func (s *Service) AttachmentMaxFileBytes() int64 {
+	if s.attachmentOptions.MaxFileBytes <= 0 {
+		return defaultAttachmentMaxFileBytes
+	}
+	return s.attachmentOptions.MaxFileBytes
+}
+
+// UploadAttachment validates and stores a file, with a thumbnail for images
+// the server can decode.
+func (s *Service) UploadAttachment(ctx context.Context, upload AttachmentUpload) (*Attachment, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+	if s.blobs == nil {
+		log.Printf("[Service.UploadAttachment] no attachment storage configured")
+		return nil, appErrors.InternalServerError
+	}
+	size := int64(len(upload.Data))
+	if size == 0 || size > s.AttachmentMaxFileBytes() {
+		return nil, appErrors.WithDetails(appErrors.InvalidAttachment, map[string]interface{}{
+			"field":        "file",
+			"maxFileBytes": s.AttachmentMaxFileBytes(),
+		})
+	}
+	contentType := http.DetectContentType(upload.Data)
+	extension, allowed := attachmentExtensions[contentType]
+	if !allowed {
+		return nil, appErrors.WithDetails(appErrors.InvalidAttachment, map[string]interface{}{
+			"field":       "file",
+			"contentType": contentType,
+		})
+	}
+	usage, err := s.AttachmentUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if usage.UsedBytes+size > usage.QuotaBytes {
+		return nil, appErrors.WithDetails(appErrors.AttachmentQuotaExceeded, map[string]interface{}{
+			"usedBytes":  usage.UsedBytes,
+			"quotaBytes": usage.QuotaBytes,
+		})
+	}
+
+	sum := sha256.Sum256(upload.Data)
+	attachment := &Attachment{
+		ID:          uuid.NewString(),
+		FileName:    attachmentFileName(upload.FileName, extension),
+		ContentType: contentType,
+		Size:        size,
+		Checksum:    hex.EncodeToString(sum[:]),
+	}
+	attachment.StorageKey = "attachments/" + userID + "/" + attachment.ID + extension
+	if err := s.blobs.Put(ctx, attachment.StorageKey, upload.Data, contentType); err != nil {
+		log.Printf("[Service.UploadAttachment] store %s: %v", attachment.StorageKey, err)
+		return nil, appErrors.InternalServerError
+	}
+	if strings.HasPrefix(contentType, "image/") {
+		thumb, width, height, ok := makeThumbnail(upload.Data)
+		if width > 0 && height > 0 {
+			attachment.Width, attachment.Height = &width, &height
+		}
+		if ok {
+			thumbKey := "attachments/" + userID + "/" + attachment.ID + "_thumb.jpg"
+			if err := s.blobs.Put(ctx, thumbKey, thumb, "image/jpeg"); err != nil {
+				log.Printf("[Service.UploadAttachment] store thumbnail %s: %v", thumbKey, err)
+			} else {
+				attachment.ThumbnailKey = &thumbKey
+				attachment.ThumbnailSize = int64(len(thumb))
+			}
+		}
+	}
+	if err := s.repo.CreateAttachment(ctx, attachment); err != nil {
+		s.deleteAttachmentBlobs(ctx, attachment)
+		return nil, err
+	}
+	s.signAttachment(ctx, attachment)
+	return attachment, nil
+}
+
+func (s *Service) Attachments(ctx context.Context) ([]*Attachment, error) {
+	attachments, err := s.repo.ListAttachments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, attachment := range attachments {
+		s.signAttachment(ctx, attachment)
+	}
+	return attachments, nil
+}
+
+func (s *Service) GetAttachment(ctx context.Context, id string) (*Attachment, error) {
+	attachment, err := s.repo.GetAttachmentByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.signAttachment(ctx, attachment)
+	return attachment, nil
+}
+
+func (s *Service) AttachmentUsage(ctx context.Context) (*AttachmentUsage, error) {
+	count, used, err := s.repo.AttachmentUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	quota := s.attachmentOptions.QuotaBytes
+	if quota <= 0 {
+		quota = defaultAttachmentQuotaBytes
+	}
+	return &AttachmentUsage{Count: count, UsedBytes: used, QuotaBytes: quota}, nil
+}
+
+// DeleteAttachment removes an attachment nothing references any more.
+// Receipts on posted (or voided) transactions stay with the ledger.
+func (s *Service) DeleteAttachment(ctx context.Context, id string) error {
+	attachment, err := s.repo.GetAttachmentByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	referenced, err := s.repo.AttachmentReferenced(ctx, id)
+	if err != nil {
+		return err
+	}
+	if referenced {
+		return appErrors.AttachmentInUse
+	}
+	if err := s.repo.DeleteAttachment(ctx, id); err != nil {
+		return err
+	}
+	s.deleteAttachmentBlobs(ctx, attachment)
+	return nil
+}
+
+// AttachToTransaction adds an attachment to a posted transaction. Only the
+// attachment list changes; the entry itself stays immutable.
+func (s *Service) AttachToTransaction(ctx context.Context, transactionID, attachmentID string) (*Transaction, error) {
+	txn, err := s.repo.GetTransactionByID(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.GetAttachmentByID(ctx, attachmentID); err != nil {
+		return nil, err
+	}
+	for _, existing := range txn.Attachments {
+		if existing == attachmentID {
+			return txn, nil
+		}
+	}
+	if len(txn.Attachments) >= maxAttachmentsPerEntry {
+		return nil, appErrors.WithDetails(appErrors.InvalidAttachment, map[string]interface{}{"field": "attachments"})
+	}
+	txn.Attachments = append(txn.Attachments, attachmentID)
+	if err := s.repo.UpdateTransactionAttachments(ctx, txn.ID, txn.Attachments); err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
+
+func (s *Service) DetachFromTransaction(ctx context.Context, transactionID, attachmentID string) (*Transaction, error) {
+	txn, err := s.repo.GetTransactionByID(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	kept := make([]string, 0, len(txn.Attachments))
+	for _, existing := range txn.Attachments {
+		if existing != attachmentID {
+			kept = append(kept, existing)
+		}
+	}
+	if len(kept) == len(txn.Attachments) {
+		return nil, appErrors.AttachmentNotFound
+	}
+	txn.Attachments = kept
+	if err := s.repo.UpdateTransactionAttachments(ctx, txn.ID, txn.Attachments); err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
+
+// validateAttachmentIDs checks that every referenced attachment belongs to
+// the user and drops blanks and repeats.
+func (s *Service) validateAttachmentIDs(ctx context.Context, ids []string) ([]string, error) {
+	result := make([]string, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		if _, err := s.repo.GetAttachmentByID(ctx, id); err != nil {
+			if err == appErrors.AttachmentNotFound {
+				return nil, appErrors.WithDetails(appErrors.AttachmentNotFound, map[string]interface{}{"field": "attachments", "id": id})
+			}
+			return nil, err
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	if len(result) > maxAttachmentsPerEntry {
+		return nil, appErrors.WithDetails(appErrors.InvalidAttachment, map[string]interface{}{"field": "attachments"})
+	}
+	return result, nil
+}
+
+func (s *Service) validateDebtAttachments(ctx context.Context, debt *Debt) error {
+	attachments, err := s.validateAttachmentIDs(ctx, debt.Attachments)
+	if err != nil {
+		return err
+	}
+	debt.Attachments = attachments
+	return nil
+}
+
+// signAttachment fills the download links. A store error leaves them empty
+// rather than failing the read.
+func (s *Service) signAttachment(ctx context.Context, attachment *Attachment) {
+	if s.blobs == nil {
+		return
+	}
+	ttl := s.attachmentOptions.URLTTL
+	if ttl <= 0 {
+		ttl = defaultAttachmentURLTTL
+	}
+	signed, err := s.blobs.SignedURL(ctx, attachment.StorageKey, ttl)
+	if err != nil {
+		log.Printf("[Service.signAttachment] attachment=%s: %v", attachment.ID, err)
+		return
+	}
+	attachment.URL = signed
+	if attachment.ThumbnailKey != nil {
+		if thumb, err := s.blobs.SignedURL(ctx, *attachment.ThumbnailKey, ttl); err == nil {
+			attachment.ThumbnailURL = thumb
+		}
+	}
+	attachment.URLExpiresAt = time.Now().UTC().Add(ttl).Format(time.RFC3339)
+}
+
+func (s *Service) deleteAttachmentBlobs(ctx context.Context, attachment *Attachment) {
+	if s.blobs == nil {
+		return
+	}
+	keys := []string{attachment.StorageKey}
+	if attachment.ThumbnailKey != nil {
+		keys = append(keys, *attachment.ThumbnailKey)
+	}
+	for _, key := range keys {
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			log.Printf("[Service.deleteAttachmentBlobs] key=%s: %v", key, err)
+		}
+	}
+}
+
+// attachmentFileName keeps the client's base name, falling back to a generic
+// one with the sniffed extension.
+func attachmentFileName(name, extension string) string {
+	name = strings.TrimSpace(path.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return "attachment" + extension
+	}
+	if len(name) > 255 {
+		name = name[len(name)-255:]
+	}
+	return name
+}
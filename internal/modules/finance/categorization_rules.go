@@ -0,0 +1,481 @@
+package finance
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	appErrors "github.com/leora/leora-server/internal/errors"
+)
+
+const (
+	CategorizationRuleActive = "active"
+	CategorizationRulePaused = "paused"
+)
+
+// defaultCategorizationRulePageLimit is how many matches a dry run or
+// re-apply lists when the caller does not ask for a page size.
+const defaultCategorizationRulePageLimit = 20
+
+const (
+	RuleTextFieldAny         = "any"
+	RuleTextFieldName        = "name"
+	RuleTextFieldDescription = "description"
+)
+
+// CategorizationRule classifies new income and expense entries. Rules run in
+// ascending Priority; each matching rule only fills fields that are still
+// empty, so an earlier rule (or the user) always wins. StopOnMatch ends the
+// evaluation after the rule matched. Paused rules are skipped.
+type CategorizationRule struct {
+	ID          string                       `json:"id"`
+	UserID      string                       `json:"userId"`
+	Name        string                       `json:"name"`
+	Priority    int                          `json:"priority"`
+	Status      string                       `json:"status"`
+	StopOnMatch bool                         `json:"stopOnMatch"`
+	Conditions  CategorizationRuleConditions `json:"conditions"`
+	Actions     CategorizationRuleActions    `json:"actions"`
+	CreatedAt   string                       `json:"createdAt,omitempty"`
+	UpdatedAt   string                       `json:"updatedAt,omitempty"`
+}
+
+// CategorizationRuleConditions must all hold for a rule to match. Text and
+// TextRegex are case-insensitive and look at TextField; Merchant matches the
+// merchant read from a bank SMS.
+type CategorizationRuleConditions struct {
+	Type           string  `json:"type,omitempty"`
+	Text           string  `json:"text,omitempty"`
+	TextRegex      string  `json:"textRegex,omitempty"`
+	TextField      string  `json:"textField,omitempty"`
+	AmountMin      *Money  `json:"amountMin,omitempty"`
+	AmountMax      *Money  `json:"amountMax,omitempty"`
+	AccountID      *string `json:"accountId,omitempty"`
+	CounterpartyID *string `json:"counterpartyId,omitempty"`
+	Merchant       string  `json:"merchant,omitempty"`
+}
+
+type CategorizationRuleActions struct {
+	CategoryID     *string  `json:"categoryId,omitempty"`
+	SubcategoryID  *string  `json:"subcategoryId,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	BudgetID       *string  `json:"budgetId,omitempty"`
+	CounterpartyID *string  `json:"counterpartyId,omitempty"`
+}
+
+// CategorizationRuleMatch is a past transaction a rule matches and the fields
+// it would change. Skipped entries are locked by a completed reconciliation
+// and keep their classification.
+type CategorizationRuleMatch struct {
+	Transaction *Transaction           `json:"transaction"`
+	Changes     map[string]interface{} `json:"changes"`
+	Skipped     bool                   `json:"skipped,omitempty"`
+}
+
+// CategorizationRuleResult reports a dry run or a re-apply. Matched counts
+// every matching transaction, Changed those whose fields differ and Skipped
+// those that would differ but are locked. Items holds one page of matches.
+type CategorizationRuleResult struct {
+	Matched int                       `json:"matched"`
+	Changed int                       `json:"changed"`
+	Skipped int                       `json:"skipped"`
+	Applied bool                      `json:"applied"`
+	Items   []CategorizationRuleMatch `json:"items"`
+}
+
+// compiledRule is a rule with its regex compiled once per evaluation.
+type compiledRule struct {
+	rule  *CategorizationRule
+	regex *regexp.Regexp
+}
+
+func (s *Service) CategorizationRules(ctx context.Context) ([]*CategorizationRule, error) {
+	rules, err := s.repo.ListCategorizationRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sortCategorizationRules(rules)
+	return rules, nil
+}
+
+func (s *Service) GetCategorizationRule(ctx context.Context, id string) (*CategorizationRule, error) {
+	return s.repo.GetCategorizationRuleByID(ctx, id)
+}
+
+func (s *Service) CreateCategorizationRule(ctx context.Context, rule *CategorizationRule) (*CategorizationRule, error) {
+	if err := s.validateCategorizationRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateCategorizationRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *Service) UpdateCategorizationRule(ctx context.Context, id string, rule *CategorizationRule) (*CategorizationRule, error) {
+	current, err := s.repo.GetCategorizationRuleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	rule.ID = current.ID
+	rule.UserID = current.UserID
+	rule.CreatedAt = current.CreatedAt
+	if err := s.validateCategorizationRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateCategorizationRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *Service) DeleteCategorizationRule(ctx context.Context, id string) error {
+	return s.repo.DeleteCategorizationRule(ctx, id)
+}
+
+// DryRunCategorizationRule lists the past transactions rule would match,
+// without saving the rule or touching the transactions.
+func (s *Service) DryRunCategorizationRule(ctx context.Context, rule *CategorizationRule, overwrite bool, page, limit int) (*CategorizationRuleResult, error) {
+	if err := s.validateCategorizationRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	return s.runCategorizationRule(ctx, rule, overwrite, false, page, limit)
+}
+
+// ApplyCategorizationRule re-applies a saved rule to history. Only the
+// classification changes (category, subcategory, tags, budget and
+// counterparty); amounts, accounts and dates stay immutable. Without
+// overwrite, fields that are already set are kept. All changes are stored
+// together; reconciled entries are skipped.
+func (s *Service) ApplyCategorizationRule(ctx context.Context, id string, overwrite bool, page, limit int) (*CategorizationRuleResult, error) {
+	rule, err := s.repo.GetCategorizationRuleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.runCategorizationRule(ctx, rule, overwrite, true, page, limit)
+}
+
+func (s *Service) runCategorizationRule(ctx context.Context, rule *CategorizationRule, overwrite, apply bool, page, limit int) (*CategorizationRuleResult, error) {
+	compiled, err := compileCategorizationRule(rule)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := s.repo.ListTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactionSortTime(transactions[i]).After(transactionSortTime(transactions[j]))
+	})
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultCategorizationRulePageLimit
+	}
+	first := (page - 1) * limit
+
+	result := &CategorizationRuleResult{Applied: apply, Items: make([]CategorizationRuleMatch, 0, limit)}
+	updates := make([]*Transaction, 0)
+	originals := make(map[string]*Transaction)
+	for _, txn := range transactions {
+		if txn.Status == TransactionStatusVoided || !categorizableTransaction(txn) || !compiled.matches(txn) {
+			continue
+		}
+		result.Matched++
+		updated := cloneTransaction(txn)
+		changes := applyCategorizationActions(updated, rule.Actions, overwrite)
+		locked := len(changes) > 0 && txn.ReconciliationID != nil
+		if index := result.Matched - 1; index >= first && index < first+limit {
+			result.Items = append(result.Items, CategorizationRuleMatch{Transaction: txn, Changes: changes, Skipped: locked})
+		}
+		switch {
+		case len(changes) == 0:
+		case locked:
+			result.Skipped++
+		default:
+			result.Changed++
+			updates = append(updates, updated)
+			originals[txn.ID] = txn
+		}
+	}
+	if !apply || len(updates) == 0 {
+		return result, nil
+	}
+
+	// Entries reconciled since they were listed come back as skipped.
+	skipped, err := s.repo.ReclassifyTransactions(ctx, updates)
+	if err != nil {
+		return nil, err
+	}
+	skippedIDs := make(map[string]bool, len(skipped))
+	for _, id := range skipped {
+		skippedIDs[id] = true
+	}
+	result.Changed -= len(skipped)
+	result.Skipped += len(skipped)
+	for i := range result.Items {
+		if skippedIDs[result.Items[i].Transaction.ID] {
+			result.Items[i].Skipped = true
+		}
+	}
+
+	budgets := make(map[string]bool)
+	for _, updated := range updates {
+		if skippedIDs[updated.ID] {
+			continue
+		}
+		for _, budgetID := range []*string{originals[updated.ID].BudgetID, updated.BudgetID} {
+			if budgetID != nil && *budgetID != "" {
+				budgets[*budgetID] = true
+			}
+		}
+	}
+	for budgetID := range budgets {
+		if _, err := s.RecalculateBudget(ctx, budgetID); err != nil {
+			log.Printf("[Service.ApplyCategorizationRule] recalculate budget=%s: %v", budgetID, err)
+		}
+	}
+	if result.Changed > 0 {
+		s.invalidateFinanceSummaryCache(ctx)
+	}
+	return result, nil
+}
+
+// applyCategorizationRules runs the user's active rules over a new entry.
+// Rule failures never block posting.
+func (s *Service) applyCategorizationRules(ctx context.Context, txn *Transaction) {
+	if !categorizableTransaction(txn) {
+		return
+	}
+	applyCompiledRules(s.activeCategorizationRules(ctx), txn)
+}
+
+// activeCategorizationRules loads the user's active rules in evaluation
+// order, skipping any that no longer compile.
+func (s *Service) activeCategorizationRules(ctx context.Context) []*compiledRule {
+	rules, err := s.repo.ListCategorizationRules(ctx)
+	if err != nil {
+		log.Printf("[Service.activeCategorizationRules] list rules: %v", err)
+		return nil
+	}
+	sortCategorizationRules(rules)
+	active := make([]*compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Status != CategorizationRuleActive {
+			continue
+		}
+		if compiled, err := compileCategorizationRule(rule); err == nil {
+			active = append(active, compiled)
+		}
+	}
+	return active
+}
+
+func applyCompiledRules(rules []*compiledRule, txn *Transaction) {
+	if !categorizableTransaction(txn) {
+		return
+	}
+	applied := make([]string, 0)
+	for _, compiled := range rules {
+		if !compiled.matches(txn) {
+			continue
+		}
+		applyCategorizationActions(txn, compiled.rule.Actions, false)
+		applied = append(applied, compiled.rule.ID)
+		if compiled.rule.StopOnMatch {
+			break
+		}
+	}
+	if len(applied) > 0 {
+		if txn.Metadata == nil {
+			txn.Metadata = map[string]interface{}{}
+		}
+		txn.Metadata["appliedRuleIds"] = applied
+	}
+}
+
+func (s *Service) validateCategorizationRule(ctx context.Context, rule *CategorizationRule) error {
+	invalid := func(field string) error {
+		return appErrors.WithDetails(appErrors.InvalidCategorizationRule, map[string]interface{}{"field": field})
+	}
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return invalid("name")
+	}
+	switch rule.Status {
+	case "":
+		rule.Status = CategorizationRuleActive
+	case CategorizationRuleActive, CategorizationRulePaused:
+	default:
+		return invalid("status")
+	}
+	conditions := &rule.Conditions
+	conditions.Text = strings.TrimSpace(conditions.Text)
+	conditions.Merchant = strings.TrimSpace(conditions.Merchant)
+	switch conditions.Type {
+	case "", TransactionTypeIncome, TransactionTypeExpense:
+	default:
+		return invalid("conditions.type")
+	}
+	switch conditions.TextField {
+	case "":
+		conditions.TextField = RuleTextFieldAny
+	case RuleTextFieldAny, RuleTextFieldName, RuleTextFieldDescription:
+	default:
+		return invalid("conditions.textField")
+	}
+	if conditions.TextRegex != "" {
+		if _, err := regexp.Compile("(?i)" + conditions.TextRegex); err != nil {
+			return invalid("conditions.textRegex")
+		}
+	}
+	if conditions.AmountMin != nil && conditions.AmountMax != nil && *conditions.AmountMin > *conditions.AmountMax {
+		return invalid("conditions.amountMax")
+	}
+	if conditions.Text == "" && conditions.TextRegex == "" && conditions.AmountMin == nil && conditions.AmountMax == nil &&
+		conditions.AccountID == nil && conditions.CounterpartyID == nil && conditions.Merchant == "" {
+		return invalid("conditions")
+	}
+	actions := &rule.Actions
+	actions.Tags = normalizeFilterTags(actions.Tags)
+	if actions.CategoryID == nil && actions.SubcategoryID == nil && len(actions.Tags) == 0 &&
+		actions.BudgetID == nil && actions.CounterpartyID == nil {
+		return invalid("actions")
+	}
+
+	if conditions.AccountID != nil {
+		if _, err := s.repo.GetAccountByID(ctx, *conditions.AccountID); err != nil {
+			return err
+		}
+	}
+	for _, counterpartyID := range []*string{conditions.CounterpartyID, actions.CounterpartyID} {
+		if counterpartyID != nil {
+			if _, err := s.repo.GetCounterpartyByID(ctx, *counterpartyID); err != nil {
+				return err
+			}
+		}
+	}
+	if actions.BudgetID != nil {
+		if _, err := s.repo.GetBudgetByID(ctx, *actions.BudgetID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func compileCategorizationRule(rule *CategorizationRule) (*compiledRule, error) {
+	compiled := &compiledRule{rule: rule}
+	if rule.Conditions.TextRegex != "" {
+		regex, err := regexp.Compile("(?i)" + rule.Conditions.TextRegex)
+		if err != nil {
+			return nil, appErrors.WithDetails(appErrors.InvalidCategorizationRule, map[string]interface{}{"field": "conditions.textRegex"})
+		}
+		compiled.regex = regex
+	}
+	return compiled, nil
+}
+
+func (c *compiledRule) matches(txn *Transaction) bool {
+	conditions := c.rule.Conditions
+	if conditions.Type != "" && txn.Type != conditions.Type {
+		return false
+	}
+	if conditions.AmountMin != nil && txn.Amount < *conditions.AmountMin {
+		return false
+	}
+	if conditions.AmountMax != nil && txn.Amount > *conditions.AmountMax {
+		return false
+	}
+	if conditions.AccountID != nil && (txn.AccountID == nil || *txn.AccountID != *conditions.AccountID) {
+		return false
+	}
+	if conditions.CounterpartyID != nil && (txn.CounterpartyID == nil || *txn.CounterpartyID != *conditions.CounterpartyID) {
+		return false
+	}
+	if conditions.Merchant != "" {
+		merchant, _ := txn.Metadata["merchant"].(string)
+		if !strings.Contains(strings.ToLower(merchant), strings.ToLower(conditions.Merchant)) {
+			return false
+		}
+	}
+	if conditions.Text == "" && c.regex == nil {
+		return true
+	}
+	for _, text := range ruleTextValues(txn, conditions.TextField) {
+		if conditions.Text != "" && !strings.Contains(strings.ToLower(text), strings.ToLower(conditions.Text)) {
+			continue
+		}
+		if c.regex != nil && !c.regex.MatchString(text) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func ruleTextValues(txn *Transaction, field string) []string {
+	values := make([]string, 0, 2)
+	if field != RuleTextFieldDescription && txn.Name != nil {
+		values = append(values, *txn.Name)
+	}
+	if field != RuleTextFieldName && txn.Description != nil {
+		values = append(values, *txn.Description)
+	}
+	return values
+}
+
+// applyCategorizationActions sets the rule's fields on txn and returns what
+// changed. Tags are added, never removed.
+func applyCategorizationActions(txn *Transaction, actions CategorizationRuleActions, overwrite bool) map[string]interface{} {
+	changes := make(map[string]interface{})
+	setField := func(name string, target **string, value *string) {
+		if value == nil || (*target != nil && **target != "" && !overwrite) {
+			return
+		}
+		if *target != nil && **target == *value {
+			return
+		}
+		next := *value
+		*target = &next
+		changes[name] = next
+	}
+	setField("categoryId", &txn.CategoryID, actions.CategoryID)
+	setField("subcategoryId", &txn.SubcategoryID, actions.SubcategoryID)
+	setField("budgetId", &txn.BudgetID, actions.BudgetID)
+	setField("counterpartyId", &txn.CounterpartyID, actions.CounterpartyID)
+
+	added := make([]string, 0)
+	for _, tag := range actions.Tags {
+		if !transactionHasTags(txn, []string{tag}, true) {
+			txn.Tags = append(txn.Tags, tag)
+			added = append(added, tag)
+		}
+	}
+	if len(added) > 0 {
+		changes["tags"] = added
+	}
+	return changes
+}
+
+// categorizableTransaction limits rules to user income and expense entries;
+// debt, budget and system entries keep the classification their flow gives
+// them, and split entries are classified line by line by the user.
+func categorizableTransaction(txn *Transaction) bool {
+	if txn.Type != TransactionTypeIncome && txn.Type != TransactionTypeExpense {
+		return false
+	}
+	return !txn.IsBalanceAdjustment && txn.DebtID == nil && len(txn.Splits) == 0
+}
+
+func sortCategorizationRules(rules []*CategorizationRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority < rules[j].Priority
+		}
+		return rules[i].CreatedAt < rules[j].CreatedAt
+	})
+}
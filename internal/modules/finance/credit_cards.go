@@ -0,0 +1,472 @@
+package finance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	appErrors "github.com/leora/leora-server/internal/errors"
+	"github.com/leora/leora-server/internal/modules/notifications"
+)
+
+const AccountTypeCredit = "credit"
+
+const (
+	CreditStatementDue         = "due"
+	CreditStatementMinimumPaid = "minimum_paid"
+	CreditStatementPaid        = "paid"
+	CreditStatementOverdue     = "overdue"
+)
+
+// creditReminderLeadDays is how long before the due date an unpaid statement
+// gets its reminder.
+const creditReminderLeadDays = 3
+
+// creditStatementCatchUp bounds how many missed cycles one run closes for a
+// card, about two years of monthly statements.
+const creditStatementCatchUp = 24
+
+// CreditTerms turns a credit account into a card with a limit and a monthly
+// statement cycle. A closing or due day past the end of a short month falls
+// on its last day. The minimum payment is MinimumPaymentPercent of the
+// statement balance but at least MinimumPaymentAmount, and never more than
+// the balance itself. AvailableCredit is computed on read.
+type CreditTerms struct {
+	CreditLimit           Money   `json:"creditLimit"`
+	StatementClosingDay   int     `json:"statementClosingDay"`
+	PaymentDueDay         int     `json:"paymentDueDay"`
+	MinimumPaymentPercent float64 `json:"minimumPaymentPercent"`
+	MinimumPaymentAmount  Money   `json:"minimumPaymentAmount"`
+	AvailableCredit       Money   `json:"availableCredit"`
+}
+
+// CreditStatement is one closed cycle of a card. Amounts are what the card
+// owes, so positive: OpeningBalance is the amount owed the day before
+// PeriodStart and StatementBalance the amount owed at the end of PeriodEnd.
+// Charges and Credits are what was spent on and paid into the card in
+// between.
+//
+// PaidAmount, RemainingAmount and Status are computed on read from the
+// payments into the card after PeriodEnd, up to the next statement's close.
+type CreditStatement struct {
+	ID                string  `json:"id"`
+	UserID            string  `json:"userId"`
+	AccountID         string  `json:"accountId"`
+	Currency          string  `json:"currency"`
+	PeriodStart       string  `json:"periodStart"`
+	PeriodEnd         string  `json:"periodEnd"`
+	DueDate           string  `json:"dueDate"`
+	OpeningBalance    Money   `json:"openingBalance"`
+	Charges           Money   `json:"charges"`
+	Credits           Money   `json:"credits"`
+	StatementBalance  Money   `json:"statementBalance"`
+	MinimumPayment    Money   `json:"minimumPayment"`
+	CreditLimit       Money   `json:"creditLimit"`
+	PaidAmount        Money   `json:"paidAmount"`
+	RemainingAmount   Money   `json:"remainingAmount"`
+	Status            string  `json:"status"`
+	ReminderSentAt    *string `json:"reminderSentAt,omitempty"`
+	OverdueNotifiedAt *string `json:"overdueNotifiedAt,omitempty"`
+	CreatedAt         string  `json:"createdAt,omitempty"`
+	UpdatedAt         string  `json:"updatedAt,omitempty"`
+}
+
+// CreditPaymentInput pays a statement from another account. Amount defaults
+// to what is still owed on the statement and Date to today.
+type CreditPaymentInput struct {
+	FromAccountID string `json:"fromAccountId"`
+	Amount        *Money `json:"amount"`
+	Date          string `json:"date"`
+}
+
+type CreditPayment struct {
+	Statement   *CreditStatement `json:"statement"`
+	Transaction *Transaction     `json:"transaction"`
+}
+
+func isCreditAccountType(accountType string) bool {
+	return strings.EqualFold(strings.TrimSpace(accountType), AccountTypeCredit)
+}
+
+// availableFunds is what an account can still spend: its balance plus, for a
+// card with terms, the credit limit.
+func availableFunds(account *Account) Money {
+	if account.Credit == nil {
+		return account.CurrentBalance
+	}
+	return account.CurrentBalance + account.Credit.CreditLimit
+}
+
+// validateCreditTerms drops terms from accounts that are not credit accounts
+// and checks them on the ones that are. A credit account without terms keeps
+// behaving like a plain account.
+func validateCreditTerms(account *Account) error {
+	if !isCreditAccountType(account.AccountType) {
+		account.Credit = nil
+		return nil
+	}
+	terms := account.Credit
+	if terms == nil {
+		return nil
+	}
+	field := ""
+	switch {
+	case terms.CreditLimit <= 0:
+		field = "credit.creditLimit"
+	case terms.StatementClosingDay < 1 || terms.StatementClosingDay > 31:
+		field = "credit.statementClosingDay"
+	case terms.PaymentDueDay < 1 || terms.PaymentDueDay > 31:
+		field = "credit.paymentDueDay"
+	case terms.MinimumPaymentPercent < 0 || terms.MinimumPaymentPercent > 100:
+		field = "credit.minimumPaymentPercent"
+	case terms.MinimumPaymentAmount < 0:
+		field = "credit.minimumPaymentAmount"
+	}
+	if field != "" {
+		return appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": field})
+	}
+	return nil
+}
+
+func applyCreditTermsPatch(terms *CreditTerms, fields map[string]interface{}) *CreditTerms {
+	patched := CreditTerms{}
+	if terms != nil {
+		patched = *terms
+	}
+	if v, ok := fields["creditLimit"].(float64); ok {
+		patched.CreditLimit = MoneyFromFloat(v)
+	}
+	if v, ok := fields["statementClosingDay"].(float64); ok {
+		patched.StatementClosingDay = int(v)
+	}
+	if v, ok := fields["paymentDueDay"].(float64); ok {
+		patched.PaymentDueDay = int(v)
+	}
+	if v, ok := fields["minimumPaymentPercent"].(float64); ok {
+		patched.MinimumPaymentPercent = v
+	}
+	if v, ok := fields["minimumPaymentAmount"].(float64); ok {
+		patched.MinimumPaymentAmount = MoneyFromFloat(v)
+	}
+	return &patched
+}
+
+// CreditStatements lists a card's statements, newest first.
+func (s *Service) CreditStatements(ctx context.Context, accountID string) ([]*CreditStatement, error) {
+	return s.creditStatements(ctx, accountID, time.Now().UTC().Format("2006-01-02"))
+}
+
+func (s *Service) creditStatements(ctx context.Context, accountID, today string) ([]*CreditStatement, error) {
+	if _, err := s.repo.GetAccountByID(ctx, accountID); err != nil {
+		return nil, err
+	}
+	statements, err := s.repo.ListCreditStatements(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := s.repo.ListTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applyCreditStatementPayments(statements, transactions, today)
+	return statements, nil
+}
+
+// PayCreditStatement transfers money from another account into the card to
+// settle a statement. A payment dated in the future is scheduled like any
+// other transfer and counts once it posts.
+func (s *Service) PayCreditStatement(ctx context.Context, accountID, statementID string, input CreditPaymentInput) (*CreditPayment, error) {
+	statements, err := s.CreditStatements(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	statement := findCreditStatement(statements, statementID)
+	if statement == nil {
+		return nil, appErrors.CreditStatementNotFound
+	}
+	fromAccountID := strings.TrimSpace(input.FromAccountID)
+	if fromAccountID == "" || fromAccountID == accountID {
+		return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "fromAccountId"})
+	}
+	amount := statement.RemainingAmount
+	if input.Amount != nil {
+		amount = *input.Amount
+	}
+	if amount <= 0 {
+		return nil, appErrors.WithDetails(appErrors.InvalidAmount, map[string]interface{}{"field": "amount"})
+	}
+	date := normalizeDateInput(strings.TrimSpace(input.Date))
+	if date == "" {
+		date = time.Now().UTC().Format("2006-01-02")
+	}
+	toAccountID := accountID
+	name := "Card payment"
+	txn, err := s.CreateTransaction(ctx, &Transaction{
+		Type:          TransactionTypeTransfer,
+		FromAccountID: &fromAccountID,
+		ToAccountID:   &toAccountID,
+		Amount:        amount,
+		Name:          &name,
+		Date:          date,
+		Metadata:      map[string]interface{}{"creditStatementId": statement.ID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	statements, err = s.CreditStatements(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return &CreditPayment{Statement: findCreditStatement(statements, statementID), Transaction: txn}, nil
+}
+
+// GenerateCreditStatements closes every finished cycle of every card across
+// all users, then sends the due-date reminder and the overdue notice for the
+// latest statement, each once. A card that fails is logged and skipped.
+func (s *Service) GenerateCreditStatements(ctx context.Context, asOf time.Time) (int, error) {
+	accounts, err := s.repo.ListCreditAccounts(ctx)
+	if err != nil {
+		return 0, err
+	}
+	today := asOf.UTC().Format("2006-01-02")
+	generated := 0
+	for _, account := range accounts {
+		userCtx := context.WithValue(ctx, "user_id", account.UserID)
+		count, err := s.closeCreditCycles(userCtx, account, today)
+		generated += count
+		if err != nil {
+			log.Printf("[Service.GenerateCreditStatements] Failed for account=%s: %v", account.ID, err)
+			continue
+		}
+		s.sendCreditReminders(userCtx, account, today)
+	}
+	return generated, nil
+}
+
+// closeCreditCycles writes a statement for each cycle that ended before
+// today, starting after the last statement or, for a new card, from its
+// creation or first entry, whichever is earlier.
+func (s *Service) closeCreditCycles(ctx context.Context, account *Account, today string) (int, error) {
+	statements, err := s.repo.ListCreditStatements(ctx, account.ID)
+	if err != nil {
+		return 0, err
+	}
+	transactions, err := s.repo.ListTransactions(ctx)
+	if err != nil {
+		return 0, err
+	}
+	start := dateOnly(account.CreatedAt)
+	if len(statements) > 0 {
+		start = statements[0].PeriodEnd
+	} else {
+		for _, txn := range transactions {
+			if date := dateOnly(txn.Date); date != "" && (start == "" || date < start) && transactionDeltaForAccount(account.ID, txn) != 0 {
+				start = date
+			}
+		}
+	}
+	cycleStart, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		return 0, fmt.Errorf("credit cycle start %q: %w", start, err)
+	}
+	if len(statements) > 0 {
+		cycleStart = cycleStart.AddDate(0, 0, 1)
+	}
+	created := 0
+	for i := 0; i < creditStatementCatchUp; i++ {
+		closing := creditCycleClose(cycleStart, account.Credit.StatementClosingDay)
+		if closing.Format("2006-01-02") >= today {
+			break
+		}
+		statement := buildCreditStatement(account, transactions, cycleStart, closing)
+		inserted, err := s.repo.CreateCreditStatement(ctx, statement)
+		if err != nil {
+			return created, err
+		}
+		if inserted {
+			created++
+		}
+		cycleStart = closing.AddDate(0, 0, 1)
+	}
+	return created, nil
+}
+
+// buildCreditStatement replays the card's ledger up to the closing date.
+// Reversals and voided entries stay in so that the opening balance plus
+// charges less credits always comes to the closing balance.
+func buildCreditStatement(account *Account, transactions []*Transaction, start, closing time.Time) *CreditStatement {
+	startDate := start.Format("2006-01-02")
+	closeDate := closing.Format("2006-01-02")
+	opening := account.InitialBalance
+	balance := account.InitialBalance
+	charges, credits := Money(0), Money(0)
+	for _, txn := range transactions {
+		delta := transactionDeltaForAccount(account.ID, txn)
+		date := dateOnly(txn.Date)
+		if delta == 0 || date > closeDate {
+			continue
+		}
+		balance += delta
+		if date < startDate {
+			opening += delta
+		} else if delta < 0 {
+			charges -= delta
+		} else {
+			credits += delta
+		}
+	}
+	terms := account.Credit
+	owed := max(-balance, 0)
+	minimum := max(owed.MulFloat(terms.MinimumPaymentPercent/100).RoundUpTo(account.Currency), terms.MinimumPaymentAmount)
+	return &CreditStatement{
+		UserID:           account.UserID,
+		AccountID:        account.ID,
+		Currency:         account.Currency,
+		PeriodStart:      startDate,
+		PeriodEnd:        closeDate,
+		DueDate:          creditDueDate(closing, terms.PaymentDueDay).Format("2006-01-02"),
+		OpeningBalance:   max(-opening, 0),
+		Charges:          charges,
+		Credits:          credits,
+		StatementBalance: owed,
+		MinimumPayment:   min(minimum, owed),
+		CreditLimit:      terms.CreditLimit,
+	}
+}
+
+// applyCreditStatementPayments credits each statement with what was
+// transferred into the card after it closed and until the next one did; the
+// latest statement takes every later payment. Statements are newest first.
+// Refunds and other income on the card are not payments. A reversed payment
+// counts against the statement it had paid.
+func applyCreditStatementPayments(statements []*CreditStatement, transactions []*Transaction, today string) {
+	byID := make(map[string]*Transaction, len(transactions))
+	for _, txn := range transactions {
+		byID[txn.ID] = txn
+	}
+	for i, statement := range statements {
+		isPayment := func(txn *Transaction) bool {
+			if txn.Type == TransactionTypeReversal && txn.ReferenceID != nil {
+				txn = byID[*txn.ReferenceID]
+			}
+			if txn == nil {
+				return false
+			}
+			return txn.Type == TransactionTypeTransferIn ||
+				(txn.Type == TransactionTypeTransfer && txn.ToAccountID != nil && *txn.ToAccountID == statement.AccountID)
+		}
+		until := ""
+		if i > 0 {
+			until = statements[i-1].PeriodEnd
+		}
+		paid := Money(0)
+		for _, txn := range transactions {
+			delta := transactionDeltaForAccount(statement.AccountID, txn)
+			date := dateOnly(txn.Date)
+			if delta == 0 || date <= statement.PeriodEnd || (until != "" && date > until) {
+				continue
+			}
+			if isPayment(txn) {
+				paid += delta
+			}
+		}
+		statement.PaidAmount = min(max(paid, 0), statement.StatementBalance)
+		statement.RemainingAmount = statement.StatementBalance - statement.PaidAmount
+		switch {
+		case statement.RemainingAmount == 0:
+			statement.Status = CreditStatementPaid
+		case statement.PaidAmount >= statement.MinimumPayment:
+			statement.Status = CreditStatementMinimumPaid
+		case today > statement.DueDate:
+			statement.Status = CreditStatementOverdue
+		default:
+			statement.Status = CreditStatementDue
+		}
+	}
+}
+
+func findCreditStatement(statements []*CreditStatement, id string) *CreditStatement {
+	for _, statement := range statements {
+		if statement.ID == id {
+			return statement
+		}
+	}
+	return nil
+}
+
+// creditCycleClose is the first closing date on or after day.
+func creditCycleClose(day time.Time, closingDay int) time.Time {
+	closing := clampedMonthDay(day.Year(), day.Month(), closingDay)
+	if closing.Before(day) {
+		closing = clampedMonthDay(day.Year(), day.Month()+1, closingDay)
+	}
+	return closing
+}
+
+// creditDueDate is the first due day after the statement closes.
+func creditDueDate(closing time.Time, dueDay int) time.Time {
+	due := clampedMonthDay(closing.Year(), closing.Month(), dueDay)
+	if !due.After(closing) {
+		due = clampedMonthDay(closing.Year(), closing.Month()+1, dueDay)
+	}
+	return due
+}
+
+func clampedMonthDay(year int, month time.Month, day int) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return time.Date(year, month, min(day, last), 0, 0, 0, 0, time.UTC)
+}
+
+// sendCreditReminders looks at the latest statement only: older ones roll
+// into it.
+func (s *Service) sendCreditReminders(ctx context.Context, account *Account, today string) {
+	if s.notifications == nil {
+		return
+	}
+	statements, err := s.creditStatements(ctx, account.ID, today)
+	if err != nil || len(statements) == 0 {
+		return
+	}
+	statement := statements[0]
+	now := time.Now().UTC().Format(time.RFC3339)
+	switch statement.Status {
+	case CreditStatementDue:
+		due, err := time.Parse("2006-01-02", statement.DueDate)
+		if err != nil || statement.ReminderSentAt != nil ||
+			today < due.AddDate(0, 0, -creditReminderLeadDays).Format("2006-01-02") {
+			return
+		}
+		statement.ReminderSentAt = &now
+	case CreditStatementOverdue:
+		if statement.OverdueNotifiedAt != nil {
+			return
+		}
+		statement.OverdueNotifiedAt = &now
+	default:
+		return
+	}
+	if err := s.repo.UpdateCreditStatement(ctx, statement); err != nil {
+		log.Printf("[Service.sendCreditReminders] Failed to record reminder for statement=%s: %v", statement.ID, err)
+		return
+	}
+	if _, err := s.notifications.Create(ctx, buildCreditStatementNotification(account, statement)); err != nil {
+		log.Printf("[Service.sendCreditReminders] Failed to notify statement=%s: %v", statement.ID, err)
+	}
+}
+
+func buildCreditStatementNotification(account *Account, statement *CreditStatement) *notifications.Notification {
+	remaining := formatAmountForCurrency(statement.RemainingAmount, statement.Currency)
+	minimum := formatAmountForCurrency(max(statement.MinimumPayment-statement.PaidAmount, 0), statement.Currency)
+	if statement.Status == CreditStatementOverdue {
+		return &notifications.Notification{
+			Title:   fmt.Sprintf("%s payment is overdue", account.Name),
+			Message: fmt.Sprintf("The minimum payment of %s was due on %s; %s is still owed on the statement.", minimum, statement.DueDate, remaining),
+		}
+	}
+	return &notifications.Notification{
+		Title:   fmt.Sprintf("%s payment due %s", account.Name, statement.DueDate),
+		Message: fmt.Sprintf("Statement balance %s, minimum payment %s.", remaining, minimum),
+	}
+}
@@ -0,0 +1,180 @@
+package finance
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DebtInterestNone            = "none"
+	DebtInterestSimple          = "simple"
+	DebtInterestCompoundMonthly = "compound_monthly"
+	DebtInterestCompoundDaily   = "compound_daily"
+)
+
+// DebtInterest is the interest breakdown returned with GET /debts/:id.
+type DebtInterest struct {
+	Mode                 string                `json:"mode"`
+	RateAnnual           float64               `json:"rateAnnual"`
+	AsOf                 string                `json:"asOf"`
+	TotalAccrued         Money                 `json:"totalAccrued"`
+	Paid                 Money                 `json:"paid"`
+	Outstanding          Money                 `json:"outstanding"`
+	PrincipalOutstanding Money                 `json:"principalOutstanding"`
+	Segments             []DebtInterestSegment `json:"segments"`
+}
+
+// DebtInterestSegment covers the days between two events (start, payments,
+// asOf). A segment that ends with a payment shows how it was split between
+// interest and principal.
+type DebtInterestSegment struct {
+	From             string  `json:"from"`
+	To               string  `json:"to"`
+	Days             int     `json:"days"`
+	OpeningPrincipal Money   `json:"openingPrincipal"`
+	InterestAccrued  Money   `json:"interestAccrued"`
+	PaymentID        *string `json:"paymentId,omitempty"`
+	PaymentAmount    Money   `json:"paymentAmount"`
+	InterestPaid     Money   `json:"interestPaid"`
+	PrincipalPaid    Money   `json:"principalPaid"`
+	ClosingPrincipal Money   `json:"closingPrincipal"`
+	ClosingInterest  Money   `json:"closingInterest"`
+}
+
+// normalizeDebtInterestMode maps stored values onto the supported modes. The
+// legacy "compound" value compounds monthly.
+func normalizeDebtInterestMode(mode *string) string {
+	if mode == nil {
+		return DebtInterestNone
+	}
+	switch strings.ToLower(strings.TrimSpace(*mode)) {
+	case DebtInterestSimple:
+		return DebtInterestSimple
+	case "compound", DebtInterestCompoundMonthly:
+		return DebtInterestCompoundMonthly
+	case DebtInterestCompoundDaily:
+		return DebtInterestCompoundDaily
+	default:
+		return DebtInterestNone
+	}
+}
+
+func isValidDebtInterestMode(mode string) bool {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", DebtInterestNone, DebtInterestSimple, "compound", DebtInterestCompoundMonthly, DebtInterestCompoundDaily:
+		return true
+	}
+	return false
+}
+
+// accrueDebtInterest returns the interest earned over days. Simple interest
+// is charged on the principal only; compound modes also charge interest on
+// unpaid interest. InterestRateAnnual is a percentage.
+func accrueDebtInterest(mode string, rateAnnual float64, principal, unpaidInterest Money, days int) Money {
+	if days <= 0 || rateAnnual <= 0 {
+		return 0
+	}
+	rate := rateAnnual / 100
+	switch mode {
+	case DebtInterestSimple:
+		return principal.MulFloat(rate * float64(days) / 365)
+	case DebtInterestCompoundMonthly:
+		months := float64(days) * 12 / 365
+		return (principal + unpaidInterest).MulFloat(math.Pow(1+rate/12, months) - 1)
+	case DebtInterestCompoundDaily:
+		return (principal + unpaidInterest).MulFloat(math.Pow(1+rate/365, float64(days)) - 1)
+	default:
+		return 0
+	}
+}
+
+// computeDebtInterest replays the debt's payments in date order up to asOf,
+// applying each payment to outstanding interest before principal.
+func computeDebtInterest(debt *Debt, payments []*DebtPayment, asOf time.Time) *DebtInterest {
+	mode := normalizeDebtInterestMode(debt.InterestMode)
+	asOfDay := time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, time.UTC)
+	result := &DebtInterest{
+		Mode:       mode,
+		RateAnnual: debt.InterestRateAnnual,
+		AsOf:       asOfDay.Format("2006-01-02"),
+		Segments:   []DebtInterestSegment{},
+	}
+
+	ordered := make([]*DebtPayment, 0, len(payments))
+	for _, payment := range payments {
+		if payment != nil && payment.DebtID == debt.ID {
+			ordered = append(ordered, payment)
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].PaymentDate != ordered[j].PaymentDate {
+			return ordered[i].PaymentDate < ordered[j].PaymentDate
+		}
+		return ordered[i].CreatedAt < ordered[j].CreatedAt
+	})
+
+	cursor, err := time.Parse("2006-01-02", debt.StartDate)
+	if err != nil {
+		cursor = asOfDay
+	}
+	principal := debt.PrincipalAmount
+	unpaid := Money(0)
+
+	addSegment := func(to time.Time, payment *DebtPayment) {
+		days := 0
+		if to.After(cursor) {
+			days = int(to.Sub(cursor).Hours() / 24)
+		}
+		accrued := accrueDebtInterest(mode, debt.InterestRateAnnual, principal, unpaid, days)
+		segment := DebtInterestSegment{
+			From:             cursor.Format("2006-01-02"),
+			To:               to.Format("2006-01-02"),
+			Days:             days,
+			OpeningPrincipal: principal,
+			InterestAccrued:  accrued,
+		}
+		unpaid += accrued
+		result.TotalAccrued += accrued
+		if payment != nil {
+			amount := payment.ConvertedAmountToDebt
+			interestPart := min(amount, unpaid)
+			principalPart := amount - interestPart
+			unpaid -= interestPart
+			principal -= principalPart
+			result.Paid += interestPart
+			paymentID := payment.ID
+			segment.PaymentID = &paymentID
+			segment.PaymentAmount = amount
+			segment.InterestPaid = interestPart
+			segment.PrincipalPaid = principalPart
+		}
+		segment.ClosingPrincipal = principal
+		segment.ClosingInterest = unpaid
+		result.Segments = append(result.Segments, segment)
+		if to.After(cursor) {
+			cursor = to
+		}
+	}
+
+	for _, payment := range ordered {
+		paidOn, err := time.Parse("2006-01-02", payment.PaymentDate)
+		if err != nil {
+			paidOn = cursor
+		}
+		if paidOn.After(asOfDay) {
+			break
+		}
+		addSegment(paidOn, payment)
+	}
+	if asOfDay.After(cursor) && principal > 0 {
+		addSegment(asOfDay, nil)
+	}
+
+	result.TotalAccrued = result.TotalAccrued.RoundTo(debt.PrincipalCurrency)
+	result.Paid = result.Paid.RoundTo(debt.PrincipalCurrency)
+	result.Outstanding = max(unpaid, 0).RoundTo(debt.PrincipalCurrency)
+	result.PrincipalOutstanding = principal.RoundTo(debt.PrincipalCurrency)
+	return result
+}
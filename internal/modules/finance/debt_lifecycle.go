@@ -0,0 +1,208 @@
+package finance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/leora/leora-server/internal/modules/notifications"
+)
+
+const (
+	DebtStatusActive   = "active"
+	DebtStatusDueSoon  = "due_soon"
+	DebtStatusOverdue  = "overdue"
+	DebtStatusPaid     = "paid"
+	DebtStatusCanceled = "canceled"
+)
+
+// debtDueSoonDays is how many days before its due date a debt becomes due_soon.
+const debtDueSoonDays = 3
+
+// defaultDebtReminderTime is used when a debt has reminders enabled but no
+// valid reminderTime.
+const defaultDebtReminderTime = "09:00"
+
+// DebtStatusEvent records a status change made by the due-date evaluator.
+type DebtStatusEvent struct {
+	ID         string `json:"id"`
+	DebtID     string `json:"debtId"`
+	UserID     string `json:"userId"`
+	FromStatus string `json:"fromStatus"`
+	ToStatus   string `json:"toStatus"`
+	DueDate    string `json:"dueDate"`
+	OccurredAt string `json:"occurredAt"`
+}
+
+// DebtReminder records a reminder notification sent for a debt on a given day.
+type DebtReminder struct {
+	ID           string `json:"id"`
+	DebtID       string `json:"debtId"`
+	UserID       string `json:"userId"`
+	ReminderDate string `json:"reminderDate"`
+	Status       string `json:"status"`
+	CreatedAt    string `json:"createdAt,omitempty"`
+}
+
+// debtLifecycleStatus returns the status an open debt should have on asOfDate
+// (YYYY-MM-DD). Paid and canceled debts, and debts without a due date, keep
+// their lifecycle out of the evaluator's hands.
+func debtLifecycleStatus(debt *Debt, asOfDate string) string {
+	switch debt.Status {
+	case DebtStatusPaid, DebtStatusCanceled:
+		return debt.Status
+	}
+	if debt.DueDate == nil || strings.TrimSpace(*debt.DueDate) == "" {
+		return DebtStatusActive
+	}
+	dueDate := strings.TrimSpace(*debt.DueDate)
+	if len(dueDate) > 10 {
+		dueDate = dueDate[:10]
+	}
+	asOf, err := time.Parse("2006-01-02", asOfDate)
+	if err != nil {
+		return debt.Status
+	}
+	switch {
+	case dueDate < asOfDate:
+		return DebtStatusOverdue
+	case dueDate <= asOf.AddDate(0, 0, debtDueSoonDays).Format("2006-01-02"):
+		return DebtStatusDueSoon
+	default:
+		return DebtStatusActive
+	}
+}
+
+// debtReminderDue reports whether now has reached the debt's reminder time of
+// day. Reminder times are HH:MM in UTC.
+func debtReminderDue(debt *Debt, now time.Time) bool {
+	if !debt.ReminderEnabled {
+		return false
+	}
+	if debt.Status != DebtStatusDueSoon && debt.Status != DebtStatusOverdue {
+		return false
+	}
+	raw := defaultDebtReminderTime
+	if debt.ReminderTime != nil && strings.TrimSpace(*debt.ReminderTime) != "" {
+		raw = strings.TrimSpace(*debt.ReminderTime)
+	}
+	at, err := time.Parse("15:04", raw)
+	if err != nil {
+		if at, err = time.Parse("15:04:05", raw); err != nil {
+			at, _ = time.Parse("15:04", defaultDebtReminderTime)
+		}
+	}
+	now = now.UTC()
+	return now.Hour()*60+now.Minute() >= at.Hour()*60+at.Minute()
+}
+
+// EvaluateDebtDueDates moves open debts between active, due_soon and overdue
+// as of now, records each change, and sends at most one reminder per debt per
+// day once the reminder time has passed. It returns the number of status
+// changes.
+func (s *Service) EvaluateDebtDueDates(ctx context.Context, now time.Time) (int, error) {
+	now = now.UTC()
+	asOfDate := now.Format("2006-01-02")
+	debts, err := s.repo.ListDebtsForDueEvaluation(ctx)
+	if err != nil {
+		return 0, err
+	}
+	changed := 0
+	for _, debt := range debts {
+		userCtx := context.WithValue(ctx, "user_id", debt.UserID)
+		next := debtLifecycleStatus(debt, asOfDate)
+		if next != debt.Status {
+			event := &DebtStatusEvent{
+				DebtID:     debt.ID,
+				FromStatus: debt.Status,
+				ToStatus:   next,
+				OccurredAt: now.Format(time.RFC3339),
+			}
+			if debt.DueDate != nil {
+				event.DueDate = *debt.DueDate
+			}
+			applied, err := s.repo.TransitionDebtStatus(userCtx, event)
+			if err != nil {
+				log.Printf("[Service.EvaluateDebtDueDates] Failed to move debt=%s to %s: %v", debt.ID, next, err)
+				continue
+			}
+			if !applied {
+				continue
+			}
+			debt.Status = next
+			changed++
+			s.invalidateFinanceSummaryCache(userCtx)
+		}
+		s.sendDebtReminder(userCtx, debt, now)
+	}
+	return changed, nil
+}
+
+// sendDebtReminder notifies the user about a due-soon or overdue debt.
+// Failures are logged; the reminder is retried on the next run only if it
+// could not be recorded.
+func (s *Service) sendDebtReminder(ctx context.Context, debt *Debt, now time.Time) {
+	if s.notifications == nil || !debtReminderDue(debt, now) {
+		return
+	}
+	reminder := &DebtReminder{
+		DebtID:       debt.ID,
+		ReminderDate: now.Format("2006-01-02"),
+		Status:       debt.Status,
+	}
+	inserted, err := s.repo.RecordDebtReminder(ctx, reminder)
+	if err != nil {
+		log.Printf("[Service.sendDebtReminder] Failed to record reminder for debt=%s: %v", debt.ID, err)
+		return
+	}
+	if !inserted {
+		return
+	}
+	if _, err := s.notifications.Create(ctx, buildDebtReminderNotification(debt)); err != nil {
+		log.Printf("[Service.sendDebtReminder] Failed to notify debt=%s: %v", debt.ID, err)
+	}
+}
+
+func buildDebtReminderNotification(debt *Debt) *notifications.Notification {
+	dueDate := ""
+	if debt.DueDate != nil {
+		dueDate = *debt.DueDate
+	}
+	amount := formatAmountForCurrency(debt.RemainingAmount, debt.PrincipalCurrency)
+	if debt.Direction == "they_owe_me" {
+		if debt.Status == DebtStatusOverdue {
+			return &notifications.Notification{
+				Title:   fmt.Sprintf("%s is overdue", debt.CounterpartyName),
+				Message: fmt.Sprintf("%s owes you %s, due %s.", debt.CounterpartyName, amount, dueDate),
+			}
+		}
+		return &notifications.Notification{
+			Title:   fmt.Sprintf("%s is due soon", debt.CounterpartyName),
+			Message: fmt.Sprintf("%s owes you %s, due %s.", debt.CounterpartyName, amount, dueDate),
+		}
+	}
+	if debt.Status == DebtStatusOverdue {
+		return &notifications.Notification{
+			Title:   fmt.Sprintf("Debt to %s is overdue", debt.CounterpartyName),
+			Message: fmt.Sprintf("You owe %s %s, due %s.", debt.CounterpartyName, amount, dueDate),
+		}
+	}
+	return &notifications.Notification{
+		Title:   fmt.Sprintf("Debt to %s is due soon", debt.CounterpartyName),
+		Message: fmt.Sprintf("You owe %s %s, due %s.", debt.CounterpartyName, amount, dueDate),
+	}
+}
+
+// describeDebtStatusEvent is the summary feed label for a recorded transition.
+func describeDebtStatusEvent(event *DebtStatusEvent) string {
+	switch event.ToStatus {
+	case DebtStatusOverdue:
+		return fmt.Sprintf("Overdue since %s", event.DueDate)
+	case DebtStatusDueSoon:
+		return fmt.Sprintf("Due %s", event.DueDate)
+	default:
+		return "Active"
+	}
+}
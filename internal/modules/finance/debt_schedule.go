@@ -0,0 +1,320 @@
+package finance
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	appErrors "github.com/leora/leora-server/internal/errors"
+)
+
+const (
+	DebtScheduleEqual   = "equal"
+	DebtScheduleAnnuity = "annuity"
+	DebtScheduleCustom  = "custom"
+)
+
+const (
+	DebtInstallmentPending = "pending"
+	DebtInstallmentPartial = "partial"
+	DebtInstallmentPaid    = "paid"
+	DebtInstallmentOverdue = "overdue"
+)
+
+// maxDebtInstallments bounds generated plans (20 years of monthly payments).
+const maxDebtInstallments = 240
+
+// DebtInstallment is one planned repayment. PaidAmount, Status, PaidAt and
+// PaymentIDs are derived by matching the debt's payments against the plan.
+type DebtInstallment struct {
+	ID              string   `json:"id"`
+	DebtID          string   `json:"debtId"`
+	UserID          string   `json:"userId"`
+	Sequence        int      `json:"sequence"`
+	PlanType        string   `json:"planType"`
+	DueDate         string   `json:"dueDate"`
+	Amount          Money    `json:"amount"`
+	PrincipalAmount Money    `json:"principalAmount"`
+	InterestAmount  Money    `json:"interestAmount"`
+	PaidAmount      Money    `json:"paidAmount"`
+	Status          string   `json:"status"`
+	PaidAt          *string  `json:"paidAt,omitempty"`
+	PaymentIDs      []string `json:"paymentIds"`
+	CreatedAt       string   `json:"createdAt,omitempty"`
+	UpdatedAt       string   `json:"updatedAt,omitempty"`
+}
+
+// DebtSchedule is returned by GET /debts/:id/schedule.
+type DebtSchedule struct {
+	DebtID       string             `json:"debtId"`
+	PlanType     string             `json:"planType,omitempty"`
+	Currency     string             `json:"currency"`
+	AsOf         string             `json:"asOf"`
+	TotalPlanned Money              `json:"totalPlanned"`
+	TotalPaid    Money              `json:"totalPaid"`
+	TotalOverdue Money              `json:"totalOverdue"`
+	NextDueDate  *string            `json:"nextDueDate,omitempty"`
+	Installments []*DebtInstallment `json:"installments"`
+}
+
+// DebtScheduleInput describes the plan to generate. Equal and annuity plans
+// use Count, Frequency and FirstDueDate; custom plans list their installments,
+// whose principal parts must add up to the debt's principal.
+type DebtScheduleInput struct {
+	PlanType     string                  `json:"planType"`
+	Count        int                     `json:"count"`
+	Frequency    string                  `json:"frequency"`
+	FirstDueDate string                  `json:"firstDueDate"`
+	Installments []DebtScheduleInputItem `json:"installments"`
+}
+
+// DebtScheduleInputItem is one custom installment. InterestAmount is the
+// part of Amount that is interest or markup rather than principal.
+type DebtScheduleInputItem struct {
+	DueDate        string `json:"dueDate"`
+	Amount         Money  `json:"amount"`
+	InterestAmount Money  `json:"interestAmount"`
+}
+
+// DebtSchedule returns the debt's installments with payment matching applied
+// as of asOf.
+func (s *Service) DebtSchedule(ctx context.Context, debtID string, asOf time.Time) (*DebtSchedule, error) {
+	debt, err := s.repo.GetDebtByID(ctx, debtID)
+	if err != nil {
+		return nil, err
+	}
+	installments, err := s.repo.ListDebtInstallments(ctx, debtID)
+	if err != nil {
+		return nil, err
+	}
+	payments, err := s.repo.ListDebtPayments(ctx, debtID)
+	if err != nil {
+		return nil, err
+	}
+	return buildDebtSchedule(debt, installments, payments, asOf), nil
+}
+
+// SetDebtSchedule replaces the debt's installment plan.
+func (s *Service) SetDebtSchedule(ctx context.Context, debtID string, input DebtScheduleInput) (*DebtSchedule, error) {
+	debt, err := s.repo.GetDebtByID(ctx, debtID)
+	if err != nil {
+		return nil, err
+	}
+	normalizeDebt(debt)
+	installments, err := generateDebtInstallments(debt, input)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceDebtInstallments(ctx, debtID, installments); err != nil {
+		return nil, err
+	}
+	return s.DebtSchedule(ctx, debtID, time.Now().UTC())
+}
+
+// ClearDebtSchedule removes the installment plan, leaving the debt with its
+// single due date.
+func (s *Service) ClearDebtSchedule(ctx context.Context, debtID string) error {
+	if _, err := s.repo.GetDebtByID(ctx, debtID); err != nil {
+		return err
+	}
+	return s.repo.ReplaceDebtInstallments(ctx, debtID, nil)
+}
+
+func generateDebtInstallments(debt *Debt, input DebtScheduleInput) ([]*DebtInstallment, error) {
+	planType := strings.ToLower(strings.TrimSpace(input.PlanType))
+	currency := debt.PrincipalCurrency
+	if planType == DebtScheduleCustom {
+		return customDebtInstallments(debt, input.Installments)
+	}
+	if planType != DebtScheduleEqual && planType != DebtScheduleAnnuity {
+		return nil, appErrors.WithDetails(appErrors.InvalidDebtSchedule, map[string]interface{}{"field": "planType"})
+	}
+	if input.Count <= 0 || input.Count > maxDebtInstallments {
+		return nil, appErrors.WithDetails(appErrors.InvalidDebtSchedule, map[string]interface{}{"field": "count"})
+	}
+	frequency := strings.ToLower(strings.TrimSpace(input.Frequency))
+	if frequency == "" {
+		frequency = RecurrenceFrequencyMonthly
+	}
+	if frequency != RecurrenceFrequencyMonthly && frequency != RecurrenceFrequencyWeekly {
+		return nil, appErrors.WithDetails(appErrors.InvalidDebtSchedule, map[string]interface{}{"field": "frequency"})
+	}
+	first, err := time.Parse("2006-01-02", normalizeDateInput(input.FirstDueDate))
+	if strings.TrimSpace(input.FirstDueDate) == "" || err != nil {
+		return nil, appErrors.WithDetails(appErrors.InvalidDebtSchedule, map[string]interface{}{"field": "firstDueDate"})
+	}
+	principal := debt.PrincipalAmount
+	if principal <= 0 {
+		return nil, appErrors.InvalidDebtAmount
+	}
+
+	periodsPerYear := 12.0
+	if frequency == RecurrenceFrequencyWeekly {
+		periodsPerYear = 52
+	}
+	rate := 0.0
+	if planType == DebtScheduleAnnuity {
+		rate = debt.InterestRateAnnual / 100 / periodsPerYear
+	}
+
+	installments := make([]*DebtInstallment, 0, input.Count)
+	balance := principal
+	payment := principal.DivRound(int64(input.Count), currency)
+	if rate > 0 {
+		payment = principal.MulFloat(rate / (1 - math.Pow(1+rate, -float64(input.Count)))).RoundTo(currency)
+	}
+	for i := 0; i < input.Count; i++ {
+		dueDate := first.AddDate(0, 0, 7*i)
+		if frequency == RecurrenceFrequencyMonthly {
+			dueDate = dateInMonth(first.Year(), first.Month()+time.Month(i), first.Day())
+		}
+		interest := balance.MulFloat(rate).RoundTo(currency)
+		principalPart := payment - interest
+		if i == input.Count-1 || principalPart > balance {
+			principalPart = balance
+		}
+		balance -= principalPart
+		installments = append(installments, &DebtInstallment{
+			Sequence:        i + 1,
+			PlanType:        planType,
+			DueDate:         dueDate.Format("2006-01-02"),
+			Amount:          principalPart + interest,
+			PrincipalAmount: principalPart,
+			InterestAmount:  interest,
+		})
+	}
+	return installments, nil
+}
+
+// customDebtInstallments orders the listed installments by due date. Their
+// principal parts must repay exactly the debt's principal; anything above it
+// has to be declared as interest.
+func customDebtInstallments(debt *Debt, items []DebtScheduleInputItem) ([]*DebtInstallment, error) {
+	if len(items) == 0 || len(items) > maxDebtInstallments {
+		return nil, appErrors.WithDetails(appErrors.InvalidDebtSchedule, map[string]interface{}{"field": "installments"})
+	}
+	if debt.PrincipalAmount <= 0 {
+		return nil, appErrors.InvalidDebtAmount
+	}
+	sorted := make([]DebtScheduleInputItem, len(items))
+	copy(sorted, items)
+	var principal Money
+	for i := range sorted {
+		sorted[i].DueDate = normalizeDateInput(sorted[i].DueDate)
+		if _, err := time.Parse("2006-01-02", sorted[i].DueDate); err != nil {
+			return nil, appErrors.WithDetails(appErrors.InvalidDebtSchedule, map[string]interface{}{"field": "installments.dueDate"})
+		}
+		if sorted[i].Amount <= 0 {
+			return nil, appErrors.WithDetails(appErrors.InvalidDebtSchedule, map[string]interface{}{"field": "installments.amount"})
+		}
+		if sorted[i].InterestAmount < 0 || sorted[i].InterestAmount > sorted[i].Amount {
+			return nil, appErrors.WithDetails(appErrors.InvalidDebtSchedule, map[string]interface{}{"field": "installments.interestAmount"})
+		}
+		principal += sorted[i].Amount - sorted[i].InterestAmount
+	}
+	if principal != debt.PrincipalAmount {
+		return nil, appErrors.WithDetails(appErrors.InvalidDebtSchedule, map[string]interface{}{
+			"field":     "installments",
+			"principal": debt.PrincipalAmount,
+			"planned":   principal,
+		})
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].DueDate < sorted[j].DueDate
+	})
+	installments := make([]*DebtInstallment, 0, len(sorted))
+	for i, item := range sorted {
+		installments = append(installments, &DebtInstallment{
+			Sequence:        i + 1,
+			PlanType:        DebtScheduleCustom,
+			DueDate:         item.DueDate,
+			Amount:          item.Amount,
+			PrincipalAmount: item.Amount - item.InterestAmount,
+			InterestAmount:  item.InterestAmount,
+		})
+	}
+	return installments, nil
+}
+
+// buildDebtSchedule matches payments to installments oldest-first: each
+// payment (in debt currency) fills the earliest unpaid installment and any
+// remainder rolls into the next one.
+func buildDebtSchedule(debt *Debt, installments []*DebtInstallment, payments []*DebtPayment, asOf time.Time) *DebtSchedule {
+	asOfDate := asOf.UTC().Format("2006-01-02")
+	schedule := &DebtSchedule{
+		DebtID:       debt.ID,
+		Currency:     debt.PrincipalCurrency,
+		AsOf:         asOfDate,
+		Installments: installments,
+	}
+	sort.SliceStable(installments, func(i, j int) bool {
+		return installments[i].Sequence < installments[j].Sequence
+	})
+	for _, installment := range installments {
+		installment.PaidAmount = 0
+		installment.PaidAt = nil
+		installment.PaymentIDs = []string{}
+		schedule.TotalPlanned += installment.Amount
+		if schedule.PlanType == "" {
+			schedule.PlanType = installment.PlanType
+		}
+	}
+
+	ordered := make([]*DebtPayment, 0, len(payments))
+	for _, payment := range payments {
+		if payment != nil && payment.DebtID == debt.ID && payment.PaymentDate <= asOfDate {
+			ordered = append(ordered, payment)
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].PaymentDate != ordered[j].PaymentDate {
+			return ordered[i].PaymentDate < ordered[j].PaymentDate
+		}
+		return ordered[i].CreatedAt < ordered[j].CreatedAt
+	})
+
+	index := 0
+	for _, payment := range ordered {
+		left := payment.ConvertedAmountToDebt
+		for left > 0 && index < len(installments) {
+			installment := installments[index]
+			applied := min(left, installment.Amount-installment.PaidAmount)
+			installment.PaidAmount += applied
+			installment.PaymentIDs = append(installment.PaymentIDs, payment.ID)
+			left -= applied
+			if installment.PaidAmount >= installment.Amount {
+				paidAt := payment.PaymentDate
+				installment.PaidAt = &paidAt
+				index++
+			}
+		}
+	}
+
+	for _, installment := range installments {
+		installment.Status = debtInstallmentStatus(installment, asOfDate)
+		schedule.TotalPaid += installment.PaidAmount
+		if installment.Status == DebtInstallmentOverdue {
+			schedule.TotalOverdue += installment.Amount - installment.PaidAmount
+		}
+		if schedule.NextDueDate == nil && installment.Status != DebtInstallmentPaid {
+			dueDate := installment.DueDate
+			schedule.NextDueDate = &dueDate
+		}
+	}
+	return schedule
+}
+
+func debtInstallmentStatus(installment *DebtInstallment, asOfDate string) string {
+	switch {
+	case installment.PaidAt != nil:
+		return DebtInstallmentPaid
+	case installment.DueDate < asOfDate:
+		return DebtInstallmentOverdue
+	case installment.PaidAmount > 0:
+		return DebtInstallmentPartial
+	default:
+		return DebtInstallmentPending
+	}
+}
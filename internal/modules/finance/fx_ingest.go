@@ -0,0 +1,142 @@
+package finance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// fxSyncRetryInterval spaces out fetches from a provider that has not yet
+// published today's rates (weekends, holidays, late publication).
+const fxSyncRetryInterval = time.Hour
+
+// fxRateStaleDays is how old the newest rate for a pair may get before it is
+// flagged as stale. It leaves room for weekends and public holidays.
+const fxRateStaleDays = 3
+
+type fxSyncState struct {
+	mu          sync.Mutex
+	lastAttempt map[string]time.Time
+}
+
+// begin reports whether source may be fetched at now and records the attempt.
+func (state *fxSyncState) begin(source string, now time.Time) bool {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.lastAttempt == nil {
+		state.lastAttempt = make(map[string]time.Time)
+	}
+	if last, ok := state.lastAttempt[source]; ok && now.Sub(last) < fxSyncRetryInterval {
+		return false
+	}
+	state.lastAttempt[source] = now
+	return true
+}
+
+// FXRateStatus describes the newest stored rate for a pair from one source.
+type FXRateStatus struct {
+	Source       string `json:"source"`
+	FromCurrency string `json:"fromCurrency"`
+	ToCurrency   string `json:"toCurrency"`
+	Date         string `json:"date"`
+	AgeDays      int    `json:"ageDays"`
+	Stale        bool   `json:"stale"`
+}
+
+// SyncFXRates fetches rates from each configured provider that has nothing
+// stored for today yet and upserts them into fx_rates. A failing provider does
+// not stop the others. It returns the number of rates stored.
+func (s *Service) SyncFXRates(ctx context.Context, now time.Time) (int, error) {
+	if len(s.fxProviders) == 0 {
+		return 0, nil
+	}
+	now = now.UTC()
+	today := now.Format("2006-01-02")
+	latest, err := s.repo.ListLatestFXRates(ctx)
+	if err != nil {
+		return 0, err
+	}
+	newest := make(map[string]string)
+	for _, rate := range latest {
+		if rate.Date > newest[rate.Source] {
+			newest[rate.Source] = rate.Date
+		}
+	}
+
+	stored := 0
+	var errs []error
+	for _, provider := range s.fxProviders {
+		source := provider.Source()
+		if newest[source] >= today || !s.fxSync.begin(source, now) {
+			continue
+		}
+		rates, err := provider.FetchRates(ctx, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("fx provider %s: %w", source, err))
+			continue
+		}
+		for _, rate := range rates {
+			normalizeFXRate(rate)
+			if err := s.repo.UpsertFXRate(ctx, rate); err != nil {
+				errs = append(errs, fmt.Errorf("fx provider %s: store %s/%s: %w", source, rate.FromCurrency, rate.ToCurrency, err))
+				break
+			}
+			stored++
+		}
+	}
+	if stored > 0 {
+		s.fxCache.reset()
+	}
+
+	if statuses, err := s.FXRateStatuses(ctx, now); err == nil {
+		stale := 0
+		for _, status := range statuses {
+			if status.Stale {
+				stale++
+			}
+		}
+		if stale > 0 {
+			log.Printf("[Service.SyncFXRates] %d of %d FX pairs are older than %d days", stale, len(statuses), fxRateStaleDays)
+		}
+	}
+	return stored, errors.Join(errs...)
+}
+
+// FXRateStatuses lists the newest rate per source and pair as of now and
+// flags those older than fxRateStaleDays.
+func (s *Service) FXRateStatuses(ctx context.Context, now time.Time) ([]*FXRateStatus, error) {
+	latest, err := s.repo.ListLatestFXRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	today, _ := time.Parse("2006-01-02", now.UTC().Format("2006-01-02"))
+	statuses := make([]*FXRateStatus, 0, len(latest))
+	for _, rate := range latest {
+		status := &FXRateStatus{
+			Source:       rate.Source,
+			FromCurrency: rate.FromCurrency,
+			ToCurrency:   rate.ToCurrency,
+			Date:         rate.Date,
+			Stale:        true,
+		}
+		if rateDate, err := time.Parse("2006-01-02", rate.Date); err == nil {
+			status.AgeDays = int(today.Sub(rateDate).Hours() / 24)
+			status.Stale = status.AgeDays > fxRateStaleDays
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Source != statuses[j].Source {
+			return statuses[i].Source < statuses[j].Source
+		}
+		if statuses[i].FromCurrency != statuses[j].FromCurrency {
+			return statuses[i].FromCurrency < statuses[j].FromCurrency
+		}
+		return statuses[i].ToCurrency < statuses[j].ToCurrency
+	})
+	return statuses, nil
+}
@@ -0,0 +1,222 @@
+package finance
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FXSourceManual = "manual"
+	FXSourceCBU    = "cbu"
+	FXSourceECB    = "ecb"
+)
+
+// DefaultCBUBaseURL serves the Central Bank of Uzbekistan archive in JSON.
+const DefaultCBUBaseURL = "https://cbu.uz/uz/arkhiv-kursov-valyut/json"
+
+// DefaultECBURL is the ECB daily reference rate feed.
+const DefaultECBURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+
+const fxProviderTimeout = 15 * time.Second
+
+// FXProvider fetches official rates for a day. Returned rates carry the
+// provider's Source and are ready to be stored in fx_rates.
+type FXProvider interface {
+	Source() string
+	FetchRates(ctx context.Context, date time.Time) ([]*FXRate, error)
+}
+
+// CBUProvider reads the Central Bank of Uzbekistan JSON archive, which quotes
+// every currency against UZS. The bank publishes a single official rate, so
+// bid and ask are derived from SpreadPercent around it.
+type CBUProvider struct {
+	BaseURL       string
+	SpreadPercent float64
+	Client        *http.Client
+}
+
+func NewCBUProvider(baseURL string, spreadPercent float64) *CBUProvider {
+	if strings.TrimSpace(baseURL) == "" {
+		baseURL = DefaultCBUBaseURL
+	}
+	return &CBUProvider{
+		BaseURL:       strings.TrimRight(baseURL, "/"),
+		SpreadPercent: spreadPercent,
+		Client:        &http.Client{Timeout: fxProviderTimeout},
+	}
+}
+
+func (p *CBUProvider) Source() string {
+	return FXSourceCBU
+}
+
+type cbuRate struct {
+	Ccy     string `json:"Ccy"`
+	Nominal string `json:"Nominal"`
+	Rate    string `json:"Rate"`
+	Date    string `json:"Date"`
+}
+
+func (p *CBUProvider) FetchRates(ctx context.Context, date time.Time) ([]*FXRate, error) {
+	url := fmt.Sprintf("%s/all/%s/", p.BaseURL, date.Format("2006-01-02"))
+	body, err := fetchFXFeed(ctx, p.Client, url)
+	if err != nil {
+		return nil, err
+	}
+	var items []cbuRate
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, fmt.Errorf("cbu: decode rates: %w", err)
+	}
+	rates := make([]*FXRate, 0, len(items))
+	for _, item := range items {
+		mid, err := strconv.ParseFloat(strings.TrimSpace(item.Rate), 64)
+		if err != nil || mid <= 0 {
+			continue
+		}
+		nominal, err := strconv.ParseFloat(strings.TrimSpace(item.Nominal), 64)
+		if err != nil || nominal <= 0 {
+			nominal = 1
+		}
+		// CBU dates are DD.MM.YYYY and name the day the rate takes effect.
+		rateDate, err := time.Parse("02.01.2006", strings.TrimSpace(item.Date))
+		if err != nil {
+			rateDate = date
+		}
+		rates = append(rates, buildProviderFXRate(FXSourceCBU, rateDate, item.Ccy, "UZS", mid, nominal, p.SpreadPercent))
+	}
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("cbu: no rates for %s", date.Format("2006-01-02"))
+	}
+	return rates, nil
+}
+
+// ECBProvider reads a feed in the ECB eurofxref XML format: cubes per day,
+// each quoting currencies against BaseCurrency. It serves the ECB itself and
+// any other bank publishing the same layout.
+type ECBProvider struct {
+	URL           string
+	BaseCurrency  string
+	SpreadPercent float64
+	Client        *http.Client
+}
+
+func NewECBProvider(url, baseCurrency string, spreadPercent float64) *ECBProvider {
+	if strings.TrimSpace(url) == "" {
+		url = DefaultECBURL
+	}
+	if strings.TrimSpace(baseCurrency) == "" {
+		baseCurrency = "EUR"
+	}
+	return &ECBProvider{
+		URL:           url,
+		BaseCurrency:  strings.ToUpper(baseCurrency),
+		SpreadPercent: spreadPercent,
+		Client:        &http.Client{Timeout: fxProviderTimeout},
+	}
+}
+
+func (p *ECBProvider) Source() string {
+	return FXSourceECB
+}
+
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// FetchRates returns the most recent day in the feed on or before date. The
+// daily feed has a single day; historical feeds list many.
+func (p *ECBProvider) FetchRates(ctx context.Context, date time.Time) ([]*FXRate, error) {
+	body, err := fetchFXFeed(ctx, p.Client, p.URL)
+	if err != nil {
+		return nil, err
+	}
+	var envelope ecbEnvelope
+	if err := xml.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("ecb: decode rates: %w", err)
+	}
+	target := date.Format("2006-01-02")
+	best := -1
+	for i, day := range envelope.Days {
+		if day.Time > target {
+			continue
+		}
+		if best < 0 || day.Time > envelope.Days[best].Time {
+			best = i
+		}
+	}
+	if best < 0 {
+		return nil, fmt.Errorf("ecb: no rates on or before %s", target)
+	}
+	day := envelope.Days[best]
+	rateDate, err := time.Parse("2006-01-02", day.Time)
+	if err != nil {
+		return nil, fmt.Errorf("ecb: invalid date %q", day.Time)
+	}
+	rates := make([]*FXRate, 0, len(day.Rates))
+	for _, item := range day.Rates {
+		mid, err := strconv.ParseFloat(strings.TrimSpace(item.Rate), 64)
+		if err != nil || mid <= 0 {
+			continue
+		}
+		rates = append(rates, buildProviderFXRate(FXSourceECB, rateDate, p.BaseCurrency, item.Currency, mid, 1, p.SpreadPercent))
+	}
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("ecb: no rates for %s", day.Time)
+	}
+	return rates, nil
+}
+
+func fetchFXFeed(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	if client == nil {
+		client = &http.Client{Timeout: fxProviderTimeout}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fx feed %s: unexpected status %d", url, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// buildProviderFXRate spreads bid and ask evenly around the published mid.
+func buildProviderFXRate(source string, date time.Time, from, to string, mid, nominal, spreadPercent float64) *FXRate {
+	half := spreadPercent / 200
+	return &FXRate{
+		Date:          date.Format("2006-01-02"),
+		FromCurrency:  strings.ToUpper(strings.TrimSpace(from)),
+		ToCurrency:    strings.ToUpper(strings.TrimSpace(to)),
+		Rate:          mid,
+		RateMid:       mid,
+		RateBid:       roundFXRate(mid * (1 - half)),
+		RateAsk:       roundFXRate(mid * (1 + half)),
+		Nominal:       nominal,
+		SpreadPercent: spreadPercent,
+		Source:        source,
+	}
+}
+
+// roundFXRate matches the DECIMAL(19,6) rate columns.
+func roundFXRate(value float64) float64 {
+	return math.Round(value*1e6) / 1e6
+}
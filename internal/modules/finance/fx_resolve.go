@@ -0,0 +1,204 @@
+package finance
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	appErrors "github.com/leora/leora-server/internal/errors"
+)
+
+const (
+	FXPathIdentity     = "identity"
+	FXPathDirect       = "direct"
+	FXPathInverse      = "inverse"
+	FXPathTriangulated = "triangulated"
+)
+
+// fxPivotCurrencies are tried in order when a pair has no stored rate either
+// way. CBU quotes everything against UZS and ECB-format feeds against EUR.
+var fxPivotCurrencies = []string{"UZS", "EUR"}
+
+// fxResolutionCacheTTL bounds how long a resolved rate is reused. Local rate
+// writes clear the cache at once; the TTL covers writes from other instances.
+const fxResolutionCacheTTL = 10 * time.Minute
+
+const fxResolutionCacheLimit = 4096
+
+// FXResolution is a rate for a pair and day together with how it was found.
+type FXResolution struct {
+	FromCurrency string            `json:"fromCurrency"`
+	ToCurrency   string            `json:"toCurrency"`
+	Date         string            `json:"date"`
+	Rate         float64           `json:"rate"`
+	Path         string            `json:"path"`
+	Via          string            `json:"via,omitempty"`
+	Legs         []FXResolutionLeg `json:"legs"`
+	exchange     ExchangeRate
+}
+
+// FXResolutionLeg is one step of a resolution. Inverted means the stored rate
+// RateID quotes the reverse pair and Rate is its inverse.
+type FXResolutionLeg struct {
+	RateID       string  `json:"rateId"`
+	FromCurrency string  `json:"fromCurrency"`
+	ToCurrency   string  `json:"toCurrency"`
+	Date         string  `json:"date"`
+	Source       string  `json:"source"`
+	Rate         float64 `json:"rate"`
+	Inverted     bool    `json:"inverted"`
+}
+
+type fxResolutionCache struct {
+	mu      sync.Mutex
+	entries map[string]fxResolutionCacheEntry
+}
+
+type fxResolutionCacheEntry struct {
+	resolution *FXResolution
+	expiresAt  time.Time
+}
+
+func (cache *fxResolutionCache) get(key string, now time.Time) *FXResolution {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	entry, ok := cache.entries[key]
+	if !ok || now.After(entry.expiresAt) {
+		return nil
+	}
+	return entry.resolution
+}
+
+func (cache *fxResolutionCache) put(key string, resolution *FXResolution, now time.Time) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.entries == nil || len(cache.entries) >= fxResolutionCacheLimit {
+		cache.entries = make(map[string]fxResolutionCacheEntry)
+	}
+	cache.entries[key] = fxResolutionCacheEntry{resolution: resolution, expiresAt: now.Add(fxResolutionCacheTTL)}
+}
+
+func (cache *fxResolutionCache) reset() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.entries = nil
+}
+
+// ResolveFXRate finds the rate converting fromCurrency into toCurrency on
+// dateValue (YYYY-MM-DD, today when empty or invalid). It uses the newest
+// stored rate for the pair on or before the date, else the inverse of the
+// reverse pair, else a cross rate through a pivot currency.
+func (s *Service) ResolveFXRate(ctx context.Context, fromCurrency, toCurrency, dateValue string) (*FXResolution, error) {
+	from := strings.ToUpper(strings.TrimSpace(fromCurrency))
+	to := strings.ToUpper(strings.TrimSpace(toCurrency))
+	date := strings.TrimSpace(dateValue)
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		date = time.Now().UTC().Format("2006-01-02")
+	}
+	if from == to {
+		return &FXResolution{FromCurrency: from, ToCurrency: to, Date: date, Rate: 1, Path: FXPathIdentity, Legs: []FXResolutionLeg{}, exchange: exchangeRateOne()}, nil
+	}
+
+	key := from + "|" + to + "|" + date
+	now := time.Now()
+	if cached := s.fxCache.get(key, now); cached != nil {
+		return cached, nil
+	}
+
+	resolution, err := s.lookupFXRate(ctx, from, to, date)
+	if err != nil {
+		return nil, err
+	}
+	s.fxCache.put(key, resolution, now)
+	return resolution, nil
+}
+
+func (s *Service) lookupFXRate(ctx context.Context, from, to, date string) (*FXResolution, error) {
+	resolution := &FXResolution{FromCurrency: from, ToCurrency: to, Date: date}
+	leg, value, err := s.fxLeg(ctx, from, to, date)
+	if err != nil {
+		return nil, err
+	}
+	if leg != nil {
+		resolution.Path = FXPathDirect
+		if leg.Inverted {
+			resolution.Path = FXPathInverse
+		}
+		resolution.Legs = []FXResolutionLeg{*leg}
+		resolution.exchange = value
+		resolution.Rate = value.Float64()
+		return resolution, nil
+	}
+
+	for _, pivot := range fxPivotCurrencies {
+		if pivot == from || pivot == to {
+			continue
+		}
+		first, firstValue, err := s.fxLeg(ctx, from, pivot, date)
+		if err != nil {
+			return nil, err
+		}
+		if first == nil {
+			continue
+		}
+		second, secondValue, err := s.fxLeg(ctx, pivot, to, date)
+		if err != nil {
+			return nil, err
+		}
+		if second == nil {
+			continue
+		}
+		resolution.Path = FXPathTriangulated
+		resolution.Via = pivot
+		resolution.Legs = []FXResolutionLeg{*first, *second}
+		resolution.exchange = firstValue.Mul(secondValue)
+		resolution.Rate = resolution.exchange.Float64()
+		return resolution, nil
+	}
+	return nil, appErrors.FXRateNotFound
+}
+
+// fxLeg returns the stored rate for from→to, trying the reverse pair when the
+// direct one is missing. A nil leg means neither is stored.
+func (s *Service) fxLeg(ctx context.Context, from, to, date string) (*FXResolutionLeg, ExchangeRate, error) {
+	for _, inverted := range []bool{false, true} {
+		pairFrom, pairTo := from, to
+		if inverted {
+			pairFrom, pairTo = to, from
+		}
+		rate, err := s.repo.FindFXRate(ctx, pairFrom, pairTo, date)
+		if err == appErrors.FXRateNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, ExchangeRate{}, err
+		}
+		value := fxRateValue(rate)
+		if value.IsZero() {
+			continue
+		}
+		if inverted {
+			value = value.Inverse()
+		}
+		return &FXResolutionLeg{
+			RateID:       rate.ID,
+			FromCurrency: from,
+			ToCurrency:   to,
+			Date:         rate.Date,
+			Source:       rate.Source,
+			Rate:         value.Float64(),
+			Inverted:     inverted,
+		}, value, nil
+	}
+	return nil, ExchangeRate{}, nil
+}
+
+// resolveFXRate is ResolveFXRate reduced to the exact rate, for conversions.
+func (s *Service) resolveFXRate(ctx context.Context, fromCurrency, toCurrency, dateValue string) (ExchangeRate, error) {
+	resolution, err := s.ResolveFXRate(ctx, fromCurrency, toCurrency, dateValue)
+	if err != nil {
+		return ExchangeRate{}, err
+	}
+	return resolution.exchange, nil
+}
@@ -1,7 +1,9 @@
 package finance
 
 import (
+	"encoding/json"
 	"errors"
+	"io"
 	"log"
 	"sort"
 	"strings"
@@ -54,14 +56,16 @@ func (h *Handler) GetAccount(c *fiber.Ctx) error {
 
 func (h *Handler) CreateAccount(c *fiber.Ctx) error {
 	type createAccountRequest struct {
-		Name           string   `json:"name"`
-		Currency       string   `json:"currency"`
-		AccountType    string   `json:"accountType"`
-		OpeningBalance *float64 `json:"opening_balance"`
-		InitialBalance *float64 `json:"initialBalance"`
-		LinkedGoalID   *string  `json:"linkedGoalId"`
-		CustomTypeID   *string  `json:"customTypeId"`
-		IsMain         *bool    `json:"isMain"`
+		Name           string           `json:"name"`
+		Currency       string           `json:"currency"`
+		AccountType    string           `json:"accountType"`
+		OpeningBalance *Money           `json:"opening_balance"`
+		InitialBalance *Money           `json:"initialBalance"`
+		LinkedGoalID   *string          `json:"linkedGoalId"`
+		CustomTypeID   *string          `json:"customTypeId"`
+		IsMain         *bool            `json:"isMain"`
+		Credit         *CreditTerms     `json:"credit"`
+		Balances       []AccountBalance `json:"balances"`
 	}
 	var payload createAccountRequest
 	if err := c.BodyParser(&payload); err != nil {
@@ -73,6 +77,8 @@ func (h *Handler) CreateAccount(c *fiber.Ctx) error {
 		AccountType:  payload.AccountType,
 		LinkedGoalID: payload.LinkedGoalID,
 		CustomTypeID: payload.CustomTypeID,
+		Credit:       payload.Credit,
+		Balances:     payload.Balances,
 	}
 	if payload.IsMain != nil {
 		account.IsMain = *payload.IsMain
@@ -170,14 +176,18 @@ func (h *Handler) DeleteAccount(c *fiber.Ctx) error {
 
 func (h *Handler) AccountTransactions(c *fiber.Ctx) error {
 	accountID := c.Params("id")
-	transactions, err := h.service.AccountTransactions(c.Context(), accountID)
+	pageRequest, pageErr := parseTransactionPage(c)
+	if pageErr != nil {
+		return response.Failure(c, pageErr)
+	}
+	page, err := h.service.AccountTransactions(c.Context(), accountID, pageRequest)
 	if err != nil {
 		if typed, ok := err.(*appErrors.Error); ok {
 			return response.Failure(c, typed)
 		}
 		return response.Failure(c, appErrors.InternalServerError)
 	}
-	return response.Success(c, transactions, nil)
+	return response.Success(c, page.Items, transactionPageMeta(page))
 }
 
 func (h *Handler) AccountBalanceHistory(c *fiber.Ctx) error {
@@ -196,7 +206,7 @@ func (h *Handler) FinanceSummary(c *fiber.Ctx) error {
 	dateFrom := c.Query("from")
 	dateTo := c.Query("to")
 	baseCurrency := c.Query("currency")
-	accountIDs := parseAccountIDs(c.Query("accountIds"))
+	accountIDs := parseQueryList(c.Query("accountIds"))
 	summary, err := h.service.FinanceSummary(c.Context(), dateFrom, dateTo, baseCurrency, accountIDs)
 	if err != nil {
 		if typed, ok := err.(*appErrors.Error); ok {
@@ -211,7 +221,7 @@ func (h *Handler) FinanceBootstrap(c *fiber.Ctx) error {
 	dateFrom := c.Query("from")
 	dateTo := c.Query("to")
 	baseCurrency := c.Query("currency")
-	accountIDs := parseAccountIDs(c.Query("accountIds"))
+	accountIDs := parseQueryList(c.Query("accountIds"))
 	bootstrap, err := h.service.FinanceBootstrap(c.Context(), dateFrom, dateTo, baseCurrency, accountIDs)
 	if err != nil {
 		if typed, ok := err.(*appErrors.Error); ok {
@@ -223,9 +233,9 @@ func (h *Handler) FinanceBootstrap(c *fiber.Ctx) error {
 }
 
 func (h *Handler) Transactions(c *fiber.Ctx) error {
-	page, limit, err := utils.ParsePaginationParams(c.Query("page"), c.Query("limit"))
-	if err != nil {
-		return response.Failure(c, appErrors.InvalidFinanceData)
+	pageRequest, pageErr := parseTransactionPage(c)
+	if pageErr != nil {
+		return response.Failure(c, pageErr)
 	}
 	filter := TransactionFilter{
 		AccountID:  c.Query("accountId"),
@@ -236,14 +246,65 @@ func (h *Handler) Transactions(c *fiber.Ctx) error {
 		GoalID:     c.Query("goalId"),
 		BudgetID:   c.Query("budgetId"),
 		DebtID:     c.Query("debtId"),
+
+		CounterpartyID: c.Query("counterpartyId"),
+		HabitID:        c.Query("habitId"),
+		Status:         c.Query("status"),
+		IncludeVoided:  c.QueryBool("includeVoided"),
+
+		AmountBasis: c.Query("amountBasis"),
+		TagsAny:     parseQueryList(c.Query("tagsAny")),
+		TagsAll:     parseQueryList(c.Query("tagsAll")),
+		Search:      c.Query("search"),
+		SortBy:      c.Query("sortBy"),
+		SortOrder:   c.Query("sortOrder"),
+	}
+	if filter.AmountMin, pageErr = parseMoneyQuery(c, "amountMin"); pageErr != nil {
+		return response.Failure(c, pageErr)
+	}
+	if filter.AmountMax, pageErr = parseMoneyQuery(c, "amountMax"); pageErr != nil {
+		return response.Failure(c, pageErr)
 	}
-	data, err := h.service.Transactions(c.Context(), filter)
+	page, err := h.service.Transactions(c.Context(), filter, pageRequest)
 	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
 		return response.Failure(c, appErrors.InternalServerError)
 	}
-	start, end := utils.SliceBounds(len(data), page, limit)
-	paged := data[start:end]
-	return response.Success(c, paged, &response.Meta{Page: page, Limit: limit, Total: len(data), TotalPages: utils.TotalPages(len(data), limit)})
+	return response.Success(c, page.Items, transactionPageMeta(page))
+}
+
+// parseTransactionPage reads cursor and limit, falling back to page-based
+// paging when no cursor is given.
+func parseTransactionPage(c *fiber.Ctx) (TransactionPageRequest, *appErrors.Error) {
+	page, limit, err := utils.ParsePaginationParams(c.Query("page"), c.Query("limit"))
+	if err != nil {
+		return TransactionPageRequest{}, appErrors.InvalidFinanceData
+	}
+	request := TransactionPageRequest{Limit: limit, Page: page}
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := DecodeTransactionCursor(raw)
+		if err != nil {
+			if typed, ok := err.(*appErrors.Error); ok {
+				return TransactionPageRequest{}, typed
+			}
+			return TransactionPageRequest{}, appErrors.InvalidFinanceData
+		}
+		request.Cursor = cursor
+		request.Page = 0
+	}
+	return request, nil
+}
+
+func transactionPageMeta(page *TransactionPage) *response.Meta {
+	meta := &response.Meta{Limit: page.Limit, NextCursor: page.NextCursor, HasMore: page.HasMore}
+	if page.Page > 0 {
+		meta.Page = page.Page
+		meta.Total = page.Total
+		meta.TotalPages = utils.TotalPages(page.Total, page.Limit)
+	}
+	return meta
 }
 
 func (h *Handler) GetTransaction(c *fiber.Ctx) error {
@@ -394,6 +455,57 @@ func (h *Handler) DeleteTransaction(c *fiber.Ctx) error {
 	return response.Success(c, fiber.Map{"id": id, "status": "deleted"}, nil)
 }
 
+func (h *Handler) ReverseTransaction(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var payload struct {
+		Note *string `json:"note"`
+		Date string  `json:"date"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&payload); err != nil {
+			return response.Failure(c, appErrors.InvalidFinanceData)
+		}
+	}
+	result, err := h.service.ReverseTransaction(c.Context(), id, TransactionReversalInput{
+		Date: payload.Date,
+		Note: payload.Note,
+	})
+	if err != nil {
+		if errors.Is(err, appErrors.TransactionNotFound) {
+			return response.Failure(c, appErrors.TransactionNotFound)
+		}
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.SuccessWithStatus(c, fiber.StatusCreated, result, nil)
+}
+
+func (h *Handler) CorrectTransaction(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var payload map[string]interface{}
+	if err := c.BodyParser(&payload); err != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+	var note *string
+	if v, ok := payload["note"].(string); ok {
+		note = &v
+		delete(payload, "note")
+	}
+	result, err := h.service.CorrectTransaction(c.Context(), id, payload, note)
+	if err != nil {
+		if errors.Is(err, appErrors.TransactionNotFound) {
+			return response.Failure(c, appErrors.TransactionNotFound)
+		}
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.SuccessWithStatus(c, fiber.StatusCreated, result, nil)
+}
+
 func (h *Handler) Budgets(c *fiber.Ctx) error {
 	page, limit, err := utils.ParsePaginationParams(c.Query("page"), c.Query("limit"))
 	if err != nil {
@@ -496,7 +608,7 @@ func (h *Handler) AddBudgetValue(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var payload struct {
 		AccountID      string  `json:"account_id"`
-		Amount         float64 `json:"amount"`
+		Amount         Money   `json:"amount"`
 		AmountCurrency string  `json:"amount_currency"`
 		Note           *string `json:"note"`
 		Date           *string `json:"date"`
@@ -515,7 +627,7 @@ func (h *Handler) AddBudgetValue(c *fiber.Ctx) error {
 		CategoryID:     payload.CategoryID,
 	})
 	if err != nil {
-		log.Printf("[Handler.AddBudgetValue] Error for budget=%s, account=%s, amount=%.2f: %v", id, payload.AccountID, payload.Amount, err)
+		log.Printf("[Handler.AddBudgetValue] Error for budget=%s, account=%s, amount=%s: %v", id, payload.AccountID, payload.Amount, err)
 		if typed, ok := err.(*appErrors.Error); ok {
 			return response.Failure(c, typed)
 		}
@@ -532,6 +644,39 @@ func (h *Handler) AddBudgetValue(c *fiber.Ctx) error {
 	}, nil)
 }
 
+func (h *Handler) BudgetPeriods(c *fiber.Ctx) error {
+	page, limit, err := utils.ParsePaginationParams(c.Query("page"), c.Query("limit"))
+	if err != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+	id := c.Params("id")
+	items, err := h.service.BudgetPeriods(c.Context(), id, c.Query("from"), c.Query("to"))
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	start, end := utils.SliceBounds(len(items), page, limit)
+	paged := items[start:end]
+	return response.Success(c, paged, &response.Meta{Page: page, Limit: limit, Total: len(items), TotalPages: utils.TotalPages(len(items), limit)})
+}
+
+func (h *Handler) CloseBudgetPeriod(c *fiber.Ctx) error {
+	id := c.Params("id")
+	period, budget, err := h.service.CloseBudgetPeriod(c.Context(), id, time.Now().UTC())
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.SuccessWithStatus(c, fiber.StatusCreated, fiber.Map{
+		"period": period,
+		"budget": budget,
+	}, nil)
+}
+
 func (h *Handler) DeleteBudget(c *fiber.Ctx) error {
 	id := c.Params("id")
 	if err := h.service.DeleteBudget(c.Context(), id); err != nil {
@@ -548,14 +693,18 @@ func (h *Handler) DeleteBudget(c *fiber.Ctx) error {
 
 func (h *Handler) BudgetTransactions(c *fiber.Ctx) error {
 	id := c.Params("id")
-	transactions, err := h.service.BudgetTransactions(c.Context(), id)
+	pageRequest, pageErr := parseTransactionPage(c)
+	if pageErr != nil {
+		return response.Failure(c, pageErr)
+	}
+	page, err := h.service.BudgetTransactions(c.Context(), id, pageRequest)
 	if err != nil {
 		if typed, ok := err.(*appErrors.Error); ok {
 			return response.Failure(c, typed)
 		}
 		return response.Failure(c, appErrors.InternalServerError)
 	}
-	return response.Success(c, transactions, nil)
+	return response.Success(c, page.Items, transactionPageMeta(page))
 }
 
 func (h *Handler) BudgetSpending(c *fiber.Ctx) error {
@@ -587,10 +736,16 @@ func (h *Handler) Debts(c *fiber.Ctx) error {
 	if err != nil {
 		return response.Failure(c, appErrors.InvalidFinanceData)
 	}
+	var overdue *bool
+	if raw := c.Query("overdue"); raw != "" {
+		value := strings.ToLower(raw) == "true"
+		overdue = &value
+	}
 	filter := DebtFilter{
 		Direction:    c.Query("direction"),
 		Status:       c.Query("status"),
 		LinkedGoalID: c.Query("linkedGoalId"),
+		Overdue:      overdue,
 	}
 	// Use the new method that embeds counterparties
 	data, err := h.service.DebtsWithCounterparties(c.Context(), filter)
@@ -604,8 +759,12 @@ func (h *Handler) Debts(c *fiber.Ctx) error {
 
 func (h *Handler) GetDebt(c *fiber.Ctx) error {
 	id := c.Params("id")
+	asOf, err := parseAsOfQuery(c.Query("asOf"))
+	if err != nil {
+		return response.Failure(c, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "asOf"}))
+	}
 	// Use the new method that embeds counterparty
-	debt, err := h.service.GetDebtWithCounterparty(c.Context(), id)
+	debt, err := h.service.GetDebtWithCounterparty(c.Context(), id, asOf)
 	if err != nil {
 		if errors.Is(err, appErrors.DebtNotFound) {
 			return response.Failure(c, appErrors.DebtNotFound)
@@ -726,7 +885,7 @@ func (h *Handler) RepayDebt(c *fiber.Ctx) error {
 	debtID := c.Params("id")
 	var payload struct {
 		AccountID      string  `json:"account_id"`
-		Amount         float64 `json:"amount"`
+		Amount         Money   `json:"amount"`
 		AmountCurrency string  `json:"amount_currency"`
 		Note           *string `json:"note"`
 		Date           *string `json:"date"`
@@ -745,7 +904,7 @@ func (h *Handler) RepayDebt(c *fiber.Ctx) error {
 		AppliedRate:    payload.AppliedRate,
 	})
 	if err != nil {
-		log.Printf("[Handler.RepayDebt] Error for debt=%s, account=%s, amount=%.2f: %v", debtID, payload.AccountID, payload.Amount, err)
+		log.Printf("[Handler.RepayDebt] Error for debt=%s, account=%s, amount=%s: %v", debtID, payload.AccountID, payload.Amount, err)
 		if typed, ok := err.(*appErrors.Error); ok {
 			return response.Failure(c, typed)
 		}
@@ -767,7 +926,7 @@ func (h *Handler) AddDebtValue(c *fiber.Ctx) error {
 	debtID := c.Params("id")
 	var payload struct {
 		AccountID      string  `json:"account_id"`
-		Amount         float64 `json:"amount"`
+		Amount         Money   `json:"amount"`
 		AmountCurrency string  `json:"amount_currency"`
 		Note           *string `json:"note"`
 		Date           *string `json:"date"`
@@ -784,7 +943,7 @@ func (h *Handler) AddDebtValue(c *fiber.Ctx) error {
 		Date:           payload.Date,
 	})
 	if err != nil {
-		log.Printf("[Handler.AddDebtValue] Error for debt=%s, account=%s, amount=%.2f: %v", debtID, payload.AccountID, payload.Amount, err)
+		log.Printf("[Handler.AddDebtValue] Error for debt=%s, account=%s, amount=%s: %v", debtID, payload.AccountID, payload.Amount, err)
 		if typed, ok := err.(*appErrors.Error); ok {
 			return response.Failure(c, typed)
 		}
@@ -909,6 +1068,49 @@ func (h *Handler) ExtendDebt(c *fiber.Ctx) error {
 	return response.Success(c, updated, nil)
 }
 
+func (h *Handler) DebtSchedule(c *fiber.Ctx) error {
+	debtID := c.Params("id")
+	asOf, err := parseAsOfQuery(c.Query("asOf"))
+	if err != nil {
+		return response.Failure(c, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "asOf"}))
+	}
+	schedule, err := h.service.DebtSchedule(c.Context(), debtID, asOf)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, schedule, nil)
+}
+
+func (h *Handler) SetDebtSchedule(c *fiber.Ctx) error {
+	debtID := c.Params("id")
+	var payload DebtScheduleInput
+	if err := c.BodyParser(&payload); err != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+	schedule, err := h.service.SetDebtSchedule(c.Context(), debtID, payload)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, schedule, nil)
+}
+
+func (h *Handler) DeleteDebtSchedule(c *fiber.Ctx) error {
+	debtID := c.Params("id")
+	if err := h.service.ClearDebtSchedule(c.Context(), debtID); err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, fiber.Map{"debtId": debtID, "status": "deleted"}, nil)
+}
+
 func (h *Handler) Counterparties(c *fiber.Ctx) error {
 	page, limit, err := utils.ParsePaginationParams(c.Query("page"), c.Query("limit"))
 	if err != nil {
@@ -1019,161 +1221,951 @@ func (h *Handler) CounterpartyDebts(c *fiber.Ctx) error {
 
 func (h *Handler) CounterpartyTransactions(c *fiber.Ctx) error {
 	id := c.Params("id")
-	transactions, err := h.service.Transactions(c.Context(), TransactionFilter{})
-	if err != nil {
-		return response.Failure(c, appErrors.InternalServerError)
+	pageRequest, pageErr := parseTransactionPage(c)
+	if pageErr != nil {
+		return response.Failure(c, pageErr)
 	}
-	filtered := make([]*Transaction, 0)
-	for _, txn := range transactions {
-		if txn.CounterpartyID != nil && *txn.CounterpartyID == id {
-			filtered = append(filtered, txn)
+	page, err := h.service.CounterpartyTransactions(c.Context(), id, pageRequest)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
 		}
+		return response.Failure(c, appErrors.InternalServerError)
 	}
-	return response.Success(c, filtered, nil)
+	return response.Success(c, page.Items, transactionPageMeta(page))
 }
 
-func (h *Handler) GetFXRates(c *fiber.Ctx) error {
-	from := strings.ToUpper(c.Query("from"))
-	to := strings.ToUpper(c.Query("to"))
-	date := c.Query("date")
-	rates, err := h.service.FXRates(c.Context())
+func (h *Handler) RecurringTransactions(c *fiber.Ctx) error {
+	page, limit, err := utils.ParsePaginationParams(c.Query("page"), c.Query("limit"))
+	if err != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+	data, err := h.service.RecurringTransactions(c.Context())
 	if err != nil {
 		return response.Failure(c, appErrors.InternalServerError)
 	}
-	if from != "" || to != "" || date != "" {
-		for _, rate := range rates {
-			if from != "" && rate.FromCurrency != from {
-				continue
-			}
-			if to != "" && rate.ToCurrency != to {
-				continue
-			}
-			if date != "" && rate.Date != date {
-				continue
-			}
-			return response.Success(c, rate, nil)
+	start, end := utils.SliceBounds(len(data), page, limit)
+	paged := data[start:end]
+	return response.Success(c, paged, &response.Meta{Page: page, Limit: limit, Total: len(data), TotalPages: utils.TotalPages(len(data), limit)})
+}
+
+func (h *Handler) GetRecurringTransaction(c *fiber.Ctx) error {
+	id := c.Params("id")
+	item, err := h.service.GetRecurringTransaction(c.Context(), id)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
 		}
-		return response.Failure(c, appErrors.FXRateNotFound)
+		return response.Failure(c, appErrors.InternalServerError)
 	}
-	return response.Success(c, rates, nil)
+	return response.Success(c, item, nil)
 }
 
-func (h *Handler) CreateFXRate(c *fiber.Ctx) error {
-	var payload FXRate
+func (h *Handler) CreateRecurringTransaction(c *fiber.Ctx) error {
+	var payload RecurringTransaction
 	if err := c.BodyParser(&payload); err != nil {
 		return response.Failure(c, appErrors.InvalidFinanceData)
 	}
-	if payload.FromCurrency == "" || payload.ToCurrency == "" || payload.Date == "" {
-		return response.Failure(c, appErrors.InvalidFinanceData)
-	}
-	created, err := h.service.CreateFXRate(c.Context(), &payload)
+	created, err := h.service.CreateRecurringTransaction(c.Context(), &payload)
 	if err != nil {
 		if typed, ok := err.(*appErrors.Error); ok {
 			return response.Failure(c, typed)
 		}
 		return response.Failure(c, appErrors.InternalServerError)
 	}
-	return response.Success(c, created, nil)
+	return response.SuccessWithStatus(c, fiber.StatusCreated, created, nil)
 }
 
-func (h *Handler) SupportedCurrencies(c *fiber.Ctx) error {
-	defaultCurrencies := []string{"USD", "EUR", "UZS", "RUB"}
-	catalog := map[string]SupportedCurrency{
-		"USD": {Code: "USD", Symbol: "$", Name: "US Dollar", Decimals: 2},
-		"EUR": {Code: "EUR", Symbol: "€", Name: "Euro", Decimals: 2},
-		"GBP": {Code: "GBP", Symbol: "£", Name: "British Pound", Decimals: 2},
-		"UZS": {Code: "UZS", Symbol: "сум", Name: "Uzbek Som", Decimals: 0},
-		"RUB": {Code: "RUB", Symbol: "₽", Name: "Russian Ruble", Decimals: 2},
-		"JPY": {Code: "JPY", Symbol: "¥", Name: "Japanese Yen", Decimals: 0},
-		"CNY": {Code: "CNY", Symbol: "¥", Name: "Chinese Yuan", Decimals: 2},
-		"CHF": {Code: "CHF", Symbol: "Fr", Name: "Swiss Franc", Decimals: 2},
-		"CAD": {Code: "CAD", Symbol: "C$", Name: "Canadian Dollar", Decimals: 2},
-		"AUD": {Code: "AUD", Symbol: "A$", Name: "Australian Dollar", Decimals: 2},
-		"TRY": {Code: "TRY", Symbol: "₺", Name: "Turkish Lira", Decimals: 2},
-		"SAR": {Code: "SAR", Symbol: "﷼", Name: "Saudi Riyal", Decimals: 2},
-		"AED": {Code: "AED", Symbol: "د.إ", Name: "UAE Dirham", Decimals: 2},
-		"USDT": {Code: "USDT", Symbol: "₮", Name: "Tether", Decimals: 2},
-	}
-	rates, _ := h.service.FXRates(c.Context())
-	seen := make(map[string]bool)
-	for _, currency := range defaultCurrencies {
-		seen[currency] = true
-	}
-	for _, rate := range rates {
-		seen[rate.FromCurrency] = true
-		seen[rate.ToCurrency] = true
-	}
-	list := make([]SupportedCurrency, 0, len(seen))
-	for currency := range seen {
-		code := strings.ToUpper(currency)
-		if item, ok := catalog[code]; ok {
-			list = append(list, item)
-			continue
-		}
-		decimals := 2
-		if code == "UZS" {
-			decimals = 0
-		}
-		list = append(list, SupportedCurrency{
-			Code:     code,
-			Symbol:   code,
-			Name:     code,
-			Decimals: decimals,
-		})
+func (h *Handler) PatchRecurringTransaction(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var payload map[string]interface{}
+	if err := c.BodyParser(&payload); err != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
 	}
-	sort.Slice(list, func(i, j int) bool {
-		return list[i].Code < list[j].Code
-	})
-	return response.Success(c, list, nil)
-}
-
-func (h *Handler) Categories(c *fiber.Ctx) error {
-	categoryType := c.Query("type")
-	activeOnly := c.Query("active") != "false"
-	categories, err := h.service.Categories(c.Context(), categoryType, activeOnly)
+	updated, err := h.service.PatchRecurringTransaction(c.Context(), id, payload)
 	if err != nil {
 		if typed, ok := err.(*appErrors.Error); ok {
 			return response.Failure(c, typed)
 		}
 		return response.Failure(c, appErrors.InternalServerError)
 	}
-	return response.Success(c, categories, nil)
+	return response.Success(c, updated, nil)
 }
 
-func (h *Handler) QuickExpenseCategories(c *fiber.Ctx) error {
-	categoryType := c.Query("type")
-	categories, err := h.service.QuickExpenseCategories(c.Context(), categoryType)
-	if err != nil {
+func (h *Handler) DeleteRecurringTransaction(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.service.DeleteRecurringTransaction(c.Context(), id); err != nil {
 		if typed, ok := err.(*appErrors.Error); ok {
 			return response.Failure(c, typed)
 		}
 		return response.Failure(c, appErrors.InternalServerError)
 	}
-	return response.Success(c, categories, nil)
+	return response.Success(c, fiber.Map{"id": id, "status": "deleted"}, nil)
 }
 
-func (h *Handler) UpdateQuickExpenseCategories(c *fiber.Ctx) error {
-	type payload struct {
-		Type       string                 `json:"type"`
-		Categories []QuickExpenseCategory `json:"categories"`
-	}
-	var req payload
-	if err := c.BodyParser(&req); err != nil {
-		return response.Failure(c, appErrors.InvalidFinanceData)
-	}
-	// Convert []QuickExpenseCategory to []*QuickExpenseCategory
-	categoryPtrs := make([]*QuickExpenseCategory, len(req.Categories))
-	for i := range req.Categories {
-		categoryPtrs[i] = &req.Categories[i]
-	}
-	updated, err := h.service.UpdateQuickExpenseCategories(c.Context(), req.Type, categoryPtrs)
+func (h *Handler) RecurringOccurrences(c *fiber.Ctx) error {
+	id := c.Params("id")
+	items, err := h.service.RecurringOccurrences(c.Context(), id, c.Query("status"))
 	if err != nil {
 		if typed, ok := err.(*appErrors.Error); ok {
 			return response.Failure(c, typed)
 		}
 		return response.Failure(c, appErrors.InternalServerError)
 	}
-	return response.Success(c, updated, nil)
+	return response.Success(c, items, nil)
+}
+
+func (h *Handler) ConfirmRecurringOccurrence(c *fiber.Ctx) error {
+	id := c.Params("id")
+	occurrenceID := c.Params("occurrenceId")
+	var payload struct {
+		Amount *Money `json:"amount"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&payload); err != nil {
+			return response.Failure(c, appErrors.InvalidFinanceData)
+		}
+	}
+	occurrence, txn, err := h.service.ConfirmRecurringOccurrence(c.Context(), id, occurrenceID, payload.Amount)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.SuccessWithStatus(c, fiber.StatusCreated, fiber.Map{"occurrence": occurrence, "transaction": txn}, nil)
+}
+
+func (h *Handler) SkipRecurringOccurrence(c *fiber.Ctx) error {
+	id := c.Params("id")
+	occurrenceID := c.Params("occurrenceId")
+	occurrence, err := h.service.SkipRecurringOccurrence(c.Context(), id, occurrenceID)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, occurrence, nil)
+}
+
+func (h *Handler) StatementImports(c *fiber.Ctx) error {
+	items, err := h.service.StatementImports(c.Context())
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, items, nil)
+}
+
+func (h *Handler) GetStatementImport(c *fiber.Ctx) error {
+	batch, err := h.service.GetStatementImport(c.Context(), c.Params("id"))
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, batch, nil)
+}
+
+func (h *Handler) ImportStatement(c *fiber.Ctx) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return response.Failure(c, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "file"}))
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+	defer file.Close()
+	// One byte over the limit is enough for the service to reject the file.
+	data, err := io.ReadAll(io.LimitReader(file, maxStatementFileBytes+1))
+	if err != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+
+	input := StatementImportInput{
+		AccountID: c.FormValue("accountId"),
+		Format:    c.FormValue("format"),
+		FileName:  fileHeader.Filename,
+		Data:      data,
+		ProfileID: c.FormValue("profileId"),
+	}
+	if input.AccountID == "" {
+		return response.Failure(c, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "accountId"}))
+	}
+	if raw := c.FormValue("mapping"); raw != "" {
+		var mapping StatementCSVMapping
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			return response.Failure(c, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "mapping"}))
+		}
+		input.Mapping = &mapping
+	}
+	batch, err := h.service.ImportStatement(c.Context(), input)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.SuccessWithStatus(c, fiber.StatusCreated, batch, nil)
+}
+
+func (h *Handler) PatchStatementImportRow(c *fiber.Ctx) error {
+	var payload map[string]interface{}
+	if err := c.BodyParser(&payload); err != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+	row, err := h.service.PatchStatementImportRow(c.Context(), c.Params("id"), c.Params("rowId"), payload)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, row, nil)
+}
+
+func (h *Handler) ApproveStatementImportRows(c *fiber.Ctx) error {
+	var payload StatementImportDecision
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&payload); err != nil {
+			return response.Failure(c, appErrors.InvalidFinanceData)
+		}
+	}
+	batch, err := h.service.ApproveStatementImportRows(c.Context(), c.Params("id"), payload)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, batch, nil)
+}
+
+func (h *Handler) RejectStatementImportRows(c *fiber.Ctx) error {
+	var payload StatementImportDecision
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&payload); err != nil {
+			return response.Failure(c, appErrors.InvalidFinanceData)
+		}
+	}
+	batch, err := h.service.RejectStatementImportRows(c.Context(), c.Params("id"), payload)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, batch, nil)
+}
+
+func (h *Handler) UndoStatementImport(c *fiber.Ctx) error {
+	batch, err := h.service.UndoStatementImport(c.Context(), c.Params("id"))
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, batch, nil)
+}
+
+func (h *Handler) StatementImportProfiles(c *fiber.Ctx) error {
+	profiles, err := h.service.StatementImportProfiles(c.Context())
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, profiles, nil)
+}
+
+func (h *Handler) CreateStatementImportProfile(c *fiber.Ctx) error {
+	var payload StatementImportProfile
+	if err := c.BodyParser(&payload); err != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+	created, err := h.service.CreateStatementImportProfile(c.Context(), &payload)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.SuccessWithStatus(c, fiber.StatusCreated, created, nil)
+}
+
+func (h *Handler) DeleteStatementImportProfile(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.service.DeleteStatementImportProfile(c.Context(), id); err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, fiber.Map{"id": id, "status": "deleted"}, nil)
+}
+
+func (h *Handler) IngestSMSMessages(c *fiber.Ctx) error {
+	var payload struct {
+		SMSInput
+		Messages []SMSInput `json:"messages"`
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+	inputs := payload.Messages
+	if len(inputs) == 0 && payload.Text != "" {
+		inputs = []SMSInput{payload.SMSInput}
+	}
+	messages, err := h.service.IngestSMSMessages(c.Context(), inputs)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.SuccessWithStatus(c, fiber.StatusCreated, messages, nil)
+}
+
+func (h *Handler) SMSMessages(c *fiber.Ctx) error {
+	messages, err := h.service.SMSMessages(c.Context(), c.Query("status"))
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, messages, nil)
+}
+
+func (h *Handler) ReprocessSMSMessage(c *fiber.Ctx) error {
+	message, err := h.service.ReprocessSMSMessage(c.Context(), c.Params("id"))
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, message, nil)
+}
+
+func (h *Handler) SMSTemplates(c *fiber.Ctx) error {
+	return response.Success(c, fiber.Map{"banks": h.service.SMSTemplateBanks()}, nil)
+}
+
+func (h *Handler) SMSCardLinks(c *fiber.Ctx) error {
+	links, err := h.service.SMSCardLinks(c.Context())
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, links, nil)
+}
+
+func (h *Handler) CreateSMSCardLink(c *fiber.Ctx) error {
+	var payload SMSCardLink
+	if err := c.BodyParser(&payload); err != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+	link, err := h.service.CreateSMSCardLink(c.Context(), &payload)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.SuccessWithStatus(c, fiber.StatusCreated, link, nil)
+}
+
+func (h *Handler) DeleteSMSCardLink(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.service.DeleteSMSCardLink(c.Context(), id); err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, fiber.Map{"id": id, "status": "deleted"}, nil)
+}
+
+func (h *Handler) CategorizationRules(c *fiber.Ctx) error {
+	rules, err := h.service.CategorizationRules(c.Context())
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, rules, nil)
+}
+
+func (h *Handler) GetCategorizationRule(c *fiber.Ctx) error {
+	rule, err := h.service.GetCategorizationRule(c.Context(), c.Params("id"))
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, rule, nil)
+}
+
+func (h *Handler) CreateCategorizationRule(c *fiber.Ctx) error {
+	var payload CategorizationRule
+	if err := c.BodyParser(&payload); err != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+	rule, err := h.service.CreateCategorizationRule(c.Context(), &payload)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.SuccessWithStatus(c, fiber.StatusCreated, rule, nil)
+}
+
+func (h *Handler) UpdateCategorizationRule(c *fiber.Ctx) error {
+	var payload CategorizationRule
+	if err := c.BodyParser(&payload); err != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+	rule, err := h.service.UpdateCategorizationRule(c.Context(), c.Params("id"), &payload)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, rule, nil)
+}
+
+func (h *Handler) DeleteCategorizationRule(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.service.DeleteCategorizationRule(c.Context(), id); err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, fiber.Map{"id": id, "status": "deleted"}, nil)
+}
+
+func (h *Handler) DryRunCategorizationRule(c *fiber.Ctx) error {
+	var payload CategorizationRule
+	if err := c.BodyParser(&payload); err != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+	page, limit, pageErr := utils.ParsePaginationParams(c.Query("page"), c.Query("limit"))
+	if pageErr != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+	result, err := h.service.DryRunCategorizationRule(c.Context(), &payload, c.QueryBool("overwrite"), page, limit)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, result, &response.Meta{Page: page, Limit: limit, Total: result.Matched, TotalPages: utils.TotalPages(result.Matched, limit)})
+}
+
+func (h *Handler) ApplyCategorizationRule(c *fiber.Ctx) error {
+	page, limit, pageErr := utils.ParsePaginationParams(c.Query("page"), c.Query("limit"))
+	if pageErr != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+	result, err := h.service.ApplyCategorizationRule(c.Context(), c.Params("id"), c.QueryBool("overwrite"), page, limit)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, result, &response.Meta{Page: page, Limit: limit, Total: result.Matched, TotalPages: utils.TotalPages(result.Matched, limit)})
+}
+
+func (h *Handler) UploadAttachment(c *fiber.Ctx) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return response.Failure(c, appErrors.WithDetails(appErrors.InvalidAttachment, map[string]interface{}{"field": "file"}))
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return response.Failure(c, appErrors.InvalidAttachment)
+	}
+	defer file.Close()
+	// One byte over the limit is enough for the service to reject the file.
+	data, err := io.ReadAll(io.LimitReader(file, h.service.AttachmentMaxFileBytes()+1))
+	if err != nil {
+		return response.Failure(c, appErrors.InvalidAttachment)
+	}
+	attachment, err := h.service.UploadAttachment(c.Context(), AttachmentUpload{
+		FileName: fileHeader.Filename,
+		Data:     data,
+	})
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.SuccessWithStatus(c, fiber.StatusCreated, attachment, nil)
+}
+
+func (h *Handler) Attachments(c *fiber.Ctx) error {
+	attachments, err := h.service.Attachments(c.Context())
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, attachments, nil)
+}
+
+func (h *Handler) AttachmentUsage(c *fiber.Ctx) error {
+	usage, err := h.service.AttachmentUsage(c.Context())
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, usage, nil)
+}
+
+func (h *Handler) GetAttachment(c *fiber.Ctx) error {
+	attachment, err := h.service.GetAttachment(c.Context(), c.Params("id"))
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, attachment, nil)
+}
+
+func (h *Handler) DeleteAttachment(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.service.DeleteAttachment(c.Context(), id); err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, fiber.Map{"id": id, "status": "deleted"}, nil)
+}
+
+func (h *Handler) AttachToTransaction(c *fiber.Ctx) error {
+	txn, err := h.service.AttachToTransaction(c.Context(), c.Params("id"), c.Params("attachmentId"))
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, txn, nil)
+}
+
+func (h *Handler) DetachFromTransaction(c *fiber.Ctx) error {
+	txn, err := h.service.DetachFromTransaction(c.Context(), c.Params("id"), c.Params("attachmentId"))
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, txn, nil)
+}
+
+func (h *Handler) Reconciliations(c *fiber.Ctx) error {
+	reconciliations, err := h.service.Reconciliations(c.Context(), c.Params("id"))
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, reconciliations, nil)
+}
+
+func (h *Handler) StartReconciliation(c *fiber.Ctx) error {
+	var payload ReconciliationInput
+	if err := c.BodyParser(&payload); err != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+	reconciliation, err := h.service.StartReconciliation(c.Context(), c.Params("id"), payload)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.SuccessWithStatus(c, fiber.StatusCreated, reconciliation, nil)
+}
+
+func (h *Handler) GetReconciliation(c *fiber.Ctx) error {
+	reconciliation, err := h.service.GetReconciliation(c.Context(), c.Params("id"))
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, reconciliation, nil)
+}
+
+func (h *Handler) SelectReconciliationTransactions(c *fiber.Ctx) error {
+	var payload ReconciliationSelection
+	if err := c.BodyParser(&payload); err != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+	reconciliation, err := h.service.SelectReconciliationTransactions(c.Context(), c.Params("id"), payload)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, reconciliation, nil)
+}
+
+func (h *Handler) CompleteReconciliation(c *fiber.Ctx) error {
+	reconciliation, err := h.service.CompleteReconciliation(c.Context(), c.Params("id"))
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, reconciliation, nil)
+}
+
+func (h *Handler) CancelReconciliation(c *fiber.Ctx) error {
+	reconciliation, err := h.service.CancelReconciliation(c.Context(), c.Params("id"))
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, reconciliation, nil)
+}
+
+func (h *Handler) NetWorth(c *fiber.Ctx) error {
+	series, err := h.service.NetWorth(c.Context(), NetWorthQuery{
+		From:         c.Query("from"),
+		To:           c.Query("to"),
+		Granularity:  c.Query("granularity"),
+		BaseCurrency: c.Query("baseCurrency"),
+	})
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, series, nil)
+}
+
+func (h *Handler) ScheduledTransactions(c *fiber.Ctx) error {
+	items, err := h.service.ScheduledTransactions(c.Context())
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, items, nil)
+}
+
+func (h *Handler) ConfirmScheduledTransaction(c *fiber.Ctx) error {
+	txn, err := h.service.ConfirmScheduledTransaction(c.Context(), c.Params("id"))
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, txn, nil)
+}
+
+func (h *Handler) SkipScheduledTransaction(c *fiber.Ctx) error {
+	txn, err := h.service.SkipScheduledTransaction(c.Context(), c.Params("id"))
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, txn, nil)
+}
+
+func (h *Handler) RescheduleTransaction(c *fiber.Ctx) error {
+	var payload struct {
+		Date string `json:"date"`
+	}
+	if err := c.BodyParser(&payload); err != nil || payload.Date == "" {
+		return response.Failure(c, appErrors.WithDetails(appErrors.InvalidTransactionDate, map[string]interface{}{"field": "date"}))
+	}
+	txn, err := h.service.RescheduleTransaction(c.Context(), c.Params("id"), payload.Date)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, txn, nil)
+}
+
+func (h *Handler) CreditStatements(c *fiber.Ctx) error {
+	statements, err := h.service.CreditStatements(c.Context(), c.Params("id"))
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, statements, nil)
+}
+
+func (h *Handler) PayCreditStatement(c *fiber.Ctx) error {
+	var payload CreditPaymentInput
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&payload); err != nil {
+			return response.Failure(c, appErrors.InvalidFinanceData)
+		}
+	}
+	payment, err := h.service.PayCreditStatement(c.Context(), c.Params("id"), c.Params("statementId"), payload)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.SuccessWithStatus(c, fiber.StatusCreated, payment, nil)
+}
+
+func (h *Handler) AddAccountCurrency(c *fiber.Ctx) error {
+	var payload AccountBalance
+	if err := c.BodyParser(&payload); err != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+	account, err := h.service.AddAccountCurrency(c.Context(), c.Params("id"), payload)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.SuccessWithStatus(c, fiber.StatusCreated, account, nil)
+}
+
+func (h *Handler) ExchangeCurrency(c *fiber.Ctx) error {
+	var payload CurrencyExchangeInput
+	if err := c.BodyParser(&payload); err != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+	exchange, err := h.service.ExchangeCurrency(c.Context(), c.Params("id"), payload)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.SuccessWithStatus(c, fiber.StatusCreated, exchange, nil)
+}
+
+func (h *Handler) MergeAccount(c *fiber.Ctx) error {
+	result, err := h.service.MergeAccount(c.Context(), c.Params("id"), c.Params("targetId"))
+	if err != nil {
+		log.Printf("[Handler.MergeAccount] Error for account=%s target=%s: %v", c.Params("id"), c.Params("targetId"), err)
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, result, nil)
+}
+
+func (h *Handler) GetFXRates(c *fiber.Ctx) error {
+	from := strings.ToUpper(c.Query("from"))
+	to := strings.ToUpper(c.Query("to"))
+	date := c.Query("date")
+	rates, err := h.service.FXRates(c.Context())
+	if err != nil {
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	if from != "" || to != "" || date != "" {
+		for _, rate := range rates {
+			if from != "" && rate.FromCurrency != from {
+				continue
+			}
+			if to != "" && rate.ToCurrency != to {
+				continue
+			}
+			if date != "" && rate.Date != date {
+				continue
+			}
+			return response.Success(c, rate, nil)
+		}
+		return response.Failure(c, appErrors.FXRateNotFound)
+	}
+	return response.Success(c, rates, nil)
+}
+
+func (h *Handler) ResolveFXRate(c *fiber.Ctx) error {
+	from := c.Query("from")
+	to := c.Query("to")
+	if strings.TrimSpace(from) == "" || strings.TrimSpace(to) == "" {
+		return response.Failure(c, appErrors.InvalidCurrency)
+	}
+	resolution, err := h.service.ResolveFXRate(c.Context(), from, to, c.Query("date"))
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, resolution, nil)
+}
+
+func (h *Handler) FXRateStatuses(c *fiber.Ctx) error {
+	statuses, err := h.service.FXRateStatuses(c.Context(), time.Now().UTC())
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, statuses, nil)
+}
+
+func (h *Handler) CreateFXRate(c *fiber.Ctx) error {
+	var payload FXRate
+	if err := c.BodyParser(&payload); err != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+	if payload.FromCurrency == "" || payload.ToCurrency == "" || payload.Date == "" {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+	created, err := h.service.CreateFXRate(c.Context(), &payload)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, created, nil)
+}
+
+func (h *Handler) SupportedCurrencies(c *fiber.Ctx) error {
+	defaultCurrencies := []string{"USD", "EUR", "UZS", "RUB"}
+	catalog := map[string]SupportedCurrency{
+		"USD": {Code: "USD", Symbol: "$", Name: "US Dollar", Decimals: 2},
+		"EUR": {Code: "EUR", Symbol: "€", Name: "Euro", Decimals: 2},
+		"GBP": {Code: "GBP", Symbol: "£", Name: "British Pound", Decimals: 2},
+		"UZS": {Code: "UZS", Symbol: "сум", Name: "Uzbek Som", Decimals: 0},
+		"RUB": {Code: "RUB", Symbol: "₽", Name: "Russian Ruble", Decimals: 2},
+		"JPY": {Code: "JPY", Symbol: "¥", Name: "Japanese Yen", Decimals: 0},
+		"CNY": {Code: "CNY", Symbol: "¥", Name: "Chinese Yuan", Decimals: 2},
+		"CHF": {Code: "CHF", Symbol: "Fr", Name: "Swiss Franc", Decimals: 2},
+		"CAD": {Code: "CAD", Symbol: "C$", Name: "Canadian Dollar", Decimals: 2},
+		"AUD": {Code: "AUD", Symbol: "A$", Name: "Australian Dollar", Decimals: 2},
+		"TRY": {Code: "TRY", Symbol: "₺", Name: "Turkish Lira", Decimals: 2},
+		"SAR": {Code: "SAR", Symbol: "﷼", Name: "Saudi Riyal", Decimals: 2},
+		"AED": {Code: "AED", Symbol: "د.إ", Name: "UAE Dirham", Decimals: 2},
+		"USDT": {Code: "USDT", Symbol: "₮", Name: "Tether", Decimals: 2},
+	}
+	rates, _ := h.service.FXRates(c.Context())
+	seen := make(map[string]bool)
+	for _, currency := range defaultCurrencies {
+		seen[currency] = true
+	}
+	for _, rate := range rates {
+		seen[rate.FromCurrency] = true
+		seen[rate.ToCurrency] = true
+	}
+	list := make([]SupportedCurrency, 0, len(seen))
+	for currency := range seen {
+		code := strings.ToUpper(currency)
+		if item, ok := catalog[code]; ok {
+			list = append(list, item)
+			continue
+		}
+		decimals := 2
+		if code == "UZS" {
+			decimals = 0
+		}
+		list = append(list, SupportedCurrency{
+			Code:     code,
+			Symbol:   code,
+			Name:     code,
+			Decimals: decimals,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Code < list[j].Code
+	})
+	return response.Success(c, list, nil)
+}
+
+func (h *Handler) Categories(c *fiber.Ctx) error {
+	categoryType := c.Query("type")
+	activeOnly := c.Query("active") != "false"
+	categories, err := h.service.Categories(c.Context(), categoryType, activeOnly)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, categories, nil)
+}
+
+func (h *Handler) QuickExpenseCategories(c *fiber.Ctx) error {
+	categoryType := c.Query("type")
+	categories, err := h.service.QuickExpenseCategories(c.Context(), categoryType)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, categories, nil)
+}
+
+func (h *Handler) UpdateQuickExpenseCategories(c *fiber.Ctx) error {
+	type payload struct {
+		Type       string                 `json:"type"`
+		Categories []QuickExpenseCategory `json:"categories"`
+	}
+	var req payload
+	if err := c.BodyParser(&req); err != nil {
+		return response.Failure(c, appErrors.InvalidFinanceData)
+	}
+	// Convert []QuickExpenseCategory to []*QuickExpenseCategory
+	categoryPtrs := make([]*QuickExpenseCategory, len(req.Categories))
+	for i := range req.Categories {
+		categoryPtrs[i] = &req.Categories[i]
+	}
+	updated, err := h.service.UpdateQuickExpenseCategories(c.Context(), req.Type, categoryPtrs)
+	if err != nil {
+		if typed, ok := err.(*appErrors.Error); ok {
+			return response.Failure(c, typed)
+		}
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	return response.Success(c, updated, nil)
 }
 
 func (h *Handler) CreateCategory(c *fiber.Ctx) error {
@@ -1220,7 +2212,19 @@ func validateAccount(account *Account) error {
 	return nil
 }
 
-func parseAccountIDs(raw string) []string {
+func parseMoneyQuery(c *fiber.Ctx, field string) (*Money, *appErrors.Error) {
+	raw := strings.TrimSpace(c.Query(field))
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := ParseMoney(raw)
+	if err != nil {
+		return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": field})
+	}
+	return &value, nil
+}
+
+func parseQueryList(raw string) []string {
 	if strings.TrimSpace(raw) == "" {
 		return nil
 	}
@@ -1272,6 +2276,16 @@ func validateTransaction(txn *Transaction) error {
 	return nil
 }
 
+// parseAsOfQuery reads an optional YYYY-MM-DD asOf query value, defaulting
+// to now.
+func parseAsOfQuery(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Now().UTC(), nil
+	}
+	return time.Parse("2006-01-02", raw)
+}
+
 func validateBudget(budget *Budget) error {
 	if strings.TrimSpace(budget.Name) == "" {
 		return errors.New("name required")
@@ -1279,6 +2293,16 @@ func validateBudget(budget *Budget) error {
 	if strings.TrimSpace(budget.Currency) == "" {
 		return errors.New("currency required")
 	}
+	switch budget.RolloverMode {
+	case "", "carryover", BudgetRolloverNone, BudgetRolloverRemainder, BudgetRolloverOverspend, BudgetRolloverBoth:
+	default:
+		return errors.New("invalid rolloverMode")
+	}
+	for _, threshold := range budget.AlertThresholds {
+		if threshold <= 0 {
+			return errors.New("alertThresholds must be positive percentages")
+		}
+	}
 	return nil
 }
 
@@ -1313,6 +2337,12 @@ func validateDebt(debt *Debt) error {
 	if debt.PrincipalAmount <= 0 {
 		return appErrors.WithDetails(appErrors.InvalidDebtAmount, map[string]interface{}{"field": "principalAmount"})
 	}
+	if debt.InterestMode != nil && !isValidDebtInterestMode(*debt.InterestMode) {
+		return appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "interestMode"})
+	}
+	if debt.InterestRateAnnual < 0 {
+		return appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "interestRateAnnual"})
+	}
 
 	// Validate due date is after start date if provided
 	if debt.DueDate != nil && strings.TrimSpace(*debt.DueDate) != "" {
@@ -0,0 +1,325 @@
+package finance
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	appErrors "github.com/leora/leora-server/internal/errors"
+)
+
+const (
+	LedgerEntityAccount = "account"
+	LedgerEntityBudget  = "budget"
+	LedgerEntityDebt    = "debt"
+)
+
+// ledgerRepairReferenceType marks the adjustment a ledger repair posts; its
+// ReferenceID is the audit record id.
+const ledgerRepairReferenceType = "ledger_repair"
+
+// LedgerCheckOptions scopes an integrity run. An empty UserID checks every
+// user that owns accounts, budgets or debts. RepairedBy names who asked for
+// the repair in the audit trail.
+type LedgerCheckOptions struct {
+	UserID     string
+	Repair     bool
+	RepairedBy string
+}
+
+// LedgerDrift is one stored figure that disagrees with what its source rows
+// add up to. Difference is Stored minus Expected.
+type LedgerDrift struct {
+	EntityType string        `json:"entityType"`
+	EntityID   string        `json:"entityId"`
+	Name       string        `json:"name"`
+	Field      string        `json:"field"`
+	Currency   string        `json:"currency"`
+	Stored     Money         `json:"stored"`
+	Expected   Money         `json:"expected"`
+	Difference Money         `json:"difference"`
+	Repair     *LedgerRepair `json:"repair,omitempty"`
+}
+
+type LedgerUserReport struct {
+	UserID          string         `json:"userId"`
+	AccountsChecked int            `json:"accountsChecked"`
+	BudgetsChecked  int            `json:"budgetsChecked"`
+	DebtsChecked    int            `json:"debtsChecked"`
+	Drifts          []*LedgerDrift `json:"drifts"`
+}
+
+// LedgerReport lists only the users with drift; UsersChecked counts all of
+// them.
+type LedgerReport struct {
+	CheckedAt     string              `json:"checkedAt"`
+	Repair        bool                `json:"repair"`
+	UsersChecked  int                 `json:"usersChecked"`
+	DriftCount    int                 `json:"driftCount"`
+	RepairedCount int                 `json:"repairedCount"`
+	Users         []*LedgerUserReport `json:"users"`
+}
+
+// LedgerRepair is the audit record of one repaired drift.
+type LedgerRepair struct {
+	ID                      string  `json:"id"`
+	UserID                  string  `json:"userId"`
+	EntityType              string  `json:"entityType"`
+	EntityID                string  `json:"entityId"`
+	Field                   string  `json:"field"`
+	Currency                string  `json:"currency"`
+	StoredAmount            Money   `json:"storedAmount"`
+	ExpectedAmount          Money   `json:"expectedAmount"`
+	AdjustmentTransactionID *string `json:"adjustmentTransactionId,omitempty"`
+	RepairedBy              string  `json:"repairedBy"`
+	CreatedAt               string  `json:"createdAt,omitempty"`
+}
+
+// CheckLedger recomputes account balances, budget rollups and debt totals
+// from their source rows and reports where the stored figures drifted. A
+// budget is rolled up the way a budget read does it, over its current window.
+//
+// With Repair set, an account keeps its stored balance — it is what the user
+// has been seeing and reconciling against — and a system adjustment is posted
+// so the ledger explains the difference. Budget and debt figures are pure
+// rollups and are rewritten from the source rows. Every repair leaves a
+// LedgerRepair record.
+func (s *Service) CheckLedger(ctx context.Context, options LedgerCheckOptions) (*LedgerReport, error) {
+	userIDs := []string{options.UserID}
+	if options.UserID == "" {
+		var err error
+		userIDs, err = s.repo.ListLedgerUserIDs(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if options.RepairedBy == "" {
+		options.RepairedBy = "system"
+	}
+	report := &LedgerReport{
+		CheckedAt: time.Now().UTC().Format(time.RFC3339),
+		Repair:    options.Repair,
+		Users:     make([]*LedgerUserReport, 0),
+	}
+	for _, userID := range userIDs {
+		userCtx := context.WithValue(ctx, "user_id", userID)
+		userReport, err := s.checkUserLedger(userCtx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if options.Repair && len(userReport.Drifts) > 0 {
+			s.repairUserLedger(userCtx, userID, userReport.Drifts, options.RepairedBy)
+			s.invalidateFinanceSummaryCache(userCtx)
+		}
+		report.UsersChecked++
+		report.DriftCount += len(userReport.Drifts)
+		for _, drift := range userReport.Drifts {
+			if drift.Repair != nil {
+				report.RepairedCount++
+			}
+		}
+		if len(userReport.Drifts) > 0 {
+			report.Users = append(report.Users, userReport)
+		}
+	}
+	return report, nil
+}
+
+func (s *Service) checkUserLedger(ctx context.Context, userID string) (*LedgerUserReport, error) {
+	report := &LedgerUserReport{UserID: userID, Drifts: make([]*LedgerDrift, 0)}
+	addDrift := func(entityType, entityID, name, field, currency string, stored, expected Money) {
+		if stored == expected {
+			return
+		}
+		report.Drifts = append(report.Drifts, &LedgerDrift{
+			EntityType: entityType,
+			EntityID:   entityID,
+			Name:       name,
+			Field:      field,
+			Currency:   currency,
+			Stored:     stored,
+			Expected:   expected,
+			Difference: stored - expected,
+		})
+	}
+
+	transactions, err := s.repo.ListTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := s.repo.ListAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		report.AccountsChecked++
+		addDrift(LedgerEntityAccount, account.ID, account.Name, "currentBalance", account.Currency,
+			account.CurrentBalance, computeAccountBalance(account, transactions))
+	}
+
+	budgets, err := s.repo.ListBudgets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, budget := range budgets {
+		report.BudgetsChecked++
+		normalizeBudget(budget)
+		rolled := *budget
+		applyBudgetRollups(&rolled, transactions)
+		addDrift(LedgerEntityBudget, budget.ID, budget.Name, "spentAmount", budget.Currency,
+			budget.SpentAmount, rolled.SpentAmount)
+		addDrift(LedgerEntityBudget, budget.ID, budget.Name, "remainingAmount", budget.Currency,
+			budget.RemainingAmount, rolled.RemainingAmount)
+	}
+
+	debts, err := s.repo.ListDebts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, debt := range debts {
+		report.DebtsChecked++
+		payments, err := s.repo.ListDebtPayments(ctx, debt.ID)
+		if err != nil {
+			return nil, err
+		}
+		totalPaid, remaining, paid := debtStoredTotals(debt, payments)
+		addDrift(LedgerEntityDebt, debt.ID, debt.CounterpartyName, "totalPaid", debt.PrincipalCurrency,
+			debt.TotalPaid, totalPaid)
+		// Payment writes read a non-positive remaining as the untouched
+		// principal, so a debt nobody has paid yet may still store zero.
+		if paid != 0 || debt.RemainingAmount > 0 {
+			addDrift(LedgerEntityDebt, debt.ID, debt.CounterpartyName, "remainingAmount", debt.PrincipalCurrency,
+				debt.RemainingAmount, remaining)
+		}
+	}
+	return report, nil
+}
+
+// debtStoredTotals returns the totals the payment writes keep on a debt row:
+// paid is clamped to the principal and remaining never goes below zero. The
+// third value is the unclamped sum of payments in the debt currency.
+func debtStoredTotals(debt *Debt, payments []*DebtPayment) (Money, Money, Money) {
+	paid := Money(0)
+	for _, payment := range payments {
+		if payment.DebtID != debt.ID {
+			continue
+		}
+		amount := payment.ConvertedAmountToDebt
+		if amount == 0 && payment.RateUsedToDebt > 0 {
+			amount = exchangeRateFromFloat(payment.RateUsedToDebt).Convert(payment.Amount, debt.PrincipalCurrency)
+		}
+		paid += amount
+	}
+	totalPaid := max(paid, 0)
+	if debt.PrincipalAmount > 0 && totalPaid > debt.PrincipalAmount {
+		totalPaid = debt.PrincipalAmount
+	}
+	return totalPaid, max(debt.PrincipalAmount-paid, 0), paid
+}
+
+// repairUserLedger fixes each drifting entity once and records every drifted
+// field. A failed repair is logged and left unrepaired in the report so the
+// rest of the run still goes through.
+func (s *Service) repairUserLedger(ctx context.Context, userID string, drifts []*LedgerDrift, repairedBy string) {
+	fixed := make(map[string]bool)
+	for _, drift := range drifts {
+		key := drift.EntityType + ":" + drift.EntityID
+		repair := &LedgerRepair{
+			ID:             uuid.NewString(),
+			UserID:         userID,
+			EntityType:     drift.EntityType,
+			EntityID:       drift.EntityID,
+			Field:          drift.Field,
+			Currency:       drift.Currency,
+			StoredAmount:   drift.Stored,
+			ExpectedAmount: drift.Expected,
+			RepairedBy:     repairedBy,
+		}
+		var adjustment *Transaction
+		var err error
+		switch drift.EntityType {
+		case LedgerEntityAccount:
+			adjustment = ledgerRepairAdjustment(drift, repair.ID)
+		case LedgerEntityBudget:
+			if !fixed[key] {
+				err = s.repairBudgetRollups(ctx, drift.EntityID)
+			}
+		case LedgerEntityDebt:
+			if !fixed[key] {
+				err = s.repairDebtTotals(ctx, drift.EntityID)
+			}
+		}
+		if err == nil {
+			fixed[key] = true
+			err = s.repo.RecordLedgerRepair(ctx, repair, adjustment)
+		}
+		if err != nil {
+			if err == appErrors.LedgerDriftChanged {
+				log.Printf("[Service.CheckLedger] %s %s changed during the check; not repaired", drift.EntityType, drift.EntityID)
+			} else {
+				log.Printf("[Service.CheckLedger] Repair failed for %s %s: %v", drift.EntityType, drift.EntityID, err)
+			}
+			continue
+		}
+		drift.Repair = repair
+	}
+}
+
+// ledgerRepairAdjustment books an account's drift into the ledger. The
+// stored balance already includes it, so the repository posts the entry
+// without moving the balance again.
+func ledgerRepairAdjustment(drift *LedgerDrift, repairID string) *Transaction {
+	accountID := drift.EntityID
+	referenceType := ledgerRepairReferenceType
+	referenceID := repairID
+	name := "Ledger repair"
+	adjustment := &Transaction{
+		Type:                TransactionTypeSystemAdjustment,
+		Status:              TransactionStatusCompleted,
+		AccountID:           &accountID,
+		ReferenceType:       &referenceType,
+		ReferenceID:         &referenceID,
+		Amount:              drift.Difference,
+		Currency:            drift.Currency,
+		BaseCurrency:        drift.Currency,
+		Name:                &name,
+		Date:                time.Now().UTC().Format("2006-01-02"),
+		IsBalanceAdjustment: true,
+		ShowStatus:          "active",
+	}
+	normalizeTransaction(adjustment)
+	return adjustment
+}
+
+func (s *Service) repairBudgetRollups(ctx context.Context, budgetID string) error {
+	budget, err := s.repo.GetBudgetByID(ctx, budgetID)
+	if err != nil {
+		return err
+	}
+	transactions, err := s.repo.ListTransactions(ctx)
+	if err != nil {
+		return err
+	}
+	normalizeBudget(budget)
+	applyBudgetRollups(budget, transactions)
+	return s.repo.UpdateBudgetRollups(ctx, budget)
+}
+
+func (s *Service) repairDebtTotals(ctx context.Context, debtID string) error {
+	debt, err := s.repo.GetDebtByID(ctx, debtID)
+	if err != nil {
+		return err
+	}
+	payments, err := s.repo.ListDebtPayments(ctx, debtID)
+	if err != nil {
+		return err
+	}
+	debt.TotalPaid, debt.RemainingAmount, _ = debtStoredTotals(debt, payments)
+	debt.PercentPaid = 0
+	if debt.PrincipalAmount > 0 {
+		debt.PercentPaid = min(debt.TotalPaid.Ratio(debt.PrincipalAmount)*100, 100)
+	}
+	return s.repo.UpdateDebt(ctx, debt)
+}
@@ -17,31 +17,37 @@ const (
 	TransactionTypeBudgetAddValue          = "budget_add_value"
 	TransactionTypeDebtAddValue            = "debt_add_value"
 	TransactionTypeDebtFullPayment         = "debt_full_payment"
+	TransactionTypeReversal                = "reversal"
 )
 
 const (
 	TransactionStatusPending   = "pending"
 	TransactionStatusCompleted = "completed"
 	TransactionStatusFailed    = "failed"
+	TransactionStatusVoided    = "voided"
 )
 
 // Account represents a financial account.
 type Account struct {
-	ID             string  `json:"id"`
-	UserID         string  `json:"userId"`
-	Name           string  `json:"name"`
-	AccountType    string  `json:"accountType"`
-	Currency       string  `json:"currency"`
-	InitialBalance float64 `json:"initialBalance"`
-	CurrentBalance float64 `json:"currentBalance"`
-	LinkedGoalID   *string `json:"linkedGoalId,omitempty"`
-	CustomTypeID   *string `json:"customTypeId,omitempty"`
-	IsMain         bool    `json:"isMain"`
-	IsArchived     bool    `json:"isArchived"`
-	ShowStatus     string  `json:"showStatus"`
-	CreatedAt      string  `json:"createdAt,omitempty"`
-	UpdatedAt      string  `json:"updatedAt,omitempty"`
-	DeletedAt      string  `json:"-"`
+	ID               string           `json:"id"`
+	UserID           string           `json:"userId"`
+	Name             string           `json:"name"`
+	AccountType      string           `json:"accountType"`
+	Currency         string           `json:"currency"`
+	InitialBalance   Money            `json:"initialBalance"`
+	CurrentBalance   Money            `json:"currentBalance"`
+	ProjectedBalance Money            `json:"projectedBalance"`
+	Credit           *CreditTerms     `json:"credit,omitempty"`
+	ParentAccountID  *string          `json:"parentAccountId,omitempty"`
+	Balances         []AccountBalance `json:"balances,omitempty"`
+	LinkedGoalID     *string          `json:"linkedGoalId,omitempty"`
+	CustomTypeID     *string          `json:"customTypeId,omitempty"`
+	IsMain           bool             `json:"isMain"`
+	IsArchived       bool             `json:"isArchived"`
+	ShowStatus       string           `json:"showStatus"`
+	CreatedAt        string           `json:"createdAt,omitempty"`
+	UpdatedAt        string           `json:"updatedAt,omitempty"`
+	DeletedAt        string           `json:"-"`
 }
 
 // Transaction models a ledger entry.
@@ -55,15 +61,15 @@ type Transaction struct {
 	ToAccountID           *string                `json:"toAccountId,omitempty"`
 	ReferenceType         *string                `json:"referenceType,omitempty"`
 	ReferenceID           *string                `json:"referenceId,omitempty"`
-	Amount                float64                `json:"amount"`
+	Amount                Money                  `json:"amount"`
 	Currency              string                 `json:"currency"`
 	BaseCurrency          string                 `json:"baseCurrency"`
 	RateUsedToBase        float64                `json:"rateUsedToBase"`
-	ConvertedAmountToBase float64                `json:"convertedAmountToBase"`
-	ToAmount              float64                `json:"toAmount"`
+	ConvertedAmountToBase Money                  `json:"convertedAmountToBase"`
+	ToAmount              Money                  `json:"toAmount"`
 	ToCurrency            *string                `json:"toCurrency,omitempty"`
 	EffectiveRateFromTo   float64                `json:"effectiveRateFromTo"`
-	FeeAmount             float64                `json:"feeAmount"`
+	FeeAmount             Money                  `json:"feeAmount"`
 	FeeCategoryID         *string                `json:"feeCategoryId,omitempty"`
 	CategoryID            *string                `json:"categoryId,omitempty"`
 	SubcategoryID         *string                `json:"subcategoryId,omitempty"`
@@ -79,6 +85,10 @@ type Transaction struct {
 	RecurringID           *string                `json:"recurringId,omitempty"`
 	Attachments           []string               `json:"attachments"`
 	Tags                  []string               `json:"tags"`
+	Splits                []TransactionSplit     `json:"splits,omitempty"`
+	ReconciliationID      *string                `json:"reconciliationId,omitempty"`
+	ClearedAt             *string                `json:"clearedAt,omitempty"`
+	IsScheduled           bool                   `json:"isScheduled"`
 	IsBalanceAdjustment   bool                   `json:"isBalanceAdjustment"`
 	SkipBudgetMatching    bool                   `json:"skipBudgetMatching"`
 	ShowStatus            string                 `json:"showStatus"`
@@ -92,13 +102,116 @@ type Transaction struct {
 	RelatedDebtID    *string `json:"relatedDebtId,omitempty"`
 	GoalName         *string `json:"goalName,omitempty"`
 	GoalType         *string `json:"goalType,omitempty"`
-	PlannedAmount    float64 `json:"plannedAmount"`
-	PaidAmount       float64 `json:"paidAmount"`
+	PlannedAmount    Money   `json:"plannedAmount"`
+	PaidAmount       Money   `json:"paidAmount"`
 	OriginalCurrency *string `json:"originalCurrency,omitempty"`
-	OriginalAmount   float64 `json:"originalAmount"`
+	OriginalAmount   Money   `json:"originalAmount"`
 	ConversionRate   float64 `json:"conversionRate"`
 }
 
+// TransactionSplit is one line of a split income or expense. When an entry
+// has lines they carry its classification instead of the parent's category,
+// subcategory and budget, and their amounts sum to the parent amount.
+type TransactionSplit struct {
+	ID            string  `json:"id"`
+	Amount        Money   `json:"amount"`
+	CategoryID    *string `json:"categoryId,omitempty"`
+	SubcategoryID *string `json:"subcategoryId,omitempty"`
+	BudgetID      *string `json:"budgetId,omitempty"`
+	Note          *string `json:"note,omitempty"`
+}
+
+// TransactionReversalInput describes a reversal or correction request.
+type TransactionReversalInput struct {
+	Date        string
+	Note        *string
+	Replacement *Transaction
+}
+
+// TransactionReversal is the outcome of voiding a ledger entry.
+type TransactionReversal struct {
+	Voided     []*Transaction `json:"voided"`
+	Reversals  []*Transaction `json:"reversals"`
+	Correction *Transaction   `json:"correction,omitempty"`
+}
+
+const (
+	RecurrenceFrequencyDaily   = "daily"
+	RecurrenceFrequencyWeekly  = "weekly"
+	RecurrenceFrequencyMonthly = "monthly"
+	RecurrenceFrequencyYearly  = "yearly"
+	RecurrenceFrequencyCustom  = "custom"
+)
+
+const (
+	RecurringPostingAuto    = "auto"
+	RecurringPostingConfirm = "confirm"
+)
+
+const (
+	RecurringStatusActive    = "active"
+	RecurringStatusPaused    = "paused"
+	RecurringStatusCompleted = "completed"
+)
+
+const (
+	RecurringOccurrencePending = "pending"
+	RecurringOccurrencePosted  = "posted"
+	RecurringOccurrenceSkipped = "skipped"
+	RecurringOccurrenceFailed  = "failed"
+)
+
+// RecurringTransaction is a template that materializes ledger entries on a schedule.
+type RecurringTransaction struct {
+	ID                   string   `json:"id"`
+	UserID               string   `json:"userId"`
+	Name                 string   `json:"name"`
+	Type                 string   `json:"type"`
+	AccountID            *string  `json:"accountId,omitempty"`
+	FromAccountID        *string  `json:"fromAccountId,omitempty"`
+	ToAccountID          *string  `json:"toAccountId,omitempty"`
+	Amount               Money    `json:"amount"`
+	Currency             string   `json:"currency"`
+	ToAmount             Money    `json:"toAmount"`
+	ToCurrency           *string  `json:"toCurrency,omitempty"`
+	CategoryID           *string  `json:"categoryId,omitempty"`
+	SubcategoryID        *string  `json:"subcategoryId,omitempty"`
+	Description          *string  `json:"description,omitempty"`
+	BudgetID             *string  `json:"budgetId,omitempty"`
+	GoalID               *string  `json:"goalId,omitempty"`
+	CounterpartyID       *string  `json:"counterpartyId,omitempty"`
+	Tags                 []string `json:"tags"`
+	Frequency            string   `json:"frequency"`
+	Interval             int      `json:"interval"`
+	IntervalUnit         string   `json:"intervalUnit,omitempty"`
+	DayOfWeek            *int     `json:"dayOfWeek,omitempty"`
+	DayOfMonth           *int     `json:"dayOfMonth,omitempty"`
+	StartDate            string   `json:"startDate"`
+	EndDate              *string  `json:"endDate,omitempty"`
+	OccurrenceCount      *int     `json:"occurrenceCount,omitempty"`
+	OccurrencesGenerated int      `json:"occurrencesGenerated"`
+	NextOccurrenceDate   *string  `json:"nextOccurrenceDate,omitempty"`
+	LastOccurrenceDate   *string  `json:"lastOccurrenceDate,omitempty"`
+	PostingMode          string   `json:"postingMode"`
+	Status               string   `json:"status"`
+	CreatedAt            string   `json:"createdAt,omitempty"`
+	UpdatedAt            string   `json:"updatedAt,omitempty"`
+	DeletedAt            string   `json:"-"`
+}
+
+// RecurringOccurrence records one scheduled instance of a recurring transaction.
+type RecurringOccurrence struct {
+	ID            string  `json:"id"`
+	RecurringID   string  `json:"recurringId"`
+	UserID        string  `json:"userId"`
+	DueDate       string  `json:"dueDate"`
+	Status        string  `json:"status"`
+	TransactionID *string `json:"transactionId,omitempty"`
+	Error         *string `json:"error,omitempty"`
+	CreatedAt     string  `json:"createdAt,omitempty"`
+	UpdatedAt     string  `json:"updatedAt,omitempty"`
+}
+
 // Budget tracks spending goals.
 type Budget struct {
 	ID                string   `json:"id"`
@@ -110,18 +223,22 @@ type Budget struct {
 	AccountID         *string  `json:"accountId,omitempty"`
 	TransactionType   *string  `json:"transactionType,omitempty"`
 	Currency          string   `json:"currency"`
-	LimitAmount       float64  `json:"limitAmount"`
+	LimitAmount       Money    `json:"limitAmount"`
+	BaseLimit         Money    `json:"baseLimit"`
+	CarriedAmount     Money    `json:"carriedAmount"`
+	EffectiveLimit    Money    `json:"effectiveLimit"`
 	PeriodType        string   `json:"periodType"`
 	StartDate         *string  `json:"startDate,omitempty"`
 	EndDate           *string  `json:"endDate,omitempty"`
-	SpentAmount       float64  `json:"spentAmount"`
-	RemainingAmount   float64  `json:"remainingAmount"`
+	SpentAmount       Money    `json:"spentAmount"`
+	RemainingAmount   Money    `json:"remainingAmount"`
 	PercentUsed       float64  `json:"percentUsed"`
 	IsOverspent       bool     `json:"isOverspent"`
 	RolloverMode      string   `json:"rolloverMode"`
 	NotifyOnExceed    bool     `json:"notifyOnExceed"`
-	ContributionTotal float64  `json:"contributionTotal"`
-	CurrentBalance    float64  `json:"currentBalance"`
+	AlertThresholds   []int    `json:"alertThresholds"`
+	ContributionTotal Money    `json:"contributionTotal"`
+	CurrentBalance    Money    `json:"currentBalance"`
 	IsArchived        bool     `json:"isArchived"`
 	ShowStatus        string   `json:"showStatus"`
 	CreatedAt         string   `json:"createdAt,omitempty"`
@@ -129,61 +246,109 @@ type Budget struct {
 	DeletedAt         string   `json:"-"`
 }
 
+const (
+	BudgetRolloverNone      = "none"
+	BudgetRolloverRemainder = "carry_remainder"
+	BudgetRolloverOverspend = "carry_overspend"
+	BudgetRolloverBoth      = "carry_both"
+)
+
+// BudgetAlertOverspent is the alert key used once spending passes the
+// effective limit; percentage thresholds use their numeric value as key.
+const BudgetAlertOverspent = "overspent"
+
+// BudgetAlert records a threshold notification already sent for a period.
+type BudgetAlert struct {
+	ID          string  `json:"id"`
+	BudgetID    string  `json:"budgetId"`
+	UserID      string  `json:"userId"`
+	PeriodStart string  `json:"periodStart"`
+	Threshold   string  `json:"threshold"`
+	PercentUsed float64 `json:"percentUsed"`
+	CreatedAt   string  `json:"createdAt,omitempty"`
+}
+
+// BudgetPeriod is the snapshot recorded when a budget period closes.
+type BudgetPeriod struct {
+	ID              string  `json:"id"`
+	BudgetID        string  `json:"budgetId"`
+	UserID          string  `json:"userId"`
+	PeriodStart     string  `json:"periodStart"`
+	PeriodEnd       string  `json:"periodEnd"`
+	Currency        string  `json:"currency"`
+	BaseLimit       Money   `json:"baseLimit"`
+	CarriedIn       Money   `json:"carriedIn"`
+	EffectiveLimit  Money   `json:"effectiveLimit"`
+	SpentAmount     Money   `json:"spentAmount"`
+	RemainingAmount Money   `json:"remainingAmount"`
+	PercentUsed     float64 `json:"percentUsed"`
+	IsOverspent     bool    `json:"isOverspent"`
+	CarriedOut      Money   `json:"carriedOut"`
+	RolloverMode    string  `json:"rolloverMode"`
+	ClosedAt        string  `json:"closedAt,omitempty"`
+	CreatedAt       string  `json:"createdAt,omitempty"`
+}
+
 // Debt represents an owed balance.
 type Debt struct {
-	ID                           string  `json:"id"`
-	UserID                       string  `json:"userId"`
-	Direction                    string  `json:"direction"`
-	CounterpartyID               *string `json:"counterpartyId,omitempty"`
-	CounterpartyName             string  `json:"counterpartyName"`
-	Description                  *string `json:"description,omitempty"`
-	PrincipalAmount              float64 `json:"principalAmount"`
-	PrincipalCurrency            string  `json:"principalCurrency"`
-	PrincipalOriginalAmount      float64 `json:"principalOriginalAmount"`
-	PrincipalOriginalCurrency    *string `json:"principalOriginalCurrency,omitempty"`
-	BaseCurrency                 string  `json:"baseCurrency"`
-	RateOnStart                  float64 `json:"rateOnStart"`
-	PrincipalBaseValue           float64 `json:"principalBaseValue"`
-	RepaymentCurrency            *string `json:"repaymentCurrency,omitempty"`
-	RepaymentAmount              float64 `json:"repaymentAmount"`
-	RepaymentRateOnStart         float64 `json:"repaymentRateOnStart"`
-	IsFixedRepaymentAmount       bool    `json:"isFixedRepaymentAmount"`
-	StartDate                    string  `json:"startDate"`
-	DueDate                      *string `json:"dueDate,omitempty"`
-	InterestMode                 *string `json:"interestMode,omitempty"`
-	InterestRateAnnual           float64 `json:"interestRateAnnual"`
-	ScheduleHint                 *string `json:"scheduleHint,omitempty"`
-	LinkedGoalID                 *string `json:"linkedGoalId,omitempty"`
-	LinkedBudgetID               *string `json:"linkedBudgetId,omitempty"`
-	FundingAccountID             *string `json:"fundingAccountId,omitempty"`
-	FundingTransactionID         *string `json:"fundingTransactionId,omitempty"`
-	LentFromAccountID            *string `json:"lentFromAccountId,omitempty"`
-	ReturnToAccountID            *string `json:"returnToAccountId,omitempty"`
-	ReceivedToAccountID          *string `json:"receivedToAccountId,omitempty"`
-	PayFromAccountID             *string `json:"payFromAccountId,omitempty"`
-	CustomRateUsed               float64 `json:"customRateUsed"`
-	ExchangeRateCurrent          float64 `json:"exchangeRateCurrent"`
-	ReminderEnabled              bool    `json:"reminderEnabled"`
-	ReminderTime                 *string `json:"reminderTime,omitempty"`
-	Status                       string  `json:"status"`
-	SettledAt                    *string `json:"settledAt,omitempty"`
-	FinalRateUsed                float64 `json:"finalRateUsed"`
-	FinalProfitLoss              float64 `json:"finalProfitLoss"`
-	FinalProfitLossCurrency      *string `json:"finalProfitLossCurrency,omitempty"`
-	TotalPaidInRepaymentCurrency float64 `json:"totalPaidInRepaymentCurrency"`
-	RemainingAmount              float64 `json:"remainingAmount"`
-	TotalPaid                    float64 `json:"totalPaid"`
-	PercentPaid                  float64 `json:"percentPaid"`
-	ShowStatus                   string  `json:"showStatus"`
-	CreatedAt                    string  `json:"createdAt,omitempty"`
-	UpdatedAt                    string  `json:"updatedAt,omitempty"`
-	DeletedAt                    string  `json:"-"`
+	ID                           string   `json:"id"`
+	UserID                       string   `json:"userId"`
+	Direction                    string   `json:"direction"`
+	CounterpartyID               *string  `json:"counterpartyId,omitempty"`
+	CounterpartyName             string   `json:"counterpartyName"`
+	Description                  *string  `json:"description,omitempty"`
+	PrincipalAmount              Money    `json:"principalAmount"`
+	PrincipalCurrency            string   `json:"principalCurrency"`
+	PrincipalOriginalAmount      Money    `json:"principalOriginalAmount"`
+	PrincipalOriginalCurrency    *string  `json:"principalOriginalCurrency,omitempty"`
+	BaseCurrency                 string   `json:"baseCurrency"`
+	RateOnStart                  float64  `json:"rateOnStart"`
+	PrincipalBaseValue           Money    `json:"principalBaseValue"`
+	RepaymentCurrency            *string  `json:"repaymentCurrency,omitempty"`
+	RepaymentAmount              Money    `json:"repaymentAmount"`
+	RepaymentRateOnStart         float64  `json:"repaymentRateOnStart"`
+	IsFixedRepaymentAmount       bool     `json:"isFixedRepaymentAmount"`
+	StartDate                    string   `json:"startDate"`
+	DueDate                      *string  `json:"dueDate,omitempty"`
+	InterestMode                 *string  `json:"interestMode,omitempty"`
+	InterestRateAnnual           float64  `json:"interestRateAnnual"`
+	ScheduleHint                 *string  `json:"scheduleHint,omitempty"`
+	LinkedGoalID                 *string  `json:"linkedGoalId,omitempty"`
+	LinkedBudgetID               *string  `json:"linkedBudgetId,omitempty"`
+	FundingAccountID             *string  `json:"fundingAccountId,omitempty"`
+	FundingTransactionID         *string  `json:"fundingTransactionId,omitempty"`
+	LentFromAccountID            *string  `json:"lentFromAccountId,omitempty"`
+	ReturnToAccountID            *string  `json:"returnToAccountId,omitempty"`
+	ReceivedToAccountID          *string  `json:"receivedToAccountId,omitempty"`
+	PayFromAccountID             *string  `json:"payFromAccountId,omitempty"`
+	CustomRateUsed               float64  `json:"customRateUsed"`
+	ExchangeRateCurrent          float64  `json:"exchangeRateCurrent"`
+	ReminderEnabled              bool     `json:"reminderEnabled"`
+	ReminderTime                 *string  `json:"reminderTime,omitempty"`
+	Status                       string   `json:"status"`
+	SettledAt                    *string  `json:"settledAt,omitempty"`
+	FinalRateUsed                float64  `json:"finalRateUsed"`
+	FinalProfitLoss              Money    `json:"finalProfitLoss"`
+	FinalProfitLossCurrency      *string  `json:"finalProfitLossCurrency,omitempty"`
+	TotalPaidInRepaymentCurrency Money    `json:"totalPaidInRepaymentCurrency"`
+	RemainingAmount              Money    `json:"remainingAmount"`
+	TotalPaid                    Money    `json:"totalPaid"`
+	PercentPaid                  float64  `json:"percentPaid"`
+	AccruedInterest              Money    `json:"accruedInterest"`
+	InterestPaid                 Money    `json:"interestPaid"`
+	TotalDue                     Money    `json:"totalDue"`
+	ShowStatus                   string   `json:"showStatus"`
+	Attachments                  []string `json:"attachments"`
+	CreatedAt                    string   `json:"createdAt,omitempty"`
+	UpdatedAt                    string   `json:"updatedAt,omitempty"`
+	DeletedAt                    string   `json:"-"`
 }
 
 // DebtResponse wraps Debt with embedded counterparty for API responses.
 type DebtResponse struct {
 	*Debt
 	Counterparty *CounterpartyEmbed `json:"counterparty,omitempty"`
+	Interest     *DebtInterest      `json:"interest,omitempty"`
 }
 
 // CounterpartyEmbed is a lightweight counterparty object for embedding in responses.
@@ -210,24 +375,25 @@ type InlineCounterparty struct {
 
 // DebtPayment represents a payment against a debt.
 type DebtPayment struct {
-	ID                    string  `json:"id"`
-	DebtID                string  `json:"debtId"`
-	Amount                float64 `json:"amount"`
-	Currency              string  `json:"currency"`
-	BaseCurrency          string  `json:"baseCurrency"`
-	RateUsedToBase        float64 `json:"rateUsedToBase"`
-	ConvertedAmountToBase float64 `json:"convertedAmountToBase"`
-	RateUsedToDebt        float64 `json:"rateUsedToDebt"`
-	ConvertedAmountToDebt float64 `json:"convertedAmountToDebt"`
-	PaymentDate           string  `json:"paymentDate"`
-	AccountID             *string `json:"accountId,omitempty"`
-	Note                  *string `json:"note,omitempty"`
-	RelatedTransactionID  *string `json:"relatedTransactionId,omitempty"`
-	AppliedRate           float64 `json:"appliedRate"`
-	CreatedAt             string  `json:"createdAt,omitempty"`
-	UpdatedAt             string  `json:"updatedAt,omitempty"`
-	DeletedAt             string  `json:"-"`
-	TransactionType       string  `json:"-"`
+	ID                    string   `json:"id"`
+	DebtID                string   `json:"debtId"`
+	Amount                Money    `json:"amount"`
+	Currency              string   `json:"currency"`
+	BaseCurrency          string   `json:"baseCurrency"`
+	RateUsedToBase        float64  `json:"rateUsedToBase"`
+	ConvertedAmountToBase Money    `json:"convertedAmountToBase"`
+	RateUsedToDebt        float64  `json:"rateUsedToDebt"`
+	ConvertedAmountToDebt Money    `json:"convertedAmountToDebt"`
+	PaymentDate           string   `json:"paymentDate"`
+	AccountID             *string  `json:"accountId,omitempty"`
+	Note                  *string  `json:"note,omitempty"`
+	RelatedTransactionID  *string  `json:"relatedTransactionId,omitempty"`
+	AppliedRate           float64  `json:"appliedRate"`
+	Attachments           []string `json:"attachments"`
+	CreatedAt             string   `json:"createdAt,omitempty"`
+	UpdatedAt             string   `json:"updatedAt,omitempty"`
+	DeletedAt             string   `json:"-"`
+	TransactionType       string   `json:"-"`
 }
 
 // Counterparty represents a person or organization.
@@ -265,14 +431,14 @@ type FXRate struct {
 type BudgetSpendingItem struct {
 	CategoryID   string  `json:"categoryId"`
 	CategoryName string  `json:"categoryName"`
-	Amount       float64 `json:"amount"`
+	Amount       Money   `json:"amount"`
 	Percentage   float64 `json:"percentage"`
 }
 
 // CurrencyBalance summarizes totals per currency.
 type CurrencyBalance struct {
-	Currency string  `json:"currency"`
-	Balance  float64 `json:"balance"`
+	Currency string `json:"currency"`
+	Balance  Money  `json:"balance"`
 }
 
 // SupportedCurrency describes display metadata for a currency.
@@ -306,10 +472,10 @@ type QuickExpenseCategory struct {
 
 // FinanceSummary holds aggregated balances and totals.
 type FinanceSummaryTotals struct {
-	Balance float64 `json:"balance"`
-	Income  float64 `json:"income"`
-	Expense float64 `json:"expense"`
-	Net     float64 `json:"net"`
+	Balance Money `json:"balance"`
+	Income  Money `json:"income"`
+	Expense Money `json:"expense"`
+	Net     Money `json:"net"`
 }
 
 type FinanceSummaryPeriod struct {
@@ -317,17 +483,27 @@ type FinanceSummaryPeriod struct {
 	To   string `json:"to"`
 }
 
+// FinanceSummaryAccount reports Balance in the account's own currency. For a
+// multi-currency account Balances lists every currency it holds, own
+// currency first, and BalanceBase is their total in the base currency.
 type FinanceSummaryAccount struct {
-	ID       string  `json:"id"`
-	Name     string  `json:"name"`
-	Balance  float64 `json:"balance"`
-	BalanceBase float64 `json:"balanceBase"`
-	Currency string  `json:"currency"`
+	ID          string                         `json:"id"`
+	Name        string                         `json:"name"`
+	Balance     Money                          `json:"balance"`
+	BalanceBase Money                          `json:"balanceBase"`
+	Currency    string                         `json:"currency"`
+	Balances    []FinanceSummaryAccountBalance `json:"balances,omitempty"`
+}
+
+type FinanceSummaryAccountBalance struct {
+	Currency    string `json:"currency"`
+	Balance     Money  `json:"balance"`
+	BalanceBase Money  `json:"balanceBase"`
 }
 
 type FinanceSummaryCategory struct {
-	CategoryID string  `json:"categoryId"`
-	Amount     float64 `json:"amount"`
+	CategoryID string `json:"categoryId"`
+	Amount     Money  `json:"amount"`
 }
 
 type FinanceSummaryChanges struct {
@@ -336,17 +512,18 @@ type FinanceSummaryChanges struct {
 }
 
 type FinanceSummaryProgress struct {
-	Used       float64 `json:"used"`
+	Used       Money   `json:"used"`
 	Percentage float64 `json:"percentage"`
-	Limit      float64 `json:"limit"`
+	Limit      Money   `json:"limit"`
 }
 
 type FinanceSummaryTransaction struct {
 	ID            string  `json:"id"`
 	Type          string  `json:"type"`
-	Amount        float64 `json:"amount"`
+	Amount        Money   `json:"amount"`
 	Currency      string  `json:"currency"`
 	Date          string  `json:"date"`
+	Status        string  `json:"status,omitempty"`
 	Description   string  `json:"description,omitempty"`
 	CategoryID    *string `json:"categoryId,omitempty"`
 	AccountID     *string `json:"accountId,omitempty"`
@@ -372,7 +549,8 @@ type FinanceSummary struct {
 	TopCategories []FinanceSummaryCategory `json:"topCategories"`
 	Progress      FinanceSummaryProgress   `json:"progress"`
 	RecentTransactions []FinanceSummaryTransaction `json:"recentTransactions"`
-	Events        []FinanceSummaryEvent    `json:"events"`
+	Upcoming           []FinanceSummaryTransaction `json:"upcoming"`
+	Events             []FinanceSummaryEvent       `json:"events"`
 }
 
 // FinanceBootstrap is returned when initializing finance flows.
@@ -384,6 +562,6 @@ type FinanceBootstrap struct {
 
 // BalanceHistoryPoint captures balance over time.
 type BalanceHistoryPoint struct {
-	Date    string  `json:"date"`
-	Balance float64 `json:"balance"`
+	Date    string `json:"date"`
+	Balance Money  `json:"balance"`
 }
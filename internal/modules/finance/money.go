@@ -0,0 +1,324 @@
+package finance
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact amount stored as ten-thousandths of a currency unit,
+// matching the DECIMAL(19,4) money columns. Sums and differences of Money are
+// exact; anything involving a rate goes through ExchangeRate or MulFloat and
+// is rounded once. On the wire it is a plain JSON number, as before.
+type Money int64
+
+// moneyDecimals is the number of decimal places Money keeps.
+const moneyDecimals = 4
+
+const moneyScale = 10000
+
+// MoneyFromFloat rounds a float to the nearest representable amount. Use it
+// only at boundaries where a float is unavoidable (map-based patches, interest
+// factors); parse text with ParseMoney instead.
+func MoneyFromFloat(value float64) Money {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0
+	}
+	parsed, err := ParseMoney(strconv.FormatFloat(value, 'f', -1, 64))
+	if err != nil {
+		return Money(math.Round(value * moneyScale))
+	}
+	return parsed
+}
+
+// ParseMoney parses a decimal string such as "-12.3456". Digits beyond the
+// fourth decimal place are rounded half away from zero.
+func ParseMoney(raw string) (Money, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, fmt.Errorf("finance: empty amount")
+	}
+	rat, ok := new(big.Rat).SetString(raw)
+	if !ok {
+		return 0, fmt.Errorf("finance: invalid amount %q", raw)
+	}
+	return moneyFromRat(rat, 1)
+}
+
+// moneyFromRat rounds value to a multiple of unit ten-thousandths, half away
+// from zero.
+func moneyFromRat(value *big.Rat, unit int64) (Money, error) {
+	num := new(big.Int).Mul(value.Num(), big.NewInt(moneyScale))
+	den := new(big.Int).Mul(value.Denom(), big.NewInt(unit))
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+	quo.Mul(quo, big.NewInt(unit))
+	if !quo.IsInt64() {
+		return 0, fmt.Errorf("finance: amount out of range")
+	}
+	return Money(quo.Int64()), nil
+}
+
+// Float64 returns the amount as a float for ratios and display maths.
+func (m Money) Float64() float64 {
+	return float64(m) / moneyScale
+}
+
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// String formats the amount without trailing zeros, e.g. "12.5" or "-3".
+func (m Money) String() string {
+	sign := ""
+	value := int64(m)
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	whole := value / moneyScale
+	frac := value % moneyScale
+	if frac == 0 {
+		return sign + strconv.FormatInt(whole, 10)
+	}
+	fracText := strings.TrimRight(fmt.Sprintf("%0*d", moneyDecimals, frac), "0")
+	return sign + strconv.FormatInt(whole, 10) + "." + fracText
+}
+
+// RoundTo rounds half away from zero to the currency's minor unit.
+func (m Money) RoundTo(currency string) Money {
+	unit := currencyUnit(currency)
+	quo, rem := int64(m)/unit, int64(m)%unit
+	if rem*2 >= unit {
+		quo++
+	} else if rem*2 <= -unit {
+		quo--
+	}
+	return Money(quo * unit)
+}
+
+// RoundUpTo rounds towards positive infinity to the currency's minor unit.
+func (m Money) RoundUpTo(currency string) Money {
+	unit := currencyUnit(currency)
+	quo, rem := int64(m)/unit, int64(m)%unit
+	if rem > 0 {
+		quo++
+	}
+	return Money(quo * unit)
+}
+
+// MulFloat multiplies by a float factor (interest, percentages) and rounds
+// the result to the nearest ten-thousandth.
+func (m Money) MulFloat(factor float64) Money {
+	rat, ok := new(big.Rat).SetString(strconv.FormatFloat(factor, 'g', -1, 64))
+	if !ok {
+		return MoneyFromFloat(m.Float64() * factor)
+	}
+	rat.Mul(rat, new(big.Rat).SetFrac64(int64(m), moneyScale))
+	result, err := moneyFromRat(rat, 1)
+	if err != nil {
+		return MoneyFromFloat(m.Float64() * factor)
+	}
+	return result
+}
+
+// DivRound divides by n and rounds half away from zero to the currency's
+// minor unit.
+func (m Money) DivRound(n int64, currency string) Money {
+	if n == 0 {
+		return 0
+	}
+	result, err := moneyFromRat(big.NewRat(int64(m), moneyScale*n), currencyUnit(currency))
+	if err != nil {
+		return 0
+	}
+	return result
+}
+
+// Ratio returns m/other as a float, or 0 when other is zero.
+func (m Money) Ratio(other Money) float64 {
+	if other == 0 {
+		return 0
+	}
+	return float64(m) / float64(other)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number, a numeric string or null. An empty
+// string is malformed, not zero.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	raw := strings.TrimSpace(string(data))
+	if raw == "null" {
+		*m = 0
+		return nil
+	}
+	raw = strings.Trim(raw, `"`)
+	parsed, err := ParseMoney(raw)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Scan reads NUMERIC columns, which lib/pq returns as text.
+func (m *Money) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case []byte:
+		parsed, err := ParseMoney(string(value))
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	case string:
+		parsed, err := ParseMoney(value)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	case int64:
+		*m = Money(value * moneyScale)
+		return nil
+	case float64:
+		*m = MoneyFromFloat(value)
+		return nil
+	default:
+		return fmt.Errorf("finance: cannot scan %T into Money", src)
+	}
+}
+
+// Value writes the exact decimal text so NUMERIC columns never see a float.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+func currencyDecimals(currency string) int {
+	if strings.EqualFold(currency, "UZS") {
+		return 0
+	}
+	return 2
+}
+
+// currencyUnit is the currency's minor unit in ten-thousandths.
+func currencyUnit(currency string) int64 {
+	unit := int64(1)
+	for i := currencyDecimals(currency); i < moneyDecimals; i++ {
+		unit *= 10
+	}
+	return unit
+}
+
+// ExchangeRate is an exact conversion factor. Rates parsed from stored
+// decimals, their inverses and cross rates stay exact until Convert rounds the
+// converted amount once.
+type ExchangeRate struct {
+	rat *big.Rat
+}
+
+func exchangeRateOne() ExchangeRate {
+	return ExchangeRate{rat: big.NewRat(1, 1)}
+}
+
+// exchangeRateFromFloat reads a stored rate by its shortest decimal form, so
+// 0.1 is exactly one tenth rather than its binary approximation.
+func exchangeRateFromFloat(value float64) ExchangeRate {
+	if value <= 0 || math.IsNaN(value) || math.IsInf(value, 0) {
+		return ExchangeRate{}
+	}
+	rat, ok := new(big.Rat).SetString(strconv.FormatFloat(value, 'g', -1, 64))
+	if !ok {
+		return ExchangeRate{}
+	}
+	return ExchangeRate{rat: rat}
+}
+
+func (r ExchangeRate) IsZero() bool {
+	return r.rat == nil || r.rat.Sign() == 0
+}
+
+func (r ExchangeRate) Inverse() ExchangeRate {
+	if r.IsZero() {
+		return ExchangeRate{}
+	}
+	return ExchangeRate{rat: new(big.Rat).Inv(r.rat)}
+}
+
+// Mul chains two rates, e.g. from→pivot and pivot→to.
+func (r ExchangeRate) Mul(other ExchangeRate) ExchangeRate {
+	if r.IsZero() || other.IsZero() {
+		return ExchangeRate{}
+	}
+	return ExchangeRate{rat: new(big.Rat).Mul(r.rat, other.rat)}
+}
+
+// Quo returns r/other, e.g. a cross rate through a common currency.
+func (r ExchangeRate) Quo(other ExchangeRate) ExchangeRate {
+	if r.IsZero() || other.IsZero() {
+		return ExchangeRate{}
+	}
+	return ExchangeRate{rat: new(big.Rat).Quo(r.rat, other.rat)}
+}
+
+// Float64 is the rate for display and the DECIMAL(19,6) rate columns.
+func (r ExchangeRate) Float64() float64 {
+	if r.IsZero() {
+		return 0
+	}
+	value, _ := r.rat.Float64()
+	return value
+}
+
+// Convert multiplies amount by the rate and rounds half away from zero to the
+// target currency's minor unit.
+func (r ExchangeRate) Convert(amount Money, currency string) Money {
+	if r.IsZero() {
+		return 0
+	}
+	product := new(big.Rat).Mul(new(big.Rat).SetFrac64(int64(amount), moneyScale), r.rat)
+	result, err := moneyFromRat(product, currencyUnit(currency))
+	if err != nil {
+		return MoneyFromFloat(amount.Float64() * r.Float64()).RoundTo(currency)
+	}
+	return result
+}
+
+// ConvertUp is Convert rounding towards positive infinity, for amounts that
+// must fully cover the converted value (e.g. debiting an account).
+func (r ExchangeRate) ConvertUp(amount Money, currency string) Money {
+	if r.IsZero() {
+		return 0
+	}
+	product := new(big.Rat).Mul(new(big.Rat).SetFrac64(int64(amount), moneyScale), r.rat)
+	unit := currencyUnit(currency)
+	num := new(big.Int).Mul(product.Num(), big.NewInt(moneyScale))
+	den := new(big.Int).Mul(product.Denom(), big.NewInt(unit))
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() > 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+	quo.Mul(quo, big.NewInt(unit))
+	if !quo.IsInt64() {
+		return MoneyFromFloat(amount.Float64() * r.Float64()).RoundUpTo(currency)
+	}
+	return Money(quo.Int64())
+}
@@ -0,0 +1,290 @@
+package finance
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	appErrors "github.com/leora/leora-server/internal/errors"
+)
+
+const (
+	NetWorthDaily   = "daily"
+	NetWorthWeekly  = "weekly"
+	NetWorthMonthly = "monthly"
+)
+
+// netWorthMaxPoints caps a series at roughly a year of daily points.
+const netWorthMaxPoints = 400
+
+type NetWorthQuery struct {
+	From         string
+	To           string
+	Granularity  string
+	BaseCurrency string
+}
+
+type NetWorthSeries struct {
+	From         string          `json:"from"`
+	To           string          `json:"to"`
+	Granularity  string          `json:"granularity"`
+	BaseCurrency string          `json:"baseCurrency"`
+	Points       []NetWorthPoint `json:"points"`
+}
+
+// NetWorthPoint is the position at the end of Date, the last day of the
+// bucket starting at PeriodStart. Assets are positive account balances plus
+// receivables; liabilities are overdrawn balances plus payables. NetWorth is
+// their difference, which is also AccountsTotal + Receivables − Payables.
+type NetWorthPoint struct {
+	PeriodStart   string            `json:"periodStart"`
+	Date          string            `json:"date"`
+	AccountsTotal Money             `json:"accountsTotal"`
+	Receivables   Money             `json:"receivables"`
+	Payables      Money             `json:"payables"`
+	Assets        Money             `json:"assets"`
+	Liabilities   Money             `json:"liabilities"`
+	NetWorth      Money             `json:"netWorth"`
+	Accounts      []NetWorthAccount `json:"accounts"`
+}
+
+type NetWorthAccount struct {
+	AccountID   string `json:"accountId"`
+	Name        string `json:"name"`
+	Currency    string `json:"currency"`
+	Balance     Money  `json:"balance"`
+	BalanceBase Money  `json:"balanceBase"`
+}
+
+type netWorthBucket struct {
+	start string
+	end   string
+}
+
+type netWorthMovement struct {
+	date   string
+	amount Money
+}
+
+// netWorthLedger is one account or debt replayed over time: an opening amount
+// from openDate on, then dated movements.
+type netWorthLedger struct {
+	openDate  string
+	closeDate string
+	opening   Money
+	movements []netWorthMovement
+	next      int
+	balance   Money
+}
+
+// advance applies every movement dated up to date and reports whether the
+// ledger exists on that date.
+func (l *netWorthLedger) advance(date string) bool {
+	for l.next < len(l.movements) && l.movements[l.next].date <= date {
+		l.balance += l.movements[l.next].amount
+		l.next++
+	}
+	return l.openDate != "" && l.openDate <= date
+}
+
+func (l *netWorthLedger) sortMovements() {
+	sort.SliceStable(l.movements, func(i, j int) bool {
+		return l.movements[i].date < l.movements[j].date
+	})
+	l.balance = l.opening
+}
+
+// NetWorth replays every account and open debt over the requested range. An
+// account counts from its creation or first entry, whichever is earlier; a
+// debt from its start date until it is settled, less the payments made by
+// each point. Amounts are converted with the rate for the point's date.
+func (s *Service) NetWorth(ctx context.Context, query NetWorthQuery) (*NetWorthSeries, error) {
+	granularity := strings.ToLower(strings.TrimSpace(query.Granularity))
+	if granularity == "" {
+		granularity = NetWorthMonthly
+	}
+	if granularity != NetWorthDaily && granularity != NetWorthWeekly && granularity != NetWorthMonthly {
+		return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "granularity"})
+	}
+	to := time.Now().UTC()
+	if strings.TrimSpace(query.To) != "" {
+		parsed, err := time.Parse("2006-01-02", strings.TrimSpace(query.To))
+		if err != nil {
+			return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "to"})
+		}
+		to = parsed
+	}
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	from := defaultNetWorthFrom(to, granularity)
+	if strings.TrimSpace(query.From) != "" {
+		parsed, err := time.Parse("2006-01-02", strings.TrimSpace(query.From))
+		if err != nil || parsed.After(to) {
+			return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "from"})
+		}
+		from = parsed
+	}
+	buckets, ok := netWorthBuckets(from, to, granularity)
+	if !ok {
+		return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{
+			"field":     "granularity",
+			"maxPoints": netWorthMaxPoints,
+		})
+	}
+
+	accounts, err := s.repo.ListAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	baseCurrency := normalizeSummaryBaseCurrency(query.BaseCurrency, accounts)
+	transactions, err := s.repo.ListTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	debts, err := s.repo.ListDebts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Name < accounts[j].Name
+	})
+	accountLedgers := make([]*netWorthLedger, len(accounts))
+	for i, account := range accounts {
+		ledger := &netWorthLedger{opening: account.InitialBalance, openDate: dateOnly(account.CreatedAt)}
+		for _, txn := range transactions {
+			if txn.Date == "" {
+				continue
+			}
+			if delta := transactionDeltaForAccount(account.ID, txn); delta != 0 {
+				ledger.movements = append(ledger.movements, netWorthMovement{date: dateOnly(txn.Date), amount: delta})
+			}
+		}
+		ledger.sortMovements()
+		// Entries backdated before the account was created still count.
+		if len(ledger.movements) > 0 && (ledger.openDate == "" || ledger.movements[0].date < ledger.openDate) {
+			ledger.openDate = ledger.movements[0].date
+		}
+		accountLedgers[i] = ledger
+	}
+
+	debtLedgers := make([]*netWorthLedger, 0, len(debts))
+	openDebts := make([]*Debt, 0, len(debts))
+	for _, debt := range debts {
+		if debt.Status == DebtStatusCanceled {
+			continue
+		}
+		payments, err := s.repo.ListDebtPayments(ctx, debt.ID)
+		if err != nil {
+			return nil, err
+		}
+		ledger := &netWorthLedger{opening: debt.PrincipalAmount, openDate: dateOnly(debt.StartDate)}
+		if ledger.openDate == "" {
+			ledger.openDate = dateOnly(debt.CreatedAt)
+		}
+		if debt.SettledAt != nil {
+			ledger.closeDate = dateOnly(*debt.SettledAt)
+		}
+		for _, payment := range payments {
+			amount := payment.ConvertedAmountToDebt
+			if amount == 0 && payment.RateUsedToDebt > 0 {
+				amount = exchangeRateFromFloat(payment.RateUsedToDebt).Convert(payment.Amount, debt.PrincipalCurrency)
+			}
+			ledger.movements = append(ledger.movements, netWorthMovement{date: dateOnly(payment.PaymentDate), amount: -amount})
+		}
+		ledger.sortMovements()
+		debtLedgers = append(debtLedgers, ledger)
+		openDebts = append(openDebts, debt)
+	}
+
+	series := &NetWorthSeries{
+		From:         from.Format("2006-01-02"),
+		To:           to.Format("2006-01-02"),
+		Granularity:  granularity,
+		BaseCurrency: baseCurrency,
+		Points:       make([]NetWorthPoint, 0, len(buckets)),
+	}
+	for _, bucket := range buckets {
+		point := NetWorthPoint{PeriodStart: bucket.start, Date: bucket.end, Accounts: make([]NetWorthAccount, 0, len(accounts))}
+		for i, account := range accounts {
+			ledger := accountLedgers[i]
+			if !ledger.advance(bucket.end) {
+				continue
+			}
+			balanceBase := convertToSummaryBase(s, ctx, ledger.balance, account.Currency, baseCurrency, bucket.end)
+			point.Accounts = append(point.Accounts, NetWorthAccount{
+				AccountID:   account.ID,
+				Name:        account.Name,
+				Currency:    account.Currency,
+				Balance:     ledger.balance,
+				BalanceBase: balanceBase,
+			})
+			point.AccountsTotal += balanceBase
+			if balanceBase >= 0 {
+				point.Assets += balanceBase
+			} else {
+				point.Liabilities -= balanceBase
+			}
+		}
+		for i, debt := range openDebts {
+			ledger := debtLedgers[i]
+			if !ledger.advance(bucket.end) || (ledger.closeDate != "" && ledger.closeDate <= bucket.end) {
+				continue
+			}
+			outstanding := convertToSummaryBase(s, ctx, max(ledger.balance, 0), debt.PrincipalCurrency, baseCurrency, bucket.end)
+			if debt.Direction == "they_owe_me" {
+				point.Receivables += outstanding
+			} else {
+				point.Payables += outstanding
+			}
+		}
+		point.Assets += point.Receivables
+		point.Liabilities += point.Payables
+		point.NetWorth = point.Assets - point.Liabilities
+		series.Points = append(series.Points, point)
+	}
+	return series, nil
+}
+
+func defaultNetWorthFrom(to time.Time, granularity string) time.Time {
+	switch granularity {
+	case NetWorthDaily:
+		return to.AddDate(0, 0, -29)
+	case NetWorthWeekly:
+		return to.AddDate(0, 0, -7*12+1)
+	default:
+		return time.Date(to.Year(), to.Month()-11, 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// netWorthBuckets splits [from, to] into days, ISO weeks or calendar months;
+// the first and last buckets are clipped to the range.
+func netWorthBuckets(from, to time.Time, granularity string) ([]netWorthBucket, bool) {
+	buckets := make([]netWorthBucket, 0)
+	for start := from; !start.After(to); {
+		end := start
+		switch granularity {
+		case NetWorthWeekly:
+			end = start.AddDate(0, 0, (7-int(start.Weekday()))%7)
+		case NetWorthMonthly:
+			end = time.Date(start.Year(), start.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+		}
+		if end.After(to) {
+			end = to
+		}
+		if len(buckets) == netWorthMaxPoints {
+			return nil, false
+		}
+		buckets = append(buckets, netWorthBucket{start: start.Format("2006-01-02"), end: end.Format("2006-01-02")})
+		start = end.AddDate(0, 0, 1)
+	}
+	return buckets, true
+}
+
+func dateOnly(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) > 10 {
+		return value[:10]
+	}
+	return value
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"time"
 
@@ -13,17 +14,32 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/leora/leora-server/internal/common/utils"
 	appErrors "github.com/leora/leora-server/internal/errors"
+	"github.com/lib/pq"
 )
 
 const (
-	accountSelectFields      = `id, user_id, name, currency, account_type AS account_type, initial_balance, current_balance, linked_goal_id, custom_type_id, is_main, is_archived, show_status, created_at, updated_at`
-	transactionSelectFields  = `id, user_id, type, status, account_id, from_account_id, to_account_id, reference_type, reference_id, amount, currency, base_currency, rate_used_to_base, converted_amount_to_base, to_amount, to_currency, effective_rate_from_to, fee_amount, fee_category_id, category_id, category, subcategory_id, name, description, date, time, linked_goal_id, budget_id, linked_debt_id, habit_id, counterparty_id, recurring_id, attachments, tags, is_balance_adjustment, skip_budget_matching, show_status, related_budget_id, related_debt_id, planned_amount, paid_amount, original_currency, original_amount, conversion_rate, occurred_at, metadata, created_at, updated_at`
-	budgetSelectFields       = `id, user_id, name, budget_type, category_ids, linked_goal_id, account_id, transaction_type, currency, limit_amount, period_type, start_date, end_date, spent_amount, remaining_amount, percent_used, is_overspent, rollover_mode, notify_on_exceed, contribution_total, current_balance, is_archived, show_status, created_at, updated_at`
-	debtSelectFields         = `id, user_id, name, balance, direction, counterparty_id, counterparty_name, description, principal_amount, principal_currency, principal_original_amount, principal_original_currency, base_currency, rate_on_start, principal_base_value, repayment_currency, repayment_amount, repayment_rate_on_start, is_fixed_repayment_amount, start_date, due_date, interest_mode, interest_rate_annual, schedule_hint, linked_goal_id, linked_budget_id, funding_account_id, funding_transaction_id, lent_from_account_id, return_to_account_id, received_to_account_id, pay_from_account_id, custom_rate_used, exchange_rate_current, reminder_enabled, reminder_time, status, settled_at, final_rate_used, final_profit_loss, final_profit_loss_currency, total_paid_in_repayment_currency, remaining_amount, total_paid, percent_paid, show_status, created_at, updated_at`
-	debtPaymentSelectFields  = `dp.id, dp.debt_id, dp.amount, dp.currency, dp.base_currency, dp.rate_used_to_base, dp.converted_amount_to_base, dp.rate_used_to_debt, dp.converted_amount_to_debt, dp.payment_date, dp.account_id, dp.note, dp.related_transaction_id, dp.applied_rate, dp.created_at AS created_at, dp.updated_at AS updated_at, dp.deleted_at`
-	counterpartySelectFields = `id, user_id, display_name, phone_number, comment, search_keywords, show_status, created_at, updated_at, deleted_at`
-	fxRateSelectFields       = `id, rate_date, from_currency, to_currency, rate, rate_mid, rate_bid, rate_ask, nominal, spread_percent, source, created_at, updated_at`
-	categorySelectFields     = `id, type, name_i18n, icon_name, color, is_default, sort_order, is_active, created_at, updated_at`
+	accountSelectFields         = `id, user_id, name, currency, account_type AS account_type, initial_balance, current_balance, credit_limit, statement_closing_day, payment_due_day, minimum_payment_percent, minimum_payment_amount, parent_account_id, linked_goal_id, custom_type_id, is_main, is_archived, show_status, created_at, updated_at`
+	creditStatementSelectFields = `id, user_id, account_id, currency, period_start, period_end, due_date, opening_balance, charges, credits, statement_balance, minimum_payment, credit_limit, reminder_sent_at, overdue_notified_at, created_at, updated_at`
+	transactionSelectFields     = `id, user_id, type, status, account_id, from_account_id, to_account_id, reference_type, reference_id, amount, currency, base_currency, rate_used_to_base, converted_amount_to_base, to_amount, to_currency, effective_rate_from_to, fee_amount, fee_category_id, category_id, category, subcategory_id, name, description, date, time, linked_goal_id, budget_id, linked_debt_id, habit_id, counterparty_id, recurring_id, attachments, tags, splits, reconciliation_id, cleared_at, is_scheduled, is_balance_adjustment, skip_budget_matching, show_status, related_budget_id, related_debt_id, planned_amount, paid_amount, original_currency, original_amount, conversion_rate, occurred_at, metadata, created_at, updated_at`
+	budgetSelectFields          = `id, user_id, name, budget_type, category_ids, linked_goal_id, account_id, transaction_type, currency, limit_amount, period_type, start_date, end_date, spent_amount, remaining_amount, percent_used, is_overspent, rollover_mode, carried_amount, notify_on_exceed, alert_thresholds, contribution_total, current_balance, is_archived, show_status, created_at, updated_at`
+	debtSelectFields            = `id, user_id, name, balance, direction, counterparty_id, counterparty_name, description, principal_amount, principal_currency, principal_original_amount, principal_original_currency, base_currency, rate_on_start, principal_base_value, repayment_currency, repayment_amount, repayment_rate_on_start, is_fixed_repayment_amount, start_date, due_date, interest_mode, interest_rate_annual, schedule_hint, linked_goal_id, linked_budget_id, funding_account_id, funding_transaction_id, lent_from_account_id, return_to_account_id, received_to_account_id, pay_from_account_id, custom_rate_used, exchange_rate_current, reminder_enabled, reminder_time, status, settled_at, final_rate_used, final_profit_loss, final_profit_loss_currency, total_paid_in_repayment_currency, remaining_amount, total_paid, percent_paid, show_status, attachments, created_at, updated_at`
+	debtPaymentSelectFields     = `dp.id, dp.debt_id, dp.amount, dp.currency, dp.base_currency, dp.rate_used_to_base, dp.converted_amount_to_base, dp.rate_used_to_debt, dp.converted_amount_to_debt, dp.payment_date, dp.account_id, dp.note, dp.related_transaction_id, dp.applied_rate, dp.attachments, dp.created_at AS created_at, dp.updated_at AS updated_at, dp.deleted_at`
+	budgetPeriodSelectFields    = `id, budget_id, user_id, period_start, period_end, currency, base_limit, carried_in, effective_limit, spent_amount, remaining_amount, percent_used, is_overspent, carried_out, rollover_mode, closed_at, created_at`
+	counterpartySelectFields    = `id, user_id, display_name, phone_number, comment, search_keywords, show_status, created_at, updated_at, deleted_at`
+	recurringSelectFields       = `id, user_id, name, type, account_id, from_account_id, to_account_id, amount, currency, to_amount, to_currency, category_id, subcategory_id, description, budget_id, goal_id, counterparty_id, tags, frequency, interval_count, interval_unit, day_of_week, day_of_month, start_date, end_date, occurrence_count, occurrences_generated, next_occurrence_date, last_occurrence_date, posting_mode, status, created_at, updated_at`
+	installmentSelectFields     = `id, debt_id, user_id, sequence, plan_type, due_date, amount, principal_amount, interest_amount, created_at, updated_at`
+	debtEventSelectFields       = `id, debt_id, user_id, from_status, to_status, due_date, occurred_at`
+	occurrenceSelectFields      = `id, recurring_id, user_id, due_date, status, transaction_id, error, created_at, updated_at`
+	fxRateSelectFields          = `id, rate_date, from_currency, to_currency, rate, rate_mid, rate_bid, rate_ask, nominal, spread_percent, source, created_at, updated_at`
+	categorySelectFields        = `id, type, name_i18n, icon_name, color, is_default, sort_order, is_active, created_at, updated_at`
+	importProfileSelectFields   = `id, user_id, name, mapping, created_at, updated_at`
+	importSelectFields          = `id, user_id, account_id, format, file_name, profile_id, status, row_count, pending_count, duplicate_count, approved_count, rejected_count, undone_at, created_at, updated_at`
+	importRowSelectFields       = `id, import_id, user_id, line_number, date, amount, currency, description, payee, reference, fingerprint, status, category_id, duplicate_of_id, transaction_id, error, created_at, updated_at`
+	smsCardLinkSelectFields     = `id, user_id, account_id, card_mask, bank, created_at, updated_at`
+	ruleSelectFields            = `id, user_id, name, priority, status, stop_on_match, conditions, actions, created_at, updated_at`
+	attachmentSelectFields      = `id, user_id, file_name, content_type, size_bytes, checksum, storage_key, thumbnail_key, thumbnail_size_bytes, width, height, created_at`
+	reconciliationSelectFields  = `id, user_id, account_id, currency, statement_date, statement_balance, status, opening_balance, cleared_balance, difference, cleared_transaction_ids, adjustment_transaction_id, completed_at, created_at, updated_at`
+	smsMessageSelectFields      = `id, user_id, sender, body, received_at, fingerprint, status, bank, direction, amount, currency, merchant, card_mask, balance, occurred_at, account_id, transaction_id, error, created_at, updated_at`
 )
 
 type PostgresRepository struct {
@@ -128,10 +144,13 @@ func (r *PostgresRepository) CreateAccount(ctx context.Context, account *Account
 		return nil, appErrors.DatabaseError
 	}
 
+	credit := creditColumnsFor(account)
 	if _, err := tx.ExecContext(ctx, `
-		INSERT INTO accounts (id, user_id, name, currency, account_type, initial_balance, current_balance, linked_goal_id, custom_type_id, is_main, is_archived, show_status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
-	`, account.ID, userID, account.Name, account.Currency, account.AccountType, account.InitialBalance, account.CurrentBalance, account.LinkedGoalID, account.CustomTypeID, account.IsMain, account.IsArchived, account.ShowStatus, account.CreatedAt, account.UpdatedAt); err != nil {
+		INSERT INTO accounts (id, user_id, name, currency, account_type, initial_balance, current_balance, credit_limit, statement_closing_day, payment_due_day, minimum_payment_percent, minimum_payment_amount, parent_account_id, linked_goal_id, custom_type_id, is_main, is_archived, show_status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
+	`, account.ID, userID, account.Name, account.Currency, account.AccountType, account.InitialBalance, account.CurrentBalance,
+		credit.limit, credit.closingDay, credit.dueDay, credit.percent, credit.minimum, account.ParentAccountID,
+		account.LinkedGoalID, account.CustomTypeID, account.IsMain, account.IsArchived, account.ShowStatus, account.CreatedAt, account.UpdatedAt); err != nil {
 		log.Printf("[CreateAccount] INSERT error for account=%s: %v", account.Name, err)
 		_ = tx.Rollback()
 		return nil, appErrors.DatabaseError
@@ -175,7 +194,7 @@ func (r *PostgresRepository) UpdateAccount(ctx context.Context, account *Account
 		return err
 	}
 	if account.Currency != "" && current.Currency != account.Currency && current.CurrentBalance != 0 {
-		log.Printf("[UpdateAccount] Cannot change currency for account with balance: id=%s, currentBalance=%.2f", account.ID, current.CurrentBalance)
+		log.Printf("[UpdateAccount] Cannot change currency for account with balance: id=%s, currentBalance=%s", account.ID, current.CurrentBalance)
 		_ = tx.Rollback()
 		return appErrors.InvalidFinanceData
 	}
@@ -184,6 +203,7 @@ func (r *PostgresRepository) UpdateAccount(ctx context.Context, account *Account
 	newBalance := desiredBalance
 	delta := desiredBalance - current.CurrentBalance
 
+	credit := creditColumnsFor(account)
 	result, err := tx.ExecContext(ctx, `
 		UPDATE accounts
 		SET name = $1,
@@ -196,11 +216,17 @@ func (r *PostgresRepository) UpdateAccount(ctx context.Context, account *Account
 			is_main = $8,
 			is_archived = $9,
 			show_status = $10,
-			updated_at = $11
+			updated_at = $11,
+			credit_limit = $14,
+			statement_closing_day = $15,
+			payment_due_day = $16,
+			minimum_payment_percent = $17,
+			minimum_payment_amount = $18
 		WHERE id = $12 AND user_id = $13 AND deleted_at IS NULL
 	`, account.Name, account.Currency, account.AccountType, account.InitialBalance, newBalance,
 		account.LinkedGoalID, account.CustomTypeID, account.IsMain, account.IsArchived, account.ShowStatus,
-		account.UpdatedAt, account.ID, userID)
+		account.UpdatedAt, account.ID, userID,
+		credit.limit, credit.closingDay, credit.dueDay, credit.percent, credit.minimum)
 
 	if err != nil {
 		log.Printf("[UpdateAccount] UPDATE error for id=%s: %v", account.ID, err)
@@ -276,7 +302,7 @@ func (r *PostgresRepository) DeleteAccount(ctx context.Context, id string) (*Tra
 
 	var withdrawal *Transaction
 	if account.CurrentBalance != 0 {
-		log.Printf("[DeleteAccount] Creating withdrawal transaction for balance=%.2f", account.CurrentBalance)
+		log.Printf("[DeleteAccount] Creating withdrawal transaction for balance=%s", account.CurrentBalance)
 		referenceType := "account"
 		referenceID := account.ID
 		withdrawal = &Transaction{
@@ -378,6 +404,186 @@ func (r *PostgresRepository) ListTransactions(ctx context.Context) ([]*Transacti
 	return transactions, nil
 }
 
+func (r *PostgresRepository) ListTransactionsPage(ctx context.Context, filter TransactionFilter, page TransactionPageRequest) ([]*Transaction, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+
+	clauses, args := transactionFilterClauses(userID, filter)
+	argIndex := len(args) + 1
+	sortColumn, sortType := transactionSortColumn(filter)
+	comparison, direction := "<", "DESC"
+	if filter.SortOrder == TransactionSortAsc {
+		comparison, direction = ">", "ASC"
+	}
+	if page.Cursor != nil {
+		clauses = append(clauses, fmt.Sprintf("(%s, created_at, id) %s ($%d::%s, $%d::timestamptz, $%d::uuid)", sortColumn, comparison, argIndex, sortType, argIndex+1, argIndex+2))
+		args = append(args, page.Cursor.Key, page.Cursor.CreatedAt, page.Cursor.ID)
+		argIndex += 3
+	}
+	limit := page.Limit
+	if limit <= 0 {
+		limit = defaultTransactionPageLimit
+	}
+	offset := 0
+	if page.Cursor == nil && page.Page > 1 {
+		offset = (page.Page - 1) * limit
+	}
+	args = append(args, limit+1, offset)
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM transactions
+		WHERE %s
+		ORDER BY %s %s, created_at %s, id %s
+		LIMIT $%d OFFSET $%d
+	`, transactionSelectFields, strings.Join(clauses, " AND "), sortColumn, direction, direction, direction, argIndex, argIndex+1)
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		log.Printf("[ListTransactionsPage] DB query error for user=%s: %v", userID, err)
+		return nil, appErrors.DatabaseError
+	}
+	defer rows.Close()
+
+	transactions := make([]*Transaction, 0, limit+1)
+	for rows.Next() {
+		var row transactionRow
+		if err := rows.StructScan(&row); err != nil {
+			log.Printf("[ListTransactionsPage] Row scan error: %v", err)
+			return nil, appErrors.DatabaseError
+		}
+		transactions = append(transactions, mapRowToTransaction(row))
+	}
+	return transactions, nil
+}
+
+func (r *PostgresRepository) CountTransactions(ctx context.Context, filter TransactionFilter) (int, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return 0, appErrors.InvalidToken
+	}
+
+	clauses, args := transactionFilterClauses(userID, filter)
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM transactions WHERE %s`, strings.Join(clauses, " AND "))
+
+	var total int
+	if err := r.db.GetContext(ctx, &total, query, args...); err != nil {
+		log.Printf("[CountTransactions] DB query error for user=%s: %v", userID, err)
+		return 0, appErrors.DatabaseError
+	}
+	return total, nil
+}
+
+// transactionFilterClauses mirrors filterTransactions as WHERE clauses over
+// the transactions table. The user id is always $1.
+func transactionFilterClauses(userID string, filter TransactionFilter) ([]string, []interface{}) {
+	clauses := []string{"user_id = $1", "deleted_at IS NULL"}
+	args := []interface{}{userID}
+	argIndex := 2
+
+	if !filter.IncludeVoided && filter.Status != TransactionStatusVoided {
+		clauses = append(clauses, fmt.Sprintf("status <> '%s'", TransactionStatusVoided))
+	}
+	if filter.Status != "" {
+		clauses = append(clauses, fmt.Sprintf("status = $%d", argIndex))
+		args = append(args, filter.Status)
+		argIndex++
+	}
+	if len(filter.AccountIDs) > 0 {
+		clauses = append(clauses, fmt.Sprintf("(account_id = ANY($%d) OR from_account_id = ANY($%d) OR to_account_id = ANY($%d))", argIndex, argIndex, argIndex))
+		args = append(args, pq.Array(filter.AccountIDs))
+		argIndex++
+	} else if filter.AccountID != "" {
+		clauses = append(clauses, fmt.Sprintf("(account_id = $%d OR from_account_id = $%d OR to_account_id = $%d)", argIndex, argIndex, argIndex))
+		args = append(args, filter.AccountID)
+		argIndex++
+	}
+	if filter.Type != "" {
+		if filter.Type == TransactionTypeTransfer {
+			clauses = append(clauses, fmt.Sprintf("type IN ('%s', '%s', '%s')", TransactionTypeTransfer, TransactionTypeTransferIn, TransactionTypeTransferOut))
+		} else {
+			clauses = append(clauses, fmt.Sprintf("type = $%d", argIndex))
+			args = append(args, filter.Type)
+			argIndex++
+		}
+	}
+	// Split entries match on any of their lines.
+	for _, item := range []struct {
+		column string
+		key    string
+		value  string
+	}{
+		{"category_id", "categoryId", filter.CategoryID},
+		{"budget_id", "budgetId", filter.BudgetID},
+	} {
+		if item.value == "" {
+			continue
+		}
+		clauses = append(clauses, fmt.Sprintf("(%s = $%d OR splits @> jsonb_build_array(jsonb_build_object('%s', $%d::text)))", item.column, argIndex, item.key, argIndex))
+		args = append(args, item.value)
+		argIndex++
+	}
+	columns := []struct {
+		column string
+		value  string
+	}{
+		{"linked_goal_id", filter.GoalID},
+		{"linked_debt_id", filter.DebtID},
+		{"counterparty_id", filter.CounterpartyID},
+		{"habit_id", filter.HabitID},
+	}
+	for _, item := range columns {
+		if item.value == "" {
+			continue
+		}
+		clauses = append(clauses, fmt.Sprintf("%s = $%d", item.column, argIndex))
+		args = append(args, item.value)
+		argIndex++
+	}
+	if filter.DateFrom != "" {
+		if _, err := time.Parse("2006-01-02", filter.DateFrom); err == nil {
+			clauses = append(clauses, fmt.Sprintf("date >= $%d", argIndex))
+			args = append(args, filter.DateFrom)
+			argIndex++
+		}
+	}
+	if filter.DateTo != "" {
+		if _, err := time.Parse("2006-01-02", filter.DateTo); err == nil {
+			clauses = append(clauses, fmt.Sprintf("date <= $%d", argIndex))
+			args = append(args, filter.DateTo)
+			argIndex++
+		}
+	}
+	amountColumn := transactionAmountColumn(filter.AmountBasis)
+	if filter.AmountMin != nil {
+		clauses = append(clauses, fmt.Sprintf("%s >= $%d", amountColumn, argIndex))
+		args = append(args, *filter.AmountMin)
+		argIndex++
+	}
+	if filter.AmountMax != nil {
+		clauses = append(clauses, fmt.Sprintf("%s <= $%d", amountColumn, argIndex))
+		args = append(args, *filter.AmountMax)
+		argIndex++
+	}
+	if len(filter.TagsAny) > 0 {
+		clauses = append(clauses, fmt.Sprintf("tags ?| $%d::text[]", argIndex))
+		args = append(args, pq.Array(filter.TagsAny))
+		argIndex++
+	}
+	if len(filter.TagsAll) > 0 {
+		clauses = append(clauses, fmt.Sprintf("tags ?& $%d::text[]", argIndex))
+		args = append(args, pq.Array(filter.TagsAll))
+		argIndex++
+	}
+	if filter.Search != "" {
+		clauses = append(clauses, fmt.Sprintf("(name ILIKE $%d OR description ILIKE $%d)", argIndex, argIndex))
+		args = append(args, "%"+escapeLikePattern(filter.Search)+"%")
+		argIndex++
+	}
+	return clauses, args
+}
+
 func (r *PostgresRepository) GetTransactionByID(ctx context.Context, id string) (*Transaction, error) {
 	userID, ok := ctx.Value("user_id").(string)
 	if !ok || userID == "" {
@@ -419,17 +625,29 @@ func (r *PostgresRepository) CreateTransaction(ctx context.Context, txn *Transac
 		return appErrors.DatabaseError
 	}
 
+	if err := r.applyTransaction(ctx, tx, userID, txn); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return appErrors.DatabaseError
+	}
+	return nil
+}
+
+// applyTransaction validates the entry against the locked account rows, inserts it
+// and moves the balances. The caller owns the surrounding database transaction.
+func (r *PostgresRepository) applyTransaction(ctx context.Context, tx *sqlx.Tx, userID string, txn *Transaction) error {
 	switch strings.ToLower(txn.Type) {
 	case TransactionTypeIncome, TransactionTypeExpense:
 		if txn.AccountID == nil || *txn.AccountID == "" {
 			log.Printf("[CreateTransaction] Missing accountId for type=%s", txn.Type)
-			_ = tx.Rollback()
 			return appErrors.InvalidFinanceData
 		}
 		account, err := fetchAccountForUpdate(ctx, tx, userID, *txn.AccountID)
 		if err != nil {
 			log.Printf("[CreateTransaction] Failed to fetch account=%s: %v", *txn.AccountID, err)
-			_ = tx.Rollback()
 			return err
 		}
 		if txn.Currency == "" {
@@ -437,19 +655,20 @@ func (r *PostgresRepository) CreateTransaction(ctx context.Context, txn *Transac
 		}
 		if txn.Currency != account.Currency {
 			log.Printf("[CreateTransaction] Currency mismatch: txn=%s, account=%s", txn.Currency, account.Currency)
-			_ = tx.Rollback()
 			return appErrors.InvalidFinanceData
 		}
-		if txn.Type == TransactionTypeExpense && account.CurrentBalance < txn.Amount {
-			log.Printf("[CreateTransaction] Insufficient funds: required=%.2f, available=%.2f", txn.Amount, account.CurrentBalance)
-			_ = tx.Rollback()
+		if !txn.IsScheduled && txn.Type == TransactionTypeExpense && account.available() < txn.Amount {
+			log.Printf("[CreateTransaction] Insufficient funds: required=%s, available=%s", txn.Amount, account.CurrentBalance)
 			return appErrors.InsufficientFunds
 		}
 		normalizeTransaction(txn)
 		if err := r.insertTransaction(ctx, tx, userID, txn); err != nil {
-			_ = tx.Rollback()
 			return err
 		}
+		if txn.IsScheduled {
+			// Moves the balance when it posts.
+			break
+		}
 		newBalance := account.CurrentBalance
 		if txn.Type == TransactionTypeIncome {
 			newBalance += txn.Amount
@@ -457,64 +676,58 @@ func (r *PostgresRepository) CreateTransaction(ctx context.Context, txn *Transac
 			newBalance -= txn.Amount
 		}
 		if err := updateAccountBalance(ctx, tx, userID, account.ID, newBalance); err != nil {
-			_ = tx.Rollback()
 			return err
 		}
 	case TransactionTypeDebtAdjustment, TransactionTypeBudgetAddValue, TransactionTypeDebtAddValue, TransactionTypeDebtFullPayment, TransactionTypeAccountDeleteWithdrawal:
 		if txn.AccountID == nil || *txn.AccountID == "" {
-			_ = tx.Rollback()
 			return appErrors.InvalidFinanceData
 		}
 		account, err := fetchAccountForUpdate(ctx, tx, userID, *txn.AccountID)
 		if err != nil {
-			_ = tx.Rollback()
 			return err
 		}
 		if txn.Currency == "" {
 			txn.Currency = account.Currency
 		}
 		if txn.Currency != account.Currency {
-			_ = tx.Rollback()
 			return appErrors.InvalidFinanceData
 		}
 		delta := txn.Amount
-		if delta < 0 && account.CurrentBalance < -delta {
-			_ = tx.Rollback()
+		if delta < 0 && account.available() < -delta {
 			return appErrors.InsufficientFunds
 		}
 		normalizeTransaction(txn)
 		if err := r.insertTransaction(ctx, tx, userID, txn); err != nil {
-			_ = tx.Rollback()
 			return err
 		}
 		newBalance := account.CurrentBalance + delta
 		if err := updateAccountBalance(ctx, tx, userID, account.ID, newBalance); err != nil {
-			_ = tx.Rollback()
 			return err
 		}
 	case TransactionTypeTransfer:
 		if txn.FromAccountID == nil || txn.ToAccountID == nil || *txn.FromAccountID == "" || *txn.ToAccountID == "" {
-			_ = tx.Rollback()
 			return appErrors.InvalidFinanceData
 		}
 		fromAccount, toAccount, err := fetchTransferAccountsForUpdate(ctx, tx, userID, *txn.FromAccountID, *txn.ToAccountID)
 		if err != nil {
-			_ = tx.Rollback()
 			return err
 		}
 		if fromAccount.Currency != toAccount.Currency {
-			_ = tx.Rollback()
-			return appErrors.InvalidFinanceData
+			// Only an exchange between the currencies of one account says
+			// what arrives on the other side.
+			if fromAccount.WalletID != toAccount.WalletID || txn.ToAmount <= 0 {
+				return appErrors.InvalidFinanceData
+			}
+			toCurrency := toAccount.Currency
+			txn.ToCurrency = &toCurrency
 		}
 		if txn.Currency == "" {
 			txn.Currency = fromAccount.Currency
 		}
 		if txn.Currency != fromAccount.Currency {
-			_ = tx.Rollback()
 			return appErrors.InvalidFinanceData
 		}
-		if fromAccount.CurrentBalance < txn.Amount {
-			_ = tx.Rollback()
+		if !txn.IsScheduled && fromAccount.available() < txn.Amount {
 			return appErrors.InsufficientFunds
 		}
 		if txn.ToAmount == 0 {
@@ -532,7 +745,6 @@ func (r *PostgresRepository) CreateTransaction(ctx context.Context, txn *Transac
 		transferOut.ReferenceID = &referenceID
 		normalizeTransaction(&transferOut)
 		if err := r.insertTransaction(ctx, tx, userID, &transferOut); err != nil {
-			_ = tx.Rollback()
 			return err
 		}
 
@@ -546,17 +758,16 @@ func (r *PostgresRepository) CreateTransaction(ctx context.Context, txn *Transac
 		transferIn.ReferenceID = &referenceID
 		normalizeTransaction(&transferIn)
 		if err := r.insertTransaction(ctx, tx, userID, &transferIn); err != nil {
-			_ = tx.Rollback()
 			return err
 		}
 
-		if err := updateAccountBalance(ctx, tx, userID, fromAccount.ID, fromAccount.CurrentBalance-txn.Amount); err != nil {
-			_ = tx.Rollback()
-			return err
-		}
-		if err := updateAccountBalance(ctx, tx, userID, toAccount.ID, toAccount.CurrentBalance+txn.ToAmount); err != nil {
-			_ = tx.Rollback()
-			return err
+		if !txn.IsScheduled {
+			if err := updateAccountBalance(ctx, tx, userID, fromAccount.ID, fromAccount.CurrentBalance-txn.Amount); err != nil {
+				return err
+			}
+			if err := updateAccountBalance(ctx, tx, userID, toAccount.ID, toAccount.CurrentBalance+txn.ToAmount); err != nil {
+				return err
+			}
 		}
 
 		txn.ID = transferOut.ID
@@ -564,13 +775,8 @@ func (r *PostgresRepository) CreateTransaction(ctx context.Context, txn *Transac
 		txn.ReferenceType = transferOut.ReferenceType
 		txn.ReferenceID = transferOut.ReferenceID
 	default:
-		_ = tx.Rollback()
 		return appErrors.InvalidFinanceData
 	}
-
-	if err := tx.Commit(); err != nil {
-		return appErrors.DatabaseError
-	}
 	return nil
 }
 
@@ -594,6 +800,10 @@ func (r *PostgresRepository) UpdateTransaction(ctx context.Context, txn *Transac
 	if err != nil {
 		return appErrors.InvalidFinanceData
 	}
+	splits, err := marshalTransactionSplits(txn.Splits)
+	if err != nil {
+		return appErrors.InvalidFinanceData
+	}
 
 	categoryValue := resolveTransactionCategory(txn)
 
@@ -641,8 +851,12 @@ func (r *PostgresRepository) UpdateTransaction(ctx context.Context, txn *Transac
 			conversion_rate = $40,
 			occurred_at = $41,
 			metadata = $42,
-			updated_at = $43
-		WHERE id = $44 AND user_id = $45 AND deleted_at IS NULL
+			updated_at = $43,
+			splits = $46,
+			reconciliation_id = $47,
+			cleared_at = $48,
+			is_scheduled = $49
+		WHERE id = $44 AND user_id = $45 AND deleted_at IS NULL AND reconciliation_id IS NULL
 	`, txn.Type, txn.Status, txn.AccountID, txn.FromAccountID, txn.ToAccountID,
 		txn.Amount, txn.Currency, txn.BaseCurrency, txn.RateUsedToBase, txn.ConvertedAmountToBase,
 		txn.ToAmount, txn.ToCurrency, txn.EffectiveRateFromTo, txn.FeeAmount, txn.FeeCategoryID,
@@ -650,7 +864,8 @@ func (r *PostgresRepository) UpdateTransaction(ctx context.Context, txn *Transac
 		txn.GoalID, txn.BudgetID, txn.DebtID, txn.HabitID, txn.CounterpartyID, txn.RecurringID,
 		attachments, tags, txn.IsBalanceAdjustment, txn.SkipBudgetMatching, txn.ShowStatus,
 		txn.RelatedBudgetID, txn.RelatedDebtID, txn.PlannedAmount, txn.PaidAmount,
-		txn.OriginalCurrency, txn.OriginalAmount, txn.ConversionRate, txn.OccurredAt, metadata, txn.UpdatedAt, txn.ID, userID)
+		txn.OriginalCurrency, txn.OriginalAmount, txn.ConversionRate, txn.OccurredAt, metadata, txn.UpdatedAt, txn.ID, userID, splits,
+		txn.ReconciliationID, txn.ClearedAt, txn.IsScheduled)
 
 	if err != nil {
 		return appErrors.DatabaseError
@@ -662,12 +877,98 @@ func (r *PostgresRepository) UpdateTransaction(ctx context.Context, txn *Transac
 	}
 
 	if rows == 0 {
+		var locked bool
+		err := r.db.GetContext(ctx, &locked, `
+			SELECT reconciliation_id IS NOT NULL FROM transactions
+			WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+		`, txn.ID, userID)
+		if err == sql.ErrNoRows {
+			return appErrors.TransactionNotFound
+		}
+		if err != nil {
+			return appErrors.DatabaseError
+		}
+		if locked {
+			return appErrors.TransactionLocked
+		}
 		return appErrors.TransactionNotFound
 	}
 
 	return nil
 }
 
+func (r *PostgresRepository) ReclassifyTransactions(ctx context.Context, updates []*Transaction) ([]string, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Printf("[ReclassifyTransactions] Begin error for user=%s: %v", userID, err)
+		return nil, appErrors.DatabaseError
+	}
+	skipped := make([]string, 0)
+	now := utils.NowUTC()
+	for _, update := range updates {
+		tags, err := json.Marshal(update.Tags)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, appErrors.InvalidFinanceData
+		}
+		result, err := tx.ExecContext(ctx, `
+			UPDATE transactions
+			SET category_id = $1,
+				category = $2,
+				subcategory_id = $3,
+				budget_id = $4,
+				counterparty_id = $5,
+				tags = $6,
+				updated_at = $7
+			WHERE id = $8 AND user_id = $9 AND deleted_at IS NULL
+				AND status <> $10 AND reconciliation_id IS NULL
+		`, update.CategoryID, resolveTransactionCategory(update), update.SubcategoryID, update.BudgetID,
+			update.CounterpartyID, tags, now, update.ID, userID, TransactionStatusVoided)
+		if err != nil {
+			log.Printf("[ReclassifyTransactions] UPDATE error for id=%s: %v", update.ID, err)
+			_ = tx.Rollback()
+			return nil, appErrors.DatabaseError
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			skipped = append(skipped, update.ID)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("[ReclassifyTransactions] Commit error for user=%s: %v", userID, err)
+		return nil, appErrors.DatabaseError
+	}
+	return skipped, nil
+}
+
+func (r *PostgresRepository) UpdateTransactionAttachments(ctx context.Context, id string, attachments []string) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	encoded, err := json.Marshal(attachments)
+	if err != nil {
+		return appErrors.InvalidFinanceData
+	}
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE transactions SET attachments = $1, updated_at = $2
+		WHERE id = $3 AND user_id = $4 AND deleted_at IS NULL
+	`, encoded, utils.NowUTC(), id, userID)
+	if err != nil {
+		log.Printf("[UpdateTransactionAttachments] UPDATE error for id=%s: %v", id, err)
+		return appErrors.DatabaseError
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return appErrors.TransactionNotFound
+	}
+	return nil
+}
+
 func resolveTransactionCategory(txn *Transaction) string {
 	if txn == nil {
 		return "Other"
@@ -682,31 +983,39 @@ func resolveTransactionCategory(txn *Transaction) string {
 }
 
 type accountBalanceRow struct {
-	ID             string  `db:"id"`
-	Currency       string  `db:"currency"`
-	InitialBalance float64 `db:"initial_balance"`
-	CurrentBalance float64 `db:"current_balance"`
+	ID             string `db:"id"`
+	Currency       string `db:"currency"`
+	InitialBalance Money  `db:"initial_balance"`
+	CurrentBalance Money  `db:"current_balance"`
+	CreditLimit    Money  `db:"credit_limit"`
+	WalletID       string `db:"wallet_id"`
+}
+
+// available is what the account can still spend: its balance plus, for a
+// credit card, the credit limit.
+func (a *accountBalanceRow) available() Money {
+	return a.CurrentBalance + a.CreditLimit
 }
 
 type debtBalanceRow struct {
-	ID                  string         `db:"id"`
-	Direction           string         `db:"direction"`
-	PrincipalAmount     float64        `db:"principal_amount"`
-	PrincipalCurrency   string         `db:"principal_currency"`
-	RepaymentCurrency   sql.NullString `db:"repayment_currency"`
-	RemainingAmount     float64        `db:"remaining_amount"`
-	TotalPaid           float64        `db:"total_paid"`
-	TotalPaidInRepaymentCurrency float64 `db:"total_paid_in_repayment_currency"`
-	FundingAccountID    sql.NullString `db:"funding_account_id"`
-	LentFromAccountID   sql.NullString `db:"lent_from_account_id"`
-	ReceivedToAccountID sql.NullString `db:"received_to_account_id"`
+	ID                           string         `db:"id"`
+	Direction                    string         `db:"direction"`
+	PrincipalAmount              Money          `db:"principal_amount"`
+	PrincipalCurrency            string         `db:"principal_currency"`
+	RepaymentCurrency            sql.NullString `db:"repayment_currency"`
+	RemainingAmount              Money          `db:"remaining_amount"`
+	TotalPaid                    Money          `db:"total_paid"`
+	TotalPaidInRepaymentCurrency Money          `db:"total_paid_in_repayment_currency"`
+	FundingAccountID             sql.NullString `db:"funding_account_id"`
+	LentFromAccountID            sql.NullString `db:"lent_from_account_id"`
+	ReceivedToAccountID          sql.NullString `db:"received_to_account_id"`
 }
 
 type debtPaymentBalanceRow struct {
 	ID                    string         `db:"id"`
-	Amount                float64        `db:"amount"`
+	Amount                Money          `db:"amount"`
 	Currency              string         `db:"currency"`
-	ConvertedAmountToDebt float64        `db:"converted_amount_to_debt"`
+	ConvertedAmountToDebt Money          `db:"converted_amount_to_debt"`
 	AccountID             sql.NullString `db:"account_id"`
 }
 
@@ -762,6 +1071,10 @@ func (r *PostgresRepository) insertTransaction(ctx context.Context, execer sqlx.
 	if err != nil {
 		return appErrors.InvalidFinanceData
 	}
+	splits, err := marshalTransactionSplits(txn.Splits)
+	if err != nil {
+		return appErrors.InvalidFinanceData
+	}
 	categoryValue := resolveTransactionCategory(txn)
 
 	if _, err := execer.ExecContext(ctx, `
@@ -775,7 +1088,8 @@ func (r *PostgresRepository) insertTransaction(ctx context.Context, execer sqlx.
 			counterparty_id, recurring_id, attachments, tags,
 			is_balance_adjustment, skip_budget_matching, show_status,
 			related_budget_id, related_debt_id, planned_amount, paid_amount,
-			original_currency, original_amount, conversion_rate, occurred_at, metadata, created_at, updated_at
+			original_currency, original_amount, conversion_rate, occurred_at, metadata, created_at, updated_at,
+			splits, reconciliation_id, cleared_at, is_scheduled
 		)
 		VALUES (
 			$1,$2,$3,$4,$5,$6,$7,
@@ -787,7 +1101,8 @@ func (r *PostgresRepository) insertTransaction(ctx context.Context, execer sqlx.
 			$31,$32,$33,$34,
 			$35,$36,$37,
 			$38,$39,$40,$41,$42,$43,$44,$45,$46,$47
-			,$48
+			,$48,
+			$49,$50,$51,$52
 		)
 	`, txn.ID, userID, txn.Type, txn.Status, txn.AccountID, txn.FromAccountID, txn.ToAccountID,
 		txn.ReferenceType, txn.ReferenceID,
@@ -799,14 +1114,43 @@ func (r *PostgresRepository) insertTransaction(ctx context.Context, execer sqlx.
 		txn.IsBalanceAdjustment, txn.SkipBudgetMatching, txn.ShowStatus,
 		txn.RelatedBudgetID, txn.RelatedDebtID, txn.PlannedAmount, txn.PaidAmount,
 		txn.OriginalCurrency, txn.OriginalAmount, txn.ConversionRate, txn.OccurredAt, metadata, txn.CreatedAt, txn.UpdatedAt,
+		splits, txn.ReconciliationID, txn.ClearedAt, txn.IsScheduled,
 	); err != nil {
-		log.Printf("[insertTransaction] INSERT error for type=%s, amount=%.2f: %v", txn.Type, txn.Amount, err)
+		log.Printf("[insertTransaction] INSERT error for type=%s, amount=%s: %v", txn.Type, txn.Amount, err)
 		return appErrors.DatabaseError
 	}
 	return nil
 }
 
-func updateAccountBalance(ctx context.Context, tx *sqlx.Tx, userID, accountID string, balance float64) error {
+// marshalTransactionSplits stores an entry without lines as an empty array.
+func marshalTransactionSplits(splits []TransactionSplit) ([]byte, error) {
+	if len(splits) == 0 {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(splits)
+}
+
+// marshalIDList stores an empty id list as [] so containment queries never
+// see a JSON null.
+func marshalIDList(ids []string) ([]byte, error) {
+	if len(ids) == 0 {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(ids)
+}
+
+func decodeIDList(data []byte) []string {
+	ids := []string{}
+	if len(data) > 0 {
+		_ = json.Unmarshal(data, &ids)
+	}
+	if ids == nil {
+		ids = []string{}
+	}
+	return ids
+}
+
+func updateAccountBalance(ctx context.Context, tx *sqlx.Tx, userID, accountID string, balance Money) error {
 	if _, err := tx.ExecContext(ctx, `
 		UPDATE accounts
 		SET current_balance = $1, updated_at = $2
@@ -820,7 +1164,7 @@ func updateAccountBalance(ctx context.Context, tx *sqlx.Tx, userID, accountID st
 func fetchAccountForUpdate(ctx context.Context, tx *sqlx.Tx, userID, accountID string) (*accountBalanceRow, error) {
 	var row accountBalanceRow
 	if err := tx.GetContext(ctx, &row, `
-		SELECT id, currency, initial_balance, current_balance
+		SELECT id, currency, initial_balance, current_balance, credit_limit
 		FROM accounts
 		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 		FOR UPDATE
@@ -846,7 +1190,7 @@ func fetchAccountForUpdate(ctx context.Context, tx *sqlx.Tx, userID, accountID s
 
 func fetchTransferAccountsForUpdate(ctx context.Context, tx *sqlx.Tx, userID, fromID, toID string) (*accountBalanceRow, *accountBalanceRow, error) {
 	rows, err := tx.QueryxContext(ctx, `
-		SELECT id, currency, current_balance
+		SELECT id, currency, current_balance, credit_limit, COALESCE(parent_account_id, id) AS wallet_id
 		FROM accounts
 		WHERE id IN ($1, $2) AND user_id = $3 AND deleted_at IS NULL
 		FOR UPDATE
@@ -1012,97 +1356,342 @@ func (r *PostgresRepository) DeleteTransaction(ctx context.Context, id string) e
 	return nil
 }
 
-// ========== BUDGETS ==========
-
-func (r *PostgresRepository) ListBudgets(ctx context.Context) ([]*Budget, error) {
+func (r *PostgresRepository) ReverseTransaction(ctx context.Context, id string, input TransactionReversalInput) (*TransactionReversal, error) {
 	userID, ok := ctx.Value("user_id").(string)
 	if !ok || userID == "" {
 		return nil, appErrors.InvalidToken
 	}
 
-	query := fmt.Sprintf(`
-		SELECT %s FROM budgets
-		WHERE user_id = $1 AND deleted_at IS NULL
-		ORDER BY created_at DESC
-	`, budgetSelectFields)
-
-	rows, err := r.db.QueryxContext(ctx, query, userID)
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
+		log.Printf("[ReverseTransaction] Failed to begin transaction: %v", err)
 		return nil, appErrors.DatabaseError
 	}
-	defer rows.Close()
 
-	var budgets []*Budget
-	for rows.Next() {
-		var row budgetRow
-		if err := rows.StructScan(&row); err != nil {
-			return nil, appErrors.DatabaseError
-		}
-		budgets = append(budgets, mapRowToBudget(row))
+	original, err := fetchTransactionForUpdate(ctx, tx, userID, id)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
 	}
-
-	return budgets, nil
-}
-
-func (r *PostgresRepository) GetBudgetByID(ctx context.Context, id string) (*Budget, error) {
-	userID, ok := ctx.Value("user_id").(string)
-	if !ok || userID == "" {
-		return nil, appErrors.InvalidToken
+	if original.Status == TransactionStatusVoided {
+		_ = tx.Rollback()
+		return nil, appErrors.TransactionAlreadyVoided
+	}
+	if !isReversibleTransactionType(original.Type) {
+		_ = tx.Rollback()
+		return nil, appErrors.WithDetails(appErrors.TransactionNotReversible, map[string]interface{}{"type": original.Type})
 	}
 
-	query := fmt.Sprintf(`
-		SELECT %s FROM budgets
-		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
-	`, budgetSelectFields)
+	entries := []*Transaction{original}
+	if original.ReferenceType != nil && *original.ReferenceType == "transfer" && original.ReferenceID != nil {
+		entries, err = fetchTransactionsByReferenceForUpdate(ctx, tx, userID, "transfer", *original.ReferenceID)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+	}
 
-	var row budgetRow
-	if err := r.db.GetContext(ctx, &row, query, id, userID); err != nil {
-		if err == sql.ErrNoRows {
-			log.Printf("[GetBudgetByID] Budget not found: id=%s", id)
-			return nil, appErrors.BudgetNotFound
+	result := &TransactionReversal{
+		Voided:    make([]*Transaction, 0, len(entries)),
+		Reversals: make([]*Transaction, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		if entry.Status == TransactionStatusVoided {
+			_ = tx.Rollback()
+			return nil, appErrors.TransactionAlreadyVoided
 		}
-		log.Printf("[GetBudgetByID] DB error for id=%s: %v", id, err)
-		return nil, appErrors.DatabaseError
+		reversals, err := r.reverseEntry(ctx, tx, userID, entry, input)
+		if err != nil {
+			log.Printf("[ReverseTransaction] Failed to reverse entry=%s: %v", entry.ID, err)
+			_ = tx.Rollback()
+			return nil, err
+		}
+		result.Voided = append(result.Voided, entry)
+		result.Reversals = append(result.Reversals, reversals...)
 	}
-	return mapRowToBudget(row), nil
-}
 
-func (r *PostgresRepository) CreateBudget(ctx context.Context, budget *Budget) error {
-	userID, ok := ctx.Value("user_id").(string)
-	if !ok || userID == "" {
-		return appErrors.InvalidToken
+	if input.Replacement != nil {
+		if input.Replacement.Amount == 0 {
+			_ = tx.Rollback()
+			return nil, appErrors.InvalidFinanceData
+		}
+		input.Replacement.Type = strings.ToLower(input.Replacement.Type)
+		if err := r.applyTransaction(ctx, tx, userID, input.Replacement); err != nil {
+			log.Printf("[ReverseTransaction] Failed to apply correction for id=%s: %v", id, err)
+			_ = tx.Rollback()
+			return nil, err
+		}
+		result.Correction = input.Replacement
 	}
 
-	if budget.ID == "" {
-		budget.ID = uuid.NewString()
+	if err := tx.Commit(); err != nil {
+		return nil, appErrors.DatabaseError
 	}
+	return result, nil
+}
 
-	now := utils.NowUTC()
-	budget.CreatedAt = now
+// reverseEntry voids entry and books its reversals. The caller holds the
+// entry's row lock, so a reconciliation cannot clear it in between.
+func (r *PostgresRepository) reverseEntry(ctx context.Context, tx *sqlx.Tx, userID string, entry *Transaction, input TransactionReversalInput) ([]*Transaction, error) {
+	if entry.ReconciliationID != nil {
+		return nil, appErrors.TransactionLocked
+	}
+	reversals := make([]*Transaction, 0, 2)
+	for _, accountID := range transactionAccountIDs(entry) {
+		delta := transactionDeltaForAccount(accountID, entry)
+		if delta == 0 {
+			continue
+		}
+		account, err := fetchAccountForUpdate(ctx, tx, userID, accountID)
+		if err != nil {
+			return nil, err
+		}
+		if delta > 0 && account.available() < delta {
+			return nil, appErrors.WithDetails(appErrors.InsufficientFunds, map[string]interface{}{
+				"required":  delta,
+				"available": account.available(),
+				"currency":  account.Currency,
+			})
+		}
+		reversal := buildReversalTransaction(entry, accountID, account.Currency, -delta, input)
+		if err := r.insertTransaction(ctx, tx, userID, reversal); err != nil {
+			return nil, err
+		}
+		if err := updateAccountBalance(ctx, tx, userID, account.ID, account.CurrentBalance-delta); err != nil {
+			return nil, err
+		}
+		reversals = append(reversals, reversal)
+	}
+
+	switch entry.Type {
+	case TransactionTypeDebtPayment, TransactionTypeDebtFullPayment:
+		if err := revertDebtPaymentForTransaction(ctx, tx, userID, entry); err != nil {
+			return nil, err
+		}
+	case TransactionTypeDebtAddValue:
+		if err := revertDebtAddValue(ctx, tx, userID, entry); err != nil {
+			return nil, err
+		}
+	}
+
+	markTransactionVoided(entry, reversals, input.Note)
+	metadata, err := json.Marshal(entry.Metadata)
+	if err != nil {
+		return nil, appErrors.InvalidFinanceData
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE transactions
+		SET status = $1, metadata = $2, updated_at = $3
+		WHERE id = $4 AND user_id = $5 AND deleted_at IS NULL
+	`, entry.Status, metadata, entry.UpdatedAt, entry.ID, userID); err != nil {
+		return nil, appErrors.DatabaseError
+	}
+	return reversals, nil
+}
+
+func fetchTransactionForUpdate(ctx context.Context, tx *sqlx.Tx, userID, id string) (*Transaction, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM transactions
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+		FOR UPDATE
+	`, transactionSelectFields)
+
+	var row transactionRow
+	if err := tx.GetContext(ctx, &row, query, id, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, appErrors.TransactionNotFound
+		}
+		log.Printf("[fetchTransactionForUpdate] DB error for id=%s: %v", id, err)
+		return nil, appErrors.DatabaseError
+	}
+	return mapRowToTransaction(row), nil
+}
+
+func fetchTransactionsByReferenceForUpdate(ctx context.Context, tx *sqlx.Tx, userID, referenceType, referenceID string) ([]*Transaction, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM transactions
+		WHERE user_id = $1 AND reference_type = $2 AND reference_id = $3 AND deleted_at IS NULL
+		ORDER BY created_at ASC
+		FOR UPDATE
+	`, transactionSelectFields)
+
+	rows, err := tx.QueryxContext(ctx, query, userID, referenceType, referenceID)
+	if err != nil {
+		return nil, appErrors.DatabaseError
+	}
+	defer rows.Close()
+
+	var transactions []*Transaction
+	for rows.Next() {
+		var row transactionRow
+		if err := rows.StructScan(&row); err != nil {
+			return nil, appErrors.DatabaseError
+		}
+		transactions = append(transactions, mapRowToTransaction(row))
+	}
+	if len(transactions) == 0 {
+		return nil, appErrors.TransactionNotFound
+	}
+	return transactions, nil
+}
+
+func revertDebtPaymentForTransaction(ctx context.Context, tx *sqlx.Tx, userID string, entry *Transaction) error {
+	if entry.DebtID == nil || *entry.DebtID == "" {
+		return nil
+	}
+	debtRow, err := fetchDebtForUpdate(ctx, tx, userID, *entry.DebtID)
+	if err != nil {
+		return err
+	}
+	var payment debtPaymentBalanceRow
+	if err := tx.GetContext(ctx, &payment, `
+		SELECT id, amount, currency, converted_amount_to_debt, account_id
+		FROM debt_payments
+		WHERE debt_id = $1 AND related_transaction_id = $2 AND deleted_at IS NULL
+		FOR UPDATE
+	`, debtRow.ID, entry.ID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return appErrors.DatabaseError
+	}
+
+	now := utils.NowUTC()
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE debt_payments
+		SET deleted_at = $1, updated_at = $2
+		WHERE id = $3 AND deleted_at IS NULL
+	`, now, now, payment.ID); err != nil {
+		return appErrors.DatabaseError
+	}
+	if err := restoreDebtPaymentTotals(ctx, tx, userID, debtRow, &payment); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE debts
+		SET status = 'active', settled_at = NULL, updated_at = $1
+		WHERE id = $2 AND user_id = $3 AND status = 'paid' AND deleted_at IS NULL
+	`, now, debtRow.ID, userID); err != nil {
+		return appErrors.DatabaseError
+	}
+	return nil
+}
+
+func revertDebtAddValue(ctx context.Context, tx *sqlx.Tx, userID string, entry *Transaction) error {
+	if entry.DebtID == nil || *entry.DebtID == "" {
+		return nil
+	}
+	debtRow, err := fetchDebtForUpdate(ctx, tx, userID, *entry.DebtID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE debts
+		SET principal_amount = GREATEST(principal_amount - $1, 0),
+			principal_base_value = GREATEST(principal_amount - $1, 0) * rate_on_start,
+			updated_at = $2
+		WHERE id = $3 AND user_id = $4 AND deleted_at IS NULL
+	`, entry.OriginalAmount.Abs(), utils.NowUTC(), debtRow.ID, userID); err != nil {
+		return appErrors.DatabaseError
+	}
+	return nil
+}
+
+// ========== BUDGETS ==========
+
+func (r *PostgresRepository) ListBudgets(ctx context.Context) ([]*Budget, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM budgets
+		WHERE user_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at DESC
+	`, budgetSelectFields)
+
+	rows, err := r.db.QueryxContext(ctx, query, userID)
+	if err != nil {
+		return nil, appErrors.DatabaseError
+	}
+	defer rows.Close()
+
+	var budgets []*Budget
+	for rows.Next() {
+		var row budgetRow
+		if err := rows.StructScan(&row); err != nil {
+			return nil, appErrors.DatabaseError
+		}
+		budgets = append(budgets, mapRowToBudget(row))
+	}
+
+	return budgets, nil
+}
+
+func (r *PostgresRepository) GetBudgetByID(ctx context.Context, id string) (*Budget, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM budgets
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+	`, budgetSelectFields)
+
+	var row budgetRow
+	if err := r.db.GetContext(ctx, &row, query, id, userID); err != nil {
+		if err == sql.ErrNoRows {
+			log.Printf("[GetBudgetByID] Budget not found: id=%s", id)
+			return nil, appErrors.BudgetNotFound
+		}
+		log.Printf("[GetBudgetByID] DB error for id=%s: %v", id, err)
+		return nil, appErrors.DatabaseError
+	}
+	return mapRowToBudget(row), nil
+}
+
+func (r *PostgresRepository) CreateBudget(ctx context.Context, budget *Budget) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	if budget.ID == "" {
+		budget.ID = uuid.NewString()
+	}
+
+	now := utils.NowUTC()
+	budget.CreatedAt = now
 	budget.UpdatedAt = now
 
 	categoryIDs, err := json.Marshal(budget.CategoryIDs)
 	if err != nil {
 		return appErrors.InvalidFinanceData
 	}
+	alertThresholds, err := json.Marshal(budget.AlertThresholds)
+	if err != nil {
+		return appErrors.InvalidFinanceData
+	}
 
 	_, err = r.db.ExecContext(ctx, `
 		INSERT INTO budgets (
 			id, user_id, name, budget_type, category_ids, linked_goal_id, account_id, transaction_type,
 			currency, limit_amount, period_type, start_date, end_date, spent_amount, remaining_amount,
 			percent_used, is_overspent, rollover_mode, notify_on_exceed, contribution_total, current_balance,
-			is_archived, show_status, created_at, updated_at
+			is_archived, show_status, created_at, updated_at, alert_thresholds
 		)
 		VALUES (
 			$1,$2,$3,$4,$5,$6,$7,$8,
 			$9,$10,$11,$12,$13,$14,$15,
 			$16,$17,$18,$19,$20,$21,
-			$22,$23,$24,$25
+			$22,$23,$24,$25,$26
 		)
 	`, budget.ID, userID, budget.Name, budget.BudgetType, categoryIDs, budget.LinkedGoalID, budget.AccountID, budget.TransactionType,
 		budget.Currency, budget.LimitAmount, budget.PeriodType, budget.StartDate, budget.EndDate, budget.SpentAmount, budget.RemainingAmount,
 		budget.PercentUsed, budget.IsOverspent, budget.RolloverMode, budget.NotifyOnExceed, budget.ContributionTotal, budget.CurrentBalance,
-		budget.IsArchived, budget.ShowStatus, budget.CreatedAt, budget.UpdatedAt)
+		budget.IsArchived, budget.ShowStatus, budget.CreatedAt, budget.UpdatedAt, alertThresholds)
 
 	if err != nil {
 		log.Printf("[CreateBudget] INSERT error for name=%s: %v", budget.Name, err)
@@ -1124,6 +1713,10 @@ func (r *PostgresRepository) UpdateBudget(ctx context.Context, budget *Budget) e
 	if err != nil {
 		return appErrors.InvalidFinanceData
 	}
+	alertThresholds, err := json.Marshal(budget.AlertThresholds)
+	if err != nil {
+		return appErrors.InvalidFinanceData
+	}
 
 	result, err := r.db.ExecContext(ctx, `
 		UPDATE budgets
@@ -1148,12 +1741,13 @@ func (r *PostgresRepository) UpdateBudget(ctx context.Context, budget *Budget) e
 			current_balance = $19,
 			is_archived = $20,
 			show_status = $21,
-			updated_at = $22
+			updated_at = $22,
+			alert_thresholds = $25
 		WHERE id = $23 AND user_id = $24 AND deleted_at IS NULL
 	`, budget.Name, budget.BudgetType, categoryIDs, budget.LinkedGoalID, budget.AccountID, budget.TransactionType, budget.Currency,
 		budget.LimitAmount, budget.PeriodType, budget.StartDate, budget.EndDate, budget.SpentAmount, budget.RemainingAmount,
 		budget.PercentUsed, budget.IsOverspent, budget.RolloverMode, budget.NotifyOnExceed, budget.ContributionTotal, budget.CurrentBalance,
-		budget.IsArchived, budget.ShowStatus, budget.UpdatedAt, budget.ID, userID)
+		budget.IsArchived, budget.ShowStatus, budget.UpdatedAt, budget.ID, userID, alertThresholds)
 
 	if err != nil {
 		log.Printf("[UpdateBudget] UPDATE error for id=%s: %v", budget.ID, err)
@@ -1174,6 +1768,27 @@ func (r *PostgresRepository) UpdateBudget(ctx context.Context, budget *Budget) e
 	return nil
 }
 
+func (r *PostgresRepository) UpdateBudgetRollups(ctx context.Context, budget *Budget) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE budgets
+		SET spent_amount = $1, remaining_amount = $2, percent_used = $3, is_overspent = $4, updated_at = $5
+		WHERE id = $6 AND user_id = $7 AND deleted_at IS NULL
+	`, budget.SpentAmount, budget.RemainingAmount, budget.PercentUsed, budget.IsOverspent, utils.NowUTC(), budget.ID, userID)
+	if err != nil {
+		log.Printf("[UpdateBudgetRollups] UPDATE error for id=%s: %v", budget.ID, err)
+		return appErrors.DatabaseError
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return appErrors.BudgetNotFound
+	}
+	return nil
+}
+
 func (r *PostgresRepository) DeleteBudget(ctx context.Context, id string) error {
 	userID, ok := ctx.Value("user_id").(string)
 	if !ok || userID == "" {
@@ -1203,92 +1818,259 @@ func (r *PostgresRepository) DeleteBudget(ctx context.Context, id string) error
 	return nil
 }
 
-// ========== DEBTS ==========
-
-func (r *PostgresRepository) ListDebts(ctx context.Context) ([]*Debt, error) {
+// RecordBudgetAlert stores the alert unless the same threshold has already
+// fired for the budget period; the boolean reports whether it was inserted.
+func (r *PostgresRepository) RecordBudgetAlert(ctx context.Context, alert *BudgetAlert) (bool, error) {
 	userID, ok := ctx.Value("user_id").(string)
 	if !ok || userID == "" {
-		return nil, appErrors.InvalidToken
+		return false, appErrors.InvalidToken
 	}
 
-	query := fmt.Sprintf(`
-		SELECT %s FROM debts
-		WHERE user_id = $1 AND deleted_at IS NULL
-		ORDER BY created_at DESC
-	`, debtSelectFields)
+	if alert.ID == "" {
+		alert.ID = uuid.NewString()
+	}
+	alert.UserID = userID
+	alert.CreatedAt = utils.NowUTC()
 
-	rows, err := r.db.QueryxContext(ctx, query, userID)
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO budget_alerts (id, budget_id, user_id, period_start, threshold, percent_used, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (budget_id, period_start, threshold) DO NOTHING
+	`, alert.ID, alert.BudgetID, userID, alert.PeriodStart, alert.Threshold, alert.PercentUsed, alert.CreatedAt)
 	if err != nil {
-		return nil, appErrors.DatabaseError
+		log.Printf("[RecordBudgetAlert] INSERT error for budget=%s: %v", alert.BudgetID, err)
+		return false, appErrors.DatabaseError
 	}
-	defer rows.Close()
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, appErrors.DatabaseError
+	}
+	return rows > 0, nil
+}
 
-	var debts []*Debt
-	for rows.Next() {
-		var row debtRow
-		if err := rows.StructScan(&row); err != nil {
-			return nil, appErrors.DatabaseError
-		}
-		debts = append(debts, mapRowToDebt(row))
+// ListBudgetsDueForClose is used by the period scheduler and is intentionally
+// not scoped to the user in ctx.
+func (r *PostgresRepository) ListBudgetsDueForClose(ctx context.Context, asOf string) ([]*Budget, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM budgets
+		WHERE deleted_at IS NULL AND show_status = 'active'
+			AND period_type IN ('weekly', 'monthly')
+			AND end_date IS NOT NULL AND end_date < $1
+		ORDER BY end_date ASC
+	`, budgetSelectFields)
+
+	var rows []budgetRow
+	if err := r.db.SelectContext(ctx, &rows, query, asOf); err != nil {
+		log.Printf("[ListBudgetsDueForClose] Query error: %v", err)
+		return nil, appErrors.DatabaseError
 	}
 
-	return debts, nil
+	budgets := make([]*Budget, 0, len(rows))
+	for _, row := range rows {
+		budgets = append(budgets, mapRowToBudget(row))
+	}
+	return budgets, nil
 }
 
-func (r *PostgresRepository) GetDebtByID(ctx context.Context, id string) (*Debt, error) {
+func (r *PostgresRepository) ListBudgetPeriods(ctx context.Context, budgetID string) ([]*BudgetPeriod, error) {
 	userID, ok := ctx.Value("user_id").(string)
 	if !ok || userID == "" {
 		return nil, appErrors.InvalidToken
 	}
 
 	query := fmt.Sprintf(`
-		SELECT %s FROM debts
-		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
-	`, debtSelectFields)
-
-	var row debtRow
-	if err := r.db.GetContext(ctx, &row, query, id, userID); err != nil {
-		if err == sql.ErrNoRows {
-			log.Printf("[GetDebtByID] Debt not found: id=%s", id)
-			return nil, appErrors.DebtNotFound
-		}
-		log.Printf("[GetDebtByID] DB error for id=%s: %v", id, err)
+		SELECT %s FROM budget_periods
+		WHERE budget_id = $1 AND user_id = $2
+		ORDER BY period_start DESC
+	`, budgetPeriodSelectFields)
+
+	var rows []budgetPeriodRow
+	if err := r.db.SelectContext(ctx, &rows, query, budgetID, userID); err != nil {
+		log.Printf("[ListBudgetPeriods] Query error: %v", err)
 		return nil, appErrors.DatabaseError
 	}
-	return mapRowToDebt(row), nil
+
+	items := make([]*BudgetPeriod, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, mapRowToBudgetPeriod(row))
+	}
+	return items, nil
 }
 
-func (r *PostgresRepository) CreateDebt(ctx context.Context, debt *Debt) error {
+// CloseBudgetPeriod stores the period snapshot and moves the budget into its
+// next window in one transaction. The unique (budget_id, period_start) key
+// makes a second close of the same window fail with BudgetPeriodClosed.
+func (r *PostgresRepository) CloseBudgetPeriod(ctx context.Context, budget *Budget, period *BudgetPeriod) error {
 	userID, ok := ctx.Value("user_id").(string)
 	if !ok || userID == "" {
 		return appErrors.InvalidToken
 	}
 
-	if debt.ID == "" {
-		debt.ID = uuid.NewString()
+	if period.ID == "" {
+		period.ID = uuid.NewString()
 	}
-
 	now := utils.NowUTC()
-	debt.CreatedAt = now
-	debt.UpdatedAt = now
+	period.BudgetID = budget.ID
+	period.UserID = userID
+	period.ClosedAt = now
+	period.CreatedAt = now
+	budget.UpdatedAt = now
 
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
-		log.Printf("[CreateDebt] Failed to begin transaction: %v", err)
 		return appErrors.DatabaseError
 	}
 
-	if _, err := tx.ExecContext(ctx, `
-		INSERT INTO debts (
-			id, user_id, name, balance, direction, counterparty_id, counterparty_name, description,
-			principal_amount, principal_currency, principal_original_amount, principal_original_currency,
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO budget_periods (
+			id, budget_id, user_id, period_start, period_end, currency,
+			base_limit, carried_in, effective_limit, spent_amount, remaining_amount,
+			percent_used, is_overspent, carried_out, rollover_mode, closed_at, created_at
+		)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17)
+		ON CONFLICT (budget_id, period_start) DO NOTHING
+	`, period.ID, budget.ID, userID, period.PeriodStart, period.PeriodEnd, period.Currency,
+		period.BaseLimit, period.CarriedIn, period.EffectiveLimit, period.SpentAmount, period.RemainingAmount,
+		period.PercentUsed, period.IsOverspent, period.CarriedOut, period.RolloverMode, period.ClosedAt, period.CreatedAt)
+	if err != nil {
+		_ = tx.Rollback()
+		log.Printf("[CloseBudgetPeriod] INSERT error for budget=%s: %v", budget.ID, err)
+		return appErrors.DatabaseError
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+		return appErrors.DatabaseError
+	}
+	if inserted == 0 {
+		_ = tx.Rollback()
+		return appErrors.BudgetPeriodClosed
+	}
+
+	result, err = tx.ExecContext(ctx, `
+		UPDATE budgets
+		SET start_date = $1,
+			end_date = $2,
+			carried_amount = $3,
+			spent_amount = $4,
+			remaining_amount = $5,
+			percent_used = $6,
+			is_overspent = $7,
+			updated_at = $8
+		WHERE id = $9 AND user_id = $10 AND deleted_at IS NULL
+	`, budget.StartDate, budget.EndDate, budget.CarriedAmount, budget.SpentAmount, budget.RemainingAmount,
+		budget.PercentUsed, budget.IsOverspent, budget.UpdatedAt, budget.ID, userID)
+	if err != nil {
+		_ = tx.Rollback()
+		log.Printf("[CloseBudgetPeriod] UPDATE error for budget=%s: %v", budget.ID, err)
+		return appErrors.DatabaseError
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+		return appErrors.DatabaseError
+	}
+	if rows == 0 {
+		_ = tx.Rollback()
+		return appErrors.BudgetNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return appErrors.DatabaseError
+	}
+	return nil
+}
+
+// ========== DEBTS ==========
+
+func (r *PostgresRepository) ListDebts(ctx context.Context) ([]*Debt, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM debts
+		WHERE user_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at DESC
+	`, debtSelectFields)
+
+	rows, err := r.db.QueryxContext(ctx, query, userID)
+	if err != nil {
+		return nil, appErrors.DatabaseError
+	}
+	defer rows.Close()
+
+	var debts []*Debt
+	for rows.Next() {
+		var row debtRow
+		if err := rows.StructScan(&row); err != nil {
+			return nil, appErrors.DatabaseError
+		}
+		debts = append(debts, mapRowToDebt(row))
+	}
+
+	return debts, nil
+}
+
+func (r *PostgresRepository) GetDebtByID(ctx context.Context, id string) (*Debt, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM debts
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+	`, debtSelectFields)
+
+	var row debtRow
+	if err := r.db.GetContext(ctx, &row, query, id, userID); err != nil {
+		if err == sql.ErrNoRows {
+			log.Printf("[GetDebtByID] Debt not found: id=%s", id)
+			return nil, appErrors.DebtNotFound
+		}
+		log.Printf("[GetDebtByID] DB error for id=%s: %v", id, err)
+		return nil, appErrors.DatabaseError
+	}
+	return mapRowToDebt(row), nil
+}
+
+func (r *PostgresRepository) CreateDebt(ctx context.Context, debt *Debt) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	if debt.ID == "" {
+		debt.ID = uuid.NewString()
+	}
+
+	now := utils.NowUTC()
+	debt.CreatedAt = now
+	debt.UpdatedAt = now
+	attachments, err := marshalIDList(debt.Attachments)
+	if err != nil {
+		return appErrors.InvalidFinanceData
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Printf("[CreateDebt] Failed to begin transaction: %v", err)
+		return appErrors.DatabaseError
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO debts (
+			id, user_id, name, balance, direction, counterparty_id, counterparty_name, description,
+			principal_amount, principal_currency, principal_original_amount, principal_original_currency,
 			base_currency, rate_on_start, principal_base_value, repayment_currency, repayment_amount,
 			repayment_rate_on_start, is_fixed_repayment_amount, start_date, due_date, interest_mode,
 			interest_rate_annual, schedule_hint, linked_goal_id, linked_budget_id, funding_account_id,
 			funding_transaction_id, lent_from_account_id, return_to_account_id, received_to_account_id,
 			pay_from_account_id, custom_rate_used, exchange_rate_current, reminder_enabled, reminder_time, status, settled_at,
 			final_rate_used, final_profit_loss, final_profit_loss_currency, total_paid_in_repayment_currency,
-			remaining_amount, total_paid, percent_paid, show_status, created_at, updated_at
+			remaining_amount, total_paid, percent_paid, show_status, attachments, created_at, updated_at
 		)
 		VALUES (
 			$1,$2,$3,$4,$5,$6,$7,$8,
@@ -1299,7 +2081,7 @@ func (r *PostgresRepository) CreateDebt(ctx context.Context, debt *Debt) error {
 			$28,$29,$30,$31,$32,
 			$33,$34,$35,$36,$37,
 			$38,$39,$40,$41,$42,
-			$43,$44,$45,$46,$47,$48
+			$43,$44,$45,$46,$47,$48,$49
 		)
 	`, debt.ID, userID, debt.CounterpartyName, debt.PrincipalAmount, debt.Direction, debt.CounterpartyID, debt.CounterpartyName, debt.Description,
 		debt.PrincipalAmount, debt.PrincipalCurrency, debt.PrincipalOriginalAmount, debt.PrincipalOriginalCurrency,
@@ -1309,7 +2091,7 @@ func (r *PostgresRepository) CreateDebt(ctx context.Context, debt *Debt) error {
 		debt.FundingTransactionID, debt.LentFromAccountID, debt.ReturnToAccountID, debt.ReceivedToAccountID,
 		debt.PayFromAccountID, debt.CustomRateUsed, debt.ExchangeRateCurrent, debt.ReminderEnabled, debt.ReminderTime, debt.Status, debt.SettledAt,
 		debt.FinalRateUsed, debt.FinalProfitLoss, debt.FinalProfitLossCurrency, debt.TotalPaidInRepaymentCurrency,
-		debt.RemainingAmount, debt.TotalPaid, debt.PercentPaid, debt.ShowStatus, debt.CreatedAt, debt.UpdatedAt); err != nil {
+		debt.RemainingAmount, debt.TotalPaid, debt.PercentPaid, debt.ShowStatus, attachments, debt.CreatedAt, debt.UpdatedAt); err != nil {
 		log.Printf("[CreateDebt] INSERT error for counterparty=%s: %v", debt.CounterpartyName, err)
 		_ = tx.Rollback()
 		return appErrors.DatabaseError
@@ -1392,6 +2174,10 @@ func (r *PostgresRepository) UpdateDebt(ctx context.Context, debt *Debt) error {
 	}
 
 	debt.UpdatedAt = utils.NowUTC()
+	attachments, err := marshalIDList(debt.Attachments)
+	if err != nil {
+		return appErrors.InvalidFinanceData
+	}
 
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -1450,8 +2236,9 @@ func (r *PostgresRepository) UpdateDebt(ctx context.Context, debt *Debt) error {
 			total_paid = $42,
 			percent_paid = $43,
 			show_status = $44,
-			updated_at = $45
-		WHERE id = $46 AND user_id = $47 AND deleted_at IS NULL
+			attachments = $45,
+			updated_at = $46
+		WHERE id = $47 AND user_id = $48 AND deleted_at IS NULL
 	`, debt.CounterpartyName, debt.PrincipalAmount, debt.Direction, debt.CounterpartyID, debt.CounterpartyName, debt.Description, debt.PrincipalAmount,
 		debt.PrincipalCurrency, debt.PrincipalOriginalAmount, debt.PrincipalOriginalCurrency, debt.BaseCurrency,
 		debt.RateOnStart, debt.PrincipalBaseValue, debt.RepaymentCurrency, debt.RepaymentAmount, debt.RepaymentRateOnStart,
@@ -1460,7 +2247,7 @@ func (r *PostgresRepository) UpdateDebt(ctx context.Context, debt *Debt) error {
 		debt.LentFromAccountID, debt.ReturnToAccountID, debt.ReceivedToAccountID, debt.PayFromAccountID,
 		debt.CustomRateUsed, debt.ExchangeRateCurrent, debt.ReminderEnabled, debt.ReminderTime, debt.Status, debt.SettledAt, debt.FinalRateUsed,
 		debt.FinalProfitLoss, debt.FinalProfitLossCurrency, debt.TotalPaidInRepaymentCurrency, debt.RemainingAmount,
-		debt.TotalPaid, debt.PercentPaid, debt.ShowStatus, debt.UpdatedAt, debt.ID, userID)
+		debt.TotalPaid, debt.PercentPaid, debt.ShowStatus, attachments, debt.UpdatedAt, debt.ID, userID)
 
 	if err != nil {
 		_ = tx.Rollback()
@@ -1637,6 +2424,10 @@ func (r *PostgresRepository) CreateDebtPayment(ctx context.Context, payment *Deb
 	now := utils.NowUTC()
 	payment.CreatedAt = now
 	payment.UpdatedAt = now
+	paymentAttachments, err := marshalIDList(payment.Attachments)
+	if err != nil {
+		return appErrors.InvalidFinanceData
+	}
 
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -1733,13 +2524,13 @@ func (r *PostgresRepository) CreateDebtPayment(ctx context.Context, payment *Deb
 		INSERT INTO debt_payments (
 			id, debt_id, amount, currency, base_currency, rate_used_to_base,
 			converted_amount_to_base, rate_used_to_debt, converted_amount_to_debt,
-			payment_date, account_id, note, related_transaction_id, applied_rate, created_at, updated_at
+			payment_date, account_id, note, related_transaction_id, applied_rate, attachments, created_at, updated_at
 		)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17)
 	`, payment.ID, payment.DebtID, payment.Amount, payment.Currency, payment.BaseCurrency, payment.RateUsedToBase,
 		payment.ConvertedAmountToBase, payment.RateUsedToDebt, payment.ConvertedAmountToDebt, payment.PaymentDate,
-		payment.AccountID, payment.Note, payment.RelatedTransactionID, payment.AppliedRate, payment.CreatedAt, payment.UpdatedAt); err != nil {
-		log.Printf("[CreateDebtPayment] INSERT error for debt=%s, amount=%.2f: %v", payment.DebtID, payment.Amount, err)
+		payment.AccountID, payment.Note, payment.RelatedTransactionID, payment.AppliedRate, paymentAttachments, payment.CreatedAt, payment.UpdatedAt); err != nil {
+		log.Printf("[CreateDebtPayment] INSERT error for debt=%s, amount=%s: %v", payment.DebtID, payment.Amount, err)
 		_ = tx.Rollback()
 		return appErrors.DatabaseError
 	}
@@ -1751,7 +2542,7 @@ func (r *PostgresRepository) CreateDebtPayment(ctx context.Context, payment *Deb
 
 	paymentInDebt := payment.ConvertedAmountToDebt
 	if paymentInDebt == 0 && payment.RateUsedToDebt > 0 {
-		paymentInDebt = payment.Amount * payment.RateUsedToDebt
+		paymentInDebt = exchangeRateFromFloat(payment.RateUsedToDebt).Convert(payment.Amount, debtRow.PrincipalCurrency)
 	}
 	remaining := debtRow.RemainingAmount
 	if remaining <= 0 {
@@ -1770,7 +2561,7 @@ func (r *PostgresRepository) CreateDebtPayment(ctx context.Context, payment *Deb
 	}
 	percentPaid := 0.0
 	if debtRow.PrincipalAmount > 0 {
-		percentPaid = (totalPaid / debtRow.PrincipalAmount) * 100
+		percentPaid = totalPaid.Ratio(debtRow.PrincipalAmount) * 100
 		if percentPaid > 100 {
 			percentPaid = 100
 		}
@@ -1811,6 +2602,10 @@ func (r *PostgresRepository) UpdateDebtPayment(ctx context.Context, payment *Deb
 	}
 
 	payment.UpdatedAt = utils.NowUTC()
+	paymentAttachments, err := marshalIDList(payment.Attachments)
+	if err != nil {
+		return appErrors.InvalidFinanceData
+	}
 
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -1913,11 +2708,12 @@ func (r *PostgresRepository) UpdateDebtPayment(ctx context.Context, payment *Deb
 			account_id = $9,
 			note = $10,
 			related_transaction_id = $11,
-			updated_at = $12
-		WHERE id = $13 AND debt_id = $14 AND deleted_at IS NULL
+			attachments = $12,
+			updated_at = $13
+		WHERE id = $14 AND debt_id = $15 AND deleted_at IS NULL
 	`, payment.Amount, payment.Currency, payment.BaseCurrency, payment.RateUsedToBase, payment.ConvertedAmountToBase,
 		payment.RateUsedToDebt, payment.ConvertedAmountToDebt, payment.PaymentDate, accountID, payment.Note,
-		payment.RelatedTransactionID, payment.UpdatedAt, payment.ID, payment.DebtID)
+		payment.RelatedTransactionID, paymentAttachments, payment.UpdatedAt, payment.ID, payment.DebtID)
 
 	if err != nil {
 		_ = tx.Rollback()
@@ -1954,7 +2750,7 @@ func (r *PostgresRepository) UpdateDebtPayment(ctx context.Context, payment *Deb
 		}
 		percentPaid := 0.0
 		if debtRow.PrincipalAmount > 0 {
-			percentPaid = (totalPaid / debtRow.PrincipalAmount) * 100
+			percentPaid = totalPaid.Ratio(debtRow.PrincipalAmount) * 100
 			if percentPaid > 100 {
 				percentPaid = 100
 			}
@@ -2086,7 +2882,21 @@ func (r *PostgresRepository) DeleteDebtPayment(ctx context.Context, debtID, paym
 		return appErrors.DebtPaymentNotFound
 	}
 
-	deltaDebt := existingPayment.ConvertedAmountToDebt
+	if err := restoreDebtPaymentTotals(ctx, tx, userID, debtRow, existingPayment); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return appErrors.DatabaseError
+	}
+
+	return nil
+}
+
+// restoreDebtPaymentTotals rolls the stored debt totals back by a removed payment.
+func restoreDebtPaymentTotals(ctx context.Context, tx *sqlx.Tx, userID string, debtRow *debtBalanceRow, payment *debtPaymentBalanceRow) error {
+	deltaDebt := payment.ConvertedAmountToDebt
 	remaining := debtRow.RemainingAmount
 	if remaining <= 0 {
 		remaining = debtRow.PrincipalAmount
@@ -2101,7 +2911,7 @@ func (r *PostgresRepository) DeleteDebtPayment(ctx context.Context, debtID, paym
 	}
 	percentPaid := 0.0
 	if debtRow.PrincipalAmount > 0 {
-		percentPaid = (totalPaid / debtRow.PrincipalAmount) * 100
+		percentPaid = totalPaid.Ratio(debtRow.PrincipalAmount) * 100
 		if percentPaid > 100 {
 			percentPaid = 100
 		}
@@ -2109,8 +2919,8 @@ func (r *PostgresRepository) DeleteDebtPayment(ctx context.Context, debtID, paym
 	totalPaidInRepayment := debtRow.TotalPaidInRepaymentCurrency
 	if debtRow.RepaymentCurrency.Valid {
 		repaymentCurrency := debtRow.RepaymentCurrency.String
-		if strings.EqualFold(repaymentCurrency, existingPayment.Currency) {
-			totalPaidInRepayment -= existingPayment.Amount
+		if strings.EqualFold(repaymentCurrency, payment.Currency) {
+			totalPaidInRepayment -= payment.Amount
 		} else if strings.EqualFold(repaymentCurrency, debtRow.PrincipalCurrency) {
 			totalPaidInRepayment -= deltaDebt
 		}
@@ -2127,14 +2937,8 @@ func (r *PostgresRepository) DeleteDebtPayment(ctx context.Context, debtID, paym
 			updated_at = $5
 		WHERE id = $6 AND user_id = $7 AND deleted_at IS NULL
 	`, remaining, totalPaid, percentPaid, totalPaidInRepayment, utils.NowUTC(), debtRow.ID, userID); err != nil {
-		_ = tx.Rollback()
-		return appErrors.DatabaseError
-	}
-
-	if err := tx.Commit(); err != nil {
 		return appErrors.DatabaseError
 	}
-
 	return nil
 }
 
@@ -2281,925 +3085,3804 @@ func (r *PostgresRepository) DeleteCounterparty(ctx context.Context, id string)
 	return nil
 }
 
-// ========== FX RATES ==========
+// ========== RECURRING TRANSACTIONS ==========
+
+func (r *PostgresRepository) ListRecurringTransactions(ctx context.Context) ([]*RecurringTransaction, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
 
-func (r *PostgresRepository) ListFXRates(ctx context.Context) ([]*FXRate, error) {
 	query := fmt.Sprintf(`
-		SELECT %s FROM fx_rates
-		ORDER BY rate_date DESC
-	`, fxRateSelectFields)
+		SELECT %s FROM recurring_transactions
+		WHERE user_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at DESC
+	`, recurringSelectFields)
 
-	rows, err := r.db.QueryxContext(ctx, query)
-	if err != nil {
+	var rows []recurringRow
+	if err := r.db.SelectContext(ctx, &rows, query, userID); err != nil {
+		log.Printf("[ListRecurringTransactions] Query error: %v", err)
 		return nil, appErrors.DatabaseError
 	}
-	defer rows.Close()
 
-	var rates []*FXRate
-	for rows.Next() {
-		var row fxRateRow
-		if err := rows.StructScan(&row); err != nil {
-			return nil, appErrors.DatabaseError
-		}
-		rates = append(rates, mapRowToFXRate(row))
+	items := make([]*RecurringTransaction, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, mapRowToRecurring(row))
 	}
-
-	return rates, nil
+	return items, nil
 }
 
-func (r *PostgresRepository) GetFXRateByID(ctx context.Context, id string) (*FXRate, error) {
-	query := fmt.Sprintf(`
-		SELECT %s FROM fx_rates
-		WHERE id = $1
-	`, fxRateSelectFields)
+func (r *PostgresRepository) ListDebtInstallments(ctx context.Context, debtID string) ([]*DebtInstallment, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
 
-	var row fxRateRow
-	if err := r.db.GetContext(ctx, &row, query, id); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, appErrors.FXRateNotFound
-		}
+	query := fmt.Sprintf(`
+		SELECT %s FROM debt_installments
+		WHERE debt_id = $1 AND user_id = $2
+		ORDER BY sequence ASC
+	`, installmentSelectFields)
+
+	var rows []debtInstallmentRow
+	if err := r.db.SelectContext(ctx, &rows, query, debtID, userID); err != nil {
+		log.Printf("[ListDebtInstallments] Query error: %v", err)
 		return nil, appErrors.DatabaseError
 	}
-	return mapRowToFXRate(row), nil
-}
 
-func (r *PostgresRepository) CreateFXRate(ctx context.Context, rate *FXRate) error {
-	if rate.ID == "" {
-		rate.ID = uuid.NewString()
+	items := make([]*DebtInstallment, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, mapRowToDebtInstallment(row))
 	}
-	now := utils.NowUTC()
-	rate.CreatedAt = now
-	rate.UpdatedAt = now
+	return items, nil
+}
 
-	_, err := r.db.ExecContext(ctx, `
-		INSERT INTO fx_rates (id, rate_date, from_currency, to_currency, rate, rate_mid, rate_bid, rate_ask, nominal, spread_percent, source, created_at, updated_at)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
-	`, rate.ID, rate.Date, rate.FromCurrency, rate.ToCurrency, rate.Rate, rate.RateMid, rate.RateBid, rate.RateAsk, rate.Nominal, rate.SpreadPercent, rate.Source, rate.CreatedAt, rate.UpdatedAt)
+// ReplaceDebtInstallments swaps the whole plan in one transaction; an empty
+// slice removes the plan.
+func (r *PostgresRepository) ReplaceDebtInstallments(ctx context.Context, debtID string, installments []*DebtInstallment) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
 
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return appErrors.DatabaseError
 	}
-	return nil
-}
-
-func (r *PostgresRepository) ListCategories(ctx context.Context, categoryType string, activeOnly bool) ([]*FinanceCategory, error) {
-	clauses := []string{"1=1"}
-	args := []interface{}{}
-	argIndex := 1
+	defer tx.Rollback()
 
-	if categoryType != "" {
-		clauses = append(clauses, fmt.Sprintf("type = $%d", argIndex))
-		args = append(args, categoryType)
-		argIndex++
+	var exists bool
+	if err := tx.GetContext(ctx, &exists, `
+		SELECT EXISTS (SELECT 1 FROM debts WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL)
+	`, debtID, userID); err != nil {
+		return appErrors.DatabaseError
 	}
-	if activeOnly {
-		clauses = append(clauses, "is_active = true")
+	if !exists {
+		return appErrors.DebtNotFound
 	}
 
-	query := fmt.Sprintf(`
-		SELECT %s FROM finance_categories
-		WHERE %s
-		ORDER BY sort_order ASC, created_at ASC
-	`, categorySelectFields, strings.Join(clauses, " AND "))
-
-	rows, err := r.db.QueryxContext(ctx, query, args...)
-	if err != nil {
-		return nil, appErrors.DatabaseError
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM debt_installments
+		WHERE debt_id = $1 AND user_id = $2
+	`, debtID, userID); err != nil {
+		log.Printf("[ReplaceDebtInstallments] DELETE error for debt=%s: %v", debtID, err)
+		return appErrors.DatabaseError
 	}
-	defer rows.Close()
 
-	var categories []*FinanceCategory
-	for rows.Next() {
-		var row categoryRow
-		if err := rows.StructScan(&row); err != nil {
-			return nil, appErrors.DatabaseError
+	now := utils.NowUTC()
+	for _, installment := range installments {
+		if installment.ID == "" {
+			installment.ID = uuid.NewString()
 		}
-		category, err := mapRowToCategory(row)
-		if err != nil {
-			return nil, appErrors.DatabaseError
+		installment.DebtID = debtID
+		installment.UserID = userID
+		installment.CreatedAt = now
+		installment.UpdatedAt = now
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO debt_installments
+				(id, debt_id, user_id, sequence, plan_type, due_date, amount, principal_amount, interest_amount, created_at, updated_at)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+		`, installment.ID, debtID, userID, installment.Sequence, installment.PlanType, installment.DueDate,
+			installment.Amount, installment.PrincipalAmount, installment.InterestAmount, now, now); err != nil {
+			log.Printf("[ReplaceDebtInstallments] INSERT error for debt=%s: %v", debtID, err)
+			return appErrors.DatabaseError
 		}
-		categories = append(categories, category)
 	}
-	return categories, nil
-}
 
-func (r *PostgresRepository) CreateCategory(ctx context.Context, category *FinanceCategory) error {
-	if category.ID == "" {
-		category.ID = uuid.NewString()
+	if err := tx.Commit(); err != nil {
+		return appErrors.DatabaseError
 	}
-	now := utils.NowUTC()
-	category.CreatedAt = now
-	category.UpdatedAt = now
-
-	namePayload, err := json.Marshal(category.NameI18n)
-	if err != nil {
-		return appErrors.InvalidFinanceData
+	return nil
+}
+
+// ListDebtsForDueEvaluation is used by the due-date evaluator and is
+// intentionally not scoped to the user in ctx.
+func (r *PostgresRepository) ListDebtsForDueEvaluation(ctx context.Context) ([]*Debt, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM debts
+		WHERE deleted_at IS NULL AND show_status = 'active'
+			AND status IN ('active', 'due_soon', 'overdue')
+		ORDER BY due_date ASC NULLS LAST
+	`, debtSelectFields)
+
+	var rows []debtRow
+	if err := r.db.SelectContext(ctx, &rows, query); err != nil {
+		log.Printf("[ListDebtsForDueEvaluation] Query error: %v", err)
+		return nil, appErrors.DatabaseError
 	}
 
-	_, err = r.db.ExecContext(ctx, `
-		INSERT INTO finance_categories (id, type, name_i18n, icon_name, color, is_default, sort_order, is_active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-	`, category.ID, category.Type, namePayload, category.IconName, category.Color, category.IsDefault, category.SortOrder, category.IsActive, category.CreatedAt, category.UpdatedAt)
-	if err != nil {
-		return appErrors.DatabaseError
+	debts := make([]*Debt, 0, len(rows))
+	for _, row := range rows {
+		debts = append(debts, mapRowToDebt(row))
 	}
-	return nil
+	return debts, nil
 }
 
-func (r *PostgresRepository) UpdateCategory(ctx context.Context, category *FinanceCategory) error {
-	category.UpdatedAt = utils.NowUTC()
-	namePayload, err := json.Marshal(category.NameI18n)
+// TransitionDebtStatus moves the debt from event.FromStatus to event.ToStatus
+// and records the event in one transaction. It reports false without error
+// when the debt's status has changed since it was read.
+func (r *PostgresRepository) TransitionDebtStatus(ctx context.Context, event *DebtStatusEvent) (bool, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return false, appErrors.InvalidToken
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return appErrors.InvalidFinanceData
+		return false, appErrors.DatabaseError
 	}
+	defer tx.Rollback()
 
-	result, err := r.db.ExecContext(ctx, `
-		UPDATE finance_categories
-		SET type = $1,
-			name_i18n = $2,
-			icon_name = $3,
-			color = $4,
-			is_default = $5,
-			sort_order = $6,
-			is_active = $7,
-			updated_at = $8
-		WHERE id = $9
-	`, category.Type, namePayload, category.IconName, category.Color, category.IsDefault, category.SortOrder, category.IsActive, category.UpdatedAt, category.ID)
+	if event.ID == "" {
+		event.ID = uuid.NewString()
+	}
+	if event.OccurredAt == "" {
+		event.OccurredAt = utils.NowUTC()
+	}
+	event.UserID = userID
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE debts
+		SET status = $1, updated_at = $2
+		WHERE id = $3 AND user_id = $4 AND status = $5 AND deleted_at IS NULL
+	`, event.ToStatus, event.OccurredAt, event.DebtID, userID, event.FromStatus)
 	if err != nil {
-		return appErrors.DatabaseError
+		log.Printf("[TransitionDebtStatus] UPDATE error for debt=%s: %v", event.DebtID, err)
+		return false, appErrors.DatabaseError
 	}
 	rows, err := result.RowsAffected()
 	if err != nil {
-		return appErrors.DatabaseError
+		return false, appErrors.DatabaseError
 	}
 	if rows == 0 {
-		return appErrors.InvalidFinanceData
+		return false, nil
 	}
-	return nil
+
+	var dueDate interface{}
+	if strings.TrimSpace(event.DueDate) != "" {
+		dueDate = event.DueDate
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO debt_status_events (id, debt_id, user_id, from_status, to_status, due_date, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, event.ID, event.DebtID, userID, event.FromStatus, event.ToStatus, dueDate, event.OccurredAt); err != nil {
+		log.Printf("[TransitionDebtStatus] INSERT error for debt=%s: %v", event.DebtID, err)
+		return false, appErrors.DatabaseError
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, appErrors.DatabaseError
+	}
+	return true, nil
 }
 
-func (r *PostgresRepository) ListQuickExpenseCategories(ctx context.Context, categoryType string) ([]*QuickExpenseCategory, error) {
+func (r *PostgresRepository) ListDebtStatusEvents(ctx context.Context, limit int) ([]*DebtStatusEvent, error) {
 	userID, ok := ctx.Value("user_id").(string)
 	if !ok || userID == "" {
 		return nil, appErrors.InvalidToken
 	}
-	args := []interface{}{userID}
-	query := `
-		SELECT category_tag, category_name, category_type
-		FROM finance_quick_exp_categories
+	if limit <= 0 {
+		limit = 50
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM debt_status_events
 		WHERE user_id = $1
-	`
-	if strings.TrimSpace(categoryType) != "" {
-		query += " AND category_type = $2"
-		args = append(args, categoryType)
+		ORDER BY occurred_at DESC
+		LIMIT $2
+	`, debtEventSelectFields)
+
+	var rows []debtStatusEventRow
+	if err := r.db.SelectContext(ctx, &rows, query, userID, limit); err != nil {
+		log.Printf("[ListDebtStatusEvents] Query error: %v", err)
+		return nil, appErrors.DatabaseError
 	}
-	query += " ORDER BY created_at ASC"
 
-	rows, err := r.db.QueryxContext(ctx, query, args...)
+	events := make([]*DebtStatusEvent, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, mapRowToDebtStatusEvent(row))
+	}
+	return events, nil
+}
+
+// RecordDebtReminder stores the reminder unless one was already sent for the
+// debt that day; the boolean reports whether it was inserted.
+func (r *PostgresRepository) RecordDebtReminder(ctx context.Context, reminder *DebtReminder) (bool, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return false, appErrors.InvalidToken
+	}
+
+	if reminder.ID == "" {
+		reminder.ID = uuid.NewString()
+	}
+	reminder.UserID = userID
+	reminder.CreatedAt = utils.NowUTC()
+
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO debt_reminders (id, debt_id, user_id, reminder_date, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (debt_id, reminder_date) DO NOTHING
+	`, reminder.ID, reminder.DebtID, userID, reminder.ReminderDate, reminder.Status, reminder.CreatedAt)
+	if err != nil {
+		log.Printf("[RecordDebtReminder] INSERT error for debt=%s: %v", reminder.DebtID, err)
+		return false, appErrors.DatabaseError
+	}
+	rows, err := result.RowsAffected()
 	if err != nil {
+		return false, appErrors.DatabaseError
+	}
+	return rows > 0, nil
+}
+
+// ListDueRecurringTransactions is used by the background generator and is
+// intentionally not scoped to the user in ctx.
+func (r *PostgresRepository) ListDueRecurringTransactions(ctx context.Context, asOf string) ([]*RecurringTransaction, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM recurring_transactions
+		WHERE status = 'active' AND deleted_at IS NULL
+			AND next_occurrence_date IS NOT NULL AND next_occurrence_date <= $1
+		ORDER BY next_occurrence_date ASC
+	`, recurringSelectFields)
+
+	var rows []recurringRow
+	if err := r.db.SelectContext(ctx, &rows, query, asOf); err != nil {
+		log.Printf("[ListDueRecurringTransactions] Query error: %v", err)
 		return nil, appErrors.DatabaseError
 	}
-	defer rows.Close()
 
-	results := make([]*QuickExpenseCategory, 0)
-	for rows.Next() {
-		var tag, name, ctype string
-		if err := rows.Scan(&tag, &name, &ctype); err != nil {
-			return nil, appErrors.DatabaseError
+	items := make([]*RecurringTransaction, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, mapRowToRecurring(row))
+	}
+	return items, nil
+}
+
+func (r *PostgresRepository) GetRecurringTransactionByID(ctx context.Context, id string) (*RecurringTransaction, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM recurring_transactions
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+	`, recurringSelectFields)
+
+	var row recurringRow
+	if err := r.db.GetContext(ctx, &row, query, id, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, appErrors.RecurringNotFound
 		}
-		results = append(results, &QuickExpenseCategory{
-			Tag:  tag,
-			Name: name,
-			Type: ctype,
-		})
+		return nil, appErrors.DatabaseError
 	}
-	return results, nil
+	return mapRowToRecurring(row), nil
 }
 
-func (r *PostgresRepository) ReplaceQuickExpenseCategories(ctx context.Context, categoryType string, categories []*QuickExpenseCategory) error {
+func (r *PostgresRepository) CreateRecurringTransaction(ctx context.Context, recurring *RecurringTransaction) error {
 	userID, ok := ctx.Value("user_id").(string)
 	if !ok || userID == "" {
 		return appErrors.InvalidToken
 	}
-	tx, err := r.db.BeginTxx(ctx, nil)
+
+	if recurring.ID == "" {
+		recurring.ID = uuid.NewString()
+	}
+	recurring.UserID = userID
+	now := utils.NowUTC()
+	recurring.CreatedAt = now
+	recurring.UpdatedAt = now
+
+	tags, err := json.Marshal(recurring.Tags)
+	if err != nil {
+		return appErrors.InvalidFinanceData
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO recurring_transactions (
+			id, user_id, name, type, account_id, from_account_id, to_account_id,
+			amount, currency, to_amount, to_currency, category_id, subcategory_id, description,
+			budget_id, goal_id, counterparty_id, tags,
+			frequency, interval_count, interval_unit, day_of_week, day_of_month,
+			start_date, end_date, occurrence_count, occurrences_generated,
+			next_occurrence_date, last_occurrence_date, posting_mode, status, created_at, updated_at
+		)
+		VALUES (
+			$1,$2,$3,$4,$5,$6,$7,
+			$8,$9,$10,$11,$12,$13,$14,
+			$15,$16,$17,$18,
+			$19,$20,$21,$22,$23,
+			$24,$25,$26,$27,
+			$28,$29,$30,$31,$32,$33
+		)
+	`, recurring.ID, userID, recurring.Name, recurring.Type, recurring.AccountID, recurring.FromAccountID, recurring.ToAccountID,
+		recurring.Amount, recurring.Currency, recurring.ToAmount, recurring.ToCurrency, recurring.CategoryID, recurring.SubcategoryID, recurring.Description,
+		recurring.BudgetID, recurring.GoalID, recurring.CounterpartyID, tags,
+		recurring.Frequency, recurring.Interval, nullableString(recurring.IntervalUnit), recurring.DayOfWeek, recurring.DayOfMonth,
+		recurring.StartDate, recurring.EndDate, recurring.OccurrenceCount, recurring.OccurrencesGenerated,
+		recurring.NextOccurrenceDate, recurring.LastOccurrenceDate, recurring.PostingMode, recurring.Status, recurring.CreatedAt, recurring.UpdatedAt,
+	)
 	if err != nil {
+		log.Printf("[CreateRecurringTransaction] INSERT error: %v", err)
 		return appErrors.DatabaseError
 	}
-	defer tx.Rollback()
+	return nil
+}
 
-	if _, err := tx.ExecContext(ctx, `
-		DELETE FROM finance_quick_exp_categories
-		WHERE user_id = $1 AND category_type = $2
-	`, userID, categoryType); err != nil {
+func (r *PostgresRepository) UpdateRecurringTransaction(ctx context.Context, recurring *RecurringTransaction) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	recurring.UpdatedAt = utils.NowUTC()
+	tags, err := json.Marshal(recurring.Tags)
+	if err != nil {
+		return appErrors.InvalidFinanceData
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE recurring_transactions
+		SET name = $1, type = $2, account_id = $3, from_account_id = $4, to_account_id = $5,
+			amount = $6, currency = $7, to_amount = $8, to_currency = $9,
+			category_id = $10, subcategory_id = $11, description = $12,
+			budget_id = $13, goal_id = $14, counterparty_id = $15, tags = $16,
+			frequency = $17, interval_count = $18, interval_unit = $19, day_of_week = $20, day_of_month = $21,
+			start_date = $22, end_date = $23, occurrence_count = $24, occurrences_generated = $25,
+			next_occurrence_date = $26, last_occurrence_date = $27, posting_mode = $28, status = $29,
+			updated_at = $30
+		WHERE id = $31 AND user_id = $32 AND deleted_at IS NULL
+	`, recurring.Name, recurring.Type, recurring.AccountID, recurring.FromAccountID, recurring.ToAccountID,
+		recurring.Amount, recurring.Currency, recurring.ToAmount, recurring.ToCurrency,
+		recurring.CategoryID, recurring.SubcategoryID, recurring.Description,
+		recurring.BudgetID, recurring.GoalID, recurring.CounterpartyID, tags,
+		recurring.Frequency, recurring.Interval, nullableString(recurring.IntervalUnit), recurring.DayOfWeek, recurring.DayOfMonth,
+		recurring.StartDate, recurring.EndDate, recurring.OccurrenceCount, recurring.OccurrencesGenerated,
+		recurring.NextOccurrenceDate, recurring.LastOccurrenceDate, recurring.PostingMode, recurring.Status,
+		recurring.UpdatedAt, recurring.ID, userID,
+	)
+	if err != nil {
+		log.Printf("[UpdateRecurringTransaction] UPDATE error for id=%s: %v", recurring.ID, err)
+		return appErrors.DatabaseError
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
 		return appErrors.DatabaseError
 	}
+	if rows == 0 {
+		return appErrors.RecurringNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) DeleteRecurringTransaction(ctx context.Context, id string) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
 
 	now := utils.NowUTC()
-	for _, entry := range categories {
-		if entry == nil || strings.TrimSpace(entry.Tag) == "" {
-			continue
-		}
-		ctype := strings.TrimSpace(entry.Type)
-		if ctype == "" {
-			ctype = categoryType
-		}
-		if _, err := tx.ExecContext(ctx, `
-			INSERT INTO finance_quick_exp_categories
-				(id, user_id, category_tag, category_name, category_type, created_at, updated_at)
-			VALUES ($1,$2,$3,$4,$5,$6,$7)
-		`, uuid.NewString(), userID, strings.TrimSpace(entry.Tag), strings.TrimSpace(entry.Name), ctype, now, now); err != nil {
-			return appErrors.DatabaseError
-		}
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE recurring_transactions
+		SET deleted_at = $1, updated_at = $2
+		WHERE id = $3 AND user_id = $4 AND deleted_at IS NULL
+	`, now, now, id, userID)
+	if err != nil {
+		return appErrors.DatabaseError
 	}
 
-	if err := tx.Commit(); err != nil {
+	rows, err := result.RowsAffected()
+	if err != nil {
 		return appErrors.DatabaseError
 	}
+	if rows == 0 {
+		return appErrors.RecurringNotFound
+	}
 	return nil
 }
 
-func mapRowToCategory(row categoryRow) (*FinanceCategory, error) {
-	name := map[string]string{}
-	if len(row.NameI18n) > 0 {
-		if err := json.Unmarshal(row.NameI18n, &name); err != nil {
-			return nil, err
-		}
+func (r *PostgresRepository) ListRecurringOccurrences(ctx context.Context, recurringID string) ([]*RecurringOccurrence, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
 	}
-	var color *string
-	if row.Color.Valid {
-		color = &row.Color.String
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM recurring_transaction_occurrences
+		WHERE recurring_id = $1 AND user_id = $2
+		ORDER BY due_date DESC
+	`, occurrenceSelectFields)
+
+	var rows []recurringOccurrenceRow
+	if err := r.db.SelectContext(ctx, &rows, query, recurringID, userID); err != nil {
+		log.Printf("[ListRecurringOccurrences] Query error: %v", err)
+		return nil, appErrors.DatabaseError
 	}
-	return &FinanceCategory{
-		ID:        row.ID,
-		Type:      row.Type,
-		NameI18n:  name,
-		IconName:  row.IconName,
-		Color:     color,
-		IsDefault: row.IsDefault,
-		SortOrder: row.SortOrder,
-		IsActive:  row.IsActive,
-		CreatedAt: row.CreatedAt.UTC().Format(time.RFC3339),
-		UpdatedAt: row.UpdatedAt.UTC().Format(time.RFC3339),
-	}, nil
+
+	items := make([]*RecurringOccurrence, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, mapRowToRecurringOccurrence(row))
+	}
+	return items, nil
 }
 
-// ========== ROW STRUCTS AND MAPPERS ==========
+func (r *PostgresRepository) GetRecurringOccurrenceByID(ctx context.Context, recurringID, occurrenceID string) (*RecurringOccurrence, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
 
-type accountRow struct {
-	ID             string         `db:"id"`
-	UserID         string         `db:"user_id"`
-	Name           string         `db:"name"`
-	Currency       string         `db:"currency"`
-	AccountType    string         `db:"account_type"`
-	InitialBalance float64        `db:"initial_balance"`
-	CurrentBalance float64        `db:"current_balance"`
-	LinkedGoalID   sql.NullString `db:"linked_goal_id"`
-	CustomTypeID   sql.NullString `db:"custom_type_id"`
-	IsMain         bool           `db:"is_main"`
-	IsArchived     bool           `db:"is_archived"`
-	ShowStatus     string         `db:"show_status"`
-	CreatedAt      string         `db:"created_at"`
-	UpdatedAt      string         `db:"updated_at"`
+	query := fmt.Sprintf(`
+		SELECT %s FROM recurring_transaction_occurrences
+		WHERE id = $1 AND recurring_id = $2 AND user_id = $3
+	`, occurrenceSelectFields)
+
+	var row recurringOccurrenceRow
+	if err := r.db.GetContext(ctx, &row, query, occurrenceID, recurringID, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, appErrors.RecurringOccurrenceNotFound
+		}
+		return nil, appErrors.DatabaseError
+	}
+	return mapRowToRecurringOccurrence(row), nil
 }
 
-func mapRowToAccount(row accountRow) *Account {
-	var linkedGoalID *string
-	if row.LinkedGoalID.Valid {
-		linkedGoalID = &row.LinkedGoalID.String
+// CreateRecurringOccurrence reports false when the occurrence for that due date
+// already exists, which keeps concurrent generators from posting twice.
+func (r *PostgresRepository) CreateRecurringOccurrence(ctx context.Context, occurrence *RecurringOccurrence) (bool, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return false, appErrors.InvalidToken
 	}
-	var customTypeID *string
-	if row.CustomTypeID.Valid {
-		customTypeID = &row.CustomTypeID.String
+
+	if occurrence.ID == "" {
+		occurrence.ID = uuid.NewString()
 	}
-	return &Account{
-		ID:             row.ID,
-		UserID:         row.UserID,
-		Name:           row.Name,
-		AccountType:    row.AccountType,
-		Currency:       row.Currency,
-		InitialBalance: row.InitialBalance,
-		CurrentBalance: row.CurrentBalance,
-		LinkedGoalID:   linkedGoalID,
-		CustomTypeID:   customTypeID,
-		IsMain:         row.IsMain,
-		IsArchived:     row.IsArchived,
+	occurrence.UserID = userID
+	now := utils.NowUTC()
+	occurrence.CreatedAt = now
+	occurrence.UpdatedAt = now
+
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO recurring_transaction_occurrences (id, recurring_id, user_id, due_date, status, transaction_id, error, created_at, updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+		ON CONFLICT (recurring_id, due_date) DO NOTHING
+	`, occurrence.ID, occurrence.RecurringID, userID, occurrence.DueDate, occurrence.Status, occurrence.TransactionID, occurrence.Error, occurrence.CreatedAt, occurrence.UpdatedAt)
+	if err != nil {
+		log.Printf("[CreateRecurringOccurrence] INSERT error for recurring=%s: %v", occurrence.RecurringID, err)
+		return false, appErrors.DatabaseError
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, appErrors.DatabaseError
+	}
+	return rows > 0, nil
+}
+
+func (r *PostgresRepository) UpdateRecurringOccurrence(ctx context.Context, occurrence *RecurringOccurrence) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	occurrence.UpdatedAt = utils.NowUTC()
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE recurring_transaction_occurrences
+		SET status = $1, transaction_id = $2, error = $3, updated_at = $4
+		WHERE id = $5 AND user_id = $6
+	`, occurrence.Status, occurrence.TransactionID, occurrence.Error, occurrence.UpdatedAt, occurrence.ID, userID)
+	if err != nil {
+		return appErrors.DatabaseError
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return appErrors.DatabaseError
+	}
+	if rows == 0 {
+		return appErrors.RecurringOccurrenceNotFound
+	}
+	return nil
+}
+
+// ========== FX RATES ==========
+
+func (r *PostgresRepository) ListFXRates(ctx context.Context) ([]*FXRate, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM fx_rates
+		ORDER BY rate_date DESC
+	`, fxRateSelectFields)
+
+	rows, err := r.db.QueryxContext(ctx, query)
+	if err != nil {
+		return nil, appErrors.DatabaseError
+	}
+	defer rows.Close()
+
+	var rates []*FXRate
+	for rows.Next() {
+		var row fxRateRow
+		if err := rows.StructScan(&row); err != nil {
+			return nil, appErrors.DatabaseError
+		}
+		rates = append(rates, mapRowToFXRate(row))
+	}
+
+	return rates, nil
+}
+
+func (r *PostgresRepository) GetFXRateByID(ctx context.Context, id string) (*FXRate, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM fx_rates
+		WHERE id = $1
+	`, fxRateSelectFields)
+
+	var row fxRateRow
+	if err := r.db.GetContext(ctx, &row, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, appErrors.FXRateNotFound
+		}
+		return nil, appErrors.DatabaseError
+	}
+	return mapRowToFXRate(row), nil
+}
+
+func (r *PostgresRepository) CreateFXRate(ctx context.Context, rate *FXRate) error {
+	if rate.ID == "" {
+		rate.ID = uuid.NewString()
+	}
+	now := utils.NowUTC()
+	rate.CreatedAt = now
+	rate.UpdatedAt = now
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO fx_rates (id, rate_date, from_currency, to_currency, rate, rate_mid, rate_bid, rate_ask, nominal, spread_percent, source, created_at, updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+	`, rate.ID, rate.Date, rate.FromCurrency, rate.ToCurrency, rate.Rate, rate.RateMid, rate.RateBid, rate.RateAsk, rate.Nominal, rate.SpreadPercent, rate.Source, rate.CreatedAt, rate.UpdatedAt)
+
+	if err != nil {
+		return appErrors.DatabaseError
+	}
+	return nil
+}
+
+func (r *PostgresRepository) UpsertFXRate(ctx context.Context, rate *FXRate) error {
+	if rate.ID == "" {
+		rate.ID = uuid.NewString()
+	}
+	now := utils.NowUTC()
+	rate.CreatedAt = now
+	rate.UpdatedAt = now
+
+	if err := r.db.GetContext(ctx, &rate.ID, `
+		INSERT INTO fx_rates (id, rate_date, from_currency, to_currency, rate, rate_mid, rate_bid, rate_ask, nominal, spread_percent, source, created_at, updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+		ON CONFLICT (rate_date, from_currency, to_currency, source) WHERE source <> 'manual'
+		DO UPDATE SET rate = EXCLUDED.rate, rate_mid = EXCLUDED.rate_mid, rate_bid = EXCLUDED.rate_bid,
+			rate_ask = EXCLUDED.rate_ask, nominal = EXCLUDED.nominal, spread_percent = EXCLUDED.spread_percent,
+			updated_at = EXCLUDED.updated_at
+		RETURNING id
+	`, rate.ID, rate.Date, rate.FromCurrency, rate.ToCurrency, rate.Rate, rate.RateMid, rate.RateBid, rate.RateAsk, rate.Nominal, rate.SpreadPercent, rate.Source, rate.CreatedAt, rate.UpdatedAt); err != nil {
+		log.Printf("[UpsertFXRate] Error for %s %s/%s on %s: %v", rate.Source, rate.FromCurrency, rate.ToCurrency, rate.Date, err)
+		return appErrors.DatabaseError
+	}
+	return nil
+}
+
+// FindFXRate returns the newest usable rate for the pair on or before date,
+// or the earliest one after it when the pair has no earlier history. Both
+// lookups walk idx_fx_rates_pair.
+func (r *PostgresRepository) FindFXRate(ctx context.Context, fromCurrency, toCurrency, date string) (*FXRate, error) {
+	lookups := []string{
+		`rate_date <= $3 ORDER BY rate_date DESC`,
+		`rate_date > $3 ORDER BY rate_date ASC`,
+	}
+	for _, lookup := range lookups {
+		query := fmt.Sprintf(`
+			SELECT %s FROM fx_rates
+			WHERE from_currency = $1 AND to_currency = $2
+				AND (rate_mid > 0 OR rate > 0 OR rate_bid > 0)
+				AND %s, (source = 'manual') DESC, created_at DESC
+			LIMIT 1
+		`, fxRateSelectFields, lookup)
+
+		var row fxRateRow
+		err := r.db.GetContext(ctx, &row, query, strings.ToUpper(fromCurrency), strings.ToUpper(toCurrency), date)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			log.Printf("[FindFXRate] Error for %s/%s on %s: %v", fromCurrency, toCurrency, date, err)
+			return nil, appErrors.DatabaseError
+		}
+		return mapRowToFXRate(row), nil
+	}
+	return nil, appErrors.FXRateNotFound
+}
+
+func (r *PostgresRepository) ListLatestFXRates(ctx context.Context) ([]*FXRate, error) {
+	query := fmt.Sprintf(`
+		SELECT DISTINCT ON (source, from_currency, to_currency) %s FROM fx_rates
+		ORDER BY source, from_currency, to_currency, rate_date DESC, created_at DESC
+	`, fxRateSelectFields)
+
+	rows, err := r.db.QueryxContext(ctx, query)
+	if err != nil {
+		return nil, appErrors.DatabaseError
+	}
+	defer rows.Close()
+
+	var rates []*FXRate
+	for rows.Next() {
+		var row fxRateRow
+		if err := rows.StructScan(&row); err != nil {
+			return nil, appErrors.DatabaseError
+		}
+		rates = append(rates, mapRowToFXRate(row))
+	}
+	return rates, nil
+}
+
+func (r *PostgresRepository) ListCategories(ctx context.Context, categoryType string, activeOnly bool) ([]*FinanceCategory, error) {
+	clauses := []string{"1=1"}
+	args := []interface{}{}
+	argIndex := 1
+
+	if categoryType != "" {
+		clauses = append(clauses, fmt.Sprintf("type = $%d", argIndex))
+		args = append(args, categoryType)
+		argIndex++
+	}
+	if activeOnly {
+		clauses = append(clauses, "is_active = true")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM finance_categories
+		WHERE %s
+		ORDER BY sort_order ASC, created_at ASC
+	`, categorySelectFields, strings.Join(clauses, " AND "))
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, appErrors.DatabaseError
+	}
+	defer rows.Close()
+
+	var categories []*FinanceCategory
+	for rows.Next() {
+		var row categoryRow
+		if err := rows.StructScan(&row); err != nil {
+			return nil, appErrors.DatabaseError
+		}
+		category, err := mapRowToCategory(row)
+		if err != nil {
+			return nil, appErrors.DatabaseError
+		}
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+
+func (r *PostgresRepository) CreateCategory(ctx context.Context, category *FinanceCategory) error {
+	if category.ID == "" {
+		category.ID = uuid.NewString()
+	}
+	now := utils.NowUTC()
+	category.CreatedAt = now
+	category.UpdatedAt = now
+
+	namePayload, err := json.Marshal(category.NameI18n)
+	if err != nil {
+		return appErrors.InvalidFinanceData
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO finance_categories (id, type, name_i18n, icon_name, color, is_default, sort_order, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, category.ID, category.Type, namePayload, category.IconName, category.Color, category.IsDefault, category.SortOrder, category.IsActive, category.CreatedAt, category.UpdatedAt)
+	if err != nil {
+		return appErrors.DatabaseError
+	}
+	return nil
+}
+
+func (r *PostgresRepository) UpdateCategory(ctx context.Context, category *FinanceCategory) error {
+	category.UpdatedAt = utils.NowUTC()
+	namePayload, err := json.Marshal(category.NameI18n)
+	if err != nil {
+		return appErrors.InvalidFinanceData
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE finance_categories
+		SET type = $1,
+			name_i18n = $2,
+			icon_name = $3,
+			color = $4,
+			is_default = $5,
+			sort_order = $6,
+			is_active = $7,
+			updated_at = $8
+		WHERE id = $9
+	`, category.Type, namePayload, category.IconName, category.Color, category.IsDefault, category.SortOrder, category.IsActive, category.UpdatedAt, category.ID)
+	if err != nil {
+		return appErrors.DatabaseError
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return appErrors.DatabaseError
+	}
+	if rows == 0 {
+		return appErrors.InvalidFinanceData
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListQuickExpenseCategories(ctx context.Context, categoryType string) ([]*QuickExpenseCategory, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+	args := []interface{}{userID}
+	query := `
+		SELECT category_tag, category_name, category_type
+		FROM finance_quick_exp_categories
+		WHERE user_id = $1
+	`
+	if strings.TrimSpace(categoryType) != "" {
+		query += " AND category_type = $2"
+		args = append(args, categoryType)
+	}
+	query += " ORDER BY created_at ASC"
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, appErrors.DatabaseError
+	}
+	defer rows.Close()
+
+	results := make([]*QuickExpenseCategory, 0)
+	for rows.Next() {
+		var tag, name, ctype string
+		if err := rows.Scan(&tag, &name, &ctype); err != nil {
+			return nil, appErrors.DatabaseError
+		}
+		results = append(results, &QuickExpenseCategory{
+			Tag:  tag,
+			Name: name,
+			Type: ctype,
+		})
+	}
+	return results, nil
+}
+
+func (r *PostgresRepository) ReplaceQuickExpenseCategories(ctx context.Context, categoryType string, categories []*QuickExpenseCategory) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return appErrors.DatabaseError
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM finance_quick_exp_categories
+		WHERE user_id = $1 AND category_type = $2
+	`, userID, categoryType); err != nil {
+		return appErrors.DatabaseError
+	}
+
+	now := utils.NowUTC()
+	for _, entry := range categories {
+		if entry == nil || strings.TrimSpace(entry.Tag) == "" {
+			continue
+		}
+		ctype := strings.TrimSpace(entry.Type)
+		if ctype == "" {
+			ctype = categoryType
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO finance_quick_exp_categories
+				(id, user_id, category_tag, category_name, category_type, created_at, updated_at)
+			VALUES ($1,$2,$3,$4,$5,$6,$7)
+		`, uuid.NewString(), userID, strings.TrimSpace(entry.Tag), strings.TrimSpace(entry.Name), ctype, now, now); err != nil {
+			return appErrors.DatabaseError
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return appErrors.DatabaseError
+	}
+	return nil
+}
+
+func mapRowToCategory(row categoryRow) (*FinanceCategory, error) {
+	name := map[string]string{}
+	if len(row.NameI18n) > 0 {
+		if err := json.Unmarshal(row.NameI18n, &name); err != nil {
+			return nil, err
+		}
+	}
+	var color *string
+	if row.Color.Valid {
+		color = &row.Color.String
+	}
+	return &FinanceCategory{
+		ID:        row.ID,
+		Type:      row.Type,
+		NameI18n:  name,
+		IconName:  row.IconName,
+		Color:     color,
+		IsDefault: row.IsDefault,
+		SortOrder: row.SortOrder,
+		IsActive:  row.IsActive,
+		CreatedAt: row.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt: row.UpdatedAt.UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// ========== ROW STRUCTS AND MAPPERS ==========
+
+type accountRow struct {
+	ID                    string         `db:"id"`
+	UserID                string         `db:"user_id"`
+	Name                  string         `db:"name"`
+	Currency              string         `db:"currency"`
+	AccountType           string         `db:"account_type"`
+	InitialBalance        Money          `db:"initial_balance"`
+	CurrentBalance        Money          `db:"current_balance"`
+	CreditLimit           Money          `db:"credit_limit"`
+	StatementClosingDay   sql.NullInt64  `db:"statement_closing_day"`
+	PaymentDueDay         sql.NullInt64  `db:"payment_due_day"`
+	MinimumPaymentPercent float64        `db:"minimum_payment_percent"`
+	MinimumPaymentAmount  Money          `db:"minimum_payment_amount"`
+	ParentAccountID       sql.NullString `db:"parent_account_id"`
+	LinkedGoalID          sql.NullString `db:"linked_goal_id"`
+	CustomTypeID          sql.NullString `db:"custom_type_id"`
+	IsMain                bool           `db:"is_main"`
+	IsArchived            bool           `db:"is_archived"`
+	ShowStatus            string         `db:"show_status"`
+	CreatedAt             string         `db:"created_at"`
+	UpdatedAt             string         `db:"updated_at"`
+}
+
+// creditColumns are an account's card terms as stored; an account without
+// terms has no cycle days and a zero limit.
+type creditColumns struct {
+	limit      Money
+	closingDay *int
+	dueDay     *int
+	percent    float64
+	minimum    Money
+}
+
+func creditColumnsFor(account *Account) creditColumns {
+	terms := account.Credit
+	if terms == nil {
+		return creditColumns{}
+	}
+	return creditColumns{
+		limit:      terms.CreditLimit,
+		closingDay: &terms.StatementClosingDay,
+		dueDay:     &terms.PaymentDueDay,
+		percent:    terms.MinimumPaymentPercent,
+		minimum:    terms.MinimumPaymentAmount,
+	}
+}
+
+func mapRowToAccount(row accountRow) *Account {
+	var linkedGoalID *string
+	if row.LinkedGoalID.Valid {
+		linkedGoalID = &row.LinkedGoalID.String
+	}
+	var customTypeID *string
+	if row.CustomTypeID.Valid {
+		customTypeID = &row.CustomTypeID.String
+	}
+	var parentAccountID *string
+	if row.ParentAccountID.Valid {
+		parentAccountID = &row.ParentAccountID.String
+	}
+	var credit *CreditTerms
+	if row.StatementClosingDay.Valid && row.PaymentDueDay.Valid {
+		credit = &CreditTerms{
+			CreditLimit:           row.CreditLimit,
+			StatementClosingDay:   int(row.StatementClosingDay.Int64),
+			PaymentDueDay:         int(row.PaymentDueDay.Int64),
+			MinimumPaymentPercent: row.MinimumPaymentPercent,
+			MinimumPaymentAmount:  row.MinimumPaymentAmount,
+		}
+	}
+	return &Account{
+		ID:              row.ID,
+		UserID:          row.UserID,
+		Name:            row.Name,
+		AccountType:     row.AccountType,
+		Currency:        row.Currency,
+		InitialBalance:  row.InitialBalance,
+		CurrentBalance:  row.CurrentBalance,
+		Credit:          credit,
+		ParentAccountID: parentAccountID,
+		LinkedGoalID:    linkedGoalID,
+		CustomTypeID:    customTypeID,
+		IsMain:          row.IsMain,
+		IsArchived:      row.IsArchived,
+		ShowStatus:      row.ShowStatus,
+		CreatedAt:       row.CreatedAt,
+		UpdatedAt:       row.UpdatedAt,
+	}
+}
+
+type transactionRow struct {
+	ID                    string         `db:"id"`
+	UserID                string         `db:"user_id"`
+	Type                  string         `db:"type"`
+	Status                string         `db:"status"`
+	AccountID             sql.NullString `db:"account_id"`
+	FromAccountID         sql.NullString `db:"from_account_id"`
+	ToAccountID           sql.NullString `db:"to_account_id"`
+	ReferenceType         sql.NullString `db:"reference_type"`
+	ReferenceID           sql.NullString `db:"reference_id"`
+	Amount                Money          `db:"amount"`
+	Currency              string         `db:"currency"`
+	BaseCurrency          sql.NullString `db:"base_currency"`
+	RateUsedToBase        float64        `db:"rate_used_to_base"`
+	ConvertedAmountToBase Money          `db:"converted_amount_to_base"`
+	ToAmount              Money          `db:"to_amount"`
+	ToCurrency            sql.NullString `db:"to_currency"`
+	EffectiveRateFromTo   float64        `db:"effective_rate_from_to"`
+	FeeAmount             Money          `db:"fee_amount"`
+	FeeCategoryID         sql.NullString `db:"fee_category_id"`
+	CategoryID            sql.NullString `db:"category_id"`
+	Category              sql.NullString `db:"category"`
+	SubcategoryID         sql.NullString `db:"subcategory_id"`
+	Name                  sql.NullString `db:"name"`
+	Description           sql.NullString `db:"description"`
+	Date                  sql.NullTime   `db:"date"`
+	Time                  sql.NullString `db:"time"`
+	GoalID                sql.NullString `db:"linked_goal_id"`
+	BudgetID              sql.NullString `db:"budget_id"`
+	DebtID                sql.NullString `db:"linked_debt_id"`
+	HabitID               sql.NullString `db:"habit_id"`
+	CounterpartyID        sql.NullString `db:"counterparty_id"`
+	RecurringID           sql.NullString `db:"recurring_id"`
+	Attachments           []byte         `db:"attachments"`
+	Tags                  []byte         `db:"tags"`
+	Splits                []byte         `db:"splits"`
+	ReconciliationID      sql.NullString `db:"reconciliation_id"`
+	ClearedAt             sql.NullTime   `db:"cleared_at"`
+	IsScheduled           bool           `db:"is_scheduled"`
+	IsBalanceAdjustment   bool           `db:"is_balance_adjustment"`
+	SkipBudgetMatching    bool           `db:"skip_budget_matching"`
+	ShowStatus            string         `db:"show_status"`
+	RelatedBudgetID       sql.NullString `db:"related_budget_id"`
+	RelatedDebtID         sql.NullString `db:"related_debt_id"`
+	PlannedAmount         Money          `db:"planned_amount"`
+	PaidAmount            Money          `db:"paid_amount"`
+	OriginalCurrency      sql.NullString `db:"original_currency"`
+	OriginalAmount        Money          `db:"original_amount"`
+	ConversionRate        float64        `db:"conversion_rate"`
+	OccurredAt            sql.NullTime   `db:"occurred_at"`
+	Metadata              []byte         `db:"metadata"`
+	CreatedAt             string         `db:"created_at"`
+	UpdatedAt             string         `db:"updated_at"`
+}
+
+func mapRowToTransaction(row transactionRow) *Transaction {
+	var accountID *string
+	if row.AccountID.Valid {
+		accountID = &row.AccountID.String
+	}
+	var fromAccountID *string
+	if row.FromAccountID.Valid {
+		fromAccountID = &row.FromAccountID.String
+	}
+	var toAccountID *string
+	if row.ToAccountID.Valid {
+		toAccountID = &row.ToAccountID.String
+	}
+	var referenceType *string
+	if row.ReferenceType.Valid {
+		referenceType = &row.ReferenceType.String
+	}
+	var referenceID *string
+	if row.ReferenceID.Valid {
+		referenceID = &row.ReferenceID.String
+	}
+	baseCurrency := row.Currency
+	if row.BaseCurrency.Valid && row.BaseCurrency.String != "" {
+		baseCurrency = row.BaseCurrency.String
+	}
+	var toCurrency *string
+	if row.ToCurrency.Valid {
+		toCurrency = &row.ToCurrency.String
+	}
+	var feeCategoryID *string
+	if row.FeeCategoryID.Valid {
+		feeCategoryID = &row.FeeCategoryID.String
+	}
+	var categoryID *string
+	if row.CategoryID.Valid {
+		categoryID = &row.CategoryID.String
+	} else if row.Category.Valid {
+		categoryID = &row.Category.String
+	}
+	var subcategoryID *string
+	if row.SubcategoryID.Valid {
+		subcategoryID = &row.SubcategoryID.String
+	}
+	var name *string
+	if row.Name.Valid {
+		name = &row.Name.String
+	}
+	var description *string
+	if row.Description.Valid {
+		description = &row.Description.String
+	}
+	date := ""
+	if row.Date.Valid {
+		date = row.Date.Time.Format("2006-01-02")
+	}
+	var timeValue *string
+	if row.Time.Valid {
+		timeValue = &row.Time.String
+	}
+	var goalID *string
+	if row.GoalID.Valid {
+		goalID = &row.GoalID.String
+	}
+	var budgetID *string
+	if row.BudgetID.Valid {
+		budgetID = &row.BudgetID.String
+	}
+	var debtID *string
+	if row.DebtID.Valid {
+		debtID = &row.DebtID.String
+	}
+	var habitID *string
+	if row.HabitID.Valid {
+		habitID = &row.HabitID.String
+	}
+	var counterpartyID *string
+	if row.CounterpartyID.Valid {
+		counterpartyID = &row.CounterpartyID.String
+	}
+	var recurringID *string
+	if row.RecurringID.Valid {
+		recurringID = &row.RecurringID.String
+	}
+	attachments := []string{}
+	if len(row.Attachments) > 0 {
+		_ = json.Unmarshal(row.Attachments, &attachments)
+	}
+	tags := []string{}
+	if len(row.Tags) > 0 {
+		_ = json.Unmarshal(row.Tags, &tags)
+	}
+	var relatedBudgetID *string
+	if row.RelatedBudgetID.Valid {
+		relatedBudgetID = &row.RelatedBudgetID.String
+	}
+	var relatedDebtID *string
+	if row.RelatedDebtID.Valid {
+		relatedDebtID = &row.RelatedDebtID.String
+	}
+	var originalCurrency *string
+	if row.OriginalCurrency.Valid {
+		originalCurrency = &row.OriginalCurrency.String
+	}
+	var occurredAt string
+	if row.OccurredAt.Valid {
+		occurredAt = row.OccurredAt.Time.UTC().Format(time.RFC3339)
+	}
+	metadata := map[string]interface{}{}
+	if len(row.Metadata) > 0 {
+		_ = json.Unmarshal(row.Metadata, &metadata)
+	}
+	var splits []TransactionSplit
+	if len(row.Splits) > 0 {
+		_ = json.Unmarshal(row.Splits, &splits)
+	}
+	if len(splits) == 0 {
+		splits = nil
+	}
+	var reconciliationID *string
+	if row.ReconciliationID.Valid {
+		reconciliationID = &row.ReconciliationID.String
+	}
+	var clearedAt *string
+	if row.ClearedAt.Valid {
+		value := row.ClearedAt.Time.UTC().Format(time.RFC3339)
+		clearedAt = &value
+	}
+	return &Transaction{
+		ID:                    row.ID,
+		UserID:                row.UserID,
+		Type:                  row.Type,
+		Status:                row.Status,
+		AccountID:             accountID,
+		FromAccountID:         fromAccountID,
+		ToAccountID:           toAccountID,
+		ReferenceType:         referenceType,
+		ReferenceID:           referenceID,
+		Amount:                row.Amount,
+		Currency:              row.Currency,
+		BaseCurrency:          baseCurrency,
+		RateUsedToBase:        row.RateUsedToBase,
+		ConvertedAmountToBase: row.ConvertedAmountToBase,
+		ToAmount:              row.ToAmount,
+		ToCurrency:            toCurrency,
+		EffectiveRateFromTo:   row.EffectiveRateFromTo,
+		FeeAmount:             row.FeeAmount,
+		FeeCategoryID:         feeCategoryID,
+		CategoryID:            categoryID,
+		SubcategoryID:         subcategoryID,
+		Name:                  name,
+		Description:           description,
+		Date:                  date,
+		Time:                  timeValue,
+		GoalID:                goalID,
+		BudgetID:              budgetID,
+		DebtID:                debtID,
+		HabitID:               habitID,
+		CounterpartyID:        counterpartyID,
+		RecurringID:           recurringID,
+		Attachments:           attachments,
+		Tags:                  tags,
+		Splits:                splits,
+		ReconciliationID:      reconciliationID,
+		ClearedAt:             clearedAt,
+		IsScheduled:           row.IsScheduled,
+		IsBalanceAdjustment:   row.IsBalanceAdjustment,
+		SkipBudgetMatching:    row.SkipBudgetMatching,
+		ShowStatus:            row.ShowStatus,
+		RelatedBudgetID:       relatedBudgetID,
+		RelatedDebtID:         relatedDebtID,
+		PlannedAmount:         row.PlannedAmount,
+		PaidAmount:            row.PaidAmount,
+		OriginalCurrency:      originalCurrency,
+		OriginalAmount:        row.OriginalAmount,
+		ConversionRate:        row.ConversionRate,
+		OccurredAt:            occurredAt,
+		Metadata:              metadata,
+		CreatedAt:             row.CreatedAt,
+		UpdatedAt:             row.UpdatedAt,
+	}
+}
+
+type budgetRow struct {
+	ID                string         `db:"id"`
+	UserID            string         `db:"user_id"`
+	Name              string         `db:"name"`
+	BudgetType        string         `db:"budget_type"`
+	CategoryIDs       []byte         `db:"category_ids"`
+	LinkedGoalID      sql.NullString `db:"linked_goal_id"`
+	AccountID         sql.NullString `db:"account_id"`
+	TransactionType   sql.NullString `db:"transaction_type"`
+	Currency          string         `db:"currency"`
+	LimitAmount       Money          `db:"limit_amount"`
+	PeriodType        string         `db:"period_type"`
+	StartDate         sql.NullTime   `db:"start_date"`
+	EndDate           sql.NullTime   `db:"end_date"`
+	SpentAmount       Money          `db:"spent_amount"`
+	RemainingAmount   Money          `db:"remaining_amount"`
+	PercentUsed       float64        `db:"percent_used"`
+	IsOverspent       bool           `db:"is_overspent"`
+	RolloverMode      string         `db:"rollover_mode"`
+	CarriedAmount     Money          `db:"carried_amount"`
+	NotifyOnExceed    bool           `db:"notify_on_exceed"`
+	AlertThresholds   []byte         `db:"alert_thresholds"`
+	ContributionTotal Money          `db:"contribution_total"`
+	CurrentBalance    Money          `db:"current_balance"`
+	IsArchived        bool           `db:"is_archived"`
+	ShowStatus        string         `db:"show_status"`
+	CreatedAt         string         `db:"created_at"`
+	UpdatedAt         string         `db:"updated_at"`
+}
+
+func mapRowToBudget(row budgetRow) *Budget {
+	categoryIDs := []string{}
+	if len(row.CategoryIDs) > 0 {
+		_ = json.Unmarshal(row.CategoryIDs, &categoryIDs)
+	}
+	var alertThresholds []int
+	if len(row.AlertThresholds) > 0 {
+		_ = json.Unmarshal(row.AlertThresholds, &alertThresholds)
+	}
+	var linkedGoalID *string
+	if row.LinkedGoalID.Valid {
+		linkedGoalID = &row.LinkedGoalID.String
+	}
+	var accountID *string
+	if row.AccountID.Valid {
+		accountID = &row.AccountID.String
+	}
+	var transactionType *string
+	if row.TransactionType.Valid {
+		transactionType = &row.TransactionType.String
+	}
+	var startDate *string
+	if row.StartDate.Valid {
+		formatted := row.StartDate.Time.Format("2006-01-02")
+		startDate = &formatted
+	}
+	var endDate *string
+	if row.EndDate.Valid {
+		formatted := row.EndDate.Time.Format("2006-01-02")
+		endDate = &formatted
+	}
+	return &Budget{
+		ID:                row.ID,
+		UserID:            row.UserID,
+		Name:              row.Name,
+		BudgetType:        row.BudgetType,
+		CategoryIDs:       categoryIDs,
+		LinkedGoalID:      linkedGoalID,
+		AccountID:         accountID,
+		TransactionType:   transactionType,
+		Currency:          row.Currency,
+		LimitAmount:       row.LimitAmount,
+		PeriodType:        row.PeriodType,
+		StartDate:         startDate,
+		EndDate:           endDate,
+		SpentAmount:       row.SpentAmount,
+		RemainingAmount:   row.RemainingAmount,
+		PercentUsed:       row.PercentUsed,
+		IsOverspent:       row.IsOverspent,
+		RolloverMode:      row.RolloverMode,
+		CarriedAmount:     row.CarriedAmount,
+		NotifyOnExceed:    row.NotifyOnExceed,
+		AlertThresholds:   alertThresholds,
+		ContributionTotal: row.ContributionTotal,
+		CurrentBalance:    row.CurrentBalance,
+		IsArchived:        row.IsArchived,
+		ShowStatus:        row.ShowStatus,
+		CreatedAt:         row.CreatedAt,
+		UpdatedAt:         row.UpdatedAt,
+	}
+}
+
+type debtRow struct {
+	ID                           string          `db:"id"`
+	UserID                       string          `db:"user_id"`
+	Name                         sql.NullString  `db:"name"`
+	Balance                      sql.NullFloat64 `db:"balance"`
+	Direction                    string          `db:"direction"`
+	CounterpartyID               sql.NullString  `db:"counterparty_id"`
+	CounterpartyName             string          `db:"counterparty_name"`
+	Description                  sql.NullString  `db:"description"`
+	PrincipalAmount              Money           `db:"principal_amount"`
+	PrincipalCurrency            string          `db:"principal_currency"`
+	PrincipalOriginalAmount      Money           `db:"principal_original_amount"`
+	PrincipalOriginalCurrency    sql.NullString  `db:"principal_original_currency"`
+	BaseCurrency                 string          `db:"base_currency"`
+	RateOnStart                  float64         `db:"rate_on_start"`
+	PrincipalBaseValue           Money           `db:"principal_base_value"`
+	RepaymentCurrency            sql.NullString  `db:"repayment_currency"`
+	RepaymentAmount              Money           `db:"repayment_amount"`
+	RepaymentRateOnStart         float64         `db:"repayment_rate_on_start"`
+	IsFixedRepaymentAmount       bool            `db:"is_fixed_repayment_amount"`
+	StartDate                    sql.NullTime    `db:"start_date"`
+	DueDate                      sql.NullTime    `db:"due_date"`
+	InterestMode                 sql.NullString  `db:"interest_mode"`
+	InterestRateAnnual           float64         `db:"interest_rate_annual"`
+	ScheduleHint                 sql.NullString  `db:"schedule_hint"`
+	LinkedGoalID                 sql.NullString  `db:"linked_goal_id"`
+	LinkedBudgetID               sql.NullString  `db:"linked_budget_id"`
+	FundingAccountID             sql.NullString  `db:"funding_account_id"`
+	FundingTransactionID         sql.NullString  `db:"funding_transaction_id"`
+	LentFromAccountID            sql.NullString  `db:"lent_from_account_id"`
+	ReturnToAccountID            sql.NullString  `db:"return_to_account_id"`
+	ReceivedToAccountID          sql.NullString  `db:"received_to_account_id"`
+	PayFromAccountID             sql.NullString  `db:"pay_from_account_id"`
+	CustomRateUsed               float64         `db:"custom_rate_used"`
+	ExchangeRateCurrent          float64         `db:"exchange_rate_current"`
+	ReminderEnabled              bool            `db:"reminder_enabled"`
+	ReminderTime                 sql.NullString  `db:"reminder_time"`
+	Status                       string          `db:"status"`
+	SettledAt                    sql.NullTime    `db:"settled_at"`
+	FinalRateUsed                float64         `db:"final_rate_used"`
+	FinalProfitLoss              Money           `db:"final_profit_loss"`
+	FinalProfitLossCurrency      sql.NullString  `db:"final_profit_loss_currency"`
+	TotalPaidInRepaymentCurrency Money           `db:"total_paid_in_repayment_currency"`
+	RemainingAmount              Money           `db:"remaining_amount"`
+	TotalPaid                    Money           `db:"total_paid"`
+	PercentPaid                  float64         `db:"percent_paid"`
+	ShowStatus                   string          `db:"show_status"`
+	Attachments                  []byte          `db:"attachments"`
+	CreatedAt                    string          `db:"created_at"`
+	UpdatedAt                    string          `db:"updated_at"`
+}
+
+func mapRowToDebt(row debtRow) *Debt {
+	var counterpartyID *string
+	if row.CounterpartyID.Valid {
+		counterpartyID = &row.CounterpartyID.String
+	}
+	var description *string
+	if row.Description.Valid {
+		description = &row.Description.String
+	}
+	var principalOriginalCurrency *string
+	if row.PrincipalOriginalCurrency.Valid {
+		principalOriginalCurrency = &row.PrincipalOriginalCurrency.String
+	}
+	var repaymentCurrency *string
+	if row.RepaymentCurrency.Valid {
+		repaymentCurrency = &row.RepaymentCurrency.String
+	}
+	var startDate string
+	if row.StartDate.Valid {
+		startDate = row.StartDate.Time.Format("2006-01-02")
+	}
+	var dueDate *string
+	if row.DueDate.Valid {
+		formatted := row.DueDate.Time.Format("2006-01-02")
+		dueDate = &formatted
+	}
+	var interestMode *string
+	if row.InterestMode.Valid {
+		interestMode = &row.InterestMode.String
+	}
+	var scheduleHint *string
+	if row.ScheduleHint.Valid {
+		scheduleHint = &row.ScheduleHint.String
+	}
+	var linkedGoalID *string
+	if row.LinkedGoalID.Valid {
+		linkedGoalID = &row.LinkedGoalID.String
+	}
+	var linkedBudgetID *string
+	if row.LinkedBudgetID.Valid {
+		linkedBudgetID = &row.LinkedBudgetID.String
+	}
+	var fundingAccountID *string
+	if row.FundingAccountID.Valid {
+		fundingAccountID = &row.FundingAccountID.String
+	}
+	var fundingTransactionID *string
+	if row.FundingTransactionID.Valid {
+		fundingTransactionID = &row.FundingTransactionID.String
+	}
+	var lentFromAccountID *string
+	if row.LentFromAccountID.Valid {
+		lentFromAccountID = &row.LentFromAccountID.String
+	}
+	var returnToAccountID *string
+	if row.ReturnToAccountID.Valid {
+		returnToAccountID = &row.ReturnToAccountID.String
+	}
+	var receivedToAccountID *string
+	if row.ReceivedToAccountID.Valid {
+		receivedToAccountID = &row.ReceivedToAccountID.String
+	}
+	var payFromAccountID *string
+	if row.PayFromAccountID.Valid {
+		payFromAccountID = &row.PayFromAccountID.String
+	}
+	var reminderTime *string
+	if row.ReminderTime.Valid {
+		reminderTime = &row.ReminderTime.String
+	}
+	var settledAt *string
+	if row.SettledAt.Valid {
+		formatted := row.SettledAt.Time.Format("2006-01-02T15:04:05Z07:00")
+		settledAt = &formatted
+	}
+	var finalProfitLossCurrency *string
+	if row.FinalProfitLossCurrency.Valid {
+		finalProfitLossCurrency = &row.FinalProfitLossCurrency.String
+	}
+	counterpartyName := row.CounterpartyName
+	if counterpartyName == "" && row.Name.Valid {
+		counterpartyName = row.Name.String
+	}
+	return &Debt{
+		ID:                           row.ID,
+		UserID:                       row.UserID,
+		Direction:                    row.Direction,
+		CounterpartyID:               counterpartyID,
+		CounterpartyName:             counterpartyName,
+		Description:                  description,
+		PrincipalAmount:              row.PrincipalAmount,
+		PrincipalCurrency:            row.PrincipalCurrency,
+		PrincipalOriginalAmount:      row.PrincipalOriginalAmount,
+		PrincipalOriginalCurrency:    principalOriginalCurrency,
+		BaseCurrency:                 row.BaseCurrency,
+		RateOnStart:                  row.RateOnStart,
+		PrincipalBaseValue:           row.PrincipalBaseValue,
+		RepaymentCurrency:            repaymentCurrency,
+		RepaymentAmount:              row.RepaymentAmount,
+		RepaymentRateOnStart:         row.RepaymentRateOnStart,
+		IsFixedRepaymentAmount:       row.IsFixedRepaymentAmount,
+		StartDate:                    startDate,
+		DueDate:                      dueDate,
+		InterestMode:                 interestMode,
+		InterestRateAnnual:           row.InterestRateAnnual,
+		ScheduleHint:                 scheduleHint,
+		LinkedGoalID:                 linkedGoalID,
+		LinkedBudgetID:               linkedBudgetID,
+		FundingAccountID:             fundingAccountID,
+		FundingTransactionID:         fundingTransactionID,
+		LentFromAccountID:            lentFromAccountID,
+		ReturnToAccountID:            returnToAccountID,
+		ReceivedToAccountID:          receivedToAccountID,
+		PayFromAccountID:             payFromAccountID,
+		CustomRateUsed:               row.CustomRateUsed,
+		ExchangeRateCurrent:          row.ExchangeRateCurrent,
+		ReminderEnabled:              row.ReminderEnabled,
+		ReminderTime:                 reminderTime,
+		Status:                       row.Status,
+		SettledAt:                    settledAt,
+		FinalRateUsed:                row.FinalRateUsed,
+		FinalProfitLoss:              row.FinalProfitLoss,
+		FinalProfitLossCurrency:      finalProfitLossCurrency,
+		TotalPaidInRepaymentCurrency: row.TotalPaidInRepaymentCurrency,
+		RemainingAmount:              row.RemainingAmount,
+		TotalPaid:                    row.TotalPaid,
+		PercentPaid:                  row.PercentPaid,
+		ShowStatus:                   row.ShowStatus,
+		Attachments:                  decodeIDList(row.Attachments),
+		CreatedAt:                    row.CreatedAt,
+		UpdatedAt:                    row.UpdatedAt,
+	}
+}
+
+type debtPaymentRow struct {
+	ID                    string         `db:"id"`
+	DebtID                string         `db:"debt_id"`
+	Amount                Money          `db:"amount"`
+	Currency              string         `db:"currency"`
+	BaseCurrency          string         `db:"base_currency"`
+	RateUsedToBase        float64        `db:"rate_used_to_base"`
+	ConvertedAmountToBase Money          `db:"converted_amount_to_base"`
+	RateUsedToDebt        float64        `db:"rate_used_to_debt"`
+	ConvertedAmountToDebt Money          `db:"converted_amount_to_debt"`
+	PaymentDate           sql.NullTime   `db:"payment_date"`
+	AccountID             sql.NullString `db:"account_id"`
+	Note                  sql.NullString `db:"note"`
+	RelatedTransactionID  sql.NullString `db:"related_transaction_id"`
+	AppliedRate           float64        `db:"applied_rate"`
+	Attachments           []byte         `db:"attachments"`
+	CreatedAt             string         `db:"created_at"`
+	UpdatedAt             string         `db:"updated_at"`
+	DeletedAt             sql.NullString `db:"deleted_at"`
+}
+
+func mapRowToDebtPayment(row debtPaymentRow) *DebtPayment {
+	var accountID *string
+	if row.AccountID.Valid {
+		accountID = &row.AccountID.String
+	}
+	var note *string
+	if row.Note.Valid {
+		note = &row.Note.String
+	}
+	var relatedTransactionID *string
+	if row.RelatedTransactionID.Valid {
+		relatedTransactionID = &row.RelatedTransactionID.String
+	}
+	paymentDate := ""
+	if row.PaymentDate.Valid {
+		paymentDate = row.PaymentDate.Time.Format("2006-01-02")
+	}
+	return &DebtPayment{
+		ID:                    row.ID,
+		DebtID:                row.DebtID,
+		Amount:                row.Amount,
+		Currency:              row.Currency,
+		BaseCurrency:          row.BaseCurrency,
+		RateUsedToBase:        row.RateUsedToBase,
+		ConvertedAmountToBase: row.ConvertedAmountToBase,
+		RateUsedToDebt:        row.RateUsedToDebt,
+		ConvertedAmountToDebt: row.ConvertedAmountToDebt,
+		PaymentDate:           paymentDate,
+		AccountID:             accountID,
+		Note:                  note,
+		RelatedTransactionID:  relatedTransactionID,
+		AppliedRate:           row.AppliedRate,
+		Attachments:           decodeIDList(row.Attachments),
+		CreatedAt:             row.CreatedAt,
+		UpdatedAt:             row.UpdatedAt,
+	}
+}
+
+type counterpartyRow struct {
+	ID             string         `db:"id"`
+	UserID         string         `db:"user_id"`
+	DisplayName    string         `db:"display_name"`
+	PhoneNumber    sql.NullString `db:"phone_number"`
+	Comment        sql.NullString `db:"comment"`
+	SearchKeywords sql.NullString `db:"search_keywords"`
+	ShowStatus     string         `db:"show_status"`
+	CreatedAt      string         `db:"created_at"`
+	UpdatedAt      string         `db:"updated_at"`
+	DeletedAt      sql.NullString `db:"deleted_at"`
+}
+
+func mapRowToCounterparty(row counterpartyRow) *Counterparty {
+	var phoneNumber *string
+	if row.PhoneNumber.Valid {
+		phoneNumber = &row.PhoneNumber.String
+	}
+	var comment *string
+	if row.Comment.Valid {
+		comment = &row.Comment.String
+	}
+	var searchKeywords *string
+	if row.SearchKeywords.Valid {
+		searchKeywords = &row.SearchKeywords.String
+	}
+	return &Counterparty{
+		ID:             row.ID,
+		UserID:         row.UserID,
+		DisplayName:    row.DisplayName,
+		PhoneNumber:    phoneNumber,
+		Comment:        comment,
+		SearchKeywords: searchKeywords,
 		ShowStatus:     row.ShowStatus,
 		CreatedAt:      row.CreatedAt,
 		UpdatedAt:      row.UpdatedAt,
 	}
 }
 
-type transactionRow struct {
-	ID                    string         `db:"id"`
-	UserID                string         `db:"user_id"`
-	Type                  string         `db:"type"`
-	Status                string         `db:"status"`
-	AccountID             sql.NullString `db:"account_id"`
-	FromAccountID         sql.NullString `db:"from_account_id"`
-	ToAccountID           sql.NullString `db:"to_account_id"`
-	ReferenceType         sql.NullString `db:"reference_type"`
-	ReferenceID           sql.NullString `db:"reference_id"`
-	Amount                float64        `db:"amount"`
-	Currency              string         `db:"currency"`
-	BaseCurrency          sql.NullString `db:"base_currency"`
-	RateUsedToBase        float64        `db:"rate_used_to_base"`
-	ConvertedAmountToBase float64        `db:"converted_amount_to_base"`
-	ToAmount              float64        `db:"to_amount"`
-	ToCurrency            sql.NullString `db:"to_currency"`
-	EffectiveRateFromTo   float64        `db:"effective_rate_from_to"`
-	FeeAmount             float64        `db:"fee_amount"`
-	FeeCategoryID         sql.NullString `db:"fee_category_id"`
-	CategoryID            sql.NullString `db:"category_id"`
-	Category              sql.NullString `db:"category"`
-	SubcategoryID         sql.NullString `db:"subcategory_id"`
-	Name                  sql.NullString `db:"name"`
-	Description           sql.NullString `db:"description"`
-	Date                  sql.NullTime   `db:"date"`
-	Time                  sql.NullString `db:"time"`
-	GoalID                sql.NullString `db:"linked_goal_id"`
-	BudgetID              sql.NullString `db:"budget_id"`
-	DebtID                sql.NullString `db:"linked_debt_id"`
-	HabitID               sql.NullString `db:"habit_id"`
-	CounterpartyID        sql.NullString `db:"counterparty_id"`
-	RecurringID           sql.NullString `db:"recurring_id"`
-	Attachments           []byte         `db:"attachments"`
-	Tags                  []byte         `db:"tags"`
-	IsBalanceAdjustment   bool           `db:"is_balance_adjustment"`
-	SkipBudgetMatching    bool           `db:"skip_budget_matching"`
-	ShowStatus            string         `db:"show_status"`
-	RelatedBudgetID       sql.NullString `db:"related_budget_id"`
-	RelatedDebtID         sql.NullString `db:"related_debt_id"`
-	PlannedAmount         float64        `db:"planned_amount"`
-	PaidAmount            float64        `db:"paid_amount"`
-	OriginalCurrency      sql.NullString `db:"original_currency"`
-	OriginalAmount        float64        `db:"original_amount"`
-	ConversionRate        float64        `db:"conversion_rate"`
-	OccurredAt            sql.NullTime   `db:"occurred_at"`
-	Metadata              []byte         `db:"metadata"`
-	CreatedAt             string         `db:"created_at"`
-	UpdatedAt             string         `db:"updated_at"`
+type fxRateRow struct {
+	ID            string       `db:"id"`
+	RateDate      sql.NullTime `db:"rate_date"`
+	FromCurrency  string       `db:"from_currency"`
+	ToCurrency    string       `db:"to_currency"`
+	Rate          float64      `db:"rate"`
+	RateMid       float64      `db:"rate_mid"`
+	RateBid       float64      `db:"rate_bid"`
+	RateAsk       float64      `db:"rate_ask"`
+	Nominal       float64      `db:"nominal"`
+	SpreadPercent float64      `db:"spread_percent"`
+	Source        string       `db:"source"`
+	CreatedAt     string       `db:"created_at"`
+	UpdatedAt     string       `db:"updated_at"`
+}
+
+func mapRowToFXRate(row fxRateRow) *FXRate {
+	date := ""
+	if row.RateDate.Valid {
+		date = row.RateDate.Time.Format("2006-01-02")
+	}
+	return &FXRate{
+		ID:            row.ID,
+		Date:          date,
+		FromCurrency:  row.FromCurrency,
+		ToCurrency:    row.ToCurrency,
+		Rate:          row.Rate,
+		RateMid:       row.RateMid,
+		RateBid:       row.RateBid,
+		RateAsk:       row.RateAsk,
+		Nominal:       row.Nominal,
+		SpreadPercent: row.SpreadPercent,
+		Source:        row.Source,
+		CreatedAt:     row.CreatedAt,
+		UpdatedAt:     row.UpdatedAt,
+	}
+}
+
+
+type recurringRow struct {
+	ID                   string         `db:"id"`
+	UserID               string         `db:"user_id"`
+	Name                 string         `db:"name"`
+	Type                 string         `db:"type"`
+	AccountID            sql.NullString `db:"account_id"`
+	FromAccountID        sql.NullString `db:"from_account_id"`
+	ToAccountID          sql.NullString `db:"to_account_id"`
+	Amount               Money          `db:"amount"`
+	Currency             string         `db:"currency"`
+	ToAmount             Money          `db:"to_amount"`
+	ToCurrency           sql.NullString `db:"to_currency"`
+	CategoryID           sql.NullString `db:"category_id"`
+	SubcategoryID        sql.NullString `db:"subcategory_id"`
+	Description          sql.NullString `db:"description"`
+	BudgetID             sql.NullString `db:"budget_id"`
+	GoalID               sql.NullString `db:"goal_id"`
+	CounterpartyID       sql.NullString `db:"counterparty_id"`
+	Tags                 []byte         `db:"tags"`
+	Frequency            string         `db:"frequency"`
+	IntervalCount        int            `db:"interval_count"`
+	IntervalUnit         sql.NullString `db:"interval_unit"`
+	DayOfWeek            sql.NullInt64  `db:"day_of_week"`
+	DayOfMonth           sql.NullInt64  `db:"day_of_month"`
+	StartDate            sql.NullTime   `db:"start_date"`
+	EndDate              sql.NullTime   `db:"end_date"`
+	OccurrenceCount      sql.NullInt64  `db:"occurrence_count"`
+	OccurrencesGenerated int            `db:"occurrences_generated"`
+	NextOccurrenceDate   sql.NullTime   `db:"next_occurrence_date"`
+	LastOccurrenceDate   sql.NullTime   `db:"last_occurrence_date"`
+	PostingMode          string         `db:"posting_mode"`
+	Status               string         `db:"status"`
+	CreatedAt            string         `db:"created_at"`
+	UpdatedAt            string         `db:"updated_at"`
+}
+
+func mapRowToRecurring(row recurringRow) *RecurringTransaction {
+	tags := []string{}
+	if len(row.Tags) > 0 {
+		_ = json.Unmarshal(row.Tags, &tags)
+	}
+	startDate := ""
+	if row.StartDate.Valid {
+		startDate = row.StartDate.Time.Format("2006-01-02")
+	}
+	return &RecurringTransaction{
+		ID:                   row.ID,
+		UserID:               row.UserID,
+		Name:                 row.Name,
+		Type:                 row.Type,
+		AccountID:            nullStringPtr(row.AccountID),
+		FromAccountID:        nullStringPtr(row.FromAccountID),
+		ToAccountID:          nullStringPtr(row.ToAccountID),
+		Amount:               row.Amount,
+		Currency:             row.Currency,
+		ToAmount:             row.ToAmount,
+		ToCurrency:           nullStringPtr(row.ToCurrency),
+		CategoryID:           nullStringPtr(row.CategoryID),
+		SubcategoryID:        nullStringPtr(row.SubcategoryID),
+		Description:          nullStringPtr(row.Description),
+		BudgetID:             nullStringPtr(row.BudgetID),
+		GoalID:               nullStringPtr(row.GoalID),
+		CounterpartyID:       nullStringPtr(row.CounterpartyID),
+		Tags:                 tags,
+		Frequency:            row.Frequency,
+		Interval:             row.IntervalCount,
+		IntervalUnit:         row.IntervalUnit.String,
+		DayOfWeek:            nullIntPtr(row.DayOfWeek),
+		DayOfMonth:           nullIntPtr(row.DayOfMonth),
+		StartDate:            startDate,
+		EndDate:              nullDatePtr(row.EndDate),
+		OccurrenceCount:      nullIntPtr(row.OccurrenceCount),
+		OccurrencesGenerated: row.OccurrencesGenerated,
+		NextOccurrenceDate:   nullDatePtr(row.NextOccurrenceDate),
+		LastOccurrenceDate:   nullDatePtr(row.LastOccurrenceDate),
+		PostingMode:          row.PostingMode,
+		Status:               row.Status,
+		CreatedAt:            row.CreatedAt,
+		UpdatedAt:            row.UpdatedAt,
+	}
+}
+
+type budgetPeriodRow struct {
+	ID              string       `db:"id"`
+	BudgetID        string       `db:"budget_id"`
+	UserID          string       `db:"user_id"`
+	PeriodStart     sql.NullTime `db:"period_start"`
+	PeriodEnd       sql.NullTime `db:"period_end"`
+	Currency        string       `db:"currency"`
+	BaseLimit       Money        `db:"base_limit"`
+	CarriedIn       Money        `db:"carried_in"`
+	EffectiveLimit  Money        `db:"effective_limit"`
+	SpentAmount     Money        `db:"spent_amount"`
+	RemainingAmount Money        `db:"remaining_amount"`
+	PercentUsed     float64      `db:"percent_used"`
+	IsOverspent     bool         `db:"is_overspent"`
+	CarriedOut      Money        `db:"carried_out"`
+	RolloverMode    string       `db:"rollover_mode"`
+	ClosedAt        string       `db:"closed_at"`
+	CreatedAt       string       `db:"created_at"`
+}
+
+func mapRowToBudgetPeriod(row budgetPeriodRow) *BudgetPeriod {
+	period := &BudgetPeriod{
+		ID:              row.ID,
+		BudgetID:        row.BudgetID,
+		UserID:          row.UserID,
+		Currency:        row.Currency,
+		BaseLimit:       row.BaseLimit,
+		CarriedIn:       row.CarriedIn,
+		EffectiveLimit:  row.EffectiveLimit,
+		SpentAmount:     row.SpentAmount,
+		RemainingAmount: row.RemainingAmount,
+		PercentUsed:     row.PercentUsed,
+		IsOverspent:     row.IsOverspent,
+		CarriedOut:      row.CarriedOut,
+		RolloverMode:    row.RolloverMode,
+		ClosedAt:        row.ClosedAt,
+		CreatedAt:       row.CreatedAt,
+	}
+	if row.PeriodStart.Valid {
+		period.PeriodStart = row.PeriodStart.Time.Format("2006-01-02")
+	}
+	if row.PeriodEnd.Valid {
+		period.PeriodEnd = row.PeriodEnd.Time.Format("2006-01-02")
+	}
+	return period
+}
+
+type debtInstallmentRow struct {
+	ID              string       `db:"id"`
+	DebtID          string       `db:"debt_id"`
+	UserID          string       `db:"user_id"`
+	Sequence        int          `db:"sequence"`
+	PlanType        string       `db:"plan_type"`
+	DueDate         sql.NullTime `db:"due_date"`
+	Amount          Money        `db:"amount"`
+	PrincipalAmount Money        `db:"principal_amount"`
+	InterestAmount  Money        `db:"interest_amount"`
+	CreatedAt       string       `db:"created_at"`
+	UpdatedAt       string       `db:"updated_at"`
+}
+
+func mapRowToDebtInstallment(row debtInstallmentRow) *DebtInstallment {
+	dueDate := ""
+	if row.DueDate.Valid {
+		dueDate = row.DueDate.Time.Format("2006-01-02")
+	}
+	return &DebtInstallment{
+		ID:              row.ID,
+		DebtID:          row.DebtID,
+		UserID:          row.UserID,
+		Sequence:        row.Sequence,
+		PlanType:        row.PlanType,
+		DueDate:         dueDate,
+		Amount:          row.Amount,
+		PrincipalAmount: row.PrincipalAmount,
+		InterestAmount:  row.InterestAmount,
+		PaymentIDs:      []string{},
+		CreatedAt:       row.CreatedAt,
+		UpdatedAt:       row.UpdatedAt,
+	}
+}
+
+type debtStatusEventRow struct {
+	ID         string       `db:"id"`
+	DebtID     string       `db:"debt_id"`
+	UserID     string       `db:"user_id"`
+	FromStatus string       `db:"from_status"`
+	ToStatus   string       `db:"to_status"`
+	DueDate    sql.NullTime `db:"due_date"`
+	OccurredAt string       `db:"occurred_at"`
+}
+
+func mapRowToDebtStatusEvent(row debtStatusEventRow) *DebtStatusEvent {
+	dueDate := ""
+	if row.DueDate.Valid {
+		dueDate = row.DueDate.Time.Format("2006-01-02")
+	}
+	return &DebtStatusEvent{
+		ID:         row.ID,
+		DebtID:     row.DebtID,
+		UserID:     row.UserID,
+		FromStatus: row.FromStatus,
+		ToStatus:   row.ToStatus,
+		DueDate:    dueDate,
+		OccurredAt: row.OccurredAt,
+	}
+}
+
+type recurringOccurrenceRow struct {
+	ID            string         `db:"id"`
+	RecurringID   string         `db:"recurring_id"`
+	UserID        string         `db:"user_id"`
+	DueDate       sql.NullTime   `db:"due_date"`
+	Status        string         `db:"status"`
+	TransactionID sql.NullString `db:"transaction_id"`
+	Error         sql.NullString `db:"error"`
+	CreatedAt     string         `db:"created_at"`
+	UpdatedAt     string         `db:"updated_at"`
+}
+
+func mapRowToRecurringOccurrence(row recurringOccurrenceRow) *RecurringOccurrence {
+	dueDate := ""
+	if row.DueDate.Valid {
+		dueDate = row.DueDate.Time.Format("2006-01-02")
+	}
+	return &RecurringOccurrence{
+		ID:            row.ID,
+		RecurringID:   row.RecurringID,
+		UserID:        row.UserID,
+		DueDate:       dueDate,
+		Status:        row.Status,
+		TransactionID: nullStringPtr(row.TransactionID),
+		Error:         nullStringPtr(row.Error),
+		CreatedAt:     row.CreatedAt,
+		UpdatedAt:     row.UpdatedAt,
+	}
+}
+
+func nullStringPtr(value sql.NullString) *string {
+	if !value.Valid {
+		return nil
+	}
+	result := value.String
+	return &result
+}
+
+func nullIntPtr(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	result := int(value.Int64)
+	return &result
+}
+
+func nullDatePtr(value sql.NullTime) *string {
+	if !value.Valid {
+		return nil
+	}
+	result := value.Time.Format("2006-01-02")
+	return &result
+}
+
+func nullableString(value string) *string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	return &value
+}
+
+func (r *PostgresRepository) ListStatementImportProfiles(ctx context.Context) ([]*StatementImportProfile, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM statement_import_profiles
+		WHERE user_id = $1
+		ORDER BY name ASC
+	`, importProfileSelectFields)
+
+	var rows []importProfileRow
+	if err := r.db.SelectContext(ctx, &rows, query, userID); err != nil {
+		log.Printf("[ListStatementImportProfiles] Query error for user=%s: %v", userID, err)
+		return nil, appErrors.DatabaseError
+	}
+	profiles := make([]*StatementImportProfile, 0, len(rows))
+	for _, row := range rows {
+		profiles = append(profiles, mapRowToImportProfile(row))
+	}
+	return profiles, nil
+}
+
+func (r *PostgresRepository) GetStatementImportProfileByID(ctx context.Context, id string) (*StatementImportProfile, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM statement_import_profiles
+		WHERE id = $1 AND user_id = $2
+	`, importProfileSelectFields)
+
+	var row importProfileRow
+	if err := r.db.GetContext(ctx, &row, query, id, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, appErrors.StatementImportProfileNotFound
+		}
+		log.Printf("[GetStatementImportProfileByID] Query error for id=%s: %v", id, err)
+		return nil, appErrors.DatabaseError
+	}
+	return mapRowToImportProfile(row), nil
+}
+
+func (r *PostgresRepository) CreateStatementImportProfile(ctx context.Context, profile *StatementImportProfile) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	mapping, err := json.Marshal(profile.Mapping)
+	if err != nil {
+		return appErrors.InvalidFinanceData
+	}
+	profile.ID = uuid.NewString()
+	profile.UserID = userID
+	now := utils.NowUTC()
+	profile.CreatedAt = now
+	profile.UpdatedAt = now
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO statement_import_profiles (id, user_id, name, mapping, created_at, updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6)
+	`, profile.ID, userID, profile.Name, mapping, now, now); err != nil {
+		log.Printf("[CreateStatementImportProfile] INSERT error for user=%s: %v", userID, err)
+		return appErrors.DatabaseError
+	}
+	return nil
+}
+
+func (r *PostgresRepository) DeleteStatementImportProfile(ctx context.Context, id string) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM statement_import_profiles WHERE id = $1 AND user_id = $2
+	`, id, userID)
+	if err != nil {
+		log.Printf("[DeleteStatementImportProfile] DELETE error for id=%s: %v", id, err)
+		return appErrors.DatabaseError
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return appErrors.StatementImportProfileNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListStatementImports(ctx context.Context) ([]*StatementImport, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM statement_imports
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, importSelectFields)
+
+	var rows []importRow
+	if err := r.db.SelectContext(ctx, &rows, query, userID); err != nil {
+		log.Printf("[ListStatementImports] Query error for user=%s: %v", userID, err)
+		return nil, appErrors.DatabaseError
+	}
+	imports := make([]*StatementImport, 0, len(rows))
+	for _, row := range rows {
+		imports = append(imports, mapRowToImport(row))
+	}
+	return imports, nil
+}
+
+func (r *PostgresRepository) GetStatementImportByID(ctx context.Context, id string) (*StatementImport, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM statement_imports
+		WHERE id = $1 AND user_id = $2
+	`, importSelectFields)
+
+	var row importRow
+	if err := r.db.GetContext(ctx, &row, query, id, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, appErrors.StatementImportNotFound
+		}
+		log.Printf("[GetStatementImportByID] Query error for id=%s: %v", id, err)
+		return nil, appErrors.DatabaseError
+	}
+	return mapRowToImport(row), nil
+}
+
+// CreateStatementImport stores the batch and all of its rows in one
+// transaction.
+func (r *PostgresRepository) CreateStatementImport(ctx context.Context, batch *StatementImport, rows []*StatementImportRow) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return appErrors.DatabaseError
+	}
+	defer tx.Rollback()
+
+	now := utils.NowUTC()
+	batch.ID = uuid.NewString()
+	batch.UserID = userID
+	batch.CreatedAt = now
+	batch.UpdatedAt = now
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO statement_imports
+			(id, user_id, account_id, format, file_name, profile_id, status, row_count, pending_count, duplicate_count, approved_count, rejected_count, created_at, updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)
+	`, batch.ID, userID, batch.AccountID, batch.Format, batch.FileName, batch.ProfileID, batch.Status,
+		batch.RowCount, batch.PendingCount, batch.DuplicateCount, batch.ApprovedCount, batch.RejectedCount, now, now); err != nil {
+		log.Printf("[CreateStatementImport] INSERT error for user=%s: %v", userID, err)
+		return appErrors.DatabaseError
+	}
+
+	for _, row := range rows {
+		row.ID = uuid.NewString()
+		row.ImportID = batch.ID
+		row.UserID = userID
+		row.CreatedAt = now
+		row.UpdatedAt = now
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO statement_import_rows
+				(id, import_id, user_id, line_number, date, amount, currency, description, payee, reference, fingerprint, status, category_id, duplicate_of_id, created_at, updated_at)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)
+		`, row.ID, batch.ID, userID, row.LineNumber, row.Date, row.Amount, row.Currency, row.Description, row.Payee,
+			row.Reference, row.Fingerprint, row.Status, row.CategoryID, row.DuplicateOfID, now, now); err != nil {
+			log.Printf("[CreateStatementImport] INSERT row error for import=%s: %v", batch.ID, err)
+			return appErrors.DatabaseError
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return appErrors.DatabaseError
+	}
+	return nil
+}
+
+func (r *PostgresRepository) UpdateStatementImport(ctx context.Context, batch *StatementImport) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+	return updateStatementImport(ctx, r.db, userID, batch)
+}
+
+func updateStatementImport(ctx context.Context, execer sqlx.ExtContext, userID string, batch *StatementImport) error {
+	batch.UpdatedAt = utils.NowUTC()
+	result, err := execer.ExecContext(ctx, `
+		UPDATE statement_imports
+		SET status = $1, row_count = $2, pending_count = $3, duplicate_count = $4, approved_count = $5,
+			rejected_count = $6, undone_at = $7, updated_at = $8
+		WHERE id = $9 AND user_id = $10
+	`, batch.Status, batch.RowCount, batch.PendingCount, batch.DuplicateCount, batch.ApprovedCount,
+		batch.RejectedCount, batch.UndoneAt, batch.UpdatedAt, batch.ID, userID)
+	if err != nil {
+		log.Printf("[UpdateStatementImport] UPDATE error for id=%s: %v", batch.ID, err)
+		return appErrors.DatabaseError
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return appErrors.StatementImportNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListStatementImportRows(ctx context.Context, importID string) ([]*StatementImportRow, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM statement_import_rows
+		WHERE import_id = $1 AND user_id = $2
+		ORDER BY line_number ASC
+	`, importRowSelectFields)
+
+	var rows []importRowRow
+	if err := r.db.SelectContext(ctx, &rows, query, importID, userID); err != nil {
+		log.Printf("[ListStatementImportRows] Query error for import=%s: %v", importID, err)
+		return nil, appErrors.DatabaseError
+	}
+	items := make([]*StatementImportRow, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, mapRowToImportRow(row))
+	}
+	return items, nil
+}
+
+func (r *PostgresRepository) UpdateStatementImportRow(ctx context.Context, row *StatementImportRow) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+	return updateStatementImportRow(ctx, r.db, userID, row)
+}
+
+func updateStatementImportRow(ctx context.Context, execer sqlx.ExtContext, userID string, row *StatementImportRow) error {
+	row.UpdatedAt = utils.NowUTC()
+	result, err := execer.ExecContext(ctx, `
+		UPDATE statement_import_rows
+		SET description = $1, payee = $2, status = $3, category_id = $4, transaction_id = $5, error = $6, updated_at = $7
+		WHERE id = $8 AND import_id = $9 AND user_id = $10
+	`, row.Description, row.Payee, row.Status, row.CategoryID, row.TransactionID, row.Error, row.UpdatedAt,
+		row.ID, row.ImportID, userID)
+	if err != nil {
+		log.Printf("[UpdateStatementImportRow] UPDATE error for id=%s: %v", row.ID, err)
+		return appErrors.DatabaseError
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return appErrors.StatementImportRowNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) UndoStatementImport(ctx context.Context, batch *StatementImport, rows []*StatementImportRow, input TransactionReversalInput) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Printf("[UndoStatementImport] Failed to begin transaction: %v", err)
+		return appErrors.DatabaseError
+	}
+	// Lock the import's entries, and the other legs of its transfers, that a
+	// completed reconciliation cleared; any of them fails the whole undo.
+	var locked []string
+	if err := tx.SelectContext(ctx, &locked, `
+		SELECT t.id FROM transactions t
+		WHERE t.user_id = $2 AND t.deleted_at IS NULL AND t.status <> $3
+			AND t.reconciliation_id IS NOT NULL
+			AND (
+				t.id IN (
+					SELECT transaction_id FROM statement_import_rows
+					WHERE import_id = $1 AND user_id = $2 AND status = $4
+				)
+				OR (t.reference_type = 'transfer' AND t.reference_id IN (
+					SELECT o.reference_id FROM transactions o
+					JOIN statement_import_rows ir ON ir.transaction_id = o.id
+					WHERE ir.import_id = $1 AND ir.user_id = $2 AND ir.status = $4
+						AND o.reference_type = 'transfer'
+				))
+			)
+		ORDER BY t.id
+		FOR UPDATE OF t
+	`, batch.ID, userID, TransactionStatusVoided, StatementRowApproved); err != nil {
+		log.Printf("[UndoStatementImport] Locked entries query error for id=%s: %v", batch.ID, err)
+		_ = tx.Rollback()
+		return appErrors.DatabaseError
+	}
+	if len(locked) > 0 {
+		_ = tx.Rollback()
+		return appErrors.WithDetails(appErrors.TransactionLocked, map[string]interface{}{"transactionIds": locked})
+	}
+	for _, row := range rows {
+		if row.Status != StatementRowUndone || row.TransactionID == nil {
+			continue
+		}
+		original, err := fetchTransactionForUpdate(ctx, tx, userID, *row.TransactionID)
+		if err == appErrors.TransactionNotFound {
+			continue
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if original.Status == TransactionStatusVoided {
+			continue
+		}
+		if isAwaitingPost(original) {
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE transactions SET status = $1, updated_at = $2
+				WHERE id = $3 AND user_id = $4
+			`, TransactionStatusVoided, utils.NowUTC(), original.ID, userID); err != nil {
+				log.Printf("[UndoStatementImport] Failed to void scheduled id=%s: %v", original.ID, err)
+				_ = tx.Rollback()
+				return appErrors.DatabaseError
+			}
+			continue
+		}
+		if !isReversibleTransactionType(original.Type) {
+			_ = tx.Rollback()
+			return appErrors.WithDetails(appErrors.TransactionNotReversible, map[string]interface{}{"type": original.Type})
+		}
+		entries := []*Transaction{original}
+		if original.ReferenceType != nil && *original.ReferenceType == "transfer" && original.ReferenceID != nil {
+			entries, err = fetchTransactionsByReferenceForUpdate(ctx, tx, userID, "transfer", *original.ReferenceID)
+			if err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+		}
+		for _, entry := range entries {
+			if entry.Status == TransactionStatusVoided {
+				continue
+			}
+			if _, err := r.reverseEntry(ctx, tx, userID, entry, input); err != nil {
+				log.Printf("[UndoStatementImport] Failed to reverse entry=%s: %v", entry.ID, err)
+				_ = tx.Rollback()
+				return err
+			}
+		}
+	}
+	for _, row := range rows {
+		if err := updateStatementImportRow(ctx, tx, userID, row); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	if err := updateStatementImport(ctx, tx, userID, batch); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return appErrors.DatabaseError
+	}
+	return nil
+}
+
+type importProfileRow struct {
+	ID        string `db:"id"`
+	UserID    string `db:"user_id"`
+	Name      string `db:"name"`
+	Mapping   []byte `db:"mapping"`
+	CreatedAt string `db:"created_at"`
+	UpdatedAt string `db:"updated_at"`
+}
+
+func mapRowToImportProfile(row importProfileRow) *StatementImportProfile {
+	profile := &StatementImportProfile{
+		ID:        row.ID,
+		UserID:    row.UserID,
+		Name:      row.Name,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+	}
+	if len(row.Mapping) > 0 {
+		_ = json.Unmarshal(row.Mapping, &profile.Mapping)
+	}
+	return profile
+}
+
+type importRow struct {
+	ID             string         `db:"id"`
+	UserID         string         `db:"user_id"`
+	AccountID      string         `db:"account_id"`
+	Format         string         `db:"format"`
+	FileName       string         `db:"file_name"`
+	ProfileID      sql.NullString `db:"profile_id"`
+	Status         string         `db:"status"`
+	RowCount       int            `db:"row_count"`
+	PendingCount   int            `db:"pending_count"`
+	DuplicateCount int            `db:"duplicate_count"`
+	ApprovedCount  int            `db:"approved_count"`
+	RejectedCount  int            `db:"rejected_count"`
+	UndoneAt       sql.NullString `db:"undone_at"`
+	CreatedAt      string         `db:"created_at"`
+	UpdatedAt      string         `db:"updated_at"`
+}
+
+func mapRowToImport(row importRow) *StatementImport {
+	batch := &StatementImport{
+		ID:             row.ID,
+		UserID:         row.UserID,
+		AccountID:      row.AccountID,
+		Format:         row.Format,
+		FileName:       row.FileName,
+		Status:         row.Status,
+		RowCount:       row.RowCount,
+		PendingCount:   row.PendingCount,
+		DuplicateCount: row.DuplicateCount,
+		ApprovedCount:  row.ApprovedCount,
+		RejectedCount:  row.RejectedCount,
+		CreatedAt:      row.CreatedAt,
+		UpdatedAt:      row.UpdatedAt,
+	}
+	if row.ProfileID.Valid {
+		batch.ProfileID = &row.ProfileID.String
+	}
+	if row.UndoneAt.Valid {
+		batch.UndoneAt = &row.UndoneAt.String
+	}
+	return batch
+}
+
+type importRowRow struct {
+	ID            string         `db:"id"`
+	ImportID      string         `db:"import_id"`
+	UserID        string         `db:"user_id"`
+	LineNumber    int            `db:"line_number"`
+	Date          sql.NullTime   `db:"date"`
+	Amount        Money          `db:"amount"`
+	Currency      string         `db:"currency"`
+	Description   string         `db:"description"`
+	Payee         string         `db:"payee"`
+	Reference     string         `db:"reference"`
+	Fingerprint   string         `db:"fingerprint"`
+	Status        string         `db:"status"`
+	CategoryID    sql.NullString `db:"category_id"`
+	DuplicateOfID sql.NullString `db:"duplicate_of_id"`
+	TransactionID sql.NullString `db:"transaction_id"`
+	Error         sql.NullString `db:"error"`
+	CreatedAt     string         `db:"created_at"`
+	UpdatedAt     string         `db:"updated_at"`
+}
+
+func mapRowToImportRow(row importRowRow) *StatementImportRow {
+	item := &StatementImportRow{
+		ID:          row.ID,
+		ImportID:    row.ImportID,
+		UserID:      row.UserID,
+		LineNumber:  row.LineNumber,
+		Amount:      row.Amount,
+		Currency:    row.Currency,
+		Description: row.Description,
+		Payee:       row.Payee,
+		Reference:   row.Reference,
+		Fingerprint: row.Fingerprint,
+		Status:      row.Status,
+		CreatedAt:   row.CreatedAt,
+		UpdatedAt:   row.UpdatedAt,
+	}
+	if row.Date.Valid {
+		item.Date = row.Date.Time.Format("2006-01-02")
+	}
+	if row.CategoryID.Valid {
+		item.CategoryID = &row.CategoryID.String
+	}
+	if row.DuplicateOfID.Valid {
+		item.DuplicateOfID = &row.DuplicateOfID.String
+	}
+	if row.TransactionID.Valid {
+		item.TransactionID = &row.TransactionID.String
+	}
+	if row.Error.Valid {
+		item.Error = &row.Error.String
+	}
+	return item
+}
+
+func (r *PostgresRepository) ListSMSCardLinks(ctx context.Context) ([]*SMSCardLink, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM sms_card_links
+		WHERE user_id = $1
+		ORDER BY card_mask ASC
+	`, smsCardLinkSelectFields)
+
+	var rows []smsCardLinkRow
+	if err := r.db.SelectContext(ctx, &rows, query, userID); err != nil {
+		log.Printf("[ListSMSCardLinks] Query error for user=%s: %v", userID, err)
+		return nil, appErrors.DatabaseError
+	}
+	links := make([]*SMSCardLink, 0, len(rows))
+	for _, row := range rows {
+		links = append(links, &SMSCardLink{
+			ID:        row.ID,
+			UserID:    row.UserID,
+			AccountID: row.AccountID,
+			CardMask:  row.CardMask,
+			Bank:      row.Bank,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+		})
+	}
+	return links, nil
+}
+
+func (r *PostgresRepository) CreateSMSCardLink(ctx context.Context, link *SMSCardLink) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	link.ID = uuid.NewString()
+	link.UserID = userID
+	now := utils.NowUTC()
+	link.CreatedAt = now
+	link.UpdatedAt = now
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO sms_card_links (id, user_id, account_id, card_mask, bank, created_at, updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
+	`, link.ID, userID, link.AccountID, link.CardMask, link.Bank, now, now); err != nil {
+		log.Printf("[CreateSMSCardLink] INSERT error for user=%s: %v", userID, err)
+		return appErrors.DatabaseError
+	}
+	return nil
+}
+
+func (r *PostgresRepository) DeleteSMSCardLink(ctx context.Context, id string) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM sms_card_links WHERE id = $1 AND user_id = $2
+	`, id, userID)
+	if err != nil {
+		log.Printf("[DeleteSMSCardLink] DELETE error for id=%s: %v", id, err)
+		return appErrors.DatabaseError
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return appErrors.SMSCardLinkNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListSMSMessages(ctx context.Context, status string) ([]*SMSMessage, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+
+	clauses := []string{"user_id = $1"}
+	args := []interface{}{userID}
+	if status != "" {
+		clauses = append(clauses, "status = $2")
+		args = append(args, status)
+	}
+	query := fmt.Sprintf(`
+		SELECT %s FROM sms_messages
+		WHERE %s
+		ORDER BY received_at DESC
+	`, smsMessageSelectFields, strings.Join(clauses, " AND "))
+
+	var rows []smsMessageRow
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		log.Printf("[ListSMSMessages] Query error for user=%s: %v", userID, err)
+		return nil, appErrors.DatabaseError
+	}
+	messages := make([]*SMSMessage, 0, len(rows))
+	for _, row := range rows {
+		messages = append(messages, mapRowToSMSMessage(row))
+	}
+	return messages, nil
+}
+
+func (r *PostgresRepository) GetSMSMessageByID(ctx context.Context, id string) (*SMSMessage, error) {
+	return r.getSMSMessage(ctx, "id", id)
+}
+
+func (r *PostgresRepository) GetSMSMessageByFingerprint(ctx context.Context, fingerprint string) (*SMSMessage, error) {
+	return r.getSMSMessage(ctx, "fingerprint", fingerprint)
+}
+
+func (r *PostgresRepository) getSMSMessage(ctx context.Context, column, value string) (*SMSMessage, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM sms_messages
+		WHERE %s = $1 AND user_id = $2
+	`, smsMessageSelectFields, column)
+
+	var row smsMessageRow
+	if err := r.db.GetContext(ctx, &row, query, value, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, appErrors.SMSMessageNotFound
+		}
+		log.Printf("[getSMSMessage] Query error for %s=%s: %v", column, value, err)
+		return nil, appErrors.DatabaseError
+	}
+	return mapRowToSMSMessage(row), nil
+}
+
+func (r *PostgresRepository) CreateSMSMessage(ctx context.Context, message *SMSMessage) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	message.ID = uuid.NewString()
+	message.UserID = userID
+	now := utils.NowUTC()
+	message.CreatedAt = now
+	message.UpdatedAt = now
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO sms_messages (id, user_id, sender, body, received_at, fingerprint, status, created_at, updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+	`, message.ID, userID, message.Sender, message.Body, message.ReceivedAt, message.Fingerprint, message.Status, now, now); err != nil {
+		log.Printf("[CreateSMSMessage] INSERT error for user=%s: %v", userID, err)
+		return appErrors.DatabaseError
+	}
+	return nil
+}
+
+func (r *PostgresRepository) UpdateSMSMessage(ctx context.Context, message *SMSMessage) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	var occurredAt interface{}
+	if message.OccurredAt != "" {
+		occurredAt = message.OccurredAt
+	}
+	message.UpdatedAt = utils.NowUTC()
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE sms_messages
+		SET status = $1, bank = $2, direction = $3, amount = $4, currency = $5, merchant = $6, card_mask = $7,
+			balance = $8, occurred_at = $9, account_id = $10, transaction_id = $11, error = $12, updated_at = $13
+		WHERE id = $14 AND user_id = $15
+	`, message.Status, message.Bank, message.Direction, message.Amount, message.Currency, message.Merchant, message.CardMask,
+		message.Balance, occurredAt, message.AccountID, message.TransactionID, message.Error, message.UpdatedAt,
+		message.ID, userID)
+	if err != nil {
+		log.Printf("[UpdateSMSMessage] UPDATE error for id=%s: %v", message.ID, err)
+		return appErrors.DatabaseError
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return appErrors.SMSMessageNotFound
+	}
+	return nil
+}
+
+type smsCardLinkRow struct {
+	ID        string `db:"id"`
+	UserID    string `db:"user_id"`
+	AccountID string `db:"account_id"`
+	CardMask  string `db:"card_mask"`
+	Bank      string `db:"bank"`
+	CreatedAt string `db:"created_at"`
+	UpdatedAt string `db:"updated_at"`
+}
+
+type smsMessageRow struct {
+	ID            string         `db:"id"`
+	UserID        string         `db:"user_id"`
+	Sender        string         `db:"sender"`
+	Body          string         `db:"body"`
+	ReceivedAt    string         `db:"received_at"`
+	Fingerprint   string         `db:"fingerprint"`
+	Status        string         `db:"status"`
+	Bank          string         `db:"bank"`
+	Direction     string         `db:"direction"`
+	Amount        Money          `db:"amount"`
+	Currency      string         `db:"currency"`
+	Merchant      string         `db:"merchant"`
+	CardMask      string         `db:"card_mask"`
+	Balance       sql.NullString `db:"balance"`
+	OccurredAt    sql.NullString `db:"occurred_at"`
+	AccountID     sql.NullString `db:"account_id"`
+	TransactionID sql.NullString `db:"transaction_id"`
+	Error         sql.NullString `db:"error"`
+	CreatedAt     string         `db:"created_at"`
+	UpdatedAt     string         `db:"updated_at"`
+}
+
+func mapRowToSMSMessage(row smsMessageRow) *SMSMessage {
+	message := &SMSMessage{
+		ID:          row.ID,
+		UserID:      row.UserID,
+		Sender:      row.Sender,
+		Body:        row.Body,
+		ReceivedAt:  row.ReceivedAt,
+		Fingerprint: row.Fingerprint,
+		Status:      row.Status,
+		Bank:        row.Bank,
+		Direction:   row.Direction,
+		Amount:      row.Amount,
+		Currency:    row.Currency,
+		Merchant:    row.Merchant,
+		CardMask:    row.CardMask,
+		CreatedAt:   row.CreatedAt,
+		UpdatedAt:   row.UpdatedAt,
+	}
+	if row.Balance.Valid {
+		if balance, err := ParseMoney(row.Balance.String); err == nil {
+			message.Balance = &balance
+		}
+	}
+	if row.OccurredAt.Valid {
+		message.OccurredAt = row.OccurredAt.String
+	}
+	if row.AccountID.Valid {
+		message.AccountID = &row.AccountID.String
+	}
+	if row.TransactionID.Valid {
+		message.TransactionID = &row.TransactionID.String
+	}
+	if row.Error.Valid {
+		message.Error = &row.Error.String
+	}
+	return message
+}
+
+func (r *PostgresRepository) ListCategorizationRules(ctx context.Context) ([]*CategorizationRule, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM categorization_rules
+		WHERE user_id = $1
+		ORDER BY priority ASC, created_at ASC
+	`, ruleSelectFields)
+
+	var rows []categorizationRuleRow
+	if err := r.db.SelectContext(ctx, &rows, query, userID); err != nil {
+		log.Printf("[ListCategorizationRules] Query error for user=%s: %v", userID, err)
+		return nil, appErrors.DatabaseError
+	}
+	rules := make([]*CategorizationRule, 0, len(rows))
+	for _, row := range rows {
+		rules = append(rules, mapRowToCategorizationRule(row))
+	}
+	return rules, nil
+}
+
+func (r *PostgresRepository) GetCategorizationRuleByID(ctx context.Context, id string) (*CategorizationRule, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM categorization_rules
+		WHERE id = $1 AND user_id = $2
+	`, ruleSelectFields)
+
+	var row categorizationRuleRow
+	if err := r.db.GetContext(ctx, &row, query, id, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, appErrors.CategorizationRuleNotFound
+		}
+		log.Printf("[GetCategorizationRuleByID] Query error for id=%s: %v", id, err)
+		return nil, appErrors.DatabaseError
+	}
+	return mapRowToCategorizationRule(row), nil
+}
+
+func (r *PostgresRepository) CreateCategorizationRule(ctx context.Context, rule *CategorizationRule) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	conditions, err := json.Marshal(rule.Conditions)
+	if err != nil {
+		return appErrors.InvalidFinanceData
+	}
+	actions, err := json.Marshal(rule.Actions)
+	if err != nil {
+		return appErrors.InvalidFinanceData
+	}
+	rule.ID = uuid.NewString()
+	rule.UserID = userID
+	now := utils.NowUTC()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO categorization_rules (id, user_id, name, priority, status, stop_on_match, conditions, actions, created_at, updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+	`, rule.ID, userID, rule.Name, rule.Priority, rule.Status, rule.StopOnMatch, conditions, actions, now, now); err != nil {
+		log.Printf("[CreateCategorizationRule] INSERT error for user=%s: %v", userID, err)
+		return appErrors.DatabaseError
+	}
+	return nil
+}
+
+func (r *PostgresRepository) UpdateCategorizationRule(ctx context.Context, rule *CategorizationRule) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	conditions, err := json.Marshal(rule.Conditions)
+	if err != nil {
+		return appErrors.InvalidFinanceData
+	}
+	actions, err := json.Marshal(rule.Actions)
+	if err != nil {
+		return appErrors.InvalidFinanceData
+	}
+	rule.UpdatedAt = utils.NowUTC()
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE categorization_rules
+		SET name = $1, priority = $2, status = $3, stop_on_match = $4, conditions = $5, actions = $6, updated_at = $7
+		WHERE id = $8 AND user_id = $9
+	`, rule.Name, rule.Priority, rule.Status, rule.StopOnMatch, conditions, actions, rule.UpdatedAt, rule.ID, userID)
+	if err != nil {
+		log.Printf("[UpdateCategorizationRule] UPDATE error for id=%s: %v", rule.ID, err)
+		return appErrors.DatabaseError
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return appErrors.CategorizationRuleNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) DeleteCategorizationRule(ctx context.Context, id string) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM categorization_rules WHERE id = $1 AND user_id = $2
+	`, id, userID)
+	if err != nil {
+		log.Printf("[DeleteCategorizationRule] DELETE error for id=%s: %v", id, err)
+		return appErrors.DatabaseError
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return appErrors.CategorizationRuleNotFound
+	}
+	return nil
+}
+
+type categorizationRuleRow struct {
+	ID          string `db:"id"`
+	UserID      string `db:"user_id"`
+	Name        string `db:"name"`
+	Priority    int    `db:"priority"`
+	Status      string `db:"status"`
+	StopOnMatch bool   `db:"stop_on_match"`
+	Conditions  []byte `db:"conditions"`
+	Actions     []byte `db:"actions"`
+	CreatedAt   string `db:"created_at"`
+	UpdatedAt   string `db:"updated_at"`
+}
+
+func mapRowToCategorizationRule(row categorizationRuleRow) *CategorizationRule {
+	rule := &CategorizationRule{
+		ID:          row.ID,
+		UserID:      row.UserID,
+		Name:        row.Name,
+		Priority:    row.Priority,
+		Status:      row.Status,
+		StopOnMatch: row.StopOnMatch,
+		CreatedAt:   row.CreatedAt,
+		UpdatedAt:   row.UpdatedAt,
+	}
+	if len(row.Conditions) > 0 {
+		_ = json.Unmarshal(row.Conditions, &rule.Conditions)
+	}
+	if len(row.Actions) > 0 {
+		_ = json.Unmarshal(row.Actions, &rule.Actions)
+	}
+	return rule
+}
+
+func (r *PostgresRepository) ListAttachments(ctx context.Context) ([]*Attachment, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM attachments
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, attachmentSelectFields)
+
+	var rows []attachmentRow
+	if err := r.db.SelectContext(ctx, &rows, query, userID); err != nil {
+		log.Printf("[ListAttachments] Query error for user=%s: %v", userID, err)
+		return nil, appErrors.DatabaseError
+	}
+	attachments := make([]*Attachment, 0, len(rows))
+	for _, row := range rows {
+		attachments = append(attachments, mapRowToAttachment(row))
+	}
+	return attachments, nil
+}
+
+func (r *PostgresRepository) GetAttachmentByID(ctx context.Context, id string) (*Attachment, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM attachments
+		WHERE id = $1 AND user_id = $2
+	`, attachmentSelectFields)
+
+	var row attachmentRow
+	if err := r.db.GetContext(ctx, &row, query, id, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, appErrors.AttachmentNotFound
+		}
+		log.Printf("[GetAttachmentByID] Query error for id=%s: %v", id, err)
+		return nil, appErrors.DatabaseError
+	}
+	return mapRowToAttachment(row), nil
+}
+
+func (r *PostgresRepository) CreateAttachment(ctx context.Context, attachment *Attachment) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	if attachment.ID == "" {
+		attachment.ID = uuid.NewString()
+	}
+	attachment.UserID = userID
+	attachment.CreatedAt = utils.NowUTC()
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO attachments (id, user_id, file_name, content_type, size_bytes, checksum, storage_key, thumbnail_key, thumbnail_size_bytes, width, height, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+	`, attachment.ID, userID, attachment.FileName, attachment.ContentType, attachment.Size, attachment.Checksum,
+		attachment.StorageKey, attachment.ThumbnailKey, attachment.ThumbnailSize, attachment.Width, attachment.Height, attachment.CreatedAt); err != nil {
+		log.Printf("[CreateAttachment] INSERT error for user=%s: %v", userID, err)
+		return appErrors.DatabaseError
+	}
+	return nil
+}
+
+func (r *PostgresRepository) DeleteAttachment(ctx context.Context, id string) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM attachments WHERE id = $1 AND user_id = $2
+	`, id, userID)
+	if err != nil {
+		log.Printf("[DeleteAttachment] DELETE error for id=%s: %v", id, err)
+		return appErrors.DatabaseError
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return appErrors.AttachmentNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) AttachmentUsage(ctx context.Context) (int, int64, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return 0, 0, appErrors.InvalidToken
+	}
+
+	var usage struct {
+		Count int   `db:"count"`
+		Bytes int64 `db:"bytes"`
+	}
+	if err := r.db.GetContext(ctx, &usage, `
+		SELECT COUNT(*) AS count, COALESCE(SUM(size_bytes + thumbnail_size_bytes), 0) AS bytes
+		FROM attachments
+		WHERE user_id = $1
+	`, userID); err != nil {
+		log.Printf("[AttachmentUsage] Query error for user=%s: %v", userID, err)
+		return 0, 0, appErrors.DatabaseError
+	}
+	return usage.Count, usage.Bytes, nil
+}
+
+func (r *PostgresRepository) AttachmentReferenced(ctx context.Context, id string) (bool, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return false, appErrors.InvalidToken
+	}
+
+	var referenced bool
+	if err := r.db.GetContext(ctx, &referenced, `
+		SELECT EXISTS (
+			SELECT 1 FROM transactions WHERE user_id = $1 AND attachments @> jsonb_build_array($2::text)
+		) OR EXISTS (
+			SELECT 1 FROM debts WHERE user_id = $1 AND attachments @> jsonb_build_array($2::text)
+		) OR EXISTS (
+			SELECT 1 FROM debt_payments dp
+			JOIN debts d ON d.id = dp.debt_id
+			WHERE d.user_id = $1 AND dp.attachments @> jsonb_build_array($2::text)
+		)
+	`, userID, id); err != nil {
+		log.Printf("[AttachmentReferenced] Query error for id=%s: %v", id, err)
+		return false, appErrors.DatabaseError
+	}
+	return referenced, nil
+}
+
+type attachmentRow struct {
+	ID                 string         `db:"id"`
+	UserID             string         `db:"user_id"`
+	FileName           string         `db:"file_name"`
+	ContentType        string         `db:"content_type"`
+	SizeBytes          int64          `db:"size_bytes"`
+	Checksum           string         `db:"checksum"`
+	StorageKey         string         `db:"storage_key"`
+	ThumbnailKey       sql.NullString `db:"thumbnail_key"`
+	ThumbnailSizeBytes int64          `db:"thumbnail_size_bytes"`
+	Width              sql.NullInt64  `db:"width"`
+	Height             sql.NullInt64  `db:"height"`
+	CreatedAt          string         `db:"created_at"`
+}
+
+func mapRowToAttachment(row attachmentRow) *Attachment {
+	attachment := &Attachment{
+		ID:            row.ID,
+		UserID:        row.UserID,
+		FileName:      row.FileName,
+		ContentType:   row.ContentType,
+		Size:          row.SizeBytes,
+		Checksum:      row.Checksum,
+		StorageKey:    row.StorageKey,
+		ThumbnailSize: row.ThumbnailSizeBytes,
+		CreatedAt:     row.CreatedAt,
+	}
+	if row.ThumbnailKey.Valid {
+		attachment.ThumbnailKey = &row.ThumbnailKey.String
+	}
+	if row.Width.Valid && row.Height.Valid {
+		width, height := int(row.Width.Int64), int(row.Height.Int64)
+		attachment.Width, attachment.Height = &width, &height
+	}
+	return attachment
+}
+
+func (r *PostgresRepository) ListReconciliations(ctx context.Context, accountID string) ([]*Reconciliation, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM account_reconciliations
+		WHERE user_id = $1 AND account_id = $2
+		ORDER BY statement_date DESC, created_at DESC
+	`, reconciliationSelectFields)
+
+	var rows []reconciliationRow
+	if err := r.db.SelectContext(ctx, &rows, query, userID, accountID); err != nil {
+		log.Printf("[ListReconciliations] Query error for account=%s: %v", accountID, err)
+		return nil, appErrors.DatabaseError
+	}
+	reconciliations := make([]*Reconciliation, 0, len(rows))
+	for _, row := range rows {
+		reconciliations = append(reconciliations, mapRowToReconciliation(row))
+	}
+	return reconciliations, nil
+}
+
+func (r *PostgresRepository) GetReconciliationByID(ctx context.Context, id string) (*Reconciliation, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM account_reconciliations
+		WHERE id = $1 AND user_id = $2
+	`, reconciliationSelectFields)
+
+	var row reconciliationRow
+	if err := r.db.GetContext(ctx, &row, query, id, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, appErrors.ReconciliationNotFound
+		}
+		log.Printf("[GetReconciliationByID] Query error for id=%s: %v", id, err)
+		return nil, appErrors.DatabaseError
+	}
+	return mapRowToReconciliation(row), nil
+}
+
+func (r *PostgresRepository) CreateReconciliation(ctx context.Context, reconciliation *Reconciliation) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	cleared, err := marshalIDList(reconciliation.ClearedTransactionIDs)
+	if err != nil {
+		return appErrors.InvalidFinanceData
+	}
+	if reconciliation.ID == "" {
+		reconciliation.ID = uuid.NewString()
+	}
+	reconciliation.UserID = userID
+	now := utils.NowUTC()
+	reconciliation.CreatedAt = now
+	reconciliation.UpdatedAt = now
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO account_reconciliations (
+			id, user_id, account_id, currency, statement_date, statement_balance, status,
+			opening_balance, cleared_balance, difference, cleared_transaction_ids, created_at, updated_at
+		)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+	`, reconciliation.ID, userID, reconciliation.AccountID, reconciliation.Currency, reconciliation.StatementDate,
+		reconciliation.StatementBalance, reconciliation.Status, reconciliation.OpeningBalance, reconciliation.ClearedBalance,
+		reconciliation.Difference, cleared, now, now); err != nil {
+		log.Printf("[CreateReconciliation] INSERT error for account=%s: %v", reconciliation.AccountID, err)
+		return appErrors.DatabaseError
+	}
+	return nil
+}
+
+func (r *PostgresRepository) UpdateReconciliation(ctx context.Context, reconciliation *Reconciliation) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	return updateReconciliation(ctx, r.db, userID, reconciliation)
 }
 
-func mapRowToTransaction(row transactionRow) *Transaction {
-	var accountID *string
-	if row.AccountID.Valid {
-		accountID = &row.AccountID.String
+func (r *PostgresRepository) CompleteReconciliation(ctx context.Context, reconciliation *Reconciliation, adjustment *Transaction) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Printf("[CompleteReconciliation] Failed to begin transaction: %v", err)
+		return appErrors.DatabaseError
+	}
+
+	var status string
+	if err := tx.GetContext(ctx, &status, `
+		SELECT status FROM account_reconciliations
+		WHERE id = $1 AND user_id = $2
+		FOR UPDATE
+	`, reconciliation.ID, userID); err != nil {
+		_ = tx.Rollback()
+		if err == sql.ErrNoRows {
+			return appErrors.ReconciliationNotFound
+		}
+		return appErrors.DatabaseError
+	}
+	if status != ReconciliationStatusInProgress {
+		_ = tx.Rollback()
+		return appErrors.WithDetails(appErrors.InvalidReconciliation, map[string]interface{}{"field": "status", "status": status})
+	}
+
+	now := utils.NowUTC()
+	if len(reconciliation.ClearedTransactionIDs) > 0 {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE transactions
+			SET reconciliation_id = $1, cleared_at = $2, updated_at = $2
+			WHERE user_id = $3 AND id = ANY($4) AND reconciliation_id IS NULL
+				AND status <> $5 AND deleted_at IS NULL
+		`, reconciliation.ID, now, userID, pq.Array(reconciliation.ClearedTransactionIDs), TransactionStatusVoided)
+		if err != nil {
+			log.Printf("[CompleteReconciliation] UPDATE transactions error for id=%s: %v", reconciliation.ID, err)
+			_ = tx.Rollback()
+			return appErrors.DatabaseError
+		}
+		// An entry voided or cleared elsewhere since it was ticked.
+		if affected, _ := result.RowsAffected(); affected != int64(len(reconciliation.ClearedTransactionIDs)) {
+			_ = tx.Rollback()
+			return appErrors.WithDetails(appErrors.InvalidReconciliation, map[string]interface{}{"field": "cleared"})
+		}
 	}
-	var fromAccountID *string
-	if row.FromAccountID.Valid {
-		fromAccountID = &row.FromAccountID.String
+
+	if adjustment != nil {
+		account, err := fetchAccountForUpdate(ctx, tx, userID, reconciliation.AccountID)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		adjustment.ReconciliationID = &reconciliation.ID
+		adjustment.ClearedAt = &now
+		if err := r.insertTransaction(ctx, tx, userID, adjustment); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if err := updateAccountBalance(ctx, tx, userID, account.ID, account.CurrentBalance+adjustment.Amount); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		reconciliation.AdjustmentTransactionID = &adjustment.ID
 	}
-	var toAccountID *string
-	if row.ToAccountID.Valid {
-		toAccountID = &row.ToAccountID.String
+
+	if err := updateReconciliation(ctx, tx, userID, reconciliation); err != nil {
+		_ = tx.Rollback()
+		return err
 	}
-	var referenceType *string
-	if row.ReferenceType.Valid {
-		referenceType = &row.ReferenceType.String
+	if err := tx.Commit(); err != nil {
+		return appErrors.DatabaseError
 	}
-	var referenceID *string
-	if row.ReferenceID.Valid {
-		referenceID = &row.ReferenceID.String
+	return nil
+}
+
+func updateReconciliation(ctx context.Context, execer sqlx.ExtContext, userID string, reconciliation *Reconciliation) error {
+	cleared, err := marshalIDList(reconciliation.ClearedTransactionIDs)
+	if err != nil {
+		return appErrors.InvalidFinanceData
 	}
-	baseCurrency := row.Currency
-	if row.BaseCurrency.Valid && row.BaseCurrency.String != "" {
-		baseCurrency = row.BaseCurrency.String
+	reconciliation.UpdatedAt = utils.NowUTC()
+	result, err := execer.ExecContext(ctx, `
+		UPDATE account_reconciliations
+		SET status = $1,
+			opening_balance = $2,
+			cleared_balance = $3,
+			difference = $4,
+			cleared_transaction_ids = $5,
+			adjustment_transaction_id = $6,
+			completed_at = $7,
+			updated_at = $8
+		WHERE id = $9 AND user_id = $10
+	`, reconciliation.Status, reconciliation.OpeningBalance, reconciliation.ClearedBalance, reconciliation.Difference,
+		cleared, reconciliation.AdjustmentTransactionID, reconciliation.CompletedAt, reconciliation.UpdatedAt,
+		reconciliation.ID, userID)
+	if err != nil {
+		log.Printf("[UpdateReconciliation] UPDATE error for id=%s: %v", reconciliation.ID, err)
+		return appErrors.DatabaseError
 	}
-	var toCurrency *string
-	if row.ToCurrency.Valid {
-		toCurrency = &row.ToCurrency.String
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return appErrors.ReconciliationNotFound
 	}
-	var feeCategoryID *string
-	if row.FeeCategoryID.Valid {
-		feeCategoryID = &row.FeeCategoryID.String
+	return nil
+}
+
+type reconciliationRow struct {
+	ID                      string         `db:"id"`
+	UserID                  string         `db:"user_id"`
+	AccountID               string         `db:"account_id"`
+	Currency                string         `db:"currency"`
+	StatementDate           sql.NullTime   `db:"statement_date"`
+	StatementBalance        Money          `db:"statement_balance"`
+	Status                  string         `db:"status"`
+	OpeningBalance          Money          `db:"opening_balance"`
+	ClearedBalance          Money          `db:"cleared_balance"`
+	Difference              Money          `db:"difference"`
+	ClearedTransactionIDs   []byte         `db:"cleared_transaction_ids"`
+	AdjustmentTransactionID sql.NullString `db:"adjustment_transaction_id"`
+	CompletedAt             sql.NullString `db:"completed_at"`
+	CreatedAt               string         `db:"created_at"`
+	UpdatedAt               string         `db:"updated_at"`
+}
+
+func mapRowToReconciliation(row reconciliationRow) *Reconciliation {
+	reconciliation := &Reconciliation{
+		ID:                    row.ID,
+		UserID:                row.UserID,
+		AccountID:             row.AccountID,
+		Currency:              row.Currency,
+		StatementBalance:      row.StatementBalance,
+		Status:                row.Status,
+		OpeningBalance:        row.OpeningBalance,
+		ClearedBalance:        row.ClearedBalance,
+		Difference:            row.Difference,
+		ClearedTransactionIDs: decodeIDList(row.ClearedTransactionIDs),
+		CreatedAt:             row.CreatedAt,
+		UpdatedAt:             row.UpdatedAt,
 	}
-	var categoryID *string
-	if row.CategoryID.Valid {
-		categoryID = &row.CategoryID.String
-	} else if row.Category.Valid {
-		categoryID = &row.Category.String
+	if row.StatementDate.Valid {
+		reconciliation.StatementDate = row.StatementDate.Time.Format("2006-01-02")
 	}
-	var subcategoryID *string
-	if row.SubcategoryID.Valid {
-		subcategoryID = &row.SubcategoryID.String
+	if row.AdjustmentTransactionID.Valid {
+		reconciliation.AdjustmentTransactionID = &row.AdjustmentTransactionID.String
 	}
-	var name *string
-	if row.Name.Valid {
-		name = &row.Name.String
+	if row.CompletedAt.Valid {
+		reconciliation.CompletedAt = &row.CompletedAt.String
 	}
-	var description *string
-	if row.Description.Valid {
-		description = &row.Description.String
+	return reconciliation
+}
+
+func (r *PostgresRepository) ListLedgerUserIDs(ctx context.Context) ([]string, error) {
+	var userIDs []string
+	if err := r.db.SelectContext(ctx, &userIDs, `
+		SELECT user_id FROM accounts WHERE deleted_at IS NULL
+		UNION
+		SELECT user_id FROM budgets WHERE deleted_at IS NULL
+		UNION
+		SELECT user_id FROM debts WHERE deleted_at IS NULL
+		ORDER BY 1
+	`); err != nil {
+		log.Printf("[ListLedgerUserIDs] Query error: %v", err)
+		return nil, appErrors.DatabaseError
 	}
-	date := ""
-	if row.Date.Valid {
-		date = row.Date.Time.Format("2006-01-02")
+	return userIDs, nil
+}
+
+func (r *PostgresRepository) RecordLedgerRepair(ctx context.Context, repair *LedgerRepair, adjustment *Transaction) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
 	}
-	var timeValue *string
-	if row.Time.Valid {
-		timeValue = &row.Time.String
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Printf("[RecordLedgerRepair] Failed to begin transaction: %v", err)
+		return appErrors.DatabaseError
 	}
-	var goalID *string
-	if row.GoalID.Valid {
-		goalID = &row.GoalID.String
+
+	if adjustment != nil {
+		// The balance lock keeps a concurrent write from slipping in between
+		// the check and the repair.
+		account, err := fetchAccountForUpdate(ctx, tx, userID, repair.EntityID)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if account.CurrentBalance != repair.StoredAmount {
+			_ = tx.Rollback()
+			return appErrors.LedgerDriftChanged
+		}
+		if err := r.insertTransaction(ctx, tx, userID, adjustment); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		repair.AdjustmentTransactionID = &adjustment.ID
 	}
-	var budgetID *string
-	if row.BudgetID.Valid {
-		budgetID = &row.BudgetID.String
+
+	if repair.ID == "" {
+		repair.ID = uuid.NewString()
 	}
-	var debtID *string
-	if row.DebtID.Valid {
-		debtID = &row.DebtID.String
+	repair.UserID = userID
+	repair.CreatedAt = utils.NowUTC()
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO ledger_repairs (
+			id, user_id, entity_type, entity_id, field, currency, stored_amount,
+			expected_amount, adjustment_transaction_id, repaired_by, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, repair.ID, userID, repair.EntityType, repair.EntityID, repair.Field, repair.Currency, repair.StoredAmount,
+		repair.ExpectedAmount, repair.AdjustmentTransactionID, repair.RepairedBy, repair.CreatedAt); err != nil {
+		log.Printf("[RecordLedgerRepair] INSERT error for %s %s: %v", repair.EntityType, repair.EntityID, err)
+		_ = tx.Rollback()
+		return appErrors.DatabaseError
 	}
-	var habitID *string
-	if row.HabitID.Valid {
-		habitID = &row.HabitID.String
+	if err := tx.Commit(); err != nil {
+		return appErrors.DatabaseError
 	}
-	var counterpartyID *string
-	if row.CounterpartyID.Valid {
-		counterpartyID = &row.CounterpartyID.String
+	return nil
+}
+
+func (r *PostgresRepository) ListScheduledTransactions(ctx context.Context) ([]*Transaction, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
 	}
-	var recurringID *string
-	if row.RecurringID.Valid {
-		recurringID = &row.RecurringID.String
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM transactions
+		WHERE user_id = $1 AND is_scheduled AND status IN ($2, $3) AND deleted_at IS NULL
+		ORDER BY date ASC, created_at ASC
+	`, transactionSelectFields)
+
+	var rows []transactionRow
+	if err := r.db.SelectContext(ctx, &rows, query, userID, TransactionStatusPending, TransactionStatusFailed); err != nil {
+		log.Printf("[ListScheduledTransactions] Query error: %v", err)
+		return nil, appErrors.DatabaseError
 	}
-	attachments := []string{}
-	if len(row.Attachments) > 0 {
-		_ = json.Unmarshal(row.Attachments, &attachments)
+	items := make([]*Transaction, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, mapRowToTransaction(row))
 	}
-	tags := []string{}
-	if len(row.Tags) > 0 {
-		_ = json.Unmarshal(row.Tags, &tags)
+	return items, nil
+}
+
+func (r *PostgresRepository) ListDueScheduledTransactions(ctx context.Context, asOf string) ([]*Transaction, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM transactions
+		WHERE is_scheduled AND status = $1 AND date <= $2 AND type <> $3 AND deleted_at IS NULL
+			AND reference_type IS DISTINCT FROM $4
+		ORDER BY date ASC, created_at ASC
+	`, transactionSelectFields)
+
+	var rows []transactionRow
+	if err := r.db.SelectContext(ctx, &rows, query, TransactionStatusPending, asOf, TransactionTypeTransferIn, smsReferenceType); err != nil {
+		log.Printf("[ListDueScheduledTransactions] Query error: %v", err)
+		return nil, appErrors.DatabaseError
 	}
-	var relatedBudgetID *string
-	if row.RelatedBudgetID.Valid {
-		relatedBudgetID = &row.RelatedBudgetID.String
+	items := make([]*Transaction, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, mapRowToTransaction(row))
 	}
-	var relatedDebtID *string
-	if row.RelatedDebtID.Valid {
-		relatedDebtID = &row.RelatedDebtID.String
+	return items, nil
+}
+
+func (r *PostgresRepository) PostScheduledTransaction(ctx context.Context, id, date string) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
 	}
-	var originalCurrency *string
-	if row.OriginalCurrency.Valid {
-		originalCurrency = &row.OriginalCurrency.String
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Printf("[PostScheduledTransaction] Failed to begin transaction: %v", err)
+		return appErrors.DatabaseError
 	}
-	var occurredAt string
-	if row.OccurredAt.Valid {
-		occurredAt = row.OccurredAt.Time.UTC().Format(time.RFC3339)
+	legs, err := fetchScheduledLegsForUpdate(ctx, tx, userID, id)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
 	}
-	metadata := map[string]interface{}{}
-	if len(row.Metadata) > 0 {
-		_ = json.Unmarshal(row.Metadata, &metadata)
+
+	deltas := make(map[string]Money)
+	legIDs := make([]string, 0, len(legs))
+	for _, leg := range legs {
+		legIDs = append(legIDs, leg.ID)
+		if leg.AccountID != nil && *leg.AccountID != "" {
+			deltas[*leg.AccountID] += ledgerDeltaForAccount(*leg.AccountID, leg)
+		}
 	}
-	return &Transaction{
-		ID:                    row.ID,
-		UserID:                row.UserID,
-		Type:                  row.Type,
-		Status:                row.Status,
-		AccountID:             accountID,
-		FromAccountID:         fromAccountID,
-		ToAccountID:           toAccountID,
-		ReferenceType:         referenceType,
-		ReferenceID:           referenceID,
-		Amount:                row.Amount,
-		Currency:              row.Currency,
-		BaseCurrency:          baseCurrency,
-		RateUsedToBase:        row.RateUsedToBase,
-		ConvertedAmountToBase: row.ConvertedAmountToBase,
-		ToAmount:              row.ToAmount,
-		ToCurrency:            toCurrency,
-		EffectiveRateFromTo:   row.EffectiveRateFromTo,
-		FeeAmount:             row.FeeAmount,
-		FeeCategoryID:         feeCategoryID,
-		CategoryID:            categoryID,
-		SubcategoryID:         subcategoryID,
-		Name:                  name,
-		Description:           description,
-		Date:                  date,
-		Time:                  timeValue,
-		GoalID:                goalID,
-		BudgetID:              budgetID,
-		DebtID:                debtID,
-		HabitID:               habitID,
-		CounterpartyID:        counterpartyID,
-		RecurringID:           recurringID,
-		Attachments:           attachments,
-		Tags:                  tags,
-		IsBalanceAdjustment:   row.IsBalanceAdjustment,
-		SkipBudgetMatching:    row.SkipBudgetMatching,
-		ShowStatus:            row.ShowStatus,
-		RelatedBudgetID:       relatedBudgetID,
-		RelatedDebtID:         relatedDebtID,
-		PlannedAmount:         row.PlannedAmount,
-		PaidAmount:            row.PaidAmount,
-		OriginalCurrency:      originalCurrency,
-		OriginalAmount:        row.OriginalAmount,
-		ConversionRate:        row.ConversionRate,
-		OccurredAt:            occurredAt,
-		Metadata:              metadata,
-		CreatedAt:             row.CreatedAt,
-		UpdatedAt:             row.UpdatedAt,
+	// Lock in a fixed order so two transfers between the same accounts
+	// cannot deadlock.
+	accountIDs := make([]string, 0, len(deltas))
+	for accountID := range deltas {
+		accountIDs = append(accountIDs, accountID)
+	}
+	sort.Strings(accountIDs)
+	for _, accountID := range accountIDs {
+		account, err := fetchAccountForUpdate(ctx, tx, userID, accountID)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		delta := deltas[accountID]
+		if delta < 0 && account.available() < -delta {
+			_ = tx.Rollback()
+			return appErrors.InsufficientFunds
+		}
+		if err := updateAccountBalance(ctx, tx, userID, accountID, account.CurrentBalance+delta); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE transactions
+		SET status = $1, is_scheduled = false, date = $2, updated_at = $3
+		WHERE user_id = $4 AND id = ANY($5)
+	`, TransactionStatusCompleted, date, utils.NowUTC(), userID, pq.Array(legIDs)); err != nil {
+		log.Printf("[PostScheduledTransaction] UPDATE error for id=%s: %v", id, err)
+		_ = tx.Rollback()
+		return appErrors.DatabaseError
+	}
+	if err := tx.Commit(); err != nil {
+		return appErrors.DatabaseError
 	}
+	return nil
 }
 
-type budgetRow struct {
-	ID                string         `db:"id"`
-	UserID            string         `db:"user_id"`
-	Name              string         `db:"name"`
-	BudgetType        string         `db:"budget_type"`
-	CategoryIDs       []byte         `db:"category_ids"`
-	LinkedGoalID      sql.NullString `db:"linked_goal_id"`
-	AccountID         sql.NullString `db:"account_id"`
-	TransactionType   sql.NullString `db:"transaction_type"`
-	Currency          string         `db:"currency"`
-	LimitAmount       float64        `db:"limit_amount"`
-	PeriodType        string         `db:"period_type"`
-	StartDate         sql.NullTime   `db:"start_date"`
-	EndDate           sql.NullTime   `db:"end_date"`
-	SpentAmount       float64        `db:"spent_amount"`
-	RemainingAmount   float64        `db:"remaining_amount"`
-	PercentUsed       float64        `db:"percent_used"`
-	IsOverspent       bool           `db:"is_overspent"`
-	RolloverMode      string         `db:"rollover_mode"`
-	NotifyOnExceed    bool           `db:"notify_on_exceed"`
-	ContributionTotal float64        `db:"contribution_total"`
-	CurrentBalance    float64        `db:"current_balance"`
-	IsArchived        bool           `db:"is_archived"`
-	ShowStatus        string         `db:"show_status"`
-	CreatedAt         string         `db:"created_at"`
-	UpdatedAt         string         `db:"updated_at"`
-}
+func (r *PostgresRepository) UpdateScheduledTransaction(ctx context.Context, id, status, date string) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
 
-func mapRowToBudget(row budgetRow) *Budget {
-	categoryIDs := []string{}
-	if len(row.CategoryIDs) > 0 {
-		_ = json.Unmarshal(row.CategoryIDs, &categoryIDs)
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Printf("[UpdateScheduledTransaction] Failed to begin transaction: %v", err)
+		return appErrors.DatabaseError
 	}
-	var linkedGoalID *string
-	if row.LinkedGoalID.Valid {
-		linkedGoalID = &row.LinkedGoalID.String
+	legs, err := fetchScheduledLegsForUpdate(ctx, tx, userID, id)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
 	}
-	var accountID *string
-	if row.AccountID.Valid {
-		accountID = &row.AccountID.String
+	legIDs := make([]string, 0, len(legs))
+	for _, leg := range legs {
+		legIDs = append(legIDs, leg.ID)
 	}
-	var transactionType *string
-	if row.TransactionType.Valid {
-		transactionType = &row.TransactionType.String
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE transactions
+		SET status = $1, date = $2, updated_at = $3
+		WHERE user_id = $4 AND id = ANY($5)
+	`, status, date, utils.NowUTC(), userID, pq.Array(legIDs)); err != nil {
+		log.Printf("[UpdateScheduledTransaction] UPDATE error for id=%s: %v", id, err)
+		_ = tx.Rollback()
+		return appErrors.DatabaseError
 	}
-	var startDate *string
-	if row.StartDate.Valid {
-		formatted := row.StartDate.Time.Format("2006-01-02")
-		startDate = &formatted
+	if err := tx.Commit(); err != nil {
+		return appErrors.DatabaseError
 	}
-	var endDate *string
-	if row.EndDate.Valid {
-		formatted := row.EndDate.Time.Format("2006-01-02")
-		endDate = &formatted
+	return nil
+}
+
+// fetchScheduledLegsForUpdate locks a scheduled entry together with the other
+// leg of its transfer, if any, and checks that it is still waiting to post.
+func fetchScheduledLegsForUpdate(ctx context.Context, tx *sqlx.Tx, userID, id string) ([]*Transaction, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM transactions
+		WHERE user_id = $1 AND deleted_at IS NULL
+			AND (id = $2 OR (reference_type = 'transfer' AND reference_id = (
+				SELECT reference_id FROM transactions
+				WHERE id = $2 AND user_id = $1 AND reference_type = 'transfer'
+			)))
+		ORDER BY id
+		FOR UPDATE
+	`, transactionSelectFields)
+
+	var rows []transactionRow
+	if err := tx.SelectContext(ctx, &rows, query, userID, id); err != nil {
+		log.Printf("[fetchScheduledLegsForUpdate] Query error for id=%s: %v", id, err)
+		return nil, appErrors.DatabaseError
 	}
-	return &Budget{
-		ID:                row.ID,
-		UserID:            row.UserID,
-		Name:              row.Name,
-		BudgetType:        row.BudgetType,
-		CategoryIDs:       categoryIDs,
-		LinkedGoalID:      linkedGoalID,
-		AccountID:         accountID,
-		TransactionType:   transactionType,
-		Currency:          row.Currency,
-		LimitAmount:       row.LimitAmount,
-		PeriodType:        row.PeriodType,
-		StartDate:         startDate,
-		EndDate:           endDate,
-		SpentAmount:       row.SpentAmount,
-		RemainingAmount:   row.RemainingAmount,
-		PercentUsed:       row.PercentUsed,
-		IsOverspent:       row.IsOverspent,
-		RolloverMode:      row.RolloverMode,
-		NotifyOnExceed:    row.NotifyOnExceed,
-		ContributionTotal: row.ContributionTotal,
-		CurrentBalance:    row.CurrentBalance,
-		IsArchived:        row.IsArchived,
-		ShowStatus:        row.ShowStatus,
-		CreatedAt:         row.CreatedAt,
-		UpdatedAt:         row.UpdatedAt,
+	if len(rows) == 0 {
+		return nil, appErrors.TransactionNotFound
 	}
+	legs := make([]*Transaction, 0, len(rows))
+	for _, row := range rows {
+		leg := mapRowToTransaction(row)
+		if !isAwaitingPost(leg) {
+			return nil, appErrors.TransactionNotScheduled
+		}
+		legs = append(legs, leg)
+	}
+	return legs, nil
 }
 
-type debtRow struct {
-	ID                           string          `db:"id"`
-	UserID                       string          `db:"user_id"`
-	Name                         sql.NullString  `db:"name"`
-	Balance                      sql.NullFloat64 `db:"balance"`
-	Direction                    string          `db:"direction"`
-	CounterpartyID               sql.NullString  `db:"counterparty_id"`
-	CounterpartyName             string          `db:"counterparty_name"`
-	Description                  sql.NullString  `db:"description"`
-	PrincipalAmount              float64         `db:"principal_amount"`
-	PrincipalCurrency            string          `db:"principal_currency"`
-	PrincipalOriginalAmount      float64         `db:"principal_original_amount"`
-	PrincipalOriginalCurrency    sql.NullString  `db:"principal_original_currency"`
-	BaseCurrency                 string          `db:"base_currency"`
-	RateOnStart                  float64         `db:"rate_on_start"`
-	PrincipalBaseValue           float64         `db:"principal_base_value"`
-	RepaymentCurrency            sql.NullString  `db:"repayment_currency"`
-	RepaymentAmount              float64         `db:"repayment_amount"`
-	RepaymentRateOnStart         float64         `db:"repayment_rate_on_start"`
-	IsFixedRepaymentAmount       bool            `db:"is_fixed_repayment_amount"`
-	StartDate                    sql.NullTime    `db:"start_date"`
-	DueDate                      sql.NullTime    `db:"due_date"`
-	InterestMode                 sql.NullString  `db:"interest_mode"`
-	InterestRateAnnual           float64         `db:"interest_rate_annual"`
-	ScheduleHint                 sql.NullString  `db:"schedule_hint"`
-	LinkedGoalID                 sql.NullString  `db:"linked_goal_id"`
-	LinkedBudgetID               sql.NullString  `db:"linked_budget_id"`
-	FundingAccountID             sql.NullString  `db:"funding_account_id"`
-	FundingTransactionID         sql.NullString  `db:"funding_transaction_id"`
-	LentFromAccountID            sql.NullString  `db:"lent_from_account_id"`
-	ReturnToAccountID            sql.NullString  `db:"return_to_account_id"`
-	ReceivedToAccountID          sql.NullString  `db:"received_to_account_id"`
-	PayFromAccountID             sql.NullString  `db:"pay_from_account_id"`
-	CustomRateUsed               float64         `db:"custom_rate_used"`
-	ExchangeRateCurrent          float64         `db:"exchange_rate_current"`
-	ReminderEnabled              bool            `db:"reminder_enabled"`
-	ReminderTime                 sql.NullString  `db:"reminder_time"`
-	Status                       string          `db:"status"`
-	SettledAt                    sql.NullTime    `db:"settled_at"`
-	FinalRateUsed                float64         `db:"final_rate_used"`
-	FinalProfitLoss              float64         `db:"final_profit_loss"`
-	FinalProfitLossCurrency      sql.NullString  `db:"final_profit_loss_currency"`
-	TotalPaidInRepaymentCurrency float64         `db:"total_paid_in_repayment_currency"`
-	RemainingAmount              float64         `db:"remaining_amount"`
-	TotalPaid                    float64         `db:"total_paid"`
-	PercentPaid                  float64         `db:"percent_paid"`
-	ShowStatus                   string          `db:"show_status"`
-	CreatedAt                    string          `db:"created_at"`
-	UpdatedAt                    string          `db:"updated_at"`
+// ========== CREDIT CARDS ==========
+
+// ListCreditAccounts is used by the statement job and is intentionally not
+// scoped to the user in ctx.
+func (r *PostgresRepository) ListCreditAccounts(ctx context.Context) ([]*Account, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM accounts
+		WHERE account_type = 'credit' AND statement_closing_day IS NOT NULL AND payment_due_day IS NOT NULL
+			AND deleted_at IS NULL
+		ORDER BY id
+	`, accountSelectFields)
+
+	var rows []accountRow
+	if err := r.db.SelectContext(ctx, &rows, query); err != nil {
+		log.Printf("[ListCreditAccounts] Query error: %v", err)
+		return nil, appErrors.DatabaseError
+	}
+	accounts := make([]*Account, 0, len(rows))
+	for _, row := range rows {
+		accounts = append(accounts, mapRowToAccount(row))
+	}
+	return accounts, nil
 }
 
-func mapRowToDebt(row debtRow) *Debt {
-	var counterpartyID *string
-	if row.CounterpartyID.Valid {
-		counterpartyID = &row.CounterpartyID.String
+func (r *PostgresRepository) ListCreditStatements(ctx context.Context, accountID string) ([]*CreditStatement, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return nil, appErrors.InvalidToken
 	}
-	var description *string
-	if row.Description.Valid {
-		description = &row.Description.String
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM credit_statements
+		WHERE user_id = $1 AND account_id = $2
+		ORDER BY period_end DESC
+	`, creditStatementSelectFields)
+
+	var rows []creditStatementRow
+	if err := r.db.SelectContext(ctx, &rows, query, userID, accountID); err != nil {
+		log.Printf("[ListCreditStatements] Query error for account=%s: %v", accountID, err)
+		return nil, appErrors.DatabaseError
 	}
-	var principalOriginalCurrency *string
-	if row.PrincipalOriginalCurrency.Valid {
-		principalOriginalCurrency = &row.PrincipalOriginalCurrency.String
+	statements := make([]*CreditStatement, 0, len(rows))
+	for _, row := range rows {
+		statements = append(statements, mapRowToCreditStatement(row))
 	}
-	var repaymentCurrency *string
-	if row.RepaymentCurrency.Valid {
-		repaymentCurrency = &row.RepaymentCurrency.String
+	return statements, nil
+}
+
+func (r *PostgresRepository) CreateCreditStatement(ctx context.Context, statement *CreditStatement) (bool, error) {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return false, appErrors.InvalidToken
 	}
-	var startDate string
-	if row.StartDate.Valid {
-		startDate = row.StartDate.Time.Format("2006-01-02")
+
+	if statement.ID == "" {
+		statement.ID = uuid.NewString()
 	}
-	var dueDate *string
-	if row.DueDate.Valid {
-		formatted := row.DueDate.Time.Format("2006-01-02")
-		dueDate = &formatted
+	now := utils.NowUTC()
+	statement.UserID = userID
+	statement.CreatedAt = now
+	statement.UpdatedAt = now
+
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO credit_statements (id, user_id, account_id, currency, period_start, period_end, due_date,
+			opening_balance, charges, credits, statement_balance, minimum_payment, credit_limit, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		ON CONFLICT (account_id, period_end) DO NOTHING
+	`, statement.ID, userID, statement.AccountID, statement.Currency, statement.PeriodStart, statement.PeriodEnd, statement.DueDate,
+		statement.OpeningBalance, statement.Charges, statement.Credits, statement.StatementBalance, statement.MinimumPayment,
+		statement.CreditLimit, statement.CreatedAt, statement.UpdatedAt)
+	if err != nil {
+		log.Printf("[CreateCreditStatement] INSERT error for account=%s: %v", statement.AccountID, err)
+		return false, appErrors.DatabaseError
 	}
-	var interestMode *string
-	if row.InterestMode.Valid {
-		interestMode = &row.InterestMode.String
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, appErrors.DatabaseError
 	}
-	var scheduleHint *string
-	if row.ScheduleHint.Valid {
-		scheduleHint = &row.ScheduleHint.String
+	return rows > 0, nil
+}
+
+func (r *PostgresRepository) UpdateCreditStatement(ctx context.Context, statement *CreditStatement) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
 	}
-	var linkedGoalID *string
-	if row.LinkedGoalID.Valid {
-		linkedGoalID = &row.LinkedGoalID.String
+
+	statement.UpdatedAt = utils.NowUTC()
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE credit_statements
+		SET reminder_sent_at = $1, overdue_notified_at = $2, updated_at = $3
+		WHERE id = $4 AND user_id = $5
+	`, statement.ReminderSentAt, statement.OverdueNotifiedAt, statement.UpdatedAt, statement.ID, userID)
+	if err != nil {
+		log.Printf("[UpdateCreditStatement] UPDATE error for id=%s: %v", statement.ID, err)
+		return appErrors.DatabaseError
 	}
-	var linkedBudgetID *string
-	if row.LinkedBudgetID.Valid {
-		linkedBudgetID = &row.LinkedBudgetID.String
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return appErrors.DatabaseError
 	}
-	var fundingAccountID *string
-	if row.FundingAccountID.Valid {
-		fundingAccountID = &row.FundingAccountID.String
+	if rows == 0 {
+		return appErrors.CreditStatementNotFound
 	}
-	var fundingTransactionID *string
-	if row.FundingTransactionID.Valid {
-		fundingTransactionID = &row.FundingTransactionID.String
+	return nil
+}
+
+type creditStatementRow struct {
+	ID                string         `db:"id"`
+	UserID            string         `db:"user_id"`
+	AccountID         string         `db:"account_id"`
+	Currency          string         `db:"currency"`
+	PeriodStart       sql.NullTime   `db:"period_start"`
+	PeriodEnd         sql.NullTime   `db:"period_end"`
+	DueDate           sql.NullTime   `db:"due_date"`
+	OpeningBalance    Money          `db:"opening_balance"`
+	Charges           Money          `db:"charges"`
+	Credits           Money          `db:"credits"`
+	StatementBalance  Money          `db:"statement_balance"`
+	MinimumPayment    Money          `db:"minimum_payment"`
+	CreditLimit       Money          `db:"credit_limit"`
+	ReminderSentAt    sql.NullString `db:"reminder_sent_at"`
+	OverdueNotifiedAt sql.NullString `db:"overdue_notified_at"`
+	CreatedAt         string         `db:"created_at"`
+	UpdatedAt         string         `db:"updated_at"`
+}
+
+func mapRowToCreditStatement(row creditStatementRow) *CreditStatement {
+	statement := &CreditStatement{
+		ID:               row.ID,
+		UserID:           row.UserID,
+		AccountID:        row.AccountID,
+		Currency:         row.Currency,
+		OpeningBalance:   row.OpeningBalance,
+		Charges:          row.Charges,
+		Credits:          row.Credits,
+		StatementBalance: row.StatementBalance,
+		MinimumPayment:   row.MinimumPayment,
+		CreditLimit:      row.CreditLimit,
+		CreatedAt:        row.CreatedAt,
+		UpdatedAt:        row.UpdatedAt,
+	}
+	if row.PeriodStart.Valid {
+		statement.PeriodStart = row.PeriodStart.Time.Format("2006-01-02")
+	}
+	if row.PeriodEnd.Valid {
+		statement.PeriodEnd = row.PeriodEnd.Time.Format("2006-01-02")
 	}
-	var lentFromAccountID *string
-	if row.LentFromAccountID.Valid {
-		lentFromAccountID = &row.LentFromAccountID.String
+	if row.DueDate.Valid {
+		statement.DueDate = row.DueDate.Time.Format("2006-01-02")
+	}
+	if row.ReminderSentAt.Valid {
+		statement.ReminderSentAt = &row.ReminderSentAt.String
+	}
+	if row.OverdueNotifiedAt.Valid {
+		statement.OverdueNotifiedAt = &row.OverdueNotifiedAt.String
+	}
+	return statement
+}
+
+func (r *PostgresRepository) MergeAccount(ctx context.Context, merge *AccountMerge, rate ExchangeRate) error {
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok || userID == "" {
+		return appErrors.InvalidToken
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Printf("[MergeAccount] Failed to begin transaction: %v", err)
+		return appErrors.DatabaseError
+	}
+
+	// Lock both accounts in id order so two merges cannot deadlock.
+	var accountRows []accountRow
+	if err := tx.SelectContext(ctx, &accountRows, fmt.Sprintf(`
+		SELECT %s FROM accounts
+		WHERE id IN ($1, $2) AND user_id = $3 AND deleted_at IS NULL
+		ORDER BY id
+		FOR UPDATE
+	`, accountSelectFields), merge.SourceAccountID, merge.TargetAccountID, userID); err != nil {
+		log.Printf("[MergeAccount] Account lock error: %v", err)
+		_ = tx.Rollback()
+		return appErrors.DatabaseError
 	}
-	var returnToAccountID *string
-	if row.ReturnToAccountID.Valid {
-		returnToAccountID = &row.ReturnToAccountID.String
+	var source, target *Account
+	for _, row := range accountRows {
+		account := mapRowToAccount(row)
+		if account.ID == merge.SourceAccountID {
+			source = account
+		} else {
+			target = account
+		}
 	}
-	var receivedToAccountID *string
-	if row.ReceivedToAccountID.Valid {
-		receivedToAccountID = &row.ReceivedToAccountID.String
+	if source == nil || target == nil {
+		_ = tx.Rollback()
+		return appErrors.AccountNotFound
 	}
-	var payFromAccountID *string
-	if row.PayFromAccountID.Valid {
-		payFromAccountID = &row.PayFromAccountID.String
+	now := utils.NowUTC()
+
+	var txnRows []transactionRow
+	if err := tx.SelectContext(ctx, &txnRows, fmt.Sprintf(`
+		SELECT %s FROM transactions
+		WHERE user_id = $1 AND deleted_at IS NULL
+			AND (account_id = $2 OR from_account_id = $2 OR to_account_id = $2)
+		FOR UPDATE
+	`, transactionSelectFields), userID, source.ID); err != nil {
+		log.Printf("[MergeAccount] Transactions query error: %v", err)
+		_ = tx.Rollback()
+		return appErrors.DatabaseError
 	}
-	var reminderTime *string
-	if row.ReminderTime.Valid {
-		reminderTime = &row.ReminderTime.String
+	for _, row := range txnRows {
+		txn := mapRowToTransaction(row)
+		if !moveTransactionToAccount(txn, merge, rate) {
+			continue
+		}
+		splits, err := marshalTransactionSplits(txn.Splits)
+		if err != nil {
+			_ = tx.Rollback()
+			return appErrors.InvalidFinanceData
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE transactions
+			SET account_id = $1, from_account_id = $2, to_account_id = $3,
+				amount = $4, currency = $5, to_amount = $6, to_currency = $7, effective_rate_from_to = $8,
+				original_currency = $9, original_amount = $10, conversion_rate = $11, splits = $12,
+				updated_at = $13
+			WHERE id = $14 AND user_id = $15
+		`, txn.AccountID, txn.FromAccountID, txn.ToAccountID,
+			txn.Amount, txn.Currency, txn.ToAmount, txn.ToCurrency, txn.EffectiveRateFromTo,
+			txn.OriginalCurrency, txn.OriginalAmount, txn.ConversionRate, splits,
+			now, txn.ID, userID); err != nil {
+			log.Printf("[MergeAccount] Transaction UPDATE error for id=%s: %v", txn.ID, err)
+			_ = tx.Rollback()
+			return appErrors.DatabaseError
+		}
+		merge.TransactionsMoved++
 	}
-	var settledAt *string
-	if row.SettledAt.Valid {
-		formatted := row.SettledAt.Time.Format("2006-01-02T15:04:05Z07:00")
-		settledAt = &formatted
+
+	var recurringRows []recurringRow
+	if err := tx.SelectContext(ctx, &recurringRows, fmt.Sprintf(`
+		SELECT %s FROM recurring_transactions
+		WHERE user_id = $1 AND deleted_at IS NULL
+			AND (account_id = $2 OR from_account_id = $2 OR to_account_id = $2)
+		FOR UPDATE
+	`, recurringSelectFields), userID, source.ID); err != nil {
+		log.Printf("[MergeAccount] Recurring query error: %v", err)
+		_ = tx.Rollback()
+		return appErrors.DatabaseError
 	}
-	var finalProfitLossCurrency *string
-	if row.FinalProfitLossCurrency.Valid {
-		finalProfitLossCurrency = &row.FinalProfitLossCurrency.String
+	for _, row := range recurringRows {
+		item := mapRowToRecurring(row)
+		if !moveRecurringToAccount(item, merge, rate) {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE recurring_transactions
+			SET account_id = $1, from_account_id = $2, to_account_id = $3,
+				amount = $4, currency = $5, to_amount = $6, to_currency = $7, updated_at = $8
+			WHERE id = $9 AND user_id = $10
+		`, item.AccountID, item.FromAccountID, item.ToAccountID,
+			item.Amount, item.Currency, item.ToAmount, item.ToCurrency, now, item.ID, userID); err != nil {
+			log.Printf("[MergeAccount] Recurring UPDATE error for id=%s: %v", item.ID, err)
+			_ = tx.Rollback()
+			return appErrors.DatabaseError
+		}
+		merge.RecurringMoved++
 	}
-	counterpartyName := row.CounterpartyName
-	if counterpartyName == "" && row.Name.Valid {
-		counterpartyName = row.Name.String
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE budgets SET account_id = $1, updated_at = $2
+		WHERE account_id = $3 AND user_id = $4 AND deleted_at IS NULL
+	`, target.ID, now, source.ID, userID)
+	if err != nil {
+		log.Printf("[MergeAccount] Budget UPDATE error: %v", err)
+		_ = tx.Rollback()
+		return appErrors.DatabaseError
 	}
-	return &Debt{
-		ID:                           row.ID,
-		UserID:                       row.UserID,
-		Direction:                    row.Direction,
-		CounterpartyID:               counterpartyID,
-		CounterpartyName:             counterpartyName,
-		Description:                  description,
-		PrincipalAmount:              row.PrincipalAmount,
-		PrincipalCurrency:            row.PrincipalCurrency,
-		PrincipalOriginalAmount:      row.PrincipalOriginalAmount,
-		PrincipalOriginalCurrency:    principalOriginalCurrency,
-		BaseCurrency:                 row.BaseCurrency,
-		RateOnStart:                  row.RateOnStart,
-		PrincipalBaseValue:           row.PrincipalBaseValue,
-		RepaymentCurrency:            repaymentCurrency,
-		RepaymentAmount:              row.RepaymentAmount,
-		RepaymentRateOnStart:         row.RepaymentRateOnStart,
-		IsFixedRepaymentAmount:       row.IsFixedRepaymentAmount,
-		StartDate:                    startDate,
-		DueDate:                      dueDate,
-		InterestMode:                 interestMode,
-		InterestRateAnnual:           row.InterestRateAnnual,
-		ScheduleHint:                 scheduleHint,
-		LinkedGoalID:                 linkedGoalID,
-		LinkedBudgetID:               linkedBudgetID,
-		FundingAccountID:             fundingAccountID,
-		FundingTransactionID:         fundingTransactionID,
-		LentFromAccountID:            lentFromAccountID,
-		ReturnToAccountID:            returnToAccountID,
-		ReceivedToAccountID:          receivedToAccountID,
-		PayFromAccountID:             payFromAccountID,
-		CustomRateUsed:               row.CustomRateUsed,
-		ExchangeRateCurrent:          row.ExchangeRateCurrent,
-		ReminderEnabled:              row.ReminderEnabled,
-		ReminderTime:                 reminderTime,
-		Status:                       row.Status,
-		SettledAt:                    settledAt,
-		FinalRateUsed:                row.FinalRateUsed,
-		FinalProfitLoss:              row.FinalProfitLoss,
-		FinalProfitLossCurrency:      finalProfitLossCurrency,
-		TotalPaidInRepaymentCurrency: row.TotalPaidInRepaymentCurrency,
-		RemainingAmount:              row.RemainingAmount,
-		TotalPaid:                    row.TotalPaid,
-		PercentPaid:                  row.PercentPaid,
-		ShowStatus:                   row.ShowStatus,
-		CreatedAt:                    row.CreatedAt,
-		UpdatedAt:                    row.UpdatedAt,
+	if moved, err := result.RowsAffected(); err == nil {
+		merge.BudgetsMoved = int(moved)
 	}
-}
-
-type debtPaymentRow struct {
-	ID                    string         `db:"id"`
-	DebtID                string         `db:"debt_id"`
-	Amount                float64        `db:"amount"`
-	Currency              string         `db:"currency"`
-	BaseCurrency          string         `db:"base_currency"`
-	RateUsedToBase        float64        `db:"rate_used_to_base"`
-	ConvertedAmountToBase float64        `db:"converted_amount_to_base"`
-	RateUsedToDebt        float64        `db:"rate_used_to_debt"`
-	ConvertedAmountToDebt float64        `db:"converted_amount_to_debt"`
-	PaymentDate           sql.NullTime   `db:"payment_date"`
-	AccountID             sql.NullString `db:"account_id"`
-	Note                  sql.NullString `db:"note"`
-	RelatedTransactionID  sql.NullString `db:"related_transaction_id"`
-	AppliedRate           float64        `db:"applied_rate"`
-	CreatedAt             string         `db:"created_at"`
-	UpdatedAt             string         `db:"updated_at"`
-	DeletedAt             sql.NullString `db:"deleted_at"`
-}
 
-func mapRowToDebtPayment(row debtPaymentRow) *DebtPayment {
-	var accountID *string
-	if row.AccountID.Valid {
-		accountID = &row.AccountID.String
+	result, err = tx.ExecContext(ctx, `
+		UPDATE debts
+		SET funding_account_id = CASE WHEN funding_account_id = $1 THEN $2 ELSE funding_account_id END,
+			lent_from_account_id = CASE WHEN lent_from_account_id = $1 THEN $2 ELSE lent_from_account_id END,
+			return_to_account_id = CASE WHEN return_to_account_id = $1 THEN $2 ELSE return_to_account_id END,
+			received_to_account_id = CASE WHEN received_to_account_id = $1 THEN $2 ELSE received_to_account_id END,
+			pay_from_account_id = CASE WHEN pay_from_account_id = $1 THEN $2 ELSE pay_from_account_id END,
+			updated_at = $3
+		WHERE user_id = $4 AND deleted_at IS NULL
+			AND $1 IN (funding_account_id, lent_from_account_id, return_to_account_id, received_to_account_id, pay_from_account_id)
+	`, source.ID, target.ID, now, userID)
+	if err != nil {
+		log.Printf("[MergeAccount] Debt UPDATE error: %v", err)
+		_ = tx.Rollback()
+		return appErrors.DatabaseError
 	}
-	var note *string
-	if row.Note.Valid {
-		note = &row.Note.String
+	if moved, err := result.RowsAffected(); err == nil {
+		merge.DebtsMoved = int(moved)
 	}
-	var relatedTransactionID *string
-	if row.RelatedTransactionID.Valid {
-		relatedTransactionID = &row.RelatedTransactionID.String
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE debt_payments SET account_id = $1, updated_at = $2
+		WHERE account_id = $3 AND debt_id IN (SELECT id FROM debts WHERE user_id = $4)
+	`, target.ID, now, source.ID, userID); err != nil {
+		log.Printf("[MergeAccount] Debt payment UPDATE error: %v", err)
+		_ = tx.Rollback()
+		return appErrors.DatabaseError
 	}
-	paymentDate := ""
-	if row.PaymentDate.Valid {
-		paymentDate = row.PaymentDate.Time.Format("2006-01-02")
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE sms_card_links SET account_id = $1, updated_at = $2
+		WHERE account_id = $3 AND user_id = $4
+	`, target.ID, now, source.ID, userID); err != nil {
+		log.Printf("[MergeAccount] SMS card link UPDATE error: %v", err)
+		_ = tx.Rollback()
+		return appErrors.DatabaseError
 	}
-	return &DebtPayment{
-		ID:                    row.ID,
-		DebtID:                row.DebtID,
-		Amount:                row.Amount,
-		Currency:              row.Currency,
-		BaseCurrency:          row.BaseCurrency,
-		RateUsedToBase:        row.RateUsedToBase,
-		ConvertedAmountToBase: row.ConvertedAmountToBase,
-		RateUsedToDebt:        row.RateUsedToDebt,
-		ConvertedAmountToDebt: row.ConvertedAmountToDebt,
-		PaymentDate:           paymentDate,
-		AccountID:             accountID,
-		Note:                  note,
-		RelatedTransactionID:  relatedTransactionID,
-		AppliedRate:           row.AppliedRate,
-		CreatedAt:             row.CreatedAt,
-		UpdatedAt:             row.UpdatedAt,
+	if source.LinkedGoalID != nil && target.LinkedGoalID == nil {
+		target.LinkedGoalID = source.LinkedGoalID
+		merge.GoalMoved = true
 	}
-}
-
-type counterpartyRow struct {
-	ID             string         `db:"id"`
-	UserID         string         `db:"user_id"`
-	DisplayName    string         `db:"display_name"`
-	PhoneNumber    sql.NullString `db:"phone_number"`
-	Comment        sql.NullString `db:"comment"`
-	SearchKeywords sql.NullString `db:"search_keywords"`
-	ShowStatus     string         `db:"show_status"`
-	CreatedAt      string         `db:"created_at"`
-	UpdatedAt      string         `db:"updated_at"`
-	DeletedAt      sql.NullString `db:"deleted_at"`
-}
 
-func mapRowToCounterparty(row counterpartyRow) *Counterparty {
-	var phoneNumber *string
-	if row.PhoneNumber.Valid {
-		phoneNumber = &row.PhoneNumber.String
+	merge.SourceBalance = source.CurrentBalance
+	merge.ConvertedBalance = source.CurrentBalance
+	initial := source.InitialBalance
+	if !rate.IsZero() {
+		merge.ConvertedBalance = rate.Convert(source.CurrentBalance, merge.TargetCurrency)
+		initial = rate.Convert(source.InitialBalance, merge.TargetCurrency)
 	}
-	var comment *string
-	if row.Comment.Valid {
-		comment = &row.Comment.String
-	}
-	var searchKeywords *string
-	if row.SearchKeywords.Valid {
-		searchKeywords = &row.SearchKeywords.String
+	target.InitialBalance += initial
+	txnRows = nil
+	if err := tx.SelectContext(ctx, &txnRows, fmt.Sprintf(`
+		SELECT %s FROM transactions
+		WHERE user_id = $1 AND deleted_at IS NULL
+			AND (account_id = $2 OR from_account_id = $2 OR to_account_id = $2)
+	`, transactionSelectFields), userID, target.ID); err != nil {
+		log.Printf("[MergeAccount] Target transactions query error: %v", err)
+		_ = tx.Rollback()
+		return appErrors.DatabaseError
 	}
-	return &Counterparty{
-		ID:             row.ID,
-		UserID:         row.UserID,
-		DisplayName:    row.DisplayName,
-		PhoneNumber:    phoneNumber,
-		Comment:        comment,
-		SearchKeywords: searchKeywords,
-		ShowStatus:     row.ShowStatus,
-		CreatedAt:      row.CreatedAt,
-		UpdatedAt:      row.UpdatedAt,
+	transactions := make([]*Transaction, 0, len(txnRows))
+	for _, row := range txnRows {
+		transactions = append(transactions, mapRowToTransaction(row))
 	}
-}
+	target.CurrentBalance = computeAccountBalance(target, transactions)
+	merge.TargetBalance = target.CurrentBalance
 
-type fxRateRow struct {
-	ID            string       `db:"id"`
-	RateDate      sql.NullTime `db:"rate_date"`
-	FromCurrency  string       `db:"from_currency"`
-	ToCurrency    string       `db:"to_currency"`
-	Rate          float64      `db:"rate"`
-	RateMid       float64      `db:"rate_mid"`
-	RateBid       float64      `db:"rate_bid"`
-	RateAsk       float64      `db:"rate_ask"`
-	Nominal       float64      `db:"nominal"`
-	SpreadPercent float64      `db:"spread_percent"`
-	Source        string       `db:"source"`
-	CreatedAt     string       `db:"created_at"`
-	UpdatedAt     string       `db:"updated_at"`
-}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE accounts
+		SET initial_balance = $1, current_balance = $2, linked_goal_id = $3, is_main = $4, updated_at = $5
+		WHERE id = $6 AND user_id = $7
+	`, target.InitialBalance, target.CurrentBalance, target.LinkedGoalID, target.IsMain || source.IsMain,
+		now, target.ID, userID); err != nil {
+		log.Printf("[MergeAccount] Target UPDATE error for id=%s: %v", target.ID, err)
+		_ = tx.Rollback()
+		return appErrors.DatabaseError
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE accounts
+		SET initial_balance = 0, current_balance = 0, linked_goal_id = NULL, is_main = false,
+			is_archived = true, show_status = 'archived', updated_at = $1
+		WHERE id = $2 AND user_id = $3
+	`, now, source.ID, userID); err != nil {
+		log.Printf("[MergeAccount] Source UPDATE error for id=%s: %v", source.ID, err)
+		_ = tx.Rollback()
+		return appErrors.DatabaseError
+	}
 
-func mapRowToFXRate(row fxRateRow) *FXRate {
-	date := ""
-	if row.RateDate.Valid {
-		date = row.RateDate.Time.Format("2006-01-02")
+	merge.ID = uuid.NewString()
+	merge.UserID = userID
+	merge.CreatedAt = now
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO account_merges (
+			id, user_id, source_account_id, target_account_id, source_currency, target_currency, rate,
+			source_balance, converted_balance, target_balance, transactions_moved, recurring_moved,
+			budgets_moved, debts_moved, goal_moved, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`, merge.ID, userID, merge.SourceAccountID, merge.TargetAccountID, merge.SourceCurrency, merge.TargetCurrency, merge.Rate,
+		merge.SourceBalance, merge.ConvertedBalance, merge.TargetBalance, merge.TransactionsMoved, merge.RecurringMoved,
+		merge.BudgetsMoved, merge.DebtsMoved, merge.GoalMoved, merge.CreatedAt); err != nil {
+		log.Printf("[MergeAccount] INSERT error for source=%s: %v", merge.SourceAccountID, err)
+		_ = tx.Rollback()
+		return appErrors.DatabaseError
 	}
-	return &FXRate{
-		ID:            row.ID,
-		Date:          date,
-		FromCurrency:  row.FromCurrency,
-		ToCurrency:    row.ToCurrency,
-		Rate:          row.Rate,
-		RateMid:       row.RateMid,
-		RateBid:       row.RateBid,
-		RateAsk:       row.RateAsk,
-		Nominal:       row.Nominal,
-		SpreadPercent: row.SpreadPercent,
-		Source:        row.Source,
-		CreatedAt:     row.CreatedAt,
-		UpdatedAt:     row.UpdatedAt,
+	if err := tx.Commit(); err != nil {
+		return appErrors.DatabaseError
 	}
+	return nil
 }
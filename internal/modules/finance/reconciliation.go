@@ -0,0 +1,316 @@
+package finance
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	appErrors "github.com/leora/leora-server/internal/errors"
+)
+
+const (
+	ReconciliationStatusInProgress = "in_progress"
+	ReconciliationStatusCompleted  = "completed"
+	ReconciliationStatusCancelled  = "cancelled"
+)
+
+// reconciliationReferenceType marks the adjustment posted when a statement
+// balance does not match the cleared balance; its ReferenceID is the
+// reconciliation id.
+const reconciliationReferenceType = "reconciliation"
+
+// Reconciliation compares an account with a bank statement. OpeningBalance is
+// the cleared balance before it started: the initial balance plus every entry
+// cleared by earlier reconciliations. ClearedBalance adds the entries ticked
+// in this one, and Difference is what the statement says on top of that.
+type Reconciliation struct {
+	ID                      string   `json:"id"`
+	UserID                  string   `json:"userId"`
+	AccountID               string   `json:"accountId"`
+	Currency                string   `json:"currency"`
+	StatementDate           string   `json:"statementDate"`
+	StatementBalance        Money    `json:"statementBalance"`
+	Status                  string   `json:"status"`
+	OpeningBalance          Money    `json:"openingBalance"`
+	ClearedBalance          Money    `json:"clearedBalance"`
+	Difference              Money    `json:"difference"`
+	ClearedTransactionIDs   []string `json:"clearedTransactionIds"`
+	AdjustmentTransactionID *string  `json:"adjustmentTransactionId,omitempty"`
+	CompletedAt             *string  `json:"completedAt,omitempty"`
+	CreatedAt               string   `json:"createdAt,omitempty"`
+	UpdatedAt               string   `json:"updatedAt,omitempty"`
+
+	// Transactions lists the uncleared entries up to the statement date while
+	// the reconciliation is open, and the entries it cleared once completed.
+	Transactions []*ReconciliationItem `json:"transactions,omitempty"`
+}
+
+// ReconciliationItem is one entry as seen from the reconciled account.
+// Amount is signed: what the entry added to or took from the balance.
+type ReconciliationItem struct {
+	Transaction *Transaction `json:"transaction"`
+	Amount      Money        `json:"amount"`
+	Cleared     bool         `json:"cleared"`
+}
+
+type ReconciliationInput struct {
+	StatementDate    string `json:"statementDate"`
+	StatementBalance *Money `json:"statementBalance"`
+}
+
+// ReconciliationSelection ticks and unticks entries of an open reconciliation.
+type ReconciliationSelection struct {
+	Cleared   []string `json:"cleared"`
+	Uncleared []string `json:"uncleared"`
+}
+
+// StartReconciliation opens a reconciliation of the account against a
+// statement. An account has at most one open reconciliation.
+func (s *Service) StartReconciliation(ctx context.Context, accountID string, input ReconciliationInput) (*Reconciliation, error) {
+	account, err := s.repo.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	statementDate := normalizeDateInput(strings.TrimSpace(input.StatementDate))
+	if _, err := time.Parse("2006-01-02", statementDate); err != nil {
+		return nil, appErrors.WithDetails(appErrors.InvalidReconciliation, map[string]interface{}{"field": "statementDate"})
+	}
+	if input.StatementBalance == nil {
+		return nil, appErrors.WithDetails(appErrors.InvalidReconciliation, map[string]interface{}{"field": "statementBalance"})
+	}
+	history, err := s.repo.ListReconciliations(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	for _, previous := range history {
+		if previous.Status == ReconciliationStatusInProgress {
+			return nil, appErrors.WithDetails(appErrors.ReconciliationInProgress, map[string]interface{}{"reconciliationId": previous.ID})
+		}
+		if previous.Status == ReconciliationStatusCompleted && previous.StatementDate > statementDate {
+			return nil, appErrors.WithDetails(appErrors.InvalidReconciliation, map[string]interface{}{
+				"field":             "statementDate",
+				"lastStatementDate": previous.StatementDate,
+			})
+		}
+	}
+
+	reconciliation := &Reconciliation{
+		AccountID:             account.ID,
+		Currency:              account.Currency,
+		StatementDate:         statementDate,
+		StatementBalance:      input.StatementBalance.RoundTo(account.Currency),
+		Status:                ReconciliationStatusInProgress,
+		ClearedTransactionIDs: []string{},
+	}
+	items, err := s.reconciliationItems(ctx, account, reconciliation)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateReconciliation(ctx, reconciliation); err != nil {
+		return nil, err
+	}
+	reconciliation.Transactions = items
+	return reconciliation, nil
+}
+
+// Reconciliations returns the account's reconciliation history, newest
+// statement first.
+func (s *Service) Reconciliations(ctx context.Context, accountID string) ([]*Reconciliation, error) {
+	if _, err := s.repo.GetAccountByID(ctx, accountID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListReconciliations(ctx, accountID)
+}
+
+func (s *Service) GetReconciliation(ctx context.Context, id string) (*Reconciliation, error) {
+	reconciliation, err := s.repo.GetReconciliationByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	account, err := s.repo.GetAccountByID(ctx, reconciliation.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.reconciliationItems(ctx, account, reconciliation)
+	if err != nil {
+		return nil, err
+	}
+	reconciliation.Transactions = items
+	return reconciliation, nil
+}
+
+// SelectReconciliationTransactions ticks or unticks entries of an open
+// reconciliation. Only uncleared entries up to the statement date qualify.
+func (s *Service) SelectReconciliationTransactions(ctx context.Context, id string, selection ReconciliationSelection) (*Reconciliation, error) {
+	reconciliation, account, err := s.openReconciliation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.reconciliationItems(ctx, account, reconciliation)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make(map[string]bool, len(items))
+	for _, item := range items {
+		candidates[item.Transaction.ID] = true
+	}
+	selected := make(map[string]bool, len(reconciliation.ClearedTransactionIDs))
+	for _, txnID := range reconciliation.ClearedTransactionIDs {
+		selected[txnID] = true
+	}
+	for _, txnID := range selection.Cleared {
+		if !candidates[txnID] {
+			return nil, appErrors.WithDetails(appErrors.InvalidReconciliation, map[string]interface{}{"field": "cleared", "transactionId": txnID})
+		}
+		selected[txnID] = true
+	}
+	for _, txnID := range selection.Uncleared {
+		delete(selected, txnID)
+	}
+	reconciliation.ClearedTransactionIDs = make([]string, 0, len(selected))
+	for _, item := range items {
+		if selected[item.Transaction.ID] {
+			reconciliation.ClearedTransactionIDs = append(reconciliation.ClearedTransactionIDs, item.Transaction.ID)
+		}
+	}
+	items, err = s.reconciliationItems(ctx, account, reconciliation)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateReconciliation(ctx, reconciliation); err != nil {
+		return nil, err
+	}
+	reconciliation.Transactions = items
+	return reconciliation, nil
+}
+
+// CompleteReconciliation clears and locks the ticked entries and posts any
+// remaining difference as a system adjustment dated on the statement.
+func (s *Service) CompleteReconciliation(ctx context.Context, id string) (*Reconciliation, error) {
+	reconciliation, account, err := s.openReconciliation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.reconciliationItems(ctx, account, reconciliation); err != nil {
+		return nil, err
+	}
+
+	var adjustment *Transaction
+	if reconciliation.Difference != 0 {
+		referenceType := reconciliationReferenceType
+		referenceID := reconciliation.ID
+		name := "Reconciliation adjustment"
+		adjustment = &Transaction{
+			Type:                TransactionTypeSystemAdjustment,
+			Status:              TransactionStatusCompleted,
+			AccountID:           &account.ID,
+			ReferenceType:       &referenceType,
+			ReferenceID:         &referenceID,
+			Amount:              reconciliation.Difference,
+			Currency:            account.Currency,
+			BaseCurrency:        account.Currency,
+			Name:                &name,
+			Date:                reconciliation.StatementDate,
+			IsBalanceAdjustment: true,
+			ShowStatus:          "active",
+		}
+		normalizeTransaction(adjustment)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	reconciliation.Status = ReconciliationStatusCompleted
+	reconciliation.CompletedAt = &now
+	if err := s.repo.CompleteReconciliation(ctx, reconciliation, adjustment); err != nil {
+		return nil, err
+	}
+	s.invalidateFinanceSummaryCache(ctx)
+	return s.GetReconciliation(ctx, id)
+}
+
+// CancelReconciliation closes an open reconciliation without clearing
+// anything. It stays in the history.
+func (s *Service) CancelReconciliation(ctx context.Context, id string) (*Reconciliation, error) {
+	reconciliation, _, err := s.openReconciliation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	reconciliation.Status = ReconciliationStatusCancelled
+	reconciliation.ClearedTransactionIDs = []string{}
+	reconciliation.ClearedBalance = reconciliation.OpeningBalance
+	reconciliation.Difference = reconciliation.StatementBalance - reconciliation.OpeningBalance
+	if err := s.repo.UpdateReconciliation(ctx, reconciliation); err != nil {
+		return nil, err
+	}
+	return reconciliation, nil
+}
+
+func (s *Service) openReconciliation(ctx context.Context, id string) (*Reconciliation, *Account, error) {
+	reconciliation, err := s.repo.GetReconciliationByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if reconciliation.Status != ReconciliationStatusInProgress {
+		return nil, nil, appErrors.WithDetails(appErrors.InvalidReconciliation, map[string]interface{}{"field": "status", "status": reconciliation.Status})
+	}
+	account, err := s.repo.GetAccountByID(ctx, reconciliation.AccountID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return reconciliation, account, nil
+}
+
+// reconciliationItems walks the account's ledger, refreshes the balances of
+// an open reconciliation and returns the entries it shows: the uncleared ones
+// up to the statement date while open, the ones it cleared once completed.
+// Voided entries and their reversals cancel out and are left out.
+func (s *Service) reconciliationItems(ctx context.Context, account *Account, reconciliation *Reconciliation) ([]*ReconciliationItem, error) {
+	transactions, err := s.repo.ListTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	selected := make(map[string]bool, len(reconciliation.ClearedTransactionIDs))
+	for _, txnID := range reconciliation.ClearedTransactionIDs {
+		selected[txnID] = true
+	}
+	open := reconciliation.Status == ReconciliationStatusInProgress
+	opening := account.InitialBalance
+	ticked := Money(0)
+	items := make([]*ReconciliationItem, 0)
+	for _, txn := range transactions {
+		if txn.Status == TransactionStatusVoided {
+			continue
+		}
+		delta := transactionDeltaForAccount(account.ID, txn)
+		if delta == 0 {
+			continue
+		}
+		normalizeTransaction(txn)
+		if txn.ReconciliationID != nil {
+			if open || *txn.ReconciliationID != reconciliation.ID {
+				opening += delta
+				continue
+			}
+			items = append(items, &ReconciliationItem{Transaction: txn, Amount: delta, Cleared: true})
+			continue
+		}
+		if !open || txn.Date > reconciliation.StatementDate {
+			continue
+		}
+		if selected[txn.ID] {
+			ticked += delta
+		}
+		items = append(items, &ReconciliationItem{Transaction: txn, Amount: delta, Cleared: selected[txn.ID]})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Transaction.Date != items[j].Transaction.Date {
+			return items[i].Transaction.Date < items[j].Transaction.Date
+		}
+		return items[i].Transaction.CreatedAt < items[j].Transaction.CreatedAt
+	})
+	if open {
+		reconciliation.OpeningBalance = opening
+		reconciliation.ClearedBalance = opening + ticked
+		reconciliation.Difference = reconciliation.StatementBalance - reconciliation.ClearedBalance
+	}
+	return items, nil
+}
@@ -21,16 +21,35 @@ type Repository interface {
 	DeleteAccount(ctx context.Context, id string) (*Transaction, error)
 
 	ListTransactions(ctx context.Context) ([]*Transaction, error)
+	ListTransactionsPage(ctx context.Context, filter TransactionFilter, page TransactionPageRequest) ([]*Transaction, error)
+	CountTransactions(ctx context.Context, filter TransactionFilter) (int, error)
 	GetTransactionByID(ctx context.Context, id string) (*Transaction, error)
 	CreateTransaction(ctx context.Context, txn *Transaction) error
+	// UpdateTransaction rewrites an entry; one cleared by a completed
+	// reconciliation is refused with TransactionLocked.
 	UpdateTransaction(ctx context.Context, txn *Transaction) error
+	// UpdateTransactionAttachments replaces only the attachment list, which
+	// may change on a locked entry too.
+	UpdateTransactionAttachments(ctx context.Context, id string, attachments []string) error
+	// ReclassifyTransactions stores the category, subcategory, tags, budget
+	// and counterparty of every entry in one transaction and returns the ids
+	// it skipped because they were voided or locked by a reconciliation.
+	ReclassifyTransactions(ctx context.Context, updates []*Transaction) ([]string, error)
 	DeleteTransaction(ctx context.Context, id string) error
+	ReverseTransaction(ctx context.Context, id string, input TransactionReversalInput) (*TransactionReversal, error)
 
 	ListBudgets(ctx context.Context) ([]*Budget, error)
 	GetBudgetByID(ctx context.Context, id string) (*Budget, error)
 	CreateBudget(ctx context.Context, budget *Budget) error
 	UpdateBudget(ctx context.Context, budget *Budget) error
+	// UpdateBudgetRollups stores the spent, remaining, percent used and
+	// overspent figures without touching the rest of the budget.
+	UpdateBudgetRollups(ctx context.Context, budget *Budget) error
 	DeleteBudget(ctx context.Context, id string) error
+	CloseBudgetPeriod(ctx context.Context, budget *Budget, period *BudgetPeriod) error
+	ListBudgetPeriods(ctx context.Context, budgetID string) ([]*BudgetPeriod, error)
+	ListBudgetsDueForClose(ctx context.Context, asOf string) ([]*Budget, error)
+	RecordBudgetAlert(ctx context.Context, alert *BudgetAlert) (bool, error)
 
 	ListDebts(ctx context.Context) ([]*Debt, error)
 	GetDebtByID(ctx context.Context, id string) (*Debt, error)
@@ -50,9 +69,32 @@ type Repository interface {
 	UpdateCounterparty(ctx context.Context, counterparty *Counterparty) error
 	DeleteCounterparty(ctx context.Context, id string) error
 
+	ListRecurringTransactions(ctx context.Context) ([]*RecurringTransaction, error)
+	ListDueRecurringTransactions(ctx context.Context, asOf string) ([]*RecurringTransaction, error)
+	GetRecurringTransactionByID(ctx context.Context, id string) (*RecurringTransaction, error)
+	CreateRecurringTransaction(ctx context.Context, recurring *RecurringTransaction) error
+	UpdateRecurringTransaction(ctx context.Context, recurring *RecurringTransaction) error
+	DeleteRecurringTransaction(ctx context.Context, id string) error
+
+	ListRecurringOccurrences(ctx context.Context, recurringID string) ([]*RecurringOccurrence, error)
+	GetRecurringOccurrenceByID(ctx context.Context, recurringID, occurrenceID string) (*RecurringOccurrence, error)
+	CreateRecurringOccurrence(ctx context.Context, occurrence *RecurringOccurrence) (bool, error)
+	UpdateRecurringOccurrence(ctx context.Context, occurrence *RecurringOccurrence) error
+
+	ListDebtInstallments(ctx context.Context, debtID string) ([]*DebtInstallment, error)
+	ReplaceDebtInstallments(ctx context.Context, debtID string, installments []*DebtInstallment) error
+
+	ListDebtsForDueEvaluation(ctx context.Context) ([]*Debt, error)
+	TransitionDebtStatus(ctx context.Context, event *DebtStatusEvent) (bool, error)
+	ListDebtStatusEvents(ctx context.Context, limit int) ([]*DebtStatusEvent, error)
+	RecordDebtReminder(ctx context.Context, reminder *DebtReminder) (bool, error)
+
 	ListFXRates(ctx context.Context) ([]*FXRate, error)
 	GetFXRateByID(ctx context.Context, id string) (*FXRate, error)
 	CreateFXRate(ctx context.Context, rate *FXRate) error
+	UpsertFXRate(ctx context.Context, rate *FXRate) error
+	FindFXRate(ctx context.Context, fromCurrency, toCurrency, date string) (*FXRate, error)
+	ListLatestFXRates(ctx context.Context) ([]*FXRate, error)
 
 	ListCategories(ctx context.Context, categoryType string, activeOnly bool) ([]*FinanceCategory, error)
 	CreateCategory(ctx context.Context, category *FinanceCategory) error
@@ -60,33 +102,154 @@ type Repository interface {
 
 	ListQuickExpenseCategories(ctx context.Context, categoryType string) ([]*QuickExpenseCategory, error)
 	ReplaceQuickExpenseCategories(ctx context.Context, categoryType string, categories []*QuickExpenseCategory) error
+
+	ListStatementImportProfiles(ctx context.Context) ([]*StatementImportProfile, error)
+	GetStatementImportProfileByID(ctx context.Context, id string) (*StatementImportProfile, error)
+	CreateStatementImportProfile(ctx context.Context, profile *StatementImportProfile) error
+	DeleteStatementImportProfile(ctx context.Context, id string) error
+
+	ListStatementImports(ctx context.Context) ([]*StatementImport, error)
+	GetStatementImportByID(ctx context.Context, id string) (*StatementImport, error)
+	CreateStatementImport(ctx context.Context, batch *StatementImport, rows []*StatementImportRow) error
+	UpdateStatementImport(ctx context.Context, batch *StatementImport) error
+	ListStatementImportRows(ctx context.Context, importID string) ([]*StatementImportRow, error)
+	UpdateStatementImportRow(ctx context.Context, row *StatementImportRow) error
+	// UndoStatementImport reverses the entries of the rows marked undone,
+	// voids the ones still waiting to post and saves the rows and the batch.
+	// Either all of it happens or none of it does.
+	UndoStatementImport(ctx context.Context, batch *StatementImport, rows []*StatementImportRow, input TransactionReversalInput) error
+
+	ListSMSCardLinks(ctx context.Context) ([]*SMSCardLink, error)
+	CreateSMSCardLink(ctx context.Context, link *SMSCardLink) error
+	DeleteSMSCardLink(ctx context.Context, id string) error
+
+	ListSMSMessages(ctx context.Context, status string) ([]*SMSMessage, error)
+	GetSMSMessageByID(ctx context.Context, id string) (*SMSMessage, error)
+	GetSMSMessageByFingerprint(ctx context.Context, fingerprint string) (*SMSMessage, error)
+	CreateSMSMessage(ctx context.Context, message *SMSMessage) error
+	UpdateSMSMessage(ctx context.Context, message *SMSMessage) error
+
+	ListCategorizationRules(ctx context.Context) ([]*CategorizationRule, error)
+	GetCategorizationRuleByID(ctx context.Context, id string) (*CategorizationRule, error)
+	CreateCategorizationRule(ctx context.Context, rule *CategorizationRule) error
+	UpdateCategorizationRule(ctx context.Context, rule *CategorizationRule) error
+	DeleteCategorizationRule(ctx context.Context, id string) error
+
+	ListAttachments(ctx context.Context) ([]*Attachment, error)
+	GetAttachmentByID(ctx context.Context, id string) (*Attachment, error)
+	CreateAttachment(ctx context.Context, attachment *Attachment) error
+	DeleteAttachment(ctx context.Context, id string) error
+	AttachmentUsage(ctx context.Context) (int, int64, error)
+	AttachmentReferenced(ctx context.Context, id string) (bool, error)
+
+	ListReconciliations(ctx context.Context, accountID string) ([]*Reconciliation, error)
+	GetReconciliationByID(ctx context.Context, id string) (*Reconciliation, error)
+	CreateReconciliation(ctx context.Context, reconciliation *Reconciliation) error
+	UpdateReconciliation(ctx context.Context, reconciliation *Reconciliation) error
+	// CompleteReconciliation marks the reconciliation's entries cleared and
+	// posts the adjustment, if any, in one step.
+	CompleteReconciliation(ctx context.Context, reconciliation *Reconciliation, adjustment *Transaction) error
+
+	// ListLedgerUserIDs returns every user that owns accounts, budgets or
+	// debts, across all users.
+	ListLedgerUserIDs(ctx context.Context) ([]string, error)
+	// RecordLedgerRepair stores the audit record and posts the adjustment,
+	// if any, without moving the account balance. It fails with
+	// LedgerDriftChanged when the balance no longer matches StoredAmount.
+	RecordLedgerRepair(ctx context.Context, repair *LedgerRepair, adjustment *Transaction) error
+
+	// ListScheduledTransactions returns the user's scheduled entries that
+	// are still pending or failed, earliest first.
+	ListScheduledTransactions(ctx context.Context) ([]*Transaction, error)
+	// ListDueScheduledTransactions returns pending scheduled entries dated
+	// on or before asOf across all users, one row per entry: the incoming
+	// leg of a transfer is left out, and so are entries recorded from an SMS,
+	// which wait for the user.
+	ListDueScheduledTransactions(ctx context.Context, asOf string) ([]*Transaction, error)
+	// PostScheduledTransaction books a pending or failed scheduled entry,
+	// and both legs of a transfer, on date: it moves the balances, marks
+	// the entry completed and clears the scheduled flag.
+	PostScheduledTransaction(ctx context.Context, id, date string) error
+	// UpdateScheduledTransaction sets the status and date of an entry that
+	// has not been posted yet, on every leg of a transfer.
+	UpdateScheduledTransaction(ctx context.Context, id, status, date string) error
+
+	// ListCreditAccounts returns the credit accounts that have card terms,
+	// across all users.
+	ListCreditAccounts(ctx context.Context) ([]*Account, error)
+	// ListCreditStatements returns a card's statements, newest first.
+	ListCreditStatements(ctx context.Context, accountID string) ([]*CreditStatement, error)
+	// CreateCreditStatement stores a closed cycle and reports whether it was
+	// new; a cycle that already has a statement is left alone.
+	CreateCreditStatement(ctx context.Context, statement *CreditStatement) (bool, error)
+	// UpdateCreditStatement records the reminders sent for a statement.
+	UpdateCreditStatement(ctx context.Context, statement *CreditStatement) error
+
+	// MergeAccount moves everything pointing at merge.SourceAccountID to
+	// merge.TargetAccountID in one step, converting at rate unless it is
+	// zero, recomputes the target balance, archives the source and stores
+	// the filled-in merge record.
+	MergeAccount(ctx context.Context, merge *AccountMerge, rate ExchangeRate) error
 }
 
 // InMemoryRepository stores finance data in memory.
 type InMemoryRepository struct {
-	mu             sync.RWMutex
-	accounts       map[string]*Account
-	transactions   map[string]*Transaction
-	budgets        map[string]*Budget
-	debts          map[string]*Debt
-	debtPayments   map[string]map[string]*DebtPayment
-	counterparties map[string]*Counterparty
-	fxRates        map[string]*FXRate
-	categories     map[string]*FinanceCategory
-	quickExp       map[string][]*QuickExpenseCategory
+	mu              sync.RWMutex
+	accounts        map[string]*Account
+	transactions    map[string]*Transaction
+	budgets         map[string]*Budget
+	budgetPeriods   map[string][]*BudgetPeriod
+	budgetAlerts    map[string]*BudgetAlert
+	debts           map[string]*Debt
+	debtPayments    map[string]map[string]*DebtPayment
+	installments    map[string][]*DebtInstallment
+	debtEvents      []*DebtStatusEvent
+	debtReminders   map[string]*DebtReminder
+	counterparties  map[string]*Counterparty
+	recurring       map[string]*RecurringTransaction
+	occurrences     map[string]*RecurringOccurrence
+	fxRates         map[string]*FXRate
+	categories      map[string]*FinanceCategory
+	quickExp        map[string][]*QuickExpenseCategory
+	importProfiles  map[string]*StatementImportProfile
+	imports         map[string]*StatementImport
+	importRows      map[string][]*StatementImportRow
+	smsCardLinks    map[string]*SMSCardLink
+	smsMessages     map[string]*SMSMessage
+	rules           map[string]*CategorizationRule
+	attachments     map[string]*Attachment
+	reconciliations map[string]*Reconciliation
+	ledgerRepairs   []*LedgerRepair
+	statements      map[string]*CreditStatement
+	merges          []*AccountMerge
 }
 
 func NewInMemoryRepository() *InMemoryRepository {
 	return &InMemoryRepository{
-		accounts:       make(map[string]*Account),
-		transactions:   make(map[string]*Transaction),
-		budgets:        make(map[string]*Budget),
-		debts:          make(map[string]*Debt),
-		debtPayments:   make(map[string]map[string]*DebtPayment),
-		counterparties: make(map[string]*Counterparty),
-		fxRates:        make(map[string]*FXRate),
-		categories:     make(map[string]*FinanceCategory),
-		quickExp:       make(map[string][]*QuickExpenseCategory),
+		accounts:        make(map[string]*Account),
+		transactions:    make(map[string]*Transaction),
+		budgets:         make(map[string]*Budget),
+		budgetPeriods:   make(map[string][]*BudgetPeriod),
+		budgetAlerts:    make(map[string]*BudgetAlert),
+		debts:           make(map[string]*Debt),
+		debtPayments:    make(map[string]map[string]*DebtPayment),
+		installments:    make(map[string][]*DebtInstallment),
+		debtReminders:   make(map[string]*DebtReminder),
+		counterparties:  make(map[string]*Counterparty),
+		recurring:       make(map[string]*RecurringTransaction),
+		occurrences:     make(map[string]*RecurringOccurrence),
+		fxRates:         make(map[string]*FXRate),
+		categories:      make(map[string]*FinanceCategory),
+		quickExp:        make(map[string][]*QuickExpenseCategory),
+		importProfiles:  make(map[string]*StatementImportProfile),
+		imports:         make(map[string]*StatementImport),
+		importRows:      make(map[string][]*StatementImportRow),
+		smsCardLinks:    make(map[string]*SMSCardLink),
+		smsMessages:     make(map[string]*SMSMessage),
+		rules:           make(map[string]*CategorizationRule),
+		attachments:     make(map[string]*Attachment),
+		reconciliations: make(map[string]*Reconciliation),
+		statements:      make(map[string]*CreditStatement),
 	}
 }
 
@@ -214,6 +377,48 @@ func (r *InMemoryRepository) ListTransactions(ctx context.Context) ([]*Transacti
 	return results, nil
 }
 
+// ListTransactionsPage returns matching transactions in the filter's sort
+// order, starting after page.Cursor or at the page.Page offset. It returns up
+// to page.Limit+1 rows; the extra row tells the caller that another page
+// follows.
+func (r *InMemoryRepository) ListTransactionsPage(ctx context.Context, filter TransactionFilter, page TransactionPageRequest) ([]*Transaction, error) {
+	transactions, err := r.ListTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if page.Limit <= 0 {
+		page.Limit = defaultTransactionPageLimit
+	}
+	transactions = filterTransactions(transactions, filter)
+	sort.Slice(transactions, func(i, j int) bool {
+		return transactionFeedBefore(transactionCursorFor(transactions[i], filter), transactionCursorFor(transactions[j], filter))
+	})
+	start := 0
+	if page.Cursor != nil {
+		start = sort.Search(len(transactions), func(i int) bool {
+			return transactionFeedBefore(*page.Cursor, transactionCursorFor(transactions[i], filter))
+		})
+	} else if page.Page > 1 {
+		start = (page.Page - 1) * page.Limit
+	}
+	if start > len(transactions) {
+		start = len(transactions)
+	}
+	end := len(transactions)
+	if start+page.Limit+1 < end {
+		end = start + page.Limit + 1
+	}
+	return transactions[start:end], nil
+}
+
+func (r *InMemoryRepository) CountTransactions(ctx context.Context, filter TransactionFilter) (int, error) {
+	transactions, err := r.ListTransactions(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return len(filterTransactions(transactions, filter)), nil
+}
+
 func (r *InMemoryRepository) GetTransactionByID(ctx context.Context, id string) (*Transaction, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -227,6 +432,10 @@ func (r *InMemoryRepository) GetTransactionByID(ctx context.Context, id string)
 func (r *InMemoryRepository) CreateTransaction(ctx context.Context, txn *Transaction) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	return r.createTransactionLocked(ctx, txn)
+}
+
+func (r *InMemoryRepository) createTransactionLocked(ctx context.Context, txn *Transaction) error {
 	userID, _ := ctx.Value("user_id").(string)
 	if txn.Amount == 0 {
 		return appErrors.InvalidFinanceData
@@ -272,7 +481,10 @@ func (r *InMemoryRepository) CreateTransaction(ctx context.Context, txn *Transac
 		if txn.Currency != account.Currency {
 			return appErrors.InvalidFinanceData
 		}
-		if txn.Type == "expense" && account.CurrentBalance < txn.Amount {
+		if txn.IsScheduled {
+			break
+		}
+		if txn.Type == "expense" && availableFunds(account) < txn.Amount {
 			return appErrors.InsufficientFunds
 		}
 		if txn.Type == "income" {
@@ -300,7 +512,13 @@ func (r *InMemoryRepository) CreateTransaction(ctx context.Context, txn *Transac
 			return appErrors.AccountNotFound
 		}
 		if fromAccount.Currency != toAccount.Currency {
-			return appErrors.InvalidFinanceData
+			// Only an exchange between the currencies of one account says
+			// what arrives on the other side.
+			if walletID(fromAccount) != walletID(toAccount) || txn.ToAmount <= 0 {
+				return appErrors.InvalidFinanceData
+			}
+			toCurrency := toAccount.Currency
+			txn.ToCurrency = &toCurrency
 		}
 		if txn.Currency == "" {
 			txn.Currency = fromAccount.Currency
@@ -308,12 +526,15 @@ func (r *InMemoryRepository) CreateTransaction(ctx context.Context, txn *Transac
 		if txn.Currency != fromAccount.Currency {
 			return appErrors.InvalidFinanceData
 		}
-		if fromAccount.CurrentBalance < txn.Amount {
-			return appErrors.InsufficientFunds
-		}
 		if txn.ToAmount == 0 {
 			txn.ToAmount = txn.Amount
 		}
+		if txn.IsScheduled {
+			break
+		}
+		if availableFunds(fromAccount) < txn.Amount {
+			return appErrors.InsufficientFunds
+		}
 		fromAccount.CurrentBalance -= txn.Amount
 		toAccount.CurrentBalance += txn.ToAmount
 		r.accounts[fromAccount.ID] = fromAccount
@@ -342,7 +563,7 @@ func (r *InMemoryRepository) CreateTransaction(ctx context.Context, txn *Transac
 		if txn.Currency != account.Currency {
 			return appErrors.InvalidFinanceData
 		}
-		if txn.Amount < 0 && account.CurrentBalance < -txn.Amount {
+		if txn.Amount < 0 && availableFunds(account) < -txn.Amount {
 			return appErrors.InsufficientFunds
 		}
 		account.CurrentBalance += txn.Amount
@@ -361,12 +582,48 @@ func (r *InMemoryRepository) UpdateTransaction(ctx context.Context, txn *Transac
 	if !ok || current == nil || current.DeletedAt != "" {
 		return appErrors.TransactionNotFound
 	}
+	if current.ReconciliationID != nil {
+		return appErrors.TransactionLocked
+	}
 	txn.CreatedAt = current.CreatedAt
 	txn.UpdatedAt = utils.NowUTC()
 	r.transactions[txn.ID] = cloneTransaction(txn)
 	return nil
 }
 
+func (r *InMemoryRepository) ReclassifyTransactions(ctx context.Context, updates []*Transaction) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	skipped := make([]string, 0)
+	now := utils.NowUTC()
+	for _, update := range updates {
+		current, ok := r.transactions[update.ID]
+		if !ok || current == nil || current.DeletedAt != "" || current.Status == TransactionStatusVoided || current.ReconciliationID != nil {
+			skipped = append(skipped, update.ID)
+			continue
+		}
+		current.CategoryID = update.CategoryID
+		current.SubcategoryID = update.SubcategoryID
+		current.BudgetID = update.BudgetID
+		current.CounterpartyID = update.CounterpartyID
+		current.Tags = append([]string(nil), update.Tags...)
+		current.UpdatedAt = now
+	}
+	return skipped, nil
+}
+
+func (r *InMemoryRepository) UpdateTransactionAttachments(ctx context.Context, id string, attachments []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.transactions[id]
+	if !ok || current == nil || current.DeletedAt != "" {
+		return appErrors.TransactionNotFound
+	}
+	current.Attachments = append([]string(nil), attachments...)
+	current.UpdatedAt = utils.NowUTC()
+	return nil
+}
+
 func (r *InMemoryRepository) DeleteTransaction(ctx context.Context, id string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -380,6 +637,168 @@ func (r *InMemoryRepository) DeleteTransaction(ctx context.Context, id string) e
 	return nil
 }
 
+func (r *InMemoryRepository) ReverseTransaction(ctx context.Context, id string, input TransactionReversalInput) (*TransactionReversal, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	original, ok := r.transactions[id]
+	if !ok || original == nil || original.DeletedAt != "" {
+		return nil, appErrors.TransactionNotFound
+	}
+	if original.Status == TransactionStatusVoided {
+		return nil, appErrors.TransactionAlreadyVoided
+	}
+	if !isReversibleTransactionType(original.Type) {
+		return nil, appErrors.WithDetails(appErrors.TransactionNotReversible, map[string]interface{}{"type": original.Type})
+	}
+
+	entries := r.referenceGroupLocked(original)
+	snapshot := r.snapshotLedgerLocked()
+	result := &TransactionReversal{
+		Voided:    make([]*Transaction, 0, len(entries)),
+		Reversals: make([]*Transaction, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		if entry.Status == TransactionStatusVoided {
+			r.restoreLedgerLocked(snapshot)
+			return nil, appErrors.TransactionAlreadyVoided
+		}
+		reversals, err := r.reverseEntryLocked(entry, input)
+		if err != nil {
+			r.restoreLedgerLocked(snapshot)
+			return nil, err
+		}
+		result.Voided = append(result.Voided, cloneTransaction(entry))
+		result.Reversals = append(result.Reversals, reversals...)
+	}
+	if input.Replacement != nil {
+		if err := r.createTransactionLocked(ctx, input.Replacement); err != nil {
+			r.restoreLedgerLocked(snapshot)
+			return nil, err
+		}
+		result.Correction = input.Replacement
+	}
+	return result, nil
+}
+
+// referenceGroupLocked returns original together with the other legs of its
+// transfer, oldest first.
+func (r *InMemoryRepository) referenceGroupLocked(original *Transaction) []*Transaction {
+	if original.ReferenceType == nil || *original.ReferenceType != "transfer" || original.ReferenceID == nil {
+		return []*Transaction{original}
+	}
+	entries := make([]*Transaction, 0, 2)
+	for _, txn := range r.transactions {
+		if txn == nil || txn.DeletedAt != "" || txn.ReferenceType == nil || txn.ReferenceID == nil {
+			continue
+		}
+		if *txn.ReferenceType == "transfer" && *txn.ReferenceID == *original.ReferenceID {
+			entries = append(entries, txn)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return utils.ParseRFC3339(entries[i].CreatedAt).Before(utils.ParseRFC3339(entries[j].CreatedAt))
+	})
+	return entries
+}
+
+func (r *InMemoryRepository) reverseEntryLocked(entry *Transaction, input TransactionReversalInput) ([]*Transaction, error) {
+	if entry.ReconciliationID != nil {
+		return nil, appErrors.TransactionLocked
+	}
+	now := utils.NowUTC()
+	reversals := make([]*Transaction, 0, 2)
+	for _, accountID := range transactionAccountIDs(entry) {
+		delta := transactionDeltaForAccount(accountID, entry)
+		if delta == 0 {
+			continue
+		}
+		account, ok := r.accounts[accountID]
+		if !ok || account == nil || account.DeletedAt != "" {
+			return nil, appErrors.AccountNotFound
+		}
+		if delta > 0 && availableFunds(account) < delta {
+			return nil, appErrors.InsufficientFunds
+		}
+		reversal := buildReversalTransaction(entry, accountID, account.Currency, -delta, input)
+		reversal.ID = uuid.NewString()
+		reversal.UserID = entry.UserID
+		reversal.CreatedAt = now
+		reversal.UpdatedAt = now
+		account.CurrentBalance -= delta
+		r.accounts[account.ID] = account
+		r.transactions[reversal.ID] = cloneTransaction(reversal)
+		reversals = append(reversals, reversal)
+	}
+
+	if entry.DebtID != nil {
+		if debt, ok := r.debts[*entry.DebtID]; ok && debt != nil && debt.DeletedAt == "" {
+			switch entry.Type {
+			case TransactionTypeDebtPayment, TransactionTypeDebtFullPayment:
+				for _, payment := range r.debtPayments[debt.ID] {
+					if payment == nil || payment.DeletedAt != "" || payment.RelatedTransactionID == nil {
+						continue
+					}
+					if *payment.RelatedTransactionID == entry.ID {
+						payment.DeletedAt = now
+						payment.UpdatedAt = now
+					}
+				}
+				if debt.Status == "paid" {
+					debt.Status = "active"
+					debt.SettledAt = nil
+				}
+			case TransactionTypeDebtAddValue:
+				debt.PrincipalAmount = max(debt.PrincipalAmount-entry.OriginalAmount.Abs(), 0)
+				debt.PrincipalBaseValue = debtPrincipalBaseValue(debt)
+			}
+			debt.UpdatedAt = now
+		}
+	}
+
+	markTransactionVoided(entry, reversals, input.Note)
+	return reversals, nil
+}
+
+type inMemoryLedgerSnapshot struct {
+	accounts     map[string]*Account
+	transactions map[string]*Transaction
+	debts        map[string]*Debt
+	debtPayments map[string]map[string]*DebtPayment
+}
+
+func (r *InMemoryRepository) snapshotLedgerLocked() inMemoryLedgerSnapshot {
+	snapshot := inMemoryLedgerSnapshot{
+		accounts:     make(map[string]*Account, len(r.accounts)),
+		transactions: make(map[string]*Transaction, len(r.transactions)),
+		debts:        make(map[string]*Debt, len(r.debts)),
+		debtPayments: make(map[string]map[string]*DebtPayment, len(r.debtPayments)),
+	}
+	for id, account := range r.accounts {
+		snapshot.accounts[id] = cloneAccount(account)
+	}
+	for id, txn := range r.transactions {
+		snapshot.transactions[id] = cloneTransaction(txn)
+	}
+	for id, debt := range r.debts {
+		snapshot.debts[id] = cloneDebt(debt)
+	}
+	for debtID, payments := range r.debtPayments {
+		copied := make(map[string]*DebtPayment, len(payments))
+		for id, payment := range payments {
+			copied[id] = cloneDebtPayment(payment)
+		}
+		snapshot.debtPayments[debtID] = copied
+	}
+	return snapshot
+}
+
+func (r *InMemoryRepository) restoreLedgerLocked(snapshot inMemoryLedgerSnapshot) {
+	r.accounts = snapshot.accounts
+	r.transactions = snapshot.transactions
+	r.debts = snapshot.debts
+	r.debtPayments = snapshot.debtPayments
+}
+
 func (r *InMemoryRepository) ListBudgets(ctx context.Context) ([]*Budget, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -427,11 +846,105 @@ func (r *InMemoryRepository) UpdateBudget(ctx context.Context, budget *Budget) e
 		return appErrors.BudgetNotFound
 	}
 	budget.CreatedAt = current.CreatedAt
+	budget.CarriedAmount = current.CarriedAmount
 	budget.UpdatedAt = utils.NowUTC()
 	r.budgets[budget.ID] = cloneBudget(budget)
 	return nil
 }
 
+func (r *InMemoryRepository) UpdateBudgetRollups(ctx context.Context, budget *Budget) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.budgets[budget.ID]
+	if !ok || current == nil || current.DeletedAt != "" {
+		return appErrors.BudgetNotFound
+	}
+	current.SpentAmount = budget.SpentAmount
+	current.RemainingAmount = budget.RemainingAmount
+	current.PercentUsed = budget.PercentUsed
+	current.IsOverspent = budget.IsOverspent
+	current.UpdatedAt = utils.NowUTC()
+	return nil
+}
+
+func (r *InMemoryRepository) ListBudgetPeriods(ctx context.Context, budgetID string) ([]*BudgetPeriod, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	results := make([]*BudgetPeriod, 0, len(r.budgetPeriods[budgetID]))
+	for _, period := range r.budgetPeriods[budgetID] {
+		copy := *period
+		results = append(results, &copy)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].PeriodStart > results[j].PeriodStart
+	})
+	return results, nil
+}
+
+func (r *InMemoryRepository) RecordBudgetAlert(ctx context.Context, alert *BudgetAlert) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := alert.BudgetID + "|" + alert.PeriodStart + "|" + alert.Threshold
+	if _, exists := r.budgetAlerts[key]; exists {
+		return false, nil
+	}
+	if alert.ID == "" {
+		alert.ID = uuid.NewString()
+	}
+	if userID, ok := ctx.Value("user_id").(string); ok {
+		alert.UserID = userID
+	}
+	alert.CreatedAt = utils.NowUTC()
+	copy := *alert
+	r.budgetAlerts[key] = &copy
+	return true, nil
+}
+
+func (r *InMemoryRepository) ListBudgetsDueForClose(ctx context.Context, asOf string) ([]*Budget, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	results := make([]*Budget, 0)
+	for _, budget := range r.budgets {
+		if budget == nil || budget.DeletedAt != "" || budget.ShowStatus != "active" {
+			continue
+		}
+		if !isRollingBudgetPeriod(budget.PeriodType) || budget.EndDate == nil || *budget.EndDate >= asOf {
+			continue
+		}
+		results = append(results, cloneBudget(budget))
+	}
+	return results, nil
+}
+
+func (r *InMemoryRepository) CloseBudgetPeriod(ctx context.Context, budget *Budget, period *BudgetPeriod) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.budgets[budget.ID]
+	if !ok || current == nil || current.DeletedAt != "" {
+		return appErrors.BudgetNotFound
+	}
+	for _, existing := range r.budgetPeriods[budget.ID] {
+		if existing.PeriodStart == period.PeriodStart {
+			return appErrors.BudgetPeriodClosed
+		}
+	}
+	if period.ID == "" {
+		period.ID = uuid.NewString()
+	}
+	now := utils.NowUTC()
+	period.BudgetID = budget.ID
+	period.UserID = current.UserID
+	period.ClosedAt = now
+	period.CreatedAt = now
+	copy := *period
+	r.budgetPeriods[budget.ID] = append(r.budgetPeriods[budget.ID], &copy)
+
+	budget.CreatedAt = current.CreatedAt
+	budget.UpdatedAt = now
+	r.budgets[budget.ID] = cloneBudget(budget)
+	return nil
+}
+
 func (r *InMemoryRepository) DeleteBudget(ctx context.Context, id string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -579,7 +1092,7 @@ func (r *InMemoryRepository) CreateDebtPayment(ctx context.Context, payment *Deb
 	default:
 		return appErrors.InvalidFinanceData
 	}
-	if delta < 0 && account.CurrentBalance < -delta {
+	if delta < 0 && availableFunds(account) < -delta {
 		return appErrors.InsufficientFunds
 	}
 	account.CurrentBalance += delta
@@ -630,7 +1143,7 @@ func (r *InMemoryRepository) CreateDebtPayment(ctx context.Context, payment *Deb
 	// Update debt totals in debt currency.
 	paymentInDebt := payment.ConvertedAmountToDebt
 	if paymentInDebt == 0 && payment.RateUsedToDebt > 0 {
-		paymentInDebt = payment.Amount * payment.RateUsedToDebt
+		paymentInDebt = exchangeRateFromFloat(payment.RateUsedToDebt).Convert(payment.Amount, debt.PrincipalCurrency)
 	}
 	remaining := debt.RemainingAmount
 	if remaining <= 0 {
@@ -649,7 +1162,7 @@ func (r *InMemoryRepository) CreateDebtPayment(ctx context.Context, payment *Deb
 	}
 	percentPaid := 0.0
 	if debt.PrincipalAmount > 0 {
-		percentPaid = (totalPaid / debt.PrincipalAmount) * 100
+		percentPaid = totalPaid.Ratio(debt.PrincipalAmount) * 100
 		if percentPaid > 100 {
 			percentPaid = 100
 		}
@@ -734,7 +1247,7 @@ func (r *InMemoryRepository) UpdateDebtPayment(ctx context.Context, payment *Deb
 		}
 		percentPaid := 0.0
 		if debt.PrincipalAmount > 0 {
-			percentPaid = (totalPaid / debt.PrincipalAmount) * 100
+			percentPaid = totalPaid.Ratio(debt.PrincipalAmount) * 100
 			if percentPaid > 100 {
 				percentPaid = 100
 			}
@@ -806,7 +1319,7 @@ func (r *InMemoryRepository) DeleteDebtPayment(ctx context.Context, debtID, paym
 	}
 	percentPaid := 0.0
 	if debt.PrincipalAmount > 0 {
-		percentPaid = (totalPaid / debt.PrincipalAmount) * 100
+		percentPaid = totalPaid.Ratio(debt.PrincipalAmount) * 100
 		if percentPaid > 100 {
 			percentPaid = 100
 		}
@@ -899,133 +1412,1375 @@ func (r *InMemoryRepository) DeleteCounterparty(ctx context.Context, id string)
 	return nil
 }
 
-func (r *InMemoryRepository) ListFXRates(ctx context.Context) ([]*FXRate, error) {
+func (r *InMemoryRepository) ListDebtInstallments(ctx context.Context, debtID string) ([]*DebtInstallment, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	results := make([]*FXRate, 0, len(r.fxRates))
-	for _, rate := range r.fxRates {
-		if rate == nil {
+	results := make([]*DebtInstallment, 0, len(r.installments[debtID]))
+	for _, installment := range r.installments[debtID] {
+		copy := *installment
+		results = append(results, &copy)
+	}
+	return results, nil
+}
+
+func (r *InMemoryRepository) ReplaceDebtInstallments(ctx context.Context, debtID string, installments []*DebtInstallment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	debt, ok := r.debts[debtID]
+	if !ok || debt == nil || debt.DeletedAt != "" {
+		return appErrors.DebtNotFound
+	}
+	now := utils.NowUTC()
+	next := make([]*DebtInstallment, 0, len(installments))
+	for _, installment := range installments {
+		if installment.ID == "" {
+			installment.ID = uuid.NewString()
+		}
+		installment.DebtID = debtID
+		installment.UserID = debt.UserID
+		installment.CreatedAt = now
+		installment.UpdatedAt = now
+		copy := *installment
+		next = append(next, &copy)
+	}
+	r.installments[debtID] = next
+	return nil
+}
+
+func (r *InMemoryRepository) ListDebtsForDueEvaluation(ctx context.Context) ([]*Debt, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	results := make([]*Debt, 0)
+	for _, debt := range r.debts {
+		if debt == nil || debt.DeletedAt != "" || debt.ShowStatus != "active" {
 			continue
 		}
-		results = append(results, cloneFXRate(rate))
+		if debt.Status == DebtStatusPaid || debt.Status == DebtStatusCanceled {
+			continue
+		}
+		results = append(results, cloneDebt(debt))
 	}
 	sort.Slice(results, func(i, j int) bool {
-		return utils.ParseRFC3339(results[i].CreatedAt).After(utils.ParseRFC3339(results[j].CreatedAt))
+		return results[i].ID < results[j].ID
 	})
 	return results, nil
 }
 
-func (r *InMemoryRepository) GetFXRateByID(ctx context.Context, id string) (*FXRate, error) {
+func (r *InMemoryRepository) TransitionDebtStatus(ctx context.Context, event *DebtStatusEvent) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	debt, ok := r.debts[event.DebtID]
+	if !ok || debt == nil || debt.DeletedAt != "" {
+		return false, appErrors.DebtNotFound
+	}
+	if debt.Status != event.FromStatus {
+		return false, nil
+	}
+	if event.ID == "" {
+		event.ID = uuid.NewString()
+	}
+	if event.OccurredAt == "" {
+		event.OccurredAt = utils.NowUTC()
+	}
+	event.UserID = debt.UserID
+	debt.Status = event.ToStatus
+	debt.UpdatedAt = event.OccurredAt
+	copy := *event
+	r.debtEvents = append(r.debtEvents, &copy)
+	return true, nil
+}
+
+func (r *InMemoryRepository) ListDebtStatusEvents(ctx context.Context, limit int) ([]*DebtStatusEvent, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	rate, ok := r.fxRates[id]
-	if !ok || rate == nil {
-		return nil, appErrors.FXRateNotFound
+	results := make([]*DebtStatusEvent, 0, len(r.debtEvents))
+	for i := len(r.debtEvents) - 1; i >= 0; i-- {
+		if limit > 0 && len(results) >= limit {
+			break
+		}
+		copy := *r.debtEvents[i]
+		results = append(results, &copy)
 	}
-	return cloneFXRate(rate), nil
+	return results, nil
 }
 
-func (r *InMemoryRepository) CreateFXRate(ctx context.Context, rate *FXRate) error {
+func (r *InMemoryRepository) RecordDebtReminder(ctx context.Context, reminder *DebtReminder) (bool, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if rate.ID == "" {
-		rate.ID = uuid.NewString()
+	key := reminder.DebtID + "|" + reminder.ReminderDate
+	if _, exists := r.debtReminders[key]; exists {
+		return false, nil
 	}
-	now := utils.NowUTC()
-	rate.CreatedAt = now
-	rate.UpdatedAt = now
-	r.fxRates[rate.ID] = cloneFXRate(rate)
-	return nil
+	if reminder.ID == "" {
+		reminder.ID = uuid.NewString()
+	}
+	if userID, ok := ctx.Value("user_id").(string); ok {
+		reminder.UserID = userID
+	}
+	reminder.CreatedAt = utils.NowUTC()
+	copy := *reminder
+	r.debtReminders[key] = &copy
+	return true, nil
 }
 
-func (r *InMemoryRepository) ListCategories(ctx context.Context, categoryType string, activeOnly bool) ([]*FinanceCategory, error) {
+func (r *InMemoryRepository) ListRecurringTransactions(ctx context.Context) ([]*RecurringTransaction, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	results := make([]*FinanceCategory, 0, len(r.categories))
-	for _, category := range r.categories {
-		if category == nil {
+	results := make([]*RecurringTransaction, 0, len(r.recurring))
+	for _, recurring := range r.recurring {
+		if recurring == nil || recurring.DeletedAt != "" {
 			continue
 		}
-		if categoryType != "" && category.Type != categoryType {
+		results = append(results, cloneRecurringTransaction(recurring))
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return utils.ParseRFC3339(results[i].CreatedAt).After(utils.ParseRFC3339(results[j].CreatedAt))
+	})
+	return results, nil
+}
+
+func (r *InMemoryRepository) ListDueRecurringTransactions(ctx context.Context, asOf string) ([]*RecurringTransaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	results := make([]*RecurringTransaction, 0)
+	for _, recurring := range r.recurring {
+		if recurring == nil || recurring.DeletedAt != "" || recurring.Status != RecurringStatusActive {
 			continue
 		}
-		if activeOnly && !category.IsActive {
+		if recurring.NextOccurrenceDate == nil || *recurring.NextOccurrenceDate > asOf {
 			continue
 		}
-		copy := *category
-		results = append(results, &copy)
+		results = append(results, cloneRecurringTransaction(recurring))
 	}
 	sort.Slice(results, func(i, j int) bool {
-		if results[i].SortOrder == results[j].SortOrder {
-			return strings.Compare(results[i].ID, results[j].ID) < 0
-		}
-		return results[i].SortOrder < results[j].SortOrder
+		return *results[i].NextOccurrenceDate < *results[j].NextOccurrenceDate
 	})
 	return results, nil
 }
 
-func (r *InMemoryRepository) CreateCategory(ctx context.Context, category *FinanceCategory) error {
+func (r *InMemoryRepository) GetRecurringTransactionByID(ctx context.Context, id string) (*RecurringTransaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	recurring, ok := r.recurring[id]
+	if !ok || recurring == nil || recurring.DeletedAt != "" {
+		return nil, appErrors.RecurringNotFound
+	}
+	return cloneRecurringTransaction(recurring), nil
+}
+
+func (r *InMemoryRepository) CreateRecurringTransaction(ctx context.Context, recurring *RecurringTransaction) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if category.ID == "" {
-		category.ID = uuid.NewString()
+	if userID, ok := ctx.Value("user_id").(string); ok && userID != "" {
+		recurring.UserID = userID
+	}
+	if recurring.ID == "" {
+		recurring.ID = uuid.NewString()
 	}
 	now := utils.NowUTC()
-	category.CreatedAt = now
-	category.UpdatedAt = now
-	copy := *category
-	r.categories[category.ID] = &copy
+	recurring.CreatedAt = now
+	recurring.UpdatedAt = now
+	r.recurring[recurring.ID] = cloneRecurringTransaction(recurring)
 	return nil
 }
 
-func (r *InMemoryRepository) UpdateCategory(ctx context.Context, category *FinanceCategory) error {
+func (r *InMemoryRepository) UpdateRecurringTransaction(ctx context.Context, recurring *RecurringTransaction) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if _, ok := r.categories[category.ID]; !ok {
-		return appErrors.InvalidFinanceData
+	current, ok := r.recurring[recurring.ID]
+	if !ok || current == nil || current.DeletedAt != "" {
+		return appErrors.RecurringNotFound
 	}
-	category.UpdatedAt = utils.NowUTC()
-	copy := *category
-	r.categories[category.ID] = &copy
+	recurring.UserID = current.UserID
+	recurring.CreatedAt = current.CreatedAt
+	recurring.UpdatedAt = utils.NowUTC()
+	r.recurring[recurring.ID] = cloneRecurringTransaction(recurring)
 	return nil
 }
 
-func (r *InMemoryRepository) ListQuickExpenseCategories(ctx context.Context, categoryType string) ([]*QuickExpenseCategory, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	userID, _ := ctx.Value("user_id").(string)
-	key := quickExpenseKey(userID, categoryType)
+func (r *InMemoryRepository) DeleteRecurringTransaction(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	recurring, ok := r.recurring[id]
+	if !ok || recurring == nil || recurring.DeletedAt != "" {
+		return appErrors.RecurringNotFound
+	}
+	recurring.DeletedAt = utils.NowUTC()
+	recurring.UpdatedAt = recurring.DeletedAt
+	return nil
+}
+
+func (r *InMemoryRepository) ListRecurringOccurrences(ctx context.Context, recurringID string) ([]*RecurringOccurrence, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	results := make([]*RecurringOccurrence, 0)
+	for _, occurrence := range r.occurrences {
+		if occurrence == nil || occurrence.RecurringID != recurringID {
+			continue
+		}
+		copy := *occurrence
+		results = append(results, &copy)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].DueDate > results[j].DueDate
+	})
+	return results, nil
+}
+
+func (r *InMemoryRepository) GetRecurringOccurrenceByID(ctx context.Context, recurringID, occurrenceID string) (*RecurringOccurrence, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	occurrence, ok := r.occurrences[occurrenceID]
+	if !ok || occurrence == nil || occurrence.RecurringID != recurringID {
+		return nil, appErrors.RecurringOccurrenceNotFound
+	}
+	copy := *occurrence
+	return &copy, nil
+}
+
+func (r *InMemoryRepository) CreateRecurringOccurrence(ctx context.Context, occurrence *RecurringOccurrence) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.occurrences {
+		if existing.RecurringID == occurrence.RecurringID && existing.DueDate == occurrence.DueDate {
+			return false, nil
+		}
+	}
+	if occurrence.ID == "" {
+		occurrence.ID = uuid.NewString()
+	}
+	now := utils.NowUTC()
+	occurrence.CreatedAt = now
+	occurrence.UpdatedAt = now
+	copy := *occurrence
+	r.occurrences[occurrence.ID] = &copy
+	return true, nil
+}
+
+func (r *InMemoryRepository) UpdateRecurringOccurrence(ctx context.Context, occurrence *RecurringOccurrence) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.occurrences[occurrence.ID]
+	if !ok || current == nil {
+		return appErrors.RecurringOccurrenceNotFound
+	}
+	occurrence.CreatedAt = current.CreatedAt
+	occurrence.UpdatedAt = utils.NowUTC()
+	copy := *occurrence
+	r.occurrences[occurrence.ID] = &copy
+	return nil
+}
+
+func (r *InMemoryRepository) ListFXRates(ctx context.Context) ([]*FXRate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	results := make([]*FXRate, 0, len(r.fxRates))
+	for _, rate := range r.fxRates {
+		if rate == nil {
+			continue
+		}
+		results = append(results, cloneFXRate(rate))
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return utils.ParseRFC3339(results[i].CreatedAt).After(utils.ParseRFC3339(results[j].CreatedAt))
+	})
+	return results, nil
+}
+
+func (r *InMemoryRepository) GetFXRateByID(ctx context.Context, id string) (*FXRate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rate, ok := r.fxRates[id]
+	if !ok || rate == nil {
+		return nil, appErrors.FXRateNotFound
+	}
+	return cloneFXRate(rate), nil
+}
+
+func (r *InMemoryRepository) CreateFXRate(ctx context.Context, rate *FXRate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if rate.ID == "" {
+		rate.ID = uuid.NewString()
+	}
+	now := utils.NowUTC()
+	rate.CreatedAt = now
+	rate.UpdatedAt = now
+	r.fxRates[rate.ID] = cloneFXRate(rate)
+	return nil
+}
+
+// UpsertFXRate replaces the rate with the same date, pair and source, or
+// stores a new one.
+func (r *InMemoryRepository) UpsertFXRate(ctx context.Context, rate *FXRate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := utils.NowUTC()
+	for _, existing := range r.fxRates {
+		if existing.Date == rate.Date && existing.Source == rate.Source &&
+			strings.EqualFold(existing.FromCurrency, rate.FromCurrency) && strings.EqualFold(existing.ToCurrency, rate.ToCurrency) {
+			rate.ID = existing.ID
+			rate.CreatedAt = existing.CreatedAt
+			rate.UpdatedAt = now
+			r.fxRates[rate.ID] = cloneFXRate(rate)
+			return nil
+		}
+	}
+	if rate.ID == "" {
+		rate.ID = uuid.NewString()
+	}
+	rate.CreatedAt = now
+	rate.UpdatedAt = now
+	r.fxRates[rate.ID] = cloneFXRate(rate)
+	return nil
+}
+
+// FindFXRate returns the newest usable rate for the pair on or before date,
+// or the earliest one after it when the pair has no earlier history. On the
+// same day a manual rate wins over provider rates.
+func (r *InMemoryRepository) FindFXRate(ctx context.Context, fromCurrency, toCurrency, date string) (*FXRate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var before, after *FXRate
+	for _, rate := range r.fxRates {
+		if rate == nil || rate.Date == "" || (rate.RateMid <= 0 && rate.Rate <= 0 && rate.RateBid <= 0) {
+			continue
+		}
+		if !strings.EqualFold(rate.FromCurrency, fromCurrency) || !strings.EqualFold(rate.ToCurrency, toCurrency) {
+			continue
+		}
+		if rate.Date <= date {
+			if before == nil || rate.Date > before.Date || (rate.Date == before.Date && fxRatePreferred(rate, before)) {
+				before = rate
+			}
+			continue
+		}
+		if after == nil || rate.Date < after.Date || (rate.Date == after.Date && fxRatePreferred(rate, after)) {
+			after = rate
+		}
+	}
+	if before != nil {
+		return cloneFXRate(before), nil
+	}
+	if after != nil {
+		return cloneFXRate(after), nil
+	}
+	return nil, appErrors.FXRateNotFound
+}
+
+func fxRatePreferred(candidate, current *FXRate) bool {
+	candidateManual := candidate.Source == FXSourceManual
+	currentManual := current.Source == FXSourceManual
+	if candidateManual != currentManual {
+		return candidateManual
+	}
+	return candidate.CreatedAt > current.CreatedAt
+}
+
+// ListLatestFXRates returns the newest rate per source and currency pair.
+func (r *InMemoryRepository) ListLatestFXRates(ctx context.Context) ([]*FXRate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	latest := make(map[string]*FXRate)
+	for _, rate := range r.fxRates {
+		if rate == nil {
+			continue
+		}
+		key := rate.Source + "|" + strings.ToUpper(rate.FromCurrency) + "|" + strings.ToUpper(rate.ToCurrency)
+		if current, ok := latest[key]; !ok || rate.Date > current.Date {
+			latest[key] = rate
+		}
+	}
+	results := make([]*FXRate, 0, len(latest))
+	for _, rate := range latest {
+		results = append(results, cloneFXRate(rate))
+	}
+	return results, nil
+}
+
+func (r *InMemoryRepository) ListCategories(ctx context.Context, categoryType string, activeOnly bool) ([]*FinanceCategory, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	results := make([]*FinanceCategory, 0, len(r.categories))
+	for _, category := range r.categories {
+		if category == nil {
+			continue
+		}
+		if categoryType != "" && category.Type != categoryType {
+			continue
+		}
+		if activeOnly && !category.IsActive {
+			continue
+		}
+		copy := *category
+		results = append(results, &copy)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].SortOrder == results[j].SortOrder {
+			return strings.Compare(results[i].ID, results[j].ID) < 0
+		}
+		return results[i].SortOrder < results[j].SortOrder
+	})
+	return results, nil
+}
+
+func (r *InMemoryRepository) CreateCategory(ctx context.Context, category *FinanceCategory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if category.ID == "" {
+		category.ID = uuid.NewString()
+	}
+	now := utils.NowUTC()
+	category.CreatedAt = now
+	category.UpdatedAt = now
+	copy := *category
+	r.categories[category.ID] = &copy
+	return nil
+}
+
+func (r *InMemoryRepository) UpdateCategory(ctx context.Context, category *FinanceCategory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.categories[category.ID]; !ok {
+		return appErrors.InvalidFinanceData
+	}
+	category.UpdatedAt = utils.NowUTC()
+	copy := *category
+	r.categories[category.ID] = &copy
+	return nil
+}
+
+func (r *InMemoryRepository) ListQuickExpenseCategories(ctx context.Context, categoryType string) ([]*QuickExpenseCategory, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	userID, _ := ctx.Value("user_id").(string)
+	key := quickExpenseKey(userID, categoryType)
 	saved := r.quickExp[key]
 	results := make([]*QuickExpenseCategory, 0, len(saved))
 	for _, entry := range saved {
 		if entry == nil {
 			continue
 		}
-		copy := *entry
-		results = append(results, &copy)
+		copy := *entry
+		results = append(results, &copy)
+	}
+	return results, nil
+}
+
+func (r *InMemoryRepository) ReplaceQuickExpenseCategories(ctx context.Context, categoryType string, categories []*QuickExpenseCategory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	userID, _ := ctx.Value("user_id").(string)
+	key := quickExpenseKey(userID, categoryType)
+	next := make([]*QuickExpenseCategory, 0, len(categories))
+	for _, entry := range categories {
+		if entry == nil {
+			continue
+		}
+		copy := *entry
+		next = append(next, &copy)
+	}
+	r.quickExp[key] = next
+	return nil
+}
+
+func quickExpenseKey(userID, categoryType string) string {
+	return strings.TrimSpace(userID) + ":" + strings.ToLower(strings.TrimSpace(categoryType))
+}
+
+func (r *InMemoryRepository) ListStatementImportProfiles(ctx context.Context) ([]*StatementImportProfile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	results := make([]*StatementImportProfile, 0, len(r.importProfiles))
+	for _, profile := range r.importProfiles {
+		copy := *profile
+		results = append(results, &copy)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+	return results, nil
+}
+
+func (r *InMemoryRepository) GetStatementImportProfileByID(ctx context.Context, id string) (*StatementImportProfile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	profile, ok := r.importProfiles[id]
+	if !ok {
+		return nil, appErrors.StatementImportProfileNotFound
+	}
+	copy := *profile
+	return &copy, nil
+}
+
+func (r *InMemoryRepository) CreateStatementImportProfile(ctx context.Context, profile *StatementImportProfile) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if profile.ID == "" {
+		profile.ID = uuid.NewString()
+	}
+	if userID, ok := ctx.Value("user_id").(string); ok {
+		profile.UserID = userID
+	}
+	now := utils.NowUTC()
+	profile.CreatedAt = now
+	profile.UpdatedAt = now
+	copy := *profile
+	r.importProfiles[profile.ID] = &copy
+	return nil
+}
+
+func (r *InMemoryRepository) DeleteStatementImportProfile(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.importProfiles[id]; !ok {
+		return appErrors.StatementImportProfileNotFound
+	}
+	delete(r.importProfiles, id)
+	return nil
+}
+
+func (r *InMemoryRepository) ListStatementImports(ctx context.Context) ([]*StatementImport, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	results := make([]*StatementImport, 0, len(r.imports))
+	for _, batch := range r.imports {
+		copy := *batch
+		results = append(results, &copy)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].CreatedAt > results[j].CreatedAt
+	})
+	return results, nil
+}
+
+func (r *InMemoryRepository) GetStatementImportByID(ctx context.Context, id string) (*StatementImport, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	batch, ok := r.imports[id]
+	if !ok {
+		return nil, appErrors.StatementImportNotFound
+	}
+	copy := *batch
+	return &copy, nil
+}
+
+func (r *InMemoryRepository) CreateStatementImport(ctx context.Context, batch *StatementImport, rows []*StatementImportRow) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if batch.ID == "" {
+		batch.ID = uuid.NewString()
+	}
+	if userID, ok := ctx.Value("user_id").(string); ok {
+		batch.UserID = userID
+	}
+	now := utils.NowUTC()
+	batch.CreatedAt = now
+	batch.UpdatedAt = now
+	stored := make([]*StatementImportRow, 0, len(rows))
+	for _, row := range rows {
+		if row.ID == "" {
+			row.ID = uuid.NewString()
+		}
+		row.ImportID = batch.ID
+		row.UserID = batch.UserID
+		row.CreatedAt = now
+		row.UpdatedAt = now
+		copy := *row
+		stored = append(stored, &copy)
+	}
+	copy := *batch
+	copy.Rows = nil
+	r.imports[batch.ID] = &copy
+	r.importRows[batch.ID] = stored
+	return nil
+}
+
+func (r *InMemoryRepository) UpdateStatementImport(ctx context.Context, batch *StatementImport) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.imports[batch.ID]; !ok {
+		return appErrors.StatementImportNotFound
+	}
+	batch.UpdatedAt = utils.NowUTC()
+	copy := *batch
+	copy.Rows = nil
+	r.imports[batch.ID] = &copy
+	return nil
+}
+
+func (r *InMemoryRepository) ListStatementImportRows(ctx context.Context, importID string) ([]*StatementImportRow, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if _, ok := r.imports[importID]; !ok {
+		return nil, appErrors.StatementImportNotFound
+	}
+	results := make([]*StatementImportRow, 0, len(r.importRows[importID]))
+	for _, row := range r.importRows[importID] {
+		copy := *row
+		results = append(results, &copy)
+	}
+	return results, nil
+}
+
+func (r *InMemoryRepository) UpdateStatementImportRow(ctx context.Context, row *StatementImportRow) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, current := range r.importRows[row.ImportID] {
+		if current.ID == row.ID {
+			row.UpdatedAt = utils.NowUTC()
+			copy := *row
+			r.importRows[row.ImportID][i] = &copy
+			return nil
+		}
+	}
+	return appErrors.StatementImportRowNotFound
+}
+
+func (r *InMemoryRepository) UndoStatementImport(ctx context.Context, batch *StatementImport, rows []*StatementImportRow, input TransactionReversalInput) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.imports[batch.ID]; !ok {
+		return appErrors.StatementImportNotFound
+	}
+	locked := make([]string, 0)
+	for _, row := range rows {
+		if row.Status != StatementRowUndone || row.TransactionID == nil {
+			continue
+		}
+		original, ok := r.transactions[*row.TransactionID]
+		if !ok || original == nil || original.DeletedAt != "" {
+			continue
+		}
+		for _, entry := range r.referenceGroupLocked(original) {
+			if entry.Status != TransactionStatusVoided && entry.ReconciliationID != nil {
+				locked = append(locked, entry.ID)
+			}
+		}
+	}
+	if len(locked) > 0 {
+		sort.Strings(locked)
+		return appErrors.WithDetails(appErrors.TransactionLocked, map[string]interface{}{"transactionIds": locked})
+	}
+	snapshot := r.snapshotLedgerLocked()
+	for _, row := range rows {
+		if row.Status != StatementRowUndone || row.TransactionID == nil {
+			continue
+		}
+		original, ok := r.transactions[*row.TransactionID]
+		if !ok || original == nil || original.DeletedAt != "" || original.Status == TransactionStatusVoided {
+			continue
+		}
+		if isAwaitingPost(original) {
+			original.Status = TransactionStatusVoided
+			original.UpdatedAt = utils.NowUTC()
+			continue
+		}
+		if !isReversibleTransactionType(original.Type) {
+			r.restoreLedgerLocked(snapshot)
+			return appErrors.WithDetails(appErrors.TransactionNotReversible, map[string]interface{}{"type": original.Type})
+		}
+		for _, entry := range r.referenceGroupLocked(original) {
+			if entry.Status == TransactionStatusVoided {
+				continue
+			}
+			if _, err := r.reverseEntryLocked(entry, input); err != nil {
+				r.restoreLedgerLocked(snapshot)
+				return err
+			}
+		}
+	}
+	now := utils.NowUTC()
+	current := r.importRows[batch.ID]
+	for _, row := range rows {
+		for i, stored := range current {
+			if stored.ID == row.ID {
+				row.UpdatedAt = now
+				copy := *row
+				current[i] = &copy
+			}
+		}
+	}
+	batch.UpdatedAt = now
+	copy := *batch
+	copy.Rows = nil
+	r.imports[batch.ID] = &copy
+	return nil
+}
+
+func (r *InMemoryRepository) ListSMSCardLinks(ctx context.Context) ([]*SMSCardLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	results := make([]*SMSCardLink, 0, len(r.smsCardLinks))
+	for _, link := range r.smsCardLinks {
+		copy := *link
+		results = append(results, &copy)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].CardMask < results[j].CardMask
+	})
+	return results, nil
+}
+
+func (r *InMemoryRepository) CreateSMSCardLink(ctx context.Context, link *SMSCardLink) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if link.ID == "" {
+		link.ID = uuid.NewString()
+	}
+	if userID, ok := ctx.Value("user_id").(string); ok {
+		link.UserID = userID
+	}
+	now := utils.NowUTC()
+	link.CreatedAt = now
+	link.UpdatedAt = now
+	copy := *link
+	r.smsCardLinks[link.ID] = &copy
+	return nil
+}
+
+func (r *InMemoryRepository) DeleteSMSCardLink(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.smsCardLinks[id]; !ok {
+		return appErrors.SMSCardLinkNotFound
+	}
+	delete(r.smsCardLinks, id)
+	return nil
+}
+
+func (r *InMemoryRepository) ListSMSMessages(ctx context.Context, status string) ([]*SMSMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	results := make([]*SMSMessage, 0, len(r.smsMessages))
+	for _, message := range r.smsMessages {
+		if status != "" && message.Status != status {
+			continue
+		}
+		copy := *message
+		results = append(results, &copy)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ReceivedAt > results[j].ReceivedAt
+	})
+	return results, nil
+}
+
+func (r *InMemoryRepository) GetSMSMessageByID(ctx context.Context, id string) (*SMSMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	message, ok := r.smsMessages[id]
+	if !ok {
+		return nil, appErrors.SMSMessageNotFound
+	}
+	copy := *message
+	return &copy, nil
+}
+
+func (r *InMemoryRepository) GetSMSMessageByFingerprint(ctx context.Context, fingerprint string) (*SMSMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, message := range r.smsMessages {
+		if message.Fingerprint == fingerprint {
+			copy := *message
+			return &copy, nil
+		}
+	}
+	return nil, appErrors.SMSMessageNotFound
+}
+
+func (r *InMemoryRepository) CreateSMSMessage(ctx context.Context, message *SMSMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if message.ID == "" {
+		message.ID = uuid.NewString()
+	}
+	if userID, ok := ctx.Value("user_id").(string); ok {
+		message.UserID = userID
+	}
+	now := utils.NowUTC()
+	message.CreatedAt = now
+	message.UpdatedAt = now
+	copy := *message
+	r.smsMessages[message.ID] = &copy
+	return nil
+}
+
+func (r *InMemoryRepository) UpdateSMSMessage(ctx context.Context, message *SMSMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.smsMessages[message.ID]; !ok {
+		return appErrors.SMSMessageNotFound
+	}
+	message.UpdatedAt = utils.NowUTC()
+	copy := *message
+	r.smsMessages[message.ID] = &copy
+	return nil
+}
+
+func (r *InMemoryRepository) ListCategorizationRules(ctx context.Context) ([]*CategorizationRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	results := make([]*CategorizationRule, 0, len(r.rules))
+	for _, rule := range r.rules {
+		copy := *rule
+		results = append(results, &copy)
+	}
+	return results, nil
+}
+
+func (r *InMemoryRepository) GetCategorizationRuleByID(ctx context.Context, id string) (*CategorizationRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rule, ok := r.rules[id]
+	if !ok {
+		return nil, appErrors.CategorizationRuleNotFound
+	}
+	copy := *rule
+	return &copy, nil
+}
+
+func (r *InMemoryRepository) CreateCategorizationRule(ctx context.Context, rule *CategorizationRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if rule.ID == "" {
+		rule.ID = uuid.NewString()
+	}
+	if userID, ok := ctx.Value("user_id").(string); ok {
+		rule.UserID = userID
+	}
+	now := utils.NowUTC()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+	copy := *rule
+	r.rules[rule.ID] = &copy
+	return nil
+}
+
+func (r *InMemoryRepository) UpdateCategorizationRule(ctx context.Context, rule *CategorizationRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.rules[rule.ID]; !ok {
+		return appErrors.CategorizationRuleNotFound
+	}
+	rule.UpdatedAt = utils.NowUTC()
+	copy := *rule
+	r.rules[rule.ID] = &copy
+	return nil
+}
+
+func (r *InMemoryRepository) DeleteCategorizationRule(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.rules[id]; !ok {
+		return appErrors.CategorizationRuleNotFound
+	}
+	delete(r.rules, id)
+	return nil
+}
+
+func (r *InMemoryRepository) ListAttachments(ctx context.Context) ([]*Attachment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	results := make([]*Attachment, 0, len(r.attachments))
+	for _, attachment := range r.attachments {
+		copy := *attachment
+		results = append(results, &copy)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].CreatedAt > results[j].CreatedAt
+	})
+	return results, nil
+}
+
+func (r *InMemoryRepository) GetAttachmentByID(ctx context.Context, id string) (*Attachment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	attachment, ok := r.attachments[id]
+	if !ok {
+		return nil, appErrors.AttachmentNotFound
+	}
+	copy := *attachment
+	return &copy, nil
+}
+
+func (r *InMemoryRepository) CreateAttachment(ctx context.Context, attachment *Attachment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if attachment.ID == "" {
+		attachment.ID = uuid.NewString()
+	}
+	if userID, ok := ctx.Value("user_id").(string); ok {
+		attachment.UserID = userID
+	}
+	attachment.CreatedAt = utils.NowUTC()
+	copy := *attachment
+	r.attachments[attachment.ID] = &copy
+	return nil
+}
+
+func (r *InMemoryRepository) DeleteAttachment(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.attachments[id]; !ok {
+		return appErrors.AttachmentNotFound
+	}
+	delete(r.attachments, id)
+	return nil
+}
+
+func (r *InMemoryRepository) AttachmentUsage(ctx context.Context) (int, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var used int64
+	for _, attachment := range r.attachments {
+		used += attachment.Size + attachment.ThumbnailSize
+	}
+	return len(r.attachments), used, nil
+}
+
+func (r *InMemoryRepository) AttachmentReferenced(ctx context.Context, id string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	contains := func(ids []string) bool {
+		for _, candidate := range ids {
+			if candidate == id {
+				return true
+			}
+		}
+		return false
+	}
+	for _, txn := range r.transactions {
+		if contains(txn.Attachments) {
+			return true, nil
+		}
+	}
+	for _, debt := range r.debts {
+		if contains(debt.Attachments) {
+			return true, nil
+		}
+	}
+	for _, payments := range r.debtPayments {
+		for _, payment := range payments {
+			if contains(payment.Attachments) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (r *InMemoryRepository) ListReconciliations(ctx context.Context, accountID string) ([]*Reconciliation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	results := make([]*Reconciliation, 0)
+	for _, reconciliation := range r.reconciliations {
+		if reconciliation.AccountID == accountID {
+			results = append(results, cloneReconciliation(reconciliation))
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].StatementDate != results[j].StatementDate {
+			return results[i].StatementDate > results[j].StatementDate
+		}
+		return results[i].CreatedAt > results[j].CreatedAt
+	})
+	return results, nil
+}
+
+func (r *InMemoryRepository) GetReconciliationByID(ctx context.Context, id string) (*Reconciliation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	reconciliation, ok := r.reconciliations[id]
+	if !ok {
+		return nil, appErrors.ReconciliationNotFound
+	}
+	return cloneReconciliation(reconciliation), nil
+}
+
+func (r *InMemoryRepository) CreateReconciliation(ctx context.Context, reconciliation *Reconciliation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if reconciliation.ID == "" {
+		reconciliation.ID = uuid.NewString()
+	}
+	if userID, ok := ctx.Value("user_id").(string); ok {
+		reconciliation.UserID = userID
+	}
+	now := utils.NowUTC()
+	reconciliation.CreatedAt = now
+	reconciliation.UpdatedAt = now
+	r.reconciliations[reconciliation.ID] = cloneReconciliation(reconciliation)
+	return nil
+}
+
+func (r *InMemoryRepository) UpdateReconciliation(ctx context.Context, reconciliation *Reconciliation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.reconciliations[reconciliation.ID]; !ok {
+		return appErrors.ReconciliationNotFound
+	}
+	reconciliation.UpdatedAt = utils.NowUTC()
+	r.reconciliations[reconciliation.ID] = cloneReconciliation(reconciliation)
+	return nil
+}
+
+func (r *InMemoryRepository) CompleteReconciliation(ctx context.Context, reconciliation *Reconciliation, adjustment *Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.reconciliations[reconciliation.ID]
+	if !ok {
+		return appErrors.ReconciliationNotFound
+	}
+	if current.Status != ReconciliationStatusInProgress {
+		return appErrors.WithDetails(appErrors.InvalidReconciliation, map[string]interface{}{"field": "status", "status": current.Status})
+	}
+	for _, txnID := range reconciliation.ClearedTransactionIDs {
+		txn, ok := r.transactions[txnID]
+		if !ok || txn.DeletedAt != "" || txn.Status == TransactionStatusVoided || txn.ReconciliationID != nil {
+			return appErrors.WithDetails(appErrors.InvalidReconciliation, map[string]interface{}{"field": "cleared", "transactionId": txnID})
+		}
+	}
+	now := utils.NowUTC()
+	if adjustment != nil {
+		account, ok := r.accounts[reconciliation.AccountID]
+		if !ok || account.DeletedAt != "" {
+			return appErrors.AccountNotFound
+		}
+		adjustment.ID = uuid.NewString()
+		adjustment.UserID = account.UserID
+		adjustment.CreatedAt = now
+		adjustment.UpdatedAt = now
+		adjustment.ReconciliationID = &reconciliation.ID
+		adjustment.ClearedAt = &now
+		account.CurrentBalance += adjustment.Amount
+		account.UpdatedAt = now
+		r.transactions[adjustment.ID] = cloneTransaction(adjustment)
+		reconciliation.AdjustmentTransactionID = &adjustment.ID
+	}
+	for _, txnID := range reconciliation.ClearedTransactionIDs {
+		txn := r.transactions[txnID]
+		reconciliationID := reconciliation.ID
+		clearedAt := now
+		txn.ReconciliationID = &reconciliationID
+		txn.ClearedAt = &clearedAt
+		txn.UpdatedAt = now
+	}
+	reconciliation.UpdatedAt = now
+	r.reconciliations[reconciliation.ID] = cloneReconciliation(reconciliation)
+	return nil
+}
+
+func (r *InMemoryRepository) ListLedgerUserIDs(ctx context.Context) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	seen := make(map[string]bool)
+	add := func(userID, deletedAt string) {
+		if userID != "" && deletedAt == "" {
+			seen[userID] = true
+		}
+	}
+	for _, account := range r.accounts {
+		add(account.UserID, account.DeletedAt)
+	}
+	for _, budget := range r.budgets {
+		add(budget.UserID, budget.DeletedAt)
+	}
+	for _, debt := range r.debts {
+		add(debt.UserID, debt.DeletedAt)
+	}
+	results := make([]string, 0, len(seen))
+	for userID := range seen {
+		results = append(results, userID)
+	}
+	sort.Strings(results)
+	return results, nil
+}
+
+func (r *InMemoryRepository) RecordLedgerRepair(ctx context.Context, repair *LedgerRepair, adjustment *Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := utils.NowUTC()
+	if adjustment != nil {
+		account, ok := r.accounts[repair.EntityID]
+		if !ok || account.DeletedAt != "" {
+			return appErrors.AccountNotFound
+		}
+		if account.CurrentBalance != repair.StoredAmount {
+			return appErrors.LedgerDriftChanged
+		}
+		adjustment.ID = uuid.NewString()
+		adjustment.UserID = account.UserID
+		adjustment.CreatedAt = now
+		adjustment.UpdatedAt = now
+		r.transactions[adjustment.ID] = cloneTransaction(adjustment)
+		repair.AdjustmentTransactionID = &adjustment.ID
+	}
+	if repair.ID == "" {
+		repair.ID = uuid.NewString()
+	}
+	repair.CreatedAt = now
+	copy := *repair
+	r.ledgerRepairs = append(r.ledgerRepairs, &copy)
+	return nil
+}
+
+func (r *InMemoryRepository) ListScheduledTransactions(ctx context.Context) ([]*Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	results := make([]*Transaction, 0)
+	for _, txn := range r.transactions {
+		if txn.DeletedAt != "" || !isAwaitingPost(txn) {
+			continue
+		}
+		results = append(results, cloneTransaction(txn))
 	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Date < results[j].Date
+	})
 	return results, nil
 }
 
-func (r *InMemoryRepository) ReplaceQuickExpenseCategories(ctx context.Context, categoryType string, categories []*QuickExpenseCategory) error {
+func (r *InMemoryRepository) ListDueScheduledTransactions(ctx context.Context, asOf string) ([]*Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	results := make([]*Transaction, 0)
+	for _, txn := range r.transactions {
+		if txn.DeletedAt != "" || !txn.IsScheduled || txn.Status != TransactionStatusPending || txn.Date > asOf {
+			continue
+		}
+		if txn.Type == TransactionTypeTransferIn || isSMSTransaction(txn) {
+			continue
+		}
+		results = append(results, cloneTransaction(txn))
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Date < results[j].Date
+	})
+	return results, nil
+}
+
+func (r *InMemoryRepository) PostScheduledTransaction(ctx context.Context, id, date string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	userID, _ := ctx.Value("user_id").(string)
-	key := quickExpenseKey(userID, categoryType)
-	next := make([]*QuickExpenseCategory, 0, len(categories))
-	for _, entry := range categories {
-		if entry == nil {
+	txn, ok := r.transactions[id]
+	if !ok || txn.DeletedAt != "" {
+		return appErrors.TransactionNotFound
+	}
+	if !isAwaitingPost(txn) {
+		return appErrors.TransactionNotScheduled
+	}
+	deltas := make(map[string]Money)
+	for _, accountID := range []*string{txn.AccountID, txn.FromAccountID, txn.ToAccountID} {
+		if accountID != nil && *accountID != "" {
+			deltas[*accountID] = ledgerDeltaForAccount(*accountID, txn)
+		}
+	}
+	for accountID, delta := range deltas {
+		account, ok := r.accounts[accountID]
+		if !ok || account.DeletedAt != "" {
+			return appErrors.AccountNotFound
+		}
+		if delta < 0 && availableFunds(account) < -delta {
+			return appErrors.InsufficientFunds
+		}
+	}
+	now := utils.NowUTC()
+	for accountID, delta := range deltas {
+		account := r.accounts[accountID]
+		account.CurrentBalance += delta
+		account.UpdatedAt = now
+	}
+	txn.Status = TransactionStatusCompleted
+	txn.IsScheduled = false
+	txn.Date = date
+	txn.UpdatedAt = now
+	return nil
+}
+
+func (r *InMemoryRepository) UpdateScheduledTransaction(ctx context.Context, id, status, date string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	txn, ok := r.transactions[id]
+	if !ok || txn.DeletedAt != "" {
+		return appErrors.TransactionNotFound
+	}
+	if !isAwaitingPost(txn) {
+		return appErrors.TransactionNotScheduled
+	}
+	txn.Status = status
+	txn.Date = date
+	txn.UpdatedAt = utils.NowUTC()
+	return nil
+}
+
+func (r *InMemoryRepository) ListCreditAccounts(ctx context.Context) ([]*Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	results := make([]*Account, 0)
+	for _, account := range r.accounts {
+		if account.DeletedAt != "" || account.Credit == nil || !isCreditAccountType(account.AccountType) {
 			continue
 		}
-		copy := *entry
-		next = append(next, &copy)
+		results = append(results, cloneAccount(account))
 	}
-	r.quickExp[key] = next
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ID < results[j].ID
+	})
+	return results, nil
+}
+
+func (r *InMemoryRepository) ListCreditStatements(ctx context.Context, accountID string) ([]*CreditStatement, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	results := make([]*CreditStatement, 0)
+	for _, statement := range r.statements {
+		if statement.AccountID == accountID {
+			copy := *statement
+			results = append(results, &copy)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].PeriodEnd > results[j].PeriodEnd
+	})
+	return results, nil
+}
+
+func (r *InMemoryRepository) CreateCreditStatement(ctx context.Context, statement *CreditStatement) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.statements {
+		if existing.AccountID == statement.AccountID && existing.PeriodEnd == statement.PeriodEnd {
+			return false, nil
+		}
+	}
+	if statement.ID == "" {
+		statement.ID = uuid.NewString()
+	}
+	if userID, ok := ctx.Value("user_id").(string); ok {
+		statement.UserID = userID
+	}
+	now := utils.NowUTC()
+	statement.CreatedAt = now
+	statement.UpdatedAt = now
+	copy := *statement
+	r.statements[statement.ID] = &copy
+	return true, nil
+}
+
+func (r *InMemoryRepository) UpdateCreditStatement(ctx context.Context, statement *CreditStatement) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.statements[statement.ID]
+	if !ok {
+		return appErrors.CreditStatementNotFound
+	}
+	current.ReminderSentAt = statement.ReminderSentAt
+	current.OverdueNotifiedAt = statement.OverdueNotifiedAt
+	current.UpdatedAt = utils.NowUTC()
+	statement.UpdatedAt = current.UpdatedAt
 	return nil
 }
 
-func quickExpenseKey(userID, categoryType string) string {
-	return strings.TrimSpace(userID) + ":" + strings.ToLower(strings.TrimSpace(categoryType))
+func (r *InMemoryRepository) MergeAccount(ctx context.Context, merge *AccountMerge, rate ExchangeRate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	source, ok := r.accounts[merge.SourceAccountID]
+	if !ok || source.DeletedAt != "" {
+		return appErrors.AccountNotFound
+	}
+	target, ok := r.accounts[merge.TargetAccountID]
+	if !ok || target.DeletedAt != "" {
+		return appErrors.AccountNotFound
+	}
+	now := utils.NowUTC()
+
+	for _, txn := range r.transactions {
+		if txn.DeletedAt == "" && moveTransactionToAccount(txn, merge, rate) {
+			txn.UpdatedAt = now
+			merge.TransactionsMoved++
+		}
+	}
+	for _, item := range r.recurring {
+		if item.DeletedAt == "" && moveRecurringToAccount(item, merge, rate) {
+			item.UpdatedAt = now
+			merge.RecurringMoved++
+		}
+	}
+	for _, budget := range r.budgets {
+		if budget.DeletedAt == "" && budget.AccountID != nil && *budget.AccountID == source.ID {
+			targetID := target.ID
+			budget.AccountID = &targetID
+			budget.UpdatedAt = now
+			merge.BudgetsMoved++
+		}
+	}
+	for _, debt := range r.debts {
+		if debt.DeletedAt == "" && moveDebtToAccount(debt, merge) {
+			debt.UpdatedAt = now
+			merge.DebtsMoved++
+		}
+	}
+	for _, payments := range r.debtPayments {
+		for _, payment := range payments {
+			if payment.AccountID != nil && *payment.AccountID == source.ID {
+				targetID := target.ID
+				payment.AccountID = &targetID
+				payment.UpdatedAt = now
+			}
+		}
+	}
+	for _, link := range r.smsCardLinks {
+		if link.AccountID == source.ID {
+			link.AccountID = target.ID
+			link.UpdatedAt = now
+		}
+	}
+	if source.LinkedGoalID != nil && target.LinkedGoalID == nil {
+		target.LinkedGoalID = source.LinkedGoalID
+		merge.GoalMoved = true
+	}
+
+	merge.SourceBalance = source.CurrentBalance
+	merge.ConvertedBalance = source.CurrentBalance
+	initial := source.InitialBalance
+	if !rate.IsZero() {
+		merge.ConvertedBalance = rate.Convert(source.CurrentBalance, merge.TargetCurrency)
+		initial = rate.Convert(source.InitialBalance, merge.TargetCurrency)
+	}
+	target.InitialBalance += initial
+	target.IsMain = target.IsMain || source.IsMain
+	transactions := make([]*Transaction, 0, len(r.transactions))
+	for _, txn := range r.transactions {
+		if txn.DeletedAt == "" {
+			transactions = append(transactions, txn)
+		}
+	}
+	target.CurrentBalance = computeAccountBalance(target, transactions)
+	target.UpdatedAt = now
+	merge.TargetBalance = target.CurrentBalance
+
+	source.InitialBalance = 0
+	source.CurrentBalance = 0
+	source.LinkedGoalID = nil
+	source.IsMain = false
+	source.IsArchived = true
+	source.ShowStatus = "archived"
+	source.UpdatedAt = now
+
+	merge.ID = uuid.NewString()
+	merge.UserID = source.UserID
+	merge.CreatedAt = now
+	copy := *merge
+	r.merges = append(r.merges, &copy)
+	return nil
+}
+
+func cloneReconciliation(reconciliation *Reconciliation) *Reconciliation {
+	copy := *reconciliation
+	copy.ClearedTransactionIDs = append([]string{}, reconciliation.ClearedTransactionIDs...)
+	copy.Transactions = nil
+	return &copy
 }
 
 func cloneAccount(account *Account) *Account {
@@ -1033,6 +2788,11 @@ func cloneAccount(account *Account) *Account {
 		return nil
 	}
 	copy := *account
+	if account.Credit != nil {
+		terms := *account.Credit
+		copy.Credit = &terms
+	}
+	copy.Balances = nil
 	return &copy
 }
 
@@ -1076,6 +2836,15 @@ func cloneCounterparty(counterparty *Counterparty) *Counterparty {
 	return &copy
 }
 
+func cloneRecurringTransaction(recurring *RecurringTransaction) *RecurringTransaction {
+	if recurring == nil {
+		return nil
+	}
+	copy := *recurring
+	copy.Tags = append([]string(nil), recurring.Tags...)
+	return &copy
+}
+
 func cloneFXRate(rate *FXRate) *FXRate {
 	if rate == nil {
 		return nil
@@ -5,6 +5,7 @@ import "github.com/gofiber/fiber/v2"
 func RegisterRoutes(router fiber.Router, handler *Handler) {
 	router.Get("/finance/summary", handler.FinanceSummary)
 	router.Get("/finance/bootstrap", handler.FinanceBootstrap)
+	router.Get("/finance/net-worth", handler.NetWorth)
 	router.Get("/finance/categories", handler.Categories)
 	router.Get("/finance/quick-exp-categories", handler.QuickExpenseCategories)
 	router.Put("/finance/quick-exp-categories", handler.UpdateQuickExpenseCategories)
@@ -20,6 +21,13 @@ func RegisterRoutes(router fiber.Router, handler *Handler) {
 	accounts.Get("/:id", handler.GetAccount)
 	accounts.Get("/:id/transactions", handler.AccountTransactions)
 	accounts.Get("/:id/balance-history", handler.AccountBalanceHistory)
+	accounts.Get("/:id/reconciliations", handler.Reconciliations)
+	accounts.Post("/:id/reconciliations", handler.StartReconciliation)
+	accounts.Get("/:id/statements", handler.CreditStatements)
+	accounts.Post("/:id/statements/:statementId/pay", handler.PayCreditStatement)
+	accounts.Post("/:id/balances", handler.AddAccountCurrency)
+	accounts.Post("/:id/exchange", handler.ExchangeCurrency)
+	accounts.Post("/:id/merge-into/:targetId", handler.MergeAccount)
 	accounts.Put("/:id", handler.UpdateAccount)
 	accounts.Patch("/:id", handler.PatchAccount)
 	accounts.Delete("/:id", handler.DeleteAccount)
@@ -29,10 +37,18 @@ func RegisterRoutes(router fiber.Router, handler *Handler) {
 	transactions.Post("", handler.CreateTransaction)
 	transactions.Post("/transfer", handler.CreateTransfer)
 	transactions.Post("/bulk", handler.CreateTransactionsBulk)
+	transactions.Get("/scheduled", handler.ScheduledTransactions)
 	transactions.Get("/:id", handler.GetTransaction)
 	transactions.Put("/:id", handler.UpdateTransaction)
 	transactions.Patch("/:id", handler.PatchTransaction)
 	transactions.Delete("/:id", handler.DeleteTransaction)
+	transactions.Post("/:id/reverse", handler.ReverseTransaction)
+	transactions.Post("/:id/correct", handler.CorrectTransaction)
+	transactions.Post("/:id/confirm", handler.ConfirmScheduledTransaction)
+	transactions.Post("/:id/skip", handler.SkipScheduledTransaction)
+	transactions.Post("/:id/reschedule", handler.RescheduleTransaction)
+	transactions.Post("/:id/attachments/:attachmentId", handler.AttachToTransaction)
+	transactions.Delete("/:id/attachments/:attachmentId", handler.DetachFromTransaction)
 
 	budgets := router.Group("/budgets")
 	budgets.Get("", handler.Budgets)
@@ -42,6 +58,8 @@ func RegisterRoutes(router fiber.Router, handler *Handler) {
 	budgets.Get("/:id/spending", handler.BudgetSpending)
 	budgets.Post("/:id/add-value", handler.AddBudgetValue)
 	budgets.Post("/:id/recalculate", handler.RecalculateBudget)
+	budgets.Get("/:id/periods", handler.BudgetPeriods)
+	budgets.Post("/:id/close-period", handler.CloseBudgetPeriod)
 	budgets.Put("/:id", handler.UpdateBudget)
 	budgets.Patch("/:id", handler.PatchBudget)
 	budgets.Delete("/:id", handler.DeleteBudget)
@@ -58,6 +76,9 @@ func RegisterRoutes(router fiber.Router, handler *Handler) {
 	debts.Delete("/:id/payments/:paymentId", handler.DeleteDebtPayment)
 	debts.Post("/:id/settle", handler.SettleDebt)
 	debts.Post("/:id/extend", handler.ExtendDebt)
+	debts.Get("/:id/schedule", handler.DebtSchedule)
+	debts.Put("/:id/schedule", handler.SetDebtSchedule)
+	debts.Delete("/:id/schedule", handler.DeleteDebtSchedule)
 	debts.Put("/:id", handler.UpdateDebt)
 	debts.Patch("/:id", handler.PatchDebt)
 	debts.Delete("/:id", handler.DeleteDebt)
@@ -71,8 +92,63 @@ func RegisterRoutes(router fiber.Router, handler *Handler) {
 	counterparties.Get("/:id/debts", handler.CounterpartyDebts)
 	counterparties.Get("/:id/transactions", handler.CounterpartyTransactions)
 
+	recurring := router.Group("/recurring-transactions")
+	recurring.Get("", handler.RecurringTransactions)
+	recurring.Post("", handler.CreateRecurringTransaction)
+	recurring.Get("/:id", handler.GetRecurringTransaction)
+	recurring.Patch("/:id", handler.PatchRecurringTransaction)
+	recurring.Delete("/:id", handler.DeleteRecurringTransaction)
+	recurring.Get("/:id/occurrences", handler.RecurringOccurrences)
+	recurring.Post("/:id/occurrences/:occurrenceId/confirm", handler.ConfirmRecurringOccurrence)
+	recurring.Post("/:id/occurrences/:occurrenceId/skip", handler.SkipRecurringOccurrence)
+
+	imports := router.Group("/imports")
+	imports.Get("/profiles", handler.StatementImportProfiles)
+	imports.Post("/profiles", handler.CreateStatementImportProfile)
+	imports.Delete("/profiles/:id", handler.DeleteStatementImportProfile)
+	imports.Get("", handler.StatementImports)
+	imports.Post("", handler.ImportStatement)
+	imports.Get("/:id", handler.GetStatementImport)
+	imports.Patch("/:id/rows/:rowId", handler.PatchStatementImportRow)
+	imports.Post("/:id/approve", handler.ApproveStatementImportRows)
+	imports.Post("/:id/reject", handler.RejectStatementImportRows)
+	imports.Post("/:id/undo", handler.UndoStatementImport)
+
+	sms := router.Group("/sms")
+	sms.Get("/templates", handler.SMSTemplates)
+	sms.Get("/card-links", handler.SMSCardLinks)
+	sms.Post("/card-links", handler.CreateSMSCardLink)
+	sms.Delete("/card-links/:id", handler.DeleteSMSCardLink)
+	sms.Get("/messages", handler.SMSMessages)
+	sms.Post("/messages", handler.IngestSMSMessages)
+	sms.Post("/messages/:id/reprocess", handler.ReprocessSMSMessage)
+
+	rules := router.Group("/categorization-rules")
+	rules.Get("", handler.CategorizationRules)
+	rules.Post("", handler.CreateCategorizationRule)
+	rules.Post("/dry-run", handler.DryRunCategorizationRule)
+	rules.Get("/:id", handler.GetCategorizationRule)
+	rules.Put("/:id", handler.UpdateCategorizationRule)
+	rules.Delete("/:id", handler.DeleteCategorizationRule)
+	rules.Post("/:id/apply", handler.ApplyCategorizationRule)
+
+	attachments := router.Group("/attachments")
+	attachments.Get("", handler.Attachments)
+	attachments.Post("", handler.UploadAttachment)
+	attachments.Get("/usage", handler.AttachmentUsage)
+	attachments.Get("/:id", handler.GetAttachment)
+	attachments.Delete("/:id", handler.DeleteAttachment)
+
+	reconciliations := router.Group("/reconciliations")
+	reconciliations.Get("/:id", handler.GetReconciliation)
+	reconciliations.Patch("/:id/transactions", handler.SelectReconciliationTransactions)
+	reconciliations.Post("/:id/complete", handler.CompleteReconciliation)
+	reconciliations.Post("/:id/cancel", handler.CancelReconciliation)
+
 	fx := router.Group("/fx")
 	fx.Get("/rates", handler.GetFXRates)
+	fx.Get("/rates/status", handler.FXRateStatuses)
+	fx.Get("/rates/resolve", handler.ResolveFXRate)
 	fx.Post("/rates/manual", handler.CreateFXRate)
 	fx.Get("/supported-currencies", handler.SupportedCurrencies)
 }
@@ -0,0 +1,252 @@
+package finance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	appErrors "github.com/leora/leora-server/internal/errors"
+	"github.com/leora/leora-server/internal/modules/notifications"
+)
+
+// scheduleIfFutureDated holds back an income, expense or transfer dated after
+// today. It is stored pending without moving any balance and posts when its
+// date arrives.
+func scheduleIfFutureDated(txn *Transaction, now time.Time) {
+	switch strings.ToLower(txn.Type) {
+	case TransactionTypeIncome, TransactionTypeExpense, TransactionTypeTransfer:
+	default:
+		return
+	}
+	if txn.Date > now.Format("2006-01-02") {
+		txn.IsScheduled = true
+		txn.Status = TransactionStatusPending
+	}
+}
+
+// isAwaitingPost reports whether a scheduled entry still has to be posted: it
+// is pending, or its automatic post failed. A skipped entry is voided.
+func isAwaitingPost(txn *Transaction) bool {
+	return txn.IsScheduled && (txn.Status == TransactionStatusPending || txn.Status == TransactionStatusFailed)
+}
+
+// splitScheduledTransactions separates the entries that have been posted from
+// the scheduled ones still waiting. Skipped entries are in neither.
+func splitScheduledTransactions(transactions []*Transaction) ([]*Transaction, []*Transaction) {
+	posted := make([]*Transaction, 0, len(transactions))
+	upcoming := make([]*Transaction, 0)
+	for _, txn := range transactions {
+		if !txn.IsScheduled {
+			posted = append(posted, txn)
+		} else if isAwaitingPost(txn) {
+			upcoming = append(upcoming, txn)
+		}
+	}
+	return posted, upcoming
+}
+
+// applyProjectedBalances sets each account's balance as it will stand once
+// every scheduled entry waiting on it has posted.
+func (s *Service) applyProjectedBalances(ctx context.Context, accounts []*Account) error {
+	if len(accounts) == 0 {
+		return nil
+	}
+	scheduled, err := s.repo.ListScheduledTransactions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		account.ProjectedBalance = account.CurrentBalance
+		for _, txn := range scheduled {
+			account.ProjectedBalance += ledgerDeltaForAccount(account.ID, txn)
+		}
+	}
+	return nil
+}
+
+// ScheduledTransactions lists the entries waiting to post, earliest first.
+func (s *Service) ScheduledTransactions(ctx context.Context) ([]*Transaction, error) {
+	items, err := s.repo.ListScheduledTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, txn := range items {
+		normalizeTransaction(txn)
+	}
+	return items, nil
+}
+
+// ConfirmScheduledTransaction posts a scheduled entry now instead of waiting
+// for its date; an entry dated in the future is posted with today's date.
+func (s *Service) ConfirmScheduledTransaction(ctx context.Context, id string) (*Transaction, error) {
+	txn, err := s.scheduledTransaction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	date := txn.Date
+	if today := time.Now().UTC().Format("2006-01-02"); date > today {
+		date = today
+	}
+	return s.postScheduledTransaction(ctx, txn, date)
+}
+
+// SkipScheduledTransaction voids a scheduled entry without posting it.
+func (s *Service) SkipScheduledTransaction(ctx context.Context, id string) (*Transaction, error) {
+	txn, err := s.scheduledTransaction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateScheduledTransaction(ctx, txn.ID, TransactionStatusVoided, txn.Date); err != nil {
+		return nil, err
+	}
+	s.invalidateFinanceSummaryCache(ctx)
+	return s.reloadTransaction(ctx, txn.ID)
+}
+
+// RescheduleTransaction moves a scheduled entry to another future date. An
+// entry whose automatic post failed goes back to pending and is tried again
+// on the new date.
+func (s *Service) RescheduleTransaction(ctx context.Context, id, date string) (*Transaction, error) {
+	date = normalizeDateInput(date)
+	if _, err := time.Parse("2006-01-02", date); err != nil || date <= time.Now().UTC().Format("2006-01-02") {
+		return nil, appErrors.WithDetails(appErrors.InvalidTransactionDate, map[string]interface{}{"field": "date"})
+	}
+	txn, err := s.scheduledTransaction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateScheduledTransaction(ctx, txn.ID, TransactionStatusPending, date); err != nil {
+		return nil, err
+	}
+	s.invalidateFinanceSummaryCache(ctx)
+	return s.reloadTransaction(ctx, txn.ID)
+}
+
+// PostDueScheduledTransactions posts every pending scheduled entry dated on
+// or before asOf across all users and notifies the owner either way. An entry
+// that cannot post, say for lack of funds, is marked failed and waits for the
+// user to confirm, reschedule or skip it.
+func (s *Service) PostDueScheduledTransactions(ctx context.Context, asOf time.Time) (int, error) {
+	due, err := s.repo.ListDueScheduledTransactions(ctx, asOf.UTC().Format("2006-01-02"))
+	if err != nil {
+		return 0, err
+	}
+	posted := 0
+	for _, txn := range due {
+		userCtx := context.WithValue(ctx, "user_id", txn.UserID)
+		_, postErr := s.postScheduledTransaction(userCtx, txn, txn.Date)
+		if postErr == appErrors.TransactionNotScheduled {
+			// Confirmed or skipped since it was listed.
+			continue
+		}
+		if postErr != nil {
+			log.Printf("[Service.PostDueScheduledTransactions] Post failed for transaction=%s: %v", txn.ID, postErr)
+			if err := s.repo.UpdateScheduledTransaction(userCtx, txn.ID, TransactionStatusFailed, txn.Date); err != nil {
+				log.Printf("[Service.PostDueScheduledTransactions] Failed to mark transaction=%s failed: %v", txn.ID, err)
+				continue
+			}
+			s.invalidateFinanceSummaryCache(userCtx)
+		} else {
+			posted++
+		}
+		s.notifyScheduledTransaction(userCtx, txn, postErr)
+	}
+	return posted, nil
+}
+
+func (s *Service) scheduledTransaction(ctx context.Context, id string) (*Transaction, error) {
+	txn, err := s.repo.GetTransactionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !isAwaitingPost(txn) {
+		return nil, appErrors.TransactionNotScheduled
+	}
+	return txn, nil
+}
+
+func (s *Service) postScheduledTransaction(ctx context.Context, txn *Transaction, date string) (*Transaction, error) {
+	if err := s.repo.PostScheduledTransaction(ctx, txn.ID, date); err != nil {
+		return nil, err
+	}
+	s.invalidateFinanceSummaryCache(ctx)
+	posted, err := s.reloadTransaction(ctx, txn.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.syncBudgetRollups(ctx, transactionBudgetIDs(posted))
+	for _, budgetID := range transactionBudgetIDs(posted) {
+		s.evaluateBudgetAlerts(ctx, budgetID)
+	}
+	return posted, nil
+}
+
+func (s *Service) reloadTransaction(ctx context.Context, id string) (*Transaction, error) {
+	txn, err := s.repo.GetTransactionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	normalizeTransaction(txn)
+	return txn, nil
+}
+
+func (s *Service) notifyScheduledTransaction(ctx context.Context, txn *Transaction, postErr error) {
+	if s.notifications == nil {
+		return
+	}
+	if _, err := s.notifications.Create(ctx, buildScheduledTransactionNotification(txn, postErr)); err != nil {
+		log.Printf("[Service.notifyScheduledTransaction] Failed to notify transaction=%s: %v", txn.ID, err)
+	}
+}
+
+func buildScheduledTransactionNotification(txn *Transaction, postErr error) *notifications.Notification {
+	label := "Scheduled " + txn.Type
+	if isTransferLeg(txn.Type) {
+		label = "Scheduled transfer"
+	}
+	if txn.Name != nil && strings.TrimSpace(*txn.Name) != "" {
+		label = *txn.Name
+	}
+	amount := formatAmountForCurrency(txn.Amount, txn.Currency)
+	if postErr != nil {
+		return &notifications.Notification{
+			Title:   fmt.Sprintf("%s could not be posted", label),
+			Message: fmt.Sprintf("%s due %s was not posted: %s. Confirm, reschedule or skip it.", amount, txn.Date, postErr.Error()),
+		}
+	}
+	return &notifications.Notification{
+		Title:   fmt.Sprintf("%s posted", label),
+		Message: fmt.Sprintf("%s scheduled for %s has been posted.", amount, txn.Date),
+	}
+}
+
+// buildSummaryUpcomingTransactions lists the next scheduled entries, soonest
+// first. A transfer appears once, by its outgoing leg.
+func buildSummaryUpcomingTransactions(
+	s *Service,
+	ctx context.Context,
+	scheduled []*Transaction,
+	accountCurrencyMap map[string]string,
+	baseCurrency string,
+	rateDate string,
+) []FinanceSummaryTransaction {
+	sort.SliceStable(scheduled, func(i, j int) bool {
+		return scheduled[i].Date < scheduled[j].Date
+	})
+	upcoming := make([]FinanceSummaryTransaction, 0, 5)
+	for _, txn := range scheduled {
+		if txn.Type == TransactionTypeTransferIn {
+			continue
+		}
+		item := buildSummaryTransaction(s, ctx, txn, accountCurrencyMap, baseCurrency, rateDate)
+		item.Status = txn.Status
+		upcoming = append(upcoming, item)
+		if len(upcoming) >= 5 {
+			break
+		}
+	}
+	return upcoming
+}
@@ -0,0 +1,157 @@
+package finance
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Scheduler runs the finance background jobs on a fixed interval.
+type Scheduler struct {
+	interval time.Duration
+	jobs     []schedulerJob
+	lock     SchedulerLock
+}
+
+// SchedulerLock keeps several server instances from running the jobs at the
+// same time. TryLock reports false when another instance holds the lock.
+type SchedulerLock interface {
+	TryLock(ctx context.Context) (unlock func(), acquired bool, err error)
+}
+
+// schedulerAdvisoryLockKey identifies the finance scheduler among Postgres
+// advisory locks.
+const schedulerAdvisoryLockKey int64 = 0x4c656f72_61666e63
+
+type postgresSchedulerLock struct {
+	db *sqlx.DB
+}
+
+// NewPostgresSchedulerLock guards each run with a session-level Postgres
+// advisory lock, held on a dedicated connection until the run ends.
+func NewPostgresSchedulerLock(db *sqlx.DB) SchedulerLock {
+	return &postgresSchedulerLock{db: db}
+}
+
+func (l *postgresSchedulerLock) TryLock(ctx context.Context) (func(), bool, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, schedulerAdvisoryLockKey).Scan(&acquired); err != nil {
+		_ = conn.Close()
+		return nil, false, err
+	}
+	if !acquired {
+		_ = conn.Close()
+		return nil, false, nil
+	}
+	unlock := func() {
+		// The lock must be released even when the run's context is done.
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, schedulerAdvisoryLockKey); err != nil {
+			log.Printf("[Scheduler] advisory unlock error: %v", err)
+		}
+		_ = conn.Close()
+	}
+	return unlock, true, nil
+}
+
+type schedulerJob struct {
+	name string
+	run  func(ctx context.Context, now time.Time) error
+}
+
+func NewScheduler(service *Service, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		interval: interval,
+		jobs: []schedulerJob{
+			{name: "fx-rates", run: func(ctx context.Context, now time.Time) error {
+				_, err := service.SyncFXRates(ctx, now)
+				return err
+			}},
+			{name: "recurring-transactions", run: func(ctx context.Context, now time.Time) error {
+				_, err := service.GenerateRecurringTransactions(ctx, now)
+				return err
+			}},
+			{name: "scheduled-transactions", run: func(ctx context.Context, now time.Time) error {
+				_, err := service.PostDueScheduledTransactions(ctx, now)
+				return err
+			}},
+			{name: "credit-statements", run: func(ctx context.Context, now time.Time) error {
+				_, err := service.GenerateCreditStatements(ctx, now)
+				return err
+			}},
+			{name: "budget-periods", run: func(ctx context.Context, now time.Time) error {
+				_, err := service.CloseElapsedBudgetPeriods(ctx, now)
+				return err
+			}},
+			{name: "debt-due-dates", run: func(ctx context.Context, now time.Time) error {
+				_, err := service.EvaluateDebtDueDates(ctx, now)
+				return err
+			}},
+		},
+	}
+}
+
+// SetLock makes every run take lock first; a run whose lock is held
+// elsewhere is skipped.
+func (s *Scheduler) SetLock(lock SchedulerLock) {
+	s.lock = lock
+}
+
+// Start runs every job once immediately and then on each tick until ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		s.runLocked(ctx, time.Now().UTC())
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.runLocked(ctx, now.UTC())
+			}
+		}
+	}()
+}
+
+func (s *Scheduler) runLocked(ctx context.Context, now time.Time) {
+	if s.lock == nil {
+		s.RunOnce(ctx, now)
+		return
+	}
+	unlock, acquired, err := s.lock.TryLock(ctx)
+	if err != nil {
+		log.Printf("[Scheduler] lock error: %v", err)
+		return
+	}
+	if !acquired {
+		log.Printf("[Scheduler] another instance is running the jobs; skipping this run")
+		return
+	}
+	defer unlock()
+	s.RunOnce(ctx, now)
+}
+
+// RunOnce executes each job in order. A failing job is logged and does not
+// prevent the remaining jobs from running.
+func (s *Scheduler) RunOnce(ctx context.Context, now time.Time) {
+	for _, job := range s.jobs {
+		s.runJob(ctx, job, now)
+	}
+}
+
+func (s *Scheduler) runJob(ctx context.Context, job schedulerJob, now time.Time) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Printf("[Scheduler] job=%s panic: %v", job.name, recovered)
+		}
+	}()
+	if err := job.run(ctx, now); err != nil {
+		log.Printf("[Scheduler] job=%s error: %v", job.name, err)
+	}
+}
@@ -11,13 +11,18 @@ import (
 	"strings"
 	"time"
 
-	"github.com/redis/go-redis/v9"
 	appErrors "github.com/leora/leora-server/internal/errors"
+	"github.com/leora/leora-server/internal/modules/notifications"
+	"github.com/leora/leora-server/internal/storage"
+	"github.com/redis/go-redis/v9"
 )
 
 // TransactionFilter captures filtering options for list endpoints.
 type TransactionFilter struct {
-	AccountID  string
+	AccountID string
+	// AccountIDs widens AccountID to the sub-balances of a multi-currency
+	// account; when set it lists AccountID as well.
+	AccountIDs []string
 	Type       string
 	CategoryID string
 	DateFrom   string
@@ -25,6 +30,28 @@ type TransactionFilter struct {
 	GoalID     string
 	BudgetID   string
 	DebtID     string
+
+	CounterpartyID string
+	HabitID        string
+	Status         string
+	IncludeVoided  bool
+
+	// AmountMin and AmountMax bound Amount, or ConvertedAmountToBase when
+	// AmountBasis is TransactionAmountBasisBase. Both ends are inclusive.
+	AmountMin   *Money
+	AmountMax   *Money
+	AmountBasis string
+
+	// TagsAny matches transactions carrying at least one of the tags and
+	// TagsAll those carrying every one of them.
+	TagsAny []string
+	TagsAll []string
+
+	// Search is a case-insensitive substring match on Name and Description.
+	Search string
+
+	SortBy    string
+	SortOrder string
 }
 
 // BudgetFilter captures budget list filters.
@@ -39,6 +66,7 @@ type DebtFilter struct {
 	Direction    string
 	Status       string
 	LinkedGoalID string
+	Overdue      *bool
 }
 
 // CounterpartyFilter captures search options.
@@ -48,8 +76,16 @@ type CounterpartyFilter struct {
 
 // Service orchestrates finance use cases.
 type Service struct {
-	repo  Repository
-	cache *redis.Client
+	repo          Repository
+	cache         *redis.Client
+	notifications *notifications.Service
+	fxProviders   []FXProvider
+	fxSync        fxSyncState
+	fxCache       fxResolutionCache
+	smsTemplates  []SMSTemplate
+
+	blobs             storage.BlobStore
+	attachmentOptions AttachmentOptions
 }
 
 const financeSummaryCacheTTL = 45 * time.Second
@@ -58,16 +94,30 @@ func NewService(repo Repository, cache *redis.Client) *Service {
 	return &Service{repo: repo, cache: cache}
 }
 
+// SetNotifications enables user-facing alerts (budget thresholds and the
+// like). Without it the finance flows run unchanged and send nothing.
+func (s *Service) SetNotifications(service *notifications.Service) {
+	s.notifications = service
+}
+
+// SetFXProviders enables scheduled rate ingestion. Providers are fetched in
+// order; without any, rates only come from manual entries.
+func (s *Service) SetFXProviders(providers ...FXProvider) {
+	s.fxProviders = providers
+}
+
 func (s *Service) Accounts(ctx context.Context) ([]*Account, error) {
 	accounts, err := s.repo.ListAccounts(ctx)
 	if err != nil {
 		return nil, err
 	}
 	for _, account := range accounts {
-		account.ShowStatus = normalizeShowStatus(account.ShowStatus)
-		account.IsArchived = account.ShowStatus == "archived"
+		normalizeAccount(account)
 	}
-	return accounts, nil
+	if err := s.applyProjectedBalances(ctx, accounts); err != nil {
+		return nil, err
+	}
+	return foldSubBalances(accounts), nil
 }
 
 func (s *Service) GetAccount(ctx context.Context, id string) (*Account, error) {
@@ -75,36 +125,86 @@ func (s *Service) GetAccount(ctx context.Context, id string) (*Account, error) {
 	if err != nil {
 		return nil, err
 	}
-	account.ShowStatus = normalizeShowStatus(account.ShowStatus)
-	account.IsArchived = account.ShowStatus == "archived"
+	subs, err := s.subBalances(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	accounts := append([]*Account{account}, subs...)
+	for _, item := range accounts {
+		normalizeAccount(item)
+	}
+	if err := s.applyProjectedBalances(ctx, accounts); err != nil {
+		return nil, err
+	}
+	if len(subs) > 0 {
+		account = foldSubBalances(accounts)[0]
+	}
 	return account, nil
 }
 
+// CreateAccount opens the account and a sub-balance for each extra currency
+// listed in Balances.
 func (s *Service) CreateAccount(ctx context.Context, account *Account) (*Account, *Transaction, error) {
-	normalizeAccount(account)
+	extra := account.Balances
+	account.Balances = nil
+	account.ParentAccountID = nil
 	account.CurrentBalance = account.InitialBalance
+	normalizeAccount(account)
+	if err := validateCreditTerms(account); err != nil {
+		return nil, nil, err
+	}
+	if err := validateNewBalances(account, nil, extra); err != nil {
+		return nil, nil, err
+	}
 	openingTxn, err := s.repo.CreateAccount(ctx, account)
 	if err != nil {
 		return nil, nil, err
 	}
+	subs := make([]*Account, 0, len(extra))
+	for _, balance := range extra {
+		sub, err := s.createSubBalance(ctx, account, balance)
+		if err != nil {
+			return nil, nil, err
+		}
+		subs = append(subs, sub)
+	}
 	s.invalidateFinanceSummaryCache(ctx)
+	if len(subs) > 0 {
+		account = foldSubBalances(append([]*Account{account}, subs...))[0]
+	}
 	return account, openingTxn, nil
 }
 
 func (s *Service) UpdateAccount(ctx context.Context, id string, account *Account) (*Account, error) {
+	current, err := s.repo.GetAccountByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 	account.ID = id
+	account.ParentAccountID = current.ParentAccountID
 	normalizeAccount(account)
-	if err := s.repo.UpdateAccount(ctx, account); err != nil {
+	if account.Credit == nil && isCreditAccountType(account.AccountType) {
+		// A full update that leaves the terms out keeps the ones on file.
+		account.Credit = current.Credit
+	}
+	if err := validateCreditTerms(account); err != nil {
 		return nil, err
 	}
-	s.invalidateFinanceSummaryCache(ctx)
-	updated, err := s.repo.GetAccountByID(ctx, id)
+	subs, err := s.subBalances(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	updated.ShowStatus = normalizeShowStatus(updated.ShowStatus)
-	updated.IsArchived = updated.ShowStatus == "archived"
-	return updated, nil
+	if err := validateAccountUpdate(current, account, subs); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateAccount(ctx, account); err != nil {
+		return nil, err
+	}
+	if err := s.syncSubBalances(ctx, account, subs); err != nil {
+		return nil, err
+	}
+	s.invalidateFinanceSummaryCache(ctx)
+	return s.GetAccount(ctx, id)
 }
 
 func (s *Service) PatchAccount(ctx context.Context, id string, fields map[string]interface{}) (*Account, error) {
@@ -112,22 +212,42 @@ func (s *Service) PatchAccount(ctx context.Context, id string, fields map[string
 	if err != nil {
 		return nil, err
 	}
+	before := *current
 	applyAccountPatch(current, fields)
 	normalizeAccount(current)
-	if err := s.repo.UpdateAccount(ctx, current); err != nil {
+	if err := validateCreditTerms(current); err != nil {
 		return nil, err
 	}
-	s.invalidateFinanceSummaryCache(ctx)
-	updated, err := s.repo.GetAccountByID(ctx, id)
+	subs, err := s.subBalances(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	updated.ShowStatus = normalizeShowStatus(updated.ShowStatus)
-	updated.IsArchived = updated.ShowStatus == "archived"
-	return updated, nil
+	if err := validateAccountUpdate(&before, current, subs); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateAccount(ctx, current); err != nil {
+		return nil, err
+	}
+	if err := s.syncSubBalances(ctx, current, subs); err != nil {
+		return nil, err
+	}
+	s.invalidateFinanceSummaryCache(ctx)
+	return s.GetAccount(ctx, id)
 }
 
+// DeleteAccount deletes the account, after its sub-balances if it holds
+// several currencies, and returns the entry withdrawing its own balance.
 func (s *Service) DeleteAccount(ctx context.Context, id string) (*Transaction, error) {
+	subs, err := s.subBalances(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range subs {
+		if _, err := s.repo.DeleteAccount(ctx, sub.ID); err != nil {
+			log.Printf("[Service.DeleteAccount] Error for sub-balance id=%s: %v", sub.ID, err)
+			return nil, err
+		}
+	}
 	txn, err := s.repo.DeleteAccount(ctx, id)
 	if err != nil {
 		log.Printf("[Service.DeleteAccount] Error for id=%s: %v", id, err)
@@ -136,16 +256,55 @@ func (s *Service) DeleteAccount(ctx context.Context, id string) (*Transaction, e
 	return txn, err
 }
 
-func (s *Service) Transactions(ctx context.Context, filter TransactionFilter) ([]*Transaction, error) {
-	transactions, err := s.repo.ListTransactions(ctx)
+// Transactions returns one page of the user's transactions matching filter,
+// in the filter's sort order (newest first by default).
+func (s *Service) Transactions(ctx context.Context, filter TransactionFilter, page TransactionPageRequest) (*TransactionPage, error) {
+	if err := normalizeTransactionFilter(&filter); err != nil {
+		return nil, err
+	}
+	if page.Cursor != nil && (page.Cursor.SortBy != filter.SortBy || page.Cursor.SortOrder != filter.SortOrder) {
+		return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "cursor"})
+	}
+	if page.Limit <= 0 {
+		page.Limit = defaultTransactionPageLimit
+	}
+	if filter.AccountID != "" {
+		subs, err := s.subBalances(ctx, filter.AccountID)
+		if err != nil {
+			return nil, err
+		}
+		for _, sub := range subs {
+			if len(filter.AccountIDs) == 0 {
+				filter.AccountIDs = []string{filter.AccountID}
+			}
+			filter.AccountIDs = append(filter.AccountIDs, sub.ID)
+		}
+	}
+	transactions, err := s.repo.ListTransactionsPage(ctx, filter, page)
 	if err != nil {
 		return nil, err
 	}
-	transactions = filterTransactions(transactions, filter)
+	result := &TransactionPage{Limit: page.Limit}
+	if len(transactions) > page.Limit {
+		transactions = transactions[:page.Limit]
+		result.HasMore = true
+	}
 	for _, txn := range transactions {
 		normalizeTransaction(txn)
 	}
-	return transactions, nil
+	if result.HasMore {
+		result.NextCursor = transactionCursorFor(transactions[len(transactions)-1], filter).Encode()
+	}
+	if page.Cursor == nil && page.Page > 0 {
+		total, err := s.repo.CountTransactions(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		result.Page = page.Page
+		result.Total = total
+	}
+	result.Items = transactions
+	return result, nil
 }
 
 func (s *Service) GetTransaction(ctx context.Context, id string) (*Transaction, error) {
@@ -158,14 +317,41 @@ func (s *Service) GetTransaction(ctx context.Context, id string) (*Transaction,
 }
 
 func (s *Service) CreateTransaction(ctx context.Context, txn *Transaction) (*Transaction, error) {
-	normalizeTransaction(txn)
+	if err := s.prepareTransaction(ctx, txn); err != nil {
+		return nil, err
+	}
 	if err := s.repo.CreateTransaction(ctx, txn); err != nil {
 		return nil, err
 	}
 	s.invalidateFinanceSummaryCache(ctx)
+	s.syncBudgetRollups(ctx, transactionBudgetIDs(txn))
+	for _, budgetID := range transactionBudgetIDs(txn) {
+		s.evaluateBudgetAlerts(ctx, budgetID)
+	}
 	return txn, nil
 }
 
+// prepareTransaction runs the checks and defaults every new entry goes
+// through before it is stored, whether created directly or booked as a
+// correction.
+func (s *Service) prepareTransaction(ctx context.Context, txn *Transaction) error {
+	normalizeTransaction(txn)
+	scheduleIfFutureDated(txn, time.Now().UTC())
+	if err := s.routeToSubBalances(ctx, txn); err != nil {
+		return err
+	}
+	if err := s.validateTransactionSplits(ctx, txn); err != nil {
+		return err
+	}
+	attachments, err := s.validateAttachmentIDs(ctx, txn.Attachments)
+	if err != nil {
+		return err
+	}
+	txn.Attachments = attachments
+	s.applyCategorizationRules(ctx, txn)
+	return nil
+}
+
 func (s *Service) UpdateTransaction(ctx context.Context, id string, txn *Transaction) (*Transaction, error) {
 	return nil, appErrors.TransactionImmutable
 }
@@ -178,6 +364,92 @@ func (s *Service) DeleteTransaction(ctx context.Context, id string) error {
 	return appErrors.TransactionImmutable
 }
 
+// ReverseTransaction voids a ledger entry by posting compensating entries.
+func (s *Service) ReverseTransaction(ctx context.Context, id string, input TransactionReversalInput) (*TransactionReversal, error) {
+	if strings.TrimSpace(input.Date) != "" {
+		input.Date = normalizeDateInput(input.Date)
+		if _, err := time.Parse("2006-01-02", input.Date); err != nil {
+			return nil, appErrors.WithDetails(appErrors.InvalidTransactionDate, map[string]interface{}{"field": "date"})
+		}
+	}
+	original, err := s.repo.GetTransactionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if original.IsScheduled {
+		// Nothing to reverse yet; the entry is skipped instead.
+		return nil, appErrors.WithDetails(appErrors.TransactionNotReversible, map[string]interface{}{"reason": "scheduled"})
+	}
+	result, err := s.repo.ReverseTransaction(ctx, id, input)
+	if err != nil {
+		log.Printf("[Service.ReverseTransaction] Error for id=%s: %v", id, err)
+		return nil, err
+	}
+	for _, txn := range result.Voided {
+		normalizeTransaction(txn)
+	}
+	for _, txn := range result.Reversals {
+		normalizeTransaction(txn)
+	}
+	if result.Correction != nil {
+		normalizeTransaction(result.Correction)
+	}
+	s.invalidateFinanceSummaryCache(ctx)
+	s.syncBudgetRollups(ctx, reversalBudgetIDs(result))
+	return result, nil
+}
+
+// CorrectTransaction reverses an income, expense or transfer and books the
+// patched copy in its place. The copy goes through the same steps as a new
+// entry, so a future date schedules it and a new currency lands on the
+// matching sub-balance. Split entries need new splits when the amount changes.
+func (s *Service) CorrectTransaction(ctx context.Context, id string, fields map[string]interface{}, note *string) (*TransactionReversal, error) {
+	original, err := s.repo.GetTransactionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if original.Status == TransactionStatusVoided {
+		return nil, appErrors.TransactionAlreadyVoided
+	}
+	replacement := buildCorrectionTransaction(original)
+	if replacement == nil {
+		return nil, appErrors.WithDetails(appErrors.TransactionNotReversible, map[string]interface{}{"type": original.Type})
+	}
+	applyTransactionPatch(replacement, fields)
+	if replacement.Type != original.Type && !(replacement.Type == TransactionTypeTransfer && isTransferLeg(original.Type)) {
+		return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "type"})
+	}
+	if replacement.Amount <= 0 {
+		return nil, appErrors.InvalidAmount
+	}
+	if _, resplit := fields["splits"]; len(original.Splits) > 0 && !resplit && replacement.Amount != original.Amount {
+		return nil, appErrors.WithDetails(appErrors.InvalidTransactionSplit, map[string]interface{}{
+			"field":  "splits",
+			"reason": "amount changed without new splits",
+		})
+	}
+	replacement.Date = normalizeDateInput(replacement.Date)
+	if _, err := time.Parse("2006-01-02", replacement.Date); err != nil {
+		return nil, appErrors.WithDetails(appErrors.InvalidTransactionDate, map[string]interface{}{"field": "date"})
+	}
+	if !strings.EqualFold(replacement.Currency, original.Currency) {
+		if err := s.unrouteSubBalances(ctx, replacement); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.prepareTransaction(ctx, replacement); err != nil {
+		return nil, err
+	}
+	result, err := s.ReverseTransaction(ctx, id, TransactionReversalInput{Note: note, Replacement: replacement})
+	if err != nil {
+		return nil, err
+	}
+	for _, budgetID := range transactionBudgetIDs(result.Correction) {
+		s.evaluateBudgetAlerts(ctx, budgetID)
+	}
+	return result, nil
+}
+
 func (s *Service) FinanceSummary(ctx context.Context, dateFrom, dateTo, baseCurrency string, accountIDs []string) (*FinanceSummary, error) {
 	if cached := s.getFinanceSummaryCache(ctx, dateFrom, dateTo, baseCurrency, accountIDs); cached != nil {
 		return cached, nil
@@ -200,6 +472,7 @@ func (s *Service) FinanceSummary(ctx context.Context, dateFrom, dateTo, baseCurr
 	if err != nil {
 		return nil, err
 	}
+	transactions, upcomingTransactions := splitScheduledTransactions(transactions)
 	filtered := filterTransactions(transactions, TransactionFilter{DateFrom: dateFrom, DateTo: dateTo})
 
 	accountFilter := make(map[string]bool)
@@ -211,31 +484,70 @@ func (s *Service) FinanceSummary(ctx context.Context, dateFrom, dateTo, baseCurr
 		}
 	}
 	if len(accountFilter) > 0 {
+		// Picking a multi-currency account picks all of its currencies.
+		for _, account := range accounts {
+			if isSubBalance(account) && accountFilter[*account.ParentAccountID] {
+				accountFilter[account.ID] = true
+			}
+		}
 		accounts = filterAccountsByIDs(accounts, accountFilter)
 		filtered = filterTransactionsByAccount(filtered, accountFilter)
+		upcomingTransactions = filterTransactionsByAccount(upcomingTransactions, accountFilter)
 	}
 
-	totalBalance := 0.0
-	byCurrency := make(map[string]float64)
+	totalBalance := Money(0)
+	byCurrency := make(map[string]Money)
 	accountsSummary := make([]FinanceSummaryAccount, 0, len(accounts))
+	accountBalances := make(map[string][]FinanceSummaryAccountBalance)
+	listed := make(map[string]bool, len(accounts))
+	for _, account := range accounts {
+		listed[account.ID] = true
+	}
 	for _, account := range accounts {
 		if account.Currency != "" {
 			byCurrency[account.Currency] += account.CurrentBalance
 		}
 		baseBalance := convertToSummaryBase(s, ctx, account.CurrentBalance, account.Currency, baseCurrency, rateDate)
 		totalBalance += baseBalance
-		accountsSummary = append(accountsSummary, FinanceSummaryAccount{
-			ID:          account.ID,
-			Name:        account.Name,
+		wallet := walletID(account)
+		if !listed[wallet] {
+			wallet = account.ID
+		}
+		accountBalances[wallet] = append(accountBalances[wallet], FinanceSummaryAccountBalance{
+			Currency:    account.Currency,
 			Balance:     account.CurrentBalance,
 			BalanceBase: baseBalance,
-			Currency:    account.Currency,
 		})
+		if wallet != account.ID {
+			continue
+		}
+		accountsSummary = append(accountsSummary, FinanceSummaryAccount{
+			ID:       account.ID,
+			Name:     account.Name,
+			Balance:  account.CurrentBalance,
+			Currency: account.Currency,
+		})
+	}
+	for i := range accountsSummary {
+		item := &accountsSummary[i]
+		balances := accountBalances[item.ID]
+		for _, balance := range balances {
+			item.BalanceBase += balance.BalanceBase
+		}
+		if len(balances) > 1 {
+			sort.SliceStable(balances, func(a, b int) bool {
+				if (balances[a].Currency == item.Currency) != (balances[b].Currency == item.Currency) {
+					return balances[a].Currency == item.Currency
+				}
+				return balances[a].Currency < balances[b].Currency
+			})
+			item.Balances = balances
+		}
 	}
 
-	totalIncome := 0.0
-	totalExpense := 0.0
-	categoryTotals := map[string]float64{}
+	totalIncome := Money(0)
+	totalExpense := Money(0)
+	categoryTotals := map[string]Money{}
 	for _, txn := range filtered {
 		txnCurrency := resolveTransactionCurrency(txn, accountCurrencyMap, baseCurrency)
 		txnDate := resolveTransactionDate(txn, rateDate)
@@ -247,8 +559,13 @@ func (s *Service) FinanceSummary(ctx context.Context, dateFrom, dateTo, baseCurr
 		if impact < 0 {
 			totalExpense += -impact
 		}
-		if txn.CategoryID != nil && *txn.CategoryID != "" && txn.Type == TransactionTypeExpense {
-			categoryTotals[*txn.CategoryID] += baseAmount
+		if txn.Type == TransactionTypeExpense {
+			amounts := splitAmounts(txn, baseAmount)
+			for i, line := range transactionLines(txn) {
+				if line.CategoryID != nil && *line.CategoryID != "" {
+					categoryTotals[*line.CategoryID] += amounts[i]
+				}
+			}
 		}
 	}
 
@@ -280,8 +597,8 @@ func (s *Service) FinanceSummary(ctx context.Context, dateFrom, dateTo, baseCurr
 		if len(accountFilter) > 0 {
 			prevFiltered = filterTransactionsByAccount(prevFiltered, accountFilter)
 		}
-		prevIncome := 0.0
-		prevExpense := 0.0
+		prevIncome := Money(0)
+		prevExpense := Money(0)
 		for _, txn := range prevFiltered {
 			txnCurrency := resolveTransactionCurrency(txn, accountCurrencyMap, baseCurrency)
 			txnDate := resolveTransactionDate(txn, rateDate)
@@ -294,12 +611,13 @@ func (s *Service) FinanceSummary(ctx context.Context, dateFrom, dateTo, baseCurr
 				prevExpense += -impact
 			}
 		}
-		changes.Income = percentChange(totalIncome, prevIncome)
-		changes.Expense = percentChange(totalExpense, prevExpense)
+		changes.Income = percentChange(totalIncome.Float64(), prevIncome.Float64())
+		changes.Expense = percentChange(totalExpense.Float64(), prevExpense.Float64())
 	}
 
 	progress := buildSummaryProgress(s, ctx, accounts, dateFrom, dateTo, baseCurrency, rateDate, accountFilter, totalExpense)
 	recentTransactions := buildSummaryRecentTransactions(s, ctx, filtered, accountCurrencyMap, baseCurrency, rateDate)
+	upcoming := buildSummaryUpcomingTransactions(s, ctx, upcomingTransactions, accountCurrencyMap, baseCurrency, rateDate)
 	events := buildSummaryEvents(s, ctx, accountFilter, dateFrom, dateTo, baseCurrency, rateDate)
 
 	summary := &FinanceSummary{
@@ -320,7 +638,8 @@ func (s *Service) FinanceSummary(ctx context.Context, dateFrom, dateTo, baseCurr
 		TopCategories: topCategories,
 		Progress:      progress,
 		RecentTransactions: recentTransactions,
-		Events:        events,
+		Upcoming:           upcoming,
+		Events:             events,
 	}
 	s.setFinanceSummaryCache(ctx, dateFrom, dateTo, baseCurrency, accountIDs, summary)
 	return summary, nil
@@ -348,7 +667,7 @@ func normalizeSummaryBaseCurrency(baseCurrency string, accounts []*Account) stri
 		return strings.ToUpper(trimmed)
 	}
 	for _, account := range accounts {
-		if account != nil && strings.TrimSpace(account.Currency) != "" {
+		if account != nil && !isSubBalance(account) && strings.TrimSpace(account.Currency) != "" {
 			return strings.ToUpper(account.Currency)
 		}
 	}
@@ -365,15 +684,15 @@ func resolveSummaryRateDate(dateFrom, dateTo string) string {
 	return time.Now().UTC().Format("2006-01-02")
 }
 
-func convertToSummaryBase(s *Service, ctx context.Context, amount float64, fromCurrency, baseCurrency, dateValue string) float64 {
+func convertToSummaryBase(s *Service, ctx context.Context, amount Money, fromCurrency, baseCurrency, dateValue string) Money {
 	if strings.TrimSpace(fromCurrency) == "" || strings.EqualFold(fromCurrency, baseCurrency) {
 		return amount
 	}
 	rate, err := s.resolveFXRate(ctx, fromCurrency, baseCurrency, dateValue)
-	if err != nil || rate == 0 {
+	if err != nil || rate.IsZero() {
 		return amount
 	}
-	return amount * rate
+	return rate.Convert(amount, baseCurrency)
 }
 
 func resolveTransactionCurrency(txn *Transaction, accountCurrencyMap map[string]string, fallbackCurrency string) string {
@@ -411,8 +730,8 @@ func resolveTransactionDate(txn *Transaction, fallback string) string {
 	return fallback
 }
 
-func transactionImpactForSummaryAmount(txn *Transaction, amount float64) float64 {
-	if txn == nil {
+func transactionImpactForSummaryAmount(txn *Transaction, amount Money) Money {
+	if txn == nil || txn.IsScheduled {
 		return 0
 	}
 	switch txn.Type {
@@ -527,27 +846,7 @@ func buildSummaryRecentTransactions(
 		if txn.Type == TransactionTypeTransfer || txn.Type == TransactionTypeTransferIn || txn.Type == TransactionTypeTransferOut {
 			continue
 		}
-		txnCurrency := resolveTransactionCurrency(txn, accountCurrencyMap, baseCurrency)
-		txnDate := resolveTransactionDate(txn, rateDate)
-		baseAmount := convertToSummaryBase(s, ctx, txn.Amount, txnCurrency, baseCurrency, txnDate)
-		description := ""
-		if txn.Description != nil && strings.TrimSpace(*txn.Description) != "" {
-			description = *txn.Description
-		} else if txn.Name != nil {
-			description = *txn.Name
-		}
-		recent = append(recent, FinanceSummaryTransaction{
-			ID:            txn.ID,
-			Type:          txn.Type,
-			Amount:        baseAmount,
-			Currency:      baseCurrency,
-			Date:          txn.Date,
-			Description:   description,
-			CategoryID:    txn.CategoryID,
-			AccountID:     txn.AccountID,
-			FromAccountID: txn.FromAccountID,
-			ToAccountID:   txn.ToAccountID,
-		})
+		recent = append(recent, buildSummaryTransaction(s, ctx, txn, accountCurrencyMap, baseCurrency, rateDate))
 		if len(recent) >= 5 {
 			break
 		}
@@ -555,20 +854,51 @@ func buildSummaryRecentTransactions(
 	return recent
 }
 
+func buildSummaryTransaction(
+	s *Service,
+	ctx context.Context,
+	txn *Transaction,
+	accountCurrencyMap map[string]string,
+	baseCurrency string,
+	rateDate string,
+) FinanceSummaryTransaction {
+	txnCurrency := resolveTransactionCurrency(txn, accountCurrencyMap, baseCurrency)
+	txnDate := resolveTransactionDate(txn, rateDate)
+	baseAmount := convertToSummaryBase(s, ctx, txn.Amount, txnCurrency, baseCurrency, txnDate)
+	description := ""
+	if txn.Description != nil && strings.TrimSpace(*txn.Description) != "" {
+		description = *txn.Description
+	} else if txn.Name != nil {
+		description = *txn.Name
+	}
+	return FinanceSummaryTransaction{
+		ID:            txn.ID,
+		Type:          txn.Type,
+		Amount:        baseAmount,
+		Currency:      baseCurrency,
+		Date:          txn.Date,
+		Description:   description,
+		CategoryID:    txn.CategoryID,
+		AccountID:     txn.AccountID,
+		FromAccountID: txn.FromAccountID,
+		ToAccountID:   txn.ToAccountID,
+	}
+}
+
 func buildSummaryProgress(
 	s *Service,
 	ctx context.Context,
 	accounts []*Account,
 	dateFrom, dateTo, baseCurrency, rateDate string,
 	accountFilter map[string]bool,
-	fallbackExpense float64,
+	fallbackExpense Money,
 ) FinanceSummaryProgress {
 	budgets, err := s.repo.ListBudgets(ctx)
 	if err != nil {
 		return FinanceSummaryProgress{Used: fallbackExpense}
 	}
-	totalLimit := 0.0
-	totalSpent := 0.0
+	totalLimit := Money(0)
+	totalSpent := Money(0)
 	for _, budget := range budgets {
 		if budget == nil {
 			continue
@@ -579,7 +909,7 @@ func buildSummaryProgress(
 		if !budgetMatchesRange(budget, dateFrom, dateTo) {
 			continue
 		}
-		limitBase := convertToSummaryBase(s, ctx, budget.LimitAmount, budget.Currency, baseCurrency, rateDate)
+		limitBase := convertToSummaryBase(s, ctx, budgetEffectiveLimit(budget), budget.Currency, baseCurrency, rateDate)
 		spentBase := convertToSummaryBase(s, ctx, budget.SpentAmount, budget.Currency, baseCurrency, rateDate)
 		totalLimit += limitBase
 		totalSpent += spentBase
@@ -590,7 +920,7 @@ func buildSummaryProgress(
 	}
 	percentage := 0.0
 	if totalLimit > 0 {
-		percentage = math.Round((used/totalLimit)*100)
+		percentage = math.Round(used.Ratio(totalLimit) * 100)
 		if percentage > 125 {
 			percentage = 125
 		}
@@ -645,26 +975,24 @@ func buildSummaryEvents(
 	dateFrom, dateTo, baseCurrency, rateDate string,
 ) []FinanceSummaryEvent {
 	events := make([]FinanceSummaryEvent, 0, 3)
-	debts, err := s.repo.ListDebts(ctx)
-	if err == nil && len(debts) > 0 {
-		filteredDebts := make([]*Debt, 0, len(debts))
-		for _, debt := range debts {
-			if debt == nil {
+	transitions, err := s.repo.ListDebtStatusEvents(ctx, 50)
+	if err == nil && len(transitions) > 0 {
+		seen := make(map[string]bool, len(transitions))
+		for _, transition := range transitions {
+			if transition == nil || seen[transition.DebtID] {
 				continue
 			}
-			if debt.Status == "paid" {
+			// Only the latest transition per debt counts, and only while the
+			// debt is still in that state.
+			seen[transition.DebtID] = true
+			if transition.ToStatus != DebtStatusDueSoon && transition.ToStatus != DebtStatusOverdue {
 				continue
 			}
-			if debt.FundingAccountID != nil && len(accountFilter) > 0 && !accountFilter[*debt.FundingAccountID] {
+			debt, err := s.repo.GetDebtByID(ctx, transition.DebtID)
+			if err != nil || debt == nil || debt.Status != transition.ToStatus {
 				continue
 			}
-			filteredDebts = append(filteredDebts, debt)
-		}
-		sort.Slice(filteredDebts, func(i, j int) bool {
-			return debtSortTime(filteredDebts[i]).Before(debtSortTime(filteredDebts[j]))
-		})
-		for _, debt := range filteredDebts {
-			if debt == nil {
+			if debt.FundingAccountID != nil && len(accountFilter) > 0 && !accountFilter[*debt.FundingAccountID] {
 				continue
 			}
 			amountBase := convertToSummaryBase(s, ctx, debt.PrincipalAmount, debt.PrincipalCurrency, baseCurrency, rateDate)
@@ -682,13 +1010,12 @@ func buildSummaryEvents(
 				title = fmt.Sprintf("You owe %s", debt.CounterpartyName)
 				icon = "alert"
 			}
-			timeLabel := describeDueDate(debt.DueDate)
 			events = append(events, FinanceSummaryEvent{
 				ID:          debt.ID,
 				Icon:        icon,
 				Title:       title,
 				Description: description,
-				Time:        timeLabel,
+				Time:        describeDebtStatusEvent(transition),
 			})
 			if len(events) >= 3 {
 				return events
@@ -717,7 +1044,7 @@ func buildSummaryEvents(
 		if budget.LimitAmount <= 0 {
 			continue
 		}
-		limitBase := convertToSummaryBase(s, ctx, budget.LimitAmount, budget.Currency, baseCurrency, rateDate)
+		limitBase := convertToSummaryBase(s, ctx, budgetEffectiveLimit(budget), budget.Currency, baseCurrency, rateDate)
 		spentBase := convertToSummaryBase(s, ctx, budget.SpentAmount, budget.Currency, baseCurrency, rateDate)
 		if spentBase <= limitBase {
 			continue
@@ -738,57 +1065,10 @@ func buildSummaryEvents(
 	return events
 }
 
-func debtSortTime(debt *Debt) time.Time {
-	if debt == nil {
-		return time.Time{}
-	}
-	if debt.DueDate != nil && strings.TrimSpace(*debt.DueDate) != "" {
-		if parsed, err := time.Parse("2006-01-02", *debt.DueDate); err == nil {
-			return parsed
-		}
-	}
-	if strings.TrimSpace(debt.StartDate) != "" {
-		if parsed, err := time.Parse("2006-01-02", debt.StartDate); err == nil {
-			return parsed
-		}
-	}
-	return time.Now().UTC()
-}
-
-func describeDueDate(dueDate *string) string {
-	if dueDate == nil || strings.TrimSpace(*dueDate) == "" {
-		return "No period"
-	}
-	target, err := time.Parse("2006-01-02", *dueDate)
-	if err != nil {
-		return "No period"
-	}
-	now := time.Now()
-	diffDays := int(math.Ceil(target.Sub(now).Hours() / 24))
-	if diffDays == 0 {
-		return "Due today"
-	}
-	if diffDays > 0 {
-		if diffDays == 1 {
-			return "In 1 day"
-		}
-		return fmt.Sprintf("In %d days", diffDays)
-	}
-	overdue := int(math.Abs(float64(diffDays)))
-	if overdue == 1 {
-		return "1 day overdue"
-	}
-	return fmt.Sprintf("%d days overdue", overdue)
-}
-
-func formatAmountForCurrency(amount float64, currency string) string {
-	rounded := roundAmountForCurrency(amount, currency)
-	decimals := 2
-	if strings.EqualFold(currency, "UZS") {
-		decimals = 0
-	}
-	format := "%." + strconv.Itoa(decimals) + "f %s"
-	return fmt.Sprintf(format, rounded, strings.ToUpper(currency))
+func formatAmountForCurrency(amount Money, currency string) string {
+	rounded := amount.RoundTo(currency)
+	format := "%." + strconv.Itoa(currencyDecimals(currency)) + "f %s"
+	return fmt.Sprintf(format, rounded.Float64(), strings.ToUpper(currency))
 }
 
 func (s *Service) Categories(ctx context.Context, categoryType string, activeOnly bool) ([]*FinanceCategory, error) {
@@ -887,7 +1167,11 @@ func (s *Service) GetBudget(ctx context.Context, id string) (*Budget, error) {
 }
 
 func (s *Service) CreateBudget(ctx context.Context, budget *Budget) (*Budget, error) {
+	budget.CarriedAmount = 0
 	normalizeBudget(budget)
+	seedBudgetWindow(budget, time.Now().UTC())
+	// Nothing points at the new budget yet.
+	applyBudgetRollups(budget, nil)
 	if err := s.repo.CreateBudget(ctx, budget); err != nil {
 		return nil, err
 	}
@@ -895,8 +1179,21 @@ func (s *Service) CreateBudget(ctx context.Context, budget *Budget) (*Budget, er
 }
 
 func (s *Service) UpdateBudget(ctx context.Context, id string, budget *Budget) (*Budget, error) {
+	current, err := s.repo.GetBudgetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 	budget.ID = id
+	budget.CarriedAmount = current.CarriedAmount
 	normalizeBudget(budget)
+	if budget.PeriodType == current.PeriodType && (budget.StartDate == nil || budget.EndDate == nil) {
+		// Keep the open window rather than jump past one not closed yet.
+		budget.StartDate, budget.EndDate = current.StartDate, current.EndDate
+	}
+	seedBudgetWindow(budget, time.Now().UTC())
+	if err := s.applyStoredBudgetRollups(ctx, budget); err != nil {
+		return nil, err
+	}
 	if err := s.repo.UpdateBudget(ctx, budget); err != nil {
 		return nil, err
 	}
@@ -910,65 +1207,305 @@ func (s *Service) PatchBudget(ctx context.Context, id string, fields map[string]
 	}
 	applyBudgetPatch(current, fields)
 	normalizeBudget(current)
+	seedBudgetWindow(current, time.Now().UTC())
+	if err := s.applyStoredBudgetRollups(ctx, current); err != nil {
+		return nil, err
+	}
 	if err := s.repo.UpdateBudget(ctx, current); err != nil {
 		return nil, err
 	}
 	return current, nil
 }
 
-type BudgetAddValueInput struct {
-	AccountID      string
-	Amount         float64
-	AmountCurrency string
-	Note           *string
-	Date           *string
-	CategoryID     *string
-}
-
-type BudgetAddValueResult struct {
-	Budget      *Budget
-	Transaction *Transaction
-}
-
-func (s *Service) AddBudgetValue(ctx context.Context, budgetID string, input BudgetAddValueInput) (*BudgetAddValueResult, error) {
-	if strings.TrimSpace(budgetID) == "" {
-		log.Printf("[Service.AddBudgetValue] Missing budgetID")
-		return nil, appErrors.InvalidFinanceData
-	}
-	if input.Amount <= 0 {
-		log.Printf("[Service.AddBudgetValue] Invalid amount=%.2f for budget=%s", input.Amount, budgetID)
-		return nil, appErrors.InvalidAmount
-	}
-	if strings.TrimSpace(input.AccountID) == "" {
-		log.Printf("[Service.AddBudgetValue] Missing accountID for budget=%s", budgetID)
-		return nil, appErrors.InvalidFinanceData
-	}
-
+// CloseBudgetPeriod snapshots the budget's current window, applies its
+// rollover mode and moves the budget into the next window. Only a window
+// that ended before asOf can be closed.
+func (s *Service) CloseBudgetPeriod(ctx context.Context, budgetID string, asOf time.Time) (*BudgetPeriod, *Budget, error) {
 	budget, err := s.repo.GetBudgetByID(ctx, budgetID)
 	if err != nil {
-		log.Printf("[Service.AddBudgetValue] Failed to get budget=%s: %v", budgetID, err)
-		return nil, err
+		return nil, nil, err
 	}
 	normalizeBudget(budget)
+	if !isRollingBudgetPeriod(budget.PeriodType) {
+		return nil, nil, appErrors.BudgetPeriodNotRolling
+	}
+	transactions, err := s.repo.ListTransactions(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	if strings.TrimSpace(input.AmountCurrency) == "" {
-		return nil, appErrors.InvalidCurrency
+	start, end := budgetPeriodWindow(budget, asOf)
+	startDate := start.Format("2006-01-02")
+	endDate := end.Format("2006-01-02")
+	if endDate >= asOf.UTC().Format("2006-01-02") {
+		return nil, nil, appErrors.BudgetPeriodNotEnded
 	}
-	if !strings.EqualFold(input.AmountCurrency, budget.Currency) {
-		log.Printf("[Service.AddBudgetValue] Currency mismatch: input=%s, budget=%s", input.AmountCurrency, budget.Currency)
-		return nil, appErrors.InvalidCurrency
+	budget.StartDate = &startDate
+	budget.EndDate = &endDate
+	applyBudgetRollups(budget, transactions)
+	period := buildBudgetPeriodSnapshot(budget)
+
+	nextStart, nextEnd := nextBudgetPeriodWindow(budget.PeriodType, start, end)
+	nextStartDate := nextStart.Format("2006-01-02")
+	nextEndDate := nextEnd.Format("2006-01-02")
+	budget.StartDate = &nextStartDate
+	budget.EndDate = &nextEndDate
+	budget.CarriedAmount = period.CarriedOut
+	applyBudgetRollups(budget, transactions)
+
+	if err := s.repo.CloseBudgetPeriod(ctx, budget, period); err != nil {
+		return nil, nil, err
 	}
+	s.invalidateFinanceSummaryCache(ctx)
+	return period, budget, nil
+}
 
-	account, err := s.repo.GetAccountByID(ctx, input.AccountID)
+// BudgetPeriods returns the closed periods of a budget, newest first,
+// optionally limited to periods starting within [from, to].
+func (s *Service) BudgetPeriods(ctx context.Context, budgetID, from, to string) ([]*BudgetPeriod, error) {
+	if _, err := s.repo.GetBudgetByID(ctx, budgetID); err != nil {
+		return nil, err
+	}
+	periods, err := s.repo.ListBudgetPeriods(ctx, budgetID)
 	if err != nil {
-		log.Printf("[Service.AddBudgetValue] Failed to get account=%s: %v", input.AccountID, err)
 		return nil, err
 	}
-	normalizeAccount(account)
-
-	dateValue := normalizeDateInput("")
-	if input.Date != nil && strings.TrimSpace(*input.Date) != "" {
-		dateValue = normalizeDateInput(*input.Date)
+	if from == "" && to == "" {
+		return periods, nil
+	}
+	filtered := make([]*BudgetPeriod, 0, len(periods))
+	for _, period := range periods {
+		if dateInRange(period.PeriodStart, from, to) {
+			filtered = append(filtered, period)
+		}
+	}
+	return filtered, nil
+}
+
+const maxBudgetPeriodCatchUp = 120
+
+// CloseElapsedBudgetPeriods closes every weekly/monthly budget window that
+// ended before asOf, across all users. Budgets that missed several boundaries
+// are closed one window at a time so each period gets its own snapshot.
+func (s *Service) CloseElapsedBudgetPeriods(ctx context.Context, asOf time.Time) (int, error) {
+	asOfDate := asOf.UTC().Format("2006-01-02")
+	due, err := s.repo.ListBudgetsDueForClose(ctx, asOfDate)
+	if err != nil {
+		return 0, err
+	}
+	closed := 0
+	for _, budget := range due {
+		userCtx := context.WithValue(ctx, "user_id", budget.UserID)
+		current := budget
+		for i := 0; i < maxBudgetPeriodCatchUp; i++ {
+			if current.EndDate == nil || *current.EndDate >= asOfDate {
+				break
+			}
+			_, next, err := s.CloseBudgetPeriod(userCtx, current.ID, asOf)
+			if err != nil {
+				log.Printf("[Service.CloseElapsedBudgetPeriods] Error for budget=%s: %v", current.ID, err)
+				break
+			}
+			closed++
+			current = next
+		}
+	}
+	return closed, nil
+}
+
+// syncBudgetRollups stores the live rollup of the given budgets after a
+// write moved their spending, so the stored figures stay what a read
+// computes and the ledger check only flags real drift. Failures are logged;
+// the check reports and repairs what is left.
+func (s *Service) syncBudgetRollups(ctx context.Context, budgetIDs []string) {
+	if len(budgetIDs) == 0 {
+		return
+	}
+	transactions, err := s.repo.ListTransactions(ctx)
+	if err != nil {
+		log.Printf("[Service.syncBudgetRollups] Failed to list transactions: %v", err)
+		return
+	}
+	for _, budgetID := range budgetIDs {
+		budget, err := s.repo.GetBudgetByID(ctx, budgetID)
+		if err != nil {
+			log.Printf("[Service.syncBudgetRollups] Failed to load budget=%s: %v", budgetID, err)
+			continue
+		}
+		s.storeBudgetRollup(ctx, budget, transactions)
+	}
+}
+
+// applyStoredBudgetRollups fills in the rollup of an edited budget before it
+// is written, rather than storing whatever the client sent.
+func (s *Service) applyStoredBudgetRollups(ctx context.Context, budget *Budget) error {
+	transactions, err := s.repo.ListTransactions(ctx)
+	if err != nil {
+		return err
+	}
+	applyBudgetRollups(budget, transactions)
+	return nil
+}
+
+// syncAllBudgetRollups does what syncBudgetRollups does for every budget of
+// the user, for writes that move many entries at once.
+func (s *Service) syncAllBudgetRollups(ctx context.Context) {
+	budgets, err := s.repo.ListBudgets(ctx)
+	if err != nil {
+		log.Printf("[Service.syncAllBudgetRollups] Failed to list budgets: %v", err)
+		return
+	}
+	transactions, err := s.repo.ListTransactions(ctx)
+	if err != nil {
+		log.Printf("[Service.syncAllBudgetRollups] Failed to list transactions: %v", err)
+		return
+	}
+	for _, budget := range budgets {
+		s.storeBudgetRollup(ctx, budget, transactions)
+	}
+}
+
+func (s *Service) storeBudgetRollup(ctx context.Context, budget *Budget, transactions []*Transaction) {
+	normalizeBudget(budget)
+	rolled := *budget
+	applyBudgetRollups(&rolled, transactions)
+	if rolled.SpentAmount == budget.SpentAmount && rolled.RemainingAmount == budget.RemainingAmount {
+		return
+	}
+	if err := s.repo.UpdateBudgetRollups(ctx, &rolled); err != nil {
+		log.Printf("[Service.storeBudgetRollup] Failed to store budget=%s: %v", budget.ID, err)
+	}
+}
+
+// evaluateBudgetAlerts notifies the user about thresholds the budget has
+// crossed in its current period. Every threshold is recorded at most once per
+// period; when several are crossed at once only the highest one is sent.
+// Failures are logged and never fail the transaction that triggered them.
+func (s *Service) evaluateBudgetAlerts(ctx context.Context, budgetID string) {
+	if s.notifications == nil {
+		return
+	}
+	budget, err := s.GetBudget(ctx, budgetID)
+	if err != nil {
+		log.Printf("[Service.evaluateBudgetAlerts] Failed to load budget=%s: %v", budgetID, err)
+		return
+	}
+	if !budget.NotifyOnExceed || budget.LimitAmount <= 0 {
+		return
+	}
+	periodStart := ""
+	if budget.StartDate != nil {
+		periodStart = *budget.StartDate
+	}
+
+	var fired *BudgetAlert
+	for _, threshold := range budgetAlertsReached(budget) {
+		alert := &BudgetAlert{
+			BudgetID:    budget.ID,
+			PeriodStart: periodStart,
+			Threshold:   threshold,
+			PercentUsed: math.Round(budget.PercentUsed*100) / 100,
+		}
+		inserted, err := s.repo.RecordBudgetAlert(ctx, alert)
+		if err != nil {
+			log.Printf("[Service.evaluateBudgetAlerts] Failed to record alert=%s for budget=%s: %v", threshold, budget.ID, err)
+			return
+		}
+		if inserted {
+			fired = alert
+		}
+	}
+	if fired == nil {
+		return
+	}
+	if _, err := s.notifications.Create(ctx, buildBudgetAlertNotification(budget, fired)); err != nil {
+		log.Printf("[Service.evaluateBudgetAlerts] Failed to notify alert=%s for budget=%s: %v", fired.Threshold, budget.ID, err)
+	}
+}
+
+// budgetAlertsReached lists the alert keys the budget has reached, in
+// ascending order, ending with BudgetAlertOverspent when it applies.
+func budgetAlertsReached(budget *Budget) []string {
+	reached := make([]string, 0, len(budget.AlertThresholds)+1)
+	for _, threshold := range budget.AlertThresholds {
+		if budget.PercentUsed >= float64(threshold) {
+			reached = append(reached, strconv.Itoa(threshold))
+		}
+	}
+	if budget.IsOverspent {
+		reached = append(reached, BudgetAlertOverspent)
+	}
+	return reached
+}
+
+func buildBudgetAlertNotification(budget *Budget, alert *BudgetAlert) *notifications.Notification {
+	if alert.Threshold == BudgetAlertOverspent {
+		return &notifications.Notification{
+			Title: fmt.Sprintf("%s is over budget", budget.Name),
+			Message: fmt.Sprintf("You have spent %.2f %s of your %.2f %s limit.",
+				budget.SpentAmount.Float64(), budget.Currency, budget.EffectiveLimit.Float64(), budget.Currency),
+		}
+	}
+	return &notifications.Notification{
+		Title: fmt.Sprintf("%s reached %s%%", budget.Name, alert.Threshold),
+		Message: fmt.Sprintf("You have spent %.2f %s of your %.2f %s limit (%.0f%%).",
+			budget.SpentAmount.Float64(), budget.Currency, budget.EffectiveLimit.Float64(), budget.Currency, budget.PercentUsed),
+	}
+}
+
+type BudgetAddValueInput struct {
+	AccountID      string
+	Amount         Money
+	AmountCurrency string
+	Note           *string
+	Date           *string
+	CategoryID     *string
+}
+
+type BudgetAddValueResult struct {
+	Budget      *Budget
+	Transaction *Transaction
+}
+
+func (s *Service) AddBudgetValue(ctx context.Context, budgetID string, input BudgetAddValueInput) (*BudgetAddValueResult, error) {
+	if strings.TrimSpace(budgetID) == "" {
+		log.Printf("[Service.AddBudgetValue] Missing budgetID")
+		return nil, appErrors.InvalidFinanceData
+	}
+	if input.Amount <= 0 {
+		log.Printf("[Service.AddBudgetValue] Invalid amount=%s for budget=%s", input.Amount, budgetID)
+		return nil, appErrors.InvalidAmount
+	}
+	if strings.TrimSpace(input.AccountID) == "" {
+		log.Printf("[Service.AddBudgetValue] Missing accountID for budget=%s", budgetID)
+		return nil, appErrors.InvalidFinanceData
+	}
+
+	budget, err := s.repo.GetBudgetByID(ctx, budgetID)
+	if err != nil {
+		log.Printf("[Service.AddBudgetValue] Failed to get budget=%s: %v", budgetID, err)
+		return nil, err
+	}
+	normalizeBudget(budget)
+
+	if strings.TrimSpace(input.AmountCurrency) == "" {
+		return nil, appErrors.InvalidCurrency
+	}
+	if !strings.EqualFold(input.AmountCurrency, budget.Currency) {
+		log.Printf("[Service.AddBudgetValue] Currency mismatch: input=%s, budget=%s", input.AmountCurrency, budget.Currency)
+		return nil, appErrors.InvalidCurrency
+	}
+
+	account, err := s.repo.GetAccountByID(ctx, input.AccountID)
+	if err != nil {
+		log.Printf("[Service.AddBudgetValue] Failed to get account=%s: %v", input.AccountID, err)
+		return nil, err
+	}
+	normalizeAccount(account)
+
+	dateValue := normalizeDateInput("")
+	if input.Date != nil && strings.TrimSpace(*input.Date) != "" {
+		dateValue = normalizeDateInput(*input.Date)
 	}
 	if _, err := time.Parse("2006-01-02", dateValue); err != nil {
 		return nil, appErrors.WithDetails(appErrors.InvalidTransactionDate, map[string]interface{}{"field": "date"})
@@ -978,27 +1515,26 @@ func (s *Service) AddBudgetValue(ctx context.Context, budgetID string, input Bud
 	budgetCurrency := budget.Currency
 
 	budgetToAccountRate := 1.0
-	debitAmount := input.Amount
+	debitAmount := input.Amount.RoundUpTo(accountCurrency)
 	if !strings.EqualFold(accountCurrency, budgetCurrency) {
 		rate, err := s.resolveFXRate(ctx, budgetCurrency, accountCurrency, dateValue)
 		if err != nil {
 			return nil, err
 		}
-		budgetToAccountRate = rate
-		if budgetToAccountRate <= 0 {
+		if rate.IsZero() {
 			return nil, appErrors.FXRateNotFound
 		}
-		debitAmount = input.Amount * budgetToAccountRate
+		budgetToAccountRate = rate.Float64()
+		debitAmount = rate.ConvertUp(input.Amount, accountCurrency)
 	}
 
-	debitAmount = roundAmountForCurrencyUp(debitAmount, accountCurrency)
 	if debitAmount <= 0 {
 		return nil, appErrors.InvalidAmount
 	}
-	if account.CurrentBalance < debitAmount {
+	if availableFunds(account) < debitAmount {
 		return nil, appErrors.WithDetails(appErrors.InsufficientFunds, map[string]interface{}{
 			"required":  debitAmount,
-			"available": account.CurrentBalance,
+			"available": availableFunds(account),
 			"currency":  accountCurrency,
 		})
 	}
@@ -1056,7 +1592,7 @@ func (s *Service) AddBudgetValue(ctx context.Context, budgetID string, input Bud
 
 type DebtValueInput struct {
 	AccountID      string
-	Amount         float64
+	Amount         Money
 	AmountCurrency string
 	Note           *string
 	Date           *string
@@ -1075,7 +1611,7 @@ func (s *Service) RepayDebt(ctx context.Context, debtID string, input DebtValueI
 		return nil, appErrors.InvalidFinanceData
 	}
 	if input.Amount <= 0 {
-		log.Printf("[Service.RepayDebt] Invalid amount=%.2f for debt=%s", input.Amount, debtID)
+		log.Printf("[Service.RepayDebt] Invalid amount=%s for debt=%s", input.Amount, debtID)
 		return nil, appErrors.InvalidAmount
 	}
 	if strings.TrimSpace(input.AccountID) == "" {
@@ -1112,7 +1648,7 @@ func (s *Service) RepayDebt(ctx context.Context, debtID string, input DebtValueI
 	debtCurrency := debt.PrincipalCurrency
 	accountCurrency := account.Currency
 
-	log.Printf("[Service.RepayDebt] === START === debtID=%s, inputAmount=%.2f, inputCurrency=%s, debtCurrency=%s, accountCurrency=%s, debt.ExchangeRateCurrent=%.6f, debt.RepaymentRateOnStart=%.6f, repaymentCurrency=%v",
+	log.Printf("[Service.RepayDebt] === START === debtID=%s, inputAmount=%s, inputCurrency=%s, debtCurrency=%s, accountCurrency=%s, debt.ExchangeRateCurrent=%.6f, debt.RepaymentRateOnStart=%.6f, repaymentCurrency=%v",
 		debtID, input.Amount, input.AmountCurrency, debtCurrency, accountCurrency, debt.ExchangeRateCurrent, debt.RepaymentRateOnStart, debt.RepaymentCurrency)
 
 	// Payment amount in debt currency - this is what will be deducted from debt
@@ -1143,12 +1679,12 @@ func (s *Service) RepayDebt(ctx context.Context, debtID string, input DebtValueI
 	// Convert amounts based on input currency
 	if inputIsAccountCurrency && !strings.EqualFold(accountCurrency, debtCurrency) {
 		// Input is in account currency - convert to debt currency for debt reduction
-		var rate float64
+		var rate ExchangeRate
 		// Use debt's stored rate when account currency matches repayment currency
 		if debtStoredRate > 0 && repaymentCurrency != "" && strings.EqualFold(accountCurrency, repaymentCurrency) {
 			// storedRate = principal→repayment, we need repayment→principal = 1/storedRate
-			rate = 1.0 / debtStoredRate
-			log.Printf("[Service.RepayDebt] Using debt stored rate (inverse): %.6f for %s->%s", rate, accountCurrency, debtCurrency)
+			rate = exchangeRateFromFloat(debtStoredRate).Inverse()
+			log.Printf("[Service.RepayDebt] Using debt stored rate (inverse): %.6f for %s->%s", rate.Float64(), accountCurrency, debtCurrency)
 		} else {
 			var err error
 			rate, err = s.resolveFXRate(ctx, accountCurrency, debtCurrency, dateValue)
@@ -1156,21 +1692,21 @@ func (s *Service) RepayDebt(ctx context.Context, debtID string, input DebtValueI
 				return nil, err
 			}
 		}
-		if rate <= 0 {
+		if rate.IsZero() {
 			return nil, appErrors.FXRateNotFound
 		}
-		paymentInDebtCurrency = input.Amount * rate
+		paymentInDebtCurrency = rate.ConvertUp(input.Amount, debtCurrency)
 		debitAmount = input.Amount // Account will be debited in its own currency
-		log.Printf("[Service.RepayDebt] Converting from account currency: %.2f %s -> %.2f %s (rate=%.6f)",
-			input.Amount, accountCurrency, paymentInDebtCurrency, debtCurrency, rate)
+		log.Printf("[Service.RepayDebt] Converting from account currency: %s %s -> %s %s (rate=%.6f)",
+			input.Amount, accountCurrency, paymentInDebtCurrency, debtCurrency, rate.Float64())
 	} else if inputIsDebtCurrency && !strings.EqualFold(debtCurrency, accountCurrency) {
 		// Input is in debt currency - convert to account currency for account debit
-		var rate float64
+		var rate ExchangeRate
 		// Use debt's stored rate when account currency matches repayment currency
 		if debtStoredRate > 0 && repaymentCurrency != "" && strings.EqualFold(accountCurrency, repaymentCurrency) {
 			// storedRate = principal→repayment = debtCurrency→accountCurrency
-			rate = debtStoredRate
-			log.Printf("[Service.RepayDebt] Using debt stored rate: %.6f for %s->%s", rate, debtCurrency, accountCurrency)
+			rate = exchangeRateFromFloat(debtStoredRate)
+			log.Printf("[Service.RepayDebt] Using debt stored rate: %.6f for %s->%s", rate.Float64(), debtCurrency, accountCurrency)
 		} else {
 			var err error
 			rate, err = s.resolveFXRate(ctx, debtCurrency, accountCurrency, dateValue)
@@ -1178,37 +1714,37 @@ func (s *Service) RepayDebt(ctx context.Context, debtID string, input DebtValueI
 				return nil, err
 			}
 		}
-		if rate <= 0 {
+		if rate.IsZero() {
 			return nil, appErrors.FXRateNotFound
 		}
-		debitAmount = input.Amount * rate
+		debitAmount = rate.ConvertUp(input.Amount, accountCurrency)
 		paymentInDebtCurrency = input.Amount // Debt will be reduced by input amount
-		log.Printf("[Service.RepayDebt] Converting from debt currency: %.2f %s -> %.2f %s (rate=%.6f)",
-			input.Amount, debtCurrency, debitAmount, accountCurrency, rate)
+		log.Printf("[Service.RepayDebt] Converting from debt currency: %s %s -> %s %s (rate=%.6f)",
+			input.Amount, debtCurrency, debitAmount, accountCurrency, rate.Float64())
 	}
 	// If currencies are same, both amounts remain as input.Amount
 
-	log.Printf("[Service.RepayDebt] BEFORE round: debitAmount=%.6f, paymentInDebtCurrency=%.6f", debitAmount, paymentInDebtCurrency)
-	debitAmount = roundAmountForCurrencyUp(debitAmount, accountCurrency)
-	log.Printf("[Service.RepayDebt] AFTER round: debitAmount=%.6f", debitAmount)
+	log.Printf("[Service.RepayDebt] BEFORE round: debitAmount=%s, paymentInDebtCurrency=%s", debitAmount, paymentInDebtCurrency)
+	debitAmount = debitAmount.RoundUpTo(accountCurrency)
+	log.Printf("[Service.RepayDebt] AFTER round: debitAmount=%s", debitAmount)
 	if debitAmount <= 0 {
 		return nil, appErrors.InvalidAmount
 	}
-	if debt.Direction == "i_owe" && account.CurrentBalance < debitAmount {
+	if debt.Direction == "i_owe" && availableFunds(account) < debitAmount {
 		return nil, appErrors.WithDetails(appErrors.InsufficientFunds, map[string]interface{}{
 			"required":  debitAmount,
-			"available": account.CurrentBalance,
+			"available": availableFunds(account),
 			"currency":  accountCurrency,
 		})
 	}
 
-	accountToDebtRate := 1.0
+	accountToDebtRate := exchangeRateOne()
 	if !strings.EqualFold(accountCurrency, debtCurrency) {
 		// Use debt's stored rate when account currency matches repayment currency
 		if debtStoredRate > 0 && repaymentCurrency != "" && strings.EqualFold(accountCurrency, repaymentCurrency) {
 			// storedRate = principal→repayment, we need repayment→principal = 1/storedRate
-			accountToDebtRate = 1.0 / debtStoredRate
-			log.Printf("[Service.RepayDebt] accountToDebtRate from debt stored rate (inverse): %.6f", accountToDebtRate)
+			accountToDebtRate = exchangeRateFromFloat(debtStoredRate).Inverse()
+			log.Printf("[Service.RepayDebt] accountToDebtRate from debt stored rate (inverse): %.6f", accountToDebtRate.Float64())
 		} else {
 			rate, err := s.resolveFXRate(ctx, accountCurrency, debtCurrency, dateValue)
 			if err != nil {
@@ -1216,30 +1752,30 @@ func (s *Service) RepayDebt(ctx context.Context, debtID string, input DebtValueI
 			}
 			accountToDebtRate = rate
 		}
-		if accountToDebtRate <= 0 {
+		if accountToDebtRate.IsZero() {
 			return nil, appErrors.FXRateNotFound
 		}
 	}
 
-	debtToBaseRate := 1.0
+	debtToBaseRate := exchangeRateOne()
 	if debt.BaseCurrency != "" && !strings.EqualFold(debt.BaseCurrency, debtCurrency) {
 		rate, err := s.resolveFXRate(ctx, debtCurrency, debt.BaseCurrency, dateValue)
 		if err != nil {
 			return nil, err
 		}
 		debtToBaseRate = rate
-		if debtToBaseRate <= 0 {
+		if debtToBaseRate.IsZero() {
 			return nil, appErrors.FXRateNotFound
 		}
 	}
 
-	remaining := debt.RemainingAmount
+	remaining := debt.TotalDue
 	if remaining <= 0 {
 		remaining = debt.PrincipalAmount
 	}
 	// Round payment in debt currency for comparison
-	paymentInDebtCurrency = roundAmountForCurrencyUp(paymentInDebtCurrency, debtCurrency)
-	isFullPayment := remaining > 0 && paymentInDebtCurrency >= remaining-0.01
+	paymentInDebtCurrency = paymentInDebtCurrency.RoundUpTo(debtCurrency)
+	isFullPayment := remaining > 0 && paymentInDebtCurrency >= remaining
 
 	// Determine the applied rate for audit trail:
 	// Use client-provided rate if available, otherwise use debt's stored rate.
@@ -1253,9 +1789,9 @@ func (s *Service) RepayDebt(ctx context.Context, debtID string, input DebtValueI
 		Amount:                debitAmount,
 		Currency:              accountCurrency,
 		BaseCurrency:          debt.BaseCurrency,
-		RateUsedToBase:        debtToBaseRate,
-		ConvertedAmountToBase: paymentInDebtCurrency * debtToBaseRate,
-		RateUsedToDebt:        accountToDebtRate,
+		RateUsedToBase:        debtToBaseRate.Float64(),
+		ConvertedAmountToBase: debtToBaseRate.Convert(paymentInDebtCurrency, debt.BaseCurrency),
+		RateUsedToDebt:        accountToDebtRate.Float64(),
 		ConvertedAmountToDebt: paymentInDebtCurrency,
 		PaymentDate:           dateValue,
 		AccountID:             &account.ID,
@@ -1286,7 +1822,7 @@ func (s *Service) RepayDebt(ctx context.Context, debtID string, input DebtValueI
 	if err != nil {
 		return nil, err
 	}
-	if updatedDebt.RemainingAmount <= 0.01 && updatedDebt.Status != "paid" {
+	if updatedDebt.TotalDue <= 0 && updatedDebt.Status != "paid" {
 		updatedDebt.Status = "paid"
 		now := time.Now().UTC().Format(time.RFC3339)
 		updatedDebt.SettledAt = &now
@@ -1346,29 +1882,28 @@ func (s *Service) AddDebtValue(ctx context.Context, debtID string, input DebtVal
 	accountCurrency := account.Currency
 
 	debtToAccountRate := 1.0
-	debitAmount := input.Amount
+	debitAmount := input.Amount.RoundUpTo(accountCurrency)
 	if !strings.EqualFold(debtCurrency, accountCurrency) {
 		rate, err := s.resolveFXRate(ctx, debtCurrency, accountCurrency, dateValue)
 		if err != nil {
 			return nil, err
 		}
-		debtToAccountRate = rate
-		if debtToAccountRate <= 0 {
+		if rate.IsZero() {
 			return nil, appErrors.FXRateNotFound
 		}
-		debitAmount = input.Amount * debtToAccountRate
+		debtToAccountRate = rate.Float64()
+		debitAmount = rate.ConvertUp(input.Amount, accountCurrency)
 	}
 
-	debitAmount = roundAmountForCurrencyUp(debitAmount, accountCurrency)
 	if debitAmount <= 0 {
 		return nil, appErrors.InvalidAmount
 	}
 
 	isExpense := debt.Direction == "they_owe_me"
-	if isExpense && account.CurrentBalance < debitAmount {
+	if isExpense && availableFunds(account) < debitAmount {
 		return nil, appErrors.WithDetails(appErrors.InsufficientFunds, map[string]interface{}{
 			"required":  debitAmount,
-			"available": account.CurrentBalance,
+			"available": availableFunds(account),
 			"currency":  accountCurrency,
 		})
 	}
@@ -1379,39 +1914,35 @@ func (s *Service) AddDebtValue(ctx context.Context, debtID string, input DebtVal
 		if err != nil {
 			return nil, err
 		}
-		accountToDebtRate = rate
-		if accountToDebtRate <= 0 {
+		if rate.IsZero() {
 			return nil, appErrors.FXRateNotFound
 		}
+		accountToDebtRate = rate.Float64()
 	}
 
 	transactionDelta := debitAmount
+	convertedToDebt := input.Amount
 	if isExpense {
 		transactionDelta = -debitAmount
+		convertedToDebt = -input.Amount
 	}
 
 	linkedDebtID := debt.ID
 	txn := &Transaction{
-		Type:           TransactionTypeDebtAddValue,
-		AccountID:      &account.ID,
-		Amount:         transactionDelta,
-		Currency:       accountCurrency,
-		BaseCurrency:   debtCurrency,
-		RateUsedToBase: accountToDebtRate,
-		ConvertedAmountToBase: input.Amount * func() float64 {
-			if transactionDelta < 0 {
-				return -1
-			} else {
-				return 1
-			}
-		}(),
-		DebtID:           &linkedDebtID,
-		RelatedDebtID:    &linkedDebtID,
-		Description:      input.Note,
-		Date:             dateValue,
-		OriginalCurrency: &debtCurrency,
-		OriginalAmount:   input.Amount,
-		ConversionRate:   debtToAccountRate,
+		Type:                  TransactionTypeDebtAddValue,
+		AccountID:             &account.ID,
+		Amount:                transactionDelta,
+		Currency:              accountCurrency,
+		BaseCurrency:          debtCurrency,
+		RateUsedToBase:        accountToDebtRate,
+		ConvertedAmountToBase: convertedToDebt,
+		DebtID:                &linkedDebtID,
+		RelatedDebtID:         &linkedDebtID,
+		Description:           input.Note,
+		Date:                  dateValue,
+		OriginalCurrency:      &debtCurrency,
+		OriginalAmount:        input.Amount,
+		ConversionRate:        debtToAccountRate,
 	}
 
 	createdTxn, err := s.CreateTransaction(ctx, txn)
@@ -1421,7 +1952,7 @@ func (s *Service) AddDebtValue(ctx context.Context, debtID string, input DebtVal
 	s.invalidateFinanceSummaryCache(ctx)
 
 	debt.PrincipalAmount += input.Amount
-	debt.PrincipalBaseValue = debt.PrincipalAmount * debt.RateOnStart
+	debt.PrincipalBaseValue = debtPrincipalBaseValue(debt)
 	if err := s.repo.UpdateDebt(ctx, debt); err != nil {
 		return nil, err
 	}
@@ -1472,16 +2003,8 @@ func (s *Service) GetDebt(ctx context.Context, id string) (*Debt, error) {
 	return debt, nil
 }
 
-func (s *Service) AccountTransactions(ctx context.Context, accountID string) ([]*Transaction, error) {
-	transactions, err := s.repo.ListTransactions(ctx)
-	if err != nil {
-		return nil, err
-	}
-	transactions = filterTransactions(transactions, TransactionFilter{AccountID: accountID})
-	for _, txn := range transactions {
-		normalizeTransaction(txn)
-	}
-	return transactions, nil
+func (s *Service) AccountTransactions(ctx context.Context, accountID string, page TransactionPageRequest) (*TransactionPage, error) {
+	return s.Transactions(ctx, TransactionFilter{AccountID: accountID}, page)
 }
 
 func (s *Service) AccountBalanceHistory(ctx context.Context, accountID string) ([]BalanceHistoryPoint, error) {
@@ -1498,16 +2021,12 @@ func (s *Service) AccountBalanceHistory(ctx context.Context, accountID string) (
 	return buildBalanceHistory(account, transactions), nil
 }
 
-func (s *Service) BudgetTransactions(ctx context.Context, budgetID string) ([]*Transaction, error) {
-	transactions, err := s.repo.ListTransactions(ctx)
-	if err != nil {
-		return nil, err
-	}
-	transactions = filterTransactions(transactions, TransactionFilter{BudgetID: budgetID})
-	for _, txn := range transactions {
-		normalizeTransaction(txn)
-	}
-	return transactions, nil
+func (s *Service) BudgetTransactions(ctx context.Context, budgetID string, page TransactionPageRequest) (*TransactionPage, error) {
+	return s.Transactions(ctx, TransactionFilter{BudgetID: budgetID}, page)
+}
+
+func (s *Service) CounterpartyTransactions(ctx context.Context, counterpartyID string, page TransactionPageRequest) (*TransactionPage, error) {
+	return s.Transactions(ctx, TransactionFilter{CounterpartyID: counterpartyID}, page)
 }
 
 func (s *Service) BudgetSpending(ctx context.Context, budgetID string) ([]BudgetSpendingItem, error) {
@@ -1520,30 +2039,32 @@ func (s *Service) BudgetSpending(ctx context.Context, budgetID string) ([]Budget
 		return nil, err
 	}
 	normalizeBudget(budget)
-	total := 0.0
+	total := Money(0)
 	byCategory := map[string]*BudgetSpendingItem{}
 	for _, txn := range transactions {
-		if txn.BudgetID == nil || *txn.BudgetID != budget.ID || txn.Type != "expense" {
+		if txn.Type != "expense" || txn.Status == TransactionStatusVoided || txn.IsScheduled {
 			continue
 		}
 		if !budgetWithinPeriod(budget, txn.Date) {
 			continue
 		}
-		if txn.CategoryID == nil {
-			continue
-		}
-		item, ok := byCategory[*txn.CategoryID]
-		if !ok {
-			item = &BudgetSpendingItem{CategoryID: *txn.CategoryID, CategoryName: *txn.CategoryID}
-			byCategory[*txn.CategoryID] = item
+		for _, line := range transactionLines(txn) {
+			if line.BudgetID == nil || *line.BudgetID != budget.ID || line.CategoryID == nil {
+				continue
+			}
+			item, ok := byCategory[*line.CategoryID]
+			if !ok {
+				item = &BudgetSpendingItem{CategoryID: *line.CategoryID, CategoryName: *line.CategoryID}
+				byCategory[*line.CategoryID] = item
+			}
+			item.Amount += line.Amount
+			total += line.Amount
 		}
-		item.Amount += txn.Amount
-		total += txn.Amount
 	}
 	items := make([]BudgetSpendingItem, 0, len(byCategory))
 	for _, item := range byCategory {
 		if total > 0 {
-			item.Percentage = (item.Amount / total) * 100
+			item.Percentage = item.Amount.Ratio(total) * 100
 		}
 		items = append(items, *item)
 	}
@@ -1575,7 +2096,7 @@ func (s *Service) SettleDebt(ctx context.Context, debtID string) (*Debt, error)
 	if err != nil {
 		return nil, err
 	}
-	if debt.RemainingAmount > 0.01 {
+	if debt.TotalDue > 0 {
 		return nil, appErrors.InvalidFinanceData
 	}
 	debt.Status = "paid"
@@ -1606,6 +2127,9 @@ func (s *Service) CreateDebt(ctx context.Context, debt *Debt) (*Debt, error) {
 		return nil, err
 	}
 	normalizeDebt(debt)
+	if err := s.validateDebtAttachments(ctx, debt); err != nil {
+		return nil, err
+	}
 	if err := s.repo.CreateDebt(ctx, debt); err != nil {
 		return nil, err
 	}
@@ -1618,6 +2142,9 @@ func (s *Service) CreateDebtWithCounterparty(ctx context.Context, debt *Debt) (*
 		return nil, err
 	}
 	normalizeDebt(debt)
+	if err := s.validateDebtAttachments(ctx, debt); err != nil {
+		return nil, err
+	}
 	if err := s.repo.CreateDebt(ctx, debt); err != nil {
 		return nil, err
 	}
@@ -1646,8 +2173,9 @@ func (s *Service) CreateDebtWithCounterparty(ctx context.Context, debt *Debt) (*
 	}, nil
 }
 
-// GetDebtWithCounterparty gets a debt by ID and embeds the counterparty.
-func (s *Service) GetDebtWithCounterparty(ctx context.Context, id string) (*DebtResponse, error) {
+// GetDebtWithCounterparty gets a debt by ID and embeds the counterparty and,
+// for interest-bearing debts, the interest breakdown accrued up to asOf.
+func (s *Service) GetDebtWithCounterparty(ctx context.Context, id string, asOf time.Time) (*DebtResponse, error) {
 	debt, err := s.repo.GetDebtByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -1657,7 +2185,7 @@ func (s *Service) GetDebtWithCounterparty(ctx context.Context, id string) (*Debt
 		return nil, err
 	}
 	normalizeDebt(debt)
-	applyDebtRollups(debt, payments)
+	interest := applyDebtRollupsAsOf(debt, payments, asOf)
 
 	// Fetch counterparty if linked
 	var counterpartyEmbed *CounterpartyEmbed
@@ -1676,6 +2204,7 @@ func (s *Service) GetDebtWithCounterparty(ctx context.Context, id string) (*Debt
 	return &DebtResponse{
 		Debt:         debt,
 		Counterparty: counterpartyEmbed,
+		Interest:     interest,
 	}, nil
 }
 
@@ -1727,6 +2256,9 @@ func (s *Service) UpdateDebt(ctx context.Context, id string, debt *Debt) (*Debt,
 	if err := s.ensureCounterpartyExists(ctx, debt.CounterpartyID); err != nil {
 		return nil, err
 	}
+	if err := s.validateDebtAttachments(ctx, debt); err != nil {
+		return nil, err
+	}
 	if err := s.repo.UpdateDebt(ctx, debt); err != nil {
 		return nil, err
 	}
@@ -1743,6 +2275,9 @@ func (s *Service) PatchDebt(ctx context.Context, id string, fields map[string]in
 	if err := s.ensureCounterpartyExists(ctx, current.CounterpartyID); err != nil {
 		return nil, err
 	}
+	if err := s.validateDebtAttachments(ctx, current); err != nil {
+		return nil, err
+	}
 	if err := s.repo.UpdateDebt(ctx, current); err != nil {
 		return nil, err
 	}
@@ -1766,6 +2301,11 @@ func (s *Service) DebtPayments(ctx context.Context, debtID string) ([]*DebtPayme
 
 func (s *Service) CreateDebtPayment(ctx context.Context, debt *Debt, payment *DebtPayment) (*DebtPayment, error) {
 	normalizeDebtPayment(payment, debt)
+	attachments, err := s.validateAttachmentIDs(ctx, payment.Attachments)
+	if err != nil {
+		return nil, err
+	}
+	payment.Attachments = attachments
 	if err := s.repo.CreateDebtPayment(ctx, payment); err != nil {
 		return nil, err
 	}
@@ -1785,6 +2325,11 @@ func (s *Service) ensureCounterpartyExists(ctx context.Context, counterpartyID *
 
 func (s *Service) UpdateDebtPayment(ctx context.Context, debt *Debt, payment *DebtPayment) (*DebtPayment, error) {
 	normalizeDebtPayment(payment, debt)
+	attachments, err := s.validateAttachmentIDs(ctx, payment.Attachments)
+	if err != nil {
+		return nil, err
+	}
+	payment.Attachments = attachments
 	if err := s.repo.UpdateDebtPayment(ctx, payment); err != nil {
 		return nil, err
 	}
@@ -1935,67 +2480,276 @@ func (s *Service) DeleteCounterparty(ctx context.Context, id string) error {
 	return s.repo.DeleteCounterparty(ctx, id)
 }
 
-func (s *Service) FXRates(ctx context.Context) ([]*FXRate, error) {
-	return s.repo.ListFXRates(ctx)
+func (s *Service) RecurringTransactions(ctx context.Context) ([]*RecurringTransaction, error) {
+	items, err := s.repo.ListRecurringTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		normalizeRecurringTransaction(item)
+	}
+	return items, nil
 }
 
-func (s *Service) CreateFXRate(ctx context.Context, rate *FXRate) (*FXRate, error) {
-	normalizeFXRate(rate)
-	if err := s.repo.CreateFXRate(ctx, rate); err != nil {
+func (s *Service) GetRecurringTransaction(ctx context.Context, id string) (*RecurringTransaction, error) {
+	item, err := s.repo.GetRecurringTransactionByID(ctx, id)
+	if err != nil {
 		return nil, err
 	}
-	return rate, nil
+	normalizeRecurringTransaction(item)
+	return item, nil
 }
 
-func (s *Service) GetFXRate(ctx context.Context, id string) (*FXRate, error) {
-	return s.repo.GetFXRateByID(ctx, id)
+func (s *Service) CreateRecurringTransaction(ctx context.Context, recurring *RecurringTransaction) (*RecurringTransaction, error) {
+	normalizeRecurringTransaction(recurring)
+	if err := validateRecurringTransaction(recurring); err != nil {
+		return nil, err
+	}
+	recurring.OccurrencesGenerated = 0
+	recurring.LastOccurrenceDate = nil
+	if recurring.Status != RecurringStatusPaused {
+		recurring.Status = RecurringStatusActive
+	}
+	scheduleRecurringTransaction(recurring)
+	if err := s.repo.CreateRecurringTransaction(ctx, recurring); err != nil {
+		return nil, err
+	}
+	return recurring, nil
 }
 
-func normalizeShowStatus(value string) string {
-	switch value {
-	case "archived", "deleted":
-		return value
-	default:
-		return "active"
+func (s *Service) PatchRecurringTransaction(ctx context.Context, id string, fields map[string]interface{}) (*RecurringTransaction, error) {
+	current, err := s.repo.GetRecurringTransactionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	applyRecurringPatch(current, fields)
+	normalizeRecurringTransaction(current)
+	if err := validateRecurringTransaction(current); err != nil {
+		return nil, err
+	}
+	scheduleRecurringTransaction(current)
+	if err := s.repo.UpdateRecurringTransaction(ctx, current); err != nil {
+		return nil, err
 	}
+	return current, nil
 }
 
-func normalizeAccount(account *Account) {
-	account.ShowStatus = normalizeShowStatus(account.ShowStatus)
-	account.IsArchived = account.ShowStatus == "archived"
+func (s *Service) DeleteRecurringTransaction(ctx context.Context, id string) error {
+	return s.repo.DeleteRecurringTransaction(ctx, id)
 }
 
-func normalizeTransaction(txn *Transaction) {
-	if txn.Type == "" {
-		txn.Type = "expense"
+func (s *Service) RecurringOccurrences(ctx context.Context, recurringID, status string) ([]*RecurringOccurrence, error) {
+	if _, err := s.repo.GetRecurringTransactionByID(ctx, recurringID); err != nil {
+		return nil, err
 	}
-	if strings.TrimSpace(txn.Date) != "" {
-		datePart := strings.Split(strings.TrimSpace(txn.Date), "T")[0]
-		datePart = strings.Split(datePart, " ")[0]
-		if datePart != "" {
-			txn.Date = datePart
-		}
+	items, err := s.repo.ListRecurringOccurrences(ctx, recurringID)
+	if err != nil {
+		return nil, err
 	}
-	if txn.BaseCurrency == "" {
-		txn.BaseCurrency = txn.Currency
+	if status == "" {
+		return items, nil
 	}
-	if txn.RateUsedToBase == 0 {
-		txn.RateUsedToBase = 1
+	filtered := make([]*RecurringOccurrence, 0, len(items))
+	for _, item := range items {
+		if item.Status == status {
+			filtered = append(filtered, item)
+		}
 	}
-	if txn.ConversionRate == 0 {
-		txn.ConversionRate = 1
+	return filtered, nil
+}
+
+// ConfirmRecurringOccurrence posts a pending (or previously failed) occurrence.
+// amount overrides the template amount for bills that vary month to month.
+func (s *Service) ConfirmRecurringOccurrence(ctx context.Context, recurringID, occurrenceID string, amount *Money) (*RecurringOccurrence, *Transaction, error) {
+	recurring, err := s.repo.GetRecurringTransactionByID(ctx, recurringID)
+	if err != nil {
+		return nil, nil, err
 	}
-	if txn.EffectiveRateFromTo == 0 {
-		txn.EffectiveRateFromTo = 1
+	occurrence, err := s.repo.GetRecurringOccurrenceByID(ctx, recurringID, occurrenceID)
+	if err != nil {
+		return nil, nil, err
 	}
-	if txn.ConvertedAmountToBase == 0 {
-		txn.ConvertedAmountToBase = txn.Amount * txn.RateUsedToBase
+	if occurrence.Status != RecurringOccurrencePending && occurrence.Status != RecurringOccurrenceFailed {
+		return nil, nil, appErrors.RecurringOccurrenceResolved
 	}
-	if txn.OriginalAmount == 0 {
-		txn.OriginalAmount = txn.Amount
+	if amount != nil && *amount <= 0 {
+		return nil, nil, appErrors.InvalidAmount
 	}
-	if len(txn.Attachments) == 0 {
-		txn.Attachments = []string{}
+	normalizeRecurringTransaction(recurring)
+	txn, err := s.postRecurringOccurrence(ctx, recurring, occurrence, amount)
+	if err != nil {
+		return nil, nil, err
+	}
+	return occurrence, txn, nil
+}
+
+func (s *Service) SkipRecurringOccurrence(ctx context.Context, recurringID, occurrenceID string) (*RecurringOccurrence, error) {
+	occurrence, err := s.repo.GetRecurringOccurrenceByID(ctx, recurringID, occurrenceID)
+	if err != nil {
+		return nil, err
+	}
+	if occurrence.Status != RecurringOccurrencePending && occurrence.Status != RecurringOccurrenceFailed {
+		return nil, appErrors.RecurringOccurrenceResolved
+	}
+	occurrence.Status = RecurringOccurrenceSkipped
+	occurrence.Error = nil
+	if err := s.repo.UpdateRecurringOccurrence(ctx, occurrence); err != nil {
+		return nil, err
+	}
+	return occurrence, nil
+}
+
+// GenerateRecurringTransactions materializes every occurrence due on or before
+// asOf across all users. Occurrences are unique per template and due date, so
+// overlapping runs never post the same occurrence twice.
+func (s *Service) GenerateRecurringTransactions(ctx context.Context, asOf time.Time) (int, error) {
+	asOfDate := asOf.UTC().Format("2006-01-02")
+	due, err := s.repo.ListDueRecurringTransactions(ctx, asOfDate)
+	if err != nil {
+		return 0, err
+	}
+	generated := 0
+	for _, recurring := range due {
+		userCtx := context.WithValue(ctx, "user_id", recurring.UserID)
+		count, err := s.materializeRecurringTransaction(userCtx, recurring, asOfDate)
+		generated += count
+		if err != nil {
+			log.Printf("[Service.GenerateRecurringTransactions] Error for recurring=%s: %v", recurring.ID, err)
+		}
+	}
+	return generated, nil
+}
+
+const maxRecurringCatchUp = 366
+
+func (s *Service) materializeRecurringTransaction(ctx context.Context, recurring *RecurringTransaction, asOfDate string) (int, error) {
+	normalizeRecurringTransaction(recurring)
+	created := 0
+	for i := 0; i < maxRecurringCatchUp; i++ {
+		if recurring.Status != RecurringStatusActive || recurring.NextOccurrenceDate == nil || *recurring.NextOccurrenceDate > asOfDate {
+			break
+		}
+		dueDate := *recurring.NextOccurrenceDate
+		occurrence := &RecurringOccurrence{
+			RecurringID: recurring.ID,
+			UserID:      recurring.UserID,
+			DueDate:     dueDate,
+			Status:      RecurringOccurrencePending,
+		}
+		inserted, err := s.repo.CreateRecurringOccurrence(ctx, occurrence)
+		if err != nil {
+			return created, err
+		}
+		if inserted {
+			created++
+			if recurring.PostingMode == RecurringPostingAuto {
+				if _, err := s.postRecurringOccurrence(ctx, recurring, occurrence, nil); err != nil {
+					log.Printf("[Service.materializeRecurringTransaction] Auto-post failed for recurring=%s date=%s: %v", recurring.ID, dueDate, err)
+				}
+			}
+		}
+		recurring.OccurrencesGenerated++
+		recurring.LastOccurrenceDate = &dueDate
+		advanceRecurringTransaction(recurring)
+	}
+	if err := s.repo.UpdateRecurringTransaction(ctx, recurring); err != nil {
+		return created, err
+	}
+	return created, nil
+}
+
+// postRecurringOccurrence books the occurrence through CreateTransaction and
+// records the outcome on the occurrence. A failed post leaves the occurrence
+// in the failed state so it can be confirmed again later.
+func (s *Service) postRecurringOccurrence(ctx context.Context, recurring *RecurringTransaction, occurrence *RecurringOccurrence, amount *Money) (*Transaction, error) {
+	txn := buildRecurringTransaction(recurring, occurrence)
+	if amount != nil {
+		txn.Amount = *amount
+	}
+	created, postErr := s.CreateTransaction(ctx, txn)
+	if postErr != nil {
+		message := postErr.Error()
+		occurrence.Status = RecurringOccurrenceFailed
+		occurrence.Error = &message
+	} else {
+		occurrence.Status = RecurringOccurrencePosted
+		occurrence.TransactionID = &created.ID
+		occurrence.Error = nil
+	}
+	if err := s.repo.UpdateRecurringOccurrence(ctx, occurrence); err != nil {
+		return nil, err
+	}
+	if postErr != nil {
+		return nil, postErr
+	}
+	return created, nil
+}
+
+func (s *Service) FXRates(ctx context.Context) ([]*FXRate, error) {
+	return s.repo.ListFXRates(ctx)
+}
+
+func (s *Service) CreateFXRate(ctx context.Context, rate *FXRate) (*FXRate, error) {
+	normalizeFXRate(rate)
+	if err := s.repo.CreateFXRate(ctx, rate); err != nil {
+		return nil, err
+	}
+	s.fxCache.reset()
+	return rate, nil
+}
+
+func (s *Service) GetFXRate(ctx context.Context, id string) (*FXRate, error) {
+	return s.repo.GetFXRateByID(ctx, id)
+}
+
+func normalizeShowStatus(value string) string {
+	switch value {
+	case "archived", "deleted":
+		return value
+	default:
+		return "active"
+	}
+}
+
+func normalizeAccount(account *Account) {
+	account.ShowStatus = normalizeShowStatus(account.ShowStatus)
+	account.IsArchived = account.ShowStatus == "archived"
+	if account.Credit != nil {
+		account.Credit.AvailableCredit = max(availableFunds(account), 0)
+	}
+}
+
+func normalizeTransaction(txn *Transaction) {
+	if txn.Type == "" {
+		txn.Type = "expense"
+	}
+	if strings.TrimSpace(txn.Date) != "" {
+		datePart := strings.Split(strings.TrimSpace(txn.Date), "T")[0]
+		datePart = strings.Split(datePart, " ")[0]
+		if datePart != "" {
+			txn.Date = datePart
+		}
+	}
+	if txn.BaseCurrency == "" {
+		txn.BaseCurrency = txn.Currency
+	}
+	if txn.RateUsedToBase == 0 {
+		txn.RateUsedToBase = 1
+	}
+	if txn.ConversionRate == 0 {
+		txn.ConversionRate = 1
+	}
+	if txn.EffectiveRateFromTo == 0 {
+		txn.EffectiveRateFromTo = 1
+	}
+	if txn.ConvertedAmountToBase == 0 {
+		txn.ConvertedAmountToBase = exchangeRateFromFloat(txn.RateUsedToBase).Convert(txn.Amount, txn.BaseCurrency)
+	}
+	if txn.OriginalAmount == 0 {
+		txn.OriginalAmount = txn.Amount
+	}
+	if len(txn.Attachments) == 0 {
+		txn.Attachments = []string{}
 	}
 	if len(txn.Tags) == 0 {
 		txn.Tags = []string{}
@@ -2031,14 +2785,42 @@ func normalizeBudget(budget *Budget) {
 	if budget.PeriodType == "" {
 		budget.PeriodType = "none"
 	}
-	if budget.RolloverMode == "" {
-		budget.RolloverMode = "none"
+	switch budget.RolloverMode {
+	case BudgetRolloverRemainder, BudgetRolloverOverspend, BudgetRolloverBoth:
+	case "carryover":
+		budget.RolloverMode = BudgetRolloverRemainder
+	default:
+		budget.RolloverMode = BudgetRolloverNone
 	}
 	budget.ShowStatus = normalizeShowStatus(budget.ShowStatus)
 	budget.IsArchived = budget.ShowStatus == "archived"
 	if budget.CategoryIDs == nil {
 		budget.CategoryIDs = []string{}
 	}
+	budget.AlertThresholds = normalizeBudgetAlertThresholds(budget.AlertThresholds)
+	budget.BaseLimit = budget.LimitAmount
+	budget.EffectiveLimit = budgetEffectiveLimit(budget)
+}
+
+var defaultBudgetAlertThresholds = []int{50, 80, 100}
+
+// normalizeBudgetAlertThresholds sorts and de-duplicates the thresholds and
+// falls back to the defaults when none are configured.
+func normalizeBudgetAlertThresholds(thresholds []int) []int {
+	seen := make(map[int]bool, len(thresholds))
+	result := make([]int, 0, len(thresholds))
+	for _, threshold := range thresholds {
+		if threshold <= 0 || seen[threshold] {
+			continue
+		}
+		seen[threshold] = true
+		result = append(result, threshold)
+	}
+	if len(result) == 0 {
+		return append([]int(nil), defaultBudgetAlertThresholds...)
+	}
+	sort.Ints(result)
+	return result
 }
 
 func normalizeDebt(debt *Debt) {
@@ -2052,7 +2834,7 @@ func normalizeDebt(debt *Debt) {
 		debt.RateOnStart = 1
 	}
 	if debt.PrincipalBaseValue == 0 {
-		debt.PrincipalBaseValue = debt.PrincipalAmount * debt.RateOnStart
+		debt.PrincipalBaseValue = debtPrincipalBaseValue(debt)
 	}
 	if debt.RepaymentRateOnStart == 0 {
 		debt.RepaymentRateOnStart = 1
@@ -2061,12 +2843,18 @@ func normalizeDebt(debt *Debt) {
 		debt.Status = "active"
 	}
 	debt.ShowStatus = normalizeShowStatus(debt.ShowStatus)
+	if debt.Attachments == nil {
+		debt.Attachments = []string{}
+	}
 }
 
 func normalizeDebtPayment(payment *DebtPayment, debt *Debt) {
 	if debt != nil && payment.BaseCurrency == "" {
 		payment.BaseCurrency = debt.BaseCurrency
 	}
+	if payment.Attachments == nil {
+		payment.Attachments = []string{}
+	}
 	if payment.RateUsedToBase == 0 {
 		payment.RateUsedToBase = 1
 	}
@@ -2074,13 +2862,23 @@ func normalizeDebtPayment(payment *DebtPayment, debt *Debt) {
 		payment.RateUsedToDebt = 1
 	}
 	if payment.ConvertedAmountToBase == 0 {
-		payment.ConvertedAmountToBase = payment.Amount * payment.RateUsedToBase
+		payment.ConvertedAmountToBase = exchangeRateFromFloat(payment.RateUsedToBase).Convert(payment.Amount, payment.BaseCurrency)
 	}
 	if payment.ConvertedAmountToDebt == 0 {
-		payment.ConvertedAmountToDebt = payment.Amount * payment.RateUsedToDebt
+		debtCurrency := payment.Currency
+		if debt != nil {
+			debtCurrency = debt.PrincipalCurrency
+		}
+		payment.ConvertedAmountToDebt = exchangeRateFromFloat(payment.RateUsedToDebt).Convert(payment.Amount, debtCurrency)
 	}
 }
 
+// debtPrincipalBaseValue converts the principal into the base currency at the
+// rate recorded when the debt started.
+func debtPrincipalBaseValue(debt *Debt) Money {
+	return exchangeRateFromFloat(debt.RateOnStart).Convert(debt.PrincipalAmount, debt.BaseCurrency)
+}
+
 func normalizeCounterparty(counterparty *Counterparty) {
 	counterparty.ShowStatus = normalizeShowStatus(counterparty.ShowStatus)
 }
@@ -2090,8 +2888,10 @@ func normalizeFXRate(rate *FXRate) {
 		rate.Nominal = 1
 	}
 	if rate.Source == "" {
-		rate.Source = "manual"
+		rate.Source = FXSourceManual
 	}
+	rate.FromCurrency = strings.ToUpper(strings.TrimSpace(rate.FromCurrency))
+	rate.ToCurrency = strings.ToUpper(strings.TrimSpace(rate.ToCurrency))
 }
 
 func normalizeDateInput(raw string) string {
@@ -2107,129 +2907,259 @@ func normalizeDateInput(raw string) string {
 	return datePart
 }
 
-func roundAmountForCurrency(amount float64, currency string) float64 {
-	decimals := 2
-	if strings.EqualFold(currency, "UZS") {
-		decimals = 0
+func normalizeRecurringTransaction(recurring *RecurringTransaction) {
+	recurring.Frequency = strings.ToLower(strings.TrimSpace(recurring.Frequency))
+	recurring.IntervalUnit = strings.ToLower(strings.TrimSpace(recurring.IntervalUnit))
+	if recurring.Interval <= 0 {
+		recurring.Interval = 1
 	}
-	factor := math.Pow(10, float64(decimals))
-	return math.Round(amount*factor) / factor
-}
-
-func roundAmountForCurrencyUp(amount float64, currency string) float64 {
-	decimals := 2
-	if strings.EqualFold(currency, "UZS") {
-		decimals = 0
+	if recurring.PostingMode == "" {
+		recurring.PostingMode = RecurringPostingAuto
+	}
+	if recurring.Status == "" {
+		recurring.Status = RecurringStatusActive
+	}
+	if strings.TrimSpace(recurring.StartDate) != "" {
+		recurring.StartDate = normalizeDateInput(recurring.StartDate)
+	}
+	if recurring.EndDate != nil {
+		if strings.TrimSpace(*recurring.EndDate) == "" {
+			recurring.EndDate = nil
+		} else {
+			endDate := normalizeDateInput(*recurring.EndDate)
+			recurring.EndDate = &endDate
+		}
+	}
+	if recurring.Tags == nil {
+		recurring.Tags = []string{}
 	}
-	factor := math.Pow(10, float64(decimals))
-	return math.Ceil(amount*factor) / factor
 }
 
-func fxRateValue(rate *FXRate) float64 {
-	if rate == nil {
-		return 0
+func validateRecurringTransaction(recurring *RecurringTransaction) error {
+	switch recurring.Type {
+	case TransactionTypeIncome, TransactionTypeExpense:
+		if recurring.AccountID == nil || *recurring.AccountID == "" {
+			return appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "accountId"})
+		}
+	case TransactionTypeTransfer:
+		if recurring.FromAccountID == nil || recurring.ToAccountID == nil || *recurring.FromAccountID == "" || *recurring.ToAccountID == "" {
+			return appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "fromAccountId"})
+		}
+	default:
+		return appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "type"})
 	}
-	value := rate.Rate
-	if value == 0 {
-		value = rate.RateMid
+	if strings.TrimSpace(recurring.Currency) == "" {
+		return appErrors.InvalidCurrency
 	}
-	if value == 0 {
-		value = rate.RateAsk
+	if recurring.Amount <= 0 {
+		return appErrors.InvalidAmount
 	}
-	if value == 0 {
-		value = rate.RateBid
+	switch recurring.PostingMode {
+	case RecurringPostingAuto, RecurringPostingConfirm:
+	default:
+		return appErrors.WithDetails(appErrors.InvalidRecurrenceRule, map[string]interface{}{"field": "postingMode"})
 	}
-	if value == 0 {
-		return 0
+	switch recurring.Status {
+	case RecurringStatusActive, RecurringStatusPaused, RecurringStatusCompleted:
+	default:
+		return appErrors.WithDetails(appErrors.InvalidRecurrenceRule, map[string]interface{}{"field": "status"})
+	}
+	switch recurring.Frequency {
+	case RecurrenceFrequencyDaily, RecurrenceFrequencyWeekly, RecurrenceFrequencyMonthly, RecurrenceFrequencyYearly:
+	case RecurrenceFrequencyCustom:
+		switch recurring.IntervalUnit {
+		case "day", "week", "month", "year":
+		default:
+			return appErrors.WithDetails(appErrors.InvalidRecurrenceRule, map[string]interface{}{"field": "intervalUnit"})
+		}
+	default:
+		return appErrors.WithDetails(appErrors.InvalidRecurrenceRule, map[string]interface{}{"field": "frequency"})
 	}
-	nominal := rate.Nominal
-	if nominal == 0 {
-		nominal = 1
+	if recurring.DayOfWeek != nil && (*recurring.DayOfWeek < 0 || *recurring.DayOfWeek > 6) {
+		return appErrors.WithDetails(appErrors.InvalidRecurrenceRule, map[string]interface{}{"field": "dayOfWeek"})
+	}
+	if recurring.DayOfMonth != nil && (*recurring.DayOfMonth < 1 || *recurring.DayOfMonth > 31) {
+		return appErrors.WithDetails(appErrors.InvalidRecurrenceRule, map[string]interface{}{"field": "dayOfMonth"})
+	}
+	start, err := time.Parse("2006-01-02", recurring.StartDate)
+	if err != nil {
+		return appErrors.WithDetails(appErrors.InvalidRecurrenceRule, map[string]interface{}{"field": "startDate"})
+	}
+	if recurring.EndDate != nil {
+		end, err := time.Parse("2006-01-02", *recurring.EndDate)
+		if err != nil || end.Before(start) {
+			return appErrors.WithDetails(appErrors.InvalidRecurrenceRule, map[string]interface{}{"field": "endDate"})
+		}
 	}
-	return value / nominal
+	if recurring.OccurrenceCount != nil && *recurring.OccurrenceCount <= 0 {
+		return appErrors.WithDetails(appErrors.InvalidRecurrenceRule, map[string]interface{}{"field": "occurrenceCount"})
+	}
+	return nil
 }
 
-func (s *Service) resolveFXRate(ctx context.Context, fromCurrency, toCurrency, dateValue string) (float64, error) {
-	if strings.EqualFold(fromCurrency, toCurrency) {
-		return 1, nil
+// recurrenceUnit resolves the step unit for a rule: day, week, month or year.
+func recurrenceUnit(recurring *RecurringTransaction) string {
+	switch recurring.Frequency {
+	case RecurrenceFrequencyDaily:
+		return "day"
+	case RecurrenceFrequencyWeekly:
+		return "week"
+	case RecurrenceFrequencyMonthly:
+		return "month"
+	case RecurrenceFrequencyYearly:
+		return "year"
+	default:
+		return recurring.IntervalUnit
 	}
-	rates, err := s.repo.ListFXRates(ctx)
-	if err != nil {
-		return 0, err
+}
+
+// dateInMonth builds a date in the given month, clamping day to the month's
+// last day so "monthly on the 31st" lands on Feb 28/29.
+func dateInMonth(year int, month time.Month, day int) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	if day > lastDay {
+		day = lastDay
 	}
-	targetDate, err := time.Parse("2006-01-02", dateValue)
-	if err != nil {
-		targetDate = time.Now().UTC()
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+func recurrenceDayOfMonth(recurring *RecurringTransaction, start time.Time) int {
+	if recurring.DayOfMonth != nil {
+		return *recurring.DayOfMonth
 	}
+	return start.Day()
+}
 
-	selectRate := func(from, to string) *FXRate {
-		var best *FXRate
-		var bestDate time.Time
-		for _, rate := range rates {
-			if !strings.EqualFold(rate.FromCurrency, from) || !strings.EqualFold(rate.ToCurrency, to) {
-				continue
-			}
-			if strings.TrimSpace(rate.Date) == "" {
-				continue
-			}
-			rateDate, err := time.Parse("2006-01-02", rate.Date)
-			if err != nil {
-				continue
-			}
-			if rateDate.After(targetDate) {
-				continue
-			}
-			if best == nil || rateDate.After(bestDate) {
-				best = rate
-				bestDate = rateDate
-			}
+// firstRecurrenceDate returns the first date on or after the start date that
+// satisfies the rule.
+func firstRecurrenceDate(recurring *RecurringTransaction) time.Time {
+	start, _ := time.Parse("2006-01-02", recurring.StartDate)
+	switch recurrenceUnit(recurring) {
+	case "week":
+		if recurring.DayOfWeek != nil {
+			offset := (*recurring.DayOfWeek - int(start.Weekday()) + 7) % 7
+			return start.AddDate(0, 0, offset)
 		}
-		if best == nil {
-			for _, rate := range rates {
-				if strings.EqualFold(rate.FromCurrency, from) && strings.EqualFold(rate.ToCurrency, to) {
-					best = rate
-					break
-				}
-			}
+	case "month":
+		candidate := dateInMonth(start.Year(), start.Month(), recurrenceDayOfMonth(recurring, start))
+		if candidate.Before(start) {
+			candidate = dateInMonth(start.Year(), start.Month()+1, recurrenceDayOfMonth(recurring, start))
 		}
-		return best
+		return candidate
 	}
+	return start
+}
 
-	direct := selectRate(fromCurrency, toCurrency)
-	if direct != nil {
-		value := fxRateValue(direct)
-		if value > 0 {
-			return value, nil
-		}
+// nextRecurrenceDate steps one interval forward from current.
+func nextRecurrenceDate(recurring *RecurringTransaction, current time.Time) time.Time {
+	start, _ := time.Parse("2006-01-02", recurring.StartDate)
+	switch recurrenceUnit(recurring) {
+	case "week":
+		return current.AddDate(0, 0, 7*recurring.Interval)
+	case "month":
+		return dateInMonth(current.Year(), current.Month()+time.Month(recurring.Interval), recurrenceDayOfMonth(recurring, start))
+	case "year":
+		return dateInMonth(current.Year()+recurring.Interval, start.Month(), start.Day())
+	default:
+		return current.AddDate(0, 0, recurring.Interval)
 	}
+}
 
-	inverse := selectRate(toCurrency, fromCurrency)
-	if inverse != nil {
-		value := fxRateValue(inverse)
-		if value > 0 {
-			return 1 / value, nil
+// scheduleRecurringTransaction recomputes the next due date from the rule,
+// skipping dates that were already generated.
+func scheduleRecurringTransaction(recurring *RecurringTransaction) {
+	next := firstRecurrenceDate(recurring)
+	if recurring.LastOccurrenceDate != nil {
+		if last, err := time.Parse("2006-01-02", *recurring.LastOccurrenceDate); err == nil {
+			for i := 0; !next.After(last) && i < 100000; i++ {
+				next = nextRecurrenceDate(recurring, next)
+			}
 		}
 	}
+	setNextRecurrenceDate(recurring, next)
+}
 
-	fromFallback, okFrom := defaultFXRates[strings.ToUpper(fromCurrency)]
-	toFallback, okTo := defaultFXRates[strings.ToUpper(toCurrency)]
-	if okFrom && okTo && toFallback > 0 {
-		return fromFallback / toFallback, nil
+func advanceRecurringTransaction(recurring *RecurringTransaction) {
+	current, err := time.Parse("2006-01-02", *recurring.NextOccurrenceDate)
+	if err != nil {
+		recurring.NextOccurrenceDate = nil
+		recurring.Status = RecurringStatusCompleted
+		return
+	}
+	setNextRecurrenceDate(recurring, nextRecurrenceDate(recurring, current))
+}
+
+func setNextRecurrenceDate(recurring *RecurringTransaction, next time.Time) {
+	nextDate := next.Format("2006-01-02")
+	exhausted := recurring.OccurrenceCount != nil && recurring.OccurrencesGenerated >= *recurring.OccurrenceCount
+	if recurring.EndDate != nil && nextDate > *recurring.EndDate {
+		exhausted = true
+	}
+	if exhausted {
+		recurring.NextOccurrenceDate = nil
+		recurring.Status = RecurringStatusCompleted
+		return
+	}
+	recurring.NextOccurrenceDate = &nextDate
+	if recurring.Status == RecurringStatusCompleted {
+		recurring.Status = RecurringStatusActive
 	}
+}
 
-	return 0, appErrors.FXRateNotFound
+func buildRecurringTransaction(recurring *RecurringTransaction, occurrence *RecurringOccurrence) *Transaction {
+	recurringID := recurring.ID
+	txn := &Transaction{
+		Type:           recurring.Type,
+		AccountID:      recurring.AccountID,
+		FromAccountID:  recurring.FromAccountID,
+		ToAccountID:    recurring.ToAccountID,
+		Amount:         recurring.Amount,
+		Currency:       recurring.Currency,
+		ToAmount:       recurring.ToAmount,
+		ToCurrency:     recurring.ToCurrency,
+		CategoryID:     recurring.CategoryID,
+		SubcategoryID:  recurring.SubcategoryID,
+		Description:    recurring.Description,
+		Date:           occurrence.DueDate,
+		GoalID:         recurring.GoalID,
+		BudgetID:       recurring.BudgetID,
+		CounterpartyID: recurring.CounterpartyID,
+		RecurringID:    &recurringID,
+		Tags:           append([]string(nil), recurring.Tags...),
+		Metadata: map[string]interface{}{
+			"recurringOccurrenceId": occurrence.ID,
+		},
+	}
+	if strings.TrimSpace(recurring.Name) != "" {
+		name := recurring.Name
+		txn.Name = &name
+	}
+	return txn
 }
 
-var defaultFXRates = map[string]float64{
-	"UZS":  1,
-	"USD":  12450,
-	"EUR":  13600,
-	"GBP":  15800,
-	"TRY":  375,
-	"SAR":  3300,
-	"AED":  3380,
-	"USDT": 12450,
-	"RUB":  140,
+func fxRateValue(rate *FXRate) ExchangeRate {
+	if rate == nil {
+		return ExchangeRate{}
+	}
+	value := rate.Rate
+	if value == 0 {
+		value = rate.RateMid
+	}
+	if value == 0 {
+		value = rate.RateAsk
+	}
+	if value == 0 {
+		value = rate.RateBid
+	}
+	if value == 0 {
+		return ExchangeRate{}
+	}
+	nominal := rate.Nominal
+	if nominal == 0 {
+		nominal = 1
+	}
+	return exchangeRateFromFloat(value).Quo(exchangeRateFromFloat(nominal))
 }
 
 func defaultFinanceCategories() []*FinanceCategory {
@@ -2284,7 +3214,18 @@ func groupTransactionsByAccount(transactions []*Transaction) map[string][]*Trans
 	return byAccount
 }
 
-func transactionDeltaForAccount(accountID string, txn *Transaction) float64 {
+// transactionDeltaForAccount is how much an entry has moved the account's
+// balance. A scheduled entry has not moved it yet.
+func transactionDeltaForAccount(accountID string, txn *Transaction) Money {
+	if txn == nil || txn.IsScheduled {
+		return 0
+	}
+	return ledgerDeltaForAccount(accountID, txn)
+}
+
+// ledgerDeltaForAccount is how much an entry moves the account's balance once
+// it is posted.
+func ledgerDeltaForAccount(accountID string, txn *Transaction) Money {
 	if txn == nil || accountID == "" {
 		return 0
 	}
@@ -2306,16 +3247,20 @@ func transactionDeltaForAccount(accountID string, txn *Transaction) float64 {
 			return txn.Amount
 		}
 	case TransactionTypeTransfer:
+		// Both sides count: a merge can leave a transfer between the
+		// same account.
+		delta := Money(0)
 		if txn.FromAccountID != nil && *txn.FromAccountID == accountID {
-			return -txn.Amount
+			delta -= txn.Amount
 		}
 		if txn.ToAccountID != nil && *txn.ToAccountID == accountID {
 			amount := txn.ToAmount
 			if amount == 0 {
 				amount = txn.Amount
 			}
-			return amount
+			delta += amount
 		}
+		return delta
 	case TransactionTypeTransferOut:
 		if txn.AccountID != nil && *txn.AccountID == accountID {
 			return -txn.Amount
@@ -2330,7 +3275,7 @@ func transactionDeltaForAccount(accountID string, txn *Transaction) float64 {
 		if txn.ToAccountID != nil && *txn.ToAccountID == accountID {
 			return txn.Amount
 		}
-	case TransactionTypeSystemAdjustment, TransactionTypeDebtCreate, TransactionTypeDebtPayment, TransactionTypeDebtAdjustment, TransactionTypeDebtFullPayment:
+	case TransactionTypeSystemAdjustment, TransactionTypeDebtCreate, TransactionTypeDebtPayment, TransactionTypeDebtAdjustment, TransactionTypeDebtFullPayment, TransactionTypeReversal:
 		if txn.AccountID != nil && *txn.AccountID == accountID {
 			return txn.Amount
 		}
@@ -2340,7 +3285,140 @@ func transactionDeltaForAccount(accountID string, txn *Transaction) float64 {
 	return 0
 }
 
-func transactionImpactForSummary(txn *Transaction) float64 {
+func isTransferLeg(txnType string) bool {
+	return txnType == TransactionTypeTransfer || txnType == TransactionTypeTransferIn || txnType == TransactionTypeTransferOut
+}
+
+// buildCorrectionTransaction copies the user-editable fields of a transaction
+// into a fresh entry. Transfer legs are folded back into a single transfer.
+func buildCorrectionTransaction(original *Transaction) *Transaction {
+	switch original.Type {
+	case TransactionTypeIncome, TransactionTypeExpense, TransactionTypeTransfer, TransactionTypeTransferIn, TransactionTypeTransferOut:
+	default:
+		return nil
+	}
+	replacement := &Transaction{
+		Type:           original.Type,
+		AccountID:      original.AccountID,
+		FromAccountID:  original.FromAccountID,
+		ToAccountID:    original.ToAccountID,
+		Amount:         original.Amount,
+		Currency:       original.Currency,
+		CategoryID:     original.CategoryID,
+		SubcategoryID:  original.SubcategoryID,
+		Name:           original.Name,
+		Description:    original.Description,
+		Date:           original.Date,
+		Time:           original.Time,
+		GoalID:         original.GoalID,
+		BudgetID:       original.BudgetID,
+		DebtID:         original.DebtID,
+		HabitID:        original.HabitID,
+		CounterpartyID: original.CounterpartyID,
+		Attachments:    append([]string{}, original.Attachments...),
+		Tags:           append([]string{}, original.Tags...),
+		ShowStatus:     original.ShowStatus,
+		Metadata:       map[string]interface{}{"correctionOf": original.ID},
+	}
+	for _, line := range original.Splits {
+		line.ID = ""
+		replacement.Splits = append(replacement.Splits, line)
+	}
+	if isTransferLeg(original.Type) {
+		replacement.Type = TransactionTypeTransfer
+		replacement.AccountID = nil
+		if original.Type == TransactionTypeTransferIn && original.ToAmount > 0 {
+			replacement.Amount = original.ToAmount
+		}
+		replacement.ToAmount = 0
+		replacement.ToCurrency = original.ToCurrency
+	}
+	return replacement
+}
+
+func isReversibleTransactionType(txnType string) bool {
+	switch txnType {
+	case TransactionTypeIncome,
+		TransactionTypeExpense,
+		TransactionTypeTransfer,
+		TransactionTypeTransferIn,
+		TransactionTypeTransferOut,
+		TransactionTypeBudgetAddValue,
+		TransactionTypeDebtAddValue,
+		TransactionTypeDebtPayment,
+		TransactionTypeDebtFullPayment:
+		return true
+	default:
+		return false
+	}
+}
+
+func transactionAccountIDs(txn *Transaction) []string {
+	ids := make([]string, 0, 3)
+	seen := make(map[string]bool, 3)
+	for _, id := range []*string{txn.AccountID, txn.FromAccountID, txn.ToAccountID} {
+		if id == nil || *id == "" || seen[*id] {
+			continue
+		}
+		seen[*id] = true
+		ids = append(ids, *id)
+	}
+	return ids
+}
+
+func buildReversalTransaction(entry *Transaction, accountID, currency string, amount Money, input TransactionReversalInput) *Transaction {
+	referenceType := "reversal"
+	referenceID := entry.ID
+	dateValue := strings.TrimSpace(input.Date)
+	if dateValue == "" {
+		dateValue = time.Now().UTC().Format("2006-01-02")
+	}
+	reversal := &Transaction{
+		Type:           TransactionTypeReversal,
+		Status:         TransactionStatusVoided,
+		AccountID:      &accountID,
+		ReferenceType:  &referenceType,
+		ReferenceID:    &referenceID,
+		Amount:         amount,
+		Currency:       currency,
+		BaseCurrency:   currency,
+		CategoryID:     entry.CategoryID,
+		Name:           entry.Name,
+		Description:    input.Note,
+		Date:           dateValue,
+		GoalID:         entry.GoalID,
+		BudgetID:       entry.BudgetID,
+		DebtID:         entry.DebtID,
+		CounterpartyID: entry.CounterpartyID,
+		ShowStatus:     "active",
+		Metadata: map[string]interface{}{
+			"reversedTransactionId":   entry.ID,
+			"reversedTransactionType": entry.Type,
+		},
+	}
+	normalizeTransaction(reversal)
+	return reversal
+}
+
+func markTransactionVoided(txn *Transaction, reversals []*Transaction, note *string) {
+	reversalIDs := make([]string, 0, len(reversals))
+	for _, reversal := range reversals {
+		reversalIDs = append(reversalIDs, reversal.ID)
+	}
+	if txn.Metadata == nil {
+		txn.Metadata = map[string]interface{}{}
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	txn.Metadata["voidedAt"] = now
+	txn.Metadata["reversedBy"] = reversalIDs
+	if note != nil && strings.TrimSpace(*note) != "" {
+		txn.Metadata["voidReason"] = strings.TrimSpace(*note)
+	}
+	txn.Status = TransactionStatusVoided
+	txn.UpdatedAt = now
+}
+
+func transactionImpactForSummary(txn *Transaction) Money {
 	if txn == nil {
 		return 0
 	}
@@ -2360,7 +3438,7 @@ func transactionImpactForSummary(txn *Transaction) float64 {
 	}
 }
 
-func computeAccountBalance(account *Account, transactions []*Transaction) float64 {
+func computeAccountBalance(account *Account, transactions []*Transaction) Money {
 	balance := account.InitialBalance
 	for _, txn := range transactions {
 		balance += transactionDeltaForAccount(account.ID, txn)
@@ -2391,13 +3469,24 @@ func buildBalanceHistory(account *Account, transactions []*Transaction) []Balanc
 func filterTransactions(transactions []*Transaction, filter TransactionFilter) []*Transaction {
 	filtered := make([]*Transaction, 0, len(transactions))
 	for _, txn := range transactions {
+		if !filter.IncludeVoided && filter.Status != TransactionStatusVoided && txn.Status == TransactionStatusVoided {
+			continue
+		}
+		if filter.Status != "" && txn.Status != filter.Status {
+			continue
+		}
 		if filter.AccountID != "" {
-			matchesAccount := txn.AccountID != nil && *txn.AccountID == filter.AccountID
-			if txn.FromAccountID != nil && *txn.FromAccountID == filter.AccountID {
-				matchesAccount = true
+			accountIDs := filter.AccountIDs
+			if len(accountIDs) == 0 {
+				accountIDs = []string{filter.AccountID}
 			}
-			if txn.ToAccountID != nil && *txn.ToAccountID == filter.AccountID {
-				matchesAccount = true
+			matchesAccount := false
+			for _, accountID := range accountIDs {
+				if (txn.AccountID != nil && *txn.AccountID == accountID) ||
+					(txn.FromAccountID != nil && *txn.FromAccountID == accountID) ||
+					(txn.ToAccountID != nil && *txn.ToAccountID == accountID) {
+					matchesAccount = true
+				}
 			}
 			if !matchesAccount {
 				continue
@@ -2413,7 +3502,9 @@ func filterTransactions(transactions []*Transaction, filter TransactionFilter) [
 			}
 		}
 		if filter.CategoryID != "" {
-			if txn.CategoryID == nil || *txn.CategoryID != filter.CategoryID {
+			if !transactionHasLine(txn, func(line TransactionSplit) bool {
+				return line.CategoryID != nil && *line.CategoryID == filter.CategoryID
+			}) {
 				continue
 			}
 		}
@@ -2423,7 +3514,9 @@ func filterTransactions(transactions []*Transaction, filter TransactionFilter) [
 			}
 		}
 		if filter.BudgetID != "" {
-			if txn.BudgetID == nil || *txn.BudgetID != filter.BudgetID {
+			if !transactionHasLine(txn, func(line TransactionSplit) bool {
+				return line.BudgetID != nil && *line.BudgetID == filter.BudgetID
+			}) {
 				continue
 			}
 		}
@@ -2432,11 +3525,39 @@ func filterTransactions(transactions []*Transaction, filter TransactionFilter) [
 				continue
 			}
 		}
+		if filter.CounterpartyID != "" {
+			if txn.CounterpartyID == nil || *txn.CounterpartyID != filter.CounterpartyID {
+				continue
+			}
+		}
+		if filter.HabitID != "" {
+			if txn.HabitID == nil || *txn.HabitID != filter.HabitID {
+				continue
+			}
+		}
 		if filter.DateFrom != "" || filter.DateTo != "" {
 			if !dateInRange(txn.Date, filter.DateFrom, filter.DateTo) {
 				continue
 			}
 		}
+		if filter.AmountMin != nil || filter.AmountMax != nil {
+			amount := transactionFilterAmount(txn, filter.AmountBasis)
+			if filter.AmountMin != nil && amount < *filter.AmountMin {
+				continue
+			}
+			if filter.AmountMax != nil && amount > *filter.AmountMax {
+				continue
+			}
+		}
+		if len(filter.TagsAny) > 0 && !transactionHasTags(txn, filter.TagsAny, false) {
+			continue
+		}
+		if len(filter.TagsAll) > 0 && !transactionHasTags(txn, filter.TagsAll, true) {
+			continue
+		}
+		if filter.Search != "" && !transactionMatchesSearch(txn, filter.Search) {
+			continue
+		}
 		filtered = append(filtered, txn)
 	}
 	return filtered
@@ -2472,6 +3593,9 @@ func filterDebts(debts []*Debt, filter DebtFilter) []*Debt {
 		if filter.Status != "" && debt.Status != filter.Status {
 			continue
 		}
+		if filter.Overdue != nil && (debt.Status == DebtStatusOverdue) != *filter.Overdue {
+			continue
+		}
 		if filter.LinkedGoalID != "" {
 			if debt.LinkedGoalID == nil || *debt.LinkedGoalID != filter.LinkedGoalID {
 				continue
@@ -2505,13 +3629,13 @@ func filterCounterparties(items []*Counterparty, filter CounterpartyFilter) []*C
 }
 
 func applyBudgetRollups(budget *Budget, transactions []*Transaction) {
-	spent := 0.0
+	spent := Money(0)
 	trackType := "expense"
 	if budget.TransactionType != nil && strings.ToLower(*budget.TransactionType) == "income" {
 		trackType = "income"
 	}
 	for _, txn := range transactions {
-		if txn.BudgetID == nil || *txn.BudgetID != budget.ID {
+		if txn.Status == TransactionStatusVoided || txn.IsScheduled {
 			continue
 		}
 		if txn.Type != trackType && txn.Type != TransactionTypeBudgetAddValue {
@@ -2528,20 +3652,127 @@ func applyBudgetRollups(budget *Budget, transactions []*Transaction) {
 		} else if strings.EqualFold(txn.BaseCurrency, budget.Currency) && txn.ConvertedAmountToBase > 0 {
 			amount = txn.ConvertedAmountToBase
 		}
-		spent += amount
+		amounts := splitAmounts(txn, amount)
+		for i, line := range transactionLines(txn) {
+			if line.BudgetID != nil && *line.BudgetID == budget.ID {
+				spent += amounts[i]
+			}
+		}
 	}
+	effectiveLimit := budgetEffectiveLimit(budget)
+	budget.BaseLimit = budget.LimitAmount
+	budget.EffectiveLimit = effectiveLimit
 	budget.SpentAmount = spent
-	budget.RemainingAmount = budget.LimitAmount - spent
-	if budget.LimitAmount > 0 {
-		budget.PercentUsed = (spent / budget.LimitAmount) * 100
-	} else {
+	budget.RemainingAmount = effectiveLimit - spent
+	switch {
+	case effectiveLimit > 0:
+		budget.PercentUsed = spent.Ratio(effectiveLimit) * 100
+	case budget.LimitAmount > 0:
+		// Carried overspend has already used up the whole limit.
+		budget.PercentUsed = 100
+	default:
 		budget.PercentUsed = 0
 	}
-	budget.IsOverspent = spent > budget.LimitAmount && budget.LimitAmount > 0
+	budget.IsOverspent = spent > effectiveLimit && budget.LimitAmount > 0
+}
+
+// budgetEffectiveLimit is the base limit adjusted by the amount carried in
+// from the previous period (negative when overspend was carried).
+func budgetEffectiveLimit(budget *Budget) Money {
+	return budget.LimitAmount + budget.CarriedAmount
+}
+
+func isRollingBudgetPeriod(periodType string) bool {
+	return periodType == "weekly" || periodType == "monthly"
+}
+
+// budgetPeriodWindow returns the budget's current window. Weekly and monthly
+// budgets without explicit dates fall back to the ISO week or calendar month
+// containing ref.
+func budgetPeriodWindow(budget *Budget, ref time.Time) (time.Time, time.Time) {
+	if budget.StartDate != nil && budget.EndDate != nil {
+		start, startErr := time.Parse("2006-01-02", *budget.StartDate)
+		end, endErr := time.Parse("2006-01-02", *budget.EndDate)
+		if startErr == nil && endErr == nil {
+			return start, end
+		}
+	}
+	day := time.Date(ref.Year(), ref.Month(), ref.Day(), 0, 0, 0, 0, time.UTC)
+	if budget.PeriodType == "weekly" {
+		offset := (int(day.Weekday()) + 6) % 7
+		start := day.AddDate(0, 0, -offset)
+		return start, start.AddDate(0, 0, 6)
+	}
+	start := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, -1)
+}
+
+// seedBudgetWindow gives a weekly or monthly budget without dates the window
+// containing now, so the period scheduler can close it.
+func seedBudgetWindow(budget *Budget, now time.Time) {
+	if !isRollingBudgetPeriod(budget.PeriodType) || (budget.StartDate != nil && budget.EndDate != nil) {
+		return
+	}
+	start, end := budgetPeriodWindow(budget, now)
+	startDate := start.Format("2006-01-02")
+	endDate := end.Format("2006-01-02")
+	budget.StartDate = &startDate
+	budget.EndDate = &endDate
+}
+
+// nextBudgetPeriodWindow returns the window that follows [start, end].
+func nextBudgetPeriodWindow(periodType string, start, end time.Time) (time.Time, time.Time) {
+	nextStart := end.AddDate(0, 0, 1)
+	if periodType == "weekly" {
+		return nextStart, nextStart.AddDate(0, 0, 6)
+	}
+	if nextStart.Day() == 1 {
+		return nextStart, nextStart.AddDate(0, 1, -1)
+	}
+	return nextStart, dateInMonth(nextStart.Year(), nextStart.Month()+1, start.Day()).AddDate(0, 0, -1)
+}
+
+// budgetCarryOut applies the rollover mode to a closing period's remainder.
+func budgetCarryOut(mode string, remaining Money) Money {
+	switch mode {
+	case BudgetRolloverRemainder:
+		return max(remaining, 0)
+	case BudgetRolloverOverspend:
+		return min(remaining, 0)
+	case BudgetRolloverBoth:
+		return remaining
+	default:
+		return 0
+	}
+}
+
+func buildBudgetPeriodSnapshot(budget *Budget) *BudgetPeriod {
+	return &BudgetPeriod{
+		BudgetID:        budget.ID,
+		PeriodStart:     *budget.StartDate,
+		PeriodEnd:       *budget.EndDate,
+		Currency:        budget.Currency,
+		BaseLimit:       budget.LimitAmount,
+		CarriedIn:       budget.CarriedAmount,
+		EffectiveLimit:  budget.EffectiveLimit,
+		SpentAmount:     budget.SpentAmount,
+		RemainingAmount: budget.RemainingAmount,
+		PercentUsed:     budget.PercentUsed,
+		IsOverspent:     budget.IsOverspent,
+		CarriedOut:      budgetCarryOut(budget.RolloverMode, budget.RemainingAmount).RoundTo(budget.Currency),
+		RolloverMode:    budget.RolloverMode,
+	}
 }
 
 func applyDebtRollups(debt *Debt, payments []*DebtPayment) {
-	totalPaid := 0.0
+	applyDebtRollupsAsOf(debt, payments, time.Now().UTC())
+}
+
+// applyDebtRollupsAsOf recomputes payment totals and, for interest-bearing
+// debts, accrues interest up to asOf. The returned breakdown is nil when the
+// debt carries no interest.
+func applyDebtRollupsAsOf(debt *Debt, payments []*DebtPayment, asOf time.Time) *DebtInterest {
+	totalPaid := Money(0)
 	for _, payment := range payments {
 		if payment.DebtID != debt.ID {
 			continue
@@ -2551,8 +3782,24 @@ func applyDebtRollups(debt *Debt, payments []*DebtPayment) {
 	debt.TotalPaid = totalPaid
 	debt.RemainingAmount = debt.PrincipalAmount - totalPaid
 	if debt.PrincipalAmount > 0 {
-		debt.PercentPaid = (totalPaid / debt.PrincipalAmount) * 100
+		debt.PercentPaid = totalPaid.Ratio(debt.PrincipalAmount) * 100
+	}
+	debt.AccruedInterest = 0
+	debt.InterestPaid = 0
+	debt.TotalDue = debt.RemainingAmount
+	if normalizeDebtInterestMode(debt.InterestMode) == DebtInterestNone || debt.InterestRateAnnual <= 0 {
+		return nil
+	}
+
+	interest := computeDebtInterest(debt, payments, asOf)
+	debt.RemainingAmount = interest.PrincipalOutstanding
+	debt.AccruedInterest = interest.Outstanding
+	debt.InterestPaid = interest.Paid
+	debt.TotalDue = (debt.RemainingAmount + debt.AccruedInterest).RoundTo(debt.PrincipalCurrency)
+	if debt.PrincipalAmount > 0 {
+		debt.PercentPaid = (debt.PrincipalAmount - debt.RemainingAmount).Ratio(debt.PrincipalAmount) * 100
 	}
+	return interest
 }
 
 func applyAccountPatch(account *Account, fields map[string]interface{}) {
@@ -2566,10 +3813,10 @@ func applyAccountPatch(account *Account, fields map[string]interface{}) {
 		account.AccountType = v
 	}
 	if v, ok := fields["initialBalance"].(float64); ok {
-		account.InitialBalance = v
+		account.InitialBalance = MoneyFromFloat(v)
 	}
 	if v, ok := fields["currentBalance"].(float64); ok {
-		account.CurrentBalance = v
+		account.CurrentBalance = MoneyFromFloat(v)
 	}
 	if v, ok := fields["linkedGoalId"].(string); ok {
 		account.LinkedGoalID = &v
@@ -2583,6 +3830,9 @@ func applyAccountPatch(account *Account, fields map[string]interface{}) {
 	if v, ok := fields["showStatus"].(string); ok {
 		account.ShowStatus = v
 	}
+	if v, ok := fields["credit"].(map[string]interface{}); ok {
+		account.Credit = applyCreditTermsPatch(account.Credit, v)
+	}
 }
 
 func applyTransactionPatch(txn *Transaction, fields map[string]interface{}) {
@@ -2599,7 +3849,7 @@ func applyTransactionPatch(txn *Transaction, fields map[string]interface{}) {
 		txn.ToAccountID = &v
 	}
 	if v, ok := fields["amount"].(float64); ok {
-		txn.Amount = v
+		txn.Amount = MoneyFromFloat(v)
 	}
 	if v, ok := fields["currency"].(string); ok {
 		txn.Currency = v
@@ -2619,6 +3869,11 @@ func applyTransactionPatch(txn *Transaction, fields map[string]interface{}) {
 	if v, ok := fields["budgetId"].(string); ok {
 		txn.BudgetID = &v
 	}
+	if v, ok := fields["splits"]; ok {
+		if splits, valid := decodeTransactionSplits(v); valid {
+			txn.Splits = splits
+		}
+	}
 	if v, ok := fields["debtId"].(string); ok {
 		txn.DebtID = &v
 	}
@@ -2659,7 +3914,7 @@ func applyBudgetPatch(budget *Budget, fields map[string]interface{}) {
 		budget.Currency = v
 	}
 	if v, ok := fields["limitAmount"].(float64); ok {
-		budget.LimitAmount = v
+		budget.LimitAmount = MoneyFromFloat(v)
 	}
 	if v, ok := fields["periodType"].(string); ok {
 		budget.PeriodType = v
@@ -2670,6 +3925,21 @@ func applyBudgetPatch(budget *Budget, fields map[string]interface{}) {
 	if v, ok := fields["endDate"].(string); ok {
 		budget.EndDate = &v
 	}
+	if v, ok := fields["rolloverMode"].(string); ok {
+		budget.RolloverMode = v
+	}
+	if v, ok := fields["notifyOnExceed"].(bool); ok {
+		budget.NotifyOnExceed = v
+	}
+	if v, ok := fields["alertThresholds"].([]interface{}); ok {
+		thresholds := make([]int, 0, len(v))
+		for _, item := range v {
+			if value := intPointerFromField(item); value != nil {
+				thresholds = append(thresholds, *value)
+			}
+		}
+		budget.AlertThresholds = thresholds
+	}
 	if v, ok := fields["showStatus"].(string); ok {
 		budget.ShowStatus = v
 	}
@@ -2686,7 +3956,7 @@ func applyDebtPatch(debt *Debt, fields map[string]interface{}) {
 		debt.CounterpartyName = v
 	}
 	if v, ok := fields["principalAmount"].(float64); ok {
-		debt.PrincipalAmount = v
+		debt.PrincipalAmount = MoneyFromFloat(v)
 	}
 	if v, ok := fields["principalCurrency"].(string); ok {
 		debt.PrincipalCurrency = v
@@ -2697,6 +3967,15 @@ func applyDebtPatch(debt *Debt, fields map[string]interface{}) {
 	if v, ok := fields["showStatus"].(string); ok {
 		debt.ShowStatus = v
 	}
+	if v, ok := fields["attachments"].([]interface{}); ok {
+		attachments := make([]string, 0, len(v))
+		for _, item := range v {
+			if id, ok := item.(string); ok {
+				attachments = append(attachments, id)
+			}
+		}
+		debt.Attachments = attachments
+	}
 	if v, ok := fields["exchangeRateCurrent"].(float64); ok {
 		debt.ExchangeRateCurrent = v
 	}
@@ -2707,7 +3986,7 @@ func applyDebtPatch(debt *Debt, fields map[string]interface{}) {
 		debt.RepaymentCurrency = &v
 	}
 	if v, ok := fields["repaymentAmount"].(float64); ok {
-		debt.RepaymentAmount = v
+		debt.RepaymentAmount = MoneyFromFloat(v)
 	}
 	if v, ok := fields["description"].(string); ok {
 		debt.Description = &v
@@ -2756,6 +4035,101 @@ func applyCounterpartyPatch(counterparty *Counterparty, fields map[string]interf
 	}
 }
 
+func applyRecurringPatch(recurring *RecurringTransaction, fields map[string]interface{}) {
+	if v, ok := fields["name"].(string); ok {
+		recurring.Name = v
+	}
+	if v, ok := fields["accountId"].(string); ok {
+		recurring.AccountID = &v
+	}
+	if v, ok := fields["fromAccountId"].(string); ok {
+		recurring.FromAccountID = &v
+	}
+	if v, ok := fields["toAccountId"].(string); ok {
+		recurring.ToAccountID = &v
+	}
+	if v, ok := fields["amount"].(float64); ok {
+		recurring.Amount = MoneyFromFloat(v)
+	}
+	if v, ok := fields["currency"].(string); ok {
+		recurring.Currency = v
+	}
+	if v, ok := fields["toAmount"].(float64); ok {
+		recurring.ToAmount = MoneyFromFloat(v)
+	}
+	if v, ok := fields["categoryId"].(string); ok {
+		recurring.CategoryID = &v
+	}
+	if v, ok := fields["subcategoryId"].(string); ok {
+		recurring.SubcategoryID = &v
+	}
+	if v, ok := fields["description"].(string); ok {
+		recurring.Description = &v
+	}
+	if v, ok := fields["budgetId"].(string); ok {
+		recurring.BudgetID = &v
+	}
+	if v, ok := fields["goalId"].(string); ok {
+		recurring.GoalID = &v
+	}
+	if v, ok := fields["counterpartyId"].(string); ok {
+		recurring.CounterpartyID = &v
+	}
+	if v, ok := fields["tags"].([]interface{}); ok {
+		tags := make([]string, 0, len(v))
+		for _, item := range v {
+			if tag, ok := item.(string); ok {
+				tags = append(tags, tag)
+			}
+		}
+		recurring.Tags = tags
+	}
+	if v, ok := fields["frequency"].(string); ok {
+		recurring.Frequency = v
+	}
+	if v, ok := fields["interval"].(float64); ok {
+		recurring.Interval = int(v)
+	}
+	if v, ok := fields["intervalUnit"].(string); ok {
+		recurring.IntervalUnit = v
+	}
+	if v, ok := fields["dayOfWeek"]; ok {
+		recurring.DayOfWeek = intPointerFromField(v)
+	}
+	if v, ok := fields["dayOfMonth"]; ok {
+		recurring.DayOfMonth = intPointerFromField(v)
+	}
+	if v, ok := fields["startDate"].(string); ok {
+		recurring.StartDate = v
+	}
+	if v, ok := fields["endDate"]; ok {
+		if value, ok := v.(string); ok && value != "" {
+			recurring.EndDate = &value
+		} else {
+			recurring.EndDate = nil
+		}
+	}
+	if v, ok := fields["occurrenceCount"]; ok {
+		recurring.OccurrenceCount = intPointerFromField(v)
+	}
+	if v, ok := fields["postingMode"].(string); ok {
+		recurring.PostingMode = v
+	}
+	if v, ok := fields["status"].(string); ok {
+		recurring.Status = v
+	}
+}
+
+// intPointerFromField converts a decoded JSON number to *int; null clears it.
+func intPointerFromField(value interface{}) *int {
+	number, ok := value.(float64)
+	if !ok {
+		return nil
+	}
+	result := int(number)
+	return &result
+}
+
 func budgetWithinPeriod(budget *Budget, dateValue string) bool {
 	if budget.PeriodType == "none" || dateValue == "" {
 		return true
@@ -2763,6 +4137,11 @@ func budgetWithinPeriod(budget *Budget, dateValue string) bool {
 	if budget.StartDate != nil && budget.EndDate != nil {
 		return dateInRange(dateValue, *budget.StartDate, *budget.EndDate)
 	}
+	if isRollingBudgetPeriod(budget.PeriodType) {
+		// Not seeded yet: count the window the scheduler would close next.
+		start, end := budgetPeriodWindow(budget, time.Now().UTC())
+		return dateInRange(dateValue, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	}
 	return true
 }
 
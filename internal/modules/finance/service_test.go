@@ -1,8 +1,22 @@
 package finance
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
+	"time"
+
+	appErrors "github.com/leora/leora-server/internal/errors"
+	"github.com/leora/leora-server/internal/modules/notifications"
+	"github.com/leora/leora-server/internal/storage"
 )
 
 func TestRepayDebtUpdatesTotalsSameCurrency(t *testing.T) {
@@ -14,8 +28,8 @@ func TestRepayDebtUpdatesTotalsSameCurrency(t *testing.T) {
 		Name:           "Cash",
 		AccountType:    "cash",
 		Currency:       "USD",
-		InitialBalance: 1000,
-		CurrentBalance: 1000,
+		InitialBalance: money(1000),
+		CurrentBalance: money(1000),
 		ShowStatus:     "active",
 	}
 	createdAccount, _, err := service.CreateAccount(ctx, account)
@@ -24,9 +38,9 @@ func TestRepayDebtUpdatesTotalsSameCurrency(t *testing.T) {
 	}
 
 	debt := &Debt{
-		Name:              "Loan",
+		CounterpartyName:  "Loan",
 		Direction:         "i_owe",
-		PrincipalAmount:   100,
+		PrincipalAmount:   money(100),
 		PrincipalCurrency: "USD",
 		BaseCurrency:      "USD",
 		ShowStatus:        "active",
@@ -37,25 +51,25 @@ func TestRepayDebtUpdatesTotalsSameCurrency(t *testing.T) {
 
 	result, err := service.RepayDebt(ctx, debt.ID, DebtValueInput{
 		AccountID:      createdAccount.ID,
-		Amount:         30,
+		Amount:         money(30),
 		AmountCurrency: "USD",
 	})
 	if err != nil {
 		t.Fatalf("repay debt: %v", err)
 	}
-	if result.Debt.RemainingAmount != 70 {
-		t.Fatalf("remaining amount mismatch: got %.2f, want 70.00", result.Debt.RemainingAmount)
+	if result.Debt.RemainingAmount != money(70) {
+		t.Fatalf("remaining amount mismatch: got %s, want 70.00", result.Debt.RemainingAmount)
 	}
-	if result.Debt.TotalPaid != 30 {
-		t.Fatalf("total paid mismatch: got %.2f, want 30.00", result.Debt.TotalPaid)
+	if result.Debt.TotalPaid != money(30) {
+		t.Fatalf("total paid mismatch: got %s, want 30.00", result.Debt.TotalPaid)
 	}
 
 	updatedAccount, err := service.GetAccount(ctx, createdAccount.ID)
 	if err != nil {
 		t.Fatalf("get account: %v", err)
 	}
-	if updatedAccount.CurrentBalance != 970 {
-		t.Fatalf("account balance mismatch: got %.2f, want 970.00", updatedAccount.CurrentBalance)
+	if updatedAccount.CurrentBalance != money(970) {
+		t.Fatalf("account balance mismatch: got %s, want 970.00", updatedAccount.CurrentBalance)
 	}
 }
 
@@ -68,8 +82,8 @@ func TestRepayDebtConvertsDebtCurrencyToAccountCurrency(t *testing.T) {
 		Name:           "UZS",
 		AccountType:    "cash",
 		Currency:       "UZS",
-		InitialBalance: 1_000_000,
-		CurrentBalance: 1_000_000,
+		InitialBalance: money(1_000_000),
+		CurrentBalance: money(1_000_000),
 		ShowStatus:     "active",
 	}
 	createdAccount, _, err := service.CreateAccount(ctx, account)
@@ -78,9 +92,9 @@ func TestRepayDebtConvertsDebtCurrencyToAccountCurrency(t *testing.T) {
 	}
 
 	debt := &Debt{
-		Name:              "USD Debt",
+		CounterpartyName:  "USD Debt",
 		Direction:         "i_owe",
-		PrincipalAmount:   100,
+		PrincipalAmount:   money(100),
 		PrincipalCurrency: "USD",
 		BaseCurrency:      "USD",
 		ShowStatus:        "active",
@@ -101,7 +115,7 @@ func TestRepayDebtConvertsDebtCurrencyToAccountCurrency(t *testing.T) {
 
 	_, err = service.RepayDebt(ctx, debt.ID, DebtValueInput{
 		AccountID:      createdAccount.ID,
-		Amount:         10,
+		Amount:         money(10),
 		AmountCurrency: "USD",
 		Date:           stringPtr("2026-01-01"),
 	})
@@ -113,8 +127,8 @@ func TestRepayDebtConvertsDebtCurrencyToAccountCurrency(t *testing.T) {
 	if err != nil {
 		t.Fatalf("get account: %v", err)
 	}
-	if updatedAccount.CurrentBalance != 880_000 {
-		t.Fatalf("account balance mismatch: got %.2f, want 880000.00", updatedAccount.CurrentBalance)
+	if updatedAccount.CurrentBalance != money(880_000) {
+		t.Fatalf("account balance mismatch: got %s, want 880000.00", updatedAccount.CurrentBalance)
 	}
 }
 
@@ -139,7 +153,7 @@ func TestFinanceSummaryUpdatesAfterTransaction(t *testing.T) {
 	_, err = service.CreateTransaction(ctx, &Transaction{
 		Type:      TransactionTypeIncome,
 		AccountID: &createdAccount.ID,
-		Amount:    120,
+		Amount:    money(120),
 		Currency:  "USD",
 	})
 	if err != nil {
@@ -150,14 +164,2906 @@ func TestFinanceSummaryUpdatesAfterTransaction(t *testing.T) {
 	if err != nil {
 		t.Fatalf("summary: %v", err)
 	}
-	if summary.Totals.Income != 120 {
-		t.Fatalf("summary income mismatch: got %.2f, want 120.00", summary.Totals.Income)
+	if summary.Totals.Income != money(120) {
+		t.Fatalf("summary income mismatch: got %s, want 120.00", summary.Totals.Income)
+	}
+	if summary.Totals.Balance != money(120) {
+		t.Fatalf("summary balance mismatch: got %s, want 120.00", summary.Totals.Balance)
+	}
+}
+
+func TestReverseTransactionRestoresBalanceAndHidesPair(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-4")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	createdAccount, _, err := service.CreateAccount(ctx, &Account{
+		Name:           "Cash",
+		AccountType:    "cash",
+		Currency:       "USD",
+		InitialBalance: money(500),
+		CurrentBalance: money(500),
+		ShowStatus:     "active",
+	})
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+
+	expense, err := service.CreateTransaction(ctx, &Transaction{
+		Type:      TransactionTypeExpense,
+		AccountID: &createdAccount.ID,
+		Amount:    money(80),
+		Currency:  "USD",
+		Date:      "2026-01-10",
+	})
+	if err != nil {
+		t.Fatalf("create transaction: %v", err)
+	}
+
+	result, err := service.ReverseTransaction(ctx, expense.ID, TransactionReversalInput{})
+	if err != nil {
+		t.Fatalf("reverse transaction: %v", err)
+	}
+	if len(result.Reversals) != 1 || result.Reversals[0].Amount != money(80) {
+		t.Fatalf("unexpected reversal entries: %+v", result.Reversals)
+	}
+	if ref := result.Reversals[0].ReferenceID; ref == nil || *ref != expense.ID {
+		t.Fatalf("reversal must reference the original transaction")
+	}
+
+	updatedAccount, err := service.GetAccount(ctx, createdAccount.ID)
+	if err != nil {
+		t.Fatalf("get account: %v", err)
+	}
+	if updatedAccount.CurrentBalance != money(500) {
+		t.Fatalf("account balance mismatch: got %s, want 500.00", updatedAccount.CurrentBalance)
+	}
+
+	visible, err := service.Transactions(ctx, TransactionFilter{Type: TransactionTypeExpense}, TransactionPageRequest{Limit: 100})
+	if err != nil {
+		t.Fatalf("list transactions: %v", err)
+	}
+	if len(visible.Items) != 0 {
+		t.Fatalf("voided expense should be hidden, got %d", len(visible.Items))
+	}
+	all, err := service.Transactions(ctx, TransactionFilter{IncludeVoided: true}, TransactionPageRequest{Limit: 100})
+	if err != nil {
+		t.Fatalf("list transactions: %v", err)
+	}
+	voided := 0
+	for _, txn := range all.Items {
+		if txn.Status == TransactionStatusVoided {
+			voided++
+		}
+	}
+	if voided != 2 {
+		t.Fatalf("voided pair mismatch: got %d, want 2", voided)
+	}
+
+	if _, err := service.ReverseTransaction(ctx, expense.ID, TransactionReversalInput{}); err != appErrors.TransactionAlreadyVoided {
+		t.Fatalf("expected already voided error, got %v", err)
+	}
+}
+
+func TestCorrectTransactionReplacesAmount(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-5")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	createdAccount, _, err := service.CreateAccount(ctx, &Account{
+		Name:           "Cash",
+		AccountType:    "cash",
+		Currency:       "USD",
+		InitialBalance: money(200),
+		CurrentBalance: money(200),
+		ShowStatus:     "active",
+	})
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	expense, err := service.CreateTransaction(ctx, &Transaction{
+		Type:      TransactionTypeExpense,
+		AccountID: &createdAccount.ID,
+		Amount:    money(50),
+		Currency:  "USD",
+		Date:      "2026-01-10",
+	})
+	if err != nil {
+		t.Fatalf("create transaction: %v", err)
+	}
+
+	result, err := service.CorrectTransaction(ctx, expense.ID, map[string]interface{}{"amount": 45.0}, nil)
+	if err != nil {
+		t.Fatalf("correct transaction: %v", err)
+	}
+	if result.Correction == nil || result.Correction.Amount != money(45) {
+		t.Fatalf("unexpected correction: %+v", result.Correction)
+	}
+
+	updatedAccount, err := service.GetAccount(ctx, createdAccount.ID)
+	if err != nil {
+		t.Fatalf("get account: %v", err)
+	}
+	if updatedAccount.CurrentBalance != money(155) {
+		t.Fatalf("account balance mismatch: got %s, want 155.00", updatedAccount.CurrentBalance)
+	}
+}
+
+func TestCorrectTransactionRunsTheCreateSteps(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-correct")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	wallet, _, err := service.CreateAccount(ctx, &Account{
+		Name:           "Wallet",
+		AccountType:    "cash",
+		Currency:       "USD",
+		InitialBalance: money(100),
+		ShowStatus:     "active",
+		Balances:       []AccountBalance{{Currency: "UZS", InitialBalance: money(1_200_000)}},
+	})
+	if err != nil {
+		t.Fatalf("create wallet: %v", err)
+	}
+	uzsID := wallet.Balances[1].AccountID
+	expense := func(amount float64, splits []TransactionSplit) *Transaction {
+		txn, err := service.CreateTransaction(ctx, &Transaction{
+			Type:      TransactionTypeExpense,
+			AccountID: &wallet.ID,
+			Amount:    money(amount),
+			Currency:  "USD",
+			Date:      "2026-01-10",
+			Splits:    splits,
+		})
+		if err != nil {
+			t.Fatalf("create expense: %v", err)
+		}
+		return txn
+	}
+	balances := func() (Money, Money) {
+		usd, _ := repo.GetAccountByID(ctx, wallet.ID)
+		uzs, _ := repo.GetAccountByID(ctx, uzsID)
+		return usd.CurrentBalance, uzs.CurrentBalance
+	}
+
+	wrongCurrency, err := service.CreateTransaction(ctx, &Transaction{
+		Type:      TransactionTypeExpense,
+		AccountID: &wallet.ID,
+		Amount:    money(20),
+		Currency:  "UZS",
+		Date:      "2026-01-10",
+	})
+	if err != nil || *wrongCurrency.AccountID != uzsID {
+		t.Fatalf("expected the expense on the UZS balance, got %+v (%v)", wrongCurrency, err)
+	}
+	result, err := service.CorrectTransaction(ctx, wrongCurrency.ID, map[string]interface{}{"currency": "USD"}, nil)
+	if err != nil {
+		t.Fatalf("correct currency: %v", err)
+	}
+	if result.Correction.AccountID == nil || *result.Correction.AccountID != wallet.ID {
+		t.Fatalf("expected the correction on the USD balance, got %v", result.Correction.AccountID)
+	}
+	if usd, uzs := balances(); usd != money(80) || uzs != money(1_200_000) {
+		t.Fatalf("expected balances 80 USD and 1200000 UZS, got %s and %s", usd, uzs)
+	}
+
+	postponed := expense(10, nil)
+	result, err = service.CorrectTransaction(ctx, postponed.ID, map[string]interface{}{"date": "2099-01-01"}, nil)
+	if err != nil {
+		t.Fatalf("correct date: %v", err)
+	}
+	if !result.Correction.IsScheduled || result.Correction.Status != TransactionStatusPending {
+		t.Fatalf("expected a future-dated correction to be scheduled, got %+v", result.Correction)
+	}
+	if usd, _ := balances(); usd != money(80) {
+		t.Fatalf("expected the scheduled correction to leave the balance alone, got %s", usd)
+	}
+
+	split := expense(30, []TransactionSplit{{Amount: money(10)}, {Amount: money(20)}})
+	_, err = service.CorrectTransaction(ctx, split.ID, map[string]interface{}{"amount": 40.0}, nil)
+	if err == nil || err.(*appErrors.Error).Code != appErrors.InvalidTransactionSplit.Code {
+		t.Fatalf("expected an amount change without new splits to be refused, got %v", err)
+	}
+	result, err = service.CorrectTransaction(ctx, split.ID, map[string]interface{}{
+		"amount": 40.0,
+		"splits": []map[string]interface{}{{"amount": 15.0}, {"amount": 25.0}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("correct split amount: %v", err)
+	}
+	if len(result.Correction.Splits) != 2 || result.Correction.Splits[1].Amount != money(25) {
+		t.Fatalf("expected the new splits on the correction, got %+v", result.Correction.Splits)
+	}
+	if usd, _ := balances(); usd != money(40) {
+		t.Fatalf("expected balance 40 USD, got %s", usd)
+	}
+}
+
+func TestRecurrenceScheduleClampsMonthEnd(t *testing.T) {
+	dayOfMonth := 31
+	count := 4
+	recurring := &RecurringTransaction{
+		Frequency:       RecurrenceFrequencyMonthly,
+		DayOfMonth:      &dayOfMonth,
+		StartDate:       "2026-01-15",
+		OccurrenceCount: &count,
+	}
+	normalizeRecurringTransaction(recurring)
+	scheduleRecurringTransaction(recurring)
+
+	want := []string{"2026-01-31", "2026-02-28", "2026-03-31", "2026-04-30"}
+	for i, expected := range want {
+		if recurring.NextOccurrenceDate == nil || *recurring.NextOccurrenceDate != expected {
+			t.Fatalf("occurrence %d mismatch: got %v, want %s", i, recurring.NextOccurrenceDate, expected)
+		}
+		recurring.OccurrencesGenerated++
+		advanceRecurringTransaction(recurring)
+	}
+	if recurring.NextOccurrenceDate != nil || recurring.Status != RecurringStatusCompleted {
+		t.Fatalf("expected schedule to complete after %d occurrences", count)
+	}
+}
+
+func TestRecurrenceScheduleWeeklyAndCustom(t *testing.T) {
+	monday := 1
+	weekly := &RecurringTransaction{
+		Frequency: RecurrenceFrequencyWeekly,
+		Interval:  2,
+		DayOfWeek: &monday,
+		StartDate: "2026-03-04",
+	}
+	normalizeRecurringTransaction(weekly)
+	scheduleRecurringTransaction(weekly)
+	if got := *weekly.NextOccurrenceDate; got != "2026-03-09" {
+		t.Fatalf("first weekly occurrence mismatch: got %s", got)
+	}
+	advanceRecurringTransaction(weekly)
+	if got := *weekly.NextOccurrenceDate; got != "2026-03-23" {
+		t.Fatalf("second weekly occurrence mismatch: got %s", got)
+	}
+
+	endDate := "2026-01-25"
+	custom := &RecurringTransaction{
+		Frequency:    RecurrenceFrequencyCustom,
+		Interval:     10,
+		IntervalUnit: "day",
+		StartDate:    "2026-01-01",
+		EndDate:      &endDate,
+	}
+	normalizeRecurringTransaction(custom)
+	scheduleRecurringTransaction(custom)
+	advanceRecurringTransaction(custom)
+	advanceRecurringTransaction(custom)
+	if got := *custom.NextOccurrenceDate; got != "2026-01-21" {
+		t.Fatalf("custom occurrence mismatch: got %s", got)
+	}
+	advanceRecurringTransaction(custom)
+	if custom.Status != RecurringStatusCompleted {
+		t.Fatalf("expected custom schedule to stop after end date")
+	}
+}
+
+func TestGenerateRecurringTransactionsPostsAndQueues(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-6")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	createdAccount, _, err := service.CreateAccount(ctx, &Account{
+		Name:           "Card",
+		AccountType:    "card",
+		Currency:       "USD",
+		InitialBalance: money(1000),
+		CurrentBalance: money(1000),
+		ShowStatus:     "active",
+	})
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+
+	rent, err := service.CreateRecurringTransaction(ctx, &RecurringTransaction{
+		Name:      "Rent",
+		Type:      TransactionTypeExpense,
+		AccountID: &createdAccount.ID,
+		Amount:    money(300),
+		Currency:  "USD",
+		Frequency: RecurrenceFrequencyMonthly,
+		StartDate: "2026-01-05",
+	})
+	if err != nil {
+		t.Fatalf("create recurring: %v", err)
+	}
+	internet, err := service.CreateRecurringTransaction(ctx, &RecurringTransaction{
+		Name:        "Internet",
+		Type:        TransactionTypeExpense,
+		AccountID:   &createdAccount.ID,
+		Amount:      money(20),
+		Currency:    "USD",
+		Frequency:   RecurrenceFrequencyMonthly,
+		StartDate:   "2026-01-10",
+		PostingMode: RecurringPostingConfirm,
+	})
+	if err != nil {
+		t.Fatalf("create recurring: %v", err)
+	}
+
+	asOf := time.Date(2026, 2, 12, 8, 0, 0, 0, time.UTC)
+	generated, err := service.GenerateRecurringTransactions(context.Background(), asOf)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if generated != 4 {
+		t.Fatalf("generated mismatch: got %d, want 4", generated)
+	}
+	if again, _ := service.GenerateRecurringTransactions(context.Background(), asOf); again != 0 {
+		t.Fatalf("second run must be a no-op, generated %d", again)
+	}
+
+	updatedAccount, err := service.GetAccount(ctx, createdAccount.ID)
+	if err != nil {
+		t.Fatalf("get account: %v", err)
+	}
+	if updatedAccount.CurrentBalance != money(400) {
+		t.Fatalf("account balance mismatch: got %s, want 400.00", updatedAccount.CurrentBalance)
+	}
+
+	storedRent, err := service.GetRecurringTransaction(ctx, rent.ID)
+	if err != nil {
+		t.Fatalf("get recurring: %v", err)
+	}
+	if storedRent.NextOccurrenceDate == nil || *storedRent.NextOccurrenceDate != "2026-03-05" {
+		t.Fatalf("next occurrence mismatch: got %v", storedRent.NextOccurrenceDate)
+	}
+
+	pending, err := service.RecurringOccurrences(ctx, internet.ID, RecurringOccurrencePending)
+	if err != nil {
+		t.Fatalf("list occurrences: %v", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("pending occurrences mismatch: got %d, want 2", len(pending))
+	}
+	amount := MoneyFromFloat(25)
+	_, txn, err := service.ConfirmRecurringOccurrence(ctx, internet.ID, pending[0].ID, &amount)
+	if err != nil {
+		t.Fatalf("confirm occurrence: %v", err)
+	}
+	if txn.RecurringID == nil || *txn.RecurringID != internet.ID || txn.Amount != money(25) {
+		t.Fatalf("confirmed transaction mismatch: %+v", txn)
 	}
-	if summary.Totals.Balance != 120 {
-		t.Fatalf("summary balance mismatch: got %.2f, want 120.00", summary.Totals.Balance)
+	if _, err := service.SkipRecurringOccurrence(ctx, internet.ID, pending[0].ID); err != appErrors.RecurringOccurrenceResolved {
+		t.Fatalf("expected resolved error, got %v", err)
 	}
 }
 
 func stringPtr(value string) *string {
 	return &value
 }
+
+func money(value float64) Money {
+	return MoneyFromFloat(value)
+}
+
+func TestCloseBudgetPeriodCarriesRemainderAndOverspend(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-7")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	createdAccount, _, err := service.CreateAccount(ctx, &Account{
+		Name:           "Cash",
+		AccountType:    "cash",
+		Currency:       "USD",
+		InitialBalance: money(1000),
+		CurrentBalance: money(1000),
+		ShowStatus:     "active",
+	})
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+
+	start, end := "2026-01-01", "2026-01-31"
+	cases := []struct {
+		mode        string
+		spent       Money
+		wantCarried Money
+	}{
+		{mode: BudgetRolloverRemainder, spent: money(70), wantCarried: money(30)},
+		{mode: BudgetRolloverOverspend, spent: money(150), wantCarried: money(-50)},
+	}
+	for _, tc := range cases {
+		budget, err := service.CreateBudget(ctx, &Budget{
+			Name:         tc.mode,
+			LimitAmount:  money(100),
+			Currency:     "USD",
+			PeriodType:   "monthly",
+			StartDate:    &start,
+			EndDate:      &end,
+			RolloverMode: tc.mode,
+		})
+		if err != nil {
+			t.Fatalf("create budget: %v", err)
+		}
+		if _, err := service.CreateTransaction(ctx, &Transaction{
+			Type:      TransactionTypeExpense,
+			AccountID: &createdAccount.ID,
+			Amount:    tc.spent,
+			Currency:  "USD",
+			Date:      "2026-01-15",
+			BudgetID:  &budget.ID,
+		}); err != nil {
+			t.Fatalf("create transaction: %v", err)
+		}
+
+		period, next, err := service.CloseBudgetPeriod(ctx, budget.ID, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatalf("close period (%s): %v", tc.mode, err)
+		}
+		if period.SpentAmount != tc.spent || period.CarriedOut != tc.wantCarried {
+			t.Fatalf("%s snapshot mismatch: spent %s carried %s", tc.mode, period.SpentAmount, period.CarriedOut)
+		}
+		if *next.StartDate != "2026-02-01" || *next.EndDate != "2026-02-28" {
+			t.Fatalf("%s next window mismatch: %s..%s", tc.mode, *next.StartDate, *next.EndDate)
+		}
+
+		stored, err := service.GetBudget(ctx, budget.ID)
+		if err != nil {
+			t.Fatalf("get budget: %v", err)
+		}
+		if stored.BaseLimit != money(100) || stored.CarriedAmount != tc.wantCarried || stored.EffectiveLimit != money(100)+tc.wantCarried {
+			t.Fatalf("%s limits mismatch: base %s carried %s effective %s", tc.mode, stored.BaseLimit, stored.CarriedAmount, stored.EffectiveLimit)
+		}
+		if stored.SpentAmount != 0 {
+			t.Fatalf("%s new period must start empty, spent %s", tc.mode, stored.SpentAmount)
+		}
+
+		if _, _, err := service.CloseBudgetPeriod(ctx, budget.ID, time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC)); err != appErrors.BudgetPeriodNotEnded {
+			t.Fatalf("%s second close in the same window: got %v, want BudgetPeriodNotEnded", tc.mode, err)
+		}
+		if stored, _ := service.GetBudget(ctx, budget.ID); *stored.StartDate != "2026-02-01" || stored.CarriedAmount != tc.wantCarried {
+			t.Fatalf("%s refused close moved the budget: %s carried %s", tc.mode, *stored.StartDate, stored.CarriedAmount)
+		}
+		if _, _, err := service.CloseBudgetPeriod(ctx, budget.ID, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+			t.Fatalf("close second period: %v", err)
+		}
+	}
+}
+
+func TestCloseElapsedBudgetPeriodsCatchesUpAndKeepsHistory(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-8")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	start, end := "2026-01-01", "2026-01-31"
+	budget, err := service.CreateBudget(ctx, &Budget{
+		Name:         "Groceries",
+		LimitAmount:  money(200),
+		Currency:     "USD",
+		PeriodType:   "monthly",
+		StartDate:    &start,
+		EndDate:      &end,
+		RolloverMode: BudgetRolloverNone,
+	})
+	if err != nil {
+		t.Fatalf("create budget: %v", err)
+	}
+
+	asOf := time.Date(2026, 4, 10, 6, 0, 0, 0, time.UTC)
+	closed, err := service.CloseElapsedBudgetPeriods(context.Background(), asOf)
+	if err != nil {
+		t.Fatalf("close elapsed: %v", err)
+	}
+	if closed != 3 {
+		t.Fatalf("closed mismatch: got %d, want 3", closed)
+	}
+	if again, _ := service.CloseElapsedBudgetPeriods(context.Background(), asOf); again != 0 {
+		t.Fatalf("second run must be a no-op, closed %d", again)
+	}
+
+	periods, err := service.BudgetPeriods(ctx, budget.ID, "", "")
+	if err != nil {
+		t.Fatalf("list periods: %v", err)
+	}
+	if len(periods) != 3 || periods[0].PeriodStart != "2026-03-01" || periods[2].PeriodEnd != "2026-01-31" {
+		t.Fatalf("unexpected periods: %+v", periods)
+	}
+
+	stored, err := service.GetBudget(ctx, budget.ID)
+	if err != nil {
+		t.Fatalf("get budget: %v", err)
+	}
+	if *stored.StartDate != "2026-04-01" || *stored.EndDate != "2026-04-30" {
+		t.Fatalf("current window mismatch: %s..%s", *stored.StartDate, *stored.EndDate)
+	}
+
+	// Editing without dates keeps the open window; turning a budget into a
+	// monthly one gives it a window the scheduler can close.
+	updated, err := service.UpdateBudget(ctx, budget.ID, &Budget{
+		Name:         "Food",
+		LimitAmount:  money(250),
+		Currency:     "USD",
+		PeriodType:   "monthly",
+		RolloverMode: BudgetRolloverNone,
+	})
+	if err != nil {
+		t.Fatalf("update budget: %v", err)
+	}
+	if updated.StartDate == nil || *updated.StartDate != "2026-04-01" || *updated.EndDate != "2026-04-30" {
+		t.Fatalf("expected the update to keep the open window, got %v..%v", updated.StartDate, updated.EndDate)
+	}
+	oneOff, err := service.CreateBudget(ctx, &Budget{Name: "Trip", LimitAmount: money(900), Currency: "USD", PeriodType: "none"})
+	if err != nil {
+		t.Fatalf("create budget: %v", err)
+	}
+	if oneOff.StartDate != nil {
+		t.Fatalf("expected a one-off budget to have no window, got %s", *oneOff.StartDate)
+	}
+	patched, err := service.PatchBudget(ctx, oneOff.ID, map[string]interface{}{"periodType": "monthly"})
+	if err != nil {
+		t.Fatalf("patch budget: %v", err)
+	}
+	want, _ := budgetPeriodWindow(&Budget{PeriodType: "monthly"}, time.Now().UTC())
+	if patched.StartDate == nil || patched.EndDate == nil || *patched.StartDate != want.Format("2006-01-02") {
+		t.Fatalf("expected the patch to seed the current month, got %v..%v", patched.StartDate, patched.EndDate)
+	}
+}
+
+func TestBudgetAlertsFireOncePerThreshold(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-9")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+	notificationService := notifications.NewService(notifications.NewInMemoryRepository())
+	service.SetNotifications(notificationService)
+
+	createdAccount, _, err := service.CreateAccount(ctx, &Account{
+		Name:           "Card",
+		AccountType:    "card",
+		Currency:       "USD",
+		InitialBalance: money(1000),
+		CurrentBalance: money(1000),
+		ShowStatus:     "active",
+	})
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	start, end := "2026-01-01", "2026-01-31"
+	budget, err := service.CreateBudget(ctx, &Budget{
+		Name:           "Food",
+		LimitAmount:    money(100),
+		Currency:       "USD",
+		PeriodType:     "monthly",
+		StartDate:      &start,
+		EndDate:        &end,
+		NotifyOnExceed: true,
+	})
+	if err != nil {
+		t.Fatalf("create budget: %v", err)
+	}
+
+	expectNotifications := func(want int) {
+		t.Helper()
+		items, err := notificationService.List(ctx)
+		if err != nil {
+			t.Fatalf("list notifications: %v", err)
+		}
+		if len(items) != want {
+			t.Fatalf("notification count mismatch: got %d, want %d", len(items), want)
+		}
+	}
+
+	for _, amount := range []Money{money(60), money(30), money(5)} {
+		if _, err := service.CreateTransaction(ctx, &Transaction{
+			Type:      TransactionTypeExpense,
+			AccountID: &createdAccount.ID,
+			Amount:    amount,
+			Currency:  "USD",
+			Date:      "2026-01-12",
+			BudgetID:  &budget.ID,
+		}); err != nil {
+			t.Fatalf("create transaction: %v", err)
+		}
+	}
+	// 60% fires the 50% alert, 90% fires 80%, 95% crosses nothing new.
+	expectNotifications(2)
+
+	date := "2026-01-20"
+	if _, err := service.AddBudgetValue(ctx, budget.ID, BudgetAddValueInput{
+		AccountID:      createdAccount.ID,
+		Amount:         money(20),
+		AmountCurrency: "USD",
+		Date:           &date,
+	}); err != nil {
+		t.Fatalf("add budget value: %v", err)
+	}
+	// 115% crosses both 100% and overspent but sends a single alert.
+	expectNotifications(3)
+	if inserted, _ := repo.RecordBudgetAlert(ctx, &BudgetAlert{BudgetID: budget.ID, PeriodStart: start, Threshold: "100"}); inserted {
+		t.Fatalf("100%% alert must already be recorded for the period")
+	}
+}
+
+func TestDebtInterestAppliesPaymentsInterestFirst(t *testing.T) {
+	simple := DebtInterestSimple
+	debt := &Debt{
+		ID:                 "debt-1",
+		PrincipalAmount:    money(1000),
+		PrincipalCurrency:  "USD",
+		StartDate:          "2026-01-01",
+		InterestMode:       &simple,
+		InterestRateAnnual: 12,
+	}
+	payments := []*DebtPayment{
+		{ID: "pay-1", DebtID: "debt-1", ConvertedAmountToDebt: money(100), PaymentDate: "2026-04-01"},
+	}
+
+	// 90 days at 12% simple on 1000 is 29.59; the rest of the payment reduces principal.
+	interest := applyDebtRollupsAsOf(debt, payments, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC))
+	if interest == nil || interest.Paid != money(29.59) {
+		t.Fatalf("interest paid mismatch: %+v", interest)
+	}
+	if debt.RemainingAmount != money(929.59) || debt.AccruedInterest != 0 || debt.TotalDue != money(929.59) {
+		t.Fatalf("rollup mismatch: remaining %s accrued %s due %s", debt.RemainingAmount, debt.AccruedInterest, debt.TotalDue)
+	}
+
+	// Another 30 days accrue on the reduced principal only.
+	applyDebtRollupsAsOf(debt, payments, time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC))
+	if debt.AccruedInterest != money(9.17) || debt.TotalDue != money(938.76) {
+		t.Fatalf("accrual mismatch: accrued %s due %s", debt.AccruedInterest, debt.TotalDue)
+	}
+
+	daily := DebtInterestCompoundDaily
+	debt.InterestMode = &daily
+	debt.InterestRateAnnual = 10
+	interest = applyDebtRollupsAsOf(debt, nil, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC))
+	if interest.Outstanding != money(105.16) || debt.TotalDue != money(1105.16) {
+		t.Fatalf("compound daily mismatch: outstanding %s due %s", interest.Outstanding, debt.TotalDue)
+	}
+
+	debt.InterestMode = nil
+	if applyDebtRollupsAsOf(debt, payments, time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)) != nil || debt.TotalDue != money(900) {
+		t.Fatalf("debt without interest must keep principal-only totals, due %s", debt.TotalDue)
+	}
+}
+
+func TestDebtScheduleMatchesPaymentsOldestFirst(t *testing.T) {
+	debt := &Debt{ID: "debt-1", PrincipalAmount: money(900), PrincipalCurrency: "USD"}
+	installments, err := generateDebtInstallments(debt, DebtScheduleInput{
+		PlanType:     DebtScheduleEqual,
+		Count:        3,
+		FirstDueDate: "2026-01-31",
+	})
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if len(installments) != 3 || installments[1].DueDate != "2026-02-28" || installments[2].Amount != money(300) {
+		t.Fatalf("unexpected plan: %+v %+v %+v", installments[0], installments[1], installments[2])
+	}
+
+	payments := []*DebtPayment{
+		{ID: "pay-1", DebtID: "debt-1", ConvertedAmountToDebt: money(400), PaymentDate: "2026-01-20"},
+		{ID: "pay-2", DebtID: "debt-1", ConvertedAmountToDebt: money(50), PaymentDate: "2026-04-10"},
+	}
+	// The first payment covers installment 1 and spills 100 into installment 2.
+	schedule := buildDebtSchedule(debt, installments, payments, time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC))
+	statuses := []string{schedule.Installments[0].Status, schedule.Installments[1].Status, schedule.Installments[2].Status}
+	if statuses[0] != DebtInstallmentPaid || statuses[1] != DebtInstallmentPartial || statuses[2] != DebtInstallmentPending {
+		t.Fatalf("unexpected statuses: %v", statuses)
+	}
+	if schedule.TotalPaid != money(400) || *schedule.NextDueDate != "2026-02-28" {
+		t.Fatalf("unexpected totals: paid %s next %v", schedule.TotalPaid, schedule.NextDueDate)
+	}
+
+	schedule = buildDebtSchedule(debt, installments, payments, time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC))
+	if schedule.Installments[1].Status != DebtInstallmentOverdue || schedule.TotalOverdue != money(200) {
+		t.Fatalf("expected installment 2 overdue by 200, got %s %s", schedule.Installments[1].Status, schedule.TotalOverdue)
+	}
+
+	if _, err := generateDebtInstallments(debt, DebtScheduleInput{PlanType: "balloon", Count: 2}); err == nil {
+		t.Fatalf("expected unknown plan type to be rejected")
+	}
+
+	_, err = generateDebtInstallments(debt, DebtScheduleInput{
+		PlanType: DebtScheduleCustom,
+		Installments: []DebtScheduleInputItem{
+			{DueDate: "2026-02-15", Amount: money(500)},
+			{DueDate: "2026-01-15", Amount: money(500)},
+		},
+	})
+	if typed, ok := err.(*appErrors.Error); !ok || typed.Code != appErrors.InvalidDebtSchedule.Code || typed.Details["field"] != "installments" {
+		t.Fatalf("expected a plan repaying 1000 of a 900 debt to be rejected, got %v", err)
+	}
+	custom, err := generateDebtInstallments(debt, DebtScheduleInput{
+		PlanType: DebtScheduleCustom,
+		Installments: []DebtScheduleInputItem{
+			{DueDate: "2026-02-15", Amount: money(500), InterestAmount: money(50)},
+			{DueDate: "2026-01-15", Amount: money(500), InterestAmount: money(50)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("generate custom: %v", err)
+	}
+	if custom[0].DueDate != "2026-01-15" || custom[0].PrincipalAmount != money(450) || custom[0].InterestAmount != money(50) {
+		t.Fatalf("unexpected custom installment: %+v", custom[0])
+	}
+}
+
+func TestEvaluateDebtDueDatesTransitionsAndRemindsOncePerDay(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-10")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+	notificationService := notifications.NewService(notifications.NewInMemoryRepository())
+	service.SetNotifications(notificationService)
+
+	dueDate := "2026-03-10"
+	reminderTime := "18:00"
+	debt := &Debt{
+		UserID:            "user-10",
+		Direction:         "i_owe",
+		CounterpartyName:  "Aziz",
+		PrincipalAmount:   money(200),
+		PrincipalCurrency: "USD",
+		RemainingAmount:   money(200),
+		StartDate:         "2026-01-01",
+		DueDate:           &dueDate,
+		ReminderEnabled:   true,
+		ReminderTime:      &reminderTime,
+		Status:            DebtStatusActive,
+		ShowStatus:        "active",
+	}
+	if err := repo.CreateDebt(ctx, debt); err != nil {
+		t.Fatalf("create debt: %v", err)
+	}
+
+	expectStatus := func(at time.Time, wantStatus string, wantNotifications int) {
+		t.Helper()
+		if _, err := service.EvaluateDebtDueDates(context.Background(), at); err != nil {
+			t.Fatalf("evaluate: %v", err)
+		}
+		current, _ := repo.GetDebtByID(ctx, debt.ID)
+		if current.Status != wantStatus {
+			t.Fatalf("status at %s: got %s, want %s", at.Format(time.RFC3339), current.Status, wantStatus)
+		}
+		items, _ := notificationService.List(ctx)
+		if len(items) != wantNotifications {
+			t.Fatalf("notifications at %s: got %d, want %d", at.Format(time.RFC3339), len(items), wantNotifications)
+		}
+	}
+
+	expectStatus(time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC), DebtStatusActive, 0)
+	// Due soon, but the reminder waits for 18:00 and then fires once that day.
+	expectStatus(time.Date(2026, 3, 8, 9, 0, 0, 0, time.UTC), DebtStatusDueSoon, 0)
+	expectStatus(time.Date(2026, 3, 8, 18, 5, 0, 0, time.UTC), DebtStatusDueSoon, 1)
+	expectStatus(time.Date(2026, 3, 8, 18, 10, 0, 0, time.UTC), DebtStatusDueSoon, 1)
+	expectStatus(time.Date(2026, 3, 11, 18, 0, 0, 0, time.UTC), DebtStatusOverdue, 2)
+
+	events, _ := repo.ListDebtStatusEvents(ctx, 0)
+	if len(events) != 2 || events[0].ToStatus != DebtStatusOverdue || events[1].FromStatus != DebtStatusActive {
+		t.Fatalf("unexpected transitions: %+v", events)
+	}
+	overdue := true
+	if listed, _ := service.Debts(ctx, DebtFilter{Overdue: &overdue}); len(listed) != 1 {
+		t.Fatalf("overdue filter returned %d debts", len(listed))
+	}
+	feed := buildSummaryEvents(service, ctx, nil, "", "", "USD", "2026-03-11")
+	if len(feed) != 1 || feed[0].Time != "Overdue since 2026-03-10" {
+		t.Fatalf("unexpected summary feed: %+v", feed)
+	}
+}
+
+func TestMoneyBalancesStayExactOverLongSequences(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-11")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	wallet, _, err := service.CreateAccount(ctx, &Account{Name: "Wallet", AccountType: "cash", Currency: "USD"})
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	savings, _, err := service.CreateAccount(ctx, &Account{Name: "Savings", AccountType: "bank", Currency: "USD"})
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+
+	// Balances are tracked in cents alongside the ledger; any float drift
+	// would show up as a mismatch after a few thousand postings.
+	rng := rand.New(rand.NewSource(9))
+	var walletCents, savingsCents int64
+	for i := 0; i < 5000; i++ {
+		cents := int64(rng.Intn(100_000) + 1)
+		txn := &Transaction{Amount: Money(cents * 100), Currency: "USD", Date: "2026-01-15"}
+		switch rng.Intn(3) {
+		case 0:
+			txn.Type = TransactionTypeIncome
+			txn.AccountID = &wallet.ID
+			walletCents += cents
+		case 1:
+			if walletCents < cents {
+				continue
+			}
+			txn.Type = TransactionTypeExpense
+			txn.AccountID = &wallet.ID
+			walletCents -= cents
+		default:
+			if walletCents < cents {
+				continue
+			}
+			txn.Type = TransactionTypeTransfer
+			txn.FromAccountID = &wallet.ID
+			txn.ToAccountID = &savings.ID
+			walletCents -= cents
+			savingsCents += cents
+		}
+		if _, err := service.CreateTransaction(ctx, txn); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+	}
+
+	transactions, err := repo.ListTransactions(ctx)
+	if err != nil {
+		t.Fatalf("list transactions: %v", err)
+	}
+	for _, tc := range []struct {
+		id    string
+		cents int64
+	}{{wallet.ID, walletCents}, {savings.ID, savingsCents}} {
+		account, _ := repo.GetAccountByID(ctx, tc.id)
+		if account.CurrentBalance != Money(tc.cents*100) {
+			t.Fatalf("balance drifted: got %s, want %d cents", account.CurrentBalance, tc.cents)
+		}
+		if recomputed := computeAccountBalance(account, transactions); recomputed != account.CurrentBalance {
+			t.Fatalf("recomputed balance %s differs from stored %s", recomputed, account.CurrentBalance)
+		}
+	}
+
+	tenth := money(0.1)
+	total := Money(0)
+	for i := 0; i < 1000; i++ {
+		total += tenth
+	}
+	if total != money(100) {
+		t.Fatalf("repeated 0.1 additions drifted: %s", total)
+	}
+}
+
+func TestMoneyConversionAndJSONAreExact(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	usdToUZS := exchangeRateFromFloat(12650.45)
+	eurToUZS := exchangeRateFromFloat(13780.1)
+	for i := 0; i < 2000; i++ {
+		amount := Money(int64(rng.Intn(10_000_000)) * 100)
+
+		// A rate divided by itself is exactly one, so converting through it is a no-op.
+		if got := usdToUZS.Quo(usdToUZS).Convert(amount, "USD"); got != amount {
+			t.Fatalf("identity conversion changed %s to %s", amount, got)
+		}
+		converted := usdToUZS.Convert(amount, "UZS")
+		if converted%Money(currencyUnit("UZS")) != 0 {
+			t.Fatalf("UZS amount %s is not whole", converted)
+		}
+		if up := usdToUZS.ConvertUp(amount, "UZS"); up < converted || up-converted > Money(currencyUnit("UZS")) {
+			t.Fatalf("ConvertUp %s is not the ceiling of %s", up, converted)
+		}
+		cross := usdToUZS.Quo(eurToUZS).Convert(amount, "EUR")
+		if direct := usdToUZS.Convert(amount, "UZS"); (eurToUZS.Inverse().Convert(direct, "EUR") - cross).Abs() > Money(currencyUnit("EUR")) {
+			t.Fatalf("cross rate %s strays from two-step conversion", cross)
+		}
+
+		// The wire format is the same JSON number a float64 amount produced.
+		asFloat, _ := json.Marshal(amount.Float64())
+		asMoney, _ := json.Marshal(amount)
+		if string(asFloat) != string(asMoney) {
+			t.Fatalf("JSON mismatch: float %s, money %s", asFloat, asMoney)
+		}
+		var decoded Money
+		if err := json.Unmarshal(asFloat, &decoded); err != nil || decoded != amount {
+			t.Fatalf("JSON round trip: got %s, want %s (%v)", decoded, amount, err)
+		}
+	}
+
+	var decoded Money
+	if err := json.Unmarshal([]byte(`"12.5"`), &decoded); err != nil || decoded != money(12.5) {
+		t.Fatalf("expected a numeric string to decode, got %s (%v)", decoded, err)
+	}
+	for _, raw := range []string{`""`, `"  "`, `"12,5"`} {
+		if err := json.Unmarshal([]byte(raw), &decoded); err == nil {
+			t.Fatalf("expected %s to be rejected", raw)
+		}
+	}
+}
+
+func TestSyncFXRatesStoresProviderRatesAndFlagsStale(t *testing.T) {
+	requests := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cbu/all/2026-10-16/", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.ServeFile(w, r, "testdata/cbu_rates.json")
+	})
+	mux.HandleFunc("/ecb.xml", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.ServeFile(w, r, "testdata/ecb_rates.xml")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ctx := context.Background()
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+	service.SetFXProviders(
+		NewCBUProvider(server.URL+"/cbu", 1),
+		NewECBProvider(server.URL+"/ecb.xml", "", 0),
+	)
+	if _, err := service.CreateFXRate(ctx, &FXRate{Date: "2026-10-01", FromCurrency: "USD", ToCurrency: "RUB", Rate: 81.2}); err != nil {
+		t.Fatalf("create manual rate: %v", err)
+	}
+
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	stored, err := service.SyncFXRates(ctx, now)
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if stored != 7 || requests != 2 {
+		t.Fatalf("stored %d rates in %d requests, want 7 in 2", stored, requests)
+	}
+	if again, _ := service.SyncFXRates(ctx, now.Add(2*time.Hour)); again != 0 || requests != 2 {
+		t.Fatalf("second sync the same day must not refetch: stored %d, requests %d", again, requests)
+	}
+
+	rates, _ := repo.ListFXRates(ctx)
+	found := 0
+	for _, rate := range rates {
+		switch {
+		case rate.Source == FXSourceCBU && rate.FromCurrency == "USD":
+			found++
+			if rate.ToCurrency != "UZS" || rate.RateMid != 12650.45 || rate.RateBid != 12587.19775 || rate.RateAsk != 12713.70225 {
+				t.Fatalf("unexpected CBU USD rate: %+v", rate)
+			}
+		case rate.Source == FXSourceCBU && rate.FromCurrency == "IDR":
+			found++
+			if rate.Nominal != 10 || rate.Date != "2026-10-16" {
+				t.Fatalf("unexpected CBU IDR rate: %+v", rate)
+			}
+		case rate.Source == FXSourceECB && rate.ToCurrency == "USD":
+			found++
+			if rate.FromCurrency != "EUR" || rate.Rate != 1.0893 || rate.RateBid != rate.RateAsk {
+				t.Fatalf("unexpected ECB USD rate: %+v", rate)
+			}
+		}
+	}
+	if found != 3 {
+		t.Fatalf("expected CBU USD, CBU IDR and ECB USD rates, found %d", found)
+	}
+	rate, err := service.resolveFXRate(ctx, "USD", "UZS", "2026-10-16")
+	if err != nil || rate.Float64() != 12650.45 {
+		t.Fatalf("conversion must use the ingested rate, got %v (%v)", rate.Float64(), err)
+	}
+
+	statuses, err := service.FXRateStatuses(ctx, now)
+	if err != nil {
+		t.Fatalf("statuses: %v", err)
+	}
+	stale := 0
+	for _, status := range statuses {
+		if status.Stale {
+			stale++
+			if status.Source != FXSourceManual || status.AgeDays != 15 {
+				t.Fatalf("unexpected stale pair: %+v", status)
+			}
+		}
+	}
+	if len(statuses) != 8 || stale != 1 {
+		t.Fatalf("got %d statuses with %d stale, want 8 with 1", len(statuses), stale)
+	}
+
+	older, err := NewECBProvider(server.URL+"/ecb.xml", "", 0).FetchRates(ctx, now.AddDate(0, 0, -1))
+	if err != nil || len(older) != 3 || older[0].Date != "2026-10-15" {
+		t.Fatalf("ECB feed must pick the latest day on or before the date: %+v (%v)", older, err)
+	}
+}
+
+func TestResolveFXRateReportsPathAndTriangulatesViaUZS(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+	for _, rate := range []*FXRate{
+		{Date: "2026-10-01", FromCurrency: "USD", ToCurrency: "UZS", Rate: 12600},
+		{Date: "2026-10-15", FromCurrency: "USD", ToCurrency: "UZS", Rate: 12650},
+		{Date: "2026-10-15", FromCurrency: "EUR", ToCurrency: "UZS", Rate: 13800},
+		{Date: "2026-10-15", FromCurrency: "RUB", ToCurrency: "UZS", Rate: 150},
+	} {
+		if _, err := service.CreateFXRate(ctx, rate); err != nil {
+			t.Fatalf("create rate: %v", err)
+		}
+	}
+
+	direct, err := service.ResolveFXRate(ctx, "usd", "uzs", "2026-10-10")
+	if err != nil || direct.Path != FXPathDirect || direct.Rate != 12600 {
+		t.Fatalf("expected the rate in effect on the date, got %+v (%v)", direct, err)
+	}
+	inverse, err := service.ResolveFXRate(ctx, "UZS", "USD", "2026-10-16")
+	if err != nil || inverse.Path != FXPathInverse || !inverse.Legs[0].Inverted {
+		t.Fatalf("expected an inverted rate, got %+v (%v)", inverse, err)
+	}
+	cross, err := service.ResolveFXRate(ctx, "EUR", "RUB", "2026-10-16")
+	if err != nil || cross.Path != FXPathTriangulated || cross.Via != "UZS" || cross.Rate != 92 || len(cross.Legs) != 2 {
+		t.Fatalf("expected EUR→RUB via UZS at 92, got %+v (%v)", cross, err)
+	}
+	if converted := cross.exchange.Convert(money(100), "RUB"); converted != money(9200) {
+		t.Fatalf("cross conversion mismatch: got %s", converted)
+	}
+	if _, err := service.ResolveFXRate(ctx, "EUR", "GBP", "2026-10-16"); err != appErrors.FXRateNotFound {
+		t.Fatalf("pairs without any stored path must not fall back to constants, got %v", err)
+	}
+
+	// A new manual rate replaces the cached resolution immediately.
+	if _, err := service.CreateFXRate(ctx, &FXRate{Date: "2026-10-16", FromCurrency: "EUR", ToCurrency: "RUB", Rate: 91}); err != nil {
+		t.Fatalf("create rate: %v", err)
+	}
+	if cross, _ = service.ResolveFXRate(ctx, "EUR", "RUB", "2026-10-16"); cross.Path != FXPathDirect || cross.Rate != 91 {
+		t.Fatalf("cache must be cleared by new rates, got %+v", cross)
+	}
+}
+
+func TestTransactionCursorPagesAreStableAndFiltered(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-page")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	createdAccount, _, err := service.CreateAccount(ctx, &Account{
+		Name:           "Cash",
+		AccountType:    "cash",
+		Currency:       "USD",
+		InitialBalance: money(1000),
+		CurrentBalance: money(1000),
+		ShowStatus:     "active",
+	})
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	counterpartyID := "cp-1"
+	post := func(date string, counterparty *string) {
+		t.Helper()
+		if _, err := service.CreateTransaction(ctx, &Transaction{
+			Type:           TransactionTypeExpense,
+			AccountID:      &createdAccount.ID,
+			Amount:         money(5),
+			Currency:       "USD",
+			Date:           date,
+			CounterpartyID: counterparty,
+		}); err != nil {
+			t.Fatalf("create transaction: %v", err)
+		}
+	}
+	// Several entries share a date so the created_at and id tie-breakers matter.
+	for _, date := range []string{"2026-10-01", "2026-10-03", "2026-10-03", "2026-10-03", "2026-10-05", "2026-10-05", "2026-10-07"} {
+		post(date, nil)
+	}
+	post("2026-10-04", &counterpartyID)
+
+	newestFirst := TransactionFilter{SortBy: TransactionSortByDate, SortOrder: TransactionSortDesc}
+	seen := make(map[string]bool)
+	var last *Transaction
+	request := TransactionPageRequest{Limit: 3}
+	for pages := 0; ; pages++ {
+		page, err := service.Transactions(ctx, TransactionFilter{Type: TransactionTypeExpense}, request)
+		if err != nil {
+			t.Fatalf("list page: %v", err)
+		}
+		if pages == 0 {
+			// Entries posted after the first page is read must not shift later pages.
+			post("2026-10-09", nil)
+		}
+		for _, txn := range page.Items {
+			if seen[txn.ID] {
+				t.Fatalf("transaction %s returned twice", txn.ID)
+			}
+			seen[txn.ID] = true
+			if last != nil && transactionFeedBefore(transactionCursorFor(txn, newestFirst), transactionCursorFor(last, newestFirst)) {
+				t.Fatalf("page order broken between %s and %s", last.ID, txn.ID)
+			}
+			last = txn
+		}
+		if !page.HasMore {
+			break
+		}
+		cursor, err := DecodeTransactionCursor(page.NextCursor)
+		if err != nil {
+			t.Fatalf("decode cursor: %v", err)
+		}
+		request.Cursor = cursor
+	}
+	if len(seen) != 8 {
+		t.Fatalf("expected the 8 entries present at the first page, got %d", len(seen))
+	}
+
+	byCounterparty, err := service.CounterpartyTransactions(ctx, counterpartyID, TransactionPageRequest{Limit: 10, Page: 1})
+	if err != nil {
+		t.Fatalf("counterparty transactions: %v", err)
+	}
+	if len(byCounterparty.Items) != 1 || byCounterparty.Total != 1 || byCounterparty.HasMore {
+		t.Fatalf("counterparty filter mismatch: %+v", byCounterparty)
+	}
+	if _, err := DecodeTransactionCursor("not-a-cursor"); err == nil {
+		t.Fatalf("malformed cursors must be rejected")
+	}
+}
+
+func TestTransactionQueryCombinesAmountTagsTextAndSort(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-query")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	createdAccount, _, err := service.CreateAccount(ctx, &Account{
+		Name:           "Uzcard",
+		AccountType:    "card",
+		Currency:       "UZS",
+		InitialBalance: money(5_000_000),
+		CurrentBalance: money(5_000_000),
+		ShowStatus:     "active",
+	})
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	akmal := "cp-akmal"
+	post := func(name string, amount float64, tags []string, counterparty *string) {
+		t.Helper()
+		if _, err := service.CreateTransaction(ctx, &Transaction{
+			Type:           TransactionTypeExpense,
+			AccountID:      &createdAccount.ID,
+			Amount:         money(amount),
+			Currency:       "UZS",
+			BaseCurrency:   "UZS",
+			Date:           "2026-10-10",
+			Name:           &name,
+			Tags:           tags,
+			CounterpartyID: counterparty,
+		}); err != nil {
+			t.Fatalf("create transaction: %v", err)
+		}
+	}
+	post("Yandex Taxi to office", 64_000, []string{"work", "taxi"}, &akmal)
+	post("taxi home", 120_000, []string{"work"}, &akmal)
+	post("Taxi airport", 30_000, []string{"work"}, &akmal)
+	post("Taxi to office", 80_000, []string{"personal"}, &akmal)
+	post("Taxi to client", 90_000, []string{"work"}, nil)
+	post("Lunch", 70_000, []string{"work"}, &akmal)
+
+	minimum := money(50_000)
+	filter := TransactionFilter{
+		Type:           TransactionTypeExpense,
+		CounterpartyID: akmal,
+		AmountMin:      &minimum,
+		AmountBasis:    TransactionAmountBasisBase,
+		TagsAll:        []string{"work"},
+		Search:         "TAXI",
+		SortBy:         TransactionSortByAmount,
+		SortOrder:      TransactionSortAsc,
+	}
+	first, err := service.Transactions(ctx, filter, TransactionPageRequest{Limit: 1})
+	if err != nil {
+		t.Fatalf("query transactions: %v", err)
+	}
+	if len(first.Items) != 1 || first.Items[0].Amount != money(64_000) || !first.HasMore {
+		t.Fatalf("expected the cheapest matching taxi first, got %+v", first)
+	}
+	cursor, err := DecodeTransactionCursor(first.NextCursor)
+	if err != nil {
+		t.Fatalf("decode cursor: %v", err)
+	}
+	second, err := service.Transactions(ctx, filter, TransactionPageRequest{Limit: 1, Cursor: cursor})
+	if err != nil {
+		t.Fatalf("query next page: %v", err)
+	}
+	if len(second.Items) != 1 || second.Items[0].Amount != money(120_000) || second.HasMore {
+		t.Fatalf("expected the remaining matching taxi, got %+v", second)
+	}
+
+	anyTag, err := service.Transactions(ctx, TransactionFilter{TagsAny: []string{"taxi", "personal"}}, TransactionPageRequest{Limit: 10})
+	if err != nil || len(anyTag.Items) != 2 {
+		t.Fatalf("tagsAny mismatch: %+v (%v)", anyTag, err)
+	}
+
+	// A cursor only continues the sort it was issued for.
+	if _, err := service.Transactions(ctx, TransactionFilter{}, TransactionPageRequest{Limit: 1, Cursor: cursor}); err == nil {
+		t.Fatalf("cursor from another sort must be rejected")
+	}
+	if _, err := service.Transactions(ctx, TransactionFilter{SortBy: "name"}, TransactionPageRequest{}); err == nil {
+		t.Fatalf("unknown sort must be rejected")
+	}
+}
+
+func TestParseStatementReadsEachFormat(t *testing.T) {
+	read := func(name string) []byte {
+		t.Helper()
+		data, err := os.ReadFile("testdata/" + name)
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		return data
+	}
+	mapping := &StatementCSVMapping{
+		Delimiter:         ";",
+		HasHeader:         true,
+		SkipRows:          1,
+		DateColumn:        "Date",
+		DateFormat:        "DD.MM.YYYY",
+		AmountColumn:      "Amount",
+		DescriptionColumn: "Details",
+		PayeeColumn:       "Counterparty",
+		ReferenceColumn:   "Reference",
+		DecimalSeparator:  ",",
+	}
+	cases := []struct {
+		file   string
+		format string
+		want   []StatementLine
+	}{
+		{"statement.csv", StatementFormatCSV, []StatementLine{
+			{Date: "2026-10-03", Amount: money(-125_000.5), Payee: "Korzinka", Description: "Card payment", Reference: "R-1001"},
+			{Date: "2026-10-05", Amount: money(8_500_000), Payee: "Acme LLC", Description: "Salary October", Reference: "R-1002"},
+			{Date: "2026-10-03", Amount: money(-125_000.5), Payee: "Korzinka", Description: "Card payment", Reference: "R-1001"},
+		}},
+		{"statement.ofx", StatementFormatOFX, []StatementLine{
+			{Date: "2026-10-02", Amount: money(-42.15), Currency: "USD", Payee: "Coffee & Co", Description: "Morning coffee", Reference: "OFX-2001"},
+			{Date: "2026-10-04", Amount: money(1250), Currency: "USD", Payee: "Payroll", Description: "October salary", Reference: "OFX-2002"},
+		}},
+		{"statement.qif", StatementFormatQIF, []StatementLine{
+			{Date: "2026-10-07", Amount: money(-18.4), Payee: "Bookshop", Description: "Notebooks", Reference: "1045"},
+			{Date: "2026-10-08", Amount: money(300), Payee: "Refund"},
+		}},
+		{"camt053.xml", StatementFormatCAMT053, []StatementLine{
+			{Date: "2026-10-09", Amount: money(-59.9), Currency: "EUR", Payee: "Stadtwerke", Description: "Electricity September", Reference: "CAMT-3001"},
+			{Date: "2026-10-10", Amount: money(150), Currency: "EUR", Payee: "Jane Doe", Description: "Transfer from savings", Reference: "E2E-2"},
+		}},
+	}
+	for _, tc := range cases {
+		data := read(tc.file)
+		if detected := detectStatementFormat(tc.file, data); detected != tc.format {
+			t.Fatalf("%s: expected format %s, got %s", tc.file, tc.format, detected)
+		}
+		var lineMapping *StatementCSVMapping
+		if tc.format == StatementFormatCSV {
+			lineMapping = mapping
+		}
+		lines, err := ParseStatement(tc.format, data, lineMapping)
+		if err != nil {
+			t.Fatalf("%s: parse: %v", tc.file, err)
+		}
+		if len(lines) != len(tc.want) {
+			t.Fatalf("%s: expected %d lines, got %d", tc.file, len(tc.want), len(lines))
+		}
+		for i, want := range tc.want {
+			got := *lines[i]
+			got.LineNumber = 0
+			if got != want {
+				t.Fatalf("%s line %d: expected %+v, got %+v", tc.file, i+1, want, got)
+			}
+		}
+	}
+
+	if _, err := ParseStatement(StatementFormatCSV, read("statement.csv"), nil); err == nil {
+		t.Fatalf("expected CSV without a mapping to be rejected")
+	}
+}
+
+func TestStatementImportFlagsDuplicatesApprovesAndUndoes(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-import")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	createdAccount, _, err := service.CreateAccount(ctx, &Account{
+		Name:           "Checking",
+		AccountType:    "bank",
+		Currency:       "USD",
+		InitialBalance: money(500),
+		CurrentBalance: money(500),
+		ShowStatus:     "active",
+	})
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	coffee := "Coffee"
+	if _, err := service.CreateTransaction(ctx, &Transaction{
+		Type:      TransactionTypeExpense,
+		AccountID: &createdAccount.ID,
+		Amount:    money(42.15),
+		Currency:  "USD",
+		Date:      "2026-10-02",
+		Name:      &coffee,
+	}); err != nil {
+		t.Fatalf("create transaction: %v", err)
+	}
+	data, err := os.ReadFile("testdata/statement.ofx")
+	if err != nil {
+		t.Fatalf("read statement: %v", err)
+	}
+	balance := func() Money {
+		t.Helper()
+		account, err := repo.GetAccountByID(ctx, createdAccount.ID)
+		if err != nil {
+			t.Fatalf("get account: %v", err)
+		}
+		return account.CurrentBalance
+	}
+
+	batch, err := service.ImportStatement(ctx, StatementImportInput{AccountID: createdAccount.ID, FileName: "statement.ofx", Data: data})
+	if err != nil {
+		t.Fatalf("import statement: %v", err)
+	}
+	if batch.Format != StatementFormatOFX || batch.DuplicateCount != 1 || batch.PendingCount != 1 {
+		t.Fatalf("expected one duplicate and one pending row, got %+v", batch)
+	}
+	if batch.Rows[0].Status != StatementRowDuplicate || batch.Rows[0].DuplicateOfID == nil {
+		t.Fatalf("expected the coffee line to match the existing expense, got %+v", batch.Rows[0])
+	}
+
+	approved, err := service.ApproveStatementImportRows(ctx, batch.ID, StatementImportDecision{})
+	if err != nil {
+		t.Fatalf("approve rows: %v", err)
+	}
+	if approved.ApprovedCount != 1 || approved.DuplicateCount != 1 || approved.Status != StatementImportStatusReview {
+		t.Fatalf("expected the salary to be approved and the duplicate kept for review, got %+v", approved)
+	}
+	if got := balance(); got != money(500-42.15+1250) {
+		t.Fatalf("expected balance after approval 1707.85, got %s", got)
+	}
+
+	again, err := service.ImportStatement(ctx, StatementImportInput{AccountID: createdAccount.ID, FileName: "statement.ofx", Data: data})
+	if err != nil {
+		t.Fatalf("re-import statement: %v", err)
+	}
+	if again.DuplicateCount != 2 || again.PendingCount != 0 {
+		t.Fatalf("expected a re-import to flag every line, got %+v", again)
+	}
+
+	csvData, err := os.ReadFile("testdata/statement.csv")
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	profile, err := service.CreateStatementImportProfile(ctx, &StatementImportProfile{
+		Name: "Local bank",
+		Mapping: StatementCSVMapping{
+			Delimiter: ";", HasHeader: true, SkipRows: 1, DateColumn: "Date", DateFormat: "DD.MM.YYYY",
+			AmountColumn: "Amount", DescriptionColumn: "Details", PayeeColumn: "Counterparty",
+			ReferenceColumn: "Reference", DecimalSeparator: ",",
+		},
+	})
+	if err != nil {
+		t.Fatalf("create profile: %v", err)
+	}
+	csvBatch, err := service.ImportStatement(ctx, StatementImportInput{AccountID: createdAccount.ID, FileName: "statement.csv", Data: csvData, ProfileID: profile.ID})
+	if err != nil {
+		t.Fatalf("import csv: %v", err)
+	}
+	if csvBatch.Rows[2].Status != StatementRowDuplicate || csvBatch.PendingCount != 2 {
+		t.Fatalf("expected the repeated CSV line to be flagged, got %+v", csvBatch)
+	}
+
+	undone, err := service.UndoStatementImport(ctx, batch.ID)
+	if err != nil {
+		t.Fatalf("undo import: %v", err)
+	}
+	if undone.Status != StatementImportStatusUndone || undone.RejectedCount != 1 {
+		t.Fatalf("expected the import to be undone, got %+v", undone)
+	}
+	if got := balance(); got != money(500-42.15) {
+		t.Fatalf("expected balance after undo 457.85, got %s", got)
+	}
+	if _, err := service.ApproveStatementImportRows(ctx, batch.ID, StatementImportDecision{}); err != appErrors.StatementImportUndone {
+		t.Fatalf("expected approving an undone import to fail, got %v", err)
+	}
+}
+
+func TestStatementImportUndoIsAllOrNothing(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-import-undo")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	createdAccount, _, err := service.CreateAccount(ctx, &Account{
+		Name:           "Checking",
+		AccountType:    "bank",
+		Currency:       "USD",
+		InitialBalance: money(500),
+		CurrentBalance: money(500),
+		ShowStatus:     "active",
+	})
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	data, err := os.ReadFile("testdata/statement.ofx")
+	if err != nil {
+		t.Fatalf("read statement: %v", err)
+	}
+	balance := func() Money {
+		t.Helper()
+		account, err := repo.GetAccountByID(ctx, createdAccount.ID)
+		if err != nil {
+			t.Fatalf("get account: %v", err)
+		}
+		return account.CurrentBalance
+	}
+
+	batch, err := service.ImportStatement(ctx, StatementImportInput{AccountID: createdAccount.ID, FileName: "statement.ofx", Data: data})
+	if err != nil {
+		t.Fatalf("import statement: %v", err)
+	}
+	approved, err := service.ApproveStatementImportRows(ctx, batch.ID, StatementImportDecision{})
+	if err != nil {
+		t.Fatalf("approve rows: %v", err)
+	}
+	if approved.ApprovedCount != 2 {
+		t.Fatalf("expected both lines to be approved, got %+v", approved)
+	}
+	rent := "Rent"
+	if _, err := service.CreateTransaction(ctx, &Transaction{
+		Type:      TransactionTypeExpense,
+		AccountID: &createdAccount.ID,
+		Amount:    money(1500),
+		Currency:  "USD",
+		Date:      "2026-10-05",
+		Name:      &rent,
+	}); err != nil {
+		t.Fatalf("create transaction: %v", err)
+	}
+	if got := balance(); got != money(500-42.15+1250-1500) {
+		t.Fatalf("expected balance 207.85, got %s", got)
+	}
+
+	// The coffee line could be reversed on its own, the salary cannot: the
+	// money has been spent. Nothing may be reversed.
+	if _, err := service.UndoStatementImport(ctx, batch.ID); err == nil || err.(*appErrors.Error).Code != appErrors.InsufficientFunds.Code {
+		t.Fatalf("expected undo to fail with insufficient funds, got %v", err)
+	}
+	if got := balance(); got != money(207.85) {
+		t.Fatalf("expected a failed undo to leave the balance at 207.85, got %s", got)
+	}
+	kept, rows, err := service.loadStatementImport(ctx, batch.ID)
+	if err != nil {
+		t.Fatalf("load import: %v", err)
+	}
+	if kept.Status == StatementImportStatusUndone || kept.UndoneAt != nil {
+		t.Fatalf("expected the import to stay open, got %+v", kept)
+	}
+	for _, row := range rows {
+		if row.Status != StatementRowApproved {
+			t.Fatalf("expected row %d to stay approved, got %s", row.LineNumber, row.Status)
+		}
+		txn, err := repo.GetTransactionByID(ctx, *row.TransactionID)
+		if err != nil {
+			t.Fatalf("get transaction: %v", err)
+		}
+		if txn.Status == TransactionStatusVoided {
+			t.Fatalf("expected row %d's entry not to be reversed", row.LineNumber)
+		}
+	}
+
+	bonus := "Bonus"
+	if _, err := service.CreateTransaction(ctx, &Transaction{
+		Type:      TransactionTypeIncome,
+		AccountID: &createdAccount.ID,
+		Amount:    money(2000),
+		Currency:  "USD",
+		Date:      "2026-10-06",
+		Name:      &bonus,
+	}); err != nil {
+		t.Fatalf("create transaction: %v", err)
+	}
+	undone, err := service.UndoStatementImport(ctx, batch.ID)
+	if err != nil {
+		t.Fatalf("undo import: %v", err)
+	}
+	if undone.Status != StatementImportStatusUndone {
+		t.Fatalf("expected the import to be undone, got %+v", undone)
+	}
+	if got := balance(); got != money(207.85+2000-1250+42.15) {
+		t.Fatalf("expected balance after undo 1000, got %s", got)
+	}
+}
+
+func TestUndoStatementImportRefusesReconciledEntries(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-import-locked")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	createdAccount, _, err := service.CreateAccount(ctx, &Account{
+		Name:           "Checking",
+		AccountType:    "bank",
+		Currency:       "USD",
+		InitialBalance: money(500),
+		CurrentBalance: money(500),
+		ShowStatus:     "active",
+	})
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	data, err := os.ReadFile("testdata/statement.ofx")
+	if err != nil {
+		t.Fatalf("read statement: %v", err)
+	}
+	batch, err := service.ImportStatement(ctx, StatementImportInput{AccountID: createdAccount.ID, FileName: "statement.ofx", Data: data})
+	if err != nil {
+		t.Fatalf("import statement: %v", err)
+	}
+	if _, err := service.ApproveStatementImportRows(ctx, batch.ID, StatementImportDecision{}); err != nil {
+		t.Fatalf("approve rows: %v", err)
+	}
+	_, rows, err := service.loadStatementImport(ctx, batch.ID)
+	if err != nil {
+		t.Fatalf("load import: %v", err)
+	}
+	cleared, _ := repo.GetTransactionByID(ctx, *rows[0].TransactionID)
+	reconciliationID := "reconciliation-1"
+	cleared.ReconciliationID = &reconciliationID
+	if err := repo.UpdateTransaction(ctx, cleared); err != nil {
+		t.Fatalf("lock entry: %v", err)
+	}
+
+	_, err = service.UndoStatementImport(ctx, batch.ID)
+	typed, ok := err.(*appErrors.Error)
+	if !ok || typed.Code != appErrors.TransactionLocked.Code {
+		t.Fatalf("expected undo to fail on the reconciled entry, got %v", err)
+	}
+	if ids, _ := typed.Details["transactionIds"].([]string); len(ids) != 1 || ids[0] != cleared.ID {
+		t.Fatalf("expected the reconciled entry to be reported, got %v", typed.Details)
+	}
+	account, _ := repo.GetAccountByID(ctx, createdAccount.ID)
+	if account.CurrentBalance != money(500-42.15+1250) {
+		t.Fatalf("expected a refused undo to leave the balance alone, got %s", account.CurrentBalance)
+	}
+	for _, row := range rows {
+		txn, _ := repo.GetTransactionByID(ctx, *row.TransactionID)
+		if txn.Status == TransactionStatusVoided {
+			t.Fatalf("expected row %d's entry not to be reversed", row.LineNumber)
+		}
+	}
+}
+
+type smsFixture struct {
+	Sender   string `json:"sender"`
+	Text     string `json:"text"`
+	Expected *struct {
+		Bank       string `json:"bank"`
+		Direction  string `json:"direction"`
+		Amount     Money  `json:"amount"`
+		Currency   string `json:"currency"`
+		Merchant   string `json:"merchant"`
+		CardMask   string `json:"cardMask"`
+		Balance    Money  `json:"balance"`
+		OccurredAt string `json:"occurredAt"`
+	} `json:"expected"`
+}
+
+func loadSMSFixtures(t *testing.T) []smsFixture {
+	t.Helper()
+	data, err := os.ReadFile("testdata/sms_messages.json")
+	if err != nil {
+		t.Fatalf("read fixtures: %v", err)
+	}
+	var fixtures []smsFixture
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		t.Fatalf("decode fixtures: %v", err)
+	}
+	return fixtures
+}
+
+func TestSMSTemplatesParseBankFixtures(t *testing.T) {
+	for i, fixture := range loadSMSFixtures(t) {
+		parsed, ok := parseSMS(DefaultSMSTemplates(), fixture.Sender, fixture.Text)
+		if fixture.Expected == nil {
+			if ok {
+				t.Fatalf("fixture %d: expected no template to match, got %+v", i, parsed)
+			}
+			continue
+		}
+		if !ok {
+			t.Fatalf("fixture %d: no template matched %q", i, fixture.Text)
+		}
+		want := fixture.Expected
+		if parsed.Bank != want.Bank || parsed.Direction != want.Direction || parsed.Amount != want.Amount ||
+			parsed.Currency != want.Currency || parsed.Merchant != want.Merchant || parsed.CardMask != want.CardMask {
+			t.Fatalf("fixture %d: expected %+v, got %+v", i, *want, parsed)
+		}
+		if parsed.Balance == nil || *parsed.Balance != want.Balance {
+			t.Fatalf("fixture %d: expected balance %s, got %v", i, want.Balance, parsed.Balance)
+		}
+		if got := parsed.OccurredAt.UTC().Format(time.RFC3339); got != want.OccurredAt {
+			t.Fatalf("fixture %d: expected time %s, got %s", i, want.OccurredAt, got)
+		}
+	}
+}
+
+func TestIngestSMSPostsPendingTransactionsOnLinkedCards(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-sms")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	createdAccount, _, err := service.CreateAccount(ctx, &Account{
+		Name:           "Humo",
+		AccountType:    "card",
+		Currency:       "UZS",
+		InitialBalance: money(2_000_000),
+		CurrentBalance: money(2_000_000),
+		ShowStatus:     "active",
+	})
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	if _, err := service.CreateSMSCardLink(ctx, &SMSCardLink{AccountID: createdAccount.ID, CardMask: "9860 **** **** 5678"}); err != nil {
+		t.Fatalf("link card: %v", err)
+	}
+	if _, err := service.CreateSMSCardLink(ctx, &SMSCardLink{AccountID: createdAccount.ID, CardMask: "*5678"}); err != appErrors.SMSCardLinkExists {
+		t.Fatalf("expected a second link for the card to be refused, got %v", err)
+	}
+
+	fixtures := loadSMSFixtures(t)
+	humo := SMSInput{Sender: fixtures[2].Sender, Text: fixtures[2].Text, ReceivedAt: "2026-10-12T04:15:30Z"}
+	uzcard := SMSInput{Sender: fixtures[0].Sender, Text: fixtures[0].Text}
+	otp := SMSInput{Sender: fixtures[8].Sender, Text: fixtures[8].Text}
+	messages, err := service.IngestSMSMessages(ctx, []SMSInput{humo, uzcard, otp})
+	if err != nil {
+		t.Fatalf("ingest: %v", err)
+	}
+	if messages[0].Status != SMSStatusPosted || messages[1].Status != SMSStatusUnmatched || messages[2].Status != SMSStatusUnparsed {
+		t.Fatalf("expected posted, unmatched and unparsed, got %s, %s, %s", messages[0].Status, messages[1].Status, messages[2].Status)
+	}
+	txn, err := repo.GetTransactionByID(ctx, *messages[0].TransactionID)
+	if err != nil {
+		t.Fatalf("get transaction: %v", err)
+	}
+	if txn.Status != TransactionStatusPending || txn.Type != TransactionTypeExpense || txn.Amount != money(45_000) ||
+		txn.Date != "2026-10-12" || txn.Name == nil || *txn.Name != "YANDEX GO" {
+		t.Fatalf("expected a pending taxi expense, got %+v", txn)
+	}
+	balance := func() Money {
+		t.Helper()
+		account, err := repo.GetAccountByID(ctx, createdAccount.ID)
+		if err != nil {
+			t.Fatalf("get account: %v", err)
+		}
+		return account.CurrentBalance
+	}
+	if got := balance(); got != money(2_000_000) {
+		t.Fatalf("expected a pending SMS entry to leave the balance alone, got %s", got)
+	}
+	if posted, err := service.PostDueScheduledTransactions(ctx, time.Now()); err != nil || posted != 0 {
+		t.Fatalf("expected the due-post job to leave SMS entries to the user, got %d %v", posted, err)
+	}
+
+	again, err := service.IngestSMSMessages(ctx, []SMSInput{humo})
+	if err != nil {
+		t.Fatalf("re-ingest: %v", err)
+	}
+	if again[0].ID != messages[0].ID {
+		t.Fatalf("expected a resent message to return the stored one")
+	}
+	confirmed, err := service.ConfirmScheduledTransaction(ctx, txn.ID)
+	if err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	if confirmed.Status != TransactionStatusCompleted || confirmed.Date != "2026-10-12" {
+		t.Fatalf("expected the taxi expense to post on its own date, got %+v", confirmed)
+	}
+	if got := balance(); got != money(1_955_000) {
+		t.Fatalf("expected one debit on the card, got balance %s", got)
+	}
+
+	if _, err := service.CreateSMSCardLink(ctx, &SMSCardLink{AccountID: createdAccount.ID, CardMask: "1234", Bank: "uzcard"}); err != nil {
+		t.Fatalf("link uzcard: %v", err)
+	}
+	waiting, err := service.SMSMessages(ctx, SMSStatusUnmatched)
+	if err != nil {
+		t.Fatalf("list unmatched: %v", err)
+	}
+	if len(waiting) != 0 {
+		t.Fatalf("expected linking the card to post the waiting message, got %d unmatched", len(waiting))
+	}
+	relinked, err := service.SMSMessages(ctx, SMSStatusPosted)
+	if err != nil {
+		t.Fatalf("list posted: %v", err)
+	}
+	if len(relinked) != 2 {
+		t.Fatalf("expected both card messages to be posted, got %d", len(relinked))
+	}
+	for _, message := range relinked {
+		if message.ID != messages[1].ID {
+			continue
+		}
+		if _, err := service.SkipScheduledTransaction(ctx, *message.TransactionID); err != nil {
+			t.Fatalf("reject uzcard entry: %v", err)
+		}
+	}
+	if got := balance(); got != money(1_955_000) {
+		t.Fatalf("expected a rejected SMS entry to leave the balance alone, got %s", got)
+	}
+	unparsed, err := service.SMSMessages(ctx, SMSStatusUnparsed)
+	if err != nil || len(unparsed) != 1 || unparsed[0].Body != fixtures[8].Text {
+		t.Fatalf("expected the code message to be kept as unparsed, got %v %v", unparsed, err)
+	}
+}
+
+func TestCategorizationRulesClassifyNewAndPastTransactions(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-rules")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	createdAccount, _, err := service.CreateAccount(ctx, &Account{
+		Name:           "Card",
+		AccountType:    "card",
+		Currency:       "UZS",
+		InitialBalance: money(5_000_000),
+		CurrentBalance: money(5_000_000),
+		ShowStatus:     "active",
+	})
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	accountID := createdAccount.ID
+	expense := func(name string, amount float64) *Transaction {
+		txn, err := service.CreateTransaction(ctx, &Transaction{
+			Type:      TransactionTypeExpense,
+			AccountID: &accountID,
+			Amount:    money(amount),
+			Currency:  "UZS",
+			Date:      "2026-10-10",
+			Name:      &name,
+		})
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		return txn
+	}
+	category := func(id string) *string { return &id }
+
+	before := expense("Korzinka Chilonzor", 120_000)
+	reconciled, _ := repo.GetTransactionByID(ctx, expense("Korzinka Sergeli", 80_000).ID)
+	reconciliationID := "reconciliation-1"
+	reconciled.ReconciliationID = &reconciliationID
+	if err := repo.UpdateTransaction(ctx, reconciled); err != nil {
+		t.Fatalf("lock entry: %v", err)
+	}
+	if _, err := service.CreateCategorizationRule(ctx, &CategorizationRule{
+		Name:       "No conditions",
+		Conditions: CategorizationRuleConditions{},
+		Actions:    CategorizationRuleActions{CategoryID: category("groceries")},
+	}); err == nil || err.(*appErrors.Error).Code != appErrors.InvalidCategorizationRule.Code {
+		t.Fatalf("expected a rule without conditions to be refused, got %v", err)
+	}
+	dryRun, err := service.DryRunCategorizationRule(ctx, &CategorizationRule{
+		Name:       "Korzinka",
+		Conditions: CategorizationRuleConditions{Text: "korzinka"},
+		Actions:    CategorizationRuleActions{CategoryID: category("groceries")},
+	}, false, 1, 20)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if dryRun.Matched != 2 || dryRun.Changed != 1 || dryRun.Skipped != 1 || dryRun.Applied {
+		t.Fatalf("expected the dry run to report one change and one locked entry, got %+v", dryRun)
+	}
+	untouched, _ := repo.GetTransactionByID(ctx, before.ID)
+	if untouched.CategoryID != nil {
+		t.Fatalf("expected the dry run not to change the transaction")
+	}
+
+	grocery, err := service.CreateCategorizationRule(ctx, &CategorizationRule{
+		Name:        "Korzinka",
+		Priority:    10,
+		StopOnMatch: true,
+		Conditions:  CategorizationRuleConditions{Text: "korzinka"},
+		Actions:     CategorizationRuleActions{CategoryID: category("groceries"), Tags: []string{"food"}},
+	})
+	if err != nil {
+		t.Fatalf("create grocery rule: %v", err)
+	}
+	largeAmount := money(100_000)
+	if _, err := service.CreateCategorizationRule(ctx, &CategorizationRule{
+		Name:       "Large purchases",
+		Priority:   20,
+		Conditions: CategorizationRuleConditions{AmountMin: &largeAmount},
+		Actions:    CategorizationRuleActions{CategoryID: category("shopping"), Tags: []string{"large"}},
+	}); err != nil {
+		t.Fatalf("create amount rule: %v", err)
+	}
+
+	matched := expense("KORZINKA Yunusobod", 250_000)
+	if matched.CategoryID == nil || *matched.CategoryID != "groceries" || len(matched.Tags) != 1 {
+		t.Fatalf("expected the first rule to stop evaluation, got %v %v", matched.CategoryID, matched.Tags)
+	}
+	large := expense("Makro", 300_000)
+	if large.CategoryID == nil || *large.CategoryID != "shopping" {
+		t.Fatalf("expected the amount rule to categorize, got %v", large.CategoryID)
+	}
+	explicitName := "Korzinka"
+	explicit, err := service.CreateTransaction(ctx, &Transaction{
+		Type:       TransactionTypeExpense,
+		AccountID:  &accountID,
+		Amount:     money(50_000),
+		Currency:   "UZS",
+		Date:       "2026-10-11",
+		Name:       &explicitName,
+		CategoryID: category("gifts"),
+	})
+	if err != nil {
+		t.Fatalf("create explicit: %v", err)
+	}
+	if *explicit.CategoryID != "gifts" {
+		t.Fatalf("expected the user's category to win, got %s", *explicit.CategoryID)
+	}
+
+	applied, err := service.ApplyCategorizationRule(ctx, grocery.ID, false, 1, 20)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if applied.Matched != 4 || applied.Changed != 1 || applied.Skipped != 1 {
+		t.Fatalf("expected four matches, one change and one skipped entry, got %+v", applied)
+	}
+	if locked, _ := repo.GetTransactionByID(ctx, reconciled.ID); locked.CategoryID != nil {
+		t.Fatalf("expected the reconciled entry to keep its classification, got %v", *locked.CategoryID)
+	}
+	paged, err := service.ApplyCategorizationRule(ctx, grocery.ID, false, 2, 3)
+	if err != nil || paged.Matched != 4 || len(paged.Items) != 1 {
+		t.Fatalf("expected the second page to hold the last match, got %+v (%v)", paged, err)
+	}
+	updated, _ := repo.GetTransactionByID(ctx, before.ID)
+	if updated.CategoryID == nil || *updated.CategoryID != "groceries" || updated.Amount != money(120_000) {
+		t.Fatalf("expected the past entry to be recategorized only, got %+v", updated)
+	}
+	kept, _ := repo.GetTransactionByID(ctx, explicit.ID)
+	if *kept.CategoryID != "gifts" {
+		t.Fatalf("expected apply without overwrite to keep the category, got %s", *kept.CategoryID)
+	}
+}
+
+func TestSplitTransactionCountsEachLineTowardsItsBudgetAndCategory(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-split")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	createdAccount, _, err := service.CreateAccount(ctx, &Account{
+		Name:           "Card",
+		AccountType:    "card",
+		Currency:       "USD",
+		InitialBalance: money(500),
+		CurrentBalance: money(500),
+		ShowStatus:     "active",
+	})
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	start, end := "2026-03-01", "2026-03-31"
+	newBudget := func(name string) *Budget {
+		budget, err := service.CreateBudget(ctx, &Budget{
+			Name:        name,
+			LimitAmount: money(200),
+			Currency:    "USD",
+			PeriodType:  "monthly",
+			StartDate:   &start,
+			EndDate:     &end,
+		})
+		if err != nil {
+			t.Fatalf("create budget %s: %v", name, err)
+		}
+		return budget
+	}
+	food, household := newBudget("Food"), newBudget("Household")
+	category := func(id string) *string { return &id }
+
+	receipt := &Transaction{
+		Type:       TransactionTypeExpense,
+		AccountID:  &createdAccount.ID,
+		Amount:     money(100),
+		Currency:   "USD",
+		Date:       "2026-03-05",
+		CategoryID: category("shopping"),
+		Splits: []TransactionSplit{
+			{Amount: money(60), CategoryID: category("groceries"), BudgetID: &food.ID},
+			{Amount: money(30), CategoryID: category("household"), BudgetID: &household.ID},
+			{Amount: money(20), CategoryID: category("kids")},
+		},
+	}
+	if _, err := service.CreateTransaction(ctx, receipt); err == nil || err.(*appErrors.Error).Code != appErrors.InvalidTransactionSplit.Code {
+		t.Fatalf("expected lines over the amount to be refused, got %v", err)
+	}
+	receipt.Splits[2].Amount = money(10)
+	created, err := service.CreateTransaction(ctx, receipt)
+	if err != nil {
+		t.Fatalf("create split: %v", err)
+	}
+	if created.CategoryID != nil || created.Splits[0].ID == "" {
+		t.Fatalf("expected the lines to carry the classification, got %+v", created)
+	}
+
+	for budget, want := range map[*Budget]Money{food: money(60), household: money(30)} {
+		recalculated, err := service.RecalculateBudget(ctx, budget.ID)
+		if err != nil {
+			t.Fatalf("recalculate %s: %v", budget.Name, err)
+		}
+		if recalculated.SpentAmount != want {
+			t.Fatalf("%s spent mismatch: got %s, want %s", budget.Name, recalculated.SpentAmount, want)
+		}
+	}
+	spending, err := service.BudgetSpending(ctx, food.ID)
+	if err != nil {
+		t.Fatalf("budget spending: %v", err)
+	}
+	if len(spending) != 1 || spending[0].CategoryID != "groceries" || spending[0].Amount != money(60) {
+		t.Fatalf("expected only the grocery line in the food budget, got %+v", spending)
+	}
+	page, err := service.BudgetTransactions(ctx, household.ID, TransactionPageRequest{})
+	if err != nil {
+		t.Fatalf("budget transactions: %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != created.ID {
+		t.Fatalf("expected the split entry under the household budget, got %d items", len(page.Items))
+	}
+
+	summary, err := service.FinanceSummary(ctx, start, end, "USD", nil)
+	if err != nil {
+		t.Fatalf("summary: %v", err)
+	}
+	categories := map[string]Money{}
+	for _, item := range summary.TopCategories {
+		categories[item.CategoryID] = item.Amount
+	}
+	if len(categories) != 3 || categories["groceries"] != money(60) || categories["household"] != money(30) || categories["kids"] != money(10) {
+		t.Fatalf("expected top categories per line, got %+v", summary.TopCategories)
+	}
+}
+
+func TestSplitAmountsShareConvertedTotalWithoutDrift(t *testing.T) {
+	txn := &Transaction{Amount: money(3), Splits: []TransactionSplit{{Amount: money(1)}, {Amount: money(1)}, {Amount: money(1)}}}
+	amounts := splitAmounts(txn, money(100))
+	total := Money(0)
+	for _, amount := range amounts {
+		total += amount
+	}
+	if total != money(100) || amounts[0] != MoneyFromFloat(33.3333) {
+		t.Fatalf("expected thirds that add up to 100, got %v", amounts)
+	}
+}
+
+func TestAttachmentsUploadThumbnailQuotaAndInUse(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-attachments")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+	store := storage.NewLocalStore(t.TempDir(), "/files", []byte("secret"))
+	service.SetAttachmentStorage(store, AttachmentOptions{MaxFileBytes: 1 << 20, QuotaBytes: 64 << 10})
+
+	var encoded bytes.Buffer
+	receipt := image.NewRGBA(image.Rect(0, 0, 800, 400))
+	for x := 0; x < 800; x++ {
+		for y := 0; y < 400; y++ {
+			receipt.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 90, A: 255})
+		}
+	}
+	if err := png.Encode(&encoded, receipt); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+
+	attachment, err := service.UploadAttachment(ctx, AttachmentUpload{FileName: "../receipt.png", Data: encoded.Bytes()})
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if attachment.ContentType != "image/png" || attachment.FileName != "receipt.png" {
+		t.Fatalf("unexpected attachment %+v", attachment)
+	}
+	if attachment.Width == nil || *attachment.Width != 800 || attachment.ThumbnailKey == nil || attachment.ThumbnailURL == "" || attachment.URL == "" {
+		t.Fatalf("expected dimensions, thumbnail and links, got %+v", attachment)
+	}
+	thumbFile, err := store.Get(ctx, *attachment.ThumbnailKey)
+	if err != nil {
+		t.Fatalf("read thumbnail: %v", err)
+	}
+	thumb, _, err := image.DecodeConfig(thumbFile)
+	thumbFile.Close()
+	if err != nil || thumb.Width != 320 || thumb.Height != 160 {
+		t.Fatalf("expected a 320x160 thumbnail, got %+v (%v)", thumb, err)
+	}
+
+	_, err = service.UploadAttachment(ctx, AttachmentUpload{FileName: "notes.txt", Data: []byte("just some text")})
+	if typed, ok := err.(*appErrors.Error); !ok || typed.Code != appErrors.InvalidAttachment.Code {
+		t.Fatalf("expected invalid attachment for text, got %v", err)
+	}
+	pdf := append([]byte("%PDF-1.4\n"), make([]byte, 64<<10)...)
+	_, err = service.UploadAttachment(ctx, AttachmentUpload{FileName: "statement.pdf", Data: pdf})
+	if typed, ok := err.(*appErrors.Error); !ok || typed.Code != appErrors.AttachmentQuotaExceeded.Code {
+		t.Fatalf("expected quota exceeded, got %v", err)
+	}
+
+	account, _, err := service.CreateAccount(ctx, &Account{
+		Name:           "Cash",
+		AccountType:    "cash",
+		Currency:       "USD",
+		InitialBalance: money(100),
+		CurrentBalance: money(100),
+		ShowStatus:     "active",
+	})
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	txn, err := service.CreateTransaction(ctx, &Transaction{
+		Type:      TransactionTypeExpense,
+		AccountID: &account.ID,
+		Amount:    money(20),
+		Currency:  "USD",
+		Date:      "2026-04-02",
+	})
+	if err != nil {
+		t.Fatalf("create transaction: %v", err)
+	}
+	if _, err := service.AttachToTransaction(ctx, txn.ID, attachment.ID); err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+	if err := service.DeleteAttachment(ctx, attachment.ID); err != appErrors.AttachmentInUse {
+		t.Fatalf("expected attachment in use, got %v", err)
+	}
+	if _, err := service.DetachFromTransaction(ctx, txn.ID, attachment.ID); err != nil {
+		t.Fatalf("detach: %v", err)
+	}
+	if err := service.DeleteAttachment(ctx, attachment.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := store.Get(ctx, attachment.StorageKey); err != storage.ErrNotFound {
+		t.Fatalf("expected stored file to be removed, got %v", err)
+	}
+	usage, err := service.AttachmentUsage(ctx)
+	if err != nil || usage.Count != 0 || usage.UsedBytes != 0 {
+		t.Fatalf("expected empty usage, got %+v (%v)", usage, err)
+	}
+}
+
+func TestReconciliationPostsDifferenceAndLocksClearedTransactions(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-reconcile")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	account, _, err := service.CreateAccount(ctx, &Account{
+		Name:           "Card",
+		AccountType:    "card",
+		Currency:       "USD",
+		InitialBalance: money(100),
+		CurrentBalance: money(100),
+		ShowStatus:     "active",
+	})
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	post := func(txnType string, amount float64, date string) *Transaction {
+		txn, err := service.CreateTransaction(ctx, &Transaction{
+			Type:      txnType,
+			AccountID: &account.ID,
+			Amount:    money(amount),
+			Currency:  "USD",
+			Date:      date,
+		})
+		if err != nil {
+			t.Fatalf("create %s: %v", txnType, err)
+		}
+		return txn
+	}
+	salary := post(TransactionTypeIncome, 50, "2026-03-01")
+	groceries := post(TransactionTypeExpense, 30, "2026-03-05")
+	later := post(TransactionTypeExpense, 10, "2026-03-20")
+
+	statementBalance := money(118)
+	reconciliation, err := service.StartReconciliation(ctx, account.ID, ReconciliationInput{
+		StatementDate:    "2026-03-10",
+		StatementBalance: &statementBalance,
+	})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if len(reconciliation.Transactions) != 2 || reconciliation.OpeningBalance != money(100) {
+		t.Fatalf("expected two uncleared entries from 100, got %d from %s", len(reconciliation.Transactions), reconciliation.OpeningBalance)
+	}
+	if _, err := service.StartReconciliation(ctx, account.ID, ReconciliationInput{StatementDate: "2026-03-11", StatementBalance: &statementBalance}); err == nil {
+		t.Fatalf("expected a second open reconciliation to be refused")
+	}
+	if _, err := service.SelectReconciliationTransactions(ctx, reconciliation.ID, ReconciliationSelection{Cleared: []string{later.ID}}); err == nil {
+		t.Fatalf("expected an entry after the statement date to be refused")
+	}
+	reconciliation, err = service.SelectReconciliationTransactions(ctx, reconciliation.ID, ReconciliationSelection{Cleared: []string{salary.ID, groceries.ID}})
+	if err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if reconciliation.ClearedBalance != money(120) || reconciliation.Difference != money(-2) {
+		t.Fatalf("expected cleared 120 and difference -2, got %s and %s", reconciliation.ClearedBalance, reconciliation.Difference)
+	}
+
+	completed, err := service.CompleteReconciliation(ctx, reconciliation.ID)
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if completed.Status != ReconciliationStatusCompleted || completed.AdjustmentTransactionID == nil || len(completed.Transactions) != 3 {
+		t.Fatalf("unexpected completed reconciliation %+v", completed)
+	}
+	adjustment, err := repo.GetTransactionByID(ctx, *completed.AdjustmentTransactionID)
+	if err != nil || adjustment.Type != TransactionTypeSystemAdjustment || adjustment.Amount != money(-2) || adjustment.Date != "2026-03-10" {
+		t.Fatalf("unexpected adjustment %+v (%v)", adjustment, err)
+	}
+	updated, _ := repo.GetAccountByID(ctx, account.ID)
+	if updated.CurrentBalance != money(108) {
+		t.Fatalf("expected balance 108, got %s", updated.CurrentBalance)
+	}
+
+	if _, err := service.ReverseTransaction(ctx, groceries.ID, TransactionReversalInput{}); err != appErrors.TransactionLocked {
+		t.Fatalf("expected cleared entry to be locked, got %v", err)
+	}
+	if _, err := repo.ReverseTransaction(ctx, groceries.ID, TransactionReversalInput{}); err != appErrors.TransactionLocked {
+		t.Fatalf("expected the repository to refuse the cleared entry, got %v", err)
+	}
+	cleared, _ := repo.GetTransactionByID(ctx, groceries.ID)
+	cleared.Tags = []string{"edited"}
+	if err := repo.UpdateTransaction(ctx, cleared); err != appErrors.TransactionLocked {
+		t.Fatalf("expected updates to the cleared entry to be refused, got %v", err)
+	}
+	if err := repo.UpdateTransactionAttachments(ctx, groceries.ID, []string{"receipt"}); err != nil {
+		t.Fatalf("attachments should stay editable: %v", err)
+	}
+	if _, err := service.ReverseTransaction(ctx, later.ID, TransactionReversalInput{}); err != nil {
+		t.Fatalf("uncleared entry should stay reversible: %v", err)
+	}
+
+	next, err := service.StartReconciliation(ctx, account.ID, ReconciliationInput{StatementDate: "2026-03-31", StatementBalance: &statementBalance})
+	if err != nil {
+		t.Fatalf("start next: %v", err)
+	}
+	if next.OpeningBalance != money(118) || len(next.Transactions) != 0 || next.Difference != 0 {
+		t.Fatalf("expected next reconciliation to open at 118 with nothing to clear, got %+v", next)
+	}
+	history, err := service.Reconciliations(ctx, account.ID)
+	if err != nil || len(history) != 2 || history[0].ID != next.ID {
+		t.Fatalf("expected history newest first, got %d (%v)", len(history), err)
+	}
+}
+
+func TestCheckLedgerReportsDriftAndRepairsWithAudit(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-ledger")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	account, _, err := service.CreateAccount(ctx, &Account{
+		Name:           "Card",
+		AccountType:    "card",
+		Currency:       "USD",
+		InitialBalance: money(500),
+		CurrentBalance: money(500),
+		ShowStatus:     "active",
+	})
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	start, end := "2026-03-01", "2026-03-31"
+	budget, err := service.CreateBudget(ctx, &Budget{
+		Name:        "Food",
+		LimitAmount: money(200),
+		Currency:    "USD",
+		PeriodType:  "monthly",
+		StartDate:   &start,
+		EndDate:     &end,
+	})
+	if err != nil {
+		t.Fatalf("create budget: %v", err)
+	}
+	if _, err := service.CreateTransaction(ctx, &Transaction{
+		Type:      TransactionTypeExpense,
+		AccountID: &account.ID,
+		Amount:    money(40),
+		Currency:  "USD",
+		Date:      "2026-03-05",
+		BudgetID:  &budget.ID,
+	}); err != nil {
+		t.Fatalf("create expense: %v", err)
+	}
+	debt := &Debt{
+		CounterpartyName:  "Loan",
+		Direction:         "i_owe",
+		PrincipalAmount:   money(100),
+		PrincipalCurrency: "USD",
+		BaseCurrency:      "USD",
+		ShowStatus:        "active",
+	}
+	if _, err := service.CreateDebt(ctx, debt); err != nil {
+		t.Fatalf("create debt: %v", err)
+	}
+	if _, err := service.RepayDebt(ctx, debt.ID, DebtValueInput{
+		AccountID:      account.ID,
+		Amount:         money(30),
+		AmountCurrency: "USD",
+	}); err != nil {
+		t.Fatalf("repay debt: %v", err)
+	}
+
+	// Simulate partial failures: a balance update, a budget rollup and a
+	// debt total that were written without their source rows.
+	stored, _ := repo.GetAccountByID(ctx, account.ID)
+	stored.CurrentBalance += money(5)
+	if err := repo.UpdateAccount(ctx, stored); err != nil {
+		t.Fatalf("tamper account: %v", err)
+	}
+	storedBudget, _ := repo.GetBudgetByID(ctx, budget.ID)
+	storedBudget.SpentAmount = money(25)
+	if err := repo.UpdateBudgetRollups(ctx, storedBudget); err != nil {
+		t.Fatalf("tamper budget: %v", err)
+	}
+	storedDebt, _ := repo.GetDebtByID(ctx, debt.ID)
+	storedDebt.TotalPaid = money(50)
+	if err := repo.UpdateDebt(ctx, storedDebt); err != nil {
+		t.Fatalf("tamper debt: %v", err)
+	}
+
+	report, err := service.CheckLedger(context.Background(), LedgerCheckOptions{})
+	if err != nil {
+		t.Fatalf("check ledger: %v", err)
+	}
+	if report.UsersChecked != 1 || len(report.Users) != 1 || report.Users[0].UserID != "user-ledger" {
+		t.Fatalf("unexpected users in report: %+v", report)
+	}
+	drifts := make(map[string]*LedgerDrift)
+	for _, drift := range report.Users[0].Drifts {
+		drifts[drift.EntityType+"."+drift.Field] = drift
+	}
+	if drift := drifts["account.currentBalance"]; drift == nil || drift.Difference != money(5) || drift.Expected != money(430) {
+		t.Fatalf("account drift not reported: %+v", drift)
+	}
+	if drift := drifts["budget.spentAmount"]; drift == nil || drift.Stored != money(25) || drift.Expected != money(40) {
+		t.Fatalf("budget drift not reported: %+v", drift)
+	}
+	if _, ok := drifts["budget.remainingAmount"]; ok {
+		t.Fatalf("remaining amount reported although the write kept it in step")
+	}
+	if drift := drifts["debt.totalPaid"]; drift == nil || drift.Stored != money(50) || drift.Expected != money(30) {
+		t.Fatalf("debt drift not reported: %+v", drift)
+	}
+	if _, ok := drifts["debt.remainingAmount"]; ok {
+		t.Fatalf("remaining amount reported although it matches the payments")
+	}
+	if report.RepairedCount != 0 || len(repo.ledgerRepairs) != 0 {
+		t.Fatalf("check without repair changed data: %+v", report)
+	}
+
+	repaired, err := service.CheckLedger(context.Background(), LedgerCheckOptions{UserID: "user-ledger", Repair: true, RepairedBy: "test"})
+	if err != nil {
+		t.Fatalf("repair ledger: %v", err)
+	}
+	if repaired.RepairedCount != repaired.DriftCount || len(repo.ledgerRepairs) != repaired.DriftCount {
+		t.Fatalf("repairs not recorded: %d of %d, %d audit rows", repaired.RepairedCount, repaired.DriftCount, len(repo.ledgerRepairs))
+	}
+	for _, drift := range repaired.Users[0].Drifts {
+		if drift.EntityType != LedgerEntityAccount {
+			continue
+		}
+		if drift.Repair.AdjustmentTransactionID == nil {
+			t.Fatalf("account repair posted no adjustment")
+		}
+		adjustment, err := repo.GetTransactionByID(ctx, *drift.Repair.AdjustmentTransactionID)
+		if err != nil || adjustment.Amount != money(5) || adjustment.Type != TransactionTypeSystemAdjustment {
+			t.Fatalf("unexpected adjustment: %+v, %v", adjustment, err)
+		}
+	}
+	after, _ := service.GetAccount(ctx, account.ID)
+	if after.CurrentBalance != money(435) {
+		t.Fatalf("repair moved the stored balance: %s", after.CurrentBalance)
+	}
+
+	clean, err := service.CheckLedger(context.Background(), LedgerCheckOptions{UserID: "user-ledger"})
+	if err != nil {
+		t.Fatalf("recheck ledger: %v", err)
+	}
+	if clean.DriftCount != 0 {
+		t.Fatalf("drift left after repair: %+v", clean.Users[0].Drifts)
+	}
+}
+
+func TestNetWorthReplaysAccountsAndDebtsAtHistoricalRates(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-net-worth")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	for _, rate := range []*FXRate{
+		{Date: "2026-01-01", FromCurrency: "USD", ToCurrency: "UZS", Rate: 12000},
+		{Date: "2026-03-01", FromCurrency: "USD", ToCurrency: "UZS", Rate: 12500},
+	} {
+		if _, err := service.CreateFXRate(ctx, rate); err != nil {
+			t.Fatalf("create rate: %v", err)
+		}
+	}
+	newAccount := func(name, currency string, initial float64) *Account {
+		account, _, err := service.CreateAccount(ctx, &Account{
+			Name:           name,
+			AccountType:    "cash",
+			Currency:       currency,
+			InitialBalance: money(initial),
+			CurrentBalance: money(initial),
+			ShowStatus:     "active",
+		})
+		if err != nil {
+			t.Fatalf("create account %s: %v", name, err)
+		}
+		return account
+	}
+	post := func(account *Account, txnType string, amount float64, date string) {
+		if _, err := service.CreateTransaction(ctx, &Transaction{
+			Type:      txnType,
+			AccountID: &account.ID,
+			Amount:    money(amount),
+			Currency:  account.Currency,
+			Date:      date,
+		}); err != nil {
+			t.Fatalf("create %s: %v", txnType, err)
+		}
+	}
+	card := newAccount("Card", "USD", 100)
+	cash := newAccount("Cash", "UZS", 1_000_000)
+	post(cash, TransactionTypeExpense, 100_000, "2026-01-15")
+	post(card, TransactionTypeIncome, 50, "2026-02-10")
+	post(card, TransactionTypeExpense, 120, "2026-03-05")
+	for _, debt := range []*Debt{
+		{CounterpartyName: "Friend", Direction: "they_owe_me", PrincipalAmount: money(1_000_000), PrincipalCurrency: "UZS", BaseCurrency: "UZS", StartDate: "2026-02-01", ShowStatus: "active"},
+		{CounterpartyName: "Bank", Direction: "i_owe", PrincipalAmount: money(10), PrincipalCurrency: "USD", BaseCurrency: "USD", StartDate: "2026-03-01", ShowStatus: "active"},
+	} {
+		if _, err := service.CreateDebt(ctx, debt); err != nil {
+			t.Fatalf("create debt: %v", err)
+		}
+	}
+
+	series, err := service.NetWorth(ctx, NetWorthQuery{From: "2026-01-01", To: "2026-03-31", Granularity: "monthly", BaseCurrency: "uzs"})
+	if err != nil {
+		t.Fatalf("net worth: %v", err)
+	}
+	if series.BaseCurrency != "UZS" || len(series.Points) != 3 {
+		t.Fatalf("unexpected series: %+v", series)
+	}
+	for i, want := range []struct {
+		date                               string
+		accounts                           int
+		assets, liabilities, netWorth, pay Money
+	}{
+		// Card has no entries before February, so it is not yet counted.
+		{"2026-01-31", 1, money(900_000), 0, money(900_000), 0},
+		{"2026-02-28", 2, money(3_700_000), 0, money(3_700_000), 0},
+		// Card's 30 USD and the 10 USD loan convert at the March rate.
+		{"2026-03-31", 2, money(2_275_000), money(125_000), money(2_150_000), money(125_000)},
+	} {
+		point := series.Points[i]
+		if point.Date != want.date || len(point.Accounts) != want.accounts || point.Assets != want.assets ||
+			point.Liabilities != want.liabilities || point.NetWorth != want.netWorth || point.Payables != want.pay {
+			t.Fatalf("point %d mismatch: %+v", i, point)
+		}
+		if point.NetWorth != point.AccountsTotal+point.Receivables-point.Payables {
+			t.Fatalf("point %d does not add up: %+v", i, point)
+		}
+	}
+
+	buckets, _ := netWorthBuckets(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 14, 0, 0, 0, 0, time.UTC), NetWorthWeekly)
+	if len(buckets) != 3 || buckets[0].end != "2026-01-04" || buckets[1].start != "2026-01-05" || buckets[2].end != "2026-01-14" {
+		t.Fatalf("weekly buckets must follow ISO weeks and clip to the range: %+v", buckets)
+	}
+	if _, err := service.NetWorth(ctx, NetWorthQuery{From: "2024-01-01", To: "2026-03-31", Granularity: "daily"}); err == nil || err.(*appErrors.Error).Code != appErrors.InvalidFinanceData.Code {
+		t.Fatalf("expected too many daily points to be rejected, got %v", err)
+	}
+}
+
+func TestScheduledTransactionsWaitForTheirDateAndPostWhenDue(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-scheduled")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	account, _, err := service.CreateAccount(ctx, &Account{
+		Name:           "Card",
+		AccountType:    "card",
+		Currency:       "USD",
+		InitialBalance: money(100),
+		CurrentBalance: money(100),
+		ShowStatus:     "active",
+	})
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	now := time.Now().UTC()
+	schedule := func(txnType string, amount float64, days int) *Transaction {
+		txn, err := service.CreateTransaction(ctx, &Transaction{
+			Type:      txnType,
+			AccountID: &account.ID,
+			Amount:    money(amount),
+			Currency:  "USD",
+			Date:      now.AddDate(0, 0, days).Format("2006-01-02"),
+		})
+		if err != nil {
+			t.Fatalf("create %s: %v", txnType, err)
+		}
+		return txn
+	}
+	rent := schedule(TransactionTypeExpense, 30, 1)
+	if !rent.IsScheduled || rent.Status != TransactionStatusPending {
+		t.Fatalf("expected a future expense to be scheduled and pending, got scheduled=%v status=%s", rent.IsScheduled, rent.Status)
+	}
+	// Funds are only checked when the entry posts.
+	laptop := schedule(TransactionTypeExpense, 500, 2)
+	bonus := schedule(TransactionTypeIncome, 20, 3)
+	if _, err := service.SkipScheduledTransaction(ctx, bonus.ID); err != nil {
+		t.Fatalf("skip: %v", err)
+	}
+
+	current, err := service.GetAccount(ctx, account.ID)
+	if err != nil {
+		t.Fatalf("get account: %v", err)
+	}
+	if current.CurrentBalance != money(100) || current.ProjectedBalance != money(-430) {
+		t.Fatalf("expected balance 100 and projected -430, got %s and %s", current.CurrentBalance, current.ProjectedBalance)
+	}
+	summary, err := service.FinanceSummary(ctx, "", "", "USD", nil)
+	if err != nil {
+		t.Fatalf("summary: %v", err)
+	}
+	if summary.Totals.Expense != 0 {
+		t.Fatalf("expected scheduled entries out of the totals, got expense=%s", summary.Totals.Expense)
+	}
+	for _, item := range summary.RecentTransactions {
+		if item.ID == rent.ID || item.ID == laptop.ID {
+			t.Fatalf("expected scheduled entries out of recent, got %+v", item)
+		}
+	}
+	if len(summary.Upcoming) != 2 || summary.Upcoming[0].ID != rent.ID || summary.Upcoming[1].ID != laptop.ID {
+		t.Fatalf("expected rent then laptop upcoming, got %+v", summary.Upcoming)
+	}
+	if _, err := service.ReverseTransaction(ctx, rent.ID, TransactionReversalInput{}); err == nil {
+		t.Fatalf("expected a scheduled entry not to be reversible")
+	}
+
+	posted, err := service.PostDueScheduledTransactions(context.Background(), now.AddDate(0, 0, 2))
+	if err != nil || posted != 1 {
+		t.Fatalf("expected one entry posted, got %d: %v", posted, err)
+	}
+	rent, _ = service.GetTransaction(ctx, rent.ID)
+	if rent.IsScheduled || rent.Status != TransactionStatusCompleted {
+		t.Fatalf("expected rent posted, got scheduled=%v status=%s", rent.IsScheduled, rent.Status)
+	}
+	laptop, _ = service.GetTransaction(ctx, laptop.ID)
+	if !laptop.IsScheduled || laptop.Status != TransactionStatusFailed {
+		t.Fatalf("expected laptop to fail for lack of funds, got scheduled=%v status=%s", laptop.IsScheduled, laptop.Status)
+	}
+	current, _ = service.GetAccount(ctx, account.ID)
+	if current.CurrentBalance != money(70) {
+		t.Fatalf("expected balance 70 after posting rent, got %s", current.CurrentBalance)
+	}
+
+	if _, err := service.RescheduleTransaction(ctx, laptop.ID, now.Format("2006-01-02")); err == nil {
+		t.Fatalf("expected rescheduling to today to be rejected")
+	}
+	rescheduled, err := service.RescheduleTransaction(ctx, laptop.ID, now.AddDate(0, 1, 0).Format("2006-01-02"))
+	if err != nil || rescheduled.Status != TransactionStatusPending {
+		t.Fatalf("expected laptop back to pending, got %v", err)
+	}
+	if _, err := service.ConfirmScheduledTransaction(ctx, laptop.ID); err != appErrors.InsufficientFunds {
+		t.Fatalf("expected confirm to need funds, got %v", err)
+	}
+	if _, err := service.SkipScheduledTransaction(ctx, laptop.ID); err != nil {
+		t.Fatalf("skip laptop: %v", err)
+	}
+	if _, err := service.ConfirmScheduledTransaction(ctx, laptop.ID); err != appErrors.TransactionNotScheduled {
+		t.Fatalf("expected a skipped entry not to be confirmable, got %v", err)
+	}
+
+	report, err := service.CheckLedger(ctx, LedgerCheckOptions{UserID: "user-scheduled"})
+	if err != nil || report.DriftCount != 0 {
+		t.Fatalf("expected a clean ledger, got %+v: %v", report, err)
+	}
+}
+
+func TestCreditCardStatementsCloseCyclesAndSettleFromTransfers(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-credit")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	checking, _, err := service.CreateAccount(ctx, &Account{
+		Name:           "Checking",
+		AccountType:    "bank",
+		Currency:       "USD",
+		InitialBalance: money(1000),
+		ShowStatus:     "active",
+	})
+	if err != nil {
+		t.Fatalf("create checking: %v", err)
+	}
+	if _, _, err := service.CreateAccount(ctx, &Account{
+		Name:        "Bad card",
+		AccountType: AccountTypeCredit,
+		Currency:    "USD",
+		Credit:      &CreditTerms{CreditLimit: money(1000), StatementClosingDay: 32, PaymentDueDay: 25},
+	}); err == nil {
+		t.Fatalf("expected a closing day past 31 to be rejected")
+	}
+	card, _, err := service.CreateAccount(ctx, &Account{
+		Name:        "Card",
+		AccountType: AccountTypeCredit,
+		Currency:    "USD",
+		ShowStatus:  "active",
+		Credit: &CreditTerms{
+			CreditLimit:           money(1000),
+			StatementClosingDay:   5,
+			PaymentDueDay:         25,
+			MinimumPaymentPercent: 5,
+			MinimumPaymentAmount:  money(20),
+		},
+	})
+	if err != nil {
+		t.Fatalf("create card: %v", err)
+	}
+	spend := func(amount float64, date string) error {
+		_, err := service.CreateTransaction(ctx, &Transaction{
+			Type:      TransactionTypeExpense,
+			AccountID: &card.ID,
+			Amount:    money(amount),
+			Currency:  "USD",
+			Date:      date,
+		})
+		return err
+	}
+	if err := spend(300, "2026-01-10"); err != nil {
+		t.Fatalf("spend: %v", err)
+	}
+	if err := spend(200, "2026-01-20"); err != nil {
+		t.Fatalf("spend: %v", err)
+	}
+	if err := spend(600, "2026-01-21"); err == nil {
+		t.Fatalf("expected spending past the credit limit to be rejected")
+	}
+	current, err := service.GetAccount(ctx, card.ID)
+	if err != nil {
+		t.Fatalf("get card: %v", err)
+	}
+	if current.CurrentBalance != money(-500) || current.Credit.AvailableCredit != money(500) {
+		t.Fatalf("expected balance -500 and 500 available, got %s and %s", current.CurrentBalance, current.Credit.AvailableCredit)
+	}
+
+	asOf := time.Date(2026, time.March, 1, 6, 0, 0, 0, time.UTC)
+	generated, err := service.GenerateCreditStatements(ctx, asOf)
+	if err != nil || generated != 1 {
+		t.Fatalf("expected one statement, got %d (err %v)", generated, err)
+	}
+	if again, _ := service.GenerateCreditStatements(ctx, asOf); again != 0 {
+		t.Fatalf("expected a closed cycle to be generated once, got %d more", again)
+	}
+	statements, err := service.CreditStatements(ctx, card.ID)
+	if err != nil || len(statements) != 1 {
+		t.Fatalf("expected one statement, got %d (err %v)", len(statements), err)
+	}
+	statement := statements[0]
+	if statement.PeriodStart != "2026-01-10" || statement.PeriodEnd != "2026-02-05" || statement.DueDate != "2026-02-25" {
+		t.Fatalf("unexpected cycle %s..%s due %s", statement.PeriodStart, statement.PeriodEnd, statement.DueDate)
+	}
+	if statement.Charges != money(500) || statement.StatementBalance != money(500) || statement.MinimumPayment != money(25) {
+		t.Fatalf("expected charges 500, balance 500 and minimum 25, got %s, %s and %s",
+			statement.Charges, statement.StatementBalance, statement.MinimumPayment)
+	}
+	if statement.Status != CreditStatementOverdue {
+		t.Fatalf("expected an unpaid statement past its due date to be overdue, got %s", statement.Status)
+	}
+
+	if _, err := service.CreateTransaction(ctx, &Transaction{
+		Type:      TransactionTypeIncome,
+		AccountID: &card.ID,
+		Amount:    money(50),
+		Currency:  "USD",
+		Date:      "2026-02-08",
+	}); err != nil {
+		t.Fatalf("refund: %v", err)
+	}
+	statements, _ = service.CreditStatements(ctx, card.ID)
+	if statements[0].PaidAmount != 0 || statements[0].RemainingAmount != money(500) {
+		t.Fatalf("expected a refund not to count as a payment, got %s paid", statements[0].PaidAmount)
+	}
+
+	partial := money(100)
+	payment, err := service.PayCreditStatement(ctx, card.ID, statement.ID, CreditPaymentInput{
+		FromAccountID: checking.ID,
+		Amount:        &partial,
+		Date:          "2026-02-10",
+	})
+	if err != nil {
+		t.Fatalf("pay minimum: %v", err)
+	}
+	if payment.Statement.Status != CreditStatementMinimumPaid || payment.Statement.RemainingAmount != money(400) {
+		t.Fatalf("expected minimum_paid with 400 left, got %s with %s", payment.Statement.Status, payment.Statement.RemainingAmount)
+	}
+	payment, err = service.PayCreditStatement(ctx, card.ID, statement.ID, CreditPaymentInput{
+		FromAccountID: checking.ID,
+		Date:          "2026-02-12",
+	})
+	if err != nil {
+		t.Fatalf("pay remaining: %v", err)
+	}
+	if payment.Transaction.Amount != money(400) || payment.Statement.Status != CreditStatementPaid {
+		t.Fatalf("expected the remaining 400 to settle the statement, got %s and %s", payment.Transaction.Amount, payment.Statement.Status)
+	}
+	if _, err := service.PayCreditStatement(ctx, card.ID, "missing", CreditPaymentInput{FromAccountID: checking.ID}); err != appErrors.CreditStatementNotFound {
+		t.Fatalf("expected CreditStatementNotFound, got %v", err)
+	}
+	current, err = service.GetAccount(ctx, card.ID)
+	if err != nil {
+		t.Fatalf("get card: %v", err)
+	}
+	if current.CurrentBalance != money(50) || current.Credit.AvailableCredit != money(1050) {
+		t.Fatalf("expected a settled card holding the refund, got %s and %s", current.CurrentBalance, current.Credit.AvailableCredit)
+	}
+}
+
+func TestMultiCurrencyAccountRoutesByCurrencyAndExchangesInside(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-wallet")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	wallet, _, err := service.CreateAccount(ctx, &Account{
+		Name:           "Wallet",
+		AccountType:    "cash",
+		Currency:       "USD",
+		InitialBalance: money(100),
+		ShowStatus:     "active",
+		Balances:       []AccountBalance{{Currency: "uzs", InitialBalance: money(1_200_000)}},
+	})
+	if err != nil {
+		t.Fatalf("create wallet: %v", err)
+	}
+	if len(wallet.Balances) != 2 || wallet.Balances[1].Currency != "UZS" || wallet.Balances[1].CurrentBalance != money(1_200_000) {
+		t.Fatalf("unexpected wallet balances: %+v", wallet.Balances)
+	}
+	uzsID := wallet.Balances[1].AccountID
+	if _, err := service.AddAccountCurrency(ctx, wallet.ID, AccountBalance{Currency: "UZS"}); err == nil {
+		t.Fatalf("expected a currency the wallet already holds to be rejected")
+	}
+
+	if _, err := service.CreateTransaction(ctx, &Transaction{
+		Type:      TransactionTypeExpense,
+		AccountID: &wallet.ID,
+		Amount:    money(240_000),
+		Currency:  "UZS",
+		Date:      "2026-01-05",
+	}); err != nil {
+		t.Fatalf("spend uzs: %v", err)
+	}
+	toAmount := money(600_000)
+	exchange, err := service.ExchangeCurrency(ctx, wallet.ID, CurrencyExchangeInput{
+		FromCurrency: "USD",
+		ToCurrency:   "UZS",
+		Amount:       money(50),
+		ToAmount:     &toAmount,
+		Date:         "2026-01-06",
+	})
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	if exchange.Account.CurrentBalance != money(50) || exchange.Account.Balances[1].CurrentBalance != money(1_560_000) {
+		t.Fatalf("unexpected balances after exchange: %+v", exchange.Account.Balances)
+	}
+	if _, err := service.ExchangeCurrency(ctx, wallet.ID, CurrencyExchangeInput{
+		FromCurrency: "USD",
+		ToCurrency:   "EUR",
+		Amount:       money(10),
+		ToAmount:     &toAmount,
+	}); err == nil {
+		t.Fatalf("expected an exchange into a currency the wallet does not hold to be rejected")
+	}
+
+	page, err := service.AccountTransactions(ctx, wallet.ID, TransactionPageRequest{})
+	if err != nil {
+		t.Fatalf("account transactions: %v", err)
+	}
+	onSub := false
+	for _, txn := range page.Items {
+		if txn.AccountID != nil && *txn.AccountID == uzsID {
+			onSub = true
+		}
+	}
+	if !onSub {
+		t.Fatalf("expected the uzs expense among the wallet's transactions")
+	}
+
+	if _, err := service.CreateFXRate(ctx, &FXRate{Date: "2026-01-01", FromCurrency: "USD", ToCurrency: "UZS", Rate: 12_000}); err != nil {
+		t.Fatalf("create fx rate: %v", err)
+	}
+	summary, err := service.FinanceSummary(ctx, "", "", "USD", nil)
+	if err != nil {
+		t.Fatalf("summary: %v", err)
+	}
+	if len(summary.Accounts) != 1 {
+		t.Fatalf("expected the wallet once in the summary, got %d accounts", len(summary.Accounts))
+	}
+	entry := summary.Accounts[0]
+	if len(entry.Balances) != 2 || entry.Balances[1].Balance != money(1_560_000) || entry.Balances[1].BalanceBase != money(130) {
+		t.Fatalf("unexpected summary balances: %+v", entry.Balances)
+	}
+	if entry.BalanceBase != money(180) {
+		t.Fatalf("summary wallet total mismatch: got %s, want 180.00", entry.BalanceBase)
+	}
+}
+
+func TestMergeAccountMovesEverythingAndConvertsCurrency(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-merge")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	create := func(name, currency string, initial float64) *Account {
+		account, _, err := service.CreateAccount(ctx, &Account{
+			Name:           name,
+			AccountType:    "cash",
+			Currency:       currency,
+			InitialBalance: money(initial),
+			ShowStatus:     "active",
+		})
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		return account
+	}
+	target := create("Cash", "USD", 100)
+	source := create("Cash (imported)", "UZS", 1_200_000)
+	card := create("Card", "UZS", 600_000)
+
+	budget, err := service.CreateBudget(ctx, &Budget{
+		Name:        "Cash spending",
+		AccountID:   &source.ID,
+		LimitAmount: money(1_000_000),
+		Currency:    "UZS",
+		PeriodType:  "none",
+	})
+	if err != nil {
+		t.Fatalf("create budget: %v", err)
+	}
+	if _, err := service.CreateTransaction(ctx, &Transaction{
+		Type:      TransactionTypeExpense,
+		AccountID: &source.ID,
+		BudgetID:  &budget.ID,
+		Amount:    money(240_000),
+		Currency:  "UZS",
+		Date:      "2026-01-05",
+	}); err != nil {
+		t.Fatalf("spend: %v", err)
+	}
+	if _, err := service.CreateTransaction(ctx, &Transaction{
+		Type:          TransactionTypeTransfer,
+		FromAccountID: &card.ID,
+		ToAccountID:   &source.ID,
+		Amount:        money(120_000),
+		Currency:      "UZS",
+		Date:          "2026-01-06",
+	}); err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	debt, err := service.CreateDebt(ctx, &Debt{
+		CounterpartyName:  "Friend",
+		Direction:         "they_owe",
+		PrincipalAmount:   money(50),
+		PrincipalCurrency: "USD",
+		BaseCurrency:      "USD",
+		ReturnToAccountID: &source.ID,
+		ShowStatus:        "active",
+	})
+	if err != nil {
+		t.Fatalf("create debt: %v", err)
+	}
+
+	if _, err := service.MergeAccount(ctx, source.ID, source.ID); err == nil {
+		t.Fatalf("expected merging an account into itself to be rejected")
+	}
+	if _, err := service.MergeAccount(ctx, source.ID, target.ID); err != appErrors.FXRateNotFound {
+		t.Fatalf("expected a missing rate to stop the merge, got %v", err)
+	}
+	if _, err := service.CreateFXRate(ctx, &FXRate{Date: "2020-01-01", FromCurrency: "USD", ToCurrency: "UZS", Rate: 12_000}); err != nil {
+		t.Fatalf("create fx rate: %v", err)
+	}
+	result, err := service.MergeAccount(ctx, source.ID, target.ID)
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if result.Merge.SourceBalance != money(1_080_000) || result.Merge.ConvertedBalance != money(90) {
+		t.Fatalf("unexpected merge record: %+v", result.Merge)
+	}
+	if result.Account.InitialBalance != money(200) || result.Account.CurrentBalance != money(190) {
+		t.Fatalf("target balance mismatch: initial %s current %s, want 200.00 and 190.00",
+			result.Account.InitialBalance, result.Account.CurrentBalance)
+	}
+	if result.Merge.BudgetsMoved != 1 || result.Merge.DebtsMoved != 1 {
+		t.Fatalf("expected the budget and the debt to move, got %+v", result.Merge)
+	}
+
+	archived, err := service.GetAccount(ctx, source.ID)
+	if err != nil {
+		t.Fatalf("get source: %v", err)
+	}
+	if !archived.IsArchived || archived.CurrentBalance != 0 {
+		t.Fatalf("expected the source archived and empty, got %+v", archived)
+	}
+	movedBudget, err := service.GetBudget(ctx, budget.ID)
+	if err != nil {
+		t.Fatalf("get budget: %v", err)
+	}
+	if movedBudget.AccountID == nil || *movedBudget.AccountID != target.ID || movedBudget.SpentAmount != money(240_000) {
+		t.Fatalf("unexpected budget after merge: account %v spent %s", movedBudget.AccountID, movedBudget.SpentAmount)
+	}
+	movedDebt, err := service.GetDebt(ctx, debt.ID)
+	if err != nil {
+		t.Fatalf("get debt: %v", err)
+	}
+	if movedDebt.ReturnToAccountID == nil || *movedDebt.ReturnToAccountID != target.ID {
+		t.Fatalf("expected the debt to return to the target, got %v", movedDebt.ReturnToAccountID)
+	}
+	cardAfter, err := service.GetAccount(ctx, card.ID)
+	if err != nil {
+		t.Fatalf("get card: %v", err)
+	}
+	if cardAfter.CurrentBalance != money(480_000) {
+		t.Fatalf("card balance mismatch: got %s, want 480000.00", cardAfter.CurrentBalance)
+	}
+
+	report, err := service.CheckLedger(ctx, LedgerCheckOptions{UserID: "user-merge"})
+	if err != nil {
+		t.Fatalf("check ledger: %v", err)
+	}
+	if report.DriftCount != 0 {
+		t.Fatalf("expected no drift after the merge, got %+v", report.Users)
+	}
+	if _, err := service.MergeAccount(ctx, card.ID, source.ID); err == nil {
+		t.Fatalf("expected merging into an archived account to be rejected")
+	}
+}
+
+func TestMergeAccountRefusesReconciledSource(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user_id", "user-merge-reconciled")
+	repo := NewInMemoryRepository()
+	service := NewService(repo, nil)
+
+	create := func(name string) *Account {
+		account, _, err := service.CreateAccount(ctx, &Account{
+			Name:           name,
+			AccountType:    "bank",
+			Currency:       "USD",
+			InitialBalance: money(100),
+			ShowStatus:     "active",
+		})
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		return account
+	}
+	target := create("Checking")
+	source := create("Checking (imported)")
+	rent, err := service.CreateTransaction(ctx, &Transaction{
+		Type:      TransactionTypeExpense,
+		AccountID: &source.ID,
+		Amount:    money(40),
+		Currency:  "USD",
+		Date:      "2026-03-02",
+	})
+	if err != nil {
+		t.Fatalf("spend: %v", err)
+	}
+	statementBalance := money(60)
+	reconciliation, err := service.StartReconciliation(ctx, source.ID, ReconciliationInput{
+		StatementDate:    "2026-03-10",
+		StatementBalance: &statementBalance,
+	})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if _, err := service.SelectReconciliationTransactions(ctx, reconciliation.ID, ReconciliationSelection{Cleared: []string{rent.ID}}); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if _, err := service.CompleteReconciliation(ctx, reconciliation.ID); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	_, err = service.MergeAccount(ctx, source.ID, target.ID)
+	if typed, ok := err.(*appErrors.Error); !ok || typed.Code != appErrors.AccountMergeBlocked.Code || typed.Details["reason"] != "reconciliations" {
+		t.Fatalf("expected merging a reconciled account to be refused, got %v", err)
+	}
+	stored, err := repo.GetTransactionByID(ctx, rent.ID)
+	if err != nil {
+		t.Fatalf("get transaction: %v", err)
+	}
+	if stored.AccountID == nil || *stored.AccountID != source.ID {
+		t.Fatalf("expected the reconciled entry to stay on the source, got %v", stored.AccountID)
+	}
+	kept, err := service.GetAccount(ctx, source.ID)
+	if err != nil {
+		t.Fatalf("get source: %v", err)
+	}
+	if kept.IsArchived || kept.CurrentBalance != money(60) {
+		t.Fatalf("expected the source untouched, got %+v", kept)
+	}
+}
@@ -0,0 +1,341 @@
+package finance
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strings"
+	"time"
+
+	appErrors "github.com/leora/leora-server/internal/errors"
+)
+
+const (
+	SMSStatusPosted    = "posted"
+	SMSStatusUnparsed  = "unparsed"
+	SMSStatusUnmatched = "unmatched"
+	SMSStatusFailed    = "failed"
+)
+
+// smsReferenceType marks transactions created from a bank notification;
+// their ReferenceID is the message id.
+const smsReferenceType = "sms"
+
+// maxSMSBatch bounds one forwarded batch from the mobile app.
+const maxSMSBatch = 200
+
+// SMSCardLink maps a card's last four digits to the account it pays from.
+// Bank is optional and only needed when two cards share the same digits.
+type SMSCardLink struct {
+	ID        string `json:"id"`
+	UserID    string `json:"userId"`
+	AccountID string `json:"accountId"`
+	CardMask  string `json:"cardMask"`
+	Bank      string `json:"bank,omitempty"`
+	CreatedAt string `json:"createdAt,omitempty"`
+	UpdatedAt string `json:"updatedAt,omitempty"`
+}
+
+// SMSMessage is a received bank notification. Every message is kept, parsed
+// or not: unparsed ones are the material for new templates, unmatched ones
+// post once their card is linked.
+type SMSMessage struct {
+	ID            string  `json:"id"`
+	UserID        string  `json:"userId"`
+	Sender        string  `json:"sender,omitempty"`
+	Body          string  `json:"body"`
+	ReceivedAt    string  `json:"receivedAt"`
+	Fingerprint   string  `json:"-"`
+	Status        string  `json:"status"`
+	Bank          string  `json:"bank,omitempty"`
+	Direction     string  `json:"direction,omitempty"`
+	Amount        Money   `json:"amount"`
+	Currency      string  `json:"currency,omitempty"`
+	Merchant      string  `json:"merchant,omitempty"`
+	CardMask      string  `json:"cardMask,omitempty"`
+	Balance       *Money  `json:"balance,omitempty"`
+	OccurredAt    string  `json:"occurredAt,omitempty"`
+	AccountID     *string `json:"accountId,omitempty"`
+	TransactionID *string `json:"transactionId,omitempty"`
+	Error         *string `json:"error,omitempty"`
+	CreatedAt     string  `json:"createdAt,omitempty"`
+	UpdatedAt     string  `json:"updatedAt,omitempty"`
+}
+
+// SMSInput is one message as received on the phone.
+type SMSInput struct {
+	Sender     string `json:"sender"`
+	Text       string `json:"text"`
+	ReceivedAt string `json:"receivedAt"`
+}
+
+// SetSMSTemplates replaces the bank notification templates. Without a call
+// the DefaultSMSTemplates are used.
+func (s *Service) SetSMSTemplates(templates ...SMSTemplate) {
+	s.smsTemplates = templates
+}
+
+func (s *Service) smsTemplateList() []SMSTemplate {
+	if s.smsTemplates == nil {
+		return DefaultSMSTemplates()
+	}
+	return s.smsTemplates
+}
+
+// SMSTemplateBanks lists the banks whose notifications can be parsed.
+func (s *Service) SMSTemplateBanks() []string {
+	banks := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, template := range s.smsTemplateList() {
+		if !seen[template.Bank()] {
+			seen[template.Bank()] = true
+			banks = append(banks, template.Bank())
+		}
+	}
+	return banks
+}
+
+// IngestSMSMessages stores and processes bank notifications. A message that
+// was already received is returned as stored, so the app can safely resend a
+// batch.
+func (s *Service) IngestSMSMessages(ctx context.Context, inputs []SMSInput) ([]*SMSMessage, error) {
+	if len(inputs) == 0 || len(inputs) > maxSMSBatch {
+		return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "messages"})
+	}
+	for _, input := range inputs {
+		if strings.TrimSpace(input.Text) == "" {
+			return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "text"})
+		}
+	}
+	links, err := s.repo.ListSMSCardLinks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*SMSMessage, 0, len(inputs))
+	for _, input := range inputs {
+		fingerprint := smsFingerprint(input.Sender, input.Text)
+		existing, err := s.repo.GetSMSMessageByFingerprint(ctx, fingerprint)
+		if err == nil {
+			results = append(results, existing)
+			continue
+		}
+		if err != appErrors.SMSMessageNotFound {
+			return nil, err
+		}
+
+		receivedAt := time.Now().UTC()
+		if parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(input.ReceivedAt)); err == nil {
+			receivedAt = parsed.UTC()
+		}
+		message := &SMSMessage{
+			Sender:      strings.TrimSpace(input.Sender),
+			Body:        strings.TrimSpace(input.Text),
+			ReceivedAt:  receivedAt.Format(time.RFC3339),
+			Fingerprint: fingerprint,
+			Status:      SMSStatusUnparsed,
+		}
+		if err := s.repo.CreateSMSMessage(ctx, message); err != nil {
+			return nil, err
+		}
+		if err := s.processSMSMessage(ctx, message, links); err != nil {
+			return nil, err
+		}
+		results = append(results, message)
+	}
+	return results, nil
+}
+
+func (s *Service) SMSMessages(ctx context.Context, status string) ([]*SMSMessage, error) {
+	switch status {
+	case "", SMSStatusPosted, SMSStatusUnparsed, SMSStatusUnmatched, SMSStatusFailed:
+	default:
+		return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "status"})
+	}
+	return s.repo.ListSMSMessages(ctx, status)
+}
+
+// ReprocessSMSMessage runs a message that did not post through the current
+// templates and card links again. Posted messages are returned unchanged.
+func (s *Service) ReprocessSMSMessage(ctx context.Context, id string) (*SMSMessage, error) {
+	message, err := s.repo.GetSMSMessageByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if message.Status == SMSStatusPosted {
+		return message, nil
+	}
+	links, err := s.repo.ListSMSCardLinks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.processSMSMessage(ctx, message, links); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (s *Service) SMSCardLinks(ctx context.Context) ([]*SMSCardLink, error) {
+	return s.repo.ListSMSCardLinks(ctx)
+}
+
+// CreateSMSCardLink links a card to an account and posts the messages that
+// were waiting for it.
+func (s *Service) CreateSMSCardLink(ctx context.Context, link *SMSCardLink) (*SMSCardLink, error) {
+	link.CardMask = smsCardMask(link.CardMask)
+	if link.CardMask == "" {
+		return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "cardMask"})
+	}
+	link.Bank = strings.ToLower(strings.TrimSpace(link.Bank))
+	if _, err := s.repo.GetAccountByID(ctx, link.AccountID); err != nil {
+		return nil, err
+	}
+	links, err := s.repo.ListSMSCardLinks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, existing := range links {
+		if existing.CardMask == link.CardMask && existing.Bank == link.Bank {
+			return nil, appErrors.SMSCardLinkExists
+		}
+	}
+	if err := s.repo.CreateSMSCardLink(ctx, link); err != nil {
+		return nil, err
+	}
+
+	waiting, err := s.repo.ListSMSMessages(ctx, SMSStatusUnmatched)
+	if err != nil {
+		return nil, err
+	}
+	links = append(links, link)
+	for _, message := range waiting {
+		if message.CardMask != link.CardMask {
+			continue
+		}
+		if err := s.processSMSMessage(ctx, message, links); err != nil {
+			log.Printf("[Service.CreateSMSCardLink] message=%s: %v", message.ID, err)
+		}
+	}
+	return link, nil
+}
+
+func (s *Service) DeleteSMSCardLink(ctx context.Context, id string) error {
+	return s.repo.DeleteSMSCardLink(ctx, id)
+}
+
+// processSMSMessage parses the message and, when its card is linked, records
+// a pending transaction on the linked account. The outcome is saved on the
+// message; only storage errors are returned.
+func (s *Service) processSMSMessage(ctx context.Context, message *SMSMessage, links []*SMSCardLink) error {
+	message.Error = nil
+	parsed, ok := parseSMS(s.smsTemplateList(), message.Sender, message.Body)
+	if !ok {
+		message.Status = SMSStatusUnparsed
+		return s.repo.UpdateSMSMessage(ctx, message)
+	}
+	message.Bank = parsed.Bank
+	message.Direction = parsed.Direction
+	message.Amount = parsed.Amount
+	message.Currency = parsed.Currency
+	message.Merchant = parsed.Merchant
+	message.CardMask = parsed.CardMask
+	message.Balance = parsed.Balance
+	occurredAt := parsed.OccurredAt
+	if occurredAt.IsZero() {
+		occurredAt, _ = time.Parse(time.RFC3339, message.ReceivedAt)
+	}
+	message.OccurredAt = occurredAt.UTC().Format(time.RFC3339)
+
+	link := findSMSCardLink(links, parsed.CardMask, parsed.Bank)
+	if link == nil {
+		message.Status = SMSStatusUnmatched
+		message.AccountID = nil
+		return s.repo.UpdateSMSMessage(ctx, message)
+	}
+	accountID := link.AccountID
+	message.AccountID = &accountID
+
+	created, err := s.CreateTransaction(ctx, smsTransaction(message, occurredAt))
+	if err != nil {
+		text := err.Error()
+		message.Status = SMSStatusFailed
+		message.Error = &text
+		log.Printf("[Service.processSMSMessage] message=%s: %v", message.ID, err)
+		return s.repo.UpdateSMSMessage(ctx, message)
+	}
+	message.Status = SMSStatusPosted
+	message.TransactionID = &created.ID
+	return s.repo.UpdateSMSMessage(ctx, message)
+}
+
+// findSMSCardLink prefers a link made for the message's bank over one that
+// matches any bank.
+func findSMSCardLink(links []*SMSCardLink, cardMask, bank string) *SMSCardLink {
+	var fallback *SMSCardLink
+	for _, link := range links {
+		if link.CardMask != cardMask {
+			continue
+		}
+		if link.Bank == bank {
+			return link
+		}
+		if link.Bank == "" {
+			fallback = link
+		}
+	}
+	return fallback
+}
+
+func smsTransaction(message *SMSMessage, occurredAt time.Time) *Transaction {
+	txnType := TransactionTypeExpense
+	if message.Direction == SMSDirectionCredit {
+		txnType = TransactionTypeIncome
+	}
+	referenceType := smsReferenceType
+	referenceID := message.ID
+	local := occurredAt.In(smsLocation)
+	clock := local.Format("15:04")
+	// Held back like a scheduled entry: it moves no balance until the user
+	// confirms it, and skipping it voids it. The due-post job leaves it alone.
+	txn := &Transaction{
+		Type:          txnType,
+		Status:        TransactionStatusPending,
+		IsScheduled:   true,
+		AccountID:     message.AccountID,
+		ReferenceType: &referenceType,
+		ReferenceID:   &referenceID,
+		Amount:        message.Amount,
+		Currency:      message.Currency,
+		Date:          local.Format("2006-01-02"),
+		Time:          &clock,
+		OccurredAt:    occurredAt.UTC().Format(time.RFC3339),
+		Metadata: map[string]interface{}{
+			"smsBank":  message.Bank,
+			"cardMask": message.CardMask,
+			"merchant": message.Merchant,
+		},
+	}
+	if message.Merchant != "" {
+		merchant := message.Merchant
+		txn.Name = &merchant
+	}
+	if message.Balance != nil {
+		txn.Metadata["reportedBalance"] = message.Balance.String()
+	}
+	return txn
+}
+
+// isSMSTransaction reports whether txn was recorded from a bank SMS.
+func isSMSTransaction(txn *Transaction) bool {
+	return txn.ReferenceType != nil && *txn.ReferenceType == smsReferenceType
+}
+
+// smsFingerprint identifies a notification by sender and text. Bank messages
+// carry their own timestamp and running balance, so identical text means the
+// same message forwarded twice.
+func smsFingerprint(sender, text string) string {
+	normalized := strings.ToLower(strings.TrimSpace(sender)) + "|" + strings.Join(strings.Fields(text), " ")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
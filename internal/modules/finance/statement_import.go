@@ -0,0 +1,589 @@
+package finance
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	appErrors "github.com/leora/leora-server/internal/errors"
+)
+
+const (
+	StatementImportStatusReview    = "review"
+	StatementImportStatusCompleted = "completed"
+	StatementImportStatusUndone    = "undone"
+)
+
+const (
+	StatementRowPending   = "pending"
+	StatementRowDuplicate = "duplicate"
+	StatementRowApproved  = "approved"
+	StatementRowRejected  = "rejected"
+	StatementRowUndone    = "undone"
+)
+
+// statementImportReferenceType marks transactions created from an import
+// row; their ReferenceID is the row id.
+const statementImportReferenceType = "statement_import"
+
+// maxStatementFileBytes bounds uploaded statement files.
+const maxStatementFileBytes = 4 << 20
+
+// StatementImportProfile is a saved CSV column mapping, usually one per bank.
+type StatementImportProfile struct {
+	ID        string              `json:"id"`
+	UserID    string              `json:"userId"`
+	Name      string              `json:"name"`
+	Mapping   StatementCSVMapping `json:"mapping"`
+	CreatedAt string              `json:"createdAt,omitempty"`
+	UpdatedAt string              `json:"updatedAt,omitempty"`
+}
+
+// StatementImport is one uploaded statement file staged for review against
+// an account.
+type StatementImport struct {
+	ID             string                `json:"id"`
+	UserID         string                `json:"userId"`
+	AccountID      string                `json:"accountId"`
+	Format         string                `json:"format"`
+	FileName       string                `json:"fileName"`
+	ProfileID      *string               `json:"profileId,omitempty"`
+	Status         string                `json:"status"`
+	RowCount       int                   `json:"rowCount"`
+	PendingCount   int                   `json:"pendingCount"`
+	DuplicateCount int                   `json:"duplicateCount"`
+	ApprovedCount  int                   `json:"approvedCount"`
+	RejectedCount  int                   `json:"rejectedCount"`
+	UndoneAt       *string               `json:"undoneAt,omitempty"`
+	CreatedAt      string                `json:"createdAt,omitempty"`
+	UpdatedAt      string                `json:"updatedAt,omitempty"`
+	Rows           []*StatementImportRow `json:"rows,omitempty"`
+}
+
+// StatementImportRow is one staged statement line. Rows that look like an
+// existing transaction start as duplicates and are only posted when approved
+// explicitly.
+type StatementImportRow struct {
+	ID            string  `json:"id"`
+	ImportID      string  `json:"importId"`
+	UserID        string  `json:"userId"`
+	LineNumber    int     `json:"lineNumber"`
+	Date          string  `json:"date"`
+	Amount        Money   `json:"amount"`
+	Currency      string  `json:"currency"`
+	Description   string  `json:"description,omitempty"`
+	Payee         string  `json:"payee,omitempty"`
+	Reference     string  `json:"reference,omitempty"`
+	Fingerprint   string  `json:"fingerprint"`
+	Status        string  `json:"status"`
+	CategoryID    *string `json:"categoryId,omitempty"`
+	DuplicateOfID *string `json:"duplicateOfId,omitempty"`
+	TransactionID *string `json:"transactionId,omitempty"`
+	Error         *string `json:"error,omitempty"`
+	CreatedAt     string  `json:"createdAt,omitempty"`
+	UpdatedAt     string  `json:"updatedAt,omitempty"`
+}
+
+// StatementImportInput is an uploaded statement. Format is detected from the
+// file when empty. CSV files need a ProfileID or an inline Mapping.
+type StatementImportInput struct {
+	AccountID string
+	Format    string
+	FileName  string
+	Data      []byte
+	ProfileID string
+	Mapping   *StatementCSVMapping
+}
+
+// StatementImportDecision selects rows to approve or reject. An empty RowIDs
+// approves every pending row; duplicates are only included when listed.
+type StatementImportDecision struct {
+	RowIDs     []string `json:"rowIds"`
+	CategoryID *string  `json:"categoryId,omitempty"`
+}
+
+// ImportStatement parses a statement file and stages its lines for review,
+// flagging lines that match transactions already on the account.
+func (s *Service) ImportStatement(ctx context.Context, input StatementImportInput) (*StatementImport, error) {
+	account, err := s.repo.GetAccountByID(ctx, input.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if len(input.Data) == 0 || len(input.Data) > maxStatementFileBytes {
+		return nil, invalidStatement(input.Format, 0, "file is empty or too large")
+	}
+	format := strings.ToLower(strings.TrimSpace(input.Format))
+	if format == "" {
+		format = detectStatementFormat(input.FileName, input.Data)
+	}
+	mapping := input.Mapping
+	var profileID *string
+	if input.ProfileID != "" {
+		profile, err := s.repo.GetStatementImportProfileByID(ctx, input.ProfileID)
+		if err != nil {
+			return nil, err
+		}
+		mapping = &profile.Mapping
+		profileID = &profile.ID
+	}
+	lines, err := ParseStatement(format, input.Data, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.ListTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existing = filterTransactions(existing, TransactionFilter{AccountID: account.ID})
+
+	batch := &StatementImport{
+		AccountID: account.ID,
+		Format:    format,
+		FileName:  input.FileName,
+		ProfileID: profileID,
+		Status:    StatementImportStatusReview,
+	}
+	rules := s.activeCategorizationRules(ctx)
+	rows := make([]*StatementImportRow, 0, len(lines))
+	seen := make(map[string]bool, len(lines))
+	for _, line := range lines {
+		currency := line.Currency
+		if currency == "" {
+			currency = account.Currency
+		}
+		row := &StatementImportRow{
+			LineNumber:  line.LineNumber,
+			Date:        line.Date,
+			Amount:      line.Amount,
+			Currency:    currency,
+			Description: line.Description,
+			Payee:       line.Payee,
+			Reference:   line.Reference,
+			Fingerprint: statementFingerprint(line.Date, line.Amount, line.Payee+" "+line.Description),
+			Status:      StatementRowPending,
+		}
+		// Suggest a category in the inbox; approval runs the rules again.
+		draft := statementRowTransaction(batch, row)
+		applyCompiledRules(rules, draft)
+		row.CategoryID = draft.CategoryID
+		if duplicate := findStatementDuplicate(row, account.ID, existing); duplicate != nil {
+			row.Status = StatementRowDuplicate
+			row.DuplicateOfID = &duplicate.ID
+		}
+		// Repeated lines in one file are duplicates unless the bank gave them
+		// distinct references.
+		fileKey := row.Fingerprint + "|" + row.Reference
+		if seen[fileKey] {
+			row.Status = StatementRowDuplicate
+		}
+		seen[fileKey] = true
+		rows = append(rows, row)
+	}
+	refreshStatementImportCounts(batch, rows)
+	if err := s.repo.CreateStatementImport(ctx, batch, rows); err != nil {
+		return nil, err
+	}
+	batch.Rows = rows
+	return batch, nil
+}
+
+func (s *Service) StatementImports(ctx context.Context) ([]*StatementImport, error) {
+	return s.repo.ListStatementImports(ctx)
+}
+
+func (s *Service) GetStatementImport(ctx context.Context, id string) (*StatementImport, error) {
+	batch, err := s.repo.GetStatementImportByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.ListStatementImportRows(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	batch.Rows = rows
+	return batch, nil
+}
+
+// PatchStatementImportRow edits a row still awaiting review. Only categoryId,
+// payee and description may change.
+func (s *Service) PatchStatementImportRow(ctx context.Context, importID, rowID string, fields map[string]interface{}) (*StatementImportRow, error) {
+	batch, rows, err := s.loadStatementImport(ctx, importID)
+	if err != nil {
+		return nil, err
+	}
+	if batch.Status == StatementImportStatusUndone {
+		return nil, appErrors.StatementImportUndone
+	}
+	row := findStatementImportRow(rows, rowID)
+	if row == nil {
+		return nil, appErrors.StatementImportRowNotFound
+	}
+	if row.Status != StatementRowPending && row.Status != StatementRowDuplicate {
+		return nil, appErrors.TransactionImmutable
+	}
+	if value, ok := fields["categoryId"]; ok {
+		if value == nil {
+			row.CategoryID = nil
+		} else if categoryID, ok := value.(string); ok && categoryID != "" {
+			row.CategoryID = &categoryID
+		} else {
+			return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "categoryId"})
+		}
+	}
+	for key, target := range map[string]*string{"payee": &row.Payee, "description": &row.Description} {
+		if value, ok := fields[key]; ok {
+			text, ok := value.(string)
+			if !ok {
+				return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": key})
+			}
+			*target = strings.TrimSpace(text)
+		}
+	}
+	if err := s.repo.UpdateStatementImportRow(ctx, row); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// ApproveStatementImportRows posts the selected rows through CreateTransaction
+// in date order. A row that cannot be posted keeps its status and records the
+// error; the others still go through.
+func (s *Service) ApproveStatementImportRows(ctx context.Context, importID string, decision StatementImportDecision) (*StatementImport, error) {
+	batch, rows, err := s.loadStatementImport(ctx, importID)
+	if err != nil {
+		return nil, err
+	}
+	if batch.Status == StatementImportStatusUndone {
+		return nil, appErrors.StatementImportUndone
+	}
+	selected, err := selectStatementImportRows(rows, decision.RowIDs)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		if selected[i].Date != selected[j].Date {
+			return selected[i].Date < selected[j].Date
+		}
+		return selected[i].LineNumber < selected[j].LineNumber
+	})
+
+	for _, row := range selected {
+		if decision.CategoryID != nil && row.CategoryID == nil {
+			row.CategoryID = decision.CategoryID
+		}
+		created, err := s.CreateTransaction(ctx, statementRowTransaction(batch, row))
+		if err != nil {
+			message := err.Error()
+			row.Error = &message
+			log.Printf("[Service.ApproveStatementImportRows] row=%s: %v", row.ID, err)
+		} else {
+			row.Status = StatementRowApproved
+			row.TransactionID = &created.ID
+			row.Error = nil
+		}
+		if err := s.repo.UpdateStatementImportRow(ctx, row); err != nil {
+			return nil, err
+		}
+	}
+	return s.saveStatementImportCounts(ctx, batch, rows)
+}
+
+// RejectStatementImportRows drops the selected rows from the inbox. An empty
+// RowIDs rejects every pending row and duplicate.
+func (s *Service) RejectStatementImportRows(ctx context.Context, importID string, decision StatementImportDecision) (*StatementImport, error) {
+	batch, rows, err := s.loadStatementImport(ctx, importID)
+	if err != nil {
+		return nil, err
+	}
+	if batch.Status == StatementImportStatusUndone {
+		return nil, appErrors.StatementImportUndone
+	}
+	selected := make([]*StatementImportRow, 0)
+	if len(decision.RowIDs) == 0 {
+		for _, row := range rows {
+			if row.Status == StatementRowPending || row.Status == StatementRowDuplicate {
+				selected = append(selected, row)
+			}
+		}
+	} else if selected, err = selectStatementImportRows(rows, decision.RowIDs); err != nil {
+		return nil, err
+	}
+	for _, row := range selected {
+		row.Status = StatementRowRejected
+		if err := s.repo.UpdateStatementImportRow(ctx, row); err != nil {
+			return nil, err
+		}
+	}
+	return s.saveStatementImportCounts(ctx, batch, rows)
+}
+
+// UndoStatementImport reverses every transaction posted from the import and
+// closes the remaining rows. Posted entries are immutable, so undo books
+// reversals and leaves the usual audit trail; entries still scheduled are
+// voided instead. The batch is undone as a unit: the repository applies the
+// whole undo in one transaction and refuses it when any entry is locked by a
+// completed reconciliation, so a row that cannot be reversed leaves the
+// import and the ledger untouched.
+func (s *Service) UndoStatementImport(ctx context.Context, importID string) (*StatementImport, error) {
+	batch, rows, err := s.loadStatementImport(ctx, importID)
+	if err != nil {
+		return nil, err
+	}
+	if batch.Status == StatementImportStatusUndone {
+		return nil, appErrors.StatementImportUndone
+	}
+	for _, row := range rows {
+		switch row.Status {
+		case StatementRowApproved:
+			row.Status = StatementRowUndone
+		case StatementRowPending, StatementRowDuplicate:
+			row.Status = StatementRowRejected
+		}
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	batch.UndoneAt = &now
+	batch.Status = StatementImportStatusUndone
+	refreshStatementImportCounts(batch, rows)
+	note := "Statement import undone"
+	if err := s.repo.UndoStatementImport(ctx, batch, rows, TransactionReversalInput{Note: &note}); err != nil {
+		log.Printf("[Service.UndoStatementImport] Error for id=%s: %v", importID, err)
+		return nil, err
+	}
+	s.invalidateFinanceSummaryCache(ctx)
+	s.syncAllBudgetRollups(ctx)
+	batch.Rows = rows
+	return batch, nil
+}
+
+func (s *Service) StatementImportProfiles(ctx context.Context) ([]*StatementImportProfile, error) {
+	return s.repo.ListStatementImportProfiles(ctx)
+}
+
+func (s *Service) CreateStatementImportProfile(ctx context.Context, profile *StatementImportProfile) (*StatementImportProfile, error) {
+	profile.Name = strings.TrimSpace(profile.Name)
+	if profile.Name == "" {
+		return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "name"})
+	}
+	mapping := profile.Mapping
+	if mapping.DateColumn == "" || (mapping.AmountColumn == "" && mapping.DebitColumn == "" && mapping.CreditColumn == "") {
+		return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "mapping"})
+	}
+	if err := s.repo.CreateStatementImportProfile(ctx, profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (s *Service) DeleteStatementImportProfile(ctx context.Context, id string) error {
+	return s.repo.DeleteStatementImportProfile(ctx, id)
+}
+
+func (s *Service) loadStatementImport(ctx context.Context, id string) (*StatementImport, []*StatementImportRow, error) {
+	batch, err := s.repo.GetStatementImportByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := s.repo.ListStatementImportRows(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return batch, rows, nil
+}
+
+func (s *Service) saveStatementImportCounts(ctx context.Context, batch *StatementImport, rows []*StatementImportRow) (*StatementImport, error) {
+	refreshStatementImportCounts(batch, rows)
+	if err := s.repo.UpdateStatementImport(ctx, batch); err != nil {
+		return nil, err
+	}
+	batch.Rows = rows
+	return batch, nil
+}
+
+func findStatementImportRow(rows []*StatementImportRow, id string) *StatementImportRow {
+	for _, row := range rows {
+		if row.ID == id {
+			return row
+		}
+	}
+	return nil
+}
+
+// selectStatementImportRows returns the listed rows that are still open, or
+// every pending row when ids is empty.
+func selectStatementImportRows(rows []*StatementImportRow, ids []string) ([]*StatementImportRow, error) {
+	selected := make([]*StatementImportRow, 0)
+	if len(ids) == 0 {
+		for _, row := range rows {
+			if row.Status == StatementRowPending {
+				selected = append(selected, row)
+			}
+		}
+		return selected, nil
+	}
+	for _, id := range ids {
+		row := findStatementImportRow(rows, id)
+		if row == nil {
+			return nil, appErrors.StatementImportRowNotFound
+		}
+		if row.Status == StatementRowPending || row.Status == StatementRowDuplicate {
+			selected = append(selected, row)
+		}
+	}
+	return selected, nil
+}
+
+// refreshStatementImportCounts recomputes the per-status counts. The import
+// stays in review while any row is open.
+func refreshStatementImportCounts(batch *StatementImport, rows []*StatementImportRow) {
+	batch.RowCount = len(rows)
+	batch.PendingCount, batch.DuplicateCount, batch.ApprovedCount, batch.RejectedCount = 0, 0, 0, 0
+	for _, row := range rows {
+		switch row.Status {
+		case StatementRowPending:
+			batch.PendingCount++
+		case StatementRowDuplicate:
+			batch.DuplicateCount++
+		case StatementRowApproved:
+			batch.ApprovedCount++
+		case StatementRowRejected:
+			batch.RejectedCount++
+		}
+	}
+	if batch.Status == StatementImportStatusUndone {
+		return
+	}
+	batch.Status = StatementImportStatusReview
+	if batch.PendingCount == 0 && batch.DuplicateCount == 0 {
+		batch.Status = StatementImportStatusCompleted
+	}
+}
+
+func statementRowTransaction(batch *StatementImport, row *StatementImportRow) *Transaction {
+	txnType := TransactionTypeIncome
+	if row.Amount < 0 {
+		txnType = TransactionTypeExpense
+	}
+	name := firstNonEmpty(row.Payee, row.Description)
+	var description *string
+	if row.Payee != "" && row.Description != "" {
+		value := row.Description
+		description = &value
+	}
+	referenceType := statementImportReferenceType
+	referenceID := row.ID
+	accountID := batch.AccountID
+	txn := &Transaction{
+		Type:          txnType,
+		AccountID:     &accountID,
+		ReferenceType: &referenceType,
+		ReferenceID:   &referenceID,
+		Amount:        row.Amount.Abs(),
+		Currency:      row.Currency,
+		CategoryID:    row.CategoryID,
+		Description:   description,
+		Date:          row.Date,
+		Metadata: map[string]interface{}{
+			"importId":          batch.ID,
+			"importFingerprint": row.Fingerprint,
+		},
+	}
+	if name != "" {
+		txn.Name = &name
+	}
+	return txn
+}
+
+// statementFingerprint identifies a statement line by date, signed amount and
+// the set of words in its text, so reordered or re-spaced descriptions from
+// the same bank still match.
+func statementFingerprint(date string, amount Money, text string) string {
+	sum := sha256.Sum256([]byte(date + "|" + amount.String() + "|" + strings.Join(statementTextTokens(text), " ")))
+	return hex.EncodeToString(sum[:])
+}
+
+// statementTextTokens lowercases text and returns its distinct words of at
+// least three letters or digits, sorted.
+func statementTextTokens(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := make(map[string]bool, len(words))
+	tokens := make([]string, 0, len(words))
+	for _, word := range words {
+		if len([]rune(word)) < 3 || seen[word] {
+			continue
+		}
+		seen[word] = true
+		tokens = append(tokens, word)
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+// findStatementDuplicate returns the transaction on the same date with the
+// same signed amount whose text matches the row: the same import fingerprint,
+// a shared word, or no text on either side.
+func findStatementDuplicate(row *StatementImportRow, accountID string, transactions []*Transaction) *Transaction {
+	rowTokens := statementTextTokens(row.Payee + " " + row.Description)
+	for _, txn := range transactions {
+		if txn.Date != row.Date || statementSignedAmount(txn, accountID) != row.Amount {
+			continue
+		}
+		if fingerprint, ok := txn.Metadata["importFingerprint"].(string); ok && fingerprint == row.Fingerprint {
+			return txn
+		}
+		text := ""
+		if txn.Name != nil {
+			text = *txn.Name
+		}
+		if txn.Description != nil {
+			text += " " + *txn.Description
+		}
+		txnTokens := statementTextTokens(text)
+		if len(rowTokens) == 0 || len(txnTokens) == 0 || tokensOverlap(rowTokens, txnTokens) {
+			return txn
+		}
+	}
+	return nil
+}
+
+// statementSignedAmount is the transaction's effect on accountID in the sign
+// convention of StatementLine.
+func statementSignedAmount(txn *Transaction, accountID string) Money {
+	switch txn.Type {
+	case TransactionTypeIncome, TransactionTypeTransferIn:
+		return txn.Amount
+	case TransactionTypeExpense, TransactionTypeTransferOut:
+		return -txn.Amount
+	case TransactionTypeTransfer:
+		if txn.FromAccountID != nil && *txn.FromAccountID == accountID {
+			return -txn.Amount
+		}
+		if txn.ToAccountID != nil && *txn.ToAccountID == accountID {
+			if txn.ToAmount != 0 {
+				return txn.ToAmount
+			}
+			return txn.Amount
+		}
+	}
+	return 0
+}
+
+func tokensOverlap(a, b []string) bool {
+	set := make(map[string]bool, len(a))
+	for _, token := range a {
+		set[token] = true
+	}
+	for _, token := range b {
+		if set[token] {
+			return true
+		}
+	}
+	return false
+}
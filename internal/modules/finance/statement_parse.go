@@ -0,0 +1,595 @@
+package finance
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	appErrors "github.com/leora/leora-server/internal/errors"
+)
+
+const (
+	StatementFormatCSV     = "csv"
+	StatementFormatOFX     = "ofx"
+	StatementFormatQIF     = "qif"
+	StatementFormatCAMT053 = "camt053"
+)
+
+// StatementLine is one entry read from a bank statement. Amount is signed:
+// negative amounts left the account, positive ones arrived.
+type StatementLine struct {
+	LineNumber  int
+	Date        string
+	Amount      Money
+	Currency    string
+	Description string
+	Payee       string
+	Reference   string
+}
+
+// StatementCSVMapping describes how to read a bank's CSV export. Column
+// references are header names (matched case-insensitively) or, for files
+// without a header, 1-based column numbers. Amounts come either from one
+// signed AmountColumn or from separate DebitColumn and CreditColumn.
+type StatementCSVMapping struct {
+	Delimiter         string `json:"delimiter,omitempty"`
+	HasHeader         bool   `json:"hasHeader"`
+	SkipRows          int    `json:"skipRows,omitempty"`
+	DateColumn        string `json:"dateColumn"`
+	DateFormat        string `json:"dateFormat,omitempty"`
+	AmountColumn      string `json:"amountColumn,omitempty"`
+	DebitColumn       string `json:"debitColumn,omitempty"`
+	CreditColumn      string `json:"creditColumn,omitempty"`
+	DescriptionColumn string `json:"descriptionColumn,omitempty"`
+	PayeeColumn       string `json:"payeeColumn,omitempty"`
+	CurrencyColumn    string `json:"currencyColumn,omitempty"`
+	ReferenceColumn   string `json:"referenceColumn,omitempty"`
+	DecimalSeparator  string `json:"decimalSeparator,omitempty"`
+	// NegateAmounts flips signed amounts for exports that show spending as
+	// positive numbers.
+	NegateAmounts bool `json:"negateAmounts,omitempty"`
+}
+
+func invalidStatement(format string, line int, reason string) error {
+	details := map[string]interface{}{"format": format, "reason": reason}
+	if line > 0 {
+		details["line"] = line
+	}
+	return appErrors.WithDetails(appErrors.InvalidStatementFile, details)
+}
+
+// detectStatementFormat guesses the format from the file name, then from the
+// content.
+func detectStatementFormat(fileName string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".ofx", ".qfx":
+		return StatementFormatOFX
+	case ".qif":
+		return StatementFormatQIF
+	case ".csv":
+		return StatementFormatCSV
+	}
+	head := strings.TrimSpace(string(data[:min(len(data), 4096)]))
+	upper := strings.ToUpper(head)
+	switch {
+	case strings.HasPrefix(upper, "OFXHEADER") || strings.Contains(upper, "<OFX>"):
+		return StatementFormatOFX
+	case strings.HasPrefix(upper, "!TYPE") || strings.HasPrefix(upper, "!ACCOUNT"):
+		return StatementFormatQIF
+	case strings.Contains(head, "BkToCstmrStmt"):
+		return StatementFormatCAMT053
+	}
+	return StatementFormatCSV
+}
+
+// ParseStatement reads a statement file. mapping is required for CSV; for
+// QIF its DateFormat, when set, overrides the date layouts tried by default.
+func ParseStatement(format string, data []byte, mapping *StatementCSVMapping) ([]*StatementLine, error) {
+	var (
+		lines []*StatementLine
+		err   error
+	)
+	switch format {
+	case StatementFormatCSV:
+		if mapping == nil {
+			return nil, invalidStatement(format, 0, "a column mapping is required")
+		}
+		lines, err = parseStatementCSV(data, *mapping)
+	case StatementFormatOFX:
+		lines, err = parseStatementOFX(data)
+	case StatementFormatQIF:
+		dateFormat := ""
+		if mapping != nil {
+			dateFormat = mapping.DateFormat
+		}
+		lines, err = parseStatementQIF(data, dateFormat)
+	case StatementFormatCAMT053:
+		lines, err = parseStatementCAMT053(data)
+	default:
+		return nil, invalidStatement(format, 0, "unsupported format")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, invalidStatement(format, 0, "no transactions found")
+	}
+	return lines, nil
+}
+
+func parseStatementCSV(data []byte, mapping StatementCSVMapping) ([]*StatementLine, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if delimiter := []rune(mapping.Delimiter); len(delimiter) == 1 {
+		reader.Comma = delimiter[0]
+	} else if mapping.Delimiter == `\t` {
+		reader.Comma = '\t'
+	}
+	if mapping.DateColumn == "" || (mapping.AmountColumn == "" && mapping.DebitColumn == "" && mapping.CreditColumn == "") {
+		return nil, invalidStatement(StatementFormatCSV, 0, "dateColumn and an amount column are required")
+	}
+
+	var header []string
+	lines := make([]*StatementLine, 0)
+	for lineNumber := 1; ; lineNumber++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, invalidStatement(StatementFormatCSV, lineNumber, err.Error())
+		}
+		if lineNumber <= mapping.SkipRows {
+			continue
+		}
+		if mapping.HasHeader && header == nil {
+			header = record
+			continue
+		}
+		if csvRecordBlank(record) {
+			continue
+		}
+		column := func(ref string) (string, error) {
+			if ref == "" {
+				return "", nil
+			}
+			index, err := csvColumnIndex(ref, header)
+			if err != nil {
+				return "", err
+			}
+			if index >= len(record) {
+				return "", nil
+			}
+			return strings.TrimSpace(record[index]), nil
+		}
+
+		rawDate, err := column(mapping.DateColumn)
+		if err != nil {
+			return nil, invalidStatement(StatementFormatCSV, lineNumber, err.Error())
+		}
+		date, err := parseStatementDate(rawDate, mapping.DateFormat)
+		if err != nil {
+			return nil, invalidStatement(StatementFormatCSV, lineNumber, fmt.Sprintf("invalid date %q", rawDate))
+		}
+		line := &StatementLine{LineNumber: lineNumber, Date: date}
+		if mapping.AmountColumn != "" {
+			raw, err := column(mapping.AmountColumn)
+			if err != nil {
+				return nil, invalidStatement(StatementFormatCSV, lineNumber, err.Error())
+			}
+			if raw == "" {
+				continue
+			}
+			if line.Amount, err = parseStatementAmount(raw, mapping.DecimalSeparator); err != nil {
+				return nil, invalidStatement(StatementFormatCSV, lineNumber, fmt.Sprintf("invalid amount %q", raw))
+			}
+			if mapping.NegateAmounts {
+				line.Amount = -line.Amount
+			}
+		} else {
+			for _, side := range []struct {
+				ref  string
+				sign Money
+			}{{mapping.DebitColumn, -1}, {mapping.CreditColumn, 1}} {
+				raw, err := column(side.ref)
+				if err != nil {
+					return nil, invalidStatement(StatementFormatCSV, lineNumber, err.Error())
+				}
+				if raw == "" {
+					continue
+				}
+				value, err := parseStatementAmount(raw, mapping.DecimalSeparator)
+				if err != nil {
+					return nil, invalidStatement(StatementFormatCSV, lineNumber, fmt.Sprintf("invalid amount %q", raw))
+				}
+				line.Amount += side.sign * value.Abs()
+			}
+		}
+		if line.Amount == 0 {
+			continue
+		}
+		for _, field := range []struct {
+			ref    string
+			target *string
+		}{
+			{mapping.DescriptionColumn, &line.Description},
+			{mapping.PayeeColumn, &line.Payee},
+			{mapping.CurrencyColumn, &line.Currency},
+			{mapping.ReferenceColumn, &line.Reference},
+		} {
+			value, err := column(field.ref)
+			if err != nil {
+				return nil, invalidStatement(StatementFormatCSV, lineNumber, err.Error())
+			}
+			*field.target = value
+		}
+		line.Currency = strings.ToUpper(line.Currency)
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+func csvRecordBlank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func csvColumnIndex(ref string, header []string) (int, error) {
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(ref)) {
+			return i, nil
+		}
+	}
+	if index, err := strconv.Atoi(strings.TrimSpace(ref)); err == nil && index >= 1 {
+		return index - 1, nil
+	}
+	return 0, fmt.Errorf("unknown column %q", ref)
+}
+
+var ofxCurrencyPattern = regexp.MustCompile(`(?i)<CURDEF>\s*([A-Za-z]{3})`)
+
+// parseStatementOFX reads OFX 1.x (SGML, closing tags optional) and OFX 2.x
+// (XML) bank and card statements, including QFX.
+func parseStatementOFX(data []byte) ([]*StatementLine, error) {
+	text := string(data)
+	currency := ""
+	if match := ofxCurrencyPattern.FindStringSubmatch(text); match != nil {
+		currency = strings.ToUpper(match[1])
+	}
+	blocks := splitOFXTransactions(text)
+	lines := make([]*StatementLine, 0, len(blocks))
+	for i, block := range blocks {
+		number := i + 1
+		rawDate := ofxTag(block, "DTPOSTED")
+		if len(rawDate) < 8 {
+			return nil, invalidStatement(StatementFormatOFX, number, "missing DTPOSTED")
+		}
+		date, err := time.Parse("20060102", rawDate[:8])
+		if err != nil {
+			return nil, invalidStatement(StatementFormatOFX, number, fmt.Sprintf("invalid date %q", rawDate))
+		}
+		rawAmount := ofxTag(block, "TRNAMT")
+		separator := "."
+		if !strings.Contains(rawAmount, ".") {
+			separator = ","
+		}
+		amount, err := parseStatementAmount(rawAmount, separator)
+		if err != nil {
+			return nil, invalidStatement(StatementFormatOFX, number, fmt.Sprintf("invalid amount %q", rawAmount))
+		}
+		line := &StatementLine{
+			LineNumber:  number,
+			Date:        date.Format("2006-01-02"),
+			Amount:      amount,
+			Currency:    currency,
+			Payee:       ofxTag(block, "NAME"),
+			Description: ofxTag(block, "MEMO"),
+			Reference:   ofxTag(block, "FITID"),
+		}
+		if override := ofxTag(block, "CURSYM"); override != "" {
+			line.Currency = strings.ToUpper(override)
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+func splitOFXTransactions(text string) []string {
+	upper := strings.ToUpper(text)
+	blocks := make([]string, 0)
+	for {
+		start := strings.Index(upper, "<STMTTRN>")
+		if start < 0 {
+			return blocks
+		}
+		text, upper = text[start+len("<STMTTRN>"):], upper[start+len("<STMTTRN>"):]
+		end := len(upper)
+		for _, marker := range []string{"</STMTTRN>", "<STMTTRN>", "</BANKTRANLIST>"} {
+			if index := strings.Index(upper, marker); index >= 0 && index < end {
+				end = index
+			}
+		}
+		blocks = append(blocks, text[:end])
+		text, upper = text[end:], upper[end:]
+	}
+}
+
+// ofxTag returns the value of an OFX element, with or without a closing tag.
+func ofxTag(block, tag string) string {
+	upper := strings.ToUpper(block)
+	start := strings.Index(upper, "<"+tag+">")
+	if start < 0 {
+		return ""
+	}
+	value := block[start+len(tag)+2:]
+	if end := strings.IndexAny(value, "<\r\n"); end >= 0 {
+		value = value[:end]
+	}
+	return strings.TrimSpace(html.UnescapeString(value))
+}
+
+// qifDateLayouts are tried in order when no date format is given. QIF comes
+// from US software, so slashed dates are month first.
+var qifDateLayouts = []string{"01/02/2006", "1/2/2006", "01/02/06", "1/2/06", "02.01.2006", "2006-01-02"}
+
+func parseStatementQIF(data []byte, dateFormat string) ([]*StatementLine, error) {
+	lines := make([]*StatementLine, 0)
+	current := &StatementLine{}
+	started := false
+	lineNumber := 0
+	for _, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		lineNumber++
+		raw = strings.TrimSpace(raw)
+		if raw == "" || strings.HasPrefix(raw, "!") {
+			continue
+		}
+		code, value := raw[0], strings.TrimSpace(raw[1:])
+		switch code {
+		case '^':
+			if started {
+				if current.Date == "" {
+					return nil, invalidStatement(StatementFormatQIF, lineNumber, "record without a date")
+				}
+				if current.Amount != 0 {
+					lines = append(lines, current)
+				}
+			}
+			current = &StatementLine{}
+			started = false
+			continue
+		case 'D':
+			date, err := parseQIFDate(value, dateFormat)
+			if err != nil {
+				return nil, invalidStatement(StatementFormatQIF, lineNumber, fmt.Sprintf("invalid date %q", value))
+			}
+			current.Date = date
+		case 'T', 'U':
+			amount, err := parseStatementAmount(value, ".")
+			if err != nil {
+				return nil, invalidStatement(StatementFormatQIF, lineNumber, fmt.Sprintf("invalid amount %q", value))
+			}
+			current.Amount = amount
+		case 'P':
+			current.Payee = value
+		case 'M':
+			current.Description = value
+		case 'N':
+			current.Reference = value
+		default:
+			continue
+		}
+		if !started {
+			current.LineNumber = lineNumber
+			started = true
+		}
+	}
+	if started && current.Date != "" && current.Amount != 0 {
+		lines = append(lines, current)
+	}
+	return lines, nil
+}
+
+func parseQIFDate(value, dateFormat string) (string, error) {
+	// Quicken writes years after 1999 as 1/2'06.
+	value = strings.ReplaceAll(strings.TrimSpace(value), "'", "/")
+	value = strings.ReplaceAll(value, " ", "")
+	if dateFormat != "" {
+		return parseStatementDate(value, dateFormat)
+	}
+	for _, layout := range qifDateLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.Format("2006-01-02"), nil
+		}
+	}
+	return "", fmt.Errorf("invalid date %q", value)
+}
+
+type camtDocument struct {
+	Statements []struct {
+		Account struct {
+			Currency string `xml:"Ccy"`
+		} `xml:"Acct"`
+		Entries []camtEntry `xml:"Ntry"`
+	} `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtEntry struct {
+	Amount struct {
+		Value    string `xml:",chardata"`
+		Currency string `xml:"Ccy,attr"`
+	} `xml:"Amt"`
+	Indicator      string   `xml:"CdtDbtInd"`
+	BookingDate    camtDate `xml:"BookgDt"`
+	ValueDate      camtDate `xml:"ValDt"`
+	Reference      string   `xml:"NtryRef"`
+	ServicerRef    string   `xml:"AcctSvcrRef"`
+	AdditionalInfo string   `xml:"AddtlNtryInf"`
+	Details        []struct {
+		EndToEndID    string   `xml:"Refs>EndToEndId"`
+		Remittance    []string `xml:"RmtInf>Ustrd"`
+		Creditor      string   `xml:"RltdPties>Cdtr>Nm"`
+		CreditorParty string   `xml:"RltdPties>Cdtr>Pty>Nm"`
+		Debtor        string   `xml:"RltdPties>Dbtr>Nm"`
+		DebtorParty   string   `xml:"RltdPties>Dbtr>Pty>Nm"`
+	} `xml:"NtryDtls>TxDtls"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+func (d camtDate) value() string {
+	if d.Date != "" {
+		return strings.TrimSpace(d.Date)
+	}
+	if len(strings.TrimSpace(d.DateTime)) >= 10 {
+		return strings.TrimSpace(d.DateTime)[:10]
+	}
+	return ""
+}
+
+// parseStatementCAMT053 reads ISO 20022 camt.053 bank-to-customer
+// statements. Entries are read regardless of the message version.
+func parseStatementCAMT053(data []byte) ([]*StatementLine, error) {
+	var document camtDocument
+	if err := xml.Unmarshal(data, &document); err != nil {
+		return nil, invalidStatement(StatementFormatCAMT053, 0, err.Error())
+	}
+	lines := make([]*StatementLine, 0)
+	number := 0
+	for _, statement := range document.Statements {
+		for _, entry := range statement.Entries {
+			number++
+			date := entry.BookingDate.value()
+			if date == "" {
+				date = entry.ValueDate.value()
+			}
+			if _, err := time.Parse("2006-01-02", date); err != nil {
+				return nil, invalidStatement(StatementFormatCAMT053, number, fmt.Sprintf("invalid date %q", date))
+			}
+			amount, err := parseStatementAmount(entry.Amount.Value, ".")
+			if err != nil {
+				return nil, invalidStatement(StatementFormatCAMT053, number, fmt.Sprintf("invalid amount %q", entry.Amount.Value))
+			}
+			amount = amount.Abs()
+			debit := strings.EqualFold(strings.TrimSpace(entry.Indicator), "DBIT")
+			if debit {
+				amount = -amount
+			}
+			currency := entry.Amount.Currency
+			if currency == "" {
+				currency = statement.Account.Currency
+			}
+			line := &StatementLine{
+				LineNumber:  number,
+				Date:        date,
+				Amount:      amount,
+				Currency:    strings.ToUpper(strings.TrimSpace(currency)),
+				Description: strings.TrimSpace(entry.AdditionalInfo),
+				Reference:   firstNonEmpty(entry.ServicerRef, entry.Reference),
+			}
+			for _, details := range entry.Details {
+				if len(details.Remittance) > 0 {
+					line.Description = strings.TrimSpace(strings.Join(details.Remittance, " "))
+				}
+				// The counterparty is whoever was on the other side of the entry.
+				if debit {
+					line.Payee = firstNonEmpty(details.Creditor, details.CreditorParty)
+				} else {
+					line.Payee = firstNonEmpty(details.Debtor, details.DebtorParty)
+				}
+				if line.Reference == "" {
+					line.Reference = strings.TrimSpace(details.EndToEndID)
+				}
+				break
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
+}
+
+// parseStatementAmount reads amounts as banks print them: grouped thousands,
+// either decimal separator, currency codes, "(12.50)" and "12.50-".
+func parseStatementAmount(raw, decimalSeparator string) (Money, error) {
+	value := strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9', r == '.', r == ',', r == '-', r == '+', r == '(', r == ')':
+			return r
+		}
+		return -1
+	}, raw)
+	negative := false
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative = true
+		value = strings.Trim(value, "()")
+	}
+	if strings.HasSuffix(value, "-") {
+		negative = true
+		value = strings.TrimSuffix(value, "-")
+	}
+	if decimalSeparator == "," {
+		value = strings.ReplaceAll(value, ".", "")
+		value = strings.ReplaceAll(value, ",", ".")
+	} else {
+		value = strings.ReplaceAll(value, ",", "")
+	}
+	amount, err := ParseMoney(value)
+	if err != nil {
+		return 0, err
+	}
+	if negative {
+		amount = -amount.Abs()
+	}
+	return amount, nil
+}
+
+// parseStatementDate parses value with format, given either as a Go layout
+// or with YYYY, YY, MM and DD placeholders. Without a format the common
+// ISO and day-first layouts are tried.
+func parseStatementDate(value, format string) (string, error) {
+	value = strings.TrimSpace(value)
+	layouts := []string{"2006-01-02", "02.01.2006", "2006/01/02", "02/01/2006", "2006-01-02T15:04:05", "02.01.2006 15:04"}
+	if format != "" {
+		layout := format
+		if !strings.Contains(format, "2006") && !strings.Contains(format, "06") {
+			layout = strings.NewReplacer("YYYY", "2006", "YY", "06", "MM", "01", "DD", "02").Replace(format)
+		}
+		layouts = []string{layout}
+	}
+	for _, layout := range layouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.Format("2006-01-02"), nil
+		}
+		// Exports often append a time the layout does not cover.
+		if len(value) > len(layout) {
+			if parsed, err := time.Parse(layout, value[:len(layout)]); err == nil {
+				return parsed.Format("2006-01-02"), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("invalid date %q", value)
+}
@@ -0,0 +1,76 @@
+package finance
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+)
+
+const (
+	// thumbnailMaxSide bounds the longer side of a generated thumbnail.
+	thumbnailMaxSide = 320
+	// maxThumbnailSourcePixels skips decoding images that would take too
+	// much memory; they are stored without a thumbnail.
+	maxThumbnailSourcePixels = 40_000_000
+	// thumbnailSamples is the sampling grid per thumbnail pixel.
+	thumbnailSamples = 4
+)
+
+// makeThumbnail scales an image down to fit thumbnailMaxSide and encodes it
+// as JPEG over a white background. It also returns the source size. ok is
+// false for formats the standard library cannot decode.
+func makeThumbnail(data []byte) (thumb []byte, width, height int, ok bool) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width <= 0 || config.Height <= 0 {
+		return nil, 0, 0, false
+	}
+	if config.Width*config.Height > maxThumbnailSourcePixels {
+		return nil, config.Width, config.Height, false
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, config.Width, config.Height, false
+	}
+
+	bounds := src.Bounds()
+	dstW, dstH := bounds.Dx(), bounds.Dy()
+	if dstW > thumbnailMaxSide || dstH > thumbnailMaxSide {
+		if dstW >= dstH {
+			dstH = max(1, dstH*thumbnailMaxSide/dstW)
+			dstW = thumbnailMaxSide
+		} else {
+			dstW = max(1, dstW*thumbnailMaxSide/dstH)
+			dstH = thumbnailMaxSide
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		for x := 0; x < dstW; x++ {
+			var r, g, b, a, n uint64
+			for sy := 0; sy < thumbnailSamples; sy++ {
+				srcY := bounds.Min.Y + (y*thumbnailSamples+sy)*bounds.Dy()/(dstH*thumbnailSamples)
+				for sx := 0; sx < thumbnailSamples; sx++ {
+					srcX := bounds.Min.X + (x*thumbnailSamples+sx)*bounds.Dx()/(dstW*thumbnailSamples)
+					cr, cg, cb, ca := src.At(srcX, srcY).RGBA()
+					r, g, b, a, n = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			// Colours are premultiplied; composite over white.
+			white := (0xffff*n - a)
+			offset := (y*dstW + x) * 4
+			dst.Pix[offset] = uint8((r + white) / n >> 8)
+			dst.Pix[offset+1] = uint8((g + white) / n >> 8)
+			dst.Pix[offset+2] = uint8((b + white) / n >> 8)
+			dst.Pix[offset+3] = 0xff
+		}
+	}
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, config.Width, config.Height, false
+	}
+	return out.Bytes(), config.Width, config.Height, true
+}
@@ -0,0 +1,134 @@
+package finance
+
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/leora/leora-server/internal/common/utils"
+	appErrors "github.com/leora/leora-server/internal/errors"
+)
+
+// defaultTransactionPageLimit matches utils.ParsePaginationParams.
+const defaultTransactionPageLimit = 20
+
+// TransactionCursor marks the last entry of a page. Transaction lists are
+// ordered by the sort key, then created_at, then id, all in SortOrder, so the
+// cursor is stable while new entries are posted. Key is the entry's sort
+// column: its date, amount or created_at.
+type TransactionCursor struct {
+	SortBy    string
+	SortOrder string
+	Key       string
+	CreatedAt string
+	ID        string
+}
+
+// TransactionPageRequest selects one page of a transaction list. Page is the
+// older offset paging and is only used when Cursor is nil.
+type TransactionPageRequest struct {
+	Limit  int
+	Cursor *TransactionCursor
+	Page   int
+}
+
+// TransactionPage is one page of a transaction list. Total is only counted
+// for offset paging.
+type TransactionPage struct {
+	Items      []*Transaction
+	Limit      int
+	Page       int
+	Total      int
+	NextCursor string
+	HasMore    bool
+}
+
+func (cursor TransactionCursor) Encode() string {
+	raw := strings.Join([]string{cursor.SortBy, cursor.SortOrder, cursor.Key, cursor.CreatedAt, cursor.ID}, "|")
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeTransactionCursor parses a cursor returned as meta.nextCursor.
+func DecodeTransactionCursor(raw string) (*TransactionCursor, error) {
+	invalid := appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "cursor"})
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, invalid
+	}
+	parts := strings.Split(string(decoded), "|")
+	if len(parts) != 5 || parts[4] == "" {
+		return nil, invalid
+	}
+	cursor := &TransactionCursor{SortBy: parts[0], SortOrder: parts[1], Key: parts[2], CreatedAt: parts[3], ID: parts[4]}
+	if cursor.SortOrder != TransactionSortAsc && cursor.SortOrder != TransactionSortDesc {
+		return nil, invalid
+	}
+	switch cursor.SortBy {
+	case TransactionSortByDate:
+		_, err = time.Parse("2006-01-02", cursor.Key)
+	case TransactionSortByAmount:
+		_, err = ParseMoney(cursor.Key)
+	case TransactionSortByCreatedAt:
+		_, err = time.Parse(time.RFC3339Nano, cursor.Key)
+	default:
+		return nil, invalid
+	}
+	if err != nil {
+		return nil, invalid
+	}
+	if _, err := time.Parse(time.RFC3339Nano, cursor.CreatedAt); err != nil {
+		return nil, invalid
+	}
+	return cursor, nil
+}
+
+// transactionCursorFor builds the cursor of txn under the filter's sort,
+// which must already be normalised.
+func transactionCursorFor(txn *Transaction, filter TransactionFilter) TransactionCursor {
+	cursor := TransactionCursor{SortBy: filter.SortBy, SortOrder: filter.SortOrder, CreatedAt: txn.CreatedAt, ID: txn.ID}
+	switch filter.SortBy {
+	case TransactionSortByAmount:
+		cursor.Key = transactionFilterAmount(txn, filter.AmountBasis).String()
+	case TransactionSortByCreatedAt:
+		cursor.Key = txn.CreatedAt
+	default:
+		cursor.Key = txn.Date
+	}
+	return cursor
+}
+
+// transactionFeedBefore reports whether a sorts before b in list order. Both
+// cursors must come from the same sort.
+func transactionFeedBefore(a, b TransactionCursor) bool {
+	cmp := compareTransactionSortKey(a.SortBy, a.Key, b.Key)
+	if cmp == 0 {
+		createdA, createdB := utils.ParseRFC3339(a.CreatedAt), utils.ParseRFC3339(b.CreatedAt)
+		cmp = createdA.Compare(createdB)
+	}
+	if cmp == 0 {
+		cmp = strings.Compare(a.ID, b.ID)
+	}
+	if a.SortOrder == TransactionSortAsc {
+		return cmp < 0
+	}
+	return cmp > 0
+}
+
+func compareTransactionSortKey(sortBy, a, b string) int {
+	switch sortBy {
+	case TransactionSortByAmount:
+		left, _ := ParseMoney(a)
+		right, _ := ParseMoney(b)
+		switch {
+		case left < right:
+			return -1
+		case left > right:
+			return 1
+		}
+		return 0
+	case TransactionSortByCreatedAt:
+		return utils.ParseRFC3339(a).Compare(utils.ParseRFC3339(b))
+	default:
+		return strings.Compare(a, b)
+	}
+}
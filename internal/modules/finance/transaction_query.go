@@ -0,0 +1,137 @@
+package finance
+
+import (
+	"strings"
+
+	appErrors "github.com/leora/leora-server/internal/errors"
+)
+
+const (
+	TransactionSortByDate      = "date"
+	TransactionSortByAmount    = "amount"
+	TransactionSortByCreatedAt = "createdAt"
+
+	TransactionSortAsc  = "asc"
+	TransactionSortDesc = "desc"
+)
+
+const (
+	TransactionAmountBasisOriginal = "original"
+	TransactionAmountBasisBase     = "base"
+)
+
+// normalizeTransactionFilter validates the query options of filter and fills
+// in the defaults: newest date first, amounts in the original currency.
+func normalizeTransactionFilter(filter *TransactionFilter) error {
+	invalid := func(field string) error {
+		return appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": field})
+	}
+
+	switch filter.SortBy {
+	case "":
+		filter.SortBy = TransactionSortByDate
+	case TransactionSortByDate, TransactionSortByAmount, TransactionSortByCreatedAt:
+	default:
+		return invalid("sortBy")
+	}
+	switch strings.ToLower(filter.SortOrder) {
+	case "", TransactionSortDesc:
+		filter.SortOrder = TransactionSortDesc
+	case TransactionSortAsc:
+		filter.SortOrder = TransactionSortAsc
+	default:
+		return invalid("sortOrder")
+	}
+	switch filter.AmountBasis {
+	case "":
+		filter.AmountBasis = TransactionAmountBasisOriginal
+	case TransactionAmountBasisOriginal, TransactionAmountBasisBase:
+	default:
+		return invalid("amountBasis")
+	}
+	if filter.AmountMin != nil && filter.AmountMax != nil && *filter.AmountMin > *filter.AmountMax {
+		return invalid("amountMax")
+	}
+	switch filter.Status {
+	case "", TransactionStatusPending, TransactionStatusCompleted, TransactionStatusFailed, TransactionStatusVoided:
+	default:
+		return invalid("status")
+	}
+	filter.TagsAny = normalizeFilterTags(filter.TagsAny)
+	filter.TagsAll = normalizeFilterTags(filter.TagsAll)
+	filter.Search = strings.TrimSpace(filter.Search)
+	return nil
+}
+
+func normalizeFilterTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if trimmed := strings.TrimSpace(tag); trimmed != "" {
+			normalized = append(normalized, trimmed)
+		}
+	}
+	return normalized
+}
+
+// transactionFilterAmount is the amount the filter's range and amount sort
+// apply to.
+func transactionFilterAmount(txn *Transaction, basis string) Money {
+	if basis == TransactionAmountBasisBase {
+		return txn.ConvertedAmountToBase
+	}
+	return txn.Amount
+}
+
+// transactionHasTags reports whether txn carries any (or, with all, every)
+// one of tags.
+func transactionHasTags(txn *Transaction, tags []string, all bool) bool {
+	carried := make(map[string]bool, len(txn.Tags))
+	for _, tag := range txn.Tags {
+		carried[tag] = true
+	}
+	for _, tag := range tags {
+		if carried[tag] != all {
+			return !all
+		}
+	}
+	return all
+}
+
+func transactionMatchesSearch(txn *Transaction, search string) bool {
+	needle := strings.ToLower(strings.TrimSpace(search))
+	if needle == "" {
+		return true
+	}
+	if txn.Name != nil && strings.Contains(strings.ToLower(*txn.Name), needle) {
+		return true
+	}
+	return txn.Description != nil && strings.Contains(strings.ToLower(*txn.Description), needle)
+}
+
+// transactionSortColumn is the SQL column matching the filter's sort and the
+// type its cursor key is cast to.
+func transactionSortColumn(filter TransactionFilter) (string, string) {
+	switch filter.SortBy {
+	case TransactionSortByAmount:
+		return transactionAmountColumn(filter.AmountBasis), "numeric"
+	case TransactionSortByCreatedAt:
+		return "created_at", "timestamptz"
+	default:
+		return "date", "date"
+	}
+}
+
+func transactionAmountColumn(basis string) string {
+	if basis == TransactionAmountBasisBase {
+		return "converted_amount_to_base"
+	}
+	return "amount"
+}
+
+// escapeLikePattern escapes LIKE wildcards so search text matches literally.
+func escapeLikePattern(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
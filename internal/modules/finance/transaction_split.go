@@ -0,0 +1,171 @@
+package finance
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+
+	"github.com/google/uuid"
+	appErrors "github.com/leora/leora-server/internal/errors"
+)
+
+// validateTransactionSplits checks the split lines of an income or expense
+// and moves the classification onto them: the parent's category, subcategory
+// and budget are cleared so nothing is counted twice.
+func (s *Service) validateTransactionSplits(ctx context.Context, txn *Transaction) error {
+	if len(txn.Splits) == 0 {
+		txn.Splits = nil
+		return nil
+	}
+	invalid := func(field string) error {
+		return appErrors.WithDetails(appErrors.InvalidTransactionSplit, map[string]interface{}{"field": field})
+	}
+	if txn.Type != TransactionTypeIncome && txn.Type != TransactionTypeExpense {
+		return invalid("type")
+	}
+	if len(txn.Splits) < 2 {
+		return invalid("splits")
+	}
+	total := Money(0)
+	budgets := make(map[string]bool)
+	for i := range txn.Splits {
+		line := &txn.Splits[i]
+		if line.Amount <= 0 {
+			return invalid("splits.amount")
+		}
+		total += line.Amount
+		if line.ID == "" {
+			line.ID = uuid.NewString()
+		}
+		line.CategoryID = nonEmptyString(line.CategoryID)
+		line.SubcategoryID = nonEmptyString(line.SubcategoryID)
+		line.BudgetID = nonEmptyString(line.BudgetID)
+		if line.BudgetID != nil && !budgets[*line.BudgetID] {
+			if _, err := s.repo.GetBudgetByID(ctx, *line.BudgetID); err != nil {
+				return err
+			}
+			budgets[*line.BudgetID] = true
+		}
+	}
+	if total != txn.Amount {
+		return appErrors.WithDetails(appErrors.InvalidTransactionSplit, map[string]interface{}{
+			"field":  "splits",
+			"amount": txn.Amount.String(),
+			"total":  total.String(),
+		})
+	}
+	txn.CategoryID = nil
+	txn.SubcategoryID = nil
+	txn.BudgetID = nil
+	return nil
+}
+
+// transactionLines returns the split lines of txn, or a single line carrying
+// the parent's classification when it is not split.
+func transactionLines(txn *Transaction) []TransactionSplit {
+	if len(txn.Splits) > 0 {
+		return txn.Splits
+	}
+	return []TransactionSplit{{
+		Amount:        txn.Amount,
+		CategoryID:    txn.CategoryID,
+		SubcategoryID: txn.SubcategoryID,
+		BudgetID:      txn.BudgetID,
+	}}
+}
+
+// splitAmounts shares total, the parent amount in some other currency, across
+// the lines of txn in proportion to their amounts. The last line takes the
+// rounding remainder so the parts always add up to total.
+func splitAmounts(txn *Transaction, total Money) []Money {
+	lines := transactionLines(txn)
+	amounts := make([]Money, len(lines))
+	if len(lines) == 1 || txn.Amount == 0 {
+		amounts[0] = total
+		return amounts
+	}
+	allocated := Money(0)
+	for i, line := range lines {
+		if i == len(lines)-1 {
+			amounts[i] = total - allocated
+			break
+		}
+		share := new(big.Rat).SetFrac64(int64(line.Amount), int64(txn.Amount))
+		share.Mul(share, new(big.Rat).SetFrac64(int64(total), moneyScale))
+		amount, err := moneyFromRat(share, 1)
+		if err != nil {
+			amount = total.MulFloat(line.Amount.Ratio(txn.Amount))
+		}
+		amounts[i] = amount
+		allocated += amount
+	}
+	return amounts
+}
+
+// transactionBudgetIDs lists the budgets txn counts towards, once each.
+func transactionBudgetIDs(txn *Transaction) []string {
+	ids := make([]string, 0, 1)
+	seen := make(map[string]bool)
+	for _, line := range transactionLines(txn) {
+		if line.BudgetID == nil || *line.BudgetID == "" || seen[*line.BudgetID] {
+			continue
+		}
+		seen[*line.BudgetID] = true
+		ids = append(ids, *line.BudgetID)
+	}
+	return ids
+}
+
+// reversalBudgetIDs lists the budgets a reversal touched: those of the voided
+// entries and of the correction, if any.
+func reversalBudgetIDs(result *TransactionReversal) []string {
+	ids := make([]string, 0, 1)
+	seen := make(map[string]bool)
+	entries := append([]*Transaction{}, result.Voided...)
+	if result.Correction != nil {
+		entries = append(entries, result.Correction)
+	}
+	for _, txn := range entries {
+		for _, id := range transactionBudgetIDs(txn) {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// transactionHasLine reports whether any line of txn matches; an entry
+// without splits is its own single line.
+func transactionHasLine(txn *Transaction, match func(line TransactionSplit) bool) bool {
+	for _, line := range transactionLines(txn) {
+		if match(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeTransactionSplits reads split lines from a JSON patch value.
+func decodeTransactionSplits(value interface{}) ([]TransactionSplit, bool) {
+	if value == nil {
+		return nil, true
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	var splits []TransactionSplit
+	if err := json.Unmarshal(data, &splits); err != nil {
+		return nil, false
+	}
+	return splits, true
+}
+
+func nonEmptyString(value *string) *string {
+	if value == nil || *value == "" {
+		return nil
+	}
+	return value
+}
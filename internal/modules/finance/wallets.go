@@ -0,0 +1,369 @@
+package finance
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	appErrors "github.com/leora/leora-server/internal/errors"
+)
+
+// AccountBalance is one currency held by a multi-currency account. The
+// account's own currency is its own balance; every other currency lives in a
+// sub-balance account whose ParentAccountID is the account, and AccountID
+// names it. Entries made on the account in that currency land there.
+//
+// On create only Currency and InitialBalance are read.
+type AccountBalance struct {
+	AccountID        string `json:"accountId"`
+	Currency         string `json:"currency"`
+	InitialBalance   Money  `json:"initialBalance"`
+	CurrentBalance   Money  `json:"currentBalance"`
+	ProjectedBalance Money  `json:"projectedBalance"`
+}
+
+// CurrencyExchangeInput converts money between two currencies of the same
+// account. ToAmount is what arrives; without it Rate is applied, and without
+// a rate the FX rate for Date.
+type CurrencyExchangeInput struct {
+	FromCurrency string  `json:"fromCurrency"`
+	ToCurrency   string  `json:"toCurrency"`
+	Amount       Money   `json:"amount"`
+	ToAmount     *Money  `json:"toAmount"`
+	Rate         float64 `json:"rate"`
+	Date         string  `json:"date"`
+	Note         *string `json:"note"`
+}
+
+type CurrencyExchange struct {
+	Account     *Account     `json:"account"`
+	Transaction *Transaction `json:"transaction"`
+}
+
+// walletID is the multi-currency account an account belongs to: the parent
+// of a sub-balance, the account itself otherwise.
+func walletID(account *Account) string {
+	if account.ParentAccountID != nil && *account.ParentAccountID != "" {
+		return *account.ParentAccountID
+	}
+	return account.ID
+}
+
+func isSubBalance(account *Account) bool {
+	return account.ParentAccountID != nil && *account.ParentAccountID != ""
+}
+
+// subBalanceName is the name a sub-balance carries where accounts are listed
+// one per currency, such as net worth.
+func subBalanceName(wallet *Account, currency string) string {
+	return fmt.Sprintf("%s (%s)", wallet.Name, currency)
+}
+
+// foldSubBalances lists each multi-currency account once, with its
+// sub-balances under Balances, own currency first.
+func foldSubBalances(accounts []*Account) []*Account {
+	children := make(map[string][]*Account)
+	for _, account := range accounts {
+		if isSubBalance(account) {
+			children[*account.ParentAccountID] = append(children[*account.ParentAccountID], account)
+		}
+	}
+	folded := make([]*Account, 0, len(accounts))
+	for _, account := range accounts {
+		if isSubBalance(account) {
+			continue
+		}
+		if subs := children[account.ID]; len(subs) > 0 {
+			sort.Slice(subs, func(i, j int) bool {
+				return subs[i].Currency < subs[j].Currency
+			})
+			account.Balances = []AccountBalance{accountBalanceOf(account)}
+			for _, sub := range subs {
+				account.Balances = append(account.Balances, accountBalanceOf(sub))
+			}
+		}
+		folded = append(folded, account)
+	}
+	return folded
+}
+
+func accountBalanceOf(account *Account) AccountBalance {
+	return AccountBalance{
+		AccountID:        account.ID,
+		Currency:         account.Currency,
+		InitialBalance:   account.InitialBalance,
+		CurrentBalance:   account.CurrentBalance,
+		ProjectedBalance: account.ProjectedBalance,
+	}
+}
+
+// balanceAccountID returns the account holding currency within a folded
+// multi-currency account, or "" when it does not hold it.
+func balanceAccountID(account *Account, currency string) string {
+	if strings.EqualFold(account.Currency, currency) {
+		return account.ID
+	}
+	for _, balance := range account.Balances {
+		if strings.EqualFold(balance.Currency, currency) {
+			return balance.AccountID
+		}
+	}
+	return ""
+}
+
+func (s *Service) subBalances(ctx context.Context, accountID string) ([]*Account, error) {
+	accounts, err := s.repo.ListAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	subs := make([]*Account, 0)
+	for _, account := range accounts {
+		if account.ParentAccountID != nil && *account.ParentAccountID == accountID {
+			subs = append(subs, account)
+		}
+	}
+	return subs, nil
+}
+
+// validateNewBalances checks the extra currencies requested for an account:
+// each once, none its own currency or one it already holds. Cards and
+// sub-balances cannot hold more currencies.
+func validateNewBalances(account *Account, held []*Account, balances []AccountBalance) error {
+	if len(balances) == 0 {
+		return nil
+	}
+	if isSubBalance(account) || account.Credit != nil {
+		return appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "balances"})
+	}
+	seen := map[string]bool{strings.ToUpper(account.Currency): true}
+	for _, sub := range held {
+		seen[strings.ToUpper(sub.Currency)] = true
+	}
+	for i := range balances {
+		currency := strings.ToUpper(strings.TrimSpace(balances[i].Currency))
+		if currency == "" || seen[currency] {
+			return appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{
+				"field":    "balances.currency",
+				"currency": balances[i].Currency,
+			})
+		}
+		seen[currency] = true
+		balances[i].Currency = currency
+	}
+	return nil
+}
+
+func (s *Service) createSubBalance(ctx context.Context, wallet *Account, balance AccountBalance) (*Account, error) {
+	parentID := wallet.ID
+	sub := &Account{
+		Name:            subBalanceName(wallet, balance.Currency),
+		AccountType:     wallet.AccountType,
+		Currency:        balance.Currency,
+		InitialBalance:  balance.InitialBalance,
+		CurrentBalance:  balance.InitialBalance,
+		ParentAccountID: &parentID,
+		CustomTypeID:    wallet.CustomTypeID,
+		ShowStatus:      wallet.ShowStatus,
+	}
+	normalizeAccount(sub)
+	if _, err := s.repo.CreateAccount(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// AddAccountCurrency opens another currency on an account, turning it into a
+// multi-currency account if it was not one yet.
+func (s *Service) AddAccountCurrency(ctx context.Context, accountID string, balance AccountBalance) (*Account, error) {
+	wallet, err := s.repo.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	held, err := s.subBalances(ctx, wallet.ID)
+	if err != nil {
+		return nil, err
+	}
+	balances := []AccountBalance{balance}
+	if err := validateNewBalances(wallet, held, balances); err != nil {
+		return nil, err
+	}
+	if _, err := s.createSubBalance(ctx, wallet, balances[0]); err != nil {
+		return nil, err
+	}
+	s.invalidateFinanceSummaryCache(ctx)
+	return s.GetAccount(ctx, wallet.ID)
+}
+
+// validateAccountUpdate keeps a sub-balance in its currency and a
+// multi-currency account clear of the currencies its sub-balances hold.
+func validateAccountUpdate(current, updated *Account, subs []*Account) error {
+	if isSubBalance(current) && !strings.EqualFold(current.Currency, updated.Currency) {
+		return appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "currency"})
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+	if updated.Credit != nil {
+		return appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "credit"})
+	}
+	for _, sub := range subs {
+		if strings.EqualFold(sub.Currency, updated.Currency) {
+			return appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "currency"})
+		}
+	}
+	return nil
+}
+
+// syncSubBalances carries a multi-currency account's name, type and status
+// over to its sub-balances.
+func (s *Service) syncSubBalances(ctx context.Context, wallet *Account, subs []*Account) error {
+	for _, sub := range subs {
+		sub.Name = subBalanceName(wallet, sub.Currency)
+		sub.AccountType = wallet.AccountType
+		sub.CustomTypeID = wallet.CustomTypeID
+		sub.ShowStatus = wallet.ShowStatus
+		normalizeAccount(sub)
+		if err := s.repo.UpdateAccount(ctx, sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routeToSubBalances points an entry made on a multi-currency account in
+// another of its currencies at the sub-balance holding it. The incoming side
+// of a transfer follows ToCurrency when given, else the currency sent.
+func (s *Service) routeToSubBalances(ctx context.Context, txn *Transaction) error {
+	accounts, err := s.repo.ListAccounts(ctx)
+	if err != nil {
+		return err
+	}
+	route := func(accountID *string, currency string) *string {
+		if accountID == nil || currency == "" {
+			return accountID
+		}
+		for _, account := range accounts {
+			if account.ParentAccountID != nil && *account.ParentAccountID == *accountID && strings.EqualFold(account.Currency, currency) {
+				subID := account.ID
+				return &subID
+			}
+		}
+		return accountID
+	}
+	if txn.Type != TransactionTypeTransfer {
+		txn.AccountID = route(txn.AccountID, txn.Currency)
+		return nil
+	}
+	sent := txn.Currency
+	if sent == "" && txn.FromAccountID != nil {
+		for _, account := range accounts {
+			if account.ID == *txn.FromAccountID {
+				sent = account.Currency
+			}
+		}
+	}
+	received := sent
+	if txn.ToCurrency != nil && *txn.ToCurrency != "" {
+		received = *txn.ToCurrency
+	}
+	txn.FromAccountID = route(txn.FromAccountID, txn.Currency)
+	txn.ToAccountID = route(txn.ToAccountID, received)
+	return nil
+}
+
+// unrouteSubBalances points the sending side of txn back at its parent
+// account when it sits on a sub-balance, so routeToSubBalances can pick the
+// balance that matches a corrected currency.
+func (s *Service) unrouteSubBalances(ctx context.Context, txn *Transaction) error {
+	accounts, err := s.repo.ListAccounts(ctx)
+	if err != nil {
+		return err
+	}
+	parents := make(map[string]string)
+	for _, account := range accounts {
+		if isSubBalance(account) {
+			parents[account.ID] = *account.ParentAccountID
+		}
+	}
+	for _, accountID := range []**string{&txn.AccountID, &txn.FromAccountID} {
+		if *accountID == nil {
+			continue
+		}
+		if parentID, ok := parents[**accountID]; ok {
+			*accountID = &parentID
+		}
+	}
+	return nil
+}
+
+// ExchangeCurrency moves money between two currencies of one account. It is
+// booked as a transfer between the two balances, so it never counts as
+// income or spending.
+func (s *Service) ExchangeCurrency(ctx context.Context, accountID string, input CurrencyExchangeInput) (*CurrencyExchange, error) {
+	account, err := s.repo.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := s.GetAccount(ctx, walletID(account))
+	if err != nil {
+		return nil, err
+	}
+	from := strings.ToUpper(strings.TrimSpace(input.FromCurrency))
+	to := strings.ToUpper(strings.TrimSpace(input.ToCurrency))
+	fromAccountID := balanceAccountID(wallet, from)
+	if from == "" || fromAccountID == "" {
+		return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "fromCurrency"})
+	}
+	toAccountID := balanceAccountID(wallet, to)
+	if to == "" || to == from || toAccountID == "" {
+		return nil, appErrors.WithDetails(appErrors.InvalidFinanceData, map[string]interface{}{"field": "toCurrency"})
+	}
+	if input.Amount <= 0 {
+		return nil, appErrors.WithDetails(appErrors.InvalidAmount, map[string]interface{}{"field": "amount"})
+	}
+	date := normalizeDateInput(strings.TrimSpace(input.Date))
+	if date == "" {
+		date = time.Now().UTC().Format("2006-01-02")
+	}
+	var toAmount Money
+	switch {
+	case input.ToAmount != nil:
+		toAmount = *input.ToAmount
+	case input.Rate > 0:
+		toAmount = exchangeRateFromFloat(input.Rate).Convert(input.Amount, to)
+	default:
+		rate, err := s.resolveFXRate(ctx, from, to, date)
+		if err != nil || rate.IsZero() {
+			return nil, appErrors.FXRateNotFound
+		}
+		toAmount = rate.Convert(input.Amount, to)
+	}
+	if toAmount <= 0 {
+		return nil, appErrors.WithDetails(appErrors.InvalidAmount, map[string]interface{}{"field": "toAmount"})
+	}
+	name := "Currency exchange"
+	txn, err := s.CreateTransaction(ctx, &Transaction{
+		Type:                TransactionTypeTransfer,
+		FromAccountID:       &fromAccountID,
+		ToAccountID:         &toAccountID,
+		Amount:              input.Amount,
+		Currency:            from,
+		ToAmount:            toAmount,
+		ToCurrency:          &to,
+		EffectiveRateFromTo: toAmount.Ratio(input.Amount),
+		Name:                &name,
+		Description:         input.Note,
+		Date:                date,
+		Metadata:            map[string]interface{}{"exchange": true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	wallet, err = s.GetAccount(ctx, wallet.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &CurrencyExchange{Account: wallet, Transaction: txn}, nil
+}
@@ -385,10 +385,18 @@ func (s *Service) buildRecentTransactions(ctx context.Context, userID, date stri
 func (s *Service) buildSpendingSummary(ctx context.Context, userID, date string) SpendingSummary {
 	summary := SpendingSummary{Categories: []CategoryAmount{}}
 	rows, err := s.db.QueryxContext(ctx, `
-		SELECT COALESCE(category_id, 'Other') as category, SUM(ABS(converted_amount_to_base)) as total
-		FROM transactions
-		WHERE user_id = $1 AND date = $2 AND type = 'expense' AND deleted_at IS NULL
-		GROUP BY category_id ORDER BY total DESC LIMIT 3
+		SELECT COALESCE(line.category_id, 'Other') as category, SUM(ABS(line.amount)) as total
+		FROM transactions t
+		CROSS JOIN LATERAL (
+			SELECT split->>'categoryId' AS category_id,
+				t.converted_amount_to_base * (split->>'amount')::numeric / NULLIF(t.amount, 0) AS amount
+			FROM jsonb_array_elements(t.splits) split
+			UNION ALL
+			SELECT t.category_id, t.converted_amount_to_base
+			WHERE jsonb_array_length(t.splits) = 0
+		) line
+		WHERE t.user_id = $1 AND t.date = $2 AND t.type = 'expense' AND t.status <> 'voided' AND NOT t.is_scheduled AND t.deleted_at IS NULL
+		GROUP BY 1 ORDER BY total DESC LIMIT 3
 	`, userID, date)
 	if err != nil {
 		return summary
@@ -450,8 +458,8 @@ func (s *Service) buildCashFlowTimeline(ctx context.Context, userID string, date
 		d := date.AddDate(0, 0, i)
 		dStr := d.Format("2006-01-02")
 		var income, expense float64
-		_ = s.db.GetContext(ctx, &income, `SELECT COALESCE(SUM(converted_amount_to_base), 0) FROM transactions WHERE user_id = $1 AND date = $2 AND type = 'income' AND deleted_at IS NULL`, userID, dStr)
-		_ = s.db.GetContext(ctx, &expense, `SELECT COALESCE(SUM(ABS(converted_amount_to_base)), 0) FROM transactions WHERE user_id = $1 AND date = $2 AND type = 'expense' AND deleted_at IS NULL`, userID, dStr)
+		_ = s.db.GetContext(ctx, &income, `SELECT COALESCE(SUM(converted_amount_to_base), 0) FROM transactions WHERE user_id = $1 AND date = $2 AND type = 'income' AND status <> 'voided' AND NOT is_scheduled AND deleted_at IS NULL`, userID, dStr)
+		_ = s.db.GetContext(ctx, &expense, `SELECT COALESCE(SUM(ABS(converted_amount_to_base)), 0) FROM transactions WHERE user_id = $1 AND date = $2 AND type = 'expense' AND status <> 'voided' AND NOT is_scheduled AND deleted_at IS NULL`, userID, dStr)
 		cf.Days = append(cf.Days, CashFlowDay{
 			Label:   d.Format("Mon"),
 			Income:  math.Round(income),
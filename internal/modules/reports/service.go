@@ -75,8 +75,8 @@ func (s *Service) FinanceSummary(ctx context.Context, fromDate, toDate string) (
 	summary.Period.From = fromDate
 	summary.Period.To = toDate
 
-	_ = s.db.GetContext(ctx, &summary.Income, `SELECT COALESCE(SUM(converted_amount_to_base), 0) FROM transactions WHERE date BETWEEN $1 AND $2 AND type = 'income' AND deleted_at IS NULL`, fromDate, toDate)
-	_ = s.db.GetContext(ctx, &summary.Expense, `SELECT COALESCE(SUM(converted_amount_to_base), 0) FROM transactions WHERE date BETWEEN $1 AND $2 AND type = 'expense' AND deleted_at IS NULL`, fromDate, toDate)
+	_ = s.db.GetContext(ctx, &summary.Income, `SELECT COALESCE(SUM(converted_amount_to_base), 0) FROM transactions WHERE date BETWEEN $1 AND $2 AND type = 'income' AND status <> 'voided' AND NOT is_scheduled AND deleted_at IS NULL`, fromDate, toDate)
+	_ = s.db.GetContext(ctx, &summary.Expense, `SELECT COALESCE(SUM(converted_amount_to_base), 0) FROM transactions WHERE date BETWEEN $1 AND $2 AND type = 'expense' AND status <> 'voided' AND NOT is_scheduled AND deleted_at IS NULL`, fromDate, toDate)
 	summary.Net = summary.Income - summary.Expense
 	if summary.Income > 0 {
 		summary.SavingsRate = (summary.Net / summary.Income) * 100
@@ -92,11 +92,20 @@ func (s *Service) FinanceCategories(ctx context.Context, fromDate, toDate string
 		Amount     float64 `db:"amount"`
 	}
 	rows := []row{}
+	// Split expenses count per line, each taking its share of the base amount.
 	query := `
-		SELECT COALESCE(category_id, 'uncategorized') as category_id, COALESCE(SUM(converted_amount_to_base), 0) as amount
-		FROM transactions
-		WHERE date BETWEEN $1 AND $2 AND type = 'expense' AND deleted_at IS NULL
-		GROUP BY category_id
+		SELECT COALESCE(line.category_id, 'uncategorized') as category_id, COALESCE(SUM(line.amount), 0) as amount
+		FROM transactions t
+		CROSS JOIN LATERAL (
+			SELECT split->>'categoryId' AS category_id,
+				t.converted_amount_to_base * (split->>'amount')::numeric / NULLIF(t.amount, 0) AS amount
+			FROM jsonb_array_elements(t.splits) split
+			UNION ALL
+			SELECT t.category_id, t.converted_amount_to_base
+			WHERE jsonb_array_length(t.splits) = 0
+		) line
+		WHERE t.date BETWEEN $1 AND $2 AND t.type = 'expense' AND t.status <> 'voided' AND NOT t.is_scheduled AND t.deleted_at IS NULL
+		GROUP BY 1
 		ORDER BY amount DESC
 	`
 	_ = s.db.SelectContext(ctx, &rows, query, fromDate, toDate)
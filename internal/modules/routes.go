@@ -1,6 +1,8 @@
 package modules
 
 import (
+	"context"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/jmoiron/sqlx"
 	"github.com/redis/go-redis/v9"
@@ -23,10 +25,23 @@ import (
 	searchModule "github.com/leora/leora-server/internal/modules/search"
 	"github.com/leora/leora-server/internal/modules/users"
 	widgetsModule "github.com/leora/leora-server/internal/modules/widgets"
+	"github.com/leora/leora-server/internal/storage"
 )
 
-// RegisterRoutes wires all module routes.
-func RegisterRoutes(app fiber.Router, cfg *config.Config, db *sqlx.DB, cache *redis.Client) {
+// Workers are the background jobs the modules need. The caller starts them
+// with the server's lifecycle context so they stop on shutdown.
+type Workers struct {
+	financeScheduler *financeModule.Scheduler
+}
+
+// Start launches every worker; they run until ctx is cancelled.
+func (w *Workers) Start(ctx context.Context) {
+	w.financeScheduler.Start(ctx)
+}
+
+// RegisterRoutes wires all module routes and returns the background workers
+// they rely on, not yet started.
+func RegisterRoutes(app fiber.Router, cfg *config.Config, db *sqlx.DB, cache *redis.Client) *Workers {
 	authRepo := authModule.NewPostgresRepository(db)
 	tokenStore := authModule.NewInMemoryTokenStore()
 	authService := authModule.NewService(authRepo, tokenStore, cfg.App.JWTSecret, cfg.App.JWTAccessTTL, cfg.App.JWTRefreshTTL)
@@ -36,6 +51,11 @@ func RegisterRoutes(app fiber.Router, cfg *config.Config, db *sqlx.DB, cache *re
 	authMiddleware := authModule.NewMiddleware(authService)
 	authModule.RegisterRoutes(app, authHandler, authMiddleware)
 
+	// Attachment downloads authenticate with the signed link itself, so they
+	// are served outside the protected group.
+	blobStore := storage.New(cfg.Storage, cfg.App.BasePath)
+	storage.RegisterRoutes(app, blobStore)
+
 	protected := app.Group("")
 	protected.Use(authMiddleware.RequireAuth())
 
@@ -62,12 +82,33 @@ func RegisterRoutes(app fiber.Router, cfg *config.Config, db *sqlx.DB, cache *re
 	focusHandler := focus.NewHandler(focus.NewService(focusRepo))
 	focus.RegisterRoutes(protected, focusHandler)
 
+	// Notifications service is shared with finance for budget alerts.
+	notificationsRepo := notifications.NewPostgresRepository(db)
+	notificationsService := notifications.NewService(notificationsRepo)
+
 	// Finance module - PostgreSQL
 	financeRepo := financeModule.NewPostgresRepository(db)
-	financeHandler := financeModule.NewHandler(financeModule.NewService(financeRepo, cache))
+	financeService := financeModule.NewService(financeRepo, cache)
+	financeService.SetNotifications(notificationsService)
+	var fxProviders []financeModule.FXProvider
+	if cfg.FX.CBUURL != "" {
+		fxProviders = append(fxProviders, financeModule.NewCBUProvider(cfg.FX.CBUURL, cfg.FX.SpreadPercent))
+	}
+	if cfg.FX.ECBURL != "" {
+		fxProviders = append(fxProviders, financeModule.NewECBProvider(cfg.FX.ECBURL, cfg.FX.ECBBaseCurrency, cfg.FX.SpreadPercent))
+	}
+	financeService.SetFXProviders(fxProviders...)
+	financeService.SetAttachmentStorage(blobStore, financeModule.AttachmentOptions{
+		MaxFileBytes: cfg.Storage.MaxFileBytes,
+		QuotaBytes:   cfg.Storage.QuotaBytes,
+		URLTTL:       cfg.Storage.URLTTL,
+	})
+	financeHandler := financeModule.NewHandler(financeService)
 	financeGroup := protected.Group("")
 	financeGroup.Use(authMiddleware.RequirePermission("finance:read"))
 	financeModule.RegisterRoutes(financeGroup, financeHandler)
+	financeScheduler := financeModule.NewScheduler(financeService, cfg.Scheduler.Interval)
+	financeScheduler.SetLock(financeModule.NewPostgresSchedulerLock(db))
 
 	// Dashboard module
 	dashboardHandler := dashboardModule.NewHandler(dashboardModule.NewService(db))
@@ -90,8 +131,7 @@ func RegisterRoutes(app fiber.Router, cfg *config.Config, db *sqlx.DB, cache *re
 	insightsModule.RegisterRoutes(protected, insightsHandler)
 
 	// Notifications module - PostgreSQL
-	notificationsRepo := notifications.NewPostgresRepository(db)
-	notificationsHandler := notifications.NewHandler(notifications.NewService(notificationsRepo))
+	notificationsHandler := notifications.NewHandler(notificationsService)
 	notifications.RegisterRoutes(protected, notificationsHandler)
 
 	// Widgets module - PostgreSQL
@@ -108,6 +148,10 @@ func RegisterRoutes(app fiber.Router, cfg *config.Config, db *sqlx.DB, cache *re
 	premiumHandler := premiumModule.NewHandler(premiumModule.NewService(premiumRepo))
 	premiumModule.RegisterRoutes(protected, premiumHandler)
 
-	adminHandler := adminModule.NewHandler(adminModule.NewService(authRepo))
+	adminService := adminModule.NewService(authRepo)
+	adminService.SetLedger(financeService)
+	adminHandler := adminModule.NewHandler(adminService)
 	adminModule.RegisterRoutes(protected, adminHandler, authMiddleware)
+
+	return &Workers{financeScheduler: financeScheduler}
 }
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/leora/leora-server/internal/config"
+)
+
+// ErrNotFound is returned when a key does not exist in the store.
+var ErrNotFound = errors.New("storage: object not found")
+
+// BlobStore keeps opaque objects under slash-separated keys.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a download link for key that stops working after ttl.
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// New builds the store selected by cfg. Local files are served under
+// basePath + "/files", see RegisterRoutes.
+func New(cfg config.StorageConfig, basePath string) BlobStore {
+	if cfg.Backend == "s3" {
+		return NewS3Store(S3Options{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+		})
+	}
+	return NewLocalStore(cfg.LocalDir, basePath+"/files", []byte(cfg.SigningSecret))
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"mime"
+	"path"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/leora/leora-server/internal/common/response"
+	appErrors "github.com/leora/leora-server/internal/errors"
+)
+
+// RegisterRoutes serves signed downloads for the local store. The signature
+// is the authorisation, so the route is registered ahead of the auth
+// middleware. S3 links go to the bucket directly and need no route.
+func RegisterRoutes(router fiber.Router, store BlobStore) {
+	local, ok := store.(*LocalStore)
+	if !ok {
+		return
+	}
+	router.Get("/files/*", local.serveSigned)
+}
+
+func (s *LocalStore) serveSigned(c *fiber.Ctx) error {
+	key := c.Params("*")
+	if err := s.Verify(key, c.Query("expires"), c.Query("signature")); err != nil {
+		return response.Failure(c, appErrors.AttachmentLinkInvalid)
+	}
+	file, err := s.Get(c.Context(), key)
+	if err == ErrNotFound {
+		return response.Failure(c, appErrors.AttachmentNotFound)
+	}
+	if err != nil {
+		return response.Failure(c, appErrors.InternalServerError)
+	}
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderCacheControl, "private, max-age=300")
+	return c.SendStream(file)
+}
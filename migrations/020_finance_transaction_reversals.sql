@@ -0,0 +1,29 @@
+-- Migration 020: voided status and reversal entries for immutable ledger corrections
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS check_transaction_status;
+ALTER TABLE transactions ADD CONSTRAINT check_transaction_status
+    CHECK (status IN ('pending', 'completed', 'failed', 'voided'));
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS check_transaction_type;
+ALTER TABLE transactions ADD CONSTRAINT check_transaction_type
+    CHECK (
+        type IN (
+            'income',
+            'expense',
+            'transfer',
+            'transfer_in',
+            'transfer_out',
+            'system_opening',
+            'system_adjustment',
+            'system_archive',
+            'debt_create',
+            'debt_payment',
+            'debt_adjustment',
+            'account_create_funding',
+            'account_delete_withdrawal',
+            'budget_add_value',
+            'debt_add_value',
+            'debt_full_payment',
+            'reversal'
+        )
+    );
@@ -0,0 +1,67 @@
+-- Migration 021: recurring transaction templates and their scheduled occurrences
+
+CREATE TABLE IF NOT EXISTS recurring_transactions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL DEFAULT '',
+    type TEXT NOT NULL,
+    account_id UUID,
+    from_account_id UUID,
+    to_account_id UUID,
+    amount DECIMAL(19,4) NOT NULL,
+    currency TEXT NOT NULL,
+    to_amount DECIMAL(19,4) NOT NULL DEFAULT 0,
+    to_currency TEXT,
+    category_id TEXT,
+    subcategory_id TEXT,
+    description TEXT,
+    budget_id UUID,
+    goal_id UUID,
+    counterparty_id UUID,
+    tags JSONB NOT NULL DEFAULT '[]'::jsonb,
+    frequency TEXT NOT NULL,
+    interval_count INTEGER NOT NULL DEFAULT 1,
+    interval_unit TEXT,
+    day_of_week INTEGER,
+    day_of_month INTEGER,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    occurrence_count INTEGER,
+    occurrences_generated INTEGER NOT NULL DEFAULT 0,
+    next_occurrence_date DATE,
+    last_occurrence_date DATE,
+    posting_mode TEXT NOT NULL DEFAULT 'auto',
+    status TEXT NOT NULL DEFAULT 'active',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT check_recurring_type CHECK (type IN ('income', 'expense', 'transfer')),
+    CONSTRAINT check_recurring_frequency CHECK (frequency IN ('daily', 'weekly', 'monthly', 'yearly', 'custom')),
+    CONSTRAINT check_recurring_posting_mode CHECK (posting_mode IN ('auto', 'confirm')),
+    CONSTRAINT check_recurring_status CHECK (status IN ('active', 'paused', 'completed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_transactions_user_id
+    ON recurring_transactions(user_id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_recurring_transactions_due
+    ON recurring_transactions(next_occurrence_date) WHERE deleted_at IS NULL AND status = 'active';
+
+CREATE TABLE IF NOT EXISTS recurring_transaction_occurrences (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    recurring_id UUID NOT NULL REFERENCES recurring_transactions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    due_date DATE NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    transaction_id UUID,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CONSTRAINT check_recurring_occurrence_status CHECK (status IN ('pending', 'posted', 'skipped', 'failed')),
+    CONSTRAINT uq_recurring_occurrence_due UNIQUE (recurring_id, due_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_occurrences_user_status
+    ON recurring_transaction_occurrences(user_id, status);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_recurring_id
+    ON transactions(recurring_id) WHERE recurring_id IS NOT NULL;
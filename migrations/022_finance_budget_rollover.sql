@@ -0,0 +1,35 @@
+-- Migration 022: budget rollover modes and closed-period snapshots
+
+ALTER TABLE budgets
+    ADD COLUMN IF NOT EXISTS carried_amount DECIMAL(19,4) NOT NULL DEFAULT 0;
+
+UPDATE budgets SET rollover_mode = 'carry_remainder' WHERE rollover_mode = 'carryover';
+UPDATE budgets SET rollover_mode = 'none'
+    WHERE rollover_mode NOT IN ('none', 'carry_remainder', 'carry_overspend', 'carry_both');
+
+ALTER TABLE budgets DROP CONSTRAINT IF EXISTS check_budget_rollover_mode;
+ALTER TABLE budgets ADD CONSTRAINT check_budget_rollover_mode
+    CHECK (rollover_mode IN ('none', 'carry_remainder', 'carry_overspend', 'carry_both'));
+
+CREATE TABLE IF NOT EXISTS budget_periods (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    budget_id UUID NOT NULL REFERENCES budgets(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    currency TEXT NOT NULL,
+    base_limit DECIMAL(19,4) NOT NULL DEFAULT 0,
+    carried_in DECIMAL(19,4) NOT NULL DEFAULT 0,
+    effective_limit DECIMAL(19,4) NOT NULL DEFAULT 0,
+    spent_amount DECIMAL(19,4) NOT NULL DEFAULT 0,
+    remaining_amount DECIMAL(19,4) NOT NULL DEFAULT 0,
+    percent_used DECIMAL(9,2) NOT NULL DEFAULT 0,
+    is_overspent BOOLEAN NOT NULL DEFAULT FALSE,
+    carried_out DECIMAL(19,4) NOT NULL DEFAULT 0,
+    rollover_mode TEXT NOT NULL DEFAULT 'none',
+    closed_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CONSTRAINT uq_budget_periods_start UNIQUE (budget_id, period_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_budget_periods_budget ON budget_periods(budget_id, period_start DESC);
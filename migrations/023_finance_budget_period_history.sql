@@ -0,0 +1,8 @@
+-- Migration 023: lookup indexes for automatic budget period closing and history
+
+CREATE INDEX IF NOT EXISTS idx_budgets_period_end
+    ON budgets(end_date)
+    WHERE deleted_at IS NULL AND period_type IN ('weekly', 'monthly');
+
+CREATE INDEX IF NOT EXISTS idx_budget_periods_user_start
+    ON budget_periods(user_id, budget_id, period_start DESC);
@@ -0,0 +1,15 @@
+-- Migration 024: per-budget alert thresholds and the alerts already sent per period
+
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS alert_thresholds JSONB NOT NULL DEFAULT '[50, 80, 100]'::jsonb;
+
+CREATE TABLE IF NOT EXISTS budget_alerts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    budget_id UUID NOT NULL REFERENCES budgets(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- Start of the budget window the alert belongs to; empty for budgets without periods.
+    period_start TEXT NOT NULL DEFAULT '',
+    threshold TEXT NOT NULL,
+    percent_used DECIMAL(9,2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CONSTRAINT uq_budget_alert_period_threshold UNIQUE (budget_id, period_start, threshold)
+);
@@ -0,0 +1,20 @@
+-- Migration 025: installment plans for debts (equal, annuity or custom dates)
+
+CREATE TABLE IF NOT EXISTS debt_installments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    debt_id UUID NOT NULL REFERENCES debts(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    sequence INTEGER NOT NULL,
+    plan_type TEXT NOT NULL,
+    due_date DATE NOT NULL,
+    amount DECIMAL(19,4) NOT NULL,
+    principal_amount DECIMAL(19,4) NOT NULL DEFAULT 0,
+    interest_amount DECIMAL(19,4) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CONSTRAINT check_debt_installment_plan_type CHECK (plan_type IN ('equal', 'annuity', 'custom')),
+    CONSTRAINT uq_debt_installment_sequence UNIQUE (debt_id, sequence)
+);
+
+CREATE INDEX IF NOT EXISTS idx_debt_installments_user_due
+    ON debt_installments(user_id, due_date);
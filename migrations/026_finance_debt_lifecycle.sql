@@ -0,0 +1,32 @@
+-- Migration 026: debt due-date lifecycle (due_soon status, transition log, reminder log)
+
+ALTER TABLE debts DROP CONSTRAINT IF EXISTS check_debt_status;
+ALTER TABLE debts ADD CONSTRAINT check_debt_status
+    CHECK (status IN ('active', 'due_soon', 'paid', 'overdue', 'canceled'));
+
+CREATE INDEX IF NOT EXISTS idx_debts_open_due_date
+    ON debts(due_date)
+    WHERE deleted_at IS NULL AND status IN ('active', 'due_soon', 'overdue');
+
+CREATE TABLE IF NOT EXISTS debt_status_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    debt_id UUID NOT NULL REFERENCES debts(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+    due_date DATE,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_debt_status_events_user_occurred
+    ON debt_status_events(user_id, occurred_at DESC);
+
+CREATE TABLE IF NOT EXISTS debt_reminders (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    debt_id UUID NOT NULL REFERENCES debts(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reminder_date DATE NOT NULL,
+    status TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CONSTRAINT uq_debt_reminder_day UNIQUE (debt_id, reminder_date)
+);
@@ -0,0 +1,8 @@
+-- Migration 027: provider-fed FX rates (one row per day, pair and source)
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_fx_rates_provider_day
+    ON fx_rates(rate_date, from_currency, to_currency, source)
+    WHERE source <> 'manual';
+
+CREATE INDEX IF NOT EXISTS idx_fx_rates_source_pair
+    ON fx_rates(source, from_currency, to_currency, rate_date DESC);
@@ -0,0 +1,5 @@
+-- Migration 028: case-normalised FX pairs for indexed rate lookup
+
+UPDATE fx_rates
+SET from_currency = UPPER(from_currency), to_currency = UPPER(to_currency)
+WHERE from_currency <> UPPER(from_currency) OR to_currency <> UPPER(to_currency);
@@ -0,0 +1,13 @@
+-- Migration 029: indexes for filtered, cursor-paginated transaction lists
+
+CREATE INDEX IF NOT EXISTS idx_transactions_user_feed
+    ON transactions(user_id, date DESC, created_at DESC, id DESC)
+    WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_user_counterparty
+    ON transactions(user_id, counterparty_id, date DESC)
+    WHERE deleted_at IS NULL AND counterparty_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_user_budget
+    ON transactions(user_id, budget_id, date DESC)
+    WHERE deleted_at IS NULL AND budget_id IS NOT NULL;
@@ -0,0 +1,31 @@
+-- Migration 030: indexes for transaction amount, tag, habit, status and text filters
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_user_amount
+    ON transactions(user_id, amount)
+    WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_user_base_amount
+    ON transactions(user_id, converted_amount_to_base)
+    WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_user_status
+    ON transactions(user_id, status, date DESC)
+    WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_user_habit
+    ON transactions(user_id, habit_id, date DESC)
+    WHERE deleted_at IS NULL AND habit_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_tags
+    ON transactions USING GIN (tags)
+    WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_name_trgm
+    ON transactions USING GIN (name gin_trgm_ops)
+    WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_description_trgm
+    ON transactions USING GIN (description gin_trgm_ops)
+    WHERE deleted_at IS NULL;
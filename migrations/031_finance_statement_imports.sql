@@ -0,0 +1,64 @@
+-- Migration 031: bank statement imports (mapping profiles, staged batches, review rows)
+
+CREATE TABLE IF NOT EXISTS statement_import_profiles (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    mapping JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_statement_import_profiles_user
+    ON statement_import_profiles(user_id, name);
+
+CREATE TABLE IF NOT EXISTS statement_imports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    format TEXT NOT NULL,
+    file_name TEXT NOT NULL DEFAULT '',
+    profile_id UUID REFERENCES statement_import_profiles(id) ON DELETE SET NULL,
+    status TEXT NOT NULL DEFAULT 'review',
+    row_count INTEGER NOT NULL DEFAULT 0,
+    pending_count INTEGER NOT NULL DEFAULT 0,
+    duplicate_count INTEGER NOT NULL DEFAULT 0,
+    approved_count INTEGER NOT NULL DEFAULT 0,
+    rejected_count INTEGER NOT NULL DEFAULT 0,
+    undone_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CONSTRAINT check_statement_import_format CHECK (format IN ('csv', 'ofx', 'qif', 'camt053')),
+    CONSTRAINT check_statement_import_status CHECK (status IN ('review', 'completed', 'undone'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_statement_imports_user_created
+    ON statement_imports(user_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS statement_import_rows (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    import_id UUID NOT NULL REFERENCES statement_imports(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    line_number INTEGER NOT NULL,
+    date DATE NOT NULL,
+    amount DECIMAL(19,4) NOT NULL,
+    currency TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    payee TEXT NOT NULL DEFAULT '',
+    reference TEXT NOT NULL DEFAULT '',
+    fingerprint TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    category_id TEXT,
+    duplicate_of_id UUID,
+    transaction_id UUID,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CONSTRAINT check_statement_import_row_status CHECK (status IN ('pending', 'duplicate', 'approved', 'rejected', 'undone'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_statement_import_rows_import
+    ON statement_import_rows(import_id, line_number);
+
+CREATE INDEX IF NOT EXISTS idx_statement_import_rows_user_fingerprint
+    ON statement_import_rows(user_id, fingerprint);
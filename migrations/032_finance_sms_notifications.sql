@@ -0,0 +1,44 @@
+-- Migration 032: bank SMS notifications (card links and received messages)
+
+CREATE TABLE IF NOT EXISTS sms_card_links (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    card_mask VARCHAR(4) NOT NULL,
+    bank TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sms_card_links_user_mask
+    ON sms_card_links(user_id, card_mask, bank);
+
+CREATE TABLE IF NOT EXISTS sms_messages (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    sender TEXT NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    fingerprint TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'unparsed',
+    bank TEXT NOT NULL DEFAULT '',
+    direction TEXT NOT NULL DEFAULT '',
+    amount DECIMAL(19,4) NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT '',
+    merchant TEXT NOT NULL DEFAULT '',
+    card_mask VARCHAR(4) NOT NULL DEFAULT '',
+    balance DECIMAL(19,4),
+    occurred_at TIMESTAMP WITH TIME ZONE,
+    account_id UUID REFERENCES accounts(id) ON DELETE SET NULL,
+    transaction_id UUID,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CONSTRAINT check_sms_message_status CHECK (status IN ('posted', 'unparsed', 'unmatched', 'failed'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sms_messages_user_fingerprint
+    ON sms_messages(user_id, fingerprint);
+
+CREATE INDEX IF NOT EXISTS idx_sms_messages_user_status
+    ON sms_messages(user_id, status, received_at DESC);
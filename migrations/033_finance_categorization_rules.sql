@@ -0,0 +1,18 @@
+-- Migration 033: user-defined auto-categorization rules
+
+CREATE TABLE IF NOT EXISTS categorization_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'active',
+    stop_on_match BOOLEAN NOT NULL DEFAULT false,
+    conditions JSONB NOT NULL DEFAULT '{}'::jsonb,
+    actions JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CONSTRAINT check_categorization_rule_status CHECK (status IN ('active', 'paused'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_categorization_rules_user_priority
+    ON categorization_rules(user_id, priority, created_at);
@@ -0,0 +1,8 @@
+-- Migration 034: split lines on income and expense transactions
+
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS splits JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+-- Budget and category filters match split lines with a containment query.
+CREATE INDEX IF NOT EXISTS idx_transactions_splits
+    ON transactions USING GIN (splits jsonb_path_ops);
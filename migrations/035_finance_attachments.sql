@@ -0,0 +1,30 @@
+-- Migration 035: uploaded receipts and documents
+
+CREATE TABLE IF NOT EXISTS attachments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    file_name TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    checksum TEXT NOT NULL,
+    storage_key TEXT NOT NULL,
+    thumbnail_key TEXT,
+    thumbnail_size_bytes BIGINT NOT NULL DEFAULT 0,
+    width INTEGER,
+    height INTEGER,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CONSTRAINT check_attachment_size CHECK (size_bytes > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_user_created
+    ON attachments(user_id, created_at DESC);
+
+ALTER TABLE debts
+    ADD COLUMN IF NOT EXISTS attachments JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+ALTER TABLE debt_payments
+    ADD COLUMN IF NOT EXISTS attachments JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+-- Deleting an attachment checks that no entry still references it.
+CREATE INDEX IF NOT EXISTS idx_transactions_attachments
+    ON transactions USING GIN (attachments jsonb_path_ops);
@@ -0,0 +1,37 @@
+-- Migration 036: account reconciliation against statement balances
+
+CREATE TABLE IF NOT EXISTS account_reconciliations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    currency VARCHAR(3) NOT NULL,
+    statement_date DATE NOT NULL,
+    statement_balance DECIMAL(19,4) NOT NULL,
+    status TEXT NOT NULL DEFAULT 'in_progress',
+    opening_balance DECIMAL(19,4) NOT NULL DEFAULT 0,
+    cleared_balance DECIMAL(19,4) NOT NULL DEFAULT 0,
+    difference DECIMAL(19,4) NOT NULL DEFAULT 0,
+    cleared_transaction_ids JSONB NOT NULL DEFAULT '[]'::jsonb,
+    adjustment_transaction_id UUID,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CONSTRAINT check_reconciliation_status CHECK (status IN ('in_progress', 'completed', 'cancelled'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_reconciliations_account
+    ON account_reconciliations(user_id, account_id, statement_date DESC);
+
+-- At most one open reconciliation per account.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_account_reconciliations_open
+    ON account_reconciliations(account_id)
+    WHERE status = 'in_progress';
+
+-- Entries cleared by a completed reconciliation are locked.
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS reconciliation_id UUID REFERENCES account_reconciliations(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS cleared_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_reconciliation
+    ON transactions(reconciliation_id)
+    WHERE reconciliation_id IS NOT NULL;
@@ -0,0 +1,19 @@
+-- Migration 037: audit trail for ledger integrity repairs
+
+CREATE TABLE IF NOT EXISTS ledger_repairs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    entity_type TEXT NOT NULL,
+    entity_id UUID NOT NULL,
+    field TEXT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    stored_amount DECIMAL(19,4) NOT NULL,
+    expected_amount DECIMAL(19,4) NOT NULL,
+    adjustment_transaction_id UUID REFERENCES transactions(id) ON DELETE SET NULL,
+    repaired_by TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CONSTRAINT check_ledger_repair_entity CHECK (entity_type IN ('account', 'budget', 'debt'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_repairs_user_created
+    ON ledger_repairs(user_id, created_at DESC);
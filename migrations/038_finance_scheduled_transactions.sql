@@ -0,0 +1,9 @@
+-- Migration 038: future-dated transactions that post when their date arrives
+
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS is_scheduled BOOLEAN NOT NULL DEFAULT false;
+
+-- The auto-post job and the upcoming list only look at entries still waiting.
+CREATE INDEX IF NOT EXISTS idx_transactions_scheduled_due
+    ON transactions(date, user_id)
+    WHERE is_scheduled AND status IN ('pending', 'failed') AND deleted_at IS NULL;
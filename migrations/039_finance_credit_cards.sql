@@ -0,0 +1,37 @@
+-- Migration 039: credit card accounts with statement cycles
+
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS credit_limit DECIMAL(19,4) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS statement_closing_day SMALLINT,
+    ADD COLUMN IF NOT EXISTS payment_due_day SMALLINT,
+    ADD COLUMN IF NOT EXISTS minimum_payment_percent NUMERIC(6,3) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS minimum_payment_amount DECIMAL(19,4) NOT NULL DEFAULT 0;
+
+ALTER TABLE accounts
+    ADD CONSTRAINT check_account_statement_closing_day CHECK (statement_closing_day BETWEEN 1 AND 31),
+    ADD CONSTRAINT check_account_payment_due_day CHECK (payment_due_day BETWEEN 1 AND 31);
+
+CREATE TABLE IF NOT EXISTS credit_statements (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    currency VARCHAR(3) NOT NULL,
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    due_date DATE NOT NULL,
+    opening_balance DECIMAL(19,4) NOT NULL DEFAULT 0,
+    charges DECIMAL(19,4) NOT NULL DEFAULT 0,
+    credits DECIMAL(19,4) NOT NULL DEFAULT 0,
+    statement_balance DECIMAL(19,4) NOT NULL DEFAULT 0,
+    minimum_payment DECIMAL(19,4) NOT NULL DEFAULT 0,
+    credit_limit DECIMAL(19,4) NOT NULL DEFAULT 0,
+    reminder_sent_at TIMESTAMP WITH TIME ZONE,
+    overdue_notified_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    -- A cycle is closed once, however often the job runs.
+    CONSTRAINT uq_credit_statements_period UNIQUE (account_id, period_end)
+);
+
+CREATE INDEX IF NOT EXISTS idx_credit_statements_account
+    ON credit_statements(user_id, account_id, period_end DESC);
@@ -0,0 +1,15 @@
+-- Migration 040: multi-currency accounts
+-- Each currency other than the account's own is held by a sub-balance account
+-- pointing at it.
+
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS parent_account_id UUID REFERENCES accounts(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_accounts_parent
+    ON accounts(parent_account_id)
+    WHERE parent_account_id IS NOT NULL;
+
+-- One sub-balance per currency.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_accounts_parent_currency
+    ON accounts(parent_account_id, currency)
+    WHERE parent_account_id IS NOT NULL AND deleted_at IS NULL;
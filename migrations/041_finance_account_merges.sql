@@ -0,0 +1,24 @@
+-- Migration 041: audit trail for accounts merged into another
+
+CREATE TABLE IF NOT EXISTS account_merges (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    source_account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    target_account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    source_currency VARCHAR(3) NOT NULL,
+    target_currency VARCHAR(3) NOT NULL,
+    -- Zero when the currencies match.
+    rate DECIMAL(19,10) NOT NULL DEFAULT 0,
+    source_balance DECIMAL(19,4) NOT NULL DEFAULT 0,
+    converted_balance DECIMAL(19,4) NOT NULL DEFAULT 0,
+    target_balance DECIMAL(19,4) NOT NULL DEFAULT 0,
+    transactions_moved INTEGER NOT NULL DEFAULT 0,
+    recurring_moved INTEGER NOT NULL DEFAULT 0,
+    budgets_moved INTEGER NOT NULL DEFAULT 0,
+    debts_moved INTEGER NOT NULL DEFAULT 0,
+    goal_moved BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_merges_user_created
+    ON account_merges(user_id, created_at DESC);
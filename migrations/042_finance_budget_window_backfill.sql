@@ -0,0 +1,13 @@
+-- Migration 042: seed the current window of weekly and monthly budgets created without dates
+
+UPDATE budgets
+SET start_date = date_trunc('week', now() AT TIME ZONE 'UTC')::date,
+    end_date = (date_trunc('week', now() AT TIME ZONE 'UTC') + INTERVAL '6 days')::date
+WHERE period_type = 'weekly' AND deleted_at IS NULL
+    AND (start_date IS NULL OR end_date IS NULL);
+
+UPDATE budgets
+SET start_date = date_trunc('month', now() AT TIME ZONE 'UTC')::date,
+    end_date = (date_trunc('month', now() AT TIME ZONE 'UTC') + INTERVAL '1 month' - INTERVAL '1 day')::date
+WHERE period_type = 'monthly' AND deleted_at IS NULL
+    AND (start_date IS NULL OR end_date IS NULL);